@@ -11,23 +11,35 @@ import (
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strings"
 	"syscall"
 
 	"github.com/forceu/gokapi/internal/configuration/database/migration"
 	"github.com/forceu/gokapi/internal/helper/systemd"
+	"github.com/forceu/gokapi/internal/logging/mail"
 	"github.com/forceu/gokapi/internal/logging/serverstats"
+	"github.com/forceu/gokapi/internal/logging/webhooks"
 
+	"github.com/forceu/gokapi/cmd/gokapi/admincommands"
 	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/backup"
 	"github.com/forceu/gokapi/internal/configuration/cloudconfig"
 	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/database/leader"
 	"github.com/forceu/gokapi/internal/configuration/setup"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
 	"github.com/forceu/gokapi/internal/encryption"
 	"github.com/forceu/gokapi/internal/environment"
 	"github.com/forceu/gokapi/internal/environment/flagparser"
+	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage"
 	"github.com/forceu/gokapi/internal/storage/filesystem"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/keyrotation"
+	"github.com/forceu/gokapi/internal/storage/postprocess"
+	"github.com/forceu/gokapi/internal/storage/scrub"
 	"github.com/forceu/gokapi/internal/webserver"
 	"github.com/forceu/gokapi/internal/webserver/authentication"
 	"github.com/forceu/gokapi/internal/webserver/ssl"
@@ -50,6 +62,9 @@ func main() {
 	passedFlags := flagparser.ParseFlags()
 	handleServiceInstall(passedFlags)
 	handleDbMigration(passedFlags)
+	handleAdminCommand(passedFlags)
+	handleBackup(passedFlags)
+	handleRestore(passedFlags)
 
 	showVersion(passedFlags)
 	fmt.Println(logo)
@@ -59,6 +74,8 @@ func main() {
 	if !reconfigureServer(passedFlags) {
 		configuration.ConnectDatabase()
 	}
+	configuration.ConnectSharedState()
+	logging.InitAuditLog()
 
 	setDeploymentPassword(passedFlags)
 	checkIfUserExists()
@@ -66,10 +83,18 @@ func main() {
 	authentication.Init(configuration.Get().Authentication)
 	createSsl(passedFlags)
 	initCloudConfig(passedFlags)
+	resumeKeyRotation()
+	handleEncryptionMaintenance(passedFlags)
+	handleScrub(passedFlags)
+	leader.Init()
+	mail.Init(configuration.Get().ServerUrl, configuration.Get().IncludeFilename, configuration.Get().PublicName)
 	storage.CleanUp(true)
+	scrub.Init()
+	postprocess.Init()
 	logging.LogStartup()
 	showDeprecationWarnings()
 	serverstats.Init()
+	webhooks.Init(configuration.Get().ServerUrl, configuration.Get().IncludeFilename)
 	go webserver.Start()
 
 	c := make(chan os.Signal)
@@ -83,8 +108,11 @@ func shutdown() {
 	fmt.Println("Shutting down...")
 	webserver.Shutdown()
 	serverstats.Shutdown()
+	webhooks.Shutdown()
 	logging.LogShutdown()
+	leader.Shutdown()
 	database.Close()
+	sharedstate.Close()
 }
 
 // Checks for command line arguments that have to be parsed before loading the configuration
@@ -206,6 +234,85 @@ func handleDbMigration(passedFlags flagparser.MainFlags) {
 	osExit(0)
 }
 
+// handleAdminCommand executes a command that modifies the database without starting the webserver and exits afterwards
+func handleAdminCommand(passedFlags flagparser.MainFlags) {
+	if len(passedFlags.AdminCommand) == 0 {
+		return
+	}
+	if !configuration.Exists() {
+		fmt.Println("Error: Gokapi has not been set up yet. Please start Gokapi without a command first.")
+		osExit(1)
+		return
+	}
+	// Messages printed while loading are redirected to stderr, so that the output of the command can be parsed
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	configuration.Load()
+	logging.SetCommandLineMode()
+	configuration.ConnectDatabase()
+	configuration.ConnectSharedState()
+	logging.InitAuditLog()
+	cConfig, ok := cloudconfig.Load()
+	if ok && aws.Init(cConfig.Aws) {
+		filesystem.SetAws()
+	}
+	os.Stdout = stdout
+	exitCode := admincommands.Run(passedFlags.AdminCommand)
+	database.Close()
+	sharedstate.Close()
+	osExit(exitCode)
+}
+
+// handleBackup creates a backup archive if requested and exits afterwards
+func handleBackup(passedFlags flagparser.MainFlags) {
+	if !passedFlags.Backup.DoBackup {
+		return
+	}
+	if !configuration.Exists() {
+		fmt.Println("Error: Gokapi has not been set up yet. Please start Gokapi without a command first.")
+		osExit(1)
+		return
+	}
+	configuration.Load()
+	configuration.ConnectDatabase()
+	fmt.Println("Creating backup...")
+	manifest, err := backup.Create(passedFlags.Backup.Output, passedFlags.Backup.IncludeData, versionGokapi)
+	database.Close()
+	if err != nil {
+		fmt.Println("Error: " + err.Error())
+		osExit(1)
+		return
+	}
+	for _, blob := range manifest.MissingBlobs {
+		fmt.Println("Warning: File " + blob + " could not be found in the data directory and has not been added")
+	}
+	fmt.Println("Backup has been written to " + passedFlags.Backup.Output)
+	osExit(0)
+}
+
+// handleRestore restores a backup archive into an empty instance if requested and exits afterwards
+func handleRestore(passedFlags flagparser.MainFlags) {
+	if !passedFlags.Restore.DoRestore {
+		return
+	}
+	fmt.Println("Restoring backup...")
+	manifest, err := backup.Restore(passedFlags.Restore.Input, !passedFlags.Restore.SkipData, passedFlags.Restore.Force)
+	if err != nil {
+		fmt.Println("Error: " + err.Error())
+		osExit(1)
+		return
+	}
+	database.Close()
+	if manifest.IncludesData && passedFlags.Restore.SkipData {
+		fmt.Println("The files of the data directory have not been restored.")
+	}
+	if !manifest.IncludesData {
+		fmt.Println("The backup does not include the files of the data directory, they have to be restored separately.")
+	}
+	fmt.Println("Backup created with Gokapi v" + manifest.GokapiVersion + " has been restored successfully.")
+	osExit(0)
+}
+
 func handleServiceInstall(passedFlags flagparser.MainFlags) {
 	if passedFlags.InstallService && passedFlags.UninstallService {
 		fmt.Println("Error: Both install and uninstall flags are set.")
@@ -229,6 +336,98 @@ func setDeploymentPassword(passedFlags flagparser.MainFlags) {
 	configuration.SetDeploymentPassword(passedFlags.DeploymentPassword)
 }
 
+// resumeKeyRotation re-encrypts the remaining file keys, if the server was stopped during a key rotation
+func resumeKeyRotation() {
+	result, err := keyrotation.ResumeKeyRotation()
+	if err != nil {
+		fmt.Println("Error: Could not resume key rotation: " + err.Error())
+		return
+	}
+	if result.RewrappedKeys > 0 || len(result.FailedFiles) > 0 {
+		fmt.Printf("An interrupted key rotation has been completed, %d file keys have been re-encrypted, %d files could not be processed.\n",
+			result.RewrappedKeys, len(result.FailedFiles))
+	}
+}
+
+// handleEncryptionMaintenance rotates the master key or encrypts existing files if requested and exits afterwards
+func handleEncryptionMaintenance(passedFlags flagparser.MainFlags) {
+	if !passedFlags.RotateEncryptionKey && !passedFlags.EncryptExistingFiles {
+		return
+	}
+	if passedFlags.EncryptExistingFiles {
+		fmt.Println("Encrypting existing files...")
+		result, err := keyrotation.EncryptPlaintextFiles()
+		if err != nil {
+			fmt.Println("Error: " + err.Error())
+			osExit(1)
+			return
+		}
+		logging.LogEncryptFiles(models.User{}, nil, result.EncryptedFiles, len(result.FailedFiles))
+		fmt.Printf("%d files have been encrypted, %d files could not be encrypted.\n", result.EncryptedFiles, len(result.FailedFiles))
+	}
+	if passedFlags.RotateEncryptionKey {
+		newPassword := ""
+		if keyrotation.RequiresPassword() {
+			newPassword = readNewEncryptionPassword()
+		}
+		fmt.Println("Rotating master encryption key...")
+		result, err := keyrotation.RotateMasterKey(newPassword)
+		if err != nil {
+			fmt.Println("Error: " + err.Error())
+			osExit(1)
+			return
+		}
+		logging.LogKeyRotation(models.User{}, nil, result.RewrappedKeys, len(result.FailedFiles))
+		fmt.Printf("The master key has been replaced, %d file keys have been re-encrypted, %d files could not be processed.\n",
+			result.RewrappedKeys, len(result.FailedFiles))
+	}
+	osExit(0)
+}
+
+// handleScrub verifies all stored files if requested, prints the findings and exits afterwards
+func handleScrub(passedFlags flagparser.MainFlags) {
+	if !passedFlags.Scrub {
+		return
+	}
+	fmt.Println("Verifying stored files...")
+	report, err := scrub.Run(passedFlags.ScrubAction)
+	if err != nil {
+		fmt.Println("Error: " + err.Error())
+		osExit(1)
+		return
+	}
+	for _, finding := range report.Findings {
+		location := "data directory"
+		if !finding.IsLocalStorage() {
+			location = "bucket " + finding.Bucket
+		}
+		line := fmt.Sprintf("%s: %s in %s", finding.Type, finding.Key, location)
+		if len(finding.FileIds) > 0 {
+			line = line + " (files: " + strings.Join(finding.FileIds, ", ") + ")"
+		}
+		if finding.Resolution != "" {
+			line = line + " - " + finding.Resolution
+		}
+		fmt.Println(line)
+	}
+	if report.Error != "" {
+		fmt.Println("Error: " + report.Error)
+	}
+	fmt.Printf("%d objects have been checked, %d problems were found.\n", report.CheckedObjects, len(report.Findings))
+	osExit(0)
+}
+
+func readNewEncryptionPassword() string {
+	fmt.Println("Please enter the new encryption password:")
+	password := helper.ReadPassword()
+	fmt.Println("Please repeat the new encryption password:")
+	if helper.ReadPassword() != password {
+		fmt.Println("Error: Passwords do not match")
+		osExit(1)
+	}
+	return password
+}
+
 var osExit = os.Exit
 
 // ASCII art logo
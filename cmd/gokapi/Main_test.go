@@ -34,3 +34,22 @@ func TestCreateSsl(t *testing.T) {
 	createSsl(flagparser.MainFlags{CreateSsl: true})
 	test.FileExists(t, "test/ssl.key")
 }
+
+func TestHandleAdminCommand(t *testing.T) {
+	handleAdminCommand(flagparser.MainFlags{})
+	osExit = test.ExitCode(t, 0)
+	handleAdminCommand(flagparser.MainFlags{AdminCommand: []string{"user", "list"}})
+	osExit = test.ExitCode(t, 2)
+	handleAdminCommand(flagparser.MainFlags{AdminCommand: []string{"user", "invalid"}})
+}
+
+func TestHandleBackupAndRestore(t *testing.T) {
+	archivePath := t.TempDir() + "/backup.tar"
+	handleBackup(flagparser.MainFlags{})
+	handleRestore(flagparser.MainFlags{})
+	osExit = test.ExitCode(t, 0)
+	handleBackup(flagparser.MainFlags{Backup: flagparser.BackupFlags{DoBackup: true, Output: archivePath}})
+	test.FileExists(t, archivePath)
+	osExit = test.ExitCode(t, 1)
+	handleRestore(flagparser.MainFlags{Restore: flagparser.RestoreFlags{DoRestore: true, Input: archivePath}})
+}
@@ -0,0 +1,178 @@
+package admincommands
+
+/**
+Offline administration commands that work directly on the configured database
+without starting the webserver
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// commandGroups contains all command groups in the order they are listed
+var commandGroups = []string{"user", "apikey", "file", "filerequest"}
+
+// commandLineUser is recorded as the initiator in the audit log
+var commandLineUser = models.User{Name: "command line"}
+
+type subCommand struct {
+	Name        string
+	Description string
+	Run         func(args []string) error
+}
+
+var subCommands = map[string][]subCommand{
+	"user": {
+		{"list", "Lists all users", userList},
+		{"create", "Creates a new user", userCreate},
+		{"delete", "Deletes a user", userDelete},
+		{"set-rank", "Changes the rank of a user", userSetRank},
+	},
+	"apikey": {
+		{"create", "Creates a new API key for a user", apiKeyCreate},
+		{"revoke", "Deletes an API key", apiKeyRevoke},
+	},
+	"file": {
+		{"list", "Lists all files that are not expired", fileList},
+		{"delete", "Deletes a file", fileDelete},
+		{"extend", "Changes the expiry or the download limit of a file", fileExtend},
+		{"release", "Publishes a file that has been quarantined after the upload", fileRelease},
+	},
+	"filerequest": {
+		{"list", "Lists all file requests", fileRequestList},
+		{"delete", "Deletes a file request and all files uploaded for it", fileRequestDelete},
+	},
+}
+
+// commandOutput receives the result of a command. All other messages are written to stderr,
+// so that the result can be parsed by scripts
+var commandOutput io.Writer = os.Stdout
+
+// errInvalidArguments is returned if the passed flags could not be parsed. An error message
+// has already been printed in this case
+var errInvalidArguments = errors.New("invalid arguments")
+
+// Run executes the command passed in args and returns the exit code
+func Run(args []string) int {
+	stdout := os.Stdout
+	commandOutput = stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+
+	if len(args) == 0 || subCommands[args[0]] == nil {
+		PrintUsage()
+		return 2
+	}
+	if len(args) < 2 {
+		printGroupUsage(args[0])
+		return 2
+	}
+	for _, cmd := range subCommands[args[0]] {
+		if cmd.Name != args[1] {
+			continue
+		}
+		err := cmd.Run(args[2:])
+		if err == nil {
+			return 0
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if errors.Is(err, errInvalidArguments) {
+			return 2
+		}
+		fmt.Println("Error: " + err.Error())
+		return 1
+	}
+	fmt.Println("Unknown command: " + args[0] + " " + args[1])
+	printGroupUsage(args[0])
+	return 2
+}
+
+// PrintUsage prints all available commands
+func PrintUsage() {
+	for _, group := range commandGroups {
+		for _, cmd := range subCommands[group] {
+			fmt.Printf("%-30s %s\n", group+" "+cmd.Name, cmd.Description)
+		}
+	}
+	fmt.Printf("%-30s %s\n", "", "Pass --help after a command to show its options")
+}
+
+func printGroupUsage(group string) {
+	fmt.Println("Usage of " + group + ":")
+	for _, cmd := range subCommands[group] {
+		fmt.Printf("  %-28s %s\n", group+" "+cmd.Name, cmd.Description)
+	}
+}
+
+// newFlagSet creates a flag set for a sub command, which always contains the --json flag
+func newFlagSet(name string) (*flag.FlagSet, *bool) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	jsonOutput := flags.Bool("json", false, "Outputs the result as JSON")
+	return flags, jsonOutput
+}
+
+// parseFlags parses the arguments and returns errInvalidArguments, if they could not be parsed
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errInvalidArguments
+	}
+	if flags.NArg() > 0 {
+		fmt.Println("Unexpected argument: " + flags.Arg(0))
+		flags.Usage()
+		return errInvalidArguments
+	}
+	return nil
+}
+
+// requireFlag prints an error and returns errInvalidArguments, if isSet is false
+func requireFlag(flags *flag.FlagSet, name string, isSet bool) error {
+	if isSet {
+		return nil
+	}
+	fmt.Println("Missing parameter --" + name)
+	flags.Usage()
+	return errInvalidArguments
+}
+
+// printOutput writes the value as JSON, if jsonOutput is true. Otherwise printText is called
+func printOutput(jsonOutput bool, value any, printText func(w io.Writer)) {
+	if !jsonOutput {
+		printText(commandOutput)
+		return
+	}
+	result, err := json.Marshal(value)
+	helper.Check(err)
+	_, _ = fmt.Fprintln(commandOutput, string(result))
+}
+
+// printOk outputs a confirmation for commands that do not return an object
+func printOk(jsonOutput bool, message string) {
+	printOutput(jsonOutput, struct {
+		Result string `json:"Result"`
+	}{"OK"}, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, message)
+	})
+}
+
+// formatTimestamp returns the timestamp as a human-readable UTC date
+func formatTimestamp(timestamp int64) string {
+	if timestamp == 0 {
+		return "never"
+	}
+	return time.Unix(timestamp, 0).UTC().Format("2006-01-02 15:04")
+}
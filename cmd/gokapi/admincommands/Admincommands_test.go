@@ -0,0 +1,271 @@
+//go:build test
+
+package admincommands
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+)
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	configuration.Load()
+	configuration.ConnectDatabase()
+	exitVal := m.Run()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+// runCommand executes the command and returns the exit code and the output written to stdout
+func runCommand(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	test.IsNil(t, err)
+	stdout := os.Stdout
+	os.Stdout = writer
+	exitCode := Run(args)
+	os.Stdout = stdout
+	test.IsNil(t, writer.Close())
+	output, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	return exitCode, string(output)
+}
+
+func TestRunInvalid(t *testing.T) {
+	exitCode, _ := runCommand(t)
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "invalid", "list")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "user")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "user", "invalid")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "user", "list", "--invalid")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "user", "list", "unexpected")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, output := runCommand(t, "user", "list", "--help")
+	test.IsEqualInt(t, exitCode, 0)
+	test.IsEqualString(t, output, "")
+}
+
+func TestUserCommands(t *testing.T) {
+	exitCode, output := runCommand(t, "user", "list", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	var userList []models.User
+	test.IsNil(t, json.Unmarshal([]byte(output), &userList))
+	test.IsEqualInt(t, len(userList), 2)
+
+	exitCode, output = runCommand(t, "user", "list")
+	test.IsEqualInt(t, exitCode, 0)
+	test.IsEqualBool(t, strings.Contains(output, "Super Admin"), true)
+
+	exitCode, _ = runCommand(t, "user", "create")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "user", "create", "--name", "cliuser", "--rank", "invalid")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "user", "create", "--name", "x")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, output = runCommand(t, "user", "create", "--name", "cliuser", "--rank", "admin", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	var newUser models.User
+	test.IsNil(t, json.Unmarshal([]byte(output), &newUser))
+	test.IsEqualString(t, newUser.Name, "cliuser")
+	test.IsEqualBool(t, newUser.IsAdmin(), true)
+	test.IsEqualBool(t, newUser.HasPermission(models.UserPermManageUsers), true)
+	exitCode, _ = runCommand(t, "user", "create", "--name", "cliuser")
+	test.IsEqualInt(t, exitCode, 1)
+
+	database.SaveApiKey(models.ApiKey{
+		Id:          "cliuserkey",
+		PublicId:    "cliuserkeypublic",
+		Permissions: models.ApiPermManageUsers | models.ApiPermView,
+		UserId:      newUser.Id,
+	})
+	exitCode, _ = runCommand(t, "user", "set-rank", "--id", "5", "--rank", "user")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "user", "set-rank", "--id", "9999", "--rank", "user")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "user", "set-rank", "--id", "7")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, output = runCommand(t, "user", "set-rank", "--id", strconv.Itoa(newUser.Id), "--rank", "user")
+	test.IsEqualInt(t, exitCode, 0)
+	test.IsEqualString(t, output, "User cliuser now has the rank User\n")
+	modifiedUser, ok := database.GetUser(newUser.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, modifiedUser.IsAdmin(), false)
+	test.IsEqualBool(t, modifiedUser.HasPermission(models.UserPermManageUsers), false)
+	key, ok := database.GetApiKey("cliuserkey")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, key.HasPermission(models.ApiPermManageUsers), false)
+	test.IsEqualBool(t, key.HasPermission(models.ApiPermView), true)
+
+	database.SaveMetaData(models.File{Id: "cliuserfile", Name: "cliuserfile", UserId: newUser.Id, ExpireAt: 2147483646})
+	exitCode, _ = runCommand(t, "user", "delete", "--id", "5")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "user", "delete", "--id", strconv.Itoa(newUser.Id), "--transfer-to", strconv.Itoa(newUser.Id))
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "user", "delete", "--id", strconv.Itoa(newUser.Id), "--transfer-to", "9999")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, output = runCommand(t, "user", "delete", "--id", strconv.Itoa(newUser.Id), "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	test.IsEqualString(t, output, "{\"Result\":\"OK\"}\n")
+	_, ok = database.GetUser(newUser.Id)
+	test.IsEqualBool(t, ok, false)
+	_, ok = database.GetApiKey("cliuserkey")
+	test.IsEqualBool(t, ok, false)
+	file, ok := database.GetMetaDataById("cliuserfile")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, file.UserId, 5)
+	database.DeleteMetaData("cliuserfile")
+}
+
+func TestApiKeyCommands(t *testing.T) {
+	exitCode, _ := runCommand(t, "apikey", "create")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "apikey", "create", "--user", "9999")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, output := runCommand(t, "apikey", "create", "--user", "7", "--name", "cli key", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	var result models.ApiKeyOutput
+	test.IsNil(t, json.Unmarshal([]byte(output), &result))
+	key, ok := database.GetApiKey(result.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, key.FriendlyName, "cli key")
+	test.IsEqualInt(t, key.UserId, 7)
+	test.IsEqualBool(t, key.HasPermission(models.ApiPermView), true)
+
+	exitCode, output = runCommand(t, "apikey", "create", "--user", "7", "--no-permissions", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	var resultNoPerm models.ApiKeyOutput
+	test.IsNil(t, json.Unmarshal([]byte(output), &resultNoPerm))
+	key, ok = database.GetApiKey(resultNoPerm.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, key.Permissions == models.ApiPermNone, true)
+
+	exitCode, _ = runCommand(t, "apikey", "revoke")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "apikey", "revoke", "--id", "invalid")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "apikey", "revoke", "--id", result.PublicId)
+	test.IsEqualInt(t, exitCode, 0)
+	_, ok = database.GetApiKey(result.Id)
+	test.IsEqualBool(t, ok, false)
+	exitCode, _ = runCommand(t, "apikey", "revoke", "--id", resultNoPerm.Id)
+	test.IsEqualInt(t, exitCode, 0)
+	_, ok = database.GetApiKey(resultNoPerm.Id)
+	test.IsEqualBool(t, ok, false)
+
+	database.SaveApiKey(models.ApiKey{Id: "clisystemkey", PublicId: "clisystemkeypublic", IsSystemKey: true, UserId: 5})
+	exitCode, _ = runCommand(t, "apikey", "revoke", "--id", "clisystemkey")
+	test.IsEqualInt(t, exitCode, 1)
+	database.DeleteApiKey("clisystemkey")
+}
+
+func TestFileCommands(t *testing.T) {
+	exitCode, output := runCommand(t, "file", "list", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	var files []models.FileApiOutput
+	test.IsNil(t, json.Unmarshal([]byte(output), &files))
+	test.IsEqualBool(t, len(files) > 0, true)
+	exitCode, output = runCommand(t, "file", "list", "--user", "9999", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	test.IsEqualString(t, output, "[]\n")
+	exitCode, output = runCommand(t, "file", "list")
+	test.IsEqualInt(t, exitCode, 0)
+	test.IsEqualBool(t, strings.Contains(output, "Wzol7LyY2QVczXynJtVo"), true)
+
+	exitCode, _ = runCommand(t, "file", "extend", "--id", "Wzol7LyY2QVczXynJtVo")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "file", "extend", "--id", "invalid", "--days", "2")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "file", "extend", "--id", "Wzol7LyY2QVczXynJtVo", "--days", "-2")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, output = runCommand(t, "file", "extend", "--id", "Wzol7LyY2QVczXynJtVo", "--days", "2", "--downloads", "5", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	var file models.FileApiOutput
+	test.IsNil(t, json.Unmarshal([]byte(output), &file))
+	test.IsEqualInt(t, file.DownloadsRemaining, 5)
+	expectedExpiry := time.Now().Add(48 * time.Hour).Unix()
+	test.IsEqualBool(t, file.ExpireAt >= expectedExpiry-10 && file.ExpireAt <= expectedExpiry, true)
+	exitCode, _ = runCommand(t, "file", "extend", "--id", "Wzol7LyY2QVczXynJtVo", "--unlimited-time", "--unlimited-downloads")
+	test.IsEqualInt(t, exitCode, 0)
+	storedFile, ok := database.GetMetaDataById("Wzol7LyY2QVczXynJtVo")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, storedFile.UnlimitedTime, true)
+	test.IsEqualBool(t, storedFile.UnlimitedDownloads, true)
+
+	database.SaveMetaData(models.File{Id: "clideletefile", Name: "clideletefile", SHA1: "clideletefile", UserId: 5, ExpireAt: 2147483646})
+	exitCode, _ = runCommand(t, "file", "delete")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "file", "delete", "--id", "invalid")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "file", "delete", "--id", "clideletefile")
+	test.IsEqualInt(t, exitCode, 0)
+	_, ok = database.GetMetaDataById("clideletefile")
+	test.IsEqualBool(t, ok, false)
+
+	database.SaveMetaData(models.File{Id: "cliquarantined", Name: "cliquarantined", SHA1: "cliquarantined", UserId: 5,
+		ExpireAt: 2147483646, DownloadsRemaining: 1, ProcessingState: models.FileStateQuarantined, QuarantineReason: "virus found"})
+	exitCode, output = runCommand(t, "file", "list", "--quarantined", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	test.IsNil(t, json.Unmarshal([]byte(output), &files))
+	test.IsEqualInt(t, len(files), 1)
+	test.IsEqualString(t, files[0].QuarantineReason, "virus found")
+	exitCode, _ = runCommand(t, "file", "release")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "file", "release", "--id", "Wzol7LyY2QVczXynJtVo")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "file", "release", "--id", "cliquarantined")
+	test.IsEqualInt(t, exitCode, 0)
+	storedFile, ok = database.GetMetaDataById("cliquarantined")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, storedFile.IsHeld(), false)
+}
+
+func TestFileRequestCommands(t *testing.T) {
+	database.SaveFileRequest(models.FileRequest{Id: "clirequest", Name: "CLI request", UserId: 7, ApiKey: "clirequestkey"})
+	database.SaveApiKey(models.ApiKey{Id: "clirequestkey", PublicId: "clirequestkeypublic", UserId: 7, UploadRequestId: "clirequest"})
+	database.SaveMetaData(models.File{Id: "clirequestfile", Name: "clirequestfile", SHA1: "clirequestfile", UserId: 7,
+		UploadRequestId: "clirequest", ExpireAt: 2147483646})
+
+	exitCode, output := runCommand(t, "filerequest", "list", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	var requests []models.FileRequest
+	test.IsNil(t, json.Unmarshal([]byte(output), &requests))
+	test.IsEqualInt(t, len(requests), 1)
+	test.IsEqualInt(t, requests[0].UploadedFiles, 1)
+	exitCode, output = runCommand(t, "filerequest", "list", "--user", "5", "--json")
+	test.IsEqualInt(t, exitCode, 0)
+	test.IsEqualString(t, output, "[]\n")
+	exitCode, output = runCommand(t, "filerequest", "list")
+	test.IsEqualInt(t, exitCode, 0)
+	test.IsEqualBool(t, strings.Contains(output, "CLI request"), true)
+
+	exitCode, _ = runCommand(t, "apikey", "revoke", "--id", "clirequestkey")
+	test.IsEqualInt(t, exitCode, 1)
+
+	exitCode, _ = runCommand(t, "filerequest", "delete")
+	test.IsEqualInt(t, exitCode, 2)
+	exitCode, _ = runCommand(t, "filerequest", "delete", "--id", "invalid")
+	test.IsEqualInt(t, exitCode, 1)
+	exitCode, _ = runCommand(t, "filerequest", "delete", "--id", "clirequest")
+	test.IsEqualInt(t, exitCode, 0)
+	_, ok := database.GetFileRequest("clirequest")
+	test.IsEqualBool(t, ok, false)
+	_, ok = database.GetApiKey("clirequestkey")
+	test.IsEqualBool(t, ok, false)
+	_, ok = database.GetMetaDataById("clirequestfile")
+	test.IsEqualBool(t, ok, false)
+}
@@ -0,0 +1,75 @@
+package admincommands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/webserver/api"
+)
+
+func apiKeyCreate(args []string) error {
+	flags, jsonOutput := newFlagSet("apikey create")
+	userId := flags.Int("user", 0, "The ID of the user that owns the new API key")
+	name := flags.String("name", "", "The friendly name of the new API key")
+	noPermissions := flags.Bool("no-permissions", false, "Creates the API key without the default permissions")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "user", *userId != 0)
+	if err != nil {
+		return err
+	}
+	user, err := getUser(*userId)
+	if err != nil {
+		return err
+	}
+	key := api.NewApiKey(user.Id, *name, !*noPermissions)
+	output := models.ApiKeyOutput{
+		Result:   "OK",
+		Id:       key.Id,
+		PublicId: key.PublicId,
+	}
+	printOutput(*jsonOutput, output, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "API key "+key.FriendlyName+" has been created for user "+user.Name)
+		_, _ = fmt.Fprintln(w, "API key:   "+key.Id)
+		_, _ = fmt.Fprintln(w, "Public ID: "+key.PublicId)
+	})
+	return nil
+}
+
+func apiKeyRevoke(args []string) error {
+	flags, jsonOutput := newFlagSet("apikey revoke")
+	id := flags.String("id", "", "The API key or its public ID")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "id", *id != "")
+	if err != nil {
+		return err
+	}
+	keyId := *id
+	if len(keyId) == api.LengthPublicId {
+		privateId, ok := database.GetApiKeyByPublicKey(keyId)
+		if ok {
+			keyId = privateId
+		}
+	}
+	key, ok := database.GetApiKey(keyId)
+	if !ok {
+		return errors.New("API key not found")
+	}
+	if key.IsSystemKey {
+		return errors.New("system keys cannot be revoked")
+	}
+	if key.IsUploadRequestKey() {
+		return errors.New("the API key belongs to file request " + key.UploadRequestId + ", delete the file request instead")
+	}
+	database.DeleteApiKey(key.Id)
+	printOk(*jsonOutput, "API key "+key.FriendlyName+" has been revoked")
+	return nil
+}
@@ -0,0 +1,70 @@
+package admincommands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/filerequest"
+)
+
+func fileRequestList(args []string) error {
+	flags, jsonOutput := newFlagSet("filerequest list")
+	userId := flags.Int("user", 0, "Only lists file requests owned by the user with this ID")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	requests := make([]models.FileRequest, 0)
+	for _, request := range filerequest.GetAll() {
+		if *userId == 0 || request.UserId == *userId {
+			requests = append(requests, request)
+		}
+	}
+	printOutput(*jsonOutput, requests, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ID\tNAME\tFILES\tSIZE\tEXPIRES\tUSER")
+		for _, request := range requests {
+			files := strconv.Itoa(request.UploadedFiles)
+			if request.MaxFiles != 0 {
+				files = files + "/" + strconv.Itoa(request.MaxFiles)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n", request.Id, request.Name, files,
+				helper.ByteCountSI(request.TotalFileSize), formatTimestamp(request.Expiry), request.UserId)
+		}
+		_ = tw.Flush()
+	})
+	return nil
+}
+
+func fileRequestDelete(args []string) error {
+	flags, jsonOutput := newFlagSet("filerequest delete")
+	id := flags.String("id", "", "The ID of the file request to delete")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "id", *id != "")
+	if err != nil {
+		return err
+	}
+	request, ok := database.GetFileRequest(*id)
+	if !ok {
+		return errors.New("file request not found")
+	}
+	logging.LogDeleteFileRequest(request, commandLineUser, nil)
+	// Same as filerequest.Delete, but the sources are deleted before the process exits
+	storage.DeleteFiles(filerequest.GetAllFiles(request), false)
+	database.DeleteFileRequest(request)
+	database.DeleteApiKey(request.ApiKey)
+	storage.CleanUp(false)
+	printOk(*jsonOutput, "File request "+request.Name+" has been deleted")
+	return nil
+}
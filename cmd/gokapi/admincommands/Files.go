@@ -0,0 +1,174 @@
+package admincommands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+)
+
+func fileList(args []string) error {
+	flags, jsonOutput := newFlagSet("file list")
+	userId := flags.Int("user", 0, "Only lists files uploaded by the user with this ID")
+	includeRequests := flags.Bool("include-requests", false, "Lists files uploaded for file requests as well")
+	onlyQuarantined := flags.Bool("quarantined", false, "Only lists files that have been quarantined after the upload")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	queryResult := database.QueryMetaData(models.FileQuery{
+		UserId:              *userId,
+		IncludeFileRequests: *includeRequests,
+		HideExpiredAt:       time.Now().Unix(),
+	})
+	files := make([]models.FileApiOutput, 0, len(queryResult.Files))
+	for _, file := range queryResult.Files {
+		if *onlyQuarantined && file.ProcessingState != models.FileStateQuarantined {
+			continue
+		}
+		files = append(files, toApiOutput(file))
+	}
+	printOutput(*jsonOutput, files, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ID\tNAME\tSIZE\tDOWNLOADS LEFT\tEXPIRES\tUSER\tSTATE")
+		for _, file := range files {
+			downloads := "unlimited"
+			if !file.UnlimitedDownloads {
+				downloads = strconv.Itoa(file.DownloadsRemaining)
+			}
+			expiry := "never"
+			if !file.UnlimitedTime {
+				expiry = formatTimestamp(file.ExpireAt)
+			}
+			state := "published"
+			if file.IsQuarantined {
+				state = "quarantined"
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", file.Id, file.Name, file.Size, downloads, expiry, file.UploaderId, state)
+		}
+		_ = tw.Flush()
+	})
+	return nil
+}
+
+func fileDelete(args []string) error {
+	flags, jsonOutput := newFlagSet("file delete")
+	id := flags.String("id", "", "The ID of the file to delete")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "id", *id != "")
+	if err != nil {
+		return err
+	}
+	file, ok := database.GetMetaDataById(*id)
+	if !ok {
+		return errors.New("file not found")
+	}
+	logging.LogDelete(file, commandLineUser, nil)
+	storage.DeleteFile(file.Id, false)
+	// Called directly instead of deleting the source in the background, as the process exits afterwards
+	storage.CleanUp(false)
+	printOk(*jsonOutput, "File "+file.Name+" has been deleted")
+	return nil
+}
+
+func fileExtend(args []string) error {
+	flags, jsonOutput := newFlagSet("file extend")
+	id := flags.String("id", "", "The ID of the file to modify")
+	days := flags.Int("days", 0, "Sets the expiry to this many days from now")
+	downloads := flags.Int("downloads", 0, "Sets the number of remaining downloads")
+	unlimitedTime := flags.Bool("unlimited-time", false, "Removes the expiry date")
+	unlimitedDownloads := flags.Bool("unlimited-downloads", false, "Removes the download limit")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "id", *id != "")
+	if err != nil {
+		return err
+	}
+	if *days < 0 || *downloads < 0 {
+		return errors.New("--days and --downloads must not be negative")
+	}
+	if *days == 0 && *downloads == 0 && !*unlimitedTime && !*unlimitedDownloads {
+		fmt.Println("At least one of --days, --downloads, --unlimited-time or --unlimited-downloads is required")
+		flags.Usage()
+		return errInvalidArguments
+	}
+	file, ok := database.GetMetaDataById(*id)
+	if !ok {
+		return errors.New("file not found")
+	}
+	if *unlimitedTime {
+		file.UnlimitedTime = true
+	} else if *days != 0 {
+		file.ExpireAt = time.Now().Add(time.Duration(*days) * 24 * time.Hour).Unix()
+		file.UnlimitedTime = false
+	}
+	if *unlimitedDownloads {
+		file.UnlimitedDownloads = true
+	} else if *downloads != 0 {
+		file.DownloadsRemaining = *downloads
+		file.UnlimitedDownloads = false
+	}
+
+	if file.HotlinkId != "" && !storage.IsAbleHotlink(file) {
+		database.DeleteHotlink(file.HotlinkId)
+		file.HotlinkId = ""
+	} else if file.HotlinkId == "" && storage.IsAbleHotlink(file) {
+		storage.AddHotlink(&file)
+	}
+	database.SaveMetaData(file)
+	logging.LogEdit(file, commandLineUser, nil)
+	output := toApiOutput(file)
+	printOutput(*jsonOutput, output, func(w io.Writer) {
+		expiry := "never"
+		if !output.UnlimitedTime {
+			expiry = formatTimestamp(output.ExpireAt)
+		}
+		downloadsLeft := "unlimited"
+		if !output.UnlimitedDownloads {
+			downloadsLeft = strconv.Itoa(output.DownloadsRemaining)
+		}
+		_, _ = fmt.Fprintf(w, "File %s now expires %s with %s downloads left\n", output.Name, expiry, downloadsLeft)
+	})
+	return nil
+}
+
+func fileRelease(args []string) error {
+	flags, jsonOutput := newFlagSet("file release")
+	id := flags.String("id", "", "The ID of the quarantined file to publish")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "id", *id != "")
+	if err != nil {
+		return err
+	}
+	file, err := storage.ReleaseFile(*id)
+	if err != nil {
+		return err
+	}
+	logging.LogRelease(file, commandLineUser, nil)
+	printOk(*jsonOutput, "File "+file.Name+" has been released and can be downloaded")
+	return nil
+}
+
+func toApiOutput(file models.File) models.FileApiOutput {
+	config := configuration.Get()
+	output, err := file.ToFileApiOutput(config.ServerUrl, config.IncludeFilename)
+	helper.Check(err)
+	return output
+}
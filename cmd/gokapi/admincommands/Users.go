@@ -0,0 +1,162 @@
+package admincommands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/webserver/api"
+	"github.com/forceu/gokapi/internal/webserver/authentication/users"
+)
+
+func userList(args []string) error {
+	flags, jsonOutput := newFlagSet("user list")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	allUsers := database.GetAllUsers()
+	printOutput(*jsonOutput, allUsers, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ID\tNAME\tRANK\tLAST ONLINE")
+		for _, user := range allUsers {
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", user.Id, user.Name, user.GetReadableUserLevel(), formatTimestamp(user.LastOnline))
+		}
+		_ = tw.Flush()
+	})
+	return nil
+}
+
+func userCreate(args []string) error {
+	flags, jsonOutput := newFlagSet("user create")
+	name := flags.String("name", "", "The name of the new user")
+	rank := flags.String("rank", "user", "The rank of the new user, either user or admin")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "name", *name != "")
+	if err != nil {
+		return err
+	}
+	newRank, err := parseRank(*rank)
+	if err != nil {
+		return err
+	}
+	newUser, err := users.Create(*name)
+	if err != nil {
+		return err
+	}
+	if newRank != newUser.UserLevel {
+		newUser, err = users.SetRank(newUser, newRank)
+		if err != nil {
+			return err
+		}
+	}
+	logging.LogUserCreation(newUser, commandLineUser, nil)
+	printOutput(*jsonOutput, newUser, func(w io.Writer) {
+		_, _ = fmt.Fprintf(w, "User %s has been created with ID %d\n", newUser.Name, newUser.Id)
+	})
+	return nil
+}
+
+func userDelete(args []string) error {
+	flags, jsonOutput := newFlagSet("user delete")
+	id := flags.Int("id", 0, "The ID of the user to delete")
+	deleteFiles := flags.Bool("delete-files", false, "Deletes all files and file requests of the user as well")
+	transferTo := flags.Int("transfer-to", 0, "The ID of the user that receives the files of the deleted user. Defaults to the super admin")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "id", *id != 0)
+	if err != nil {
+		return err
+	}
+	userToDelete, err := getUser(*id)
+	if err != nil {
+		return err
+	}
+	if userToDelete.IsSuperAdmin() {
+		return errors.New("the super admin cannot be deleted")
+	}
+	newOwner, ok := database.GetSuperAdmin()
+	if *transferTo != 0 {
+		newOwner, err = getUser(*transferTo)
+		if err != nil {
+			return err
+		}
+		ok = true
+	}
+	if !ok && !*deleteFiles {
+		return errors.New("no user found that can receive the files, please pass --transfer-to")
+	}
+	if newOwner.Id == userToDelete.Id && !*deleteFiles {
+		return errors.New("files cannot be transferred to the user that is deleted")
+	}
+	logging.LogUserDeletion(userToDelete, commandLineUser, nil)
+	api.DeleteUser(userToDelete, newOwner.Id, *deleteFiles)
+	printOk(*jsonOutput, "User "+userToDelete.Name+" has been deleted")
+	return nil
+}
+
+func userSetRank(args []string) error {
+	flags, jsonOutput := newFlagSet("user set-rank")
+	id := flags.Int("id", 0, "The ID of the user to modify")
+	rank := flags.String("rank", "", "The new rank of the user, either user or admin")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "id", *id != 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlag(flags, "rank", *rank != "")
+	if err != nil {
+		return err
+	}
+	newRank, err := parseRank(*rank)
+	if err != nil {
+		return err
+	}
+	user, err := getUser(*id)
+	if err != nil {
+		return err
+	}
+	if user.IsSuperAdmin() {
+		return errors.New("the rank of the super admin cannot be changed")
+	}
+	user, err = users.SetRank(user, newRank)
+	if err != nil {
+		return err
+	}
+	logging.LogUserEdit(user, commandLineUser, nil)
+	printOutput(*jsonOutput, user, func(w io.Writer) {
+		_, _ = fmt.Fprintf(w, "User %s now has the rank %s\n", user.Name, user.GetReadableUserLevel())
+	})
+	return nil
+}
+
+func getUser(id int) (models.User, error) {
+	user, ok := database.GetUser(id)
+	if !ok {
+		return models.User{}, fmt.Errorf("user with ID %d not found", id)
+	}
+	return user, nil
+}
+
+func parseRank(rank string) (models.UserRank, error) {
+	switch rank {
+	case "admin":
+		return models.UserLevelAdmin, nil
+	case "user":
+		return models.UserLevelUser, nil
+	default:
+		return 0, errors.New("invalid rank " + rank + ", must be either user or admin")
+	}
+}
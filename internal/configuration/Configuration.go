@@ -13,11 +13,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/forceu/gokapi/internal/configuration/cloudconfig"
 	"github.com/forceu/gokapi/internal/configuration/configupgrade"
 	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/database/dbabstraction"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate/redisstate"
 	"github.com/forceu/gokapi/internal/environment"
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/logging"
@@ -105,6 +109,26 @@ func ConnectDatabase() {
 	database.Upgrade()
 }
 
+// ConnectSharedState connects to the backend that shares temporary state with other instances,
+// if SHARED_STATE_URL is set. Otherwise, all temporary state is kept in memory
+func ConnectSharedState() {
+	if parsedEnvironment.SharedStateUrl == "" {
+		return
+	}
+	config, err := database.ParseUrl(parsedEnvironment.SharedStateUrl, false)
+	helper.Check(err)
+	if config.Type != dbabstraction.TypeRedis {
+		fmt.Println("Error: Only Redis is supported for SHARED_STATE_URL")
+		os.Exit(1)
+	}
+	backend, err := redisstate.New(config)
+	if err != nil {
+		fmt.Println("Error: Could not connect to shared state:", err)
+		os.Exit(1)
+	}
+	sharedstate.Init(backend)
+}
+
 // UsesHttps returns true if Gokapi URL is set to a secure URL
 func UsesHttps() bool {
 	return usesHttps
@@ -117,18 +141,49 @@ func Get() *models.Configuration {
 
 // Save the configuration as a json file
 func save() {
-	file, err := os.OpenFile(parsedEnvironment.ConfigPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	err := saveToFile()
 	if err != nil {
 		fmt.Println("Error writing configuration:", err)
 		os.Exit(1)
 	}
-	defer file.Close()
+}
 
+// saveToFile writes the configuration to a temporary file first and replaces the configuration file with it
+// afterwards, so that the previous configuration is kept if writing fails
+func saveToFile() error {
+	configPath := parsedEnvironment.ConfigPath
+	file, err := os.CreateTemp(filepath.Dir(configPath), filepath.Base(configPath)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
 	_, err = io.Copy(file, bytes.NewReader(serverSettings.ToJson()))
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
 	if err != nil {
-		fmt.Println("Error writing configuration:", err)
-		os.Exit(1)
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	err = os.Chmod(file.Name(), 0600)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(file.Name(), configPath)
+	if err != nil {
+		return err
 	}
+	// Makes sure that the rename is persisted as well
+	dir, err := os.Open(filepath.Dir(configPath))
+	if err != nil {
+		return nil
+	}
+	_ = dir.Sync()
+	_ = dir.Close()
+	return nil
 }
 
 // LoadFromSetup creates a new configuration file after a user completed the setup. If cloudConfig is not nil, a new
@@ -208,6 +263,19 @@ func SetDeploymentPassword(newPassword string) {
 	os.Exit(0)
 }
 
+// SetEncryption replaces the encryption settings and saves the configuration, e.g. after the master key was rotated.
+// If the configuration cannot be saved, the previous settings are kept and an error is returned
+func SetEncryption(encryptionConfig models.Encryption) error {
+	previousConfig := serverSettings.Encryption
+	serverSettings.Encryption = encryptionConfig
+	err := saveToFile()
+	if err != nil {
+		serverSettings.Encryption = previousConfig
+		return err
+	}
+	return nil
+}
+
 // Deprecated: SHA1 is not secure, this is only used for migrating
 // passwords from <v2.2.5 to the current version
 // Will be removed soon.
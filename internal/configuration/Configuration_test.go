@@ -76,6 +76,33 @@ func TestLoadFromSetup(t *testing.T) {
 	test.IsEqualString(t, serverSettings.ServerUrl, "serverurl")
 }
 
+func TestSetEncryption(t *testing.T) {
+	Load()
+	previousEncryption := serverSettings.Encryption
+	newEncryption := models.Encryption{Level: 2, Cipher: []byte("newcipher")}
+	test.IsNil(t, SetEncryption(newEncryption))
+	settings, err := loadFromFile(parsedEnvironment.ConfigPath)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(settings.Encryption.Cipher), "newcipher")
+	entries, err := os.ReadDir(parsedEnvironment.ConfigDir)
+	test.IsNil(t, err)
+	for _, entry := range entries {
+		test.IsEqualBool(t, strings.Contains(entry.Name(), ".tmp"), false)
+	}
+
+	// If the configuration cannot be written, the previous settings are kept
+	configPath := parsedEnvironment.ConfigPath
+	parsedEnvironment.ConfigPath = "test/invalid/config.json"
+	test.IsNotNil(t, SetEncryption(previousEncryption))
+	parsedEnvironment.ConfigPath = configPath
+	test.IsEqualString(t, string(serverSettings.Encryption.Cipher), "newcipher")
+	settings, err = loadFromFile(parsedEnvironment.ConfigPath)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(settings.Encryption.Cipher), "newcipher")
+
+	test.IsNil(t, SetEncryption(previousEncryption))
+}
+
 func TestUsesHttps(t *testing.T) {
 	usesHttps = false
 	test.IsEqualBool(t, UsesHttps(), false)
@@ -0,0 +1,213 @@
+package backup
+
+/**
+Creates and restores archives that contain the configuration, the database and optionally the stored files
+*/
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/environment"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// formatVersion is the version of the archive layout. Needs to be increased if the layout changes
+const formatVersion = 1
+
+const (
+	entryManifest    = "manifest.json"
+	entryConfig      = "config/config.json"
+	entryCloudConfig = "config/cloudconfig.yml"
+	entryDatabase    = "database/database.json"
+	prefixData       = "data/"
+)
+
+// Create writes a backup archive to the given path. If includeData is true, all files of the
+// data directory that are referenced by the database are added as well.
+// Requires the configuration to be loaded and the database to be connected
+func Create(path string, includeData bool, gokapiVersion string) (models.BackupManifest, error) {
+	config := configuration.Get()
+	manifest := models.BackupManifest{
+		FormatVersion: formatVersion,
+		GokapiVersion: gokapiVersion,
+		SchemaVersion: database.GetSchemaVersion(),
+		ConfigVersion: config.ConfigVersion,
+		CreatedAt:     time.Now().Unix(),
+		IncludesData:  includeData,
+		MissingBlobs:  make([]string, 0),
+		Entries:       make([]models.BackupEntry, 0),
+	}
+
+	configPath, _, _, cloudConfigPath := environment.GetConfigPaths()
+	configContent, err := os.ReadFile(configPath)
+	if err != nil {
+		return models.BackupManifest{}, err
+	}
+	snapshot := database.Export()
+	databaseContent, err := json.Marshal(snapshot)
+	if err != nil {
+		return models.BackupManifest{}, err
+	}
+
+	// The archive is written to a temporary file first, so that an existing backup is not
+	// replaced by an incomplete one if an error occurs
+	tempPath := path + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return models.BackupManifest{}, err
+	}
+	writer := tar.NewWriter(file)
+	err = writeArchive(writer, &manifest, configContent, cloudConfigPath, databaseContent, snapshot, config.DataDir)
+	if err == nil {
+		err = writer.Close()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return models.BackupManifest{}, err
+	}
+	return manifest, nil
+}
+
+func writeArchive(writer *tar.Writer, manifest *models.BackupManifest, configContent []byte, cloudConfigPath string,
+	databaseContent []byte, snapshot models.DatabaseSnapshot, dataDir string) error {
+	err := addBytes(writer, manifest, entryConfig, configContent)
+	if err != nil {
+		return err
+	}
+	exists, err := helper.FileExists(cloudConfigPath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		cloudConfigContent, err := os.ReadFile(cloudConfigPath)
+		if err != nil {
+			return err
+		}
+		err = addBytes(writer, manifest, entryCloudConfig, cloudConfigContent)
+		if err != nil {
+			return err
+		}
+	}
+	err = addBytes(writer, manifest, entryDatabase, databaseContent)
+	if err != nil {
+		return err
+	}
+	if manifest.IncludesData {
+		for _, blob := range getLocalBlobs(snapshot) {
+			isMissing, err := addDataFile(writer, manifest, dataDir, blob)
+			if err != nil {
+				return err
+			}
+			if isMissing {
+				manifest.MissingBlobs = append(manifest.MissingBlobs, blob)
+			}
+		}
+	}
+	// The manifest is added last, as it contains the checksums of all other entries
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeEntry(writer, entryManifest, int64(len(manifestContent)), bytes.NewReader(manifestContent), io.Discard)
+}
+
+// getLocalBlobs returns the names of all files in the data directory that are referenced by the snapshot
+func getLocalBlobs(snapshot models.DatabaseSnapshot) []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	files := make([]models.File, 0, len(snapshot.Files)+len(snapshot.FileVersions))
+	files = append(files, snapshot.Files...)
+	for _, version := range snapshot.FileVersions {
+		files = append(files, version.ToFile())
+	}
+	for _, file := range files {
+		if !file.IsLocalStorage() || file.SHA1 == "" || seen[file.SHA1] {
+			continue
+		}
+		seen[file.SHA1] = true
+		result = append(result, file.SHA1)
+	}
+	return result
+}
+
+func addBytes(writer *tar.Writer, manifest *models.BackupManifest, name string, content []byte) error {
+	hasher := sha256.New()
+	err := writeEntry(writer, name, int64(len(content)), bytes.NewReader(content), hasher)
+	if err != nil {
+		return err
+	}
+	manifest.Entries = append(manifest.Entries, models.BackupEntry{
+		Name:   name,
+		Size:   int64(len(content)),
+		Sha256: hex.EncodeToString(hasher.Sum(nil)),
+	})
+	return nil
+}
+
+// addDataFile adds a file of the data directory to the archive. Returns true if the file does not exist
+func addDataFile(writer *tar.Writer, manifest *models.BackupManifest, dataDir, name string) (bool, error) {
+	file, err := os.Open(filepath.Join(dataDir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	hasher := sha256.New()
+	err = writeEntry(writer, prefixData+name, info.Size(), file, hasher)
+	if err != nil {
+		return false, err
+	}
+	manifest.Entries = append(manifest.Entries, models.BackupEntry{
+		Name:   prefixData + name,
+		Size:   info.Size(),
+		Sha256: hex.EncodeToString(hasher.Sum(nil)),
+	})
+	return false, nil
+}
+
+func writeEntry(writer *tar.Writer, name string, size int64, content io.Reader, hasher io.Writer) error {
+	err := writer.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0600,
+		ModTime:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(io.MultiWriter(writer, hasher), io.LimitReader(content, size))
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("%s has been modified while creating the backup", name)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+//go:build test
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+)
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(true)
+	configuration.Load()
+	configuration.ConnectDatabase()
+	exitVal := m.Run()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "backup.tar")
+	filesBefore := database.GetAllMetadata()
+	apiKeysBefore := database.GetAllApiKeys()
+	usersBefore := database.GetAllUsers()
+	hotlinkFile, ok := database.GetHotlink("PhSs6mFtf8O5YGlLMfNw9rYXx9XRNkzCnJZpQBi7inunv3Z4A.jpg")
+	test.IsEqualBool(t, ok, true)
+
+	manifest, err := Create(archivePath, true, "1.0.0")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, manifest.FormatVersion, formatVersion)
+	test.IsEqualString(t, manifest.GokapiVersion, "1.0.0")
+	test.IsEqualInt(t, manifest.SchemaVersion, database.GetSchemaVersion())
+	test.IsEqualBool(t, manifest.IncludesData, true)
+	test.IsEqualString(t, manifest.Entries[0].Name, entryConfig)
+	test.IsEqualString(t, manifest.Entries[1].Name, entryDatabase)
+	test.FileExists(t, archivePath)
+	test.FileDoesNotExist(t, archivePath+".tmp")
+	_, err = verifyArchive(archivePath)
+	test.IsNil(t, err)
+
+	_, err = Restore(archivePath, true, false)
+	test.IsEqual(t, err, ErrorConfigExists)
+	_, err = Restore(archivePath, true, true)
+	test.IsEqual(t, err, ErrorDatabaseNotEmpty)
+
+	// Restore into an empty instance
+	database.Close()
+	testconfiguration.Delete()
+	testconfiguration.SetDirEnv()
+	test.IsEqualBool(t, configuration.Exists(), false)
+	// If the data cannot be extracted, the database stays empty and the restore can be repeated
+	blockedPath := os.Getenv("GOKAPI_DATA_DIR") + "/e017693e4a04a59d0b0f400fe98177fe7ee13cf7"
+	test.IsNil(t, os.MkdirAll(blockedPath+"/blocked", 0700))
+	_, err = Restore(archivePath, true, false)
+	test.IsNotNil(t, err)
+	test.IsNil(t, os.RemoveAll(blockedPath))
+	restored, err := Restore(archivePath, true, true)
+	test.IsNil(t, err)
+	test.IsEqualString(t, restored.GokapiVersion, "1.0.0")
+	test.IsEqualBool(t, configuration.Exists(), true)
+	test.IsEqualInt(t, len(database.GetAllMetadata()), len(filesBefore))
+	test.IsEqualInt(t, len(database.GetAllApiKeys()), len(apiKeysBefore))
+	test.IsEqualInt(t, len(database.GetAllUsers()), len(usersBefore))
+	for id, file := range filesBefore {
+		restoredFile, ok := database.GetMetaDataById(id)
+		test.IsEqualBool(t, ok, true)
+		test.IsEqual(t, restoredFile, file)
+	}
+	for id := range apiKeysBefore {
+		_, ok = database.GetApiKey(id)
+		test.IsEqualBool(t, ok, true)
+	}
+	restoredHotlink, ok := database.GetHotlink("PhSs6mFtf8O5YGlLMfNw9rYXx9XRNkzCnJZpQBi7inunv3Z4A.jpg")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, restoredHotlink, hotlinkFile)
+	user, ok := database.GetUser(5)
+	test.IsEqualBool(t, ok, true)
+	test.IsNotEmpty(t, user.Password)
+
+	for _, blob := range getLocalBlobs(database.Export()) {
+		isMissing := false
+		for _, missing := range manifest.MissingBlobs {
+			if missing == blob {
+				isMissing = true
+			}
+		}
+		if !isMissing {
+			test.FileExists(t, configuration.Get().DataDir+"/"+blob)
+		}
+	}
+	content, err := os.ReadFile(configuration.Get().DataDir + "/e017693e4a04a59d0b0f400fe98177fe7ee13cf7")
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(content), "789")
+	// Files that are not referenced by the database are not part of the backup
+	test.FileDoesNotExist(t, configuration.Get().DataDir+"/replacetest1")
+}
+
+func TestCreateWithoutData(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "backup.tar")
+	manifest, err := Create(archivePath, false, "1.0.0")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, manifest.IncludesData, false)
+	test.IsEqualInt(t, len(manifest.Entries), 2)
+	test.IsEqualInt(t, len(manifest.MissingBlobs), 0)
+}
+
+func TestVerifyArchive(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "backup.tar")
+	_, err := Create(archivePath, true, "1.0.0")
+	test.IsNil(t, err)
+
+	_, err = verifyArchive(filepath.Join(dir, "invalid.tar"))
+	test.IsNotNil(t, err)
+
+	modified := filepath.Join(dir, "modified.tar")
+	rewriteArchive(t, archivePath, modified, func(name string, content []byte) []byte {
+		if name == entryDatabase {
+			return append(content, ' ')
+		}
+		return content
+	}, nil)
+	_, err = verifyArchive(modified)
+	test.IsNotNil(t, err)
+
+	removed := filepath.Join(dir, "removed.tar")
+	rewriteArchive(t, archivePath, removed, func(name string, content []byte) []byte {
+		if name == entryManifest {
+			return nil
+		}
+		return content
+	}, nil)
+	_, err = verifyArchive(removed)
+	test.IsNotNil(t, err)
+
+	extraEntry := filepath.Join(dir, "extra.tar")
+	rewriteArchive(t, archivePath, extraEntry, func(name string, content []byte) []byte {
+		return content
+	}, map[string][]byte{"data/unknown": []byte("test")})
+	_, err = verifyArchive(extraEntry)
+	test.IsNotNil(t, err)
+
+	traversal := filepath.Join(dir, "traversal.tar")
+	rewriteArchive(t, archivePath, traversal, func(name string, content []byte) []byte {
+		return content
+	}, map[string][]byte{"data/../config.json": []byte("test")})
+	_, err = verifyArchive(traversal)
+	test.IsNotNil(t, err)
+}
+
+func TestIsValidDataEntry(t *testing.T) {
+	test.IsEqualBool(t, isValidDataEntry("data/e017693e4a04a59d0b0f400fe98177fe7ee13cf7"), true)
+	test.IsEqualBool(t, isValidDataEntry("data/"), false)
+	test.IsEqualBool(t, isValidDataEntry("data/.."), false)
+	test.IsEqualBool(t, isValidDataEntry("data/../config.json"), false)
+	test.IsEqualBool(t, isValidDataEntry("data/sub/file"), false)
+	test.IsEqualBool(t, isValidDataEntry("config.json"), false)
+}
+
+func TestGetLocalBlobs(t *testing.T) {
+	blobs := getLocalBlobs(models.DatabaseSnapshot{
+		Files: []models.File{{Id: "file1", SHA1: "hash1"}, {Id: "file2", SHA1: "hash1"}, {Id: "cloud", SHA1: "hash2", AwsBucket: "bucket"}},
+		FileVersions: []models.FileVersion{{Id: "version1", FileId: "file1", SHA1: "hash3"},
+			{Id: "version2", FileId: "file1", SHA1: "hash1"}, {Id: "version3", FileId: "cloud", SHA1: "hash4", AwsBucket: "bucket"}},
+	})
+	test.IsEqualInt(t, len(blobs), 2)
+	test.IsEqualString(t, blobs[0], "hash1")
+	test.IsEqualString(t, blobs[1], "hash3")
+}
+
+// rewriteArchive copies an archive and passes the content of every entry to modify. Entries are skipped
+// if modify returns nil. All entries of additional are appended to the new archive
+func rewriteArchive(t *testing.T, source, destination string, modify func(name string, content []byte) []byte, additional map[string][]byte) {
+	t.Helper()
+	input, err := os.Open(source)
+	test.IsNil(t, err)
+	defer input.Close()
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	reader := tar.NewReader(input)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		test.IsNil(t, err)
+		content, err := io.ReadAll(reader)
+		test.IsNil(t, err)
+		content = modify(header.Name, content)
+		if content == nil {
+			continue
+		}
+		header.Size = int64(len(content))
+		test.IsNil(t, writer.WriteHeader(header))
+		_, err = writer.Write(content)
+		test.IsNil(t, err)
+	}
+	for name, content := range additional {
+		test.IsNil(t, writer.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: int64(len(content)), Mode: 0600}))
+		_, err = writer.Write(content)
+		test.IsNil(t, err)
+	}
+	test.IsNil(t, writer.Close())
+	test.IsNil(t, os.WriteFile(destination, buffer.Bytes(), 0600))
+}
@@ -0,0 +1,308 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/configupgrade"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/database/dbabstraction"
+	"github.com/forceu/gokapi/internal/environment"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// ErrorConfigExists is returned if a backup is restored, but a configuration already exists
+var ErrorConfigExists = errors.New("a configuration already exists, pass --force to replace it")
+
+// ErrorDatabaseNotEmpty is returned if a backup is restored into a database that already contains users or files
+var ErrorDatabaseNotEmpty = errors.New("the database already contains users or files, a backup can only be restored into an empty database")
+
+// Restore verifies the archive at the given path and restores it into an empty instance. If restoreData is false,
+// the files of the data directory are not restored, even if they are included in the archive. An existing
+// configuration is only replaced if overwriteConfig is true. Leaves the database connected on success
+func Restore(path string, restoreData, overwriteConfig bool) (models.BackupManifest, error) {
+	content, err := verifyArchive(path)
+	if err != nil {
+		return models.BackupManifest{}, err
+	}
+	manifest := content.Manifest
+	if configuration.Exists() && !overwriteConfig {
+		return models.BackupManifest{}, ErrorConfigExists
+	}
+
+	var config models.Configuration
+	err = json.Unmarshal(content.Config, &config)
+	if err != nil {
+		return models.BackupManifest{}, fmt.Errorf("invalid configuration in backup: %w", err)
+	}
+	var snapshot models.DatabaseSnapshot
+	err = json.Unmarshal(content.Database, &snapshot)
+	if err != nil {
+		return models.BackupManifest{}, fmt.Errorf("invalid database content in backup: %w", err)
+	}
+	err = connectEmptyDatabase(config.DatabaseUrl, manifest.SchemaVersion)
+	if err != nil {
+		return models.BackupManifest{}, err
+	}
+
+	err = writeConfigFiles(content)
+	if err != nil {
+		database.Close()
+		return models.BackupManifest{}, err
+	}
+	configuration.Load()
+
+	// The data is extracted before the database is imported, so that the restore can be
+	// repeated with the still empty database if extracting fails
+	if restoreData && manifest.IncludesData {
+		err = extractData(path, manifest, configuration.Get().DataDir)
+		if err != nil {
+			database.Close()
+			return models.BackupManifest{}, err
+		}
+	}
+	database.Import(snapshot)
+	return manifest, nil
+}
+
+// archiveContent contains the entries of an archive that are kept in memory while restoring
+type archiveContent struct {
+	Manifest    models.BackupManifest
+	Config      []byte
+	CloudConfig []byte
+	Database    []byte
+}
+
+// verifyArchive reads the complete archive and returns an error if an entry does not match the manifest
+// or if the archive was created by a newer version of Gokapi
+func verifyArchive(path string) (archiveContent, error) {
+	var result archiveContent
+	file, err := os.Open(path)
+	if err != nil {
+		return archiveContent{}, err
+	}
+	defer file.Close()
+
+	foundEntries := make(map[string]models.BackupEntry)
+	var manifestContent []byte
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return archiveContent{}, fmt.Errorf("invalid archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return archiveContent{}, fmt.Errorf("invalid archive: unexpected entry %s", header.Name)
+		}
+		_, exists := foundEntries[header.Name]
+		if exists {
+			return archiveContent{}, fmt.Errorf("invalid archive: duplicate entry %s", header.Name)
+		}
+		var inMemory *[]byte
+		switch header.Name {
+		case entryManifest:
+			inMemory = &manifestContent
+		case entryConfig:
+			inMemory = &result.Config
+		case entryCloudConfig:
+			inMemory = &result.CloudConfig
+		case entryDatabase:
+			inMemory = &result.Database
+		default:
+			if !isValidDataEntry(header.Name) {
+				return archiveContent{}, fmt.Errorf("invalid archive: unexpected entry %s", header.Name)
+			}
+		}
+		entry, err := hashEntry(reader, header.Name, inMemory)
+		if err != nil {
+			return archiveContent{}, err
+		}
+		foundEntries[header.Name] = entry
+	}
+
+	if manifestContent == nil {
+		return archiveContent{}, errors.New("invalid archive: manifest is missing")
+	}
+	err = json.Unmarshal(manifestContent, &result.Manifest)
+	if err != nil {
+		return archiveContent{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	err = checkVersions(result.Manifest)
+	if err != nil {
+		return archiveContent{}, err
+	}
+	delete(foundEntries, entryManifest)
+	for _, entry := range result.Manifest.Entries {
+		found, ok := foundEntries[entry.Name]
+		if !ok {
+			return archiveContent{}, fmt.Errorf("invalid archive: %s is missing", entry.Name)
+		}
+		if found.Size != entry.Size || found.Sha256 != entry.Sha256 {
+			return archiveContent{}, fmt.Errorf("invalid archive: checksum of %s does not match", entry.Name)
+		}
+		delete(foundEntries, entry.Name)
+	}
+	for name := range foundEntries {
+		return archiveContent{}, fmt.Errorf("invalid archive: %s is not part of the manifest", name)
+	}
+	if result.Config == nil || result.Database == nil {
+		return archiveContent{}, errors.New("invalid archive: configuration or database is missing")
+	}
+	return result, nil
+}
+
+func checkVersions(manifest models.BackupManifest) error {
+	if manifest.FormatVersion != formatVersion {
+		return fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+	if manifest.ConfigVersion > configupgrade.CurrentConfigVersion {
+		return fmt.Errorf("the backup was created with configuration version %d, but this version of Gokapi only supports up to version %d",
+			manifest.ConfigVersion, configupgrade.CurrentConfigVersion)
+	}
+	return nil
+}
+
+// hashEntry reads the current entry of the archive and returns its size and checksum.
+// If inMemory is not nil, the content is stored in it as well
+func hashEntry(reader io.Reader, name string, inMemory *[]byte) (models.BackupEntry, error) {
+	hasher := sha256.New()
+	var output io.Writer = hasher
+	var buffer bytes.Buffer
+	if inMemory != nil {
+		output = io.MultiWriter(hasher, &buffer)
+	}
+	size, err := io.Copy(output, reader)
+	if err != nil {
+		return models.BackupEntry{}, fmt.Errorf("invalid archive: %w", err)
+	}
+	if inMemory != nil {
+		*inMemory = buffer.Bytes()
+	}
+	return models.BackupEntry{
+		Name:   name,
+		Size:   size,
+		Sha256: hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+// isValidDataEntry returns true if the name is a file directly inside the data directory of the archive
+func isValidDataEntry(name string) bool {
+	if !strings.HasPrefix(name, prefixData) {
+		return false
+	}
+	fileName := strings.TrimPrefix(name, prefixData)
+	return fileName != "" && fileName != "." && fileName != ".." && !strings.ContainsAny(fileName, `/\`)
+}
+
+// connectEmptyDatabase connects to the database with the given URL and returns an error if it is not empty
+// or if the backup was created with a newer database schema.
+// The schema version can only be checked after connecting, as it differs between database types
+func connectEmptyDatabase(dbUrl string, schemaVersion int) error {
+	dbConfig, err := database.ParseUrl(dbUrl, false)
+	if err != nil {
+		return err
+	}
+	if dbConfig.Type == dbabstraction.TypeSqlite {
+		helper.CreateDir(filepath.Dir(dbConfig.HostUrl))
+	}
+	database.Connect(dbConfig)
+	if schemaVersion > database.GetSchemaVersion() {
+		database.Close()
+		return fmt.Errorf("the backup was created with database schema version %d, but this version of Gokapi only supports up to version %d",
+			schemaVersion, database.GetSchemaVersion())
+	}
+	database.Upgrade()
+	if !database.IsEmpty() {
+		database.Close()
+		return ErrorDatabaseNotEmpty
+	}
+	return nil
+}
+
+func writeConfigFiles(content archiveContent) error {
+	configPath, configDir, _, cloudConfigPath := environment.GetConfigPaths()
+	helper.CreateDir(configDir)
+	err := os.WriteFile(configPath, content.Config, 0600)
+	if err != nil {
+		return err
+	}
+	if content.CloudConfig == nil {
+		// An existing cloud configuration would otherwise be used with the restored configuration
+		err = os.Remove(cloudConfigPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(cloudConfigPath, content.CloudConfig, 0600)
+}
+
+// extractData writes all files of the data directory that are included in the archive to dataDir
+func extractData(path string, manifest models.BackupManifest, dataDir string) error {
+	expected := make(map[string]models.BackupEntry)
+	for _, entry := range manifest.Entries {
+		expected[entry.Name] = entry
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !isValidDataEntry(header.Name) {
+			continue
+		}
+		err = extractFile(reader, expected[header.Name], filepath.Join(dataDir, strings.TrimPrefix(header.Name, prefixData)))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractFile writes the current entry of the archive to a temporary file and moves it to the destination,
+// if the checksum is still correct
+func extractFile(reader io.Reader, entry models.BackupEntry, destination string) error {
+	tempPath := destination + ".restore"
+	output, err := os.OpenFile(tempPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(output, hasher), reader)
+	closeErr := output.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && hex.EncodeToString(hasher.Sum(nil)) != entry.Sha256 {
+		err = fmt.Errorf("checksum of %s does not match", entry.Name)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, destination)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+	}
+	return err
+}
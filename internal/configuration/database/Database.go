@@ -51,6 +51,10 @@ func ParseUrl(dbUrl string, mustExist bool) (models.DbConnection, error) {
 	case "redis":
 		result.Type = dbabstraction.TypeRedis
 		result.HostUrl = u.Host
+	case "postgres", "postgresql":
+		// The PostgreSQL driver parses the URL itself, therefore the complete URL is passed on
+		result.Type = dbabstraction.TypePostgres
+		result.HostUrl = dbUrl
 	default:
 		return models.DbConnection{}, fmt.Errorf("unsupported database type: %s\n", dbUrl)
 	}
@@ -72,28 +76,122 @@ func Migrate(configOld, configNew models.DbConnection) {
 	dbNew, err := dbabstraction.GetNew(configNew)
 	helper.Check(err)
 
-	apiKeys := dbOld.GetAllApiKeys()
-	for _, apiKey := range apiKeys {
-		dbNew.SaveApiKey(apiKey)
+	importSnapshot(dbNew, exportSnapshot(dbOld))
+	dbOld.Close()
+	dbNew.Close()
+}
+
+// Export returns the content of the database, except for temporary data like sessions
+func Export() models.DatabaseSnapshot {
+	return exportSnapshot(db)
+}
+
+// Import writes the content of the snapshot to the database. Existing entries with the same ID are overwritten
+func Import(snapshot models.DatabaseSnapshot) {
+	importSnapshot(db, snapshot)
+}
+
+// IsEmpty returns true if the database does not contain any users or files
+func IsEmpty() bool {
+	return len(db.GetAllUsers()) == 0 && len(db.GetAllMetadata()) == 0
+}
+
+func exportSnapshot(source dbabstraction.Database) models.DatabaseSnapshot {
+	result := models.DatabaseSnapshot{
+		ApiKeys:      make([]models.ApiKey, 0),
+		Users:        make([]models.BackupUser, 0),
+		Files:        make([]models.File, 0),
+		FileRequests: source.GetAllFileRequests(),
+		Webhooks:     source.GetAllWebhooks(),
+		Bundles:      source.GetAllBundles(),
+		ShareLinks:   source.GetAllShareLinks(),
+		FileVersions: source.GetAllFileVersions(),
+		AuditEvents:  make([]models.AuditEvent, 0),
+		StatTraffic:  source.GetStatTraffic(),
 	}
-	users := dbOld.GetAllUsers()
-	for _, user := range users {
-		dbNew.SaveUser(user, false)
-		dbNew.SaveEnd2EndInfo(dbOld.GetEnd2EndInfo(user.Id), user.Id)
+	for _, apiKey := range source.GetAllApiKeys() {
+		result.ApiKeys = append(result.ApiKeys, apiKey)
+	}
+	for _, user := range source.GetAllUsers() {
+		backupUser := models.BackupUser{
+			User:         user,
+			PasswordHash: user.Password,
+			E2EInfo:      source.GetEnd2EndInfo(user.Id),
+		}
+		userTotp, ok := source.GetUserTotp(user.Id)
+		if ok {
+			backupUser.Totp = &userTotp
+		}
+		userQuota, ok := source.GetUserQuota(user.Id)
+		if ok {
+			backupUser.Quota = &userQuota
+		}
+		result.Users = append(result.Users, backupUser)
+	}
+	for _, file := range source.GetAllMetadata() {
+		result.Files = append(result.Files, file)
+	}
+	// Events are returned latest first, but have to be saved in chronological order to keep their order
+	auditEvents := source.QueryAuditEvents(models.AuditQuery{}).Events
+	for i := len(auditEvents) - 1; i >= 0; i-- {
+		result.AuditEvents = append(result.AuditEvents, auditEvents[i])
+	}
+	trafficSince, ok := source.GetTrafficSince()
+	if ok {
+		result.TrafficSince = trafficSince
+	}
+	return result
+}
+
+func importSnapshot(destination dbabstraction.Database, snapshot models.DatabaseSnapshot) {
+	for _, apiKey := range snapshot.ApiKeys {
+		destination.SaveApiKey(apiKey)
 	}
-	files := dbOld.GetAllMetadata()
-	for _, file := range files {
-		dbNew.SaveMetaData(file)
+	for _, backupUser := range snapshot.Users {
+		user := backupUser.User
+		user.Password = backupUser.PasswordHash
+		destination.SaveUser(user, false)
+		destination.SaveEnd2EndInfo(backupUser.E2EInfo, user.Id)
+		if backupUser.Totp != nil {
+			destination.SaveUserTotp(*backupUser.Totp)
+		}
+		if backupUser.Quota != nil {
+			destination.SaveUserQuota(*backupUser.Quota)
+		}
+	}
+	for _, file := range snapshot.Files {
+		destination.SaveMetaData(file)
 		if file.HotlinkId != "" {
-			dbNew.SaveHotlink(file)
+			destination.SaveHotlink(file)
 		}
 	}
-	requests := dbOld.GetAllFileRequests()
-	for _, request := range requests {
-		dbNew.SaveFileRequest(request)
+	for _, request := range snapshot.FileRequests {
+		destination.SaveFileRequest(request)
+	}
+	for _, webhook := range snapshot.Webhooks {
+		destination.SaveWebhook(webhook)
+	}
+	for _, bundle := range snapshot.Bundles {
+		destination.SaveBundle(bundle)
+	}
+	for _, link := range snapshot.ShareLinks {
+		destination.SaveShareLink(link)
+	}
+	for _, version := range snapshot.FileVersions {
+		destination.SaveFileVersion(version)
+	}
+	for _, event := range snapshot.AuditEvents {
+		destination.SaveAuditEvent(event)
+	}
+	destination.SaveStatTraffic(snapshot.StatTraffic)
+	if snapshot.TrafficSince != 0 {
+		destination.SaveTrafficSince(snapshot.TrafficSince)
 	}
-	dbOld.Close()
-	dbNew.Close()
+}
+
+// GetSchemaVersion returns the version number of the database schema that is used by this version of Gokapi
+func GetSchemaVersion() int {
+	return db.GetSchemaVersion()
 }
 
 // RunGarbageCollection runs the databases GC
@@ -211,6 +309,11 @@ func GetMetaDataById(id string) (models.File, bool) {
 	return db.GetMetaDataById(id)
 }
 
+// QueryMetaData returns all files matching the query, sorted and paginated as requested
+func QueryMetaData(query models.FileQuery) models.FileQueryResult {
+	return db.QueryMetaData(query)
+}
+
 // SaveMetaData stores the metadata of a file to the disk
 func SaveMetaData(file models.File) {
 	db.SaveMetaData(file)
@@ -353,6 +456,191 @@ func DeleteFileRequest(request models.FileRequest) {
 	db.DeleteFileRequest(request)
 }
 
+// Webhook Section
+
+// GetWebhook returns the webhook or false if not found
+func GetWebhook(id string) (models.Webhook, bool) {
+	return db.GetWebhook(id)
+}
+
+// GetAllWebhooks returns an array with all webhooks, ordered by creation date
+func GetAllWebhooks() []models.Webhook {
+	return db.GetAllWebhooks()
+}
+
+// SaveWebhook stores the webhook in the database
+func SaveWebhook(webhook models.Webhook) {
+	db.SaveWebhook(webhook)
+}
+
+// DeleteWebhook deletes the webhook with the given ID and all queued deliveries for it
+func DeleteWebhook(id string) {
+	db.DeleteWebhook(id)
+}
+
+// GetDueWebhookDeliveries returns up to limit queued deliveries, that are due at the given timestamp
+func GetDueWebhookDeliveries(timestamp int64, limit int) []models.WebhookDelivery {
+	return db.GetDueWebhookDeliveries(timestamp, limit)
+}
+
+// SaveWebhookDelivery adds the delivery to the queue or updates it
+func SaveWebhookDelivery(delivery models.WebhookDelivery) {
+	db.SaveWebhookDelivery(delivery)
+}
+
+// DeleteWebhookDelivery removes the delivery with the given ID from the queue
+func DeleteWebhookDelivery(id string) {
+	db.DeleteWebhookDelivery(id)
+}
+
+// Bundle Section
+
+// GetBundle returns the bundle or false if not found
+func GetBundle(id string) (models.Bundle, bool) {
+	return db.GetBundle(id)
+}
+
+// GetAllBundles returns an array with all bundles, ordered by creation date
+func GetAllBundles() []models.Bundle {
+	return db.GetAllBundles()
+}
+
+// SaveBundle stores the bundle in the database
+func SaveBundle(bundle models.Bundle) {
+	db.SaveBundle(bundle)
+}
+
+// IncreaseBundleDownloadCount increases the download count of a bundle, preventing race conditions
+func IncreaseBundleDownloadCount(id string, decreaseRemainingDownloads bool) {
+	db.IncreaseBundleDownloadCount(id, decreaseRemainingDownloads)
+}
+
+// DeleteBundle deletes the bundle with the given ID
+func DeleteBundle(id string) {
+	db.DeleteBundle(id)
+}
+
+// Share Link Section
+
+// GetShareLink returns the share link or false if not found
+func GetShareLink(id string) (models.ShareLink, bool) {
+	return db.GetShareLink(id)
+}
+
+// GetAllShareLinks returns an array with all share links, ordered by creation date
+func GetAllShareLinks() []models.ShareLink {
+	return db.GetAllShareLinks()
+}
+
+// SaveShareLink stores the share link in the database
+func SaveShareLink(link models.ShareLink) {
+	db.SaveShareLink(link)
+}
+
+// IncreaseShareLinkDownloadCount increases the download count of a share link, preventing race conditions
+func IncreaseShareLinkDownloadCount(id string, decreaseRemainingDownloads bool) {
+	db.IncreaseShareLinkDownloadCount(id, decreaseRemainingDownloads)
+}
+
+// DeleteShareLink deletes the share link with the given ID
+func DeleteShareLink(id string) {
+	db.DeleteShareLink(id)
+}
+
+// GetFileVersion returns the file version or false if not found
+func GetFileVersion(id string) (models.FileVersion, bool) {
+	return db.GetFileVersion(id)
+}
+
+// GetFileVersions returns all versions of the given file, latest first
+func GetFileVersions(fileId string) []models.FileVersion {
+	return db.GetFileVersions(fileId)
+}
+
+// GetAllFileVersions returns an array with all file versions, ordered by replacement date
+func GetAllFileVersions() []models.FileVersion {
+	return db.GetAllFileVersions()
+}
+
+// SaveFileVersion stores the file version in the database
+func SaveFileVersion(version models.FileVersion) {
+	db.SaveFileVersion(version)
+}
+
+// DeleteFileVersion deletes the file version with the given ID
+func DeleteFileVersion(id string) {
+	db.DeleteFileVersion(id)
+}
+
+// Two-Factor Authentication Section
+
+// GetUserTotp returns the TOTP settings of a user or false if not found
+func GetUserTotp(userId int) (models.UserTotp, bool) {
+	return db.GetUserTotp(userId)
+}
+
+// SaveUserTotp stores the TOTP settings of a user
+func SaveUserTotp(totp models.UserTotp) {
+	db.SaveUserTotp(totp)
+}
+
+// DeleteUserTotp deletes the TOTP settings of a user
+func DeleteUserTotp(userId int) {
+	db.DeleteUserTotp(userId)
+}
+
+// Quota Section
+
+// GetUserQuota returns the quota of a user or false if no quota has been set
+func GetUserQuota(userId int) (models.UserQuota, bool) {
+	return db.GetUserQuota(userId)
+}
+
+// SaveUserQuota stores the quota of a user
+func SaveUserQuota(quota models.UserQuota) {
+	db.SaveUserQuota(quota)
+}
+
+// DeleteUserQuota deletes the quota of a user
+func DeleteUserQuota(userId int) {
+	db.DeleteUserQuota(userId)
+}
+
+// Lease Section
+
+// TryAcquireLease acquires the lease or renews it, if it is already held by the same owner.
+// Returns true, if the lease is held by the owner afterwards
+func TryAcquireLease(lease models.Lease) bool {
+	return db.TryAcquireLease(lease)
+}
+
+// GetLease returns the lease with the given name or false if it does not exist
+func GetLease(name string) (models.Lease, bool) {
+	return db.GetLease(name)
+}
+
+// ReleaseLease deletes the lease with the given name, if it is held by the owner
+func ReleaseLease(name, owner string) {
+	db.ReleaseLease(name, owner)
+}
+
+// Audit Log Section
+
+// SaveAuditEvent stores a new audit event. The ID of the event is assigned by the database
+func SaveAuditEvent(event models.AuditEvent) {
+	db.SaveAuditEvent(event)
+}
+
+// QueryAuditEvents returns all audit events matching the given query, latest first
+func QueryAuditEvents(query models.AuditQuery) models.AuditQueryResult {
+	return db.QueryAuditEvents(query)
+}
+
+// DeleteAuditEventsBefore deletes all audit events that were created before the given timestamp
+func DeleteAuditEventsBefore(timestamp int64) {
+	db.DeleteAuditEventsBefore(timestamp)
+}
+
 // Statistics
 
 // GetStatTraffic returns the total traffic from statistics
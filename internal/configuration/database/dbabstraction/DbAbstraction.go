@@ -3,6 +3,7 @@ package dbabstraction
 import (
 	"fmt"
 
+	"github.com/forceu/gokapi/internal/configuration/database/provider/postgres"
 	"github.com/forceu/gokapi/internal/configuration/database/provider/redis"
 	"github.com/forceu/gokapi/internal/configuration/database/provider/sqlite"
 	"github.com/forceu/gokapi/internal/models"
@@ -13,6 +14,8 @@ const (
 	TypeSqlite = iota
 	// TypeRedis specifies to use a Redis database
 	TypeRedis
+	// TypePostgres specifies to use a PostgreSQL database
+	TypePostgres
 )
 
 // Database declares the required functions for a database connection
@@ -67,6 +70,8 @@ type Database interface {
 	GetAllMetadata() map[string]models.File
 	// GetMetaDataById returns a models.File from the ID passed or false if the id is not valid
 	GetMetaDataById(id string) (models.File, bool)
+	// QueryMetaData returns all files matching the query, sorted and paginated as requested
+	QueryMetaData(query models.FileQuery) models.FileQueryResult
 	// SaveMetaData stores the metadata of a file to the disk
 	SaveMetaData(file models.File)
 	// DeleteMetaData deletes information about a file
@@ -107,6 +112,83 @@ type Database interface {
 	// DeleteFileRequest deletes a file request with the given ID
 	DeleteFileRequest(request models.FileRequest)
 
+	// GetWebhook returns the webhook or false if not found
+	GetWebhook(id string) (models.Webhook, bool)
+	// GetAllWebhooks returns an array with all webhooks, ordered by creation date
+	GetAllWebhooks() []models.Webhook
+	// SaveWebhook stores the webhook in the database
+	SaveWebhook(webhook models.Webhook)
+	// DeleteWebhook deletes the webhook with the given ID and all queued deliveries for it
+	DeleteWebhook(id string)
+	// GetDueWebhookDeliveries returns up to limit queued deliveries, that are due at the given timestamp
+	GetDueWebhookDeliveries(timestamp int64, limit int) []models.WebhookDelivery
+	// SaveWebhookDelivery adds the delivery to the queue or updates it
+	SaveWebhookDelivery(delivery models.WebhookDelivery)
+	// DeleteWebhookDelivery removes the delivery with the given ID from the queue
+	DeleteWebhookDelivery(id string)
+
+	// GetBundle returns the bundle or false if not found
+	GetBundle(id string) (models.Bundle, bool)
+	// GetAllBundles returns an array with all bundles, ordered by creation date
+	GetAllBundles() []models.Bundle
+	// SaveBundle stores the bundle in the database
+	SaveBundle(bundle models.Bundle)
+	// IncreaseBundleDownloadCount increases the download count of a bundle, preventing race conditions
+	IncreaseBundleDownloadCount(id string, decreaseRemainingDownloads bool)
+	// DeleteBundle deletes the bundle with the given ID
+	DeleteBundle(id string)
+
+	// GetShareLink returns the share link or false if not found
+	GetShareLink(id string) (models.ShareLink, bool)
+	// GetAllShareLinks returns an array with all share links, ordered by creation date
+	GetAllShareLinks() []models.ShareLink
+	// SaveShareLink stores the share link in the database
+	SaveShareLink(link models.ShareLink)
+	// IncreaseShareLinkDownloadCount increases the download count of a share link, preventing race conditions
+	IncreaseShareLinkDownloadCount(id string, decreaseRemainingDownloads bool)
+	// DeleteShareLink deletes the share link with the given ID
+	DeleteShareLink(id string)
+
+	// GetFileVersion returns the file version or false if not found
+	GetFileVersion(id string) (models.FileVersion, bool)
+	// GetFileVersions returns all versions of the given file, latest first
+	GetFileVersions(fileId string) []models.FileVersion
+	// GetAllFileVersions returns an array with all file versions, ordered by replacement date
+	GetAllFileVersions() []models.FileVersion
+	// SaveFileVersion stores the file version in the database
+	SaveFileVersion(version models.FileVersion)
+	// DeleteFileVersion deletes the file version with the given ID
+	DeleteFileVersion(id string)
+
+	// GetUserTotp returns the TOTP settings of a user or false if not found
+	GetUserTotp(userId int) (models.UserTotp, bool)
+	// SaveUserTotp stores the TOTP settings of a user
+	SaveUserTotp(totp models.UserTotp)
+	// DeleteUserTotp deletes the TOTP settings of a user
+	DeleteUserTotp(userId int)
+
+	// GetUserQuota returns the quota of a user or false if no quota has been set
+	GetUserQuota(userId int) (models.UserQuota, bool)
+	// SaveUserQuota stores the quota of a user
+	SaveUserQuota(quota models.UserQuota)
+	// DeleteUserQuota deletes the quota of a user
+	DeleteUserQuota(userId int)
+
+	// TryAcquireLease acquires the lease or renews it, if it is already held by the same owner.
+	// Returns true, if the lease is held by the owner afterwards
+	TryAcquireLease(lease models.Lease) bool
+	// GetLease returns the lease with the given name or false if it does not exist
+	GetLease(name string) (models.Lease, bool)
+	// ReleaseLease deletes the lease with the given name, if it is held by the owner
+	ReleaseLease(name, owner string)
+
+	// SaveAuditEvent stores a new audit event. The ID of the event is assigned by the database
+	SaveAuditEvent(event models.AuditEvent)
+	// QueryAuditEvents returns all audit events matching the given query, latest first
+	QueryAuditEvents(query models.AuditQuery) models.AuditQueryResult
+	// DeleteAuditEventsBefore deletes all audit events that were created before the given timestamp
+	DeleteAuditEventsBefore(timestamp int64)
+
 	// GetStatTraffic returns the total traffic from statistics
 	GetStatTraffic() uint64
 	// SaveStatTraffic stores the total traffic
@@ -124,6 +206,8 @@ func GetNew(config models.DbConnection) (Database, error) {
 		return sqlite.New(config)
 	case TypeRedis:
 		return redis.New(config)
+	case TypePostgres:
+		return postgres.New(config)
 	default:
 		return nil, fmt.Errorf("unsupported database: type %v", config.Type)
 	}
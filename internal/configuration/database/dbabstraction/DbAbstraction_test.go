@@ -14,6 +14,10 @@ var configRedis = models.DbConnection{
 	Type: 1, // dbabstraction.TypeRedis
 }
 
+var configPostgres = models.DbConnection{
+	Type: 2, // dbabstraction.TypePostgres
+}
+
 func TestGetNew(t *testing.T) {
 	result, err := GetNew(configSqlite)
 	test.IsNotNil(t, err)
@@ -21,7 +25,10 @@ func TestGetNew(t *testing.T) {
 	result, err = GetNew(configRedis)
 	test.IsNotNil(t, err)
 	test.IsEqualInt(t, result.GetType(), 1)
+	result, err = GetNew(configPostgres)
+	test.IsNotNil(t, err)
+	test.IsEqualInt(t, result.GetType(), 2)
 
-	_, err = GetNew(models.DbConnection{Type: 2})
+	_, err = GetNew(models.DbConnection{Type: 3})
 	test.IsNotNil(t, err)
 }
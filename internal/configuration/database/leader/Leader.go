@@ -0,0 +1,119 @@
+package leader
+
+/**
+Lease-based leader election between multiple instances that share a database
+*/
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// LeaseCleanup is the name of the lease that is required to run the periodic cleanup
+const LeaseCleanup = "cleanup"
+
+// leaseDuration is the time after which the lease can be acquired by another instance,
+// if it has not been renewed, e.g. because the instance holding it was terminated
+const leaseDuration = 3 * time.Minute
+
+// renewInterval is the interval in which the lease is renewed or acquired
+const renewInterval = time.Minute
+
+var instanceId string
+var isStarted atomic.Bool
+var heldUntil atomic.Int64
+var stopChannel chan bool
+var mutex sync.Mutex
+
+// Init tries to acquire the lease and keeps renewing it in the background. If another instance
+// holds the lease, it is acquired as soon as the other instance stops renewing it
+func Init() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if isStarted.Load() {
+		return
+	}
+	instanceId = newInstanceId()
+	stopChannel = make(chan bool)
+	renew()
+	isStarted.Store(true)
+	go renewLoop(stopChannel)
+}
+
+// Shutdown stops renewing the lease and releases it, so that another instance can take over immediately
+func Shutdown() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !isStarted.Load() {
+		return
+	}
+	close(stopChannel)
+	isStarted.Store(false)
+	heldUntil.Store(0)
+	database.ReleaseLease(LeaseCleanup, instanceId)
+}
+
+// IsLeader returns true, if this instance holds the lease and should run the periodic cleanup.
+// If Init has not been called, the instance is the only one and always the leader
+func IsLeader() bool {
+	if !isStarted.Load() {
+		return true
+	}
+	return heldUntil.Load() > time.Now().Unix()
+}
+
+// GetStatus returns the ID of this instance and the instance currently holding the lease
+func GetStatus() models.LeaderStatus {
+	result := models.LeaderStatus{
+		InstanceId: instanceId,
+		IsLeader:   IsLeader(),
+	}
+	lease, ok := database.GetLease(LeaseCleanup)
+	if ok && !lease.IsExpired(time.Now().Unix()) {
+		result.Leader = lease.Owner
+		result.LeaseExpiry = lease.ExpiresAt
+	}
+	return result
+}
+
+func newInstanceId() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "gokapi"
+	}
+	return hostname + "-" + helper.GenerateRandomString(8)
+}
+
+// renew acquires or renews the lease. The lease is only considered to be held until shortly
+// before it expires, to prevent two instances from running the cleanup at the same time
+func renew() {
+	expiry := time.Now().Add(leaseDuration).Unix()
+	if database.TryAcquireLease(models.Lease{Name: LeaseCleanup, Owner: instanceId, ExpiresAt: expiry}) {
+		heldUntil.Store(expiry - int64(renewInterval.Seconds()))
+		return
+	}
+	heldUntil.Store(0)
+}
+
+func renewLoop(stop chan bool) {
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			mutex.Lock()
+			if isStarted.Load() {
+				renew()
+			}
+			mutex.Unlock()
+		}
+	}
+}
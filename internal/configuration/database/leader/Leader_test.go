@@ -0,0 +1,73 @@
+package leader
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+)
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	// The configuration package cannot be used, as it indirectly imports this package
+	dbConfig, err := database.ParseUrl(testconfiguration.SqliteUrl, false)
+	if err != nil {
+		panic(err)
+	}
+	database.Connect(dbConfig)
+	database.Upgrade()
+	exitVal := m.Run()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+func TestNotStarted(t *testing.T) {
+	test.IsEqualBool(t, IsLeader(), true)
+	status := GetStatus()
+	test.IsEqualBool(t, status.IsLeader, true)
+	test.IsEmpty(t, status.Leader)
+	Shutdown()
+}
+
+func TestInitAndShutdown(t *testing.T) {
+	Init()
+	test.IsEqualBool(t, IsLeader(), true)
+	firstId := instanceId
+	Init()
+	test.IsEqualString(t, instanceId, firstId)
+	status := GetStatus()
+	test.IsEqualString(t, status.InstanceId, firstId)
+	test.IsEqualString(t, status.Leader, firstId)
+	test.IsEqualBool(t, status.IsLeader, true)
+	test.IsEqualBool(t, status.LeaseExpiry > time.Now().Unix(), true)
+
+	Shutdown()
+	test.IsEqualBool(t, IsLeader(), true)
+	_, ok := database.GetLease(LeaseCleanup)
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestOtherInstanceIsLeader(t *testing.T) {
+	otherLease := models.Lease{Name: LeaseCleanup, Owner: "otherInstance", ExpiresAt: time.Now().Add(time.Minute).Unix()}
+	test.IsEqualBool(t, database.TryAcquireLease(otherLease), true)
+	Init()
+	test.IsEqualBool(t, IsLeader(), false)
+	status := GetStatus()
+	test.IsEqualBool(t, status.IsLeader, false)
+	test.IsEqualString(t, status.Leader, "otherInstance")
+
+	// The lease is taken over once the other instance stops renewing it
+	otherLease.ExpiresAt = time.Now().Add(-time.Second).Unix()
+	database.ReleaseLease(LeaseCleanup, "otherInstance")
+	test.IsEqualBool(t, database.TryAcquireLease(otherLease), true)
+	mutex.Lock()
+	renew()
+	mutex.Unlock()
+	test.IsEqualBool(t, IsLeader(), true)
+	test.IsEqualString(t, GetStatus().Leader, instanceId)
+	Shutdown()
+}
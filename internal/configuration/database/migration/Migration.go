@@ -32,6 +32,8 @@ func getType(input int) string {
 		return "SQLite"
 	case dbabstraction.TypeRedis:
 		return "Redis"
+	case dbabstraction.TypePostgres:
+		return "PostgreSQL"
 	}
 	return "Invalid"
 }
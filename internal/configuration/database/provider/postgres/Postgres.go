@@ -0,0 +1,380 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	// Required for the postgres driver
+	_ "github.com/lib/pq"
+)
+
+// DatabaseProvider contains the database instance
+type DatabaseProvider struct {
+	postgresDb *sql.DB
+}
+
+// DatabaseSchemeVersion contains the version number to be expected from the current database. If lower, an upgrade will be performed
+const DatabaseSchemeVersion = 5
+
+// New returns an instance
+func New(dbConfig models.DbConnection) (DatabaseProvider, error) {
+	return DatabaseProvider{}.init(dbConfig)
+}
+
+// GetType returns 2, for being a Postgres interface
+func (p DatabaseProvider) GetType() int {
+	return 2 // dbabstraction.Postgres
+}
+
+// Upgrade migrates the DB to a new Gokapi version, if required
+func (p DatabaseProvider) Upgrade(currentDbVersion int) {
+	// The PostgreSQL provider was introduced with schema version 1
+	if currentDbVersion < 1 {
+		fmt.Println("Error: Invalid PostgreSQL database version. Unable to continue.")
+		osExit(1)
+		return
+	}
+	// < v2.3.0
+	if currentDbVersion < 2 {
+		_, err := p.postgresDb.Exec(`ALTER TABLE FileMetaData ADD COLUMN ProcessingState INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE FileMetaData ADD COLUMN QuarantineReason TEXT NOT NULL DEFAULT '';`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 3 {
+		_, err := p.postgresDb.Exec(`CREATE TABLE FileVersions (
+			Id	TEXT NOT NULL,
+			FileId	TEXT NOT NULL,
+			Revision	INTEGER NOT NULL,
+			Name	TEXT NOT NULL,
+			Size	TEXT NOT NULL,
+			SHA1	TEXT NOT NULL,
+			ContentType	TEXT NOT NULL,
+			AwsBucket	TEXT NOT NULL,
+			SizeBytes	BIGINT NOT NULL,
+			ReplacedAt	BIGINT NOT NULL,
+			ReplacedBy	INTEGER NOT NULL,
+			Encryption	BYTEA NOT NULL,
+			PRIMARY KEY (Id)
+		);
+		CREATE INDEX idx_FileVersions_FileId ON FileVersions (FileId);`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 4 {
+		_, err := p.postgresDb.Exec(`ALTER TABLE FileMetaData ADD COLUMN PublishAt BIGINT NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 5 {
+		_, err := p.postgresDb.Exec(`ALTER TABLE FileMetaData ADD COLUMN RequireLogin BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE FileMetaData ADD COLUMN AllowedUsers TEXT NOT NULL DEFAULT '';
+		ALTER TABLE FileMetaData ADD COLUMN AllowedGroups TEXT NOT NULL DEFAULT '';
+		ALTER TABLE UploadRequests ADD COLUMN RequireLogin BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE UploadRequests ADD COLUMN AllowedUsers TEXT NOT NULL DEFAULT '';
+		ALTER TABLE UploadRequests ADD COLUMN AllowedGroups TEXT NOT NULL DEFAULT '';
+		ALTER TABLE Sessions ADD COLUMN Groups TEXT NOT NULL DEFAULT '';
+		ALTER TABLE Sessions ADD COLUMN RecipientName TEXT NOT NULL DEFAULT '';`)
+		helper.Check(err)
+	}
+}
+
+// GetDbVersion gets the version number of the database
+func (p DatabaseProvider) GetDbVersion() int {
+	var version int
+	row := p.postgresDb.QueryRow("SELECT Version FROM SchemaVersion WHERE Id = 1")
+	err := row.Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0
+		}
+		helper.Check(err)
+		return 0
+	}
+	return version
+}
+
+// SetDbVersion sets the version number of the database
+func (p DatabaseProvider) SetDbVersion(newVersion int) {
+	_, err := p.postgresDb.Exec(`INSERT INTO SchemaVersion (Id, Version) VALUES (1, $1)
+					ON CONFLICT (Id) DO UPDATE SET Version = EXCLUDED.Version`, newVersion)
+	helper.Check(err)
+}
+
+// GetSchemaVersion returns the version number, which the database should be at if fully upgraded
+func (p DatabaseProvider) GetSchemaVersion() int {
+	return DatabaseSchemeVersion
+}
+
+// Init connects to the database and creates the table structure, if necessary
+func (p DatabaseProvider) init(dbConfig models.DbConnection) (DatabaseProvider, error) {
+	if dbConfig.HostUrl == "" {
+		return DatabaseProvider{}, errors.New("empty database url was provided")
+	}
+	if p.postgresDb == nil {
+		var err error
+		p.postgresDb, err = sql.Open("postgres", dbConfig.HostUrl)
+		if err != nil {
+			return DatabaseProvider{}, err
+		}
+		p.postgresDb.SetMaxOpenConns(10)
+		p.postgresDb.SetMaxIdleConns(5)
+		err = p.postgresDb.Ping()
+		if err != nil {
+			return DatabaseProvider{}, err
+		}
+		exists, err := p.tableExists("SchemaVersion")
+		if err != nil {
+			return DatabaseProvider{}, err
+		}
+		if !exists {
+			return p, p.createNewDatabase()
+		}
+	}
+	return p, nil
+}
+
+func (p DatabaseProvider) tableExists(name string) (bool, error) {
+	var exists bool
+	row := p.postgresDb.QueryRow("SELECT to_regclass(current_schema() || '.' || lower($1::text)) IS NOT NULL", name)
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+// Close the database connection
+func (p DatabaseProvider) Close() {
+	if p.postgresDb != nil {
+		err := p.postgresDb.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+	p.postgresDb = nil
+}
+
+// RunGarbageCollection runs the databases GC
+func (p DatabaseProvider) RunGarbageCollection() {
+	p.cleanExpiredSessions()
+	p.cleanApiKeys()
+}
+
+func (p DatabaseProvider) createNewDatabase() error {
+	sqlStmt := `CREATE TABLE ApiKeys (
+			Id	TEXT NOT NULL,
+			FriendlyName	TEXT NOT NULL,
+			LastUsed	BIGINT NOT NULL,
+			Permissions	INTEGER NOT NULL DEFAULT 0,
+			Expiry	BIGINT NOT NULL DEFAULT 0,
+			IsSystemKey	BOOLEAN NOT NULL DEFAULT FALSE,
+			UserId	INTEGER NOT NULL,
+			PublicId	TEXT NOT NULL UNIQUE,
+			UploadRequestId	TEXT NOT NULL,
+			PRIMARY KEY (Id)
+		);
+		CREATE TABLE E2EConfig (
+			UserId	INTEGER NOT NULL,
+			Config	BYTEA NOT NULL,
+			PRIMARY KEY (UserId)
+		);
+		CREATE TABLE FileMetaData (
+			Id	TEXT NOT NULL,
+			Name	TEXT NOT NULL,
+			Size	TEXT NOT NULL,
+			SHA1	TEXT NOT NULL,
+			ExpireAt	BIGINT NOT NULL,
+			SizeBytes	BIGINT NOT NULL,
+			DownloadsRemaining	INTEGER NOT NULL,
+			DownloadCount	INTEGER NOT NULL,
+			PasswordHash	TEXT NOT NULL,
+			HotlinkId	TEXT NOT NULL,
+			ContentType	TEXT NOT NULL,
+			AwsBucket	TEXT NOT NULL,
+			Encryption	BYTEA NOT NULL,
+			UnlimitedDownloads	BOOLEAN NOT NULL,
+			UnlimitedTime	BOOLEAN NOT NULL,
+			UserId	INTEGER NOT NULL,
+			UploadDate	BIGINT NOT NULL,
+			PendingDeletion	BIGINT NOT NULL,
+			UploadRequestId	TEXT NOT NULL,
+			IsEncrypted	BOOLEAN NOT NULL DEFAULT FALSE,
+			ProcessingState	INTEGER NOT NULL DEFAULT 0,
+			QuarantineReason	TEXT NOT NULL DEFAULT '',
+			PublishAt	BIGINT NOT NULL DEFAULT 0,
+			RequireLogin	BOOLEAN NOT NULL DEFAULT FALSE,
+			AllowedUsers	TEXT NOT NULL DEFAULT '',
+			AllowedGroups	TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (Id)
+		);
+		CREATE INDEX idx_FileMetaData_UploadDate ON FileMetaData (UploadDate, Id);
+		CREATE TABLE Hotlinks (
+			Id	TEXT NOT NULL,
+			FileId	TEXT NOT NULL UNIQUE,
+			PRIMARY KEY (Id)
+		);
+		CREATE TABLE Sessions (
+			Id	TEXT NOT NULL,
+			RenewAt	BIGINT NOT NULL,
+			ValidUntil	BIGINT NOT NULL,
+			UserId	INTEGER NOT NULL,
+			Groups	TEXT NOT NULL DEFAULT '',
+			RecipientName	TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (Id)
+		);
+		CREATE TABLE Users (
+			Id	INTEGER GENERATED BY DEFAULT AS IDENTITY,
+			Name	TEXT NOT NULL UNIQUE,
+			Password	TEXT,
+			Permissions	BIGINT NOT NULL,
+			Userlevel	INTEGER NOT NULL,
+			LastOnline	BIGINT NOT NULL DEFAULT 0,
+			ResetPassword	BOOLEAN NOT NULL DEFAULT FALSE,
+			PRIMARY KEY (Id)
+		);
+		CREATE TABLE UploadRequests (
+			Id	TEXT NOT NULL,
+			Name	TEXT NOT NULL,
+			UserId	INTEGER NOT NULL,
+			Expiry	BIGINT NOT NULL,
+			MaxFiles	INTEGER NOT NULL,
+			MaxSize	INTEGER NOT NULL,
+			Creation	BIGINT NOT NULL,
+			ApiKey	TEXT NOT NULL UNIQUE,
+			Note	TEXT NOT NULL,
+			RequireLogin	BOOLEAN NOT NULL DEFAULT FALSE,
+			AllowedUsers	TEXT NOT NULL DEFAULT '',
+			AllowedGroups	TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (Id)
+		);
+		CREATE TABLE Webhooks (
+			Id	TEXT NOT NULL,
+			Url	TEXT NOT NULL,
+			Secret	TEXT NOT NULL,
+			Events	TEXT NOT NULL,
+			UserId	INTEGER NOT NULL,
+			Creation	BIGINT NOT NULL,
+			PRIMARY KEY (Id)
+		);
+		CREATE TABLE WebhookDeliveries (
+			Id	TEXT NOT NULL,
+			WebhookId	TEXT NOT NULL,
+			Event	TEXT NOT NULL,
+			Payload	TEXT NOT NULL,
+			Attempts	INTEGER NOT NULL,
+			NextAttempt	BIGINT NOT NULL,
+			LastError	TEXT NOT NULL,
+			PRIMARY KEY (Id)
+		);
+		CREATE INDEX idx_WebhookDeliveries_NextAttempt ON WebhookDeliveries (NextAttempt);
+		CREATE TABLE Bundles (
+			Id	TEXT NOT NULL,
+			Name	TEXT NOT NULL,
+			FileIds	TEXT NOT NULL,
+			PasswordHash	TEXT NOT NULL,
+			UserId	INTEGER NOT NULL,
+			ExpireAt	BIGINT NOT NULL,
+			Creation	BIGINT NOT NULL,
+			DownloadsRemaining	INTEGER NOT NULL,
+			DownloadCount	INTEGER NOT NULL,
+			UnlimitedDownloads	BOOLEAN NOT NULL,
+			UnlimitedTime	BOOLEAN NOT NULL,
+			PRIMARY KEY (Id)
+		);
+		CREATE TABLE ShareLinks (
+			Id	TEXT NOT NULL,
+			FileId	TEXT NOT NULL,
+			Name	TEXT NOT NULL,
+			PasswordHash	TEXT NOT NULL,
+			UserId	INTEGER NOT NULL,
+			ExpireAt	BIGINT NOT NULL,
+			Creation	BIGINT NOT NULL,
+			DownloadsRemaining	INTEGER NOT NULL,
+			DownloadCount	INTEGER NOT NULL,
+			UnlimitedDownloads	BOOLEAN NOT NULL,
+			UnlimitedTime	BOOLEAN NOT NULL,
+			PRIMARY KEY (Id)
+		);
+		CREATE INDEX idx_ShareLinks_FileId ON ShareLinks (FileId);
+		CREATE TABLE UserTotp (
+			UserId	INTEGER NOT NULL,
+			Secret	TEXT NOT NULL,
+			RecoveryCodes	TEXT NOT NULL,
+			IsConfirmed	BOOLEAN NOT NULL,
+			LastUsedStep	BIGINT NOT NULL,
+			PRIMARY KEY (UserId)
+		);
+		CREATE TABLE AuditLog (
+			Id	BIGSERIAL NOT NULL,
+			Timestamp	BIGINT NOT NULL,
+			Action	TEXT NOT NULL,
+			Result	TEXT NOT NULL,
+			UserId	INTEGER NOT NULL,
+			ApiKeyId	TEXT NOT NULL,
+			Ip	TEXT NOT NULL,
+			FileId	TEXT NOT NULL,
+			FileRequestId	TEXT NOT NULL,
+			Details	TEXT NOT NULL,
+			PRIMARY KEY (Id)
+		);
+		CREATE INDEX idx_AuditLog_Timestamp ON AuditLog (Timestamp);
+		CREATE TABLE UserQuotas (
+			UserId	INTEGER NOT NULL,
+			MaxStorageMb	INTEGER NOT NULL,
+			MaxFiles	INTEGER NOT NULL,
+			MaxExpiryDays	INTEGER NOT NULL,
+			MaxDownloads	INTEGER NOT NULL,
+			PRIMARY KEY (UserId)
+		);
+		CREATE TABLE Leases (
+			Name	TEXT NOT NULL,
+			Owner	TEXT NOT NULL,
+			ExpiresAt	BIGINT NOT NULL,
+			PRIMARY KEY (Name)
+		);
+		CREATE TABLE FileVersions (
+			Id	TEXT NOT NULL,
+			FileId	TEXT NOT NULL,
+			Revision	INTEGER NOT NULL,
+			Name	TEXT NOT NULL,
+			Size	TEXT NOT NULL,
+			SHA1	TEXT NOT NULL,
+			ContentType	TEXT NOT NULL,
+			AwsBucket	TEXT NOT NULL,
+			SizeBytes	BIGINT NOT NULL,
+			ReplacedAt	BIGINT NOT NULL,
+			ReplacedBy	INTEGER NOT NULL,
+			Encryption	BYTEA NOT NULL,
+			PRIMARY KEY (Id)
+		);
+		CREATE INDEX idx_FileVersions_FileId ON FileVersions (FileId);
+		CREATE TABLE Statistics (
+			Type	INTEGER NOT NULL,
+			Value	BIGINT,
+			PRIMARY KEY (Type)
+		);
+		CREATE TABLE SchemaVersion (
+			Id	INTEGER NOT NULL,
+			Version	INTEGER NOT NULL,
+			PRIMARY KEY (Id)
+		);`
+	err := p.rawPostgres(sqlStmt)
+	if err != nil {
+		return err
+	}
+	p.SetDbVersion(DatabaseSchemeVersion)
+	return nil
+}
+
+// rawPostgres runs a raw SQL statement. Should only be used for upgrading
+func (p DatabaseProvider) rawPostgres(statement string) error {
+	if p.postgresDb == nil {
+		panic("Postgres not initialised")
+	}
+	_, err := p.postgresDb.Exec(statement)
+	return err
+}
+
+var osExit = os.Exit
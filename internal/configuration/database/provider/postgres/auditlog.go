@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectAuditEvents = "SELECT Id, Timestamp, Action, Result, UserId, ApiKeyId, Ip, FileId, FileRequestId, Details FROM AuditLog"
+
+// SaveAuditEvent stores a new audit event. The ID of the event is assigned by the database
+func (p DatabaseProvider) SaveAuditEvent(event models.AuditEvent) {
+	_, err := p.postgresDb.Exec(`INSERT INTO AuditLog (Timestamp, Action, Result, UserId, ApiKeyId, Ip, FileId, FileRequestId, Details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, event.Timestamp, event.Action, event.Result, event.UserId, event.ApiKeyId,
+		event.Ip, event.FileId, event.FileRequestId, event.Details)
+	helper.Check(err)
+}
+
+// QueryAuditEvents returns all audit events matching the given query, latest first
+func (p DatabaseProvider) QueryAuditEvents(query models.AuditQuery) models.AuditQueryResult {
+	var conditions []string
+	var args []any
+	addArg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if query.UserId != 0 {
+		conditions = append(conditions, "UserId = "+addArg(query.UserId))
+	}
+	if query.ApiKeyId != "" {
+		conditions = append(conditions, "ApiKeyId = "+addArg(query.ApiKeyId))
+	}
+	if query.Ip != "" {
+		conditions = append(conditions, "Ip = "+addArg(query.Ip))
+	}
+	if query.FileId != "" {
+		conditions = append(conditions, "FileId = "+addArg(query.FileId))
+	}
+	if query.FileRequestId != "" {
+		conditions = append(conditions, "FileRequestId = "+addArg(query.FileRequestId))
+	}
+	if query.Action != "" {
+		if query.IsActionPrefix() {
+			conditions = append(conditions, "Action LIKE "+addArg(escapeLike(query.Action)+"%")+` ESCAPE '\'`)
+		} else {
+			conditions = append(conditions, "Action = "+addArg(query.Action))
+		}
+	}
+	if query.Result != "" {
+		conditions = append(conditions, "Result = "+addArg(query.Result))
+	}
+	if query.Since != 0 {
+		conditions = append(conditions, "Timestamp >= "+addArg(query.Since))
+	}
+	if query.Until != 0 {
+		conditions = append(conditions, "Timestamp < "+addArg(query.Until))
+	}
+	if query.BeforeId != 0 {
+		conditions = append(conditions, "Id < "+addArg(query.BeforeId))
+	}
+
+	statement := selectAuditEvents
+	if len(conditions) > 0 {
+		statement = statement + " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement = statement + " ORDER BY Id DESC"
+	if query.Limit > 0 {
+		statement = statement + " LIMIT " + addArg(query.Limit+1)
+	}
+
+	result := models.AuditQueryResult{Events: make([]models.AuditEvent, 0)}
+	rows, err := p.postgresDb.Query(statement, args...)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		var event models.AuditEvent
+		err = rows.Scan(&event.Id, &event.Timestamp, &event.Action, &event.Result, &event.UserId, &event.ApiKeyId,
+			&event.Ip, &event.FileId, &event.FileRequestId, &event.Details)
+		helper.Check(err)
+		result.Events = append(result.Events, event)
+	}
+	if query.Limit > 0 && len(result.Events) > query.Limit {
+		result.Events = result.Events[:query.Limit]
+		result.HasMore = true
+	}
+	return result
+}
+
+// DeleteAuditEventsBefore deletes all audit events that were created before the given timestamp
+func (p DatabaseProvider) DeleteAuditEventsBefore(timestamp int64) {
+	_, err := p.postgresDb.Exec("DELETE FROM AuditLog WHERE Timestamp < $1", timestamp)
+	helper.Check(err)
+}
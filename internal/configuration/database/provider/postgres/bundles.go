@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectBundles = `SELECT Id, Name, FileIds, PasswordHash, UserId, ExpireAt, Creation, DownloadsRemaining,
+	DownloadCount, UnlimitedDownloads, UnlimitedTime FROM Bundles`
+
+func scanBundle(row rowScanner) (models.Bundle, error) {
+	var result models.Bundle
+	err := row.Scan(&result.Id, &result.Name, &result.FileIds, &result.PasswordHash, &result.UserId, &result.ExpireAt,
+		&result.CreationDate, &result.DownloadsRemaining, &result.DownloadCount, &result.UnlimitedDownloads,
+		&result.UnlimitedTime)
+	return result, err
+}
+
+// GetBundle returns the bundle or false if not found
+func (p DatabaseProvider) GetBundle(id string) (models.Bundle, bool) {
+	result, err := scanBundle(p.postgresDb.QueryRow(selectBundles+" WHERE Id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Bundle{}, false
+		}
+		helper.Check(err)
+		return models.Bundle{}, false
+	}
+	return result, true
+}
+
+// GetAllBundles returns an array with all bundles, ordered by creation date
+func (p DatabaseProvider) GetAllBundles() []models.Bundle {
+	result := make([]models.Bundle, 0)
+	rows, err := p.postgresDb.Query(selectBundles + " ORDER BY Creation, Id")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		bundle, err := scanBundle(rows)
+		helper.Check(err)
+		result = append(result, bundle)
+	}
+	return result
+}
+
+// SaveBundle stores the bundle in the database
+func (p DatabaseProvider) SaveBundle(bundle models.Bundle) {
+	_, err := p.postgresDb.Exec(`INSERT INTO Bundles (Id, Name, FileIds, PasswordHash, UserId, ExpireAt,
+		Creation, DownloadsRemaining, DownloadCount, UnlimitedDownloads, UnlimitedTime)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (Id) DO UPDATE SET Name = EXCLUDED.Name, FileIds = EXCLUDED.FileIds,
+			PasswordHash = EXCLUDED.PasswordHash, UserId = EXCLUDED.UserId, ExpireAt = EXCLUDED.ExpireAt,
+			Creation = EXCLUDED.Creation, DownloadsRemaining = EXCLUDED.DownloadsRemaining,
+			DownloadCount = EXCLUDED.DownloadCount, UnlimitedDownloads = EXCLUDED.UnlimitedDownloads,
+			UnlimitedTime = EXCLUDED.UnlimitedTime`,
+		bundle.Id, bundle.Name, bundle.FileIds, bundle.PasswordHash, bundle.UserId, bundle.ExpireAt, bundle.CreationDate,
+		bundle.DownloadsRemaining, bundle.DownloadCount, bundle.UnlimitedDownloads, bundle.UnlimitedTime)
+	helper.Check(err)
+}
+
+// IncreaseBundleDownloadCount increases the download count of a bundle, preventing race conditions
+func (p DatabaseProvider) IncreaseBundleDownloadCount(id string, decreaseRemainingDownloads bool) {
+	if decreaseRemainingDownloads {
+		_, err := p.postgresDb.Exec(`UPDATE Bundles SET DownloadCount = DownloadCount + 1,
+                   DownloadsRemaining = DownloadsRemaining - 1 WHERE Id = $1`, id)
+		helper.Check(err)
+	} else {
+		_, err := p.postgresDb.Exec(`UPDATE Bundles SET DownloadCount = DownloadCount + 1 WHERE Id = $1`, id)
+		helper.Check(err)
+	}
+}
+
+// DeleteBundle deletes the bundle with the given ID
+func (p DatabaseProvider) DeleteBundle(id string) {
+	_, err := p.postgresDb.Exec("DELETE FROM Bundles WHERE Id = $1", id)
+	helper.Check(err)
+}
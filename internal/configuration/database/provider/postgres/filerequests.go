@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+type schemaFileRequests struct {
+	Id       string
+	Name     string
+	UserId   int
+	Expiry   int64
+	MaxFiles int
+	MaxSize  int
+	Creation int64
+	ApiKey   string
+	Note     string
+	// Access restriction
+	RequireLogin  bool
+	AllowedUsers  string
+	AllowedGroups string
+}
+
+func (rowData schemaFileRequests) ToFileRequest() models.FileRequest {
+	return models.FileRequest{
+		Id:            rowData.Id,
+		Name:          rowData.Name,
+		UserId:        rowData.UserId,
+		MaxFiles:      rowData.MaxFiles,
+		MaxSize:       rowData.MaxSize,
+		Expiry:        rowData.Expiry,
+		CreationDate:  rowData.Creation,
+		ApiKey:        rowData.ApiKey,
+		Notes:         rowData.Note,
+		RequireLogin:  rowData.RequireLogin,
+		AllowedUsers:  rowData.AllowedUsers,
+		AllowedGroups: rowData.AllowedGroups,
+	}
+}
+
+const selectFileRequests = "SELECT Id, Name, UserId, Expiry, MaxFiles, MaxSize, Creation, ApiKey, Note, RequireLogin, AllowedUsers, AllowedGroups FROM UploadRequests"
+
+// GetFileRequest returns the FileRequest or false if not found
+func (p DatabaseProvider) GetFileRequest(id string) (models.FileRequest, bool) {
+	if id == "" {
+		return models.FileRequest{}, false
+	}
+	var rowResult schemaFileRequests
+	row := p.postgresDb.QueryRow(selectFileRequests+" WHERE Id = $1", id)
+	err := row.Scan(&rowResult.Id, &rowResult.Name, &rowResult.UserId, &rowResult.Expiry,
+		&rowResult.MaxFiles, &rowResult.MaxSize, &rowResult.Creation, &rowResult.ApiKey, &rowResult.Note,
+		&rowResult.RequireLogin, &rowResult.AllowedUsers, &rowResult.AllowedGroups)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.FileRequest{}, false
+		}
+		helper.Check(err)
+		return models.FileRequest{}, false
+	}
+	return rowResult.ToFileRequest(), true
+}
+
+// GetAllFileRequests returns an array with all file requests, ordered by creation date
+func (p DatabaseProvider) GetAllFileRequests() []models.FileRequest {
+	result := make([]models.FileRequest, 0)
+	rows, err := p.postgresDb.Query(selectFileRequests + " ORDER BY Creation DESC, Name")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		rowData := schemaFileRequests{}
+		err = rows.Scan(&rowData.Id, &rowData.Name, &rowData.UserId, &rowData.Expiry, &rowData.MaxFiles,
+			&rowData.MaxSize, &rowData.Creation, &rowData.ApiKey, &rowData.Note,
+			&rowData.RequireLogin, &rowData.AllowedUsers, &rowData.AllowedGroups)
+		helper.Check(err)
+		result = append(result, rowData.ToFileRequest())
+	}
+	return result
+}
+
+// SaveFileRequest stores the file request associated with the file in the database
+func (p DatabaseProvider) SaveFileRequest(request models.FileRequest) {
+	_, err := p.postgresDb.Exec(`INSERT INTO UploadRequests (Id, Name, UserId, Expiry, MaxFiles, MaxSize, Creation, ApiKey, Note,
+			RequireLogin, AllowedUsers, AllowedGroups)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (Id) DO UPDATE SET Name = EXCLUDED.Name, UserId = EXCLUDED.UserId, Expiry = EXCLUDED.Expiry,
+			MaxFiles = EXCLUDED.MaxFiles, MaxSize = EXCLUDED.MaxSize, Creation = EXCLUDED.Creation,
+			ApiKey = EXCLUDED.ApiKey, Note = EXCLUDED.Note, RequireLogin = EXCLUDED.RequireLogin,
+			AllowedUsers = EXCLUDED.AllowedUsers, AllowedGroups = EXCLUDED.AllowedGroups`,
+		request.Id, request.Name, request.UserId, request.Expiry, request.MaxFiles, request.MaxSize,
+		request.CreationDate, request.ApiKey, request.Notes, request.RequireLogin, request.AllowedUsers, request.AllowedGroups)
+	helper.Check(err)
+}
+
+// DeleteFileRequest deletes a file request with the given ID
+func (p DatabaseProvider) DeleteFileRequest(request models.FileRequest) {
+	if request.Id == "" {
+		return
+	}
+	_, err := p.postgresDb.Exec("DELETE FROM UploadRequests WHERE Id = $1", request.Id)
+	helper.Check(err)
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectFileVersions = `SELECT Id, FileId, Revision, Name, Size, SHA1, ContentType, AwsBucket, SizeBytes, ReplacedAt,
+	ReplacedBy, Encryption FROM FileVersions`
+
+func scanFileVersion(row rowScanner) (models.FileVersion, error) {
+	var result models.FileVersion
+	var encryption []byte
+	err := row.Scan(&result.Id, &result.FileId, &result.Revision, &result.Name, &result.Size, &result.SHA1, &result.ContentType,
+		&result.AwsBucket, &result.SizeBytes, &result.ReplacedAt, &result.ReplacedBy, &encryption)
+	if err != nil {
+		return result, err
+	}
+	dec := gob.NewDecoder(bytes.NewBuffer(encryption))
+	err = dec.Decode(&result.Encryption)
+	return result, err
+}
+
+func (p DatabaseProvider) queryFileVersions(query string, args ...any) []models.FileVersion {
+	result := make([]models.FileVersion, 0)
+	rows, err := p.postgresDb.Query(query, args...)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		version, err := scanFileVersion(rows)
+		helper.Check(err)
+		result = append(result, version)
+	}
+	return result
+}
+
+// GetFileVersion returns the file version or false if not found
+func (p DatabaseProvider) GetFileVersion(id string) (models.FileVersion, bool) {
+	result, err := scanFileVersion(p.postgresDb.QueryRow(selectFileVersions+" WHERE Id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.FileVersion{}, false
+		}
+		helper.Check(err)
+		return models.FileVersion{}, false
+	}
+	return result, true
+}
+
+// GetFileVersions returns all versions of the given file, latest first
+func (p DatabaseProvider) GetFileVersions(fileId string) []models.FileVersion {
+	return p.queryFileVersions(selectFileVersions+" WHERE FileId = $1 ORDER BY Revision DESC", fileId)
+}
+
+// GetAllFileVersions returns an array with all file versions, ordered by replacement date
+func (p DatabaseProvider) GetAllFileVersions() []models.FileVersion {
+	return p.queryFileVersions(selectFileVersions + " ORDER BY ReplacedAt, Id")
+}
+
+// SaveFileVersion stores the file version in the database
+func (p DatabaseProvider) SaveFileVersion(version models.FileVersion) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(version.Encryption)
+	helper.Check(err)
+	_, err = p.postgresDb.Exec(`INSERT INTO FileVersions (Id, FileId, Revision, Name, Size, SHA1, ContentType,
+		AwsBucket, SizeBytes, ReplacedAt, ReplacedBy, Encryption)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (Id) DO UPDATE SET FileId = EXCLUDED.FileId, Revision = EXCLUDED.Revision, Name = EXCLUDED.Name, Size = EXCLUDED.Size,
+			SHA1 = EXCLUDED.SHA1, ContentType = EXCLUDED.ContentType, AwsBucket = EXCLUDED.AwsBucket,
+			SizeBytes = EXCLUDED.SizeBytes, ReplacedAt = EXCLUDED.ReplacedAt, ReplacedBy = EXCLUDED.ReplacedBy,
+			Encryption = EXCLUDED.Encryption`,
+		version.Id, version.FileId, version.Revision, version.Name, version.Size, version.SHA1, version.ContentType,
+		version.AwsBucket, version.SizeBytes, version.ReplacedAt, version.ReplacedBy, buf.Bytes())
+	helper.Check(err)
+}
+
+// DeleteFileVersion deletes the file version with the given ID
+func (p DatabaseProvider) DeleteFileVersion(id string) {
+	_, err := p.postgresDb.Exec("DELETE FROM FileVersions WHERE Id = $1", id)
+	helper.Check(err)
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// GetHotlink returns the id of the file associated or false if not found
+func (p DatabaseProvider) GetHotlink(id string) (string, bool) {
+	var fileId string
+	row := p.postgresDb.QueryRow("SELECT FileId FROM Hotlinks WHERE Id = $1", id)
+	err := row.Scan(&fileId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false
+		}
+		helper.Check(err)
+		return "", false
+	}
+	return fileId, true
+}
+
+// GetAllHotlinks returns an array with all hotlink ids
+func (p DatabaseProvider) GetAllHotlinks() []string {
+	ids := make([]string, 0)
+	rows, err := p.postgresDb.Query("SELECT Id FROM Hotlinks")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		helper.Check(err)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// SaveHotlink stores the hotlink associated with the file in the database
+func (p DatabaseProvider) SaveHotlink(file models.File) {
+	// FileId is unique, therefore an older hotlink for the same file has to be removed first
+	_, err := p.postgresDb.Exec("DELETE FROM Hotlinks WHERE FileId = $1 AND Id <> $2", file.Id, file.HotlinkId)
+	helper.Check(err)
+	_, err = p.postgresDb.Exec(`INSERT INTO Hotlinks (Id, FileId) VALUES ($1, $2)
+		ON CONFLICT (Id) DO UPDATE SET FileId = EXCLUDED.FileId`,
+		file.HotlinkId, file.Id)
+	helper.Check(err)
+}
+
+// DeleteHotlink deletes a hotlink with the given hotlink ID
+func (p DatabaseProvider) DeleteHotlink(id string) {
+	if id == "" {
+		return
+	}
+	_, err := p.postgresDb.Exec("DELETE FROM Hotlinks WHERE Id = $1", id)
+	helper.Check(err)
+}
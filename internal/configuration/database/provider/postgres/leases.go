@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// TryAcquireLease acquires the lease or renews it, if it is already held by the same owner.
+// Returns true, if the lease is held by the owner afterwards
+func (p DatabaseProvider) TryAcquireLease(lease models.Lease) bool {
+	result, err := p.postgresDb.Exec(`INSERT INTO Leases (Name, Owner, ExpiresAt) VALUES ($1, $2, $3)
+		ON CONFLICT(Name) DO UPDATE SET Owner = EXCLUDED.Owner, ExpiresAt = EXCLUDED.ExpiresAt
+		WHERE Leases.Owner = EXCLUDED.Owner OR Leases.ExpiresAt < $4`,
+		lease.Name, lease.Owner, lease.ExpiresAt, time.Now().Unix())
+	helper.Check(err)
+	rowsAffected, err := result.RowsAffected()
+	helper.Check(err)
+	return rowsAffected == 1
+}
+
+// GetLease returns the lease with the given name or false if it does not exist
+func (p DatabaseProvider) GetLease(name string) (models.Lease, bool) {
+	var result models.Lease
+	row := p.postgresDb.QueryRow("SELECT Name, Owner, ExpiresAt FROM Leases WHERE Name = $1", name)
+	err := row.Scan(&result.Name, &result.Owner, &result.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Lease{}, false
+		}
+		helper.Check(err)
+		return models.Lease{}, false
+	}
+	return result, true
+}
+
+// ReleaseLease deletes the lease with the given name, if it is held by the owner
+func (p DatabaseProvider) ReleaseLease(name, owner string) {
+	_, err := p.postgresDb.Exec("DELETE FROM Leases WHERE Name = $1 AND Owner = $2", name, owner)
+	helper.Check(err)
+}
@@ -0,0 +1,277 @@
+package postgres
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+type schemaMetaData struct {
+	Id                 string
+	Name               string
+	Size               string
+	SHA1               string
+	ExpireAt           int64
+	SizeBytes          int64
+	DownloadsRemaining int
+	DownloadCount      int
+	PasswordHash       string
+	HotlinkId          string
+	ContentType        string
+	AwsBucket          string
+	Encryption         []byte
+	UnlimitedDownloads bool
+	UnlimitedTime      bool
+	UserId             int
+	UploadDate         int64
+	PendingDeletion    int64
+	UploadRequestId    string
+	ProcessingState    int
+	QuarantineReason   string
+	PublishAt          int64
+	RequireLogin       bool
+	AllowedUsers       string
+	AllowedGroups      string
+}
+
+const selectMetaData = `SELECT Id, Name, Size, SHA1, ExpireAt, SizeBytes, DownloadsRemaining, DownloadCount, PasswordHash,
+		HotlinkId, ContentType, AwsBucket, Encryption, UnlimitedDownloads, UnlimitedTime, UserId, UploadDate,
+		PendingDeletion, UploadRequestId, ProcessingState, QuarantineReason, PublishAt, RequireLogin,
+		AllowedUsers, AllowedGroups FROM FileMetaData`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMetaData(row rowScanner) (schemaMetaData, error) {
+	rowData := schemaMetaData{}
+	err := row.Scan(&rowData.Id, &rowData.Name, &rowData.Size, &rowData.SHA1, &rowData.ExpireAt, &rowData.SizeBytes,
+		&rowData.DownloadsRemaining, &rowData.DownloadCount, &rowData.PasswordHash, &rowData.HotlinkId, &rowData.ContentType,
+		&rowData.AwsBucket, &rowData.Encryption, &rowData.UnlimitedDownloads, &rowData.UnlimitedTime, &rowData.UserId,
+		&rowData.UploadDate, &rowData.PendingDeletion, &rowData.UploadRequestId, &rowData.ProcessingState, &rowData.QuarantineReason,
+		&rowData.PublishAt, &rowData.RequireLogin, &rowData.AllowedUsers, &rowData.AllowedGroups)
+	return rowData, err
+}
+
+func (rowData schemaMetaData) ToFileModel() (models.File, error) {
+	result := models.File{
+		Id:                 rowData.Id,
+		Name:               rowData.Name,
+		Size:               rowData.Size,
+		SHA1:               rowData.SHA1,
+		ExpireAt:           rowData.ExpireAt,
+		SizeBytes:          rowData.SizeBytes,
+		DownloadsRemaining: rowData.DownloadsRemaining,
+		DownloadCount:      rowData.DownloadCount,
+		PasswordHash:       rowData.PasswordHash,
+		HotlinkId:          rowData.HotlinkId,
+		ContentType:        rowData.ContentType,
+		AwsBucket:          rowData.AwsBucket,
+		Encryption:         models.EncryptionInfo{},
+		UnlimitedDownloads: rowData.UnlimitedDownloads,
+		UnlimitedTime:      rowData.UnlimitedTime,
+		UserId:             rowData.UserId,
+		UploadDate:         rowData.UploadDate,
+		PendingDeletion:    rowData.PendingDeletion,
+		UploadRequestId:    rowData.UploadRequestId,
+		ProcessingState:    rowData.ProcessingState,
+		QuarantineReason:   rowData.QuarantineReason,
+		PublishAt:          rowData.PublishAt,
+		RequireLogin:       rowData.RequireLogin,
+		AllowedUsers:       rowData.AllowedUsers,
+		AllowedGroups:      rowData.AllowedGroups,
+	}
+
+	buf := bytes.NewBuffer(rowData.Encryption)
+	dec := gob.NewDecoder(buf)
+	err := dec.Decode(&result.Encryption)
+	return result, err
+}
+
+// GetAllMetadata returns a map of all available files
+func (p DatabaseProvider) GetAllMetadata() map[string]models.File {
+	result := make(map[string]models.File)
+	rows, err := p.postgresDb.Query(selectMetaData)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		rowData, err := scanMetaData(rows)
+		helper.Check(err)
+		metaData, err := rowData.ToFileModel()
+		helper.Check(err)
+		result[metaData.Id] = metaData
+	}
+	return result
+}
+
+// QueryMetaData returns all files matching the given query. Filtering, sorting and pagination is done by the database
+func (p DatabaseProvider) QueryMetaData(query models.FileQuery) models.FileQueryResult {
+	var conditions []string
+	var args []any
+	addArg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if query.UserId != 0 {
+		conditions = append(conditions, "UserId = "+addArg(query.UserId))
+	}
+	if query.FileRequestId != "" {
+		conditions = append(conditions, "UploadRequestId = "+addArg(query.FileRequestId))
+	} else if !query.IncludeFileRequests {
+		conditions = append(conditions, "UploadRequestId = ''")
+	}
+	if query.NameContains != "" {
+		conditions = append(conditions, "Name ILIKE "+addArg("%"+escapeLike(query.NameContains)+"%")+` ESCAPE '\'`)
+	}
+	if query.ContentTypePrefix != "" {
+		conditions = append(conditions, "ContentType ILIKE "+addArg(escapeLike(query.ContentTypePrefix)+"%")+` ESCAPE '\'`)
+	}
+	switch query.Encryption {
+	case models.FileFilterEncryptionOnly:
+		conditions = append(conditions, "IsEncrypted")
+	case models.FileFilterEncryptionNone:
+		conditions = append(conditions, "NOT IsEncrypted")
+	}
+	if query.ExpiresAfter != 0 {
+		conditions = append(conditions, "(UnlimitedTime OR ExpireAt > "+addArg(query.ExpiresAfter)+")")
+	}
+	if query.ExpiresBefore != 0 {
+		conditions = append(conditions, "(NOT UnlimitedTime AND ExpireAt < "+addArg(query.ExpiresBefore)+")")
+	}
+	if query.HideExpiredAt != 0 {
+		conditions = append(conditions, "(UnlimitedTime OR ExpireAt >= "+addArg(query.HideExpiredAt)+
+			") AND (UnlimitedDownloads OR DownloadsRemaining >= 1)")
+	}
+
+	column := getSortColumn(query.SortBy)
+	direction := "ASC"
+	comparator := ">"
+	if query.SortDescending {
+		direction = "DESC"
+		comparator = "<"
+	}
+	if query.HasCursor() {
+		var cursorValue any = query.CursorValue
+		if query.SortBy == models.FileSortName {
+			cursorValue = query.CursorName
+		}
+		conditions = append(conditions, "("+column+", Id) "+comparator+" ("+addArg(cursorValue)+", "+addArg(query.CursorId)+")")
+	}
+
+	statement := selectMetaData
+	if len(conditions) > 0 {
+		statement = statement + " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement = statement + " ORDER BY " + column + " " + direction + ", Id " + direction
+	if query.Limit > 0 {
+		statement = statement + " LIMIT " + addArg(query.Limit+1)
+	}
+
+	result := models.FileQueryResult{Files: make([]models.File, 0)}
+	rows, err := p.postgresDb.Query(statement, args...)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		rowData, err := scanMetaData(rows)
+		helper.Check(err)
+		metaData, err := rowData.ToFileModel()
+		helper.Check(err)
+		result.Files = append(result.Files, metaData)
+	}
+	if query.Limit > 0 && len(result.Files) > query.Limit {
+		result.Files = result.Files[:query.Limit]
+		result.HasMore = true
+	}
+	return result
+}
+
+func getSortColumn(sortBy int) string {
+	switch sortBy {
+	case models.FileSortName:
+		return "Name"
+	case models.FileSortSize:
+		return "SizeBytes"
+	case models.FileSortExpiry:
+		return "ExpireAt"
+	case models.FileSortDownloads:
+		return "DownloadCount"
+	default:
+		return "UploadDate"
+	}
+}
+
+// escapeLike escapes all wildcard characters of a LIKE pattern with a backslash
+func escapeLike(input string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+	return replacer.Replace(input)
+}
+
+// GetMetaDataById returns a models.File from the ID passed or false if the id is not valid
+func (p DatabaseProvider) GetMetaDataById(id string) (models.File, bool) {
+	rowData, err := scanMetaData(p.postgresDb.QueryRow(selectMetaData+" WHERE Id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.File{}, false
+		}
+		helper.Check(err)
+		return models.File{}, false
+	}
+	result, err := rowData.ToFileModel()
+	helper.Check(err)
+	return result, true
+}
+
+// SaveMetaData stores the metadata of a file to the disk
+func (p DatabaseProvider) SaveMetaData(file models.File) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(file.Encryption)
+	helper.Check(err)
+
+	_, err = p.postgresDb.Exec(`INSERT INTO FileMetaData (Id, Name, Size, SHA1, ExpireAt, SizeBytes,
+			DownloadsRemaining, DownloadCount, PasswordHash, HotlinkId, ContentType, AwsBucket, Encryption,
+			UnlimitedDownloads, UnlimitedTime, UserId, UploadDate, PendingDeletion, UploadRequestId, IsEncrypted,
+			ProcessingState, QuarantineReason, PublishAt, RequireLogin, AllowedUsers, AllowedGroups)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23,
+			$24, $25, $26)
+		ON CONFLICT (Id) DO UPDATE SET Name = EXCLUDED.Name, Size = EXCLUDED.Size, SHA1 = EXCLUDED.SHA1,
+			ExpireAt = EXCLUDED.ExpireAt, SizeBytes = EXCLUDED.SizeBytes, DownloadsRemaining = EXCLUDED.DownloadsRemaining,
+			DownloadCount = EXCLUDED.DownloadCount, PasswordHash = EXCLUDED.PasswordHash, HotlinkId = EXCLUDED.HotlinkId,
+			ContentType = EXCLUDED.ContentType, AwsBucket = EXCLUDED.AwsBucket, Encryption = EXCLUDED.Encryption,
+			UnlimitedDownloads = EXCLUDED.UnlimitedDownloads, UnlimitedTime = EXCLUDED.UnlimitedTime,
+			UserId = EXCLUDED.UserId, UploadDate = EXCLUDED.UploadDate, PendingDeletion = EXCLUDED.PendingDeletion,
+			UploadRequestId = EXCLUDED.UploadRequestId, IsEncrypted = EXCLUDED.IsEncrypted,
+			ProcessingState = EXCLUDED.ProcessingState, QuarantineReason = EXCLUDED.QuarantineReason,
+			PublishAt = EXCLUDED.PublishAt, RequireLogin = EXCLUDED.RequireLogin, AllowedUsers = EXCLUDED.AllowedUsers,
+			AllowedGroups = EXCLUDED.AllowedGroups`,
+		file.Id, file.Name, file.Size, file.SHA1, file.ExpireAt, file.SizeBytes,
+		file.DownloadsRemaining, file.DownloadCount, file.PasswordHash, file.HotlinkId, file.ContentType,
+		file.AwsBucket, buf.Bytes(), file.UnlimitedDownloads, file.UnlimitedTime, file.UserId, file.UploadDate,
+		file.PendingDeletion, file.UploadRequestId, file.Encryption.IsEncrypted, file.ProcessingState, file.QuarantineReason,
+		file.PublishAt, file.RequireLogin, file.AllowedUsers, file.AllowedGroups)
+	helper.Check(err)
+}
+
+// IncreaseDownloadCount increases the download count of a file, preventing race conditions
+func (p DatabaseProvider) IncreaseDownloadCount(id string, decreaseRemainingDownloads bool) {
+	if decreaseRemainingDownloads {
+		_, err := p.postgresDb.Exec(`UPDATE FileMetaData SET DownloadCount = DownloadCount + 1,
+                        DownloadsRemaining = DownloadsRemaining - 1 WHERE Id = $1`, id)
+		helper.Check(err)
+	} else {
+		_, err := p.postgresDb.Exec(`UPDATE FileMetaData SET DownloadCount = DownloadCount + 1 WHERE Id = $1`, id)
+		helper.Check(err)
+	}
+}
+
+// DeleteMetaData deletes information about a file
+func (p DatabaseProvider) DeleteMetaData(id string) {
+	_, err := p.postgresDb.Exec("DELETE FROM FileMetaData WHERE Id = $1", id)
+	helper.Check(err)
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// GetSession returns the session with the given ID or false if not a valid ID
+func (p DatabaseProvider) GetSession(id string) (models.Session, bool) {
+	var result models.Session
+	row := p.postgresDb.QueryRow("SELECT RenewAt, ValidUntil, UserId, Groups, RecipientName FROM Sessions WHERE Id = $1", id)
+	err := row.Scan(&result.RenewAt, &result.ValidUntil, &result.UserId, &result.Groups, &result.RecipientName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, false
+		}
+		helper.Check(err)
+		return models.Session{}, false
+	}
+	return result, true
+}
+
+// SaveSession stores the given session. After the expiry passed, it will be deleted automatically
+func (p DatabaseProvider) SaveSession(id string, session models.Session) {
+	_, err := p.postgresDb.Exec(`INSERT INTO Sessions (Id, RenewAt, ValidUntil, UserId, Groups, RecipientName)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (Id) DO UPDATE SET RenewAt = EXCLUDED.RenewAt, ValidUntil = EXCLUDED.ValidUntil, UserId = EXCLUDED.UserId,
+			Groups = EXCLUDED.Groups, RecipientName = EXCLUDED.RecipientName`,
+		id, session.RenewAt, session.ValidUntil, session.UserId, session.Groups, session.RecipientName)
+	helper.Check(err)
+}
+
+// DeleteSession deletes a session with the given ID
+func (p DatabaseProvider) DeleteSession(id string) {
+	_, err := p.postgresDb.Exec("DELETE FROM Sessions WHERE Id = $1", id)
+	helper.Check(err)
+}
+
+// DeleteAllSessions logs all users out
+func (p DatabaseProvider) DeleteAllSessions() {
+	//goland:noinspection SqlWithoutWhere
+	_, err := p.postgresDb.Exec("DELETE FROM Sessions")
+	helper.Check(err)
+}
+
+// DeleteAllSessionsByUser logs the specific users out
+func (p DatabaseProvider) DeleteAllSessionsByUser(userId int) {
+	_, err := p.postgresDb.Exec("DELETE FROM Sessions WHERE UserId = $1", userId)
+	helper.Check(err)
+}
+
+func (p DatabaseProvider) cleanExpiredSessions() {
+	_, err := p.postgresDb.Exec("DELETE FROM Sessions WHERE ValidUntil < $1", time.Now().Unix())
+	helper.Check(err)
+}
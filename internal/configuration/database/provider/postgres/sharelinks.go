@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectShareLinks = `SELECT Id, FileId, Name, PasswordHash, UserId, ExpireAt, Creation, DownloadsRemaining,
+	DownloadCount, UnlimitedDownloads, UnlimitedTime FROM ShareLinks`
+
+func scanShareLink(row rowScanner) (models.ShareLink, error) {
+	var result models.ShareLink
+	err := row.Scan(&result.Id, &result.FileId, &result.Name, &result.PasswordHash, &result.UserId, &result.ExpireAt,
+		&result.CreationDate, &result.DownloadsRemaining, &result.DownloadCount, &result.UnlimitedDownloads,
+		&result.UnlimitedTime)
+	return result, err
+}
+
+// GetShareLink returns the share link or false if not found
+func (p DatabaseProvider) GetShareLink(id string) (models.ShareLink, bool) {
+	result, err := scanShareLink(p.postgresDb.QueryRow(selectShareLinks+" WHERE Id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ShareLink{}, false
+		}
+		helper.Check(err)
+		return models.ShareLink{}, false
+	}
+	return result, true
+}
+
+// GetAllShareLinks returns an array with all share links, ordered by creation date
+func (p DatabaseProvider) GetAllShareLinks() []models.ShareLink {
+	result := make([]models.ShareLink, 0)
+	rows, err := p.postgresDb.Query(selectShareLinks + " ORDER BY Creation, Id")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		helper.Check(err)
+		result = append(result, link)
+	}
+	return result
+}
+
+// SaveShareLink stores the share link in the database
+func (p DatabaseProvider) SaveShareLink(link models.ShareLink) {
+	_, err := p.postgresDb.Exec(`INSERT INTO ShareLinks (Id, FileId, Name, PasswordHash, UserId, ExpireAt,
+		Creation, DownloadsRemaining, DownloadCount, UnlimitedDownloads, UnlimitedTime)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (Id) DO UPDATE SET FileId = EXCLUDED.FileId, Name = EXCLUDED.Name,
+			PasswordHash = EXCLUDED.PasswordHash, UserId = EXCLUDED.UserId, ExpireAt = EXCLUDED.ExpireAt,
+			Creation = EXCLUDED.Creation, DownloadsRemaining = EXCLUDED.DownloadsRemaining,
+			DownloadCount = EXCLUDED.DownloadCount, UnlimitedDownloads = EXCLUDED.UnlimitedDownloads,
+			UnlimitedTime = EXCLUDED.UnlimitedTime`,
+		link.Id, link.FileId, link.Name, link.PasswordHash, link.UserId, link.ExpireAt, link.CreationDate,
+		link.DownloadsRemaining, link.DownloadCount, link.UnlimitedDownloads, link.UnlimitedTime)
+	helper.Check(err)
+}
+
+// IncreaseShareLinkDownloadCount increases the download count of a share link, preventing race conditions
+func (p DatabaseProvider) IncreaseShareLinkDownloadCount(id string, decreaseRemainingDownloads bool) {
+	if decreaseRemainingDownloads {
+		_, err := p.postgresDb.Exec(`UPDATE ShareLinks SET DownloadCount = DownloadCount + 1,
+                   DownloadsRemaining = DownloadsRemaining - 1 WHERE Id = $1`, id)
+		helper.Check(err)
+	} else {
+		_, err := p.postgresDb.Exec(`UPDATE ShareLinks SET DownloadCount = DownloadCount + 1 WHERE Id = $1`, id)
+		helper.Check(err)
+	}
+}
+
+// DeleteShareLink deletes the share link with the given ID
+func (p DatabaseProvider) DeleteShareLink(id string) {
+	_, err := p.postgresDb.Exec("DELETE FROM ShareLinks WHERE Id = $1", id)
+	helper.Check(err)
+}
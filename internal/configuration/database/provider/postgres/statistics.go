@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+)
+
+const statIdTraffic = 1
+const statIdTrafficSince = 2
+
+// GetStatTraffic returns the total traffic from statistics
+func (p DatabaseProvider) GetStatTraffic() uint64 {
+	var result int64
+	row := p.postgresDb.QueryRow("SELECT Value FROM Statistics WHERE Type = $1", statIdTraffic)
+	err := row.Scan(&result)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0
+		}
+		helper.Check(err)
+		return 0
+	}
+	return uint64(result)
+}
+
+// SaveStatTraffic stores the total traffic
+func (p DatabaseProvider) SaveStatTraffic(totalTraffic uint64) {
+	p.saveStatistic(statIdTraffic, int64(totalTraffic))
+}
+
+// SaveTrafficSince stores the beginning of traffic counting
+func (p DatabaseProvider) SaveTrafficSince(since int64) {
+	p.saveStatistic(statIdTrafficSince, since)
+}
+
+func (p DatabaseProvider) saveStatistic(statType int, value int64) {
+	_, err := p.postgresDb.Exec(`INSERT INTO Statistics (Type, Value) VALUES ($1, $2)
+					ON CONFLICT (Type) DO UPDATE SET Value = EXCLUDED.Value`, statType, value)
+	helper.Check(err)
+}
+
+// GetTrafficSince gets the beginning of traffic counting
+func (p DatabaseProvider) GetTrafficSince() (int64, bool) {
+	var result int64
+	row := p.postgresDb.QueryRow("SELECT Value FROM Statistics WHERE Type = $1", statIdTrafficSince)
+	err := row.Scan(&result)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false
+		}
+		helper.Check(err)
+		return 0, false
+	}
+	return result, true
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// GetUserQuota returns the quota of a user or false if no quota has been set
+func (p DatabaseProvider) GetUserQuota(userId int) (models.UserQuota, bool) {
+	var result models.UserQuota
+	row := p.postgresDb.QueryRow("SELECT UserId, MaxStorageMb, MaxFiles, MaxExpiryDays, MaxDownloads FROM UserQuotas WHERE UserId = $1", userId)
+	err := row.Scan(&result.UserId, &result.MaxStorageMb, &result.MaxFiles, &result.MaxExpiryDays, &result.MaxDownloads)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserQuota{}, false
+		}
+		helper.Check(err)
+		return models.UserQuota{}, false
+	}
+	return result, true
+}
+
+// SaveUserQuota stores the quota of a user
+func (p DatabaseProvider) SaveUserQuota(quota models.UserQuota) {
+	_, err := p.postgresDb.Exec(`INSERT INTO UserQuotas (UserId, MaxStorageMb, MaxFiles, MaxExpiryDays, MaxDownloads)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (UserId) DO UPDATE SET MaxStorageMb = EXCLUDED.MaxStorageMb, MaxFiles = EXCLUDED.MaxFiles,
+		MaxExpiryDays = EXCLUDED.MaxExpiryDays, MaxDownloads = EXCLUDED.MaxDownloads`,
+		quota.UserId, quota.MaxStorageMb, quota.MaxFiles, quota.MaxExpiryDays, quota.MaxDownloads)
+	helper.Check(err)
+}
+
+// DeleteUserQuota deletes the quota of a user
+func (p DatabaseProvider) DeleteUserQuota(userId int) {
+	_, err := p.postgresDb.Exec("DELETE FROM UserQuotas WHERE UserId = $1", userId)
+	helper.Check(err)
+}
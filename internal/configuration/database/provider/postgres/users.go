@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+type schemaUser struct {
+	Id            int
+	Name          string
+	Password      sql.NullString
+	Permissions   int64
+	UserLevel     int
+	LastOnline    int64
+	ResetPassword bool
+}
+
+func (s schemaUser) ToUser() models.User {
+	pw := ""
+	if s.Password.Valid {
+		pw = s.Password.String
+	}
+	return models.User{
+		Id:            s.Id,
+		Name:          s.Name,
+		Permissions:   models.UserPermission(s.Permissions),
+		UserLevel:     models.UserRank(s.UserLevel),
+		LastOnline:    s.LastOnline,
+		Password:      pw,
+		ResetPassword: s.ResetPassword,
+	}
+}
+
+const selectUsers = "SELECT Id, Name, Password, Permissions, Userlevel, LastOnline, ResetPassword FROM Users"
+
+// GetAllUsers returns a map with all users
+func (p DatabaseProvider) GetAllUsers() []models.User {
+	var result []models.User
+	rows, err := p.postgresDb.Query(selectUsers + " ORDER BY Userlevel, LastOnline DESC, Name")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		row := schemaUser{}
+		err = rows.Scan(&row.Id, &row.Name, &row.Password, &row.Permissions, &row.UserLevel, &row.LastOnline, &row.ResetPassword)
+		helper.Check(err)
+		result = append(result, row.ToUser())
+	}
+	return result
+}
+
+func (p DatabaseProvider) getUserWithConstraint(isName bool, searchValue any) (models.User, bool) {
+	rowResult := schemaUser{}
+	query := selectUsers + " WHERE Id = $1"
+	if isName {
+		query = selectUsers + " WHERE Name = $1"
+	}
+	row := p.postgresDb.QueryRow(query, searchValue)
+	err := row.Scan(&rowResult.Id, &rowResult.Name, &rowResult.Password, &rowResult.Permissions, &rowResult.UserLevel, &rowResult.LastOnline, &rowResult.ResetPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, false
+		}
+		helper.Check(err)
+		return models.User{}, false
+	}
+	return rowResult.ToUser(), true
+}
+
+// GetUser returns a models.User if valid or false if the ID is not valid
+func (p DatabaseProvider) GetUser(id int) (models.User, bool) {
+	return p.getUserWithConstraint(false, id)
+}
+
+// GetUserByName returns a models.User if valid or false if the name is not valid
+func (p DatabaseProvider) GetUserByName(username string) (models.User, bool) {
+	return p.getUserWithConstraint(true, username)
+}
+
+// SaveUser saves a user to the database. If isNewUser is true, a new Id will be generated
+func (p DatabaseProvider) SaveUser(user models.User, isNewUser bool) {
+	if isNewUser {
+		_, err := p.postgresDb.Exec("INSERT INTO Users (Name, Password, Permissions, Userlevel, LastOnline, ResetPassword) VALUES ($1, $2, $3, $4, $5, $6)",
+			user.Name, user.Password, int64(user.Permissions), int(user.UserLevel), user.LastOnline, user.ResetPassword)
+		helper.Check(err)
+		return
+	}
+	_, err := p.postgresDb.Exec(`INSERT INTO Users (Id, Name, Password, Permissions, Userlevel, LastOnline, ResetPassword)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (Id) DO UPDATE SET Name = EXCLUDED.Name, Password = EXCLUDED.Password, Permissions = EXCLUDED.Permissions,
+			Userlevel = EXCLUDED.Userlevel, LastOnline = EXCLUDED.LastOnline, ResetPassword = EXCLUDED.ResetPassword`,
+		user.Id, user.Name, user.Password, int64(user.Permissions), int(user.UserLevel), user.LastOnline, user.ResetPassword)
+	helper.Check(err)
+	// As the ID was set manually, the identity sequence needs to be adjusted to not hand out this ID again
+	_, err = p.postgresDb.Exec("SELECT setval(pg_get_serial_sequence('users', 'id'), GREATEST((SELECT MAX(Id) FROM Users), 1))")
+	helper.Check(err)
+}
+
+// UpdateUserLastOnline writes the last online time to the database
+func (p DatabaseProvider) UpdateUserLastOnline(id int) {
+	_, err := p.postgresDb.Exec("UPDATE Users SET LastOnline = $1 WHERE Id = $2", time.Now().Unix(), id)
+	helper.Check(err)
+}
+
+// DeleteUser deletes a user with the given ID
+func (p DatabaseProvider) DeleteUser(id int) {
+	_, err := p.postgresDb.Exec("DELETE FROM Users WHERE Id = $1", id)
+	helper.Check(err)
+}
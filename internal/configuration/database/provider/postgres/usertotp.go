@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// GetUserTotp returns the TOTP settings of a user or false if not found
+func (p DatabaseProvider) GetUserTotp(userId int) (models.UserTotp, bool) {
+	var result models.UserTotp
+	row := p.postgresDb.QueryRow("SELECT UserId, Secret, RecoveryCodes, IsConfirmed, LastUsedStep FROM UserTotp WHERE UserId = $1", userId)
+	err := row.Scan(&result.UserId, &result.Secret, &result.RecoveryCodes, &result.IsConfirmed, &result.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserTotp{}, false
+		}
+		helper.Check(err)
+		return models.UserTotp{}, false
+	}
+	return result, true
+}
+
+// SaveUserTotp stores the TOTP settings of a user
+func (p DatabaseProvider) SaveUserTotp(totp models.UserTotp) {
+	_, err := p.postgresDb.Exec(`INSERT INTO UserTotp (UserId, Secret, RecoveryCodes, IsConfirmed, LastUsedStep)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (UserId) DO UPDATE SET Secret = EXCLUDED.Secret, RecoveryCodes = EXCLUDED.RecoveryCodes,
+		IsConfirmed = EXCLUDED.IsConfirmed, LastUsedStep = EXCLUDED.LastUsedStep`,
+		totp.UserId, totp.Secret, totp.RecoveryCodes, totp.IsConfirmed, totp.LastUsedStep)
+	helper.Check(err)
+}
+
+// DeleteUserTotp deletes the TOTP settings of a user
+func (p DatabaseProvider) DeleteUserTotp(userId int) {
+	_, err := p.postgresDb.Exec("DELETE FROM UserTotp WHERE UserId = $1", userId)
+	helper.Check(err)
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectWebhooks = "SELECT Id, Url, Secret, Events, UserId, Creation FROM Webhooks"
+const selectWebhookDeliveries = "SELECT Id, WebhookId, Event, Payload, Attempts, NextAttempt, LastError FROM WebhookDeliveries"
+
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var result models.Webhook
+	err := row.Scan(&result.Id, &result.Url, &result.Secret, &result.Events, &result.UserId, &result.CreationDate)
+	return result, err
+}
+
+// GetWebhook returns the webhook or false if not found
+func (p DatabaseProvider) GetWebhook(id string) (models.Webhook, bool) {
+	result, err := scanWebhook(p.postgresDb.QueryRow(selectWebhooks+" WHERE Id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Webhook{}, false
+		}
+		helper.Check(err)
+		return models.Webhook{}, false
+	}
+	return result, true
+}
+
+// GetAllWebhooks returns an array with all webhooks, ordered by creation date
+func (p DatabaseProvider) GetAllWebhooks() []models.Webhook {
+	result := make([]models.Webhook, 0)
+	rows, err := p.postgresDb.Query(selectWebhooks + " ORDER BY Creation, Id")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		helper.Check(err)
+		result = append(result, webhook)
+	}
+	return result
+}
+
+// SaveWebhook stores the webhook in the database
+func (p DatabaseProvider) SaveWebhook(webhook models.Webhook) {
+	_, err := p.postgresDb.Exec(`INSERT INTO Webhooks (Id, Url, Secret, Events, UserId, Creation)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (Id) DO UPDATE SET Url = EXCLUDED.Url, Secret = EXCLUDED.Secret, Events = EXCLUDED.Events,
+			UserId = EXCLUDED.UserId, Creation = EXCLUDED.Creation`,
+		webhook.Id, webhook.Url, webhook.Secret, webhook.Events, webhook.UserId, webhook.CreationDate)
+	helper.Check(err)
+}
+
+// DeleteWebhook deletes the webhook with the given ID and all queued deliveries for it
+func (p DatabaseProvider) DeleteWebhook(id string) {
+	_, err := p.postgresDb.Exec("DELETE FROM WebhookDeliveries WHERE WebhookId = $1", id)
+	helper.Check(err)
+	_, err = p.postgresDb.Exec("DELETE FROM Webhooks WHERE Id = $1", id)
+	helper.Check(err)
+}
+
+// GetDueWebhookDeliveries returns up to limit queued deliveries, that are due at the given timestamp.
+// The deliveries are ordered by the time of their next attempt
+func (p DatabaseProvider) GetDueWebhookDeliveries(timestamp int64, limit int) []models.WebhookDelivery {
+	result := make([]models.WebhookDelivery, 0)
+	rows, err := p.postgresDb.Query(selectWebhookDeliveries+" WHERE NextAttempt <= $1 ORDER BY NextAttempt, Id LIMIT $2", timestamp, limit)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		err = rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.Event, &delivery.Payload, &delivery.Attempts,
+			&delivery.NextAttempt, &delivery.LastError)
+		helper.Check(err)
+		result = append(result, delivery)
+	}
+	return result
+}
+
+// SaveWebhookDelivery adds the delivery to the queue or updates it
+func (p DatabaseProvider) SaveWebhookDelivery(delivery models.WebhookDelivery) {
+	_, err := p.postgresDb.Exec(`INSERT INTO WebhookDeliveries (Id, WebhookId, Event, Payload, Attempts, NextAttempt, LastError)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (Id) DO UPDATE SET WebhookId = EXCLUDED.WebhookId, Event = EXCLUDED.Event, Payload = EXCLUDED.Payload,
+			Attempts = EXCLUDED.Attempts, NextAttempt = EXCLUDED.NextAttempt, LastError = EXCLUDED.LastError`,
+		delivery.Id, delivery.WebhookId, delivery.Event, delivery.Payload, delivery.Attempts, delivery.NextAttempt, delivery.LastError)
+	helper.Check(err)
+}
+
+// DeleteWebhookDelivery removes the delivery with the given ID from the queue
+func (p DatabaseProvider) DeleteWebhookDelivery(id string) {
+	_, err := p.postgresDb.Exec("DELETE FROM WebhookDeliveries WHERE Id = $1", id)
+	helper.Check(err)
+}
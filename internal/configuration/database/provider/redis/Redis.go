@@ -21,7 +21,7 @@ type DatabaseProvider struct {
 }
 
 // DatabaseSchemeVersion contains the version number to be expected from the current database. If lower, an upgrade will be performed
-const DatabaseSchemeVersion = 8
+const DatabaseSchemeVersion = 9
 
 // New returns an instance
 func New(dbConfig models.DbConnection) (DatabaseProvider, error) {
@@ -162,6 +162,12 @@ func (p DatabaseProvider) Upgrade(currentDbVersion int) {
 	if currentDbVersion < 8 {
 		p.DeleteAllSessions()
 	}
+	// < v2.3.0
+	if currentDbVersion < 9 {
+		for _, file := range p.GetAllMetadata() {
+			p.updateFileIndex(nil, &file)
+		}
+	}
 }
 
 const keyDbVersion = "dbversion"
@@ -355,11 +361,12 @@ func (p DatabaseProvider) deleteKey(id string) {
 	helper.Check(err)
 }
 
-func (p DatabaseProvider) increaseHashmapIntField(id string, field string) {
+func (p DatabaseProvider) increaseHashmapIntField(id string, field string) int {
 	conn := p.pool.Get()
 	defer conn.Close()
-	_, err := conn.Do("HINCRBY", p.dbPrefix+id, field, 1)
+	result, err := redigo.Int(conn.Do("HINCRBY", p.dbPrefix+id, field, 1))
 	helper.Check(err)
+	return result
 }
 
 func (p DatabaseProvider) decreaseHashmapIntField(id string, field string) {
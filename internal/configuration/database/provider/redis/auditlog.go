@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"strconv"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	prefixAuditEvents = "audit:"
+	keyAuditIndex     = "auditindex"
+	keyAuditCounter   = "auditcounter"
+	auditBatchSize    = 100
+)
+
+func dbToAuditEvent(input []any) (models.AuditEvent, error) {
+	var result models.AuditEvent
+	err := redigo.ScanStruct(input, &result)
+	return result, err
+}
+
+// SaveAuditEvent stores a new audit event. The ID of the event is assigned by the database
+func (p DatabaseProvider) SaveAuditEvent(event models.AuditEvent) {
+	conn := p.pool.Get()
+	defer conn.Close()
+	id, err := redigo.Int64(conn.Do("INCR", p.dbPrefix+keyAuditCounter))
+	helper.Check(err)
+	event.Id = id
+	p.setHashMap(p.buildArgs(prefixAuditEvents + strconv.FormatInt(id, 10)).AddFlat(event))
+	_, err = conn.Do("ZADD", p.dbPrefix+keyAuditIndex, id, id)
+	helper.Check(err)
+}
+
+// QueryAuditEvents returns all audit events matching the given query, latest first
+func (p DatabaseProvider) QueryAuditEvents(query models.AuditQuery) models.AuditQueryResult {
+	result := models.AuditQueryResult{Events: make([]models.AuditEvent, 0)}
+	conn := p.pool.Get()
+	defer conn.Close()
+	maxScore := "+inf"
+	if query.BeforeId != 0 {
+		maxScore = "(" + strconv.FormatInt(query.BeforeId, 10)
+	}
+	offset := 0
+	for {
+		ids, err := redigo.Strings(conn.Do("ZREVRANGEBYSCORE", p.dbPrefix+keyAuditIndex, maxScore, "-inf", "LIMIT", offset, auditBatchSize))
+		helper.Check(err)
+		for _, id := range ids {
+			values, ok := p.getHashMap(prefixAuditEvents + id)
+			if !ok {
+				continue
+			}
+			event, err := dbToAuditEvent(values)
+			helper.Check(err)
+			if !query.Matches(event) {
+				continue
+			}
+			if query.Limit > 0 && len(result.Events) == query.Limit {
+				result.HasMore = true
+				return result
+			}
+			result.Events = append(result.Events, event)
+		}
+		if len(ids) < auditBatchSize {
+			return result
+		}
+		offset = offset + auditBatchSize
+	}
+}
+
+// DeleteAuditEventsBefore deletes all audit events that were created before the given timestamp.
+// As the IDs increase with time, the events are read in ascending order until the first event is found
+// that was created at or after the timestamp
+func (p DatabaseProvider) DeleteAuditEventsBefore(timestamp int64) {
+	conn := p.pool.Get()
+	defer conn.Close()
+	for {
+		// Deleted events are removed from the index, therefore the next batch always starts at the beginning
+		ids, err := redigo.Strings(conn.Do("ZRANGE", p.dbPrefix+keyAuditIndex, 0, auditBatchSize-1))
+		helper.Check(err)
+		for _, id := range ids {
+			values, ok := p.getHashMap(prefixAuditEvents + id)
+			if ok {
+				event, err := dbToAuditEvent(values)
+				helper.Check(err)
+				if event.Timestamp >= timestamp {
+					return
+				}
+				p.deleteKey(prefixAuditEvents + id)
+			}
+			_, err = conn.Do("ZREM", p.dbPrefix+keyAuditIndex, id)
+			helper.Check(err)
+		}
+		if len(ids) < auditBatchSize {
+			return
+		}
+	}
+}
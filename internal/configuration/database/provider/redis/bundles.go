@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	prefixBundles = "bu:"
+)
+
+func dbToBundle(input []any) (models.Bundle, error) {
+	var result models.Bundle
+	err := redigo.ScanStruct(input, &result)
+	return result, err
+}
+
+// GetBundle returns the bundle or false if not found
+func (p DatabaseProvider) GetBundle(id string) (models.Bundle, bool) {
+	result, ok := p.getHashMap(prefixBundles + id)
+	if !ok {
+		return models.Bundle{}, false
+	}
+	bundle, err := dbToBundle(result)
+	helper.Check(err)
+	return bundle, true
+}
+
+// GetAllBundles returns an array with all bundles, ordered by creation date
+func (p DatabaseProvider) GetAllBundles() []models.Bundle {
+	result := make([]models.Bundle, 0)
+	for _, v := range p.getAllHashesWithPrefix(prefixBundles) {
+		bundle, err := dbToBundle(v)
+		helper.Check(err)
+		result = append(result, bundle)
+	}
+	slices.SortFunc(result, func(a, b models.Bundle) int {
+		return cmp.Or(
+			cmp.Compare(a.CreationDate, b.CreationDate),
+			cmp.Compare(a.Id, b.Id),
+		)
+	})
+	return result
+}
+
+// SaveBundle stores the bundle in the database
+func (p DatabaseProvider) SaveBundle(bundle models.Bundle) {
+	p.setHashMap(p.buildArgs(prefixBundles + bundle.Id).AddFlat(bundle))
+}
+
+// IncreaseBundleDownloadCount increases the download count of a bundle, preventing race conditions
+func (p DatabaseProvider) IncreaseBundleDownloadCount(id string, decreaseRemainingDownloads bool) {
+	if decreaseRemainingDownloads {
+		p.decreaseHashmapIntField(prefixBundles+id, "downloadsremaining")
+	}
+	p.increaseHashmapIntField(prefixBundles+id, "downloadcount")
+}
+
+// DeleteBundle deletes the bundle with the given ID
+func (p DatabaseProvider) DeleteBundle(id string) {
+	p.deleteKey(prefixBundles + id)
+}
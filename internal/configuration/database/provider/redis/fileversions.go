@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"slices"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	prefixFileVersions = "fver:"
+)
+
+func dbToFileVersion(input []any) (models.FileVersion, error) {
+	var result models.FileVersion
+	err := redigo.ScanStruct(input, &result)
+	if err != nil || result.InternalRedisEncryption == nil {
+		return result, err
+	}
+	dec := gob.NewDecoder(bytes.NewBuffer(result.InternalRedisEncryption))
+	err = dec.Decode(&result.Encryption)
+	result.InternalRedisEncryption = nil
+	return result, err
+}
+
+// GetFileVersion returns the file version or false if not found
+func (p DatabaseProvider) GetFileVersion(id string) (models.FileVersion, bool) {
+	result, ok := p.getHashMap(prefixFileVersions + id)
+	if !ok {
+		return models.FileVersion{}, false
+	}
+	version, err := dbToFileVersion(result)
+	helper.Check(err)
+	return version, true
+}
+
+// GetFileVersions returns all versions of the given file, latest first
+func (p DatabaseProvider) GetFileVersions(fileId string) []models.FileVersion {
+	result := make([]models.FileVersion, 0)
+	for _, version := range p.GetAllFileVersions() {
+		if version.FileId == fileId {
+			result = append(result, version)
+		}
+	}
+	slices.SortFunc(result, func(a, b models.FileVersion) int {
+		return cmp.Compare(b.Revision, a.Revision)
+	})
+	return result
+}
+
+// GetAllFileVersions returns an array with all file versions, ordered by replacement date
+func (p DatabaseProvider) GetAllFileVersions() []models.FileVersion {
+	result := make([]models.FileVersion, 0)
+	for _, v := range p.getAllHashesWithPrefix(prefixFileVersions) {
+		version, err := dbToFileVersion(v)
+		helper.Check(err)
+		result = append(result, version)
+	}
+	slices.SortFunc(result, func(a, b models.FileVersion) int {
+		return cmp.Or(
+			cmp.Compare(a.ReplacedAt, b.ReplacedAt),
+			cmp.Compare(a.Id, b.Id),
+		)
+	})
+	return result
+}
+
+// SaveFileVersion stores the file version in the database
+func (p DatabaseProvider) SaveFileVersion(version models.FileVersion) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(version.Encryption)
+	helper.Check(err)
+	version.InternalRedisEncryption = buf.Bytes()
+	p.setHashMap(p.buildArgs(prefixFileVersions + version.Id).AddFlat(version))
+}
+
+// DeleteFileVersion deletes the file version with the given ID
+func (p DatabaseProvider) DeleteFileVersion(id string) {
+	p.deleteKey(prefixFileVersions + id)
+}
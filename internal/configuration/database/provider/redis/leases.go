@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	prefixLease = "lease:"
+)
+
+// scriptAcquireLease stores the lease in the hash KEYS[1], if it does not exist or is held by the same owner.
+// The key expires together with the lease
+var scriptAcquireLease = redigo.NewScript(1, `
+local owner = redis.call('HGET', KEYS[1], 'owner')
+if owner and owner ~= ARGV[2] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'name', ARGV[1], 'owner', ARGV[2], 'expiresat', ARGV[3])
+redis.call('EXPIREAT', KEYS[1], tonumber(ARGV[3]) + 1)
+return 1`)
+
+// scriptReleaseLease deletes the lease KEYS[1], if it is held by the owner ARGV[1]
+var scriptReleaseLease = redigo.NewScript(1, `
+if redis.call('HGET', KEYS[1], 'owner') == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+
+// TryAcquireLease acquires the lease or renews it, if it is already held by the same owner.
+// Returns true, if the lease is held by the owner afterwards
+func (p DatabaseProvider) TryAcquireLease(lease models.Lease) bool {
+	conn := p.pool.Get()
+	defer conn.Close()
+	result, err := redigo.Int(scriptAcquireLease.Do(conn, p.dbPrefix+prefixLease+lease.Name, lease.Name, lease.Owner, lease.ExpiresAt))
+	helper.Check(err)
+	return result == 1
+}
+
+// GetLease returns the lease with the given name or false if it does not exist
+func (p DatabaseProvider) GetLease(name string) (models.Lease, bool) {
+	var result models.Lease
+	values, ok := p.getHashMap(prefixLease + name)
+	if !ok {
+		return models.Lease{}, false
+	}
+	err := redigo.ScanStruct(values, &result)
+	helper.Check(err)
+	return result, true
+}
+
+// ReleaseLease deletes the lease with the given name, if it is held by the owner
+func (p DatabaseProvider) ReleaseLease(name, owner string) {
+	conn := p.pool.Get()
+	defer conn.Close()
+	_, err := scriptReleaseLease.Do(conn, p.dbPrefix+prefixLease+name, owner)
+	helper.Check(err)
+}
@@ -3,16 +3,22 @@ package redis
 import (
 	"bytes"
 	"encoding/gob"
+	"fmt"
+	"strings"
+
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
 	redigo "github.com/gomodule/redigo/redis"
-	"strings"
 )
 
 const (
-	prefixMetaData = "fmeta:"
+	prefixMetaData  = "fmeta:"
+	prefixFileIndex = "fidx:"
 )
 
+// queryBatchSize is the number of files that are read at once from a sorted index, when running a query
+const queryBatchSize = 100
+
 // GetAllMetadata returns a map of all available files
 func (p DatabaseProvider) GetAllMetadata() map[string]models.File {
 	result := make(map[string]models.File)
@@ -78,12 +84,22 @@ func (p DatabaseProvider) GetMetaDataById(id string) (models.File, bool) {
 func (p DatabaseProvider) SaveMetaData(file models.File) {
 	marshalledFile, err := marshalEncryptionInfo(file)
 	helper.Check(err)
+	oldFile, exists := p.GetMetaDataById(file.Id)
 	p.setHashMap(p.buildArgs(prefixMetaData + file.Id).AddFlat(marshalledFile))
+	if exists {
+		p.updateFileIndex(&oldFile, &file)
+	} else {
+		p.updateFileIndex(nil, &file)
+	}
 }
 
 // DeleteMetaData deletes information about a file
 func (p DatabaseProvider) DeleteMetaData(id string) {
+	oldFile, exists := p.GetMetaDataById(id)
 	p.deleteKey(prefixMetaData + id)
+	if exists {
+		p.updateFileIndex(&oldFile, nil)
+	}
 }
 
 // IncreaseDownloadCount increases the download count of a file, preventing race conditions
@@ -91,5 +107,117 @@ func (p DatabaseProvider) IncreaseDownloadCount(id string, decreaseRemainingDown
 	if decreaseRemainingDownloads {
 		p.decreaseHashmapIntField(prefixMetaData+id, "DownloadsRemaining")
 	}
-	p.increaseHashmapIntField(prefixMetaData+id, "DownloadCount")
+	newCount := p.increaseHashmapIntField(prefixMetaData+id, "DownloadCount")
+	conn := p.pool.Get()
+	defer conn.Close()
+	key := p.dbPrefix + getIndexKey(models.FileSortDownloads)
+	_, err := conn.Do("ZREM", key, getNumericIndexMember(int64(newCount-1), id))
+	helper.Check(err)
+	_, err = conn.Do("ZADD", key, 0, getNumericIndexMember(int64(newCount), id))
+	helper.Check(err)
+}
+
+// QueryMetaData returns all files matching the given query. The files are read in batches from
+// a sorted index and filtered afterwards, until enough files have been found
+func (p DatabaseProvider) QueryMetaData(query models.FileQuery) models.FileQueryResult {
+	result := models.FileQueryResult{Files: make([]models.File, 0)}
+	command, start, end := "ZRANGEBYLEX", "-", "+"
+	if query.SortDescending {
+		command, start, end = "ZREVRANGEBYLEX", "+", "-"
+	}
+	if query.HasCursor() {
+		start = "(" + getIndexMember(query.SortBy, query.CursorValue, query.CursorName, query.CursorId)
+	}
+	key := p.dbPrefix + getIndexKey(query.SortBy)
+
+	conn := p.pool.Get()
+	defer conn.Close()
+	for {
+		members, err := redigo.Strings(conn.Do(command, key, start, end, "LIMIT", 0, queryBatchSize))
+		helper.Check(err)
+		for _, member := range members {
+			err = conn.Send("HGETALL", p.dbPrefix+prefixMetaData+getIdFromIndexMember(member))
+			helper.Check(err)
+		}
+		helper.Check(conn.Flush())
+		// All replies have to be received before returning, otherwise the connection cannot be reused
+		files := make([]models.File, 0, len(members))
+		for _, member := range members {
+			values, err := redigo.Values(conn.Receive())
+			helper.Check(err)
+			if len(values) == 0 {
+				continue
+			}
+			file, err := dbToMetadata(getIdFromIndexMember(member), values)
+			helper.Check(err)
+			files = append(files, file)
+		}
+		for _, file := range files {
+			if !query.Matches(file) {
+				continue
+			}
+			if query.Limit > 0 && len(result.Files) == query.Limit {
+				result.HasMore = true
+				return result
+			}
+			result.Files = append(result.Files, file)
+		}
+		if len(members) < queryBatchSize {
+			return result
+		}
+		start = "(" + members[len(members)-1]
+	}
+}
+
+// updateFileIndex updates the sorted sets that are used for querying files. All members are stored with
+// the same score, so that they are sorted lexicographically by the sort value and then by the file ID.
+// oldFile is removed from the index and newFile added, either can be nil
+func (p DatabaseProvider) updateFileIndex(oldFile, newFile *models.File) {
+	conn := p.pool.Get()
+	defer conn.Close()
+	for _, sortBy := range []int{models.FileSortUploadDate, models.FileSortName, models.FileSortSize,
+		models.FileSortExpiry, models.FileSortDownloads} {
+		key := p.dbPrefix + getIndexKey(sortBy)
+		query := models.FileQuery{SortBy: sortBy}
+		if oldFile != nil {
+			_, err := conn.Do("ZREM", key, getIndexMember(sortBy, query.SortValue(*oldFile), oldFile.Name, oldFile.Id))
+			helper.Check(err)
+		}
+		if newFile != nil {
+			_, err := conn.Do("ZADD", key, 0, getIndexMember(sortBy, query.SortValue(*newFile), newFile.Name, newFile.Id))
+			helper.Check(err)
+		}
+	}
+}
+
+func getIndexKey(sortBy int) string {
+	switch sortBy {
+	case models.FileSortName:
+		return prefixFileIndex + "name"
+	case models.FileSortSize:
+		return prefixFileIndex + "size"
+	case models.FileSortExpiry:
+		return prefixFileIndex + "expiry"
+	case models.FileSortDownloads:
+		return prefixFileIndex + "downloads"
+	default:
+		return prefixFileIndex + "uploaddate"
+	}
+}
+
+func getIndexMember(sortBy int, value int64, name, id string) string {
+	if sortBy == models.FileSortName {
+		return name + "\x00" + id
+	}
+	return getNumericIndexMember(value, id)
+}
+
+// getNumericIndexMember flips the sign bit and pads the value, so that the lexicographical order
+// of the member is the same as the numerical order of the value
+func getNumericIndexMember(value int64, id string) string {
+	return fmt.Sprintf("%020d", uint64(value)^(1<<63)) + "\x00" + id
+}
+
+func getIdFromIndexMember(member string) string {
+	return member[strings.LastIndex(member, "\x00")+1:]
 }
@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	prefixShareLinks = "sl:"
+)
+
+func dbToShareLink(input []any) (models.ShareLink, error) {
+	var result models.ShareLink
+	err := redigo.ScanStruct(input, &result)
+	return result, err
+}
+
+// GetShareLink returns the share link or false if not found
+func (p DatabaseProvider) GetShareLink(id string) (models.ShareLink, bool) {
+	result, ok := p.getHashMap(prefixShareLinks + id)
+	if !ok {
+		return models.ShareLink{}, false
+	}
+	link, err := dbToShareLink(result)
+	helper.Check(err)
+	return link, true
+}
+
+// GetAllShareLinks returns an array with all share links, ordered by creation date
+func (p DatabaseProvider) GetAllShareLinks() []models.ShareLink {
+	result := make([]models.ShareLink, 0)
+	for _, v := range p.getAllHashesWithPrefix(prefixShareLinks) {
+		link, err := dbToShareLink(v)
+		helper.Check(err)
+		result = append(result, link)
+	}
+	slices.SortFunc(result, func(a, b models.ShareLink) int {
+		return cmp.Or(
+			cmp.Compare(a.CreationDate, b.CreationDate),
+			cmp.Compare(a.Id, b.Id),
+		)
+	})
+	return result
+}
+
+// SaveShareLink stores the share link in the database
+func (p DatabaseProvider) SaveShareLink(link models.ShareLink) {
+	p.setHashMap(p.buildArgs(prefixShareLinks + link.Id).AddFlat(link))
+}
+
+// IncreaseShareLinkDownloadCount increases the download count of a share link, preventing race conditions
+func (p DatabaseProvider) IncreaseShareLinkDownloadCount(id string, decreaseRemainingDownloads bool) {
+	if decreaseRemainingDownloads {
+		p.decreaseHashmapIntField(prefixShareLinks+id, "downloadsremaining")
+	}
+	p.increaseHashmapIntField(prefixShareLinks+id, "downloadcount")
+}
+
+// DeleteShareLink deletes the share link with the given ID
+func (p DatabaseProvider) DeleteShareLink(id string) {
+	p.deleteKey(prefixShareLinks + id)
+}
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"strconv"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	prefixUserQuota = "quota:"
+)
+
+// GetUserQuota returns the quota of a user or false if no quota has been set
+func (p DatabaseProvider) GetUserQuota(userId int) (models.UserQuota, bool) {
+	var result models.UserQuota
+	values, ok := p.getHashMap(prefixUserQuota + strconv.Itoa(userId))
+	if !ok {
+		return models.UserQuota{}, false
+	}
+	err := redigo.ScanStruct(values, &result)
+	helper.Check(err)
+	return result, true
+}
+
+// SaveUserQuota stores the quota of a user
+func (p DatabaseProvider) SaveUserQuota(quota models.UserQuota) {
+	p.setHashMap(p.buildArgs(prefixUserQuota + strconv.Itoa(quota.UserId)).AddFlat(quota))
+}
+
+// DeleteUserQuota deletes the quota of a user
+func (p DatabaseProvider) DeleteUserQuota(userId int) {
+	p.deleteKey(prefixUserQuota + strconv.Itoa(userId))
+}
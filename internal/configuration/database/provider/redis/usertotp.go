@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"strconv"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	prefixUserTotp = "totp:"
+)
+
+// GetUserTotp returns the TOTP settings of a user or false if not found
+func (p DatabaseProvider) GetUserTotp(userId int) (models.UserTotp, bool) {
+	var result models.UserTotp
+	values, ok := p.getHashMap(prefixUserTotp + strconv.Itoa(userId))
+	if !ok {
+		return models.UserTotp{}, false
+	}
+	err := redigo.ScanStruct(values, &result)
+	helper.Check(err)
+	return result, true
+}
+
+// SaveUserTotp stores the TOTP settings of a user
+func (p DatabaseProvider) SaveUserTotp(totp models.UserTotp) {
+	p.setHashMap(p.buildArgs(prefixUserTotp + strconv.Itoa(totp.UserId)).AddFlat(totp))
+}
+
+// DeleteUserTotp deletes the TOTP settings of a user
+func (p DatabaseProvider) DeleteUserTotp(userId int) {
+	p.deleteKey(prefixUserTotp + strconv.Itoa(userId))
+}
@@ -0,0 +1,113 @@
+package redis
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	prefixWebhooks          = "wh:"
+	prefixWebhookDeliveries = "whd:"
+	keyWebhookQueue         = "whqueue"
+)
+
+func dbToWebhook(input []any) (models.Webhook, error) {
+	var result models.Webhook
+	err := redigo.ScanStruct(input, &result)
+	return result, err
+}
+
+func dbToWebhookDelivery(input []any) (models.WebhookDelivery, error) {
+	var result models.WebhookDelivery
+	err := redigo.ScanStruct(input, &result)
+	return result, err
+}
+
+// GetWebhook returns the webhook or false if not found
+func (p DatabaseProvider) GetWebhook(id string) (models.Webhook, bool) {
+	result, ok := p.getHashMap(prefixWebhooks + id)
+	if !ok {
+		return models.Webhook{}, false
+	}
+	webhook, err := dbToWebhook(result)
+	helper.Check(err)
+	return webhook, true
+}
+
+// GetAllWebhooks returns an array with all webhooks, ordered by creation date
+func (p DatabaseProvider) GetAllWebhooks() []models.Webhook {
+	result := make([]models.Webhook, 0)
+	for _, v := range p.getAllHashesWithPrefix(prefixWebhooks) {
+		webhook, err := dbToWebhook(v)
+		helper.Check(err)
+		result = append(result, webhook)
+	}
+	slices.SortFunc(result, func(a, b models.Webhook) int {
+		return cmp.Or(
+			cmp.Compare(a.CreationDate, b.CreationDate),
+			cmp.Compare(a.Id, b.Id),
+		)
+	})
+	return result
+}
+
+// SaveWebhook stores the webhook in the database
+func (p DatabaseProvider) SaveWebhook(webhook models.Webhook) {
+	p.setHashMap(p.buildArgs(prefixWebhooks + webhook.Id).AddFlat(webhook))
+}
+
+// DeleteWebhook deletes the webhook with the given ID and all queued deliveries for it
+func (p DatabaseProvider) DeleteWebhook(id string) {
+	for _, v := range p.getAllHashesWithPrefix(prefixWebhookDeliveries) {
+		delivery, err := dbToWebhookDelivery(v)
+		helper.Check(err)
+		if delivery.WebhookId == id {
+			p.DeleteWebhookDelivery(delivery.Id)
+		}
+	}
+	p.deleteKey(prefixWebhooks + id)
+}
+
+// GetDueWebhookDeliveries returns up to limit queued deliveries, that are due at the given timestamp.
+// The deliveries are ordered by the time of their next attempt
+func (p DatabaseProvider) GetDueWebhookDeliveries(timestamp int64, limit int) []models.WebhookDelivery {
+	result := make([]models.WebhookDelivery, 0)
+	conn := p.pool.Get()
+	defer conn.Close()
+	ids, err := redigo.Strings(conn.Do("ZRANGEBYSCORE", p.dbPrefix+keyWebhookQueue, "-inf", timestamp, "LIMIT", 0, limit))
+	helper.Check(err)
+	for _, id := range ids {
+		values, ok := p.getHashMap(prefixWebhookDeliveries + id)
+		if !ok {
+			_, err = conn.Do("ZREM", p.dbPrefix+keyWebhookQueue, id)
+			helper.Check(err)
+			continue
+		}
+		delivery, err := dbToWebhookDelivery(values)
+		helper.Check(err)
+		result = append(result, delivery)
+	}
+	return result
+}
+
+// SaveWebhookDelivery adds the delivery to the queue or updates it
+func (p DatabaseProvider) SaveWebhookDelivery(delivery models.WebhookDelivery) {
+	p.setHashMap(p.buildArgs(prefixWebhookDeliveries + delivery.Id).AddFlat(delivery))
+	conn := p.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("ZADD", p.dbPrefix+keyWebhookQueue, delivery.NextAttempt, delivery.Id)
+	helper.Check(err)
+}
+
+// DeleteWebhookDelivery removes the delivery with the given ID from the queue
+func (p DatabaseProvider) DeleteWebhookDelivery(id string) {
+	p.deleteKey(prefixWebhookDeliveries + id)
+	conn := p.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("ZREM", p.dbPrefix+keyWebhookQueue, id)
+	helper.Check(err)
+}
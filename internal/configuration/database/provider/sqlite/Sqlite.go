@@ -22,7 +22,7 @@ type DatabaseProvider struct {
 }
 
 // DatabaseSchemeVersion contains the version number to be expected from the current database. If lower, an upgrade will be performed
-const DatabaseSchemeVersion = 15
+const DatabaseSchemeVersion = 27
 
 // New returns an instance
 func New(dbConfig models.DbConnection) (DatabaseProvider, error) {
@@ -42,6 +42,34 @@ func (p DatabaseProvider) Upgrade(currentDbVersion int) {
 		osExit(1)
 		return
 	}
+	// < v2.3.0
+	// Added before all other upgrades, as some of them read and save the metadata with the current schema
+	if currentDbVersion < 16 {
+		err := p.rawSqlite(`ALTER TABLE FileMetaData ADD COLUMN "IsEncrypted" INTEGER NOT NULL DEFAULT 0;
+		CREATE INDEX "idx_FileMetaData_UploadDate" ON "FileMetaData" ("UploadDate", "Id");`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	// Added before all other upgrades, as some of them read and save the metadata with the current schema
+	if currentDbVersion < 24 {
+		err := p.rawSqlite(`ALTER TABLE FileMetaData ADD COLUMN "ProcessingState" INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE FileMetaData ADD COLUMN "QuarantineReason" TEXT NOT NULL DEFAULT '';`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	// Added before all other upgrades, as some of them read and save the metadata with the current schema
+	if currentDbVersion < 26 {
+		err := p.rawSqlite(`ALTER TABLE FileMetaData ADD COLUMN "PublishAt" INTEGER NOT NULL DEFAULT 0;`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	// Added before all other upgrades, as some of them read and save the metadata with the current schema
+	if currentDbVersion < 27 {
+		err := p.rawSqlite(`ALTER TABLE FileMetaData ADD COLUMN "RequireLogin" INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE FileMetaData ADD COLUMN "AllowedUsers" TEXT NOT NULL DEFAULT '';
+		ALTER TABLE FileMetaData ADD COLUMN "AllowedGroups" TEXT NOT NULL DEFAULT '';`)
+		helper.Check(err)
+	}
 	// < v2.2.0-dev
 	if currentDbVersion < 11 {
 		err := p.rawSqlite("ALTER TABLE FileMetaData DROP COLUMN ExpireAtString;")
@@ -114,6 +142,157 @@ func (p DatabaseProvider) Upgrade(currentDbVersion int) {
 	if currentDbVersion < 15 {
 		p.DeleteAllSessions()
 	}
+	// < v2.3.0
+	if currentDbVersion < 16 {
+		// Encryption is stored as a blob, therefore the new column has to be set for every file
+		for _, file := range p.GetAllMetadata() {
+			if file.Encryption.IsEncrypted {
+				p.SaveMetaData(file)
+			}
+		}
+	}
+	// < v2.3.0
+	if currentDbVersion < 17 {
+		err := p.rawSqlite(`CREATE TABLE "Webhooks" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"Url"	TEXT NOT NULL,
+			"Secret"	TEXT NOT NULL,
+			"Events"	TEXT NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"Creation"	INTEGER NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE TABLE "WebhookDeliveries" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"WebhookId"	TEXT NOT NULL,
+			"Event"	TEXT NOT NULL,
+			"Payload"	TEXT NOT NULL,
+			"Attempts"	INTEGER NOT NULL,
+			"NextAttempt"	INTEGER NOT NULL,
+			"LastError"	TEXT NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE INDEX "idx_WebhookDeliveries_NextAttempt" ON "WebhookDeliveries" ("NextAttempt");`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 18 {
+		err := p.rawSqlite(`CREATE TABLE "Bundles" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"Name"	TEXT NOT NULL,
+			"FileIds"	TEXT NOT NULL,
+			"PasswordHash"	TEXT NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"ExpireAt"	INTEGER NOT NULL,
+			"Creation"	INTEGER NOT NULL,
+			"DownloadsRemaining"	INTEGER NOT NULL,
+			"DownloadCount"	INTEGER NOT NULL,
+			"UnlimitedDownloads"	INTEGER NOT NULL,
+			"UnlimitedTime"	INTEGER NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 19 {
+		err := p.rawSqlite(`CREATE TABLE "ShareLinks" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"FileId"	TEXT NOT NULL,
+			"Name"	TEXT NOT NULL,
+			"PasswordHash"	TEXT NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"ExpireAt"	INTEGER NOT NULL,
+			"Creation"	INTEGER NOT NULL,
+			"DownloadsRemaining"	INTEGER NOT NULL,
+			"DownloadCount"	INTEGER NOT NULL,
+			"UnlimitedDownloads"	INTEGER NOT NULL,
+			"UnlimitedTime"	INTEGER NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE INDEX "idx_ShareLinks_FileId" ON "ShareLinks" ("FileId");`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 20 {
+		err := p.rawSqlite(`CREATE TABLE "UserTotp" (
+			"UserId"	INTEGER NOT NULL UNIQUE,
+			"Secret"	TEXT NOT NULL,
+			"RecoveryCodes"	TEXT NOT NULL,
+			"IsConfirmed"	INTEGER NOT NULL,
+			"LastUsedStep"	INTEGER NOT NULL,
+			PRIMARY KEY("UserId")
+		) WITHOUT ROWID;`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 21 {
+		err := p.rawSqlite(`CREATE TABLE "AuditLog" (
+			"Id"	INTEGER NOT NULL UNIQUE,
+			"Timestamp"	INTEGER NOT NULL,
+			"Action"	TEXT NOT NULL,
+			"Result"	TEXT NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"ApiKeyId"	TEXT NOT NULL,
+			"Ip"	TEXT NOT NULL,
+			"FileId"	TEXT NOT NULL,
+			"FileRequestId"	TEXT NOT NULL,
+			"Details"	TEXT NOT NULL,
+			PRIMARY KEY("Id" AUTOINCREMENT)
+		);
+		CREATE INDEX "idx_AuditLog_Timestamp" ON "AuditLog" ("Timestamp");`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 22 {
+		err := p.rawSqlite(`CREATE TABLE "UserQuotas" (
+			"UserId"	INTEGER NOT NULL UNIQUE,
+			"MaxStorageMb"	INTEGER NOT NULL,
+			"MaxFiles"	INTEGER NOT NULL,
+			"MaxExpiryDays"	INTEGER NOT NULL,
+			"MaxDownloads"	INTEGER NOT NULL,
+			PRIMARY KEY("UserId")
+		) WITHOUT ROWID;`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 23 {
+		err := p.rawSqlite(`CREATE TABLE "Leases" (
+			"Name"	TEXT NOT NULL UNIQUE,
+			"Owner"	TEXT NOT NULL,
+			"ExpiresAt"	INTEGER NOT NULL,
+			PRIMARY KEY("Name")
+		) WITHOUT ROWID;`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 25 {
+		err := p.rawSqlite(`CREATE TABLE "FileVersions" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"FileId"	TEXT NOT NULL,
+			"Revision"	INTEGER NOT NULL,
+			"Name"	TEXT NOT NULL,
+			"Size"	TEXT NOT NULL,
+			"SHA1"	TEXT NOT NULL,
+			"ContentType"	TEXT NOT NULL,
+			"AwsBucket"	TEXT NOT NULL,
+			"SizeBytes"	INTEGER NOT NULL,
+			"ReplacedAt"	INTEGER NOT NULL,
+			"ReplacedBy"	INTEGER NOT NULL,
+			"Encryption"	BLOB NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE INDEX "idx_FileVersions_FileId" ON "FileVersions" ("FileId");`)
+		helper.Check(err)
+	}
+	// < v2.3.0
+	if currentDbVersion < 27 {
+		err := p.rawSqlite(`ALTER TABLE UploadRequests ADD COLUMN "requireLogin" INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE UploadRequests ADD COLUMN "allowedUsers" TEXT NOT NULL DEFAULT '';
+		ALTER TABLE UploadRequests ADD COLUMN "allowedGroups" TEXT NOT NULL DEFAULT '';
+		ALTER TABLE Sessions ADD COLUMN "Groups" TEXT NOT NULL DEFAULT '';
+		ALTER TABLE Sessions ADD COLUMN "RecipientName" TEXT NOT NULL DEFAULT '';`)
+		helper.Check(err)
+	}
 }
 
 // GetDbVersion gets the version number of the database
@@ -225,8 +404,16 @@ func (p DatabaseProvider) createNewDatabase() error {
 			"UploadDate"	INTEGER NOT NULL,
 			"PendingDeletion"	INTEGER NOT NULL,
 			"UploadRequestId"	TEXT NOT NULL,
+			"IsEncrypted"	INTEGER NOT NULL DEFAULT 0,
+			"ProcessingState"	INTEGER NOT NULL DEFAULT 0,
+			"QuarantineReason"	TEXT NOT NULL DEFAULT '',
+			"PublishAt"	INTEGER NOT NULL DEFAULT 0,
+			"RequireLogin"	INTEGER NOT NULL DEFAULT 0,
+			"AllowedUsers"	TEXT NOT NULL DEFAULT '',
+			"AllowedGroups"	TEXT NOT NULL DEFAULT '',
 			PRIMARY KEY("Id")
 		);
+		CREATE INDEX "idx_FileMetaData_UploadDate" ON "FileMetaData" ("UploadDate", "Id");
 		CREATE TABLE "Hotlinks" (
 			"Id"	TEXT NOT NULL UNIQUE,
 			"FileId"	TEXT NOT NULL UNIQUE,
@@ -237,6 +424,8 @@ func (p DatabaseProvider) createNewDatabase() error {
 			"RenewAt"	INTEGER NOT NULL,
 			"ValidUntil"	INTEGER NOT NULL,
 			"UserId"	INTEGER NOT NULL,
+			"Groups"	TEXT NOT NULL DEFAULT '',
+			"RecipientName"	TEXT NOT NULL DEFAULT '',
 			PRIMARY KEY("Id")
 		) WITHOUT ROWID;
 		CREATE TABLE "Users" (
@@ -259,6 +448,9 @@ func (p DatabaseProvider) createNewDatabase() error {
 			"creation"	INTEGER NOT NULL,
 			"apiKey"	TEXT NOT NULL UNIQUE,
 			"note"	TEXT NOT NULL,
+			"requireLogin"	INTEGER NOT NULL DEFAULT 0,
+			"allowedUsers"	TEXT NOT NULL DEFAULT '',
+			"allowedGroups"	TEXT NOT NULL DEFAULT '',
 			PRIMARY KEY("id")
 		);
 		CREATE TABLE "Statistics" (
@@ -266,7 +458,108 @@ func (p DatabaseProvider) createNewDatabase() error {
 				"type"	INTEGER NOT NULL UNIQUE,
 				"value"	INTEGER,
 				PRIMARY KEY("id" AUTOINCREMENT)
-			);`
+			);
+		CREATE TABLE "Webhooks" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"Url"	TEXT NOT NULL,
+			"Secret"	TEXT NOT NULL,
+			"Events"	TEXT NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"Creation"	INTEGER NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE TABLE "WebhookDeliveries" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"WebhookId"	TEXT NOT NULL,
+			"Event"	TEXT NOT NULL,
+			"Payload"	TEXT NOT NULL,
+			"Attempts"	INTEGER NOT NULL,
+			"NextAttempt"	INTEGER NOT NULL,
+			"LastError"	TEXT NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE INDEX "idx_WebhookDeliveries_NextAttempt" ON "WebhookDeliveries" ("NextAttempt");
+		CREATE TABLE "Bundles" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"Name"	TEXT NOT NULL,
+			"FileIds"	TEXT NOT NULL,
+			"PasswordHash"	TEXT NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"ExpireAt"	INTEGER NOT NULL,
+			"Creation"	INTEGER NOT NULL,
+			"DownloadsRemaining"	INTEGER NOT NULL,
+			"DownloadCount"	INTEGER NOT NULL,
+			"UnlimitedDownloads"	INTEGER NOT NULL,
+			"UnlimitedTime"	INTEGER NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE TABLE "ShareLinks" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"FileId"	TEXT NOT NULL,
+			"Name"	TEXT NOT NULL,
+			"PasswordHash"	TEXT NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"ExpireAt"	INTEGER NOT NULL,
+			"Creation"	INTEGER NOT NULL,
+			"DownloadsRemaining"	INTEGER NOT NULL,
+			"DownloadCount"	INTEGER NOT NULL,
+			"UnlimitedDownloads"	INTEGER NOT NULL,
+			"UnlimitedTime"	INTEGER NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE INDEX "idx_ShareLinks_FileId" ON "ShareLinks" ("FileId");
+		CREATE TABLE "UserTotp" (
+			"UserId"	INTEGER NOT NULL UNIQUE,
+			"Secret"	TEXT NOT NULL,
+			"RecoveryCodes"	TEXT NOT NULL,
+			"IsConfirmed"	INTEGER NOT NULL,
+			"LastUsedStep"	INTEGER NOT NULL,
+			PRIMARY KEY("UserId")
+		) WITHOUT ROWID;
+		CREATE TABLE "AuditLog" (
+			"Id"	INTEGER NOT NULL UNIQUE,
+			"Timestamp"	INTEGER NOT NULL,
+			"Action"	TEXT NOT NULL,
+			"Result"	TEXT NOT NULL,
+			"UserId"	INTEGER NOT NULL,
+			"ApiKeyId"	TEXT NOT NULL,
+			"Ip"	TEXT NOT NULL,
+			"FileId"	TEXT NOT NULL,
+			"FileRequestId"	TEXT NOT NULL,
+			"Details"	TEXT NOT NULL,
+			PRIMARY KEY("Id" AUTOINCREMENT)
+		);
+		CREATE INDEX "idx_AuditLog_Timestamp" ON "AuditLog" ("Timestamp");
+		CREATE TABLE "UserQuotas" (
+			"UserId"	INTEGER NOT NULL UNIQUE,
+			"MaxStorageMb"	INTEGER NOT NULL,
+			"MaxFiles"	INTEGER NOT NULL,
+			"MaxExpiryDays"	INTEGER NOT NULL,
+			"MaxDownloads"	INTEGER NOT NULL,
+			PRIMARY KEY("UserId")
+		) WITHOUT ROWID;
+		CREATE TABLE "Leases" (
+			"Name"	TEXT NOT NULL UNIQUE,
+			"Owner"	TEXT NOT NULL,
+			"ExpiresAt"	INTEGER NOT NULL,
+			PRIMARY KEY("Name")
+		) WITHOUT ROWID;
+		CREATE TABLE "FileVersions" (
+			"Id"	TEXT NOT NULL UNIQUE,
+			"FileId"	TEXT NOT NULL,
+			"Revision"	INTEGER NOT NULL,
+			"Name"	TEXT NOT NULL,
+			"Size"	TEXT NOT NULL,
+			"SHA1"	TEXT NOT NULL,
+			"ContentType"	TEXT NOT NULL,
+			"AwsBucket"	TEXT NOT NULL,
+			"SizeBytes"	INTEGER NOT NULL,
+			"ReplacedAt"	INTEGER NOT NULL,
+			"ReplacedBy"	INTEGER NOT NULL,
+			"Encryption"	BLOB NOT NULL,
+			PRIMARY KEY("Id")
+		) WITHOUT ROWID;
+		CREATE INDEX "idx_FileVersions_FileId" ON "FileVersions" ("FileId");`
 	err := p.rawSqlite(sqlStmt)
 	if err != nil {
 		return err
@@ -210,6 +210,116 @@ func TestDatabaseProvider_IncreaseDownloadCount(t *testing.T) {
 	dbInstance.DeleteMetaData(newFile.Id)
 }
 
+func TestQueryMetaData(t *testing.T) {
+	for id := range dbInstance.GetAllMetadata() {
+		dbInstance.DeleteMetaData(id)
+	}
+	files := []models.File{
+		{Id: "q1", Name: "Holiday.jpg", ContentType: "image/jpeg", UserId: 1, UploadDate: 100, SizeBytes: 300,
+			ExpireAt: 1000, DownloadsRemaining: 1, DownloadCount: 5},
+		{Id: "q2", Name: "notes.txt", ContentType: "text/plain", UserId: 2, UploadDate: 200, SizeBytes: 100,
+			UnlimitedTime: true, UnlimitedDownloads: true, DownloadCount: 1,
+			Encryption: models.EncryptionInfo{IsEncrypted: true}},
+		{Id: "q3", Name: "holiday_2.png", ContentType: "image/png", UserId: 1, UploadDate: 300, SizeBytes: 200,
+			ExpireAt: 3000, UnlimitedDownloads: true, DownloadCount: 3},
+		{Id: "q4", Name: "request.pdf", ContentType: "application/pdf", UserId: 1, UploadDate: 300, SizeBytes: 400,
+			UnlimitedTime: true, UnlimitedDownloads: true, UploadRequestId: "request1"},
+		{Id: "q5", Name: "expired.txt", ContentType: "text/plain", UserId: 2, UploadDate: 500, SizeBytes: 500,
+			UnlimitedTime: true, DownloadsRemaining: 0},
+	}
+	for _, file := range files {
+		dbInstance.SaveMetaData(file)
+	}
+	getIds := func(result models.FileQueryResult) []string {
+		ids := make([]string, 0)
+		for _, file := range result.Files {
+			ids = append(ids, file.Id)
+		}
+		return ids
+	}
+
+	result := dbInstance.QueryMetaData(models.FileQuery{})
+	test.IsEqual(t, getIds(result), []string{"q1", "q2", "q3", "q5"})
+	test.IsEqualBool(t, result.HasMore, false)
+	result = dbInstance.QueryMetaData(models.FileQuery{IncludeFileRequests: true, SortDescending: true})
+	test.IsEqual(t, getIds(result), []string{"q5", "q4", "q3", "q2", "q1"})
+	result = dbInstance.QueryMetaData(models.FileQuery{FileRequestId: "request1"})
+	test.IsEqual(t, getIds(result), []string{"q4"})
+	result = dbInstance.QueryMetaData(models.FileQuery{HideExpiredAt: 2000})
+	test.IsEqual(t, getIds(result), []string{"q2", "q3"})
+	result = dbInstance.QueryMetaData(models.FileQuery{UserId: 1, IncludeFileRequests: true})
+	test.IsEqual(t, getIds(result), []string{"q1", "q3", "q4"})
+	result = dbInstance.QueryMetaData(models.FileQuery{NameContains: "HOLIDAY_"})
+	test.IsEqual(t, getIds(result), []string{"q3"})
+	result = dbInstance.QueryMetaData(models.FileQuery{ContentTypePrefix: "image/"})
+	test.IsEqual(t, getIds(result), []string{"q1", "q3"})
+	result = dbInstance.QueryMetaData(models.FileQuery{Encryption: models.FileFilterEncryptionOnly})
+	test.IsEqual(t, getIds(result), []string{"q2"})
+	result = dbInstance.QueryMetaData(models.FileQuery{Encryption: models.FileFilterEncryptionNone})
+	test.IsEqual(t, getIds(result), []string{"q1", "q3", "q5"})
+	result = dbInstance.QueryMetaData(models.FileQuery{ExpiresAfter: 1000})
+	test.IsEqual(t, getIds(result), []string{"q2", "q3", "q5"})
+	result = dbInstance.QueryMetaData(models.FileQuery{ExpiresBefore: 3000})
+	test.IsEqual(t, getIds(result), []string{"q1"})
+	result = dbInstance.QueryMetaData(models.FileQuery{SortBy: models.FileSortName})
+	test.IsEqual(t, getIds(result), []string{"q1", "q5", "q3", "q2"})
+	result = dbInstance.QueryMetaData(models.FileQuery{SortBy: models.FileSortSize, SortDescending: true})
+	test.IsEqual(t, getIds(result), []string{"q5", "q1", "q3", "q2"})
+
+	dbInstance.IncreaseDownloadCount("q2", false)
+	dbInstance.IncreaseDownloadCount("q2", false)
+	dbInstance.IncreaseDownloadCount("q2", false)
+	result = dbInstance.QueryMetaData(models.FileQuery{SortBy: models.FileSortDownloads})
+	test.IsEqual(t, getIds(result), []string{"q5", "q3", "q2", "q1"})
+
+	query := models.FileQuery{Limit: 2, IncludeFileRequests: true}
+	result = dbInstance.QueryMetaData(query)
+	test.IsEqual(t, getIds(result), []string{"q1", "q2"})
+	test.IsEqualBool(t, result.HasMore, true)
+	err := query.SetCursor(query.GetCursor(result.Files[1]))
+	test.IsNil(t, err)
+	result = dbInstance.QueryMetaData(query)
+	test.IsEqual(t, getIds(result), []string{"q3", "q4"})
+	test.IsEqualBool(t, result.HasMore, true)
+	err = query.SetCursor(query.GetCursor(result.Files[1]))
+	test.IsNil(t, err)
+	result = dbInstance.QueryMetaData(query)
+	test.IsEqual(t, getIds(result), []string{"q5"})
+	test.IsEqualBool(t, result.HasMore, false)
+
+	query = models.FileQuery{Limit: 3, SortBy: models.FileSortName, SortDescending: true}
+	result = dbInstance.QueryMetaData(query)
+	test.IsEqual(t, getIds(result), []string{"q2", "q3", "q5"})
+	test.IsEqualBool(t, result.HasMore, true)
+	err = query.SetCursor(query.GetCursor(result.Files[2]))
+	test.IsNil(t, err)
+	result = dbInstance.QueryMetaData(query)
+	test.IsEqual(t, getIds(result), []string{"q1"})
+	test.IsEqualBool(t, result.HasMore, false)
+
+	dbInstance.DeleteMetaData("q3")
+	result = dbInstance.QueryMetaData(models.FileQuery{SortBy: models.FileSortSize})
+	test.IsEqual(t, getIds(result), []string{"q2", "q1", "q5"})
+
+	// The name filter ignores the case of non-ASCII characters on all providers, the same way as models.FileQuery
+	unicodeFile := models.File{Id: "q6", Name: "Änderungen_Übersicht.PDF", ContentType: "Application/PDF", UploadDate: 600,
+		UnlimitedTime: true, UnlimitedDownloads: true}
+	dbInstance.SaveMetaData(unicodeFile)
+	for _, name := range []string{"ä", "Ä", "ÄNDERUNGEN", "übersicht.pdf"} {
+		query = models.FileQuery{NameContains: name, ContentTypePrefix: "application/pdf"}
+		test.IsEqualBool(t, query.Matches(unicodeFile), true)
+		result = dbInstance.QueryMetaData(query)
+		test.IsEqual(t, getIds(result), []string{"q6"})
+	}
+	result = dbInstance.QueryMetaData(models.FileQuery{NameContains: "ö"})
+	test.IsEqual(t, getIds(result), []string{})
+	dbInstance.DeleteMetaData(unicodeFile.Id)
+	for _, file := range files {
+		dbInstance.DeleteMetaData(file.Id)
+	}
+	test.IsEqualInt(t, len(dbInstance.QueryMetaData(models.FileQuery{IncludeFileRequests: true}).Files), 0)
+}
+
 func TestApiKey(t *testing.T) {
 	key1 := models.ApiKey{
 		Id:           "newkey",
@@ -274,6 +384,17 @@ func TestSession(t *testing.T) {
 	test.IsEqualBool(t, ok, true)
 	test.IsEqualBool(t, session.RenewAt == renewAt, true)
 
+	dbInstance.SaveSession("recipientsession", models.Session{
+		RenewAt:       renewAt,
+		ValidUntil:    time.Now().Add(2 * time.Hour).Unix(),
+		Groups:        "staff\nsales",
+		RecipientName: "recipient@example.com",
+	})
+	session, ok = dbInstance.GetSession("recipientsession")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, session.Groups, "staff\nsales")
+	test.IsEqualString(t, session.RecipientName, "recipient@example.com")
+
 	dbInstance.DeleteSession("newsession")
 	_, ok = dbInstance.GetSession("newsession")
 	test.IsEqualBool(t, ok, false)
@@ -343,6 +464,17 @@ func TestFileRequest(t *testing.T) {
 	test.IsEqualBool(t, ok, true)
 	test.IsEqualString(t, request.Id, "req1")
 	test.IsEqualString(t, request.Name, "New file request")
+	test.IsEqualBool(t, request.RequireLogin, false)
+
+	req1.RequireLogin = true
+	req1.AllowedUsers = "user1,user2"
+	req1.AllowedGroups = "staff"
+	dbInstance.SaveFileRequest(req1)
+	request, ok = dbInstance.GetFileRequest("req1")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, request.RequireLogin, true)
+	test.IsEqualString(t, request.AllowedUsers, "user1,user2")
+	test.IsEqualString(t, request.AllowedGroups, "staff")
 
 	// Get invalid file request
 	_, ok = dbInstance.GetFileRequest("invalid")
@@ -684,11 +816,470 @@ func TestDatabaseProvider_Upgrade(t *testing.T) {
 	// instance.Upgrade(instance.GetDbVersion())
 	// test.IsEqualInt(t, exitCode, 0)
 
+	instance.SaveMetaData(models.File{Id: "upgradeEnc", Name: "enc", Encryption: models.EncryptionInfo{IsEncrypted: true}})
+	instance.SaveMetaData(models.File{Id: "upgradePlain", Name: "plain"})
+	err = instance.rawSqlite(sqlDowngradeToV15)
+	test.IsNil(t, err)
+	instance.SetDbVersion(15)
+	instance.Upgrade(instance.GetDbVersion())
+	result := instance.QueryMetaData(models.FileQuery{Encryption: models.FileFilterEncryptionOnly})
+	test.IsEqualInt(t, len(result.Files), 1)
+	test.IsEqualString(t, result.Files[0].Id, "upgradeEnc")
+	result = instance.QueryMetaData(models.FileQuery{Encryption: models.FileFilterEncryptionNone})
+	test.IsEqualInt(t, len(result.Files), 1)
+	test.IsEqualString(t, result.Files[0].Id, "upgradePlain")
+	instance.SaveMetaData(models.File{Id: "upgradePublish", Name: "publish", PublishAt: 2000000000})
+	file, ok := instance.GetMetaDataById("upgradePublish")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt64(t, file.PublishAt, 2000000000)
+	instance.SaveMetaData(models.File{Id: "upgradeAccess", Name: "access", RequireLogin: true, AllowedGroups: "staff"})
+	file, ok = instance.GetMetaDataById("upgradeAccess")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, file.RequireLogin, true)
+	test.IsEqualString(t, file.AllowedGroups, "staff")
+	instance.SaveFileRequest(models.FileRequest{Id: "upgradeRequest", ApiKey: "upgradeRequestKey", RequireLogin: true})
+	request, ok := instance.GetFileRequest("upgradeRequest")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, request.RequireLogin, true)
+	instance.SaveSession("upgradeSession", models.Session{ValidUntil: 2147483645, Groups: "staff"})
+	session, ok := instance.GetSession("upgradeSession")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, session.Groups, "staff")
+	instance.SaveWebhook(models.Webhook{Id: "upgradeHook", Url: "https://example.com", Events: "file.upload"})
+	_, ok = instance.GetWebhook("upgradeHook")
+	test.IsEqualBool(t, ok, true)
+	instance.SaveBundle(models.Bundle{Id: "upgradeBundle", FileIds: "upgradeEnc"})
+	_, ok = instance.GetBundle("upgradeBundle")
+	test.IsEqualBool(t, ok, true)
+	instance.SaveShareLink(models.ShareLink{Id: "upgradeLink", FileId: "upgradeEnc"})
+	_, ok = instance.GetShareLink("upgradeLink")
+	test.IsEqualBool(t, ok, true)
+	instance.SaveUserTotp(models.UserTotp{UserId: 1, Secret: "upgradeSecret"})
+	_, ok = instance.GetUserTotp(1)
+	test.IsEqualBool(t, ok, true)
+	instance.SaveAuditEvent(models.AuditEvent{Timestamp: 100, Action: models.AuditActionStartup, Result: models.AuditResultSuccess})
+	test.IsEqualInt(t, len(instance.QueryAuditEvents(models.AuditQuery{}).Events), 1)
+	instance.SaveUserQuota(models.UserQuota{UserId: 1, MaxFiles: 5})
+	_, ok = instance.GetUserQuota(1)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, instance.TryAcquireLease(models.Lease{Name: "upgrade", Owner: "test", ExpiresAt: 100}), true)
+	_, ok = instance.GetLease("upgrade")
+	test.IsEqualBool(t, ok, true)
+	instance.SaveFileVersion(models.FileVersion{Id: "upgradeVersion", FileId: "upgradeEnc"})
+	_, ok = instance.GetFileVersion("upgradeVersion")
+	test.IsEqualBool(t, ok, true)
+
+	// Upgrading from v13 reads and saves the metadata of SVG files with hotlinks
+	instance.SaveMetaData(models.File{Id: "upgradeSvg", Name: "image.svg", HotlinkId: "upgradeSvgHotlink"})
+	instance.SaveHotlink(models.File{Id: "upgradeSvg", HotlinkId: "upgradeSvgHotlink"})
+	err = instance.rawSqlite(sqlDowngradeToV15)
+	test.IsNil(t, err)
+	instance.SetDbVersion(13)
+	instance.Upgrade(instance.GetDbVersion())
+	file, ok = instance.GetMetaDataById("upgradeSvg")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.HotlinkId, "")
+	_, ok = instance.GetHotlink("upgradeSvgHotlink")
+	test.IsEqualBool(t, ok, false)
+	file, ok = instance.GetMetaDataById("upgradeEnc")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, file.Encryption.IsEncrypted, true)
+	result = instance.QueryMetaData(models.FileQuery{Encryption: models.FileFilterEncryptionOnly})
+	test.IsEqualInt(t, len(result.Files), 1)
 }
 
+const sqlDowngradeToV15 = `DROP INDEX "idx_FileMetaData_UploadDate"; ALTER TABLE FileMetaData DROP COLUMN IsEncrypted;
+		ALTER TABLE FileMetaData DROP COLUMN ProcessingState; ALTER TABLE FileMetaData DROP COLUMN QuarantineReason;
+		ALTER TABLE FileMetaData DROP COLUMN PublishAt; ALTER TABLE FileMetaData DROP COLUMN RequireLogin;
+		ALTER TABLE FileMetaData DROP COLUMN AllowedUsers; ALTER TABLE FileMetaData DROP COLUMN AllowedGroups;
+		ALTER TABLE UploadRequests DROP COLUMN requireLogin; ALTER TABLE UploadRequests DROP COLUMN allowedUsers;
+		ALTER TABLE UploadRequests DROP COLUMN allowedGroups; ALTER TABLE Sessions DROP COLUMN Groups;
+		ALTER TABLE Sessions DROP COLUMN RecipientName;
+		DROP TABLE Webhooks; DROP TABLE WebhookDeliveries; DROP TABLE Bundles; DROP TABLE ShareLinks; DROP TABLE UserTotp; DROP TABLE AuditLog; DROP TABLE UserQuotas; DROP TABLE Leases;
+		DROP TABLE FileVersions;`
+
 func TestRawSql(t *testing.T) {
 	dbInstance.Close()
 	dbInstance.sqliteDb = nil
 	defer test.ExpectPanic(t)
 	_ = dbInstance.rawSqlite("Select * from Sessions")
 }
+
+func TestWebhooks(t *testing.T) {
+	instance, err := New(config)
+	test.IsNil(t, err)
+	dbInstance = instance
+
+	dbInstance.SaveWebhook(models.Webhook{Id: "wh1", Url: "https://example.com/hook", Secret: "secret1", Events: "file.upload,file.delete", UserId: 5, CreationDate: 200})
+	dbInstance.SaveWebhook(models.Webhook{Id: "wh2", Url: "http://127.0.0.1/hook", Secret: "secret2", Events: "user.create", UserId: 6, CreationDate: 100})
+
+	webhook, ok := dbInstance.GetWebhook("wh1")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, webhook.Url, "https://example.com/hook")
+	test.IsEqualString(t, webhook.Secret, "secret1")
+	test.IsEqualString(t, webhook.Events, "file.upload,file.delete")
+	test.IsEqualInt(t, webhook.UserId, 5)
+	test.IsEqualInt64(t, webhook.CreationDate, 200)
+	_, ok = dbInstance.GetWebhook("invalid")
+	test.IsEqualBool(t, ok, false)
+
+	webhooks := dbInstance.GetAllWebhooks()
+	test.IsEqualInt(t, len(webhooks), 2)
+	test.IsEqualString(t, webhooks[0].Id, "wh2")
+	test.IsEqualString(t, webhooks[1].Id, "wh1")
+
+	webhook.Events = "file.download"
+	dbInstance.SaveWebhook(webhook)
+	webhook, ok = dbInstance.GetWebhook("wh1")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, webhook.Events, "file.download")
+
+	dbInstance.SaveWebhookDelivery(models.WebhookDelivery{Id: "d1", WebhookId: "wh1", Event: "file.download", Payload: "{}", NextAttempt: 300})
+	dbInstance.SaveWebhookDelivery(models.WebhookDelivery{Id: "d2", WebhookId: "wh2", Event: "user.create", Payload: "{}", NextAttempt: 100})
+	dbInstance.SaveWebhookDelivery(models.WebhookDelivery{Id: "d3", WebhookId: "wh1", Event: "file.download", Payload: "{}", NextAttempt: 200})
+
+	deliveries := dbInstance.GetDueWebhookDeliveries(50, 10)
+	test.IsEqualInt(t, len(deliveries), 0)
+	deliveries = dbInstance.GetDueWebhookDeliveries(250, 10)
+	test.IsEqualInt(t, len(deliveries), 2)
+	test.IsEqualString(t, deliveries[0].Id, "d2")
+	test.IsEqualString(t, deliveries[1].Id, "d3")
+	deliveries = dbInstance.GetDueWebhookDeliveries(500, 1)
+	test.IsEqualInt(t, len(deliveries), 1)
+	test.IsEqualString(t, deliveries[0].Id, "d2")
+
+	delivery := deliveries[0]
+	delivery.Attempts = 2
+	delivery.LastError = "timeout"
+	delivery.NextAttempt = 1000
+	dbInstance.SaveWebhookDelivery(delivery)
+	deliveries = dbInstance.GetDueWebhookDeliveries(500, 10)
+	test.IsEqualInt(t, len(deliveries), 2)
+	deliveries = dbInstance.GetDueWebhookDeliveries(1000, 10)
+	test.IsEqualInt(t, len(deliveries), 3)
+	test.IsEqualString(t, deliveries[2].Id, "d2")
+	test.IsEqualInt(t, deliveries[2].Attempts, 2)
+	test.IsEqualString(t, deliveries[2].LastError, "timeout")
+
+	dbInstance.DeleteWebhookDelivery("d2")
+	deliveries = dbInstance.GetDueWebhookDeliveries(1000, 10)
+	test.IsEqualInt(t, len(deliveries), 2)
+
+	dbInstance.DeleteWebhook("wh1")
+	_, ok = dbInstance.GetWebhook("wh1")
+	test.IsEqualBool(t, ok, false)
+	deliveries = dbInstance.GetDueWebhookDeliveries(1000, 10)
+	test.IsEqualInt(t, len(deliveries), 0)
+	dbInstance.DeleteWebhook("wh2")
+	test.IsEqualInt(t, len(dbInstance.GetAllWebhooks()), 0)
+}
+
+func TestBundles(t *testing.T) {
+	instance, err := New(config)
+	test.IsNil(t, err)
+	dbInstance = instance
+
+	dbInstance.SaveBundle(models.Bundle{Id: "bundle1", Name: "First", FileIds: "file1,file2", PasswordHash: "hash",
+		UserId: 5, ExpireAt: 1000, CreationDate: 200, DownloadsRemaining: 3, UnlimitedTime: true})
+	dbInstance.SaveBundle(models.Bundle{Id: "bundle2", Name: "Second", UserId: 6, CreationDate: 100, UnlimitedDownloads: true})
+
+	bundle, ok := dbInstance.GetBundle("bundle1")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, bundle.Name, "First")
+	test.IsEqualString(t, bundle.FileIds, "file1,file2")
+	test.IsEqualString(t, bundle.PasswordHash, "hash")
+	test.IsEqualInt(t, bundle.UserId, 5)
+	test.IsEqualInt64(t, bundle.ExpireAt, 1000)
+	test.IsEqualInt64(t, bundle.CreationDate, 200)
+	test.IsEqualInt(t, bundle.DownloadsRemaining, 3)
+	test.IsEqualBool(t, bundle.UnlimitedTime, true)
+	test.IsEqualBool(t, bundle.UnlimitedDownloads, false)
+	_, ok = dbInstance.GetBundle("invalid")
+	test.IsEqualBool(t, ok, false)
+
+	bundles := dbInstance.GetAllBundles()
+	test.IsEqualInt(t, len(bundles), 2)
+	test.IsEqualString(t, bundles[0].Id, "bundle2")
+	test.IsEqualBool(t, bundles[0].UnlimitedDownloads, true)
+	test.IsEqualString(t, bundles[1].Id, "bundle1")
+
+	dbInstance.IncreaseBundleDownloadCount("bundle1", true)
+	dbInstance.IncreaseBundleDownloadCount("bundle1", false)
+	bundle, _ = dbInstance.GetBundle("bundle1")
+	test.IsEqualInt(t, bundle.DownloadCount, 2)
+	test.IsEqualInt(t, bundle.DownloadsRemaining, 2)
+
+	bundle.FileIds = "file3"
+	dbInstance.SaveBundle(bundle)
+	bundle, _ = dbInstance.GetBundle("bundle1")
+	test.IsEqualString(t, bundle.FileIds, "file3")
+
+	dbInstance.DeleteBundle("bundle1")
+	_, ok = dbInstance.GetBundle("bundle1")
+	test.IsEqualBool(t, ok, false)
+	dbInstance.DeleteBundle("bundle2")
+	test.IsEqualInt(t, len(dbInstance.GetAllBundles()), 0)
+}
+
+func TestShareLinks(t *testing.T) {
+	instance, err := New(config)
+	test.IsNil(t, err)
+	dbInstance = instance
+
+	dbInstance.SaveShareLink(models.ShareLink{Id: "link1", FileId: "file1", Name: "First", PasswordHash: "hash",
+		UserId: 5, ExpireAt: 1000, CreationDate: 200, DownloadsRemaining: 3, UnlimitedTime: true})
+	dbInstance.SaveShareLink(models.ShareLink{Id: "link2", FileId: "file1", UserId: 6, CreationDate: 100, UnlimitedDownloads: true})
+
+	link, ok := dbInstance.GetShareLink("link1")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, link.FileId, "file1")
+	test.IsEqualString(t, link.Name, "First")
+	test.IsEqualString(t, link.PasswordHash, "hash")
+	test.IsEqualInt(t, link.UserId, 5)
+	test.IsEqualInt64(t, link.ExpireAt, 1000)
+	test.IsEqualInt64(t, link.CreationDate, 200)
+	test.IsEqualInt(t, link.DownloadsRemaining, 3)
+	test.IsEqualBool(t, link.UnlimitedTime, true)
+	test.IsEqualBool(t, link.UnlimitedDownloads, false)
+	_, ok = dbInstance.GetShareLink("invalid")
+	test.IsEqualBool(t, ok, false)
+
+	links := dbInstance.GetAllShareLinks()
+	test.IsEqualInt(t, len(links), 2)
+	test.IsEqualString(t, links[0].Id, "link2")
+	test.IsEqualBool(t, links[0].UnlimitedDownloads, true)
+	test.IsEqualString(t, links[1].Id, "link1")
+
+	dbInstance.IncreaseShareLinkDownloadCount("link1", true)
+	dbInstance.IncreaseShareLinkDownloadCount("link1", false)
+	link, _ = dbInstance.GetShareLink("link1")
+	test.IsEqualInt(t, link.DownloadCount, 2)
+	test.IsEqualInt(t, link.DownloadsRemaining, 2)
+	link2, _ := dbInstance.GetShareLink("link2")
+	test.IsEqualInt(t, link2.DownloadCount, 0)
+
+	link.Name = "Renamed"
+	dbInstance.SaveShareLink(link)
+	link, _ = dbInstance.GetShareLink("link1")
+	test.IsEqualString(t, link.Name, "Renamed")
+
+	dbInstance.DeleteShareLink("link1")
+	_, ok = dbInstance.GetShareLink("link1")
+	test.IsEqualBool(t, ok, false)
+	_, ok = dbInstance.GetShareLink("link2")
+	test.IsEqualBool(t, ok, true)
+	dbInstance.DeleteShareLink("link2")
+	test.IsEqualInt(t, len(dbInstance.GetAllShareLinks()), 0)
+}
+
+func TestFileVersions(t *testing.T) {
+	instance, err := New(config)
+	test.IsNil(t, err)
+	dbInstance = instance
+
+	dbInstance.SaveFileVersion(models.FileVersion{Id: "version1", FileId: "file1", Revision: 1, Name: "first.txt", Size: "1 B",
+		SHA1: "hash1", ContentType: "text/plain", SizeBytes: 1, ReplacedAt: 100, ReplacedBy: 5,
+		Encryption: models.EncryptionInfo{IsEncrypted: true, DecryptionKey: []byte("key"), Nonce: []byte("nonce")}})
+	dbInstance.SaveFileVersion(models.FileVersion{Id: "version2", FileId: "file1", Revision: 2, Name: "second.txt", SHA1: "hash2",
+		AwsBucket: "bucket", ReplacedAt: 200, ReplacedBy: 6})
+	dbInstance.SaveFileVersion(models.FileVersion{Id: "version3", FileId: "file2", SHA1: "hash3", ReplacedAt: 150})
+
+	version, ok := dbInstance.GetFileVersion("version1")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, version.FileId, "file1")
+	test.IsEqualInt(t, version.Revision, 1)
+	test.IsEqualString(t, version.Name, "first.txt")
+	test.IsEqualString(t, version.Size, "1 B")
+	test.IsEqualString(t, version.SHA1, "hash1")
+	test.IsEqualString(t, version.ContentType, "text/plain")
+	test.IsEqualInt64(t, version.SizeBytes, 1)
+	test.IsEqualInt64(t, version.ReplacedAt, 100)
+	test.IsEqualInt(t, version.ReplacedBy, 5)
+	test.IsEqualBool(t, version.Encryption.IsEncrypted, true)
+	test.IsEqualString(t, string(version.Encryption.DecryptionKey), "key")
+	test.IsEqualString(t, string(version.Encryption.Nonce), "nonce")
+	version, ok = dbInstance.GetFileVersion("version2")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, version.AwsBucket, "bucket")
+	test.IsEqualBool(t, version.Encryption.IsEncrypted, false)
+	_, ok = dbInstance.GetFileVersion("invalid")
+	test.IsEqualBool(t, ok, false)
+
+	versions := dbInstance.GetFileVersions("file1")
+	test.IsEqualInt(t, len(versions), 2)
+	test.IsEqualString(t, versions[0].Id, "version2")
+	test.IsEqualString(t, versions[1].Id, "version1")
+	test.IsEqualInt(t, len(dbInstance.GetFileVersions("invalid")), 0)
+
+	versions = dbInstance.GetAllFileVersions()
+	test.IsEqualInt(t, len(versions), 3)
+	test.IsEqualString(t, versions[0].Id, "version1")
+	test.IsEqualString(t, versions[1].Id, "version3")
+	test.IsEqualString(t, versions[2].Id, "version2")
+
+	version.Name = "renamed.txt"
+	dbInstance.SaveFileVersion(version)
+	version, _ = dbInstance.GetFileVersion("version2")
+	test.IsEqualString(t, version.Name, "renamed.txt")
+
+	dbInstance.DeleteFileVersion("version1")
+	_, ok = dbInstance.GetFileVersion("version1")
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualInt(t, len(dbInstance.GetFileVersions("file1")), 1)
+	dbInstance.DeleteFileVersion("version2")
+	dbInstance.DeleteFileVersion("version3")
+	test.IsEqualInt(t, len(dbInstance.GetAllFileVersions()), 0)
+}
+
+func TestUserTotp(t *testing.T) {
+	instance, err := New(config)
+	test.IsNil(t, err)
+	dbInstance = instance
+
+	_, ok := dbInstance.GetUserTotp(20)
+	test.IsEqualBool(t, ok, false)
+	dbInstance.SaveUserTotp(models.UserTotp{UserId: 20, Secret: "JBSWY3DPEHPK3PXP", RecoveryCodes: "hash1,hash2"})
+	totp, ok := dbInstance.GetUserTotp(20)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, totp.UserId, 20)
+	test.IsEqualString(t, totp.Secret, "JBSWY3DPEHPK3PXP")
+	test.IsEqualString(t, totp.RecoveryCodes, "hash1,hash2")
+	test.IsEqualBool(t, totp.IsConfirmed, false)
+	test.IsEqualInt64(t, totp.LastUsedStep, 0)
+
+	totp.IsConfirmed = true
+	totp.LastUsedStep = 58000000
+	totp.RecoveryCodes = "hash2"
+	dbInstance.SaveUserTotp(totp)
+	totp, ok = dbInstance.GetUserTotp(20)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, totp.IsConfirmed, true)
+	test.IsEqualInt64(t, totp.LastUsedStep, 58000000)
+	test.IsEqualString(t, totp.RecoveryCodes, "hash2")
+
+	dbInstance.DeleteUserTotp(20)
+	_, ok = dbInstance.GetUserTotp(20)
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestAuditLog(t *testing.T) {
+	instance, err := New(config)
+	test.IsNil(t, err)
+	dbInstance = instance
+
+	result := dbInstance.QueryAuditEvents(models.AuditQuery{})
+	test.IsEqualInt(t, len(result.Events), 0)
+	test.IsEqualBool(t, result.HasMore, false)
+
+	dbInstance.SaveAuditEvent(models.AuditEvent{Timestamp: 100, Action: models.AuditActionLogin, Result: models.AuditResultSuccess, UserId: 5, Ip: "127.0.0.1", Details: "login"})
+	dbInstance.SaveAuditEvent(models.AuditEvent{Timestamp: 200, Action: models.AuditActionFileUpload, Result: models.AuditResultSuccess, UserId: 5, ApiKeyId: "key1", FileId: "file1"})
+	dbInstance.SaveAuditEvent(models.AuditEvent{Timestamp: 300, Action: models.AuditActionFileDelete, Result: models.AuditResultSuccess, UserId: 6, FileId: "file1"})
+	dbInstance.SaveAuditEvent(models.AuditEvent{Timestamp: 400, Action: models.AuditActionLogin, Result: models.AuditResultFailure, Ip: "127.0.0.2"})
+	dbInstance.SaveAuditEvent(models.AuditEvent{Timestamp: 500, Action: models.AuditActionFileRequestCreate, Result: models.AuditResultSuccess, UserId: 6, FileRequestId: "request1"})
+
+	result = dbInstance.QueryAuditEvents(models.AuditQuery{})
+	test.IsEqualInt(t, len(result.Events), 5)
+	test.IsEqualBool(t, result.HasMore, false)
+	test.IsEqualString(t, result.Events[0].Action, models.AuditActionFileRequestCreate)
+	test.IsEqualString(t, result.Events[4].Action, models.AuditActionLogin)
+	test.IsEqualString(t, result.Events[4].Details, "login")
+	test.IsEqualString(t, result.Events[4].Ip, "127.0.0.1")
+	test.IsEqualBool(t, result.Events[0].Id > result.Events[1].Id, true)
+
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{UserId: 5}).Events), 2)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{ApiKeyId: "key1"}).Events), 1)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{Ip: "127.0.0.2"}).Events), 1)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{FileId: "file1"}).Events), 2)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{FileRequestId: "request1"}).Events), 1)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{Action: models.AuditActionLogin}).Events), 2)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{Action: "file."}).Events), 2)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{Action: "file_"}).Events), 0)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{Result: models.AuditResultFailure}).Events), 1)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{Since: 200, Until: 400}).Events), 2)
+
+	result = dbInstance.QueryAuditEvents(models.AuditQuery{Limit: 2})
+	test.IsEqualInt(t, len(result.Events), 2)
+	test.IsEqualBool(t, result.HasMore, true)
+	test.IsEqualInt64(t, result.Events[1].Timestamp, 400)
+	result = dbInstance.QueryAuditEvents(models.AuditQuery{Limit: 2, BeforeId: result.Events[1].Id})
+	test.IsEqualInt(t, len(result.Events), 2)
+	test.IsEqualBool(t, result.HasMore, true)
+	test.IsEqualInt64(t, result.Events[0].Timestamp, 300)
+	result = dbInstance.QueryAuditEvents(models.AuditQuery{Limit: 2, BeforeId: result.Events[1].Id})
+	test.IsEqualInt(t, len(result.Events), 1)
+	test.IsEqualBool(t, result.HasMore, false)
+
+	dbInstance.DeleteAuditEventsBefore(300)
+	result = dbInstance.QueryAuditEvents(models.AuditQuery{})
+	test.IsEqualInt(t, len(result.Events), 3)
+	test.IsEqualInt64(t, result.Events[2].Timestamp, 300)
+	dbInstance.DeleteAuditEventsBefore(1000)
+	test.IsEqualInt(t, len(dbInstance.QueryAuditEvents(models.AuditQuery{}).Events), 0)
+}
+
+func TestUserQuota(t *testing.T) {
+	instance, err := New(config)
+	test.IsNil(t, err)
+	dbInstance = instance
+
+	_, ok := dbInstance.GetUserQuota(20)
+	test.IsEqualBool(t, ok, false)
+	dbInstance.SaveUserQuota(models.UserQuota{UserId: 20, MaxStorageMb: 100, MaxFiles: 10, MaxExpiryDays: models.QuotaUseDefault})
+	quota, ok := dbInstance.GetUserQuota(20)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, quota.UserId, 20)
+	test.IsEqualInt(t, quota.MaxStorageMb, 100)
+	test.IsEqualInt(t, quota.MaxFiles, 10)
+	test.IsEqualInt(t, quota.MaxExpiryDays, models.QuotaUseDefault)
+	test.IsEqualInt(t, quota.MaxDownloads, 0)
+
+	quota.MaxDownloads = 5
+	dbInstance.SaveUserQuota(quota)
+	quota, ok = dbInstance.GetUserQuota(20)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, quota.MaxDownloads, 5)
+
+	dbInstance.DeleteUserQuota(20)
+	_, ok = dbInstance.GetUserQuota(20)
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestLeases(t *testing.T) {
+	instance, err := New(config)
+	test.IsNil(t, err)
+	dbInstance = instance
+
+	_, ok := dbInstance.GetLease("cleanup")
+	test.IsEqualBool(t, ok, false)
+	expiry := time.Now().Add(time.Minute).Unix()
+	test.IsEqualBool(t, dbInstance.TryAcquireLease(models.Lease{Name: "cleanup", Owner: "node1", ExpiresAt: expiry}), true)
+	test.IsEqualBool(t, dbInstance.TryAcquireLease(models.Lease{Name: "cleanup", Owner: "node2", ExpiresAt: expiry}), false)
+	lease, ok := dbInstance.GetLease("cleanup")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, lease.Name, "cleanup")
+	test.IsEqualString(t, lease.Owner, "node1")
+	test.IsEqualInt64(t, lease.ExpiresAt, expiry)
+
+	test.IsEqualBool(t, dbInstance.TryAcquireLease(models.Lease{Name: "cleanup", Owner: "node1", ExpiresAt: expiry + 60}), true)
+	lease, _ = dbInstance.GetLease("cleanup")
+	test.IsEqualInt64(t, lease.ExpiresAt, expiry+60)
+	test.IsEqualBool(t, dbInstance.TryAcquireLease(models.Lease{Name: "other", Owner: "node2", ExpiresAt: expiry}), true)
+
+	dbInstance.ReleaseLease("cleanup", "node2")
+	_, ok = dbInstance.GetLease("cleanup")
+	test.IsEqualBool(t, ok, true)
+	dbInstance.ReleaseLease("cleanup", "node1")
+	_, ok = dbInstance.GetLease("cleanup")
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualBool(t, dbInstance.TryAcquireLease(models.Lease{Name: "cleanup", Owner: "node2", ExpiresAt: expiry}), true)
+	dbInstance.ReleaseLease("cleanup", "node2")
+	dbInstance.ReleaseLease("other", "node2")
+
+	// An expired lease can be acquired by another owner
+	expired := time.Now().Add(-time.Minute).Unix()
+	test.IsEqualBool(t, dbInstance.TryAcquireLease(models.Lease{Name: "expired", Owner: "node1", ExpiresAt: expired}), true)
+	test.IsEqualBool(t, dbInstance.TryAcquireLease(models.Lease{Name: "expired", Owner: "node2", ExpiresAt: expiry}), true)
+	lease, _ = dbInstance.GetLease("expired")
+	test.IsEqualString(t, lease.Owner, "node2")
+	dbInstance.ReleaseLease("expired", "node2")
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"strings"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectAuditEvents = "SELECT Id, Timestamp, Action, Result, UserId, ApiKeyId, Ip, FileId, FileRequestId, Details FROM AuditLog"
+
+// SaveAuditEvent stores a new audit event. The ID of the event is assigned by the database
+func (p DatabaseProvider) SaveAuditEvent(event models.AuditEvent) {
+	_, err := p.sqliteDb.Exec(`INSERT INTO AuditLog (Timestamp, Action, Result, UserId, ApiKeyId, Ip, FileId, FileRequestId, Details)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, event.Timestamp, event.Action, event.Result, event.UserId, event.ApiKeyId,
+		event.Ip, event.FileId, event.FileRequestId, event.Details)
+	helper.Check(err)
+}
+
+// QueryAuditEvents returns all audit events matching the given query, latest first
+func (p DatabaseProvider) QueryAuditEvents(query models.AuditQuery) models.AuditQueryResult {
+	var conditions []string
+	var args []any
+	if query.UserId != 0 {
+		conditions = append(conditions, "UserId = ?")
+		args = append(args, query.UserId)
+	}
+	if query.ApiKeyId != "" {
+		conditions = append(conditions, "ApiKeyId = ?")
+		args = append(args, query.ApiKeyId)
+	}
+	if query.Ip != "" {
+		conditions = append(conditions, "Ip = ?")
+		args = append(args, query.Ip)
+	}
+	if query.FileId != "" {
+		conditions = append(conditions, "FileId = ?")
+		args = append(args, query.FileId)
+	}
+	if query.FileRequestId != "" {
+		conditions = append(conditions, "FileRequestId = ?")
+		args = append(args, query.FileRequestId)
+	}
+	if query.Action != "" {
+		if query.IsActionPrefix() {
+			conditions = append(conditions, `Action LIKE ? ESCAPE '\'`)
+			args = append(args, escapeLike(query.Action)+"%")
+		} else {
+			conditions = append(conditions, "Action = ?")
+			args = append(args, query.Action)
+		}
+	}
+	if query.Result != "" {
+		conditions = append(conditions, "Result = ?")
+		args = append(args, query.Result)
+	}
+	if query.Since != 0 {
+		conditions = append(conditions, "Timestamp >= ?")
+		args = append(args, query.Since)
+	}
+	if query.Until != 0 {
+		conditions = append(conditions, "Timestamp < ?")
+		args = append(args, query.Until)
+	}
+	if query.BeforeId != 0 {
+		conditions = append(conditions, "Id < ?")
+		args = append(args, query.BeforeId)
+	}
+
+	statement := selectAuditEvents
+	if len(conditions) > 0 {
+		statement = statement + " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement = statement + " ORDER BY Id DESC"
+	if query.Limit > 0 {
+		statement = statement + " LIMIT ?"
+		args = append(args, query.Limit+1)
+	}
+
+	result := models.AuditQueryResult{Events: make([]models.AuditEvent, 0)}
+	rows, err := p.sqliteDb.Query(statement, args...)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		var event models.AuditEvent
+		err = rows.Scan(&event.Id, &event.Timestamp, &event.Action, &event.Result, &event.UserId, &event.ApiKeyId,
+			&event.Ip, &event.FileId, &event.FileRequestId, &event.Details)
+		helper.Check(err)
+		result.Events = append(result.Events, event)
+	}
+	if query.Limit > 0 && len(result.Events) > query.Limit {
+		result.Events = result.Events[:query.Limit]
+		result.HasMore = true
+	}
+	return result
+}
+
+// DeleteAuditEventsBefore deletes all audit events that were created before the given timestamp
+func (p DatabaseProvider) DeleteAuditEventsBefore(timestamp int64) {
+	_, err := p.sqliteDb.Exec("DELETE FROM AuditLog WHERE Timestamp < ?", timestamp)
+	helper.Check(err)
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectBundles = `SELECT Id, Name, FileIds, PasswordHash, UserId, ExpireAt, Creation, DownloadsRemaining,
+	DownloadCount, UnlimitedDownloads, UnlimitedTime FROM Bundles`
+
+func scanBundle(row rowScanner) (models.Bundle, error) {
+	var result models.Bundle
+	var unlimitedDownloads, unlimitedTime int
+	err := row.Scan(&result.Id, &result.Name, &result.FileIds, &result.PasswordHash, &result.UserId, &result.ExpireAt,
+		&result.CreationDate, &result.DownloadsRemaining, &result.DownloadCount, &unlimitedDownloads, &unlimitedTime)
+	result.UnlimitedDownloads = unlimitedDownloads == 1
+	result.UnlimitedTime = unlimitedTime == 1
+	return result, err
+}
+
+// GetBundle returns the bundle or false if not found
+func (p DatabaseProvider) GetBundle(id string) (models.Bundle, bool) {
+	result, err := scanBundle(p.sqliteDb.QueryRow(selectBundles+" WHERE Id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Bundle{}, false
+		}
+		helper.Check(err)
+		return models.Bundle{}, false
+	}
+	return result, true
+}
+
+// GetAllBundles returns an array with all bundles, ordered by creation date
+func (p DatabaseProvider) GetAllBundles() []models.Bundle {
+	result := make([]models.Bundle, 0)
+	rows, err := p.sqliteDb.Query(selectBundles + " ORDER BY Creation, Id")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		bundle, err := scanBundle(rows)
+		helper.Check(err)
+		result = append(result, bundle)
+	}
+	return result
+}
+
+// SaveBundle stores the bundle in the database
+func (p DatabaseProvider) SaveBundle(bundle models.Bundle) {
+	unlimitedDownloads := 0
+	unlimitedTime := 0
+	if bundle.UnlimitedDownloads {
+		unlimitedDownloads = 1
+	}
+	if bundle.UnlimitedTime {
+		unlimitedTime = 1
+	}
+	_, err := p.sqliteDb.Exec(`INSERT OR REPLACE INTO Bundles (Id, Name, FileIds, PasswordHash, UserId, ExpireAt,
+		Creation, DownloadsRemaining, DownloadCount, UnlimitedDownloads, UnlimitedTime)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		bundle.Id, bundle.Name, bundle.FileIds, bundle.PasswordHash, bundle.UserId, bundle.ExpireAt, bundle.CreationDate,
+		bundle.DownloadsRemaining, bundle.DownloadCount, unlimitedDownloads, unlimitedTime)
+	helper.Check(err)
+}
+
+// IncreaseBundleDownloadCount increases the download count of a bundle, preventing race conditions
+func (p DatabaseProvider) IncreaseBundleDownloadCount(id string, decreaseRemainingDownloads bool) {
+	if decreaseRemainingDownloads {
+		_, err := p.sqliteDb.Exec(`UPDATE Bundles SET DownloadCount = DownloadCount + 1,
+                   DownloadsRemaining = DownloadsRemaining - 1 WHERE Id = ?`, id)
+		helper.Check(err)
+	} else {
+		_, err := p.sqliteDb.Exec(`UPDATE Bundles SET DownloadCount = DownloadCount + 1 WHERE Id = ?`, id)
+		helper.Check(err)
+	}
+}
+
+// DeleteBundle deletes the bundle with the given ID
+func (p DatabaseProvider) DeleteBundle(id string) {
+	_, err := p.sqliteDb.Exec("DELETE FROM Bundles WHERE Id = ?", id)
+	helper.Check(err)
+}
@@ -18,6 +18,10 @@ type schemaFileRequests struct {
 	Creation int64
 	ApiKey   string
 	Note     string
+	// Access restriction
+	RequireLogin  bool
+	AllowedUsers  string
+	AllowedGroups string
 }
 
 // GetFileRequest returns the FileRequest or false if not found
@@ -28,7 +32,8 @@ func (p DatabaseProvider) GetFileRequest(id string) (models.FileRequest, bool) {
 	var rowResult schemaFileRequests
 	row := p.sqliteDb.QueryRow("SELECT * FROM UploadRequests WHERE Id = ?", id)
 	err := row.Scan(&rowResult.Id, &rowResult.Name, &rowResult.UserId, &rowResult.Expiry,
-		&rowResult.MaxFiles, &rowResult.MaxSize, &rowResult.Creation, &rowResult.ApiKey, &rowResult.Note)
+		&rowResult.MaxFiles, &rowResult.MaxSize, &rowResult.Creation, &rowResult.ApiKey, &rowResult.Note,
+		&rowResult.RequireLogin, &rowResult.AllowedUsers, &rowResult.AllowedGroups)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.FileRequest{}, false
@@ -37,15 +42,18 @@ func (p DatabaseProvider) GetFileRequest(id string) (models.FileRequest, bool) {
 		return models.FileRequest{}, false
 	}
 	result := models.FileRequest{
-		Id:           rowResult.Id,
-		Name:         rowResult.Name,
-		UserId:       rowResult.UserId,
-		MaxFiles:     rowResult.MaxFiles,
-		MaxSize:      rowResult.MaxSize,
-		Expiry:       rowResult.Expiry,
-		CreationDate: rowResult.Creation,
-		ApiKey:       rowResult.ApiKey,
-		Notes:        rowResult.Note,
+		Id:            rowResult.Id,
+		Name:          rowResult.Name,
+		UserId:        rowResult.UserId,
+		MaxFiles:      rowResult.MaxFiles,
+		MaxSize:       rowResult.MaxSize,
+		Expiry:        rowResult.Expiry,
+		CreationDate:  rowResult.Creation,
+		ApiKey:        rowResult.ApiKey,
+		Notes:         rowResult.Note,
+		RequireLogin:  rowResult.RequireLogin,
+		AllowedUsers:  rowResult.AllowedUsers,
+		AllowedGroups: rowResult.AllowedGroups,
 	}
 	return result, true
 }
@@ -59,18 +67,22 @@ func (p DatabaseProvider) GetAllFileRequests() []models.FileRequest {
 	for rows.Next() {
 		rowData := schemaFileRequests{}
 		err = rows.Scan(&rowData.Id, &rowData.Name, &rowData.UserId, &rowData.Expiry, &rowData.MaxFiles,
-			&rowData.MaxSize, &rowData.Creation, &rowData.ApiKey, &rowData.Note)
+			&rowData.MaxSize, &rowData.Creation, &rowData.ApiKey, &rowData.Note,
+			&rowData.RequireLogin, &rowData.AllowedUsers, &rowData.AllowedGroups)
 		helper.Check(err)
 		result = append(result, models.FileRequest{
-			Id:           rowData.Id,
-			Name:         rowData.Name,
-			UserId:       rowData.UserId,
-			MaxFiles:     rowData.MaxFiles,
-			MaxSize:      rowData.MaxSize,
-			Expiry:       rowData.Expiry,
-			CreationDate: rowData.Creation,
-			ApiKey:       rowData.ApiKey,
-			Notes:        rowData.Note,
+			Id:            rowData.Id,
+			Name:          rowData.Name,
+			UserId:        rowData.UserId,
+			MaxFiles:      rowData.MaxFiles,
+			MaxSize:       rowData.MaxSize,
+			Expiry:        rowData.Expiry,
+			CreationDate:  rowData.Creation,
+			ApiKey:        rowData.ApiKey,
+			Notes:         rowData.Note,
+			RequireLogin:  rowData.RequireLogin,
+			AllowedUsers:  rowData.AllowedUsers,
+			AllowedGroups: rowData.AllowedGroups,
 		})
 	}
 	return result
@@ -88,12 +100,17 @@ func (p DatabaseProvider) SaveFileRequest(request models.FileRequest) {
 		Creation: request.CreationDate,
 		ApiKey:   request.ApiKey,
 		Note:     request.Notes,
+
+		RequireLogin:  request.RequireLogin,
+		AllowedUsers:  request.AllowedUsers,
+		AllowedGroups: request.AllowedGroups,
 	}
 
 	_, err := p.sqliteDb.Exec(`INSERT OR REPLACE INTO UploadRequests
-   				 (id, name, userid, expiry, maxFiles, maxSize, creation, apiKey, note) 
-         			 VALUES  (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		newData.Id, newData.Name, newData.UserId, newData.Expiry, newData.MaxFiles, newData.MaxSize, newData.Creation, newData.ApiKey, newData.Note)
+   				 (id, name, userid, expiry, maxFiles, maxSize, creation, apiKey, note, requireLogin, allowedUsers, allowedGroups) 
+         			 VALUES  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		newData.Id, newData.Name, newData.UserId, newData.Expiry, newData.MaxFiles, newData.MaxSize, newData.Creation, newData.ApiKey, newData.Note,
+		newData.RequireLogin, newData.AllowedUsers, newData.AllowedGroups)
 	helper.Check(err)
 }
 
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectFileVersions = `SELECT Id, FileId, Revision, Name, Size, SHA1, ContentType, AwsBucket, SizeBytes, ReplacedAt,
+	ReplacedBy, Encryption FROM FileVersions`
+
+func scanFileVersion(row rowScanner) (models.FileVersion, error) {
+	var result models.FileVersion
+	var encryption []byte
+	err := row.Scan(&result.Id, &result.FileId, &result.Revision, &result.Name, &result.Size, &result.SHA1, &result.ContentType,
+		&result.AwsBucket, &result.SizeBytes, &result.ReplacedAt, &result.ReplacedBy, &encryption)
+	if err != nil {
+		return result, err
+	}
+	dec := gob.NewDecoder(bytes.NewBuffer(encryption))
+	err = dec.Decode(&result.Encryption)
+	return result, err
+}
+
+func (p DatabaseProvider) queryFileVersions(query string, args ...any) []models.FileVersion {
+	result := make([]models.FileVersion, 0)
+	rows, err := p.sqliteDb.Query(query, args...)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		version, err := scanFileVersion(rows)
+		helper.Check(err)
+		result = append(result, version)
+	}
+	return result
+}
+
+// GetFileVersion returns the file version or false if not found
+func (p DatabaseProvider) GetFileVersion(id string) (models.FileVersion, bool) {
+	result, err := scanFileVersion(p.sqliteDb.QueryRow(selectFileVersions+" WHERE Id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.FileVersion{}, false
+		}
+		helper.Check(err)
+		return models.FileVersion{}, false
+	}
+	return result, true
+}
+
+// GetFileVersions returns all versions of the given file, latest first
+func (p DatabaseProvider) GetFileVersions(fileId string) []models.FileVersion {
+	return p.queryFileVersions(selectFileVersions+" WHERE FileId = ? ORDER BY Revision DESC", fileId)
+}
+
+// GetAllFileVersions returns an array with all file versions, ordered by replacement date
+func (p DatabaseProvider) GetAllFileVersions() []models.FileVersion {
+	return p.queryFileVersions(selectFileVersions + " ORDER BY ReplacedAt, Id")
+}
+
+// SaveFileVersion stores the file version in the database
+func (p DatabaseProvider) SaveFileVersion(version models.FileVersion) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(version.Encryption)
+	helper.Check(err)
+	_, err = p.sqliteDb.Exec(`INSERT OR REPLACE INTO FileVersions (Id, FileId, Revision, Name, Size, SHA1, ContentType,
+		AwsBucket, SizeBytes, ReplacedAt, ReplacedBy, Encryption) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		version.Id, version.FileId, version.Revision, version.Name, version.Size, version.SHA1, version.ContentType,
+		version.AwsBucket, version.SizeBytes, version.ReplacedAt, version.ReplacedBy, buf.Bytes())
+	helper.Check(err)
+}
+
+// DeleteFileVersion deletes the file version with the given ID
+func (p DatabaseProvider) DeleteFileVersion(id string) {
+	_, err := p.sqliteDb.Exec("DELETE FROM FileVersions WHERE Id = ?", id)
+	helper.Check(err)
+}
@@ -3,13 +3,31 @@ package sqlite
 import (
 	"bytes"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/gob"
 	"errors"
+	"strings"
 
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
+	"modernc.org/sqlite"
 )
 
+// The built-in lower() and LIKE of SQLite only ignore the case of ASCII characters. unicode_lower()
+// lowercases a text the same way as models.FileQuery and the other database providers
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("unicode_lower", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch value := args[0].(type) {
+		case string:
+			return strings.ToLower(value), nil
+		case []byte:
+			return strings.ToLower(string(value)), nil
+		default:
+			return value, nil
+		}
+	})
+}
+
 type schemaMetaData struct {
 	Id                 string
 	Name               string
@@ -30,6 +48,13 @@ type schemaMetaData struct {
 	UploadDate         int64
 	PendingDeletion    int64
 	UploadRequestId    string
+	IsEncrypted        int
+	ProcessingState    int
+	QuarantineReason   string
+	PublishAt          int64
+	RequireLogin       int
+	AllowedUsers       string
+	AllowedGroups      string
 }
 
 func (rowData schemaMetaData) ToFileModel() (models.File, error) {
@@ -53,6 +78,12 @@ func (rowData schemaMetaData) ToFileModel() (models.File, error) {
 		UploadDate:         rowData.UploadDate,
 		PendingDeletion:    rowData.PendingDeletion,
 		UploadRequestId:    rowData.UploadRequestId,
+		ProcessingState:    rowData.ProcessingState,
+		QuarantineReason:   rowData.QuarantineReason,
+		PublishAt:          rowData.PublishAt,
+		RequireLogin:       rowData.RequireLogin == 1,
+		AllowedUsers:       rowData.AllowedUsers,
+		AllowedGroups:      rowData.AllowedGroups,
 	}
 
 	buf := bytes.NewBuffer(rowData.Encryption)
@@ -61,46 +92,158 @@ func (rowData schemaMetaData) ToFileModel() (models.File, error) {
 	return result, err
 }
 
+const selectMetaData = `SELECT Id, Name, Size, SHA1, ExpireAt, SizeBytes, DownloadsRemaining, DownloadCount, PasswordHash,
+		HotlinkId, ContentType, AwsBucket, Encryption, UnlimitedDownloads, UnlimitedTime, UserId, UploadDate,
+		PendingDeletion, UploadRequestId, ProcessingState, QuarantineReason, PublishAt, RequireLogin,
+		AllowedUsers, AllowedGroups FROM FileMetaData`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMetaData(row rowScanner) (schemaMetaData, error) {
+	rowData := schemaMetaData{}
+	err := row.Scan(&rowData.Id, &rowData.Name, &rowData.Size, &rowData.SHA1, &rowData.ExpireAt, &rowData.SizeBytes,
+		&rowData.DownloadsRemaining, &rowData.DownloadCount, &rowData.PasswordHash, &rowData.HotlinkId, &rowData.ContentType,
+		&rowData.AwsBucket, &rowData.Encryption, &rowData.UnlimitedDownloads, &rowData.UnlimitedTime, &rowData.UserId,
+		&rowData.UploadDate, &rowData.PendingDeletion, &rowData.UploadRequestId, &rowData.ProcessingState, &rowData.QuarantineReason,
+		&rowData.PublishAt, &rowData.RequireLogin, &rowData.AllowedUsers, &rowData.AllowedGroups)
+	return rowData, err
+}
+
 // GetAllMetadata returns a map of all available files
 func (p DatabaseProvider) GetAllMetadata() map[string]models.File {
 	result := make(map[string]models.File)
-	rows, err := p.sqliteDb.Query("SELECT * FROM FileMetaData")
+	rows, err := p.sqliteDb.Query(selectMetaData)
 	helper.Check(err)
 	defer rows.Close()
 	for rows.Next() {
-		rowData := schemaMetaData{}
-		err = rows.Scan(&rowData.Id, &rowData.Name, &rowData.Size, &rowData.SHA1, &rowData.ExpireAt, &rowData.SizeBytes,
-			&rowData.DownloadsRemaining, &rowData.DownloadCount, &rowData.PasswordHash, &rowData.HotlinkId, &rowData.ContentType,
-			&rowData.AwsBucket, &rowData.Encryption, &rowData.UnlimitedDownloads, &rowData.UnlimitedTime, &rowData.UserId,
-			&rowData.UploadDate, &rowData.PendingDeletion, &rowData.UploadRequestId)
+		rowData, err := scanMetaData(rows)
 		helper.Check(err)
-		var metaData models.File
-		metaData, err = rowData.ToFileModel()
+		metaData, err := rowData.ToFileModel()
 		helper.Check(err)
 		result[metaData.Id] = metaData
 	}
 	return result
 }
 
+// QueryMetaData returns all files matching the given query. Filtering, sorting and pagination is done by the database
+func (p DatabaseProvider) QueryMetaData(query models.FileQuery) models.FileQueryResult {
+	var conditions []string
+	var args []any
+	if query.UserId != 0 {
+		conditions = append(conditions, "UserId = ?")
+		args = append(args, query.UserId)
+	}
+	if query.FileRequestId != "" {
+		conditions = append(conditions, "UploadRequestId = ?")
+		args = append(args, query.FileRequestId)
+	} else if !query.IncludeFileRequests {
+		conditions = append(conditions, "UploadRequestId = ''")
+	}
+	if query.NameContains != "" {
+		conditions = append(conditions, "instr(unicode_lower(Name), ?) > 0")
+		args = append(args, strings.ToLower(query.NameContains))
+	}
+	if query.ContentTypePrefix != "" {
+		conditions = append(conditions, "instr(unicode_lower(ContentType), ?) = 1")
+		args = append(args, strings.ToLower(query.ContentTypePrefix))
+	}
+	switch query.Encryption {
+	case models.FileFilterEncryptionOnly:
+		conditions = append(conditions, "IsEncrypted = 1")
+	case models.FileFilterEncryptionNone:
+		conditions = append(conditions, "IsEncrypted = 0")
+	}
+	if query.ExpiresAfter != 0 {
+		conditions = append(conditions, "(UnlimitedTime = 1 OR ExpireAt > ?)")
+		args = append(args, query.ExpiresAfter)
+	}
+	if query.ExpiresBefore != 0 {
+		conditions = append(conditions, "(UnlimitedTime = 0 AND ExpireAt < ?)")
+		args = append(args, query.ExpiresBefore)
+	}
+	if query.HideExpiredAt != 0 {
+		conditions = append(conditions, "(UnlimitedTime = 1 OR ExpireAt >= ?) AND (UnlimitedDownloads = 1 OR DownloadsRemaining >= 1)")
+		args = append(args, query.HideExpiredAt)
+	}
+
+	column := getSortColumn(query.SortBy)
+	direction := "ASC"
+	comparator := ">"
+	if query.SortDescending {
+		direction = "DESC"
+		comparator = "<"
+	}
+	if query.HasCursor() {
+		var cursorValue any = query.CursorValue
+		if query.SortBy == models.FileSortName {
+			cursorValue = query.CursorName
+		}
+		conditions = append(conditions, "("+column+" "+comparator+" ? OR ("+column+" = ? AND Id "+comparator+" ?))")
+		args = append(args, cursorValue, cursorValue, query.CursorId)
+	}
+
+	statement := selectMetaData
+	if len(conditions) > 0 {
+		statement = statement + " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement = statement + " ORDER BY " + column + " " + direction + ", Id " + direction
+	if query.Limit > 0 {
+		statement = statement + " LIMIT ?"
+		args = append(args, query.Limit+1)
+	}
+
+	result := models.FileQueryResult{Files: make([]models.File, 0)}
+	rows, err := p.sqliteDb.Query(statement, args...)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		rowData, err := scanMetaData(rows)
+		helper.Check(err)
+		metaData, err := rowData.ToFileModel()
+		helper.Check(err)
+		result.Files = append(result.Files, metaData)
+	}
+	if query.Limit > 0 && len(result.Files) > query.Limit {
+		result.Files = result.Files[:query.Limit]
+		result.HasMore = true
+	}
+	return result
+}
+
+func getSortColumn(sortBy int) string {
+	switch sortBy {
+	case models.FileSortName:
+		return "Name"
+	case models.FileSortSize:
+		return "SizeBytes"
+	case models.FileSortExpiry:
+		return "ExpireAt"
+	case models.FileSortDownloads:
+		return "DownloadCount"
+	default:
+		return "UploadDate"
+	}
+}
+
+// escapeLike escapes all wildcard characters of a LIKE pattern with a backslash
+func escapeLike(input string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+	return replacer.Replace(input)
+}
+
 // GetMetaDataById returns a models.File from the ID passed or false if the id is not valid
 func (p DatabaseProvider) GetMetaDataById(id string) (models.File, bool) {
-	result := models.File{}
-	rowData := schemaMetaData{}
-
-	row := p.sqliteDb.QueryRow("SELECT * FROM FileMetaData WHERE Id = ?", id)
-	err := row.Scan(&rowData.Id, &rowData.Name, &rowData.Size, &rowData.SHA1, &rowData.ExpireAt, &rowData.SizeBytes,
-		&rowData.DownloadsRemaining, &rowData.DownloadCount, &rowData.PasswordHash,
-		&rowData.HotlinkId, &rowData.ContentType, &rowData.AwsBucket, &rowData.Encryption,
-		&rowData.UnlimitedDownloads, &rowData.UnlimitedTime, &rowData.UserId, &rowData.UploadDate,
-		&rowData.PendingDeletion, &rowData.UploadRequestId)
+	rowData, err := scanMetaData(p.sqliteDb.QueryRow(selectMetaData+" WHERE Id = ?", id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return result, false
+			return models.File{}, false
 		}
 		helper.Check(err)
-		return result, false
+		return models.File{}, false
 	}
-	result, err = rowData.ToFileModel()
+	result, err := rowData.ToFileModel()
 	helper.Check(err)
 	return result, true
 }
@@ -124,6 +267,11 @@ func (p DatabaseProvider) SaveMetaData(file models.File) {
 		UploadDate:         file.UploadDate,
 		PendingDeletion:    file.PendingDeletion,
 		UploadRequestId:    file.UploadRequestId,
+		ProcessingState:    file.ProcessingState,
+		QuarantineReason:   file.QuarantineReason,
+		PublishAt:          file.PublishAt,
+		AllowedUsers:       file.AllowedUsers,
+		AllowedGroups:      file.AllowedGroups,
 	}
 
 	if file.UnlimitedDownloads {
@@ -132,6 +280,12 @@ func (p DatabaseProvider) SaveMetaData(file models.File) {
 	if file.UnlimitedTime {
 		newData.UnlimitedTime = 1
 	}
+	if file.Encryption.IsEncrypted {
+		newData.IsEncrypted = 1
+	}
+	if file.RequireLogin {
+		newData.RequireLogin = 1
+	}
 
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
@@ -141,12 +295,14 @@ func (p DatabaseProvider) SaveMetaData(file models.File) {
 
 	_, err = p.sqliteDb.Exec(`INSERT OR REPLACE INTO FileMetaData (Id, Name, Size, SHA1, ExpireAt, SizeBytes, 
                                    DownloadsRemaining, DownloadCount, PasswordHash, HotlinkId, ContentType, AwsBucket, Encryption,
-                                   UnlimitedDownloads, UnlimitedTime, UserId, UploadDate, PendingDeletion, UploadRequestId)
-          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,?)`,
+                                   UnlimitedDownloads, UnlimitedTime, UserId, UploadDate, PendingDeletion, UploadRequestId, IsEncrypted,
+                                   ProcessingState, QuarantineReason, PublishAt, RequireLogin, AllowedUsers, AllowedGroups)
+          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		newData.Id, newData.Name, newData.Size, newData.SHA1, newData.ExpireAt, newData.SizeBytes,
 		newData.DownloadsRemaining, newData.DownloadCount, newData.PasswordHash, newData.HotlinkId, newData.ContentType,
 		newData.AwsBucket, newData.Encryption, newData.UnlimitedDownloads, newData.UnlimitedTime, newData.UserId, newData.UploadDate,
-		newData.PendingDeletion, newData.UploadRequestId)
+		newData.PendingDeletion, newData.UploadRequestId, newData.IsEncrypted, newData.ProcessingState, newData.QuarantineReason,
+		newData.PublishAt, newData.RequireLogin, newData.AllowedUsers, newData.AllowedGroups)
 	helper.Check(err)
 }
 
@@ -10,17 +10,20 @@ import (
 )
 
 type schemaSessions struct {
-	Id         string
-	RenewAt    int64
-	ValidUntil int64
-	UserId     int
+	Id            string
+	RenewAt       int64
+	ValidUntil    int64
+	UserId        int
+	Groups        string
+	RecipientName string
 }
 
 // GetSession returns the session with the given ID or false if not a valid ID
 func (p DatabaseProvider) GetSession(id string) (models.Session, bool) {
 	var rowResult schemaSessions
 	row := p.sqliteDb.QueryRow("SELECT * FROM Sessions WHERE Id = ?", id)
-	err := row.Scan(&rowResult.Id, &rowResult.RenewAt, &rowResult.ValidUntil, &rowResult.UserId)
+	err := row.Scan(&rowResult.Id, &rowResult.RenewAt, &rowResult.ValidUntil, &rowResult.UserId,
+		&rowResult.Groups, &rowResult.RecipientName)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.Session{}, false
@@ -29,9 +32,11 @@ func (p DatabaseProvider) GetSession(id string) (models.Session, bool) {
 		return models.Session{}, false
 	}
 	result := models.Session{
-		RenewAt:    rowResult.RenewAt,
-		ValidUntil: rowResult.ValidUntil,
-		UserId:     rowResult.UserId,
+		RenewAt:       rowResult.RenewAt,
+		ValidUntil:    rowResult.ValidUntil,
+		UserId:        rowResult.UserId,
+		Groups:        rowResult.Groups,
+		RecipientName: rowResult.RecipientName,
 	}
 	return result, true
 }
@@ -39,14 +44,17 @@ func (p DatabaseProvider) GetSession(id string) (models.Session, bool) {
 // SaveSession stores the given session. After the expiry passed, it will be deleted automatically
 func (p DatabaseProvider) SaveSession(id string, session models.Session) {
 	newData := schemaSessions{
-		Id:         id,
-		RenewAt:    session.RenewAt,
-		ValidUntil: session.ValidUntil,
-		UserId:     session.UserId,
+		Id:            id,
+		RenewAt:       session.RenewAt,
+		ValidUntil:    session.ValidUntil,
+		UserId:        session.UserId,
+		Groups:        session.Groups,
+		RecipientName: session.RecipientName,
 	}
 
-	_, err := p.sqliteDb.Exec("INSERT OR REPLACE INTO Sessions (Id, RenewAt, ValidUntil, UserId) VALUES (?, ?, ?, ?)",
-		newData.Id, newData.RenewAt, newData.ValidUntil, newData.UserId)
+	_, err := p.sqliteDb.Exec(`INSERT OR REPLACE INTO Sessions (Id, RenewAt, ValidUntil, UserId, Groups, RecipientName)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		newData.Id, newData.RenewAt, newData.ValidUntil, newData.UserId, newData.Groups, newData.RecipientName)
 	helper.Check(err)
 }
 
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectShareLinks = `SELECT Id, FileId, Name, PasswordHash, UserId, ExpireAt, Creation, DownloadsRemaining,
+	DownloadCount, UnlimitedDownloads, UnlimitedTime FROM ShareLinks`
+
+func scanShareLink(row rowScanner) (models.ShareLink, error) {
+	var result models.ShareLink
+	var unlimitedDownloads, unlimitedTime int
+	err := row.Scan(&result.Id, &result.FileId, &result.Name, &result.PasswordHash, &result.UserId, &result.ExpireAt,
+		&result.CreationDate, &result.DownloadsRemaining, &result.DownloadCount, &unlimitedDownloads, &unlimitedTime)
+	result.UnlimitedDownloads = unlimitedDownloads == 1
+	result.UnlimitedTime = unlimitedTime == 1
+	return result, err
+}
+
+// GetShareLink returns the share link or false if not found
+func (p DatabaseProvider) GetShareLink(id string) (models.ShareLink, bool) {
+	result, err := scanShareLink(p.sqliteDb.QueryRow(selectShareLinks+" WHERE Id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ShareLink{}, false
+		}
+		helper.Check(err)
+		return models.ShareLink{}, false
+	}
+	return result, true
+}
+
+// GetAllShareLinks returns an array with all share links, ordered by creation date
+func (p DatabaseProvider) GetAllShareLinks() []models.ShareLink {
+	result := make([]models.ShareLink, 0)
+	rows, err := p.sqliteDb.Query(selectShareLinks + " ORDER BY Creation, Id")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		helper.Check(err)
+		result = append(result, link)
+	}
+	return result
+}
+
+// SaveShareLink stores the share link in the database
+func (p DatabaseProvider) SaveShareLink(link models.ShareLink) {
+	unlimitedDownloads := 0
+	unlimitedTime := 0
+	if link.UnlimitedDownloads {
+		unlimitedDownloads = 1
+	}
+	if link.UnlimitedTime {
+		unlimitedTime = 1
+	}
+	_, err := p.sqliteDb.Exec(`INSERT OR REPLACE INTO ShareLinks (Id, FileId, Name, PasswordHash, UserId, ExpireAt,
+		Creation, DownloadsRemaining, DownloadCount, UnlimitedDownloads, UnlimitedTime)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		link.Id, link.FileId, link.Name, link.PasswordHash, link.UserId, link.ExpireAt, link.CreationDate,
+		link.DownloadsRemaining, link.DownloadCount, unlimitedDownloads, unlimitedTime)
+	helper.Check(err)
+}
+
+// IncreaseShareLinkDownloadCount increases the download count of a share link, preventing race conditions
+func (p DatabaseProvider) IncreaseShareLinkDownloadCount(id string, decreaseRemainingDownloads bool) {
+	if decreaseRemainingDownloads {
+		_, err := p.sqliteDb.Exec(`UPDATE ShareLinks SET DownloadCount = DownloadCount + 1,
+                   DownloadsRemaining = DownloadsRemaining - 1 WHERE Id = ?`, id)
+		helper.Check(err)
+	} else {
+		_, err := p.sqliteDb.Exec(`UPDATE ShareLinks SET DownloadCount = DownloadCount + 1 WHERE Id = ?`, id)
+		helper.Check(err)
+	}
+}
+
+// DeleteShareLink deletes the share link with the given ID
+func (p DatabaseProvider) DeleteShareLink(id string) {
+	_, err := p.sqliteDb.Exec("DELETE FROM ShareLinks WHERE Id = ?", id)
+	helper.Check(err)
+}
@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// GetUserTotp returns the TOTP settings of a user or false if not found
+func (p DatabaseProvider) GetUserTotp(userId int) (models.UserTotp, bool) {
+	var result models.UserTotp
+	var isConfirmed int
+	row := p.sqliteDb.QueryRow("SELECT UserId, Secret, RecoveryCodes, IsConfirmed, LastUsedStep FROM UserTotp WHERE UserId = ?", userId)
+	err := row.Scan(&result.UserId, &result.Secret, &result.RecoveryCodes, &isConfirmed, &result.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserTotp{}, false
+		}
+		helper.Check(err)
+		return models.UserTotp{}, false
+	}
+	result.IsConfirmed = isConfirmed == 1
+	return result, true
+}
+
+// SaveUserTotp stores the TOTP settings of a user
+func (p DatabaseProvider) SaveUserTotp(totp models.UserTotp) {
+	isConfirmed := 0
+	if totp.IsConfirmed {
+		isConfirmed = 1
+	}
+	_, err := p.sqliteDb.Exec(`INSERT OR REPLACE INTO UserTotp (UserId, Secret, RecoveryCodes, IsConfirmed, LastUsedStep)
+		VALUES (?, ?, ?, ?, ?)`, totp.UserId, totp.Secret, totp.RecoveryCodes, isConfirmed, totp.LastUsedStep)
+	helper.Check(err)
+}
+
+// DeleteUserTotp deletes the TOTP settings of a user
+func (p DatabaseProvider) DeleteUserTotp(userId int) {
+	_, err := p.sqliteDb.Exec("DELETE FROM UserTotp WHERE UserId = ?", userId)
+	helper.Check(err)
+}
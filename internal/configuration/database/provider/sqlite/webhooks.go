@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const selectWebhooks = "SELECT Id, Url, Secret, Events, UserId, Creation FROM Webhooks"
+const selectWebhookDeliveries = "SELECT Id, WebhookId, Event, Payload, Attempts, NextAttempt, LastError FROM WebhookDeliveries"
+
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var result models.Webhook
+	err := row.Scan(&result.Id, &result.Url, &result.Secret, &result.Events, &result.UserId, &result.CreationDate)
+	return result, err
+}
+
+// GetWebhook returns the webhook or false if not found
+func (p DatabaseProvider) GetWebhook(id string) (models.Webhook, bool) {
+	result, err := scanWebhook(p.sqliteDb.QueryRow(selectWebhooks+" WHERE Id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Webhook{}, false
+		}
+		helper.Check(err)
+		return models.Webhook{}, false
+	}
+	return result, true
+}
+
+// GetAllWebhooks returns an array with all webhooks, ordered by creation date
+func (p DatabaseProvider) GetAllWebhooks() []models.Webhook {
+	result := make([]models.Webhook, 0)
+	rows, err := p.sqliteDb.Query(selectWebhooks + " ORDER BY Creation, Id")
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		helper.Check(err)
+		result = append(result, webhook)
+	}
+	return result
+}
+
+// SaveWebhook stores the webhook in the database
+func (p DatabaseProvider) SaveWebhook(webhook models.Webhook) {
+	_, err := p.sqliteDb.Exec(`INSERT OR REPLACE INTO Webhooks (Id, Url, Secret, Events, UserId, Creation)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		webhook.Id, webhook.Url, webhook.Secret, webhook.Events, webhook.UserId, webhook.CreationDate)
+	helper.Check(err)
+}
+
+// DeleteWebhook deletes the webhook with the given ID and all queued deliveries for it
+func (p DatabaseProvider) DeleteWebhook(id string) {
+	_, err := p.sqliteDb.Exec("DELETE FROM WebhookDeliveries WHERE WebhookId = ?", id)
+	helper.Check(err)
+	_, err = p.sqliteDb.Exec("DELETE FROM Webhooks WHERE Id = ?", id)
+	helper.Check(err)
+}
+
+// GetDueWebhookDeliveries returns up to limit queued deliveries, that are due at the given timestamp.
+// The deliveries are ordered by the time of their next attempt
+func (p DatabaseProvider) GetDueWebhookDeliveries(timestamp int64, limit int) []models.WebhookDelivery {
+	result := make([]models.WebhookDelivery, 0)
+	rows, err := p.sqliteDb.Query(selectWebhookDeliveries+" WHERE NextAttempt <= ? ORDER BY NextAttempt, Id LIMIT ?", timestamp, limit)
+	helper.Check(err)
+	defer rows.Close()
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		err = rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.Event, &delivery.Payload, &delivery.Attempts,
+			&delivery.NextAttempt, &delivery.LastError)
+		helper.Check(err)
+		result = append(result, delivery)
+	}
+	return result
+}
+
+// SaveWebhookDelivery adds the delivery to the queue or updates it
+func (p DatabaseProvider) SaveWebhookDelivery(delivery models.WebhookDelivery) {
+	_, err := p.sqliteDb.Exec(`INSERT OR REPLACE INTO WebhookDeliveries (Id, WebhookId, Event, Payload, Attempts, NextAttempt, LastError)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		delivery.Id, delivery.WebhookId, delivery.Event, delivery.Payload, delivery.Attempts, delivery.NextAttempt, delivery.LastError)
+	helper.Check(err)
+}
+
+// DeleteWebhookDelivery removes the delivery with the given ID from the queue
+func (p DatabaseProvider) DeleteWebhookDelivery(id string) {
+	_, err := p.sqliteDb.Exec("DELETE FROM WebhookDeliveries WHERE Id = ?", id)
+	helper.Check(err)
+}
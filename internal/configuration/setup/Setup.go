@@ -33,6 +33,7 @@ import (
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/webserver/authentication/ldap"
 )
 
 // webserverDir is the embedded version of the "static" folder
@@ -228,9 +229,12 @@ type authSettings struct {
 	UserInternalAuth          string
 	UserOAuth                 string
 	UserHeader                string
+	UserLdap                  string
 	PasswordInternalAuth      string
 	OnlyRegisteredUsersOAuth  bool
 	OnlyRegisteredUsersHeader bool
+	OnlyRegisteredUsersLdap   bool
+	RequireTwoFactorForAdmins bool
 }
 
 func toConfiguration(formObjects *[]jsonFormObject) (models.Configuration, *cloudconfig.CloudConfig, configuration.End2EndReconfigParameters, authSettings, error) {
@@ -276,6 +280,11 @@ func toConfiguration(formObjects *[]jsonFormObject) (models.Configuration, *clou
 		return models.Configuration{}, nil, configuration.End2EndReconfigParameters{}, authSettings{}, err
 	}
 
+	err = parseLdapSettings(&result, &authInfo, formObjects)
+	if err != nil {
+		return models.Configuration{}, nil, configuration.End2EndReconfigParameters{}, authSettings{}, err
+	}
+
 	err = parseServerSettings(&result, formObjects)
 	if err != nil {
 		return models.Configuration{}, nil, configuration.End2EndReconfigParameters{}, authSettings{}, err
@@ -301,10 +310,19 @@ func toConfiguration(formObjects *[]jsonFormObject) (models.Configuration, *clou
 	case models.AuthenticationHeader:
 		result.Authentication.Username = authInfo.UserHeader
 		result.Authentication.OnlyRegisteredUsers = authInfo.OnlyRegisteredUsersHeader
+	case models.AuthenticationLdap:
+		result.Authentication.Username = authInfo.UserLdap
+		result.Authentication.OnlyRegisteredUsers = authInfo.OnlyRegisteredUsersLdap
+		err = ldap.CheckConfig(result.Authentication)
+		if err != nil {
+			return models.Configuration{}, nil, configuration.End2EndReconfigParameters{}, authSettings{}, err
+		}
 	case models.AuthenticationDisabled:
 		result.Authentication.Username = "admin@gokapi"
 	}
 	result.Authentication.Username = strings.ToLower(result.Authentication.Username)
+	result.Authentication.RequireTwoFactorForAdmins = result.Authentication.Method == models.AuthenticationInternal &&
+		authInfo.RequireTwoFactorForAdmins
 
 	return result, cloudSettings, e2eConfig, authInfo, nil
 }
@@ -364,6 +382,20 @@ func parseDatabaseSettings(result *models.Configuration, formObjects *[]jsonForm
 		dbUrl.RawQuery = query.Encode()
 		result.DatabaseUrl = dbUrl.String()
 		return nil
+	case dbabstraction.TypePostgres:
+		pgUrl, err := getFormValueString(formObjects, "postgres_url")
+		if err != nil {
+			return err
+		}
+		dbSettings, err := database.ParseUrl(pgUrl, false)
+		if err != nil {
+			return err
+		}
+		if dbSettings.Type != dbabstraction.TypePostgres {
+			return errors.New("invalid PostgreSQL URL, it must start with postgres://")
+		}
+		result.DatabaseUrl = pgUrl
+		return nil
 	default:
 		return errors.New("unsupported database selected")
 	}
@@ -372,7 +404,7 @@ func parseDatabaseSettings(result *models.Configuration, formObjects *[]jsonForm
 // checkForAllDbValues tests if all values were passed, even if they were not required for this particular database
 // This is done to ensure that no invalid form was passed and makes testing easier
 func checkForAllDbValues(formObjects *[]jsonFormObject) error {
-	expectedValues := []string{"dbtype_sel", "sqlite_location", "redis_location", "redis_prefix", "redis_user", "redis_password"}
+	expectedValues := []string{"dbtype_sel", "sqlite_location", "redis_location", "redis_prefix", "redis_user", "redis_password", "postgres_url"}
 	for _, value := range expectedValues {
 		_, err := getFormValueString(formObjects, value)
 		if err != nil {
@@ -399,6 +431,11 @@ func parseBasicAuthSettings(result *models.Configuration, authInfo *authSettings
 	if isInitialSetup || pw != "unc" {
 		authInfo.PasswordInternalAuth = configuration.HashPassword(pw, false, "")
 	}
+
+	authInfo.RequireTwoFactorForAdmins, err = getFormValueBool(formObjects, "auth_require_2fa_admins")
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -468,6 +505,11 @@ func parseHeaderAuthSettings(result *models.Configuration, authInfo *authSetting
 	if err != nil {
 		return err
 	}
+	groupKey, err := getFormValueString(formObjects, "auth_header_groupkey")
+	if err != nil {
+		return err
+	}
+	result.Authentication.HeaderGroupKey = strings.TrimSpace(groupKey)
 
 	authInfo.OnlyRegisteredUsersHeader, err = getFormValueBool(formObjects, "auth_header_only_registered_users")
 	if err != nil {
@@ -476,6 +518,86 @@ func parseHeaderAuthSettings(result *models.Configuration, authInfo *authSetting
 	return nil
 }
 
+func parseLdapSettings(result *models.Configuration, authInfo *authSettings, formObjects *[]jsonFormObject) error {
+	var err error
+	result.Authentication.LdapUrl, err = getFormValueString(formObjects, "ldap_url")
+	if err != nil {
+		return err
+	}
+	result.Authentication.LdapStartTls, err = getFormValueBool(formObjects, "ldap_starttls")
+	if err != nil {
+		return err
+	}
+	username, err := getFormValueString(formObjects, "ldap_admin_user")
+	if err != nil {
+		return err
+	}
+	authInfo.UserLdap = username
+	authInfo.OnlyRegisteredUsersLdap, err = getFormValueBool(formObjects, "ldap_only_registered_users")
+	if err != nil {
+		return err
+	}
+
+	useSearch, err := getFormValueBool(formObjects, "ldap_lookup_sel")
+	if err != nil {
+		return err
+	}
+	dnTemplate, err := getFormValueString(formObjects, "ldap_dn_template")
+	if err != nil {
+		return err
+	}
+	bindDn, err := getFormValueString(formObjects, "ldap_bind_dn")
+	if err != nil {
+		return err
+	}
+	bindPassword, err := getFormValueString(formObjects, "ldap_bind_pw")
+	if err != nil {
+		return err
+	}
+	searchBase, err := getFormValueString(formObjects, "ldap_search_base")
+	if err != nil {
+		return err
+	}
+	searchFilter, err := getFormValueString(formObjects, "ldap_search_filter")
+	if err != nil {
+		return err
+	}
+	if useSearch {
+		result.Authentication.LdapUserDnTemplate = ""
+		result.Authentication.LdapBindDn = bindDn
+		result.Authentication.LdapBindPassword = bindPassword
+		result.Authentication.LdapSearchBase = searchBase
+		result.Authentication.LdapSearchFilter = searchFilter
+	} else {
+		result.Authentication.LdapUserDnTemplate = dnTemplate
+		result.Authentication.LdapBindDn = ""
+		result.Authentication.LdapBindPassword = ""
+		result.Authentication.LdapSearchBase = ""
+		result.Authentication.LdapSearchFilter = ""
+	}
+
+	restrictGroups, err := getFormValueBool(formObjects, "ldap_restrict_groups")
+	if err != nil {
+		return err
+	}
+	groupAttribute, err := getFormValueString(formObjects, "ldap_group_attribute")
+	if err != nil {
+		return err
+	}
+	allowedGroups, err := getFormValueString(formObjects, "ldap_allowed_groups")
+	if err != nil {
+		return err
+	}
+	if restrictGroups {
+		result.Authentication.LdapGroupAttribute = groupAttribute
+		result.Authentication.LdapGroups = splitAndTrim(allowedGroups)
+	} else {
+		result.Authentication.LdapGroupAttribute = ""
+		result.Authentication.LdapGroups = []string{}
+	}
+	return nil
+}
+
 func parseServerSettings(result *models.Configuration, formObjects *[]jsonFormObject) error {
 	var err error
 	port, err := getFormValueInt(formObjects, "port")
@@ -525,7 +647,7 @@ func parseServerSettings(result *models.Configuration, formObjects *[]jsonFormOb
 	if err != nil {
 		return err
 	}
-	if result.Authentication.Method < 0 || result.Authentication.Method > 3 {
+	if result.Authentication.Method < 0 || result.Authentication.Method > models.AuthenticationLdap {
 		return errors.New("invalid authentication mode provided")
 	}
 
@@ -697,6 +819,7 @@ type setupView struct {
 	IsConfigNotMounted bool
 	Port               int
 	OAuthGroups        string
+	LdapGroups         string
 	Auth               models.AuthenticationConfig
 	Settings           models.Configuration
 	CloudSettings      cloudconfig.CloudConfig
@@ -727,6 +850,7 @@ func (v *setupView) loadFromConfig() {
 	v.Auth = settings.Authentication
 	v.CloudSettings, _ = cloudconfig.Load()
 	v.OAuthGroups = strings.Join(settings.Authentication.OAuthGroups, ";")
+	v.LdapGroups = strings.Join(settings.Authentication.LdapGroups, ";")
 
 	if strings.Contains(settings.Port, "localhost") || strings.Contains(settings.Port, "127.0.0.1") {
 		v.LocalhostOnly = true
@@ -0,0 +1,173 @@
+package sharedstate
+
+/**
+Shared state between multiple Gokapi instances that use the same database
+*/
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Backend stores temporary state that has to be shared between all instances.
+// All functions return an error, if the backend cannot be reached
+type Backend interface {
+	// Set stores the value for the key. The key is deleted after ttl
+	Set(key string, value []byte, ttl time.Duration) error
+	// Get returns the value for the key or false if it does not exist
+	Get(key string) ([]byte, bool, error)
+	// Delete deletes the key
+	Delete(key string) error
+	// GetAndDelete returns the value for the key and deletes it in the same operation, so that
+	// the value is only returned to a single caller. Returns false, if the key does not exist
+	GetAndDelete(key string) ([]byte, bool, error)
+
+	// SetGroupEntry stores the value for the id in the group. The entry is deleted after ttl
+	SetGroupEntry(group, id string, value []byte, ttl time.Duration) error
+	// GetGroupEntries returns all entries of the group that have not expired yet
+	GetGroupEntries(group string) (map[string][]byte, error)
+	// DeleteGroupEntry deletes the entry with the id from the group
+	DeleteGroupEntry(group, id string) error
+	// DeleteGroup deletes all entries of the group
+	DeleteGroup(group string) error
+
+	// TryLock acquires the lock with the given name, if it is not held by another instance.
+	// The lock is released automatically after ttl
+	TryLock(name string, ttl time.Duration) (bool, error)
+	// RenewLock resets the time after which the lock with the given name is released automatically to ttl.
+	// Returns false, if the lock is not held by this instance anymore
+	RenewLock(name string, ttl time.Duration) (bool, error)
+	// Unlock releases the lock with the given name, if it is held by this instance
+	Unlock(name string) error
+	// IsLocked returns true, if the lock with the given name is held by any instance
+	IsLocked(name string) (bool, error)
+
+	// ReserveTokens takes cost tokens from the token bucket of the key, which is refilled with
+	// ratePerSecond tokens and holds up to burst tokens. Returns the duration the caller has to wait
+	// before the tokens are available. If reserveIfUnavailable is false and the tokens are not
+	// available immediately, no tokens are taken and false is returned
+	ReserveTokens(key string, ratePerSecond float64, burst, cost int, reserveIfUnavailable bool) (time.Duration, bool, error)
+
+	// Publish sends the message to all instances that subscribed to the channel, including this one
+	Publish(channel string, message []byte) error
+	// Subscribe calls handler for every message that is published to the channel. Must not block
+	// or fail if the backend cannot be reached, but subscribe once it is available again
+	Subscribe(channel string, handler func(message []byte))
+
+	// Close disconnects from the backend
+	Close()
+}
+
+var backend Backend
+
+// Init sets the backend that is used to share state with other instances
+func Init(newBackend Backend) {
+	backend = newBackend
+}
+
+// IsEnabled returns true, if state is shared with other instances. If false, all state
+// is kept in memory of the local instance
+func IsEnabled() bool {
+	return backend != nil
+}
+
+// Get returns the backend. Must only be called if IsEnabled returns true
+func Get() Backend {
+	if backend == nil {
+		panic("shared state backend is not initialised")
+	}
+	return backend
+}
+
+// Close disconnects from the backend and keeps all further state in memory
+func Close() {
+	if backend == nil {
+		return
+	}
+	backend.Close()
+	backend = nil
+}
+
+var renewals = make(map[string]chan struct{})
+var renewalMutex sync.Mutex
+
+// TryLock acquires the lock with the given name, if it is not held by another instance. While the lock is held,
+// it is renewed every third of ttl, so that it is only released automatically after ttl if this instance is terminated.
+// Returns an error if the backend cannot be reached. Must be released with Unlock
+func TryLock(name string, ttl time.Duration) (bool, error) {
+	currentBackend := Get()
+	isAcquired, err := currentBackend.TryLock(name, ttl)
+	if err != nil || !isAcquired {
+		return false, err
+	}
+	stop := make(chan struct{})
+	renewalMutex.Lock()
+	renewals[name] = stop
+	renewalMutex.Unlock()
+	go renewLock(currentBackend, name, ttl, stop)
+	return true, nil
+}
+
+// Lock blocks until the lock with the given name is acquired. The lock is renewed like with TryLock.
+// Returns an error if the backend cannot be reached. Must be released with Unlock
+func Lock(name string, ttl time.Duration) error {
+	for {
+		isAcquired, err := TryLock(name, ttl)
+		if err != nil {
+			return err
+		}
+		if isAcquired {
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Unlock stops renewing the lock with the given name and releases it
+func Unlock(name string) error {
+	renewalMutex.Lock()
+	stop, ok := renewals[name]
+	if ok {
+		close(stop)
+		delete(renewals, name)
+	}
+	renewalMutex.Unlock()
+	return Get().Unlock(name)
+}
+
+// renewLock renews the lock until stop is closed. If the lock could not be renewed in time,
+// other instances are able to acquire it while it is still in use, which is logged as a warning
+func renewLock(currentBackend Backend, name string, ttl time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	lastRenewal := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			isRenewed, err := currentBackend.RenewLock(name, ttl)
+			if err != nil {
+				if time.Since(lastRenewal) >= ttl {
+					fmt.Println("WARNING: Shared lock " + name + " has expired while it was still held, " +
+						"because the shared state could not be reached: " + err.Error())
+					return
+				}
+				fmt.Println("Could not renew shared lock " + name + ": " + err.Error())
+				continue
+			}
+			if !isRenewed {
+				fmt.Println("WARNING: Shared lock " + name + " has expired while it was still held. " +
+					"Other instances might have modified the same data in the meantime")
+				return
+			}
+			lastRenewal = time.Now()
+		}
+	}
+}
+
+// PrintError logs an error of the backend, after which the caller continues with a fallback
+func PrintError(err error) {
+	fmt.Println("Error accessing shared state: " + err.Error())
+}
@@ -0,0 +1,59 @@
+package sharedstate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate/redisstate"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestLockRenewal(t *testing.T) {
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	defer sharedstate.Close()
+	otherInstance, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	defer otherInstance.Close()
+
+	test.IsNil(t, sharedstate.Lock("renewed", 300*time.Millisecond))
+	// The TTL is only reduced in miniredis, if time is fast-forwarded
+	mRedis.FastForward(250 * time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	test.IsEqualBool(t, mRedis.TTL("state:lock:renewed") > 100*time.Millisecond, true)
+	mRedis.FastForward(250 * time.Millisecond)
+	isLocked, err := otherInstance.IsLocked("renewed")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, true)
+
+	test.IsNil(t, sharedstate.Unlock("renewed"))
+	isLocked, err = otherInstance.IsLocked("renewed")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, false)
+	isAcquired, err := otherInstance.TryLock("renewed", time.Minute)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isAcquired, true)
+	// Releasing the lock again must not release the lock of the other instance
+	test.IsNil(t, sharedstate.Unlock("renewed"))
+	isLocked, err = otherInstance.IsLocked("renewed")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, true)
+}
+
+func TestLockUnreachable(t *testing.T) {
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	defer sharedstate.Close()
+
+	test.IsNil(t, sharedstate.Lock("unreachable", time.Minute))
+	mRedis.Close()
+	test.IsNotNil(t, sharedstate.Unlock("unreachable"))
+	test.IsNotNil(t, sharedstate.Lock("unreachable", time.Minute))
+}
@@ -0,0 +1,420 @@
+package redisstate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const keyPrefix = "state:"
+const groupValuesSuffix = ":values"
+const groupExpirySuffix = ":expiry"
+
+// scriptSetGroupEntry stores the value in the hash KEYS[1] and its expiry in the sorted set KEYS[2].
+// The TTL of both keys is only extended, so that it is never shorter than the TTL of any entry
+var scriptSetGroupEntry = redigo.NewScript(2, `
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+local ttl = tonumber(ARGV[4])
+for _, key in ipairs(KEYS) do
+	if redis.call('PTTL', key) < ttl then
+		redis.call('PEXPIRE', key, ttl)
+	end
+end
+return 1`)
+
+// scriptUnlock deletes the lock KEYS[1] only if it is held by the instance with the token ARGV[1]
+var scriptUnlock = redigo.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+
+// scriptGetAndDelete returns the value of KEYS[1] and deletes it. GETDEL is not used, as it
+// requires at least Redis 6.2
+var scriptGetAndDelete = redigo.NewScript(1, `
+local value = redis.call('GET', KEYS[1])
+if value then
+	redis.call('DEL', KEYS[1])
+end
+return value`)
+
+// scriptRenewLock sets the TTL of the lock KEYS[1] to ARGV[2] milliseconds, only if it is held by
+// the instance with the token ARGV[1]
+var scriptRenewLock = redigo.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`)
+
+// scriptReserveTokens implements a token bucket stored in the hash KEYS[1]. Returns the time in
+// seconds until the tokens are available and 1, if the tokens were taken
+var scriptReserveTokens = redigo.NewScript(1, `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+local reserve = ARGV[5] == '1'
+local tokens = tonumber(redis.call('HGET', KEYS[1], 'tokens'))
+local last = tonumber(redis.call('HGET', KEYS[1], 'last'))
+if tokens == nil or last == nil then
+	tokens = burst
+	last = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+local wait = 0
+if tokens < cost then
+	wait = (cost - tokens) / rate
+	if not reserve then
+		return {tostring(wait), 0}
+	end
+end
+tokens = tokens - cost
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+redis.call('EXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 60)
+return {tostring(wait), 1}`)
+
+// Backend shares state with other instances through Redis
+type Backend struct {
+	pool        *redigo.Pool
+	prefix      string
+	token       string
+	subscribers map[string][]func(message []byte)
+	subMutex    sync.Mutex
+	subConn     *redigo.PubSubConn
+	isReceiving bool
+	isClosed    bool
+}
+
+// New connects to the Redis server with the given configuration
+func New(config models.DbConnection) (*Backend, error) {
+	if config.HostUrl == "" {
+		return nil, errors.New("empty redis url was provided")
+	}
+	b := &Backend{
+		pool:        newPool(config),
+		prefix:      config.RedisPrefix + keyPrefix,
+		token:       helper.GenerateRandomString(30),
+		subscribers: make(map[string][]func(message []byte)),
+	}
+	conn, err := b.pool.Dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_, err = conn.Do("PING")
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func getDialOptions(config models.DbConnection) []redigo.DialOption {
+	dialOptions := []redigo.DialOption{redigo.DialClientName("gokapi")}
+	if config.Username != "" {
+		dialOptions = append(dialOptions, redigo.DialUsername(config.Username))
+	}
+	if config.Password != "" {
+		dialOptions = append(dialOptions, redigo.DialPassword(config.Password))
+	}
+	if config.RedisUseSsl {
+		dialOptions = append(dialOptions, redigo.DialUseTLS(true))
+	}
+	return dialOptions
+}
+
+func newPool(config models.DbConnection) *redigo.Pool {
+	return &redigo.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 2 * time.Minute,
+		Dial: func() (redigo.Conn, error) {
+			return redigo.Dial("tcp", config.HostUrl, getDialOptions(config)...)
+		},
+		TestOnBorrow: func(c redigo.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+}
+
+func (b *Backend) do(command string, args ...any) (any, error) {
+	conn := b.pool.Get()
+	defer conn.Close()
+	return conn.Do(command, args...)
+}
+
+// Set stores the value for the key. The key is deleted after ttl
+func (b *Backend) Set(key string, value []byte, ttl time.Duration) error {
+	_, err := b.do("SET", b.prefix+key, value, "PX", ttl.Milliseconds())
+	return err
+}
+
+// Get returns the value for the key or false if it does not exist
+func (b *Backend) Get(key string) ([]byte, bool, error) {
+	value, err := redigo.Bytes(b.do("GET", b.prefix+key))
+	if errors.Is(err, redigo.ErrNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Delete deletes the key
+func (b *Backend) Delete(key string) error {
+	_, err := b.do("DEL", b.prefix+key)
+	return err
+}
+
+// GetAndDelete returns the value for the key and deletes it in the same operation, so that
+// the value is only returned to a single caller. Returns false, if the key does not exist
+func (b *Backend) GetAndDelete(key string) ([]byte, bool, error) {
+	conn := b.pool.Get()
+	defer conn.Close()
+	value, err := redigo.Bytes(scriptGetAndDelete.Do(conn, b.prefix+key))
+	if errors.Is(err, redigo.ErrNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// SetGroupEntry stores the value for the id in the group. The entry is deleted after ttl
+func (b *Backend) SetGroupEntry(group, id string, value []byte, ttl time.Duration) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+	expiry := time.Now().Add(ttl).UnixMilli()
+	_, err := scriptSetGroupEntry.Do(conn, b.prefix+group+groupValuesSuffix, b.prefix+group+groupExpirySuffix,
+		id, value, expiry, ttl.Milliseconds())
+	return err
+}
+
+// GetGroupEntries returns all entries of the group that have not expired yet
+func (b *Backend) GetGroupEntries(group string) (map[string][]byte, error) {
+	conn := b.pool.Get()
+	defer conn.Close()
+	keyValues := b.prefix + group + groupValuesSuffix
+	keyExpiry := b.prefix + group + groupExpirySuffix
+	expired, err := redigo.Strings(conn.Do("ZRANGEBYSCORE", keyExpiry, "-inf", time.Now().UnixMilli()))
+	if err != nil {
+		return nil, err
+	}
+	if len(expired) > 0 {
+		_, err = conn.Do("HDEL", redigo.Args{}.Add(keyValues).AddFlat(expired)...)
+		if err != nil {
+			return nil, err
+		}
+		_, err = conn.Do("ZREM", redigo.Args{}.Add(keyExpiry).AddFlat(expired)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	values, err := redigo.Values(conn.Do("HGETALL", keyValues))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		id, _ := redigo.String(values[i], nil)
+		value, _ := redigo.Bytes(values[i+1], nil)
+		result[id] = value
+	}
+	return result, nil
+}
+
+// DeleteGroupEntry deletes the entry with the id from the group
+func (b *Backend) DeleteGroupEntry(group, id string) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("HDEL", b.prefix+group+groupValuesSuffix, id)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Do("ZREM", b.prefix+group+groupExpirySuffix, id)
+	return err
+}
+
+// DeleteGroup deletes all entries of the group
+func (b *Backend) DeleteGroup(group string) error {
+	_, err := b.do("DEL", b.prefix+group+groupValuesSuffix, b.prefix+group+groupExpirySuffix)
+	return err
+}
+
+// TryLock acquires the lock with the given name, if it is not held by another instance.
+// The lock is released automatically after ttl
+func (b *Backend) TryLock(name string, ttl time.Duration) (bool, error) {
+	_, err := redigo.String(b.do("SET", b.prefix+"lock:"+name, b.token, "NX", "PX", ttl.Milliseconds()))
+	if errors.Is(err, redigo.ErrNil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RenewLock resets the time after which the lock with the given name is released automatically to ttl.
+// Returns false, if the lock is not held by this instance anymore
+func (b *Backend) RenewLock(name string, ttl time.Duration) (bool, error) {
+	conn := b.pool.Get()
+	defer conn.Close()
+	result, err := redigo.Int(scriptRenewLock.Do(conn, b.prefix+"lock:"+name, b.token, ttl.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
+
+// Unlock releases the lock with the given name, if it is held by this instance
+func (b *Backend) Unlock(name string) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+	_, err := scriptUnlock.Do(conn, b.prefix+"lock:"+name, b.token)
+	return err
+}
+
+// IsLocked returns true, if the lock with the given name is held by any instance
+func (b *Backend) IsLocked(name string) (bool, error) {
+	return redigo.Bool(b.do("EXISTS", b.prefix+"lock:"+name))
+}
+
+// ReserveTokens takes cost tokens from the token bucket of the key, which is refilled with
+// ratePerSecond tokens and holds up to burst tokens. Returns the duration the caller has to wait
+// before the tokens are available. If reserveIfUnavailable is false and the tokens are not
+// available immediately, no tokens are taken and false is returned
+func (b *Backend) ReserveTokens(key string, ratePerSecond float64, burst, cost int, reserveIfUnavailable bool) (time.Duration, bool, error) {
+	conn := b.pool.Get()
+	defer conn.Close()
+	reserve := "0"
+	if reserveIfUnavailable {
+		reserve = "1"
+	}
+	now := float64(time.Now().UnixMicro()) / 1000000
+	values, err := redigo.Values(scriptReserveTokens.Do(conn, b.prefix+"ratelimit:"+key,
+		ratePerSecond, burst, cost, strconv.FormatFloat(now, 'f', 6, 64), reserve))
+	if err != nil {
+		return 0, false, err
+	}
+	if len(values) != 2 {
+		return 0, false, errors.New("unexpected result of rate limiter")
+	}
+	waitString, err := redigo.String(values[0], nil)
+	if err != nil {
+		return 0, false, err
+	}
+	waitSeconds, err := strconv.ParseFloat(waitString, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	isTaken, err := redigo.Int(values[1], nil)
+	if err != nil {
+		return 0, false, err
+	}
+	return time.Duration(waitSeconds * float64(time.Second)), isTaken == 1, nil
+}
+
+// Publish sends the message to all instances that subscribed to the channel, including this one
+func (b *Backend) Publish(channel string, message []byte) error {
+	_, err := b.do("PUBLISH", b.prefix+channel, message)
+	return err
+}
+
+// Subscribe calls handler for every message that is published to the channel. The connection for the
+// subscription is established in the background and is re-established, if it is lost
+func (b *Backend) Subscribe(channel string, handler func(message []byte)) {
+	b.subMutex.Lock()
+	defer b.subMutex.Unlock()
+	_, exists := b.subscribers[channel]
+	b.subscribers[channel] = append(b.subscribers[channel], handler)
+	if exists {
+		return
+	}
+	if !b.isReceiving {
+		b.isReceiving = true
+		go b.receive()
+		return
+	}
+	// If there is no connection yet, all channels are subscribed once it is established
+	if b.subConn != nil {
+		err := b.subConn.Subscribe(b.prefix + channel)
+		if err != nil {
+			fmt.Println("Could not subscribe to shared state channel "+channel+":", err)
+		}
+	}
+}
+
+// receive delivers all messages of the subscription to the handlers. If the connection is lost,
+// a new connection is established and all channels are subscribed again
+func (b *Backend) receive() {
+	conn := b.connectSubscription(nil)
+	for conn != nil {
+		switch message := conn.Receive().(type) {
+		case redigo.Message:
+			b.subMutex.Lock()
+			handlers := b.subscribers[message.Channel[len(b.prefix):]]
+			b.subMutex.Unlock()
+			for _, handler := range handlers {
+				handler(message.Data)
+			}
+		case error:
+			_ = conn.Close()
+			conn = b.connectSubscription(message)
+		}
+	}
+}
+
+// connectSubscription establishes a new connection for the subscription and subscribes to all channels.
+// If cause is not nil, the previous connection has been lost. Retries until the connection is established
+// and returns nil, if the backend has been closed
+func (b *Backend) connectSubscription(cause error) *redigo.PubSubConn {
+	for {
+		b.subMutex.Lock()
+		if b.isClosed {
+			b.subMutex.Unlock()
+			return nil
+		}
+		b.subMutex.Unlock()
+		if cause != nil {
+			fmt.Println("Lost connection to shared state, reconnecting:", cause)
+			time.Sleep(time.Second)
+		}
+		conn, err := b.pool.Dial()
+		if err != nil {
+			cause = err
+			continue
+		}
+		subConn := &redigo.PubSubConn{Conn: conn}
+		b.subMutex.Lock()
+		if b.isClosed {
+			b.subMutex.Unlock()
+			_ = conn.Close()
+			return nil
+		}
+		for channel := range b.subscribers {
+			_ = subConn.Subscribe(b.prefix + channel)
+		}
+		b.subConn = subConn
+		b.subMutex.Unlock()
+		return subConn
+	}
+}
+
+// Close disconnects from the Redis server
+func (b *Backend) Close() {
+	b.subMutex.Lock()
+	b.isClosed = true
+	if b.subConn != nil {
+		_ = b.subConn.Close()
+	}
+	b.subMutex.Unlock()
+	_ = b.pool.Close()
+}
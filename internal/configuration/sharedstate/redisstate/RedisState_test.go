@@ -0,0 +1,282 @@
+package redisstate
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+)
+
+var config = models.DbConnection{
+	RedisPrefix: "test_",
+	HostUrl:     "127.0.0.1:16380",
+	Type:        1, // dbabstraction.TypeRedis
+}
+
+var mRedis *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	mRedis = miniredis.NewMiniRedis()
+	err := mRedis.StartAddr("127.0.0.1:16380")
+	if err != nil {
+		log.Fatal("Could not start miniredis")
+	}
+	exitVal := m.Run()
+	mRedis.Close()
+	os.Exit(exitVal)
+}
+
+func getBackend(t *testing.T) *Backend {
+	t.Helper()
+	backend, err := New(config)
+	test.IsNil(t, err)
+	return backend
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(models.DbConnection{})
+	test.IsNotNil(t, err)
+	_, err = New(models.DbConnection{HostUrl: "127.0.0.1:1"})
+	test.IsNotNil(t, err)
+	backend := getBackend(t)
+	backend.Close()
+}
+
+func TestKeyValue(t *testing.T) {
+	backend := getBackend(t)
+	defer backend.Close()
+	_, ok, err := backend.Get("key")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	test.IsNil(t, backend.Set("key", []byte("value"), time.Minute))
+	value, ok, err := backend.Get("key")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, string(value), "value")
+	test.IsEqualBool(t, mRedis.Exists("test_state:key"), true)
+	mRedis.FastForward(2 * time.Minute)
+	_, ok, err = backend.Get("key")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	test.IsNil(t, backend.Set("key", []byte("value"), time.Minute))
+	test.IsNil(t, backend.Delete("key"))
+	_, ok, err = backend.Get("key")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+
+	test.IsNil(t, backend.Set("key", []byte("value"), time.Minute))
+	value, ok, err = backend.GetAndDelete("key")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, string(value), "value")
+	test.IsEqualBool(t, mRedis.Exists("test_state:key"), false)
+	_, ok, err = backend.GetAndDelete("key")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+}
+
+func getGroupEntries(t *testing.T, backend *Backend, group string) map[string][]byte {
+	t.Helper()
+	entries, err := backend.GetGroupEntries(group)
+	test.IsNil(t, err)
+	return entries
+}
+
+func TestGroups(t *testing.T) {
+	backend := getBackend(t)
+	defer backend.Close()
+	test.IsEqualInt(t, len(getGroupEntries(t, backend, "group")), 0)
+	test.IsNil(t, backend.SetGroupEntry("group", "id1", []byte("value1"), time.Hour))
+	test.IsNil(t, backend.SetGroupEntry("group", "id2", []byte("value2"), time.Hour))
+	test.IsNil(t, backend.SetGroupEntry("group", "expired", []byte("value3"), -time.Second))
+	entries := getGroupEntries(t, backend, "group")
+	test.IsEqualInt(t, len(entries), 2)
+	test.IsEqualString(t, string(entries["id1"]), "value1")
+	test.IsEqualString(t, string(entries["id2"]), "value2")
+	test.IsEqualBool(t, mRedis.TTL("test_state:group:values") > 59*time.Minute, true)
+
+	test.IsNil(t, backend.SetGroupEntry("group", "id3", []byte("value3"), time.Minute))
+	test.IsEqualBool(t, mRedis.TTL("test_state:group:values") > 59*time.Minute, true)
+
+	test.IsNil(t, backend.DeleteGroupEntry("group", "id1"))
+	entries = getGroupEntries(t, backend, "group")
+	test.IsEqualInt(t, len(entries), 2)
+	_, ok := entries["id1"]
+	test.IsEqualBool(t, ok, false)
+	test.IsNil(t, backend.DeleteGroup("group"))
+	test.IsEqualInt(t, len(getGroupEntries(t, backend, "group")), 0)
+}
+
+func isLocked(t *testing.T, backend *Backend, name string) bool {
+	t.Helper()
+	result, err := backend.IsLocked(name)
+	test.IsNil(t, err)
+	return result
+}
+
+func tryLock(t *testing.T, backend *Backend, name string) bool {
+	t.Helper()
+	result, err := backend.TryLock(name, time.Minute)
+	test.IsNil(t, err)
+	return result
+}
+
+func TestLocks(t *testing.T) {
+	backend := getBackend(t)
+	defer backend.Close()
+	otherInstance := getBackend(t)
+	defer otherInstance.Close()
+
+	test.IsEqualBool(t, isLocked(t, backend, "lock"), false)
+	test.IsEqualBool(t, tryLock(t, backend, "lock"), true)
+	test.IsEqualBool(t, tryLock(t, backend, "lock"), false)
+	test.IsEqualBool(t, tryLock(t, otherInstance, "lock"), false)
+	test.IsEqualBool(t, isLocked(t, otherInstance, "lock"), true)
+	test.IsNil(t, otherInstance.Unlock("lock"))
+	test.IsEqualBool(t, isLocked(t, backend, "lock"), true)
+	test.IsNil(t, backend.Unlock("lock"))
+	test.IsEqualBool(t, isLocked(t, backend, "lock"), false)
+	test.IsEqualBool(t, tryLock(t, otherInstance, "lock"), true)
+	mRedis.FastForward(2 * time.Minute)
+	test.IsEqualBool(t, tryLock(t, backend, "lock"), true)
+	test.IsNil(t, backend.Unlock("lock"))
+}
+
+func TestRenewLock(t *testing.T) {
+	backend := getBackend(t)
+	defer backend.Close()
+	otherInstance := getBackend(t)
+	defer otherInstance.Close()
+
+	isRenewed, err := backend.RenewLock("renewlock", time.Minute)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isRenewed, false)
+	test.IsEqualBool(t, tryLock(t, backend, "renewlock"), true)
+	mRedis.FastForward(50 * time.Second)
+	isRenewed, err = backend.RenewLock("renewlock", time.Minute)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isRenewed, true)
+	mRedis.FastForward(50 * time.Second)
+	test.IsEqualBool(t, isLocked(t, otherInstance, "renewlock"), true)
+	isRenewed, err = otherInstance.RenewLock("renewlock", time.Minute)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isRenewed, false)
+	mRedis.FastForward(20 * time.Second)
+	test.IsEqualBool(t, isLocked(t, otherInstance, "renewlock"), false)
+	isRenewed, err = backend.RenewLock("renewlock", time.Minute)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isRenewed, false)
+}
+
+func TestReserveTokens(t *testing.T) {
+	backend := getBackend(t)
+	defer backend.Close()
+	for i := 0; i < 3; i++ {
+		wait, ok, err := backend.ReserveTokens("limiter", 1, 3, 1, false)
+		test.IsNil(t, err)
+		test.IsEqualBool(t, ok, true)
+		test.IsEqualBool(t, wait == 0, true)
+	}
+	wait, ok, err := backend.ReserveTokens("limiter", 1, 3, 1, false)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualBool(t, wait > 0, true)
+	wait, ok, err = backend.ReserveTokens("limiter", 1, 3, 1, true)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, wait > 0 && wait <= time.Second, true)
+	wait, ok, err = backend.ReserveTokens("limiter", 1, 3, 1, true)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, wait > time.Second && wait <= 2*time.Second, true)
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	backend := getBackend(t)
+	otherInstance := getBackend(t)
+	defer otherInstance.Close()
+
+	received := make(chan string, 10)
+	backend.Subscribe("channel", func(message []byte) {
+		received <- "first:" + string(message)
+	})
+	backend.Subscribe("channel", func(message []byte) {
+		received <- "second:" + string(message)
+	})
+	backend.Subscribe("otherchannel", func(message []byte) {
+		received <- "other:" + string(message)
+	})
+	time.Sleep(100 * time.Millisecond)
+	test.IsNil(t, otherInstance.Publish("channel", []byte("message")))
+	test.IsEqualString(t, waitForMessage(t, received), "first:message")
+	test.IsEqualString(t, waitForMessage(t, received), "second:message")
+	test.IsNil(t, backend.Publish("otherchannel", []byte("message2")))
+	test.IsEqualString(t, waitForMessage(t, received), "other:message2")
+	backend.Close()
+	time.Sleep(100 * time.Millisecond)
+	test.IsEqualInt(t, len(received), 0)
+}
+
+func TestUnreachableServer(t *testing.T) {
+	server := miniredis.NewMiniRedis()
+	err := server.StartAddr("127.0.0.1:16381")
+	test.IsNil(t, err)
+	backend, err := New(models.DbConnection{HostUrl: "127.0.0.1:16381", Type: 1})
+	test.IsNil(t, err)
+	defer backend.Close()
+	server.Close()
+
+	// No function may panic, if the server cannot be reached
+	test.IsNotNil(t, backend.Set("key", []byte("value"), time.Minute))
+	_, _, err = backend.Get("key")
+	test.IsNotNil(t, err)
+	test.IsNotNil(t, backend.Delete("key"))
+	_, _, err = backend.GetAndDelete("key")
+	test.IsNotNil(t, err)
+	test.IsNotNil(t, backend.SetGroupEntry("group", "id", []byte("value"), time.Minute))
+	_, err = backend.GetGroupEntries("group")
+	test.IsNotNil(t, err)
+	test.IsNotNil(t, backend.DeleteGroupEntry("group", "id"))
+	test.IsNotNil(t, backend.DeleteGroup("group"))
+	_, err = backend.TryLock("lock", time.Minute)
+	test.IsNotNil(t, err)
+	_, err = backend.RenewLock("lock", time.Minute)
+	test.IsNotNil(t, err)
+	test.IsNotNil(t, backend.Unlock("lock"))
+	_, err = backend.IsLocked("lock")
+	test.IsNotNil(t, err)
+	_, _, err = backend.ReserveTokens("limiter", 1, 3, 1, false)
+	test.IsNotNil(t, err)
+	test.IsNotNil(t, backend.Publish("channel", []byte("message")))
+
+	received := make(chan string, 10)
+	backend.Subscribe("channel", func(message []byte) {
+		received <- string(message)
+	})
+	time.Sleep(100 * time.Millisecond)
+	err = server.Restart()
+	test.IsNil(t, err)
+	defer server.Close()
+	// Publishing is retried, as the subscription is only established after the reconnect delay
+	deadline := time.Now().Add(5 * time.Second)
+	for len(received) == 0 && time.Now().Before(deadline) {
+		test.IsNil(t, backend.Publish("channel", []byte("message")))
+		time.Sleep(100 * time.Millisecond)
+	}
+	test.IsEqualString(t, waitForMessage(t, received), "message")
+}
+
+func waitForMessage(t *testing.T, received chan string) string {
+	t.Helper()
+	select {
+	case message := <-received:
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+		return ""
+	}
+}
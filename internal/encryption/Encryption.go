@@ -11,6 +11,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/forceu/gokapi/internal/helper"
@@ -38,10 +39,13 @@ const FullEncryptionInput = 4
 const EndToEndEncryption = 5
 
 var encryptedKey, ramCipher []byte
+var keyMutex sync.RWMutex
 
 // IsDecryptionAvailable returns true if the master encryption key has been
 // loaded into memory, meaning server-side decryption is possible.
 func IsDecryptionAvailable() bool {
+	keyMutex.RLock()
+	defer keyMutex.RUnlock()
 	return len(ramCipher) > 0
 }
 
@@ -60,6 +64,12 @@ func Init(config models.Configuration) {
 	case EndToEndEncryption:
 		return
 	}
+	if len(config.Encryption.PreviousKey) != 0 {
+		err := loadPreviousMasterKey(config.Encryption.PreviousKey, config.Encryption.PreviousKeyNonce)
+		if err != nil {
+			log.Fatal("Could not load the master key of an unfinished key rotation: " + err.Error())
+		}
+	}
 }
 
 func initWithPassword(saltPw, expectedChecksum, saltChecksum string) {
@@ -67,15 +77,23 @@ func initWithPassword(saltPw, expectedChecksum, saltChecksum string) {
 		log.Fatal("Empty salt provided. Please rerun setup with --reconfigure")
 	}
 	pw := readAndCheckPassword(expectedChecksum, saltChecksum)
-	cipherKey, err := scrypt.Key([]byte(pw), []byte(saltPw), 1048576, 8, 1, blockSize)
+	cipherKey, err := GetKeyFromPassword(pw, saltPw)
 	if err != nil {
-		cipherKey = []byte{}
 		log.Fatal(err)
 	}
 
 	storeMasterKey(cipherKey)
 }
 
+// GetKeyFromPassword derives the master key from the encryption password
+func GetKeyFromPassword(pw, saltPw string) ([]byte, error) {
+	cipherKey, err := scrypt.Key([]byte(pw), []byte(saltPw), 1048576, 8, 1, blockSize)
+	if err != nil {
+		return []byte{}, err
+	}
+	return cipherKey, nil
+}
+
 func readAndCheckPassword(expectedChecksum, saltChecksum string) string {
 	fmt.Println("Please enter encryption password:")
 	pw := helper.ReadPassword()
@@ -126,19 +144,27 @@ func initWithCipher(cipherKey []byte) {
 }
 
 func storeMasterKey(cipherKey []byte) {
-	var err error
-	ramCipher, err = getRandomData(blockSize)
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+	ramCipher, encryptedKey = protectKey(cipherKey)
+	previousRamCipher, previousEncryptedKey = nil, nil
+}
+
+// protectKey encrypts a key with a random cipher, so that it is not stored in plaintext in memory
+func protectKey(cipherKey []byte) ([]byte, []byte) {
+	protectionCipher, err := getRandomData(blockSize)
 	if err != nil {
 		log.Fatal(err)
 	}
-	encryptedKey, err = EncryptDecryptBytes(cipherKey, ramCipher, make([]byte, nonceSize), true)
+	protectedKey, err := EncryptDecryptBytes(cipherKey, protectionCipher, make([]byte, nonceSize), true)
 	if err != nil {
 		log.Fatal(err)
 	}
+	return protectionCipher, protectedKey
 }
 
-func getMasterCipher() []byte {
-	key, err := EncryptDecryptBytes(encryptedKey, ramCipher, make([]byte, nonceSize), false)
+func unprotectKey(protectionCipher, protectedKey []byte) []byte {
+	key, err := EncryptDecryptBytes(protectedKey, protectionCipher, make([]byte, nonceSize), false)
 	if err != nil {
 		key = []byte{}
 		log.Fatal(err)
@@ -146,6 +172,12 @@ func getMasterCipher() []byte {
 	return key
 }
 
+func getMasterCipher() []byte {
+	keyMutex.RLock()
+	defer keyMutex.RUnlock()
+	return unprotectKey(ramCipher, encryptedKey)
+}
+
 // Encrypt encrypts a file
 func Encrypt(encInfo *models.EncryptionInfo, input io.Reader, output io.Writer) error {
 	key, err := generateNewFileKey(encInfo)
@@ -159,6 +191,17 @@ func Encrypt(encInfo *models.EncryptionInfo, input io.Reader, output io.Writer)
 	return err
 }
 
+// EncryptWriter returns a writer that encrypts everything written to it with a newly generated
+// file key and writes the result to output. The file key is stored in encInfo. Close needs to be
+// called after the last write, otherwise the last encrypted package is not written
+func EncryptWriter(encInfo *models.EncryptionInfo, output io.Writer) (io.WriteCloser, error) {
+	key, err := generateNewFileKey(encInfo)
+	if err != nil {
+		return nil, err
+	}
+	return GetEncryptWriter(key, output)
+}
+
 func createDecryptReader(encInfo models.EncryptionInfo, input io.Reader) (*sio.DecReader, error) {
 	key, err := GetCipherFromFile(encInfo)
 	if err != nil {
@@ -179,6 +222,83 @@ func DecryptReader(encInfo models.EncryptionInfo, input io.Reader, output io.Wri
 	return err
 }
 
+// DecryptReadSeeker decrypts a server-side encrypted file with random access. Only the
+// encrypted packages that are required for the requested range are read and decrypted
+type DecryptReadSeeker struct {
+	reader *sio.DecReaderAt
+	size   int64
+	offset int64
+}
+
+// GetDecryptReadSeeker returns a reader that decrypts an encrypted file and supports seeking, so that
+// it can be used for range requests. Size is the size of the plaintext
+func GetDecryptReadSeeker(encInfo models.EncryptionInfo, input io.ReaderAt, size int64) (*DecryptReadSeeker, error) {
+	key, err := GetCipherFromFile(encInfo)
+	if err != nil {
+		return nil, err
+	}
+	stream := getStream(key)
+	nonce := make([]byte, stream.NonceSize()) // Nonce is not used
+	return &DecryptReadSeeker{
+		reader: stream.DecryptReaderAt(input, nonce, nil),
+		size:   size,
+	}, nil
+}
+
+// Read behaves like specified by the io.Reader interface. If the current offset is not aligned
+// to the start of a package, only the remainder of the package is read, so that subsequent
+// reads do not need to decrypt any package twice
+func (d *DecryptReadSeeker) Read(p []byte) (int, error) {
+	if d.offset >= d.size {
+		return 0, io.EOF
+	}
+	if remaining := d.size - d.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	if remainder := int(d.offset % sio.BufSize); remainder != 0 && len(p) > sio.BufSize-remainder {
+		p = p[:sio.BufSize-remainder]
+	}
+	n, err := d.reader.ReadAt(p, d.offset)
+	d.offset += int64(n)
+	if errors.Is(err, io.EOF) {
+		if d.offset < d.size {
+			return n, io.ErrUnexpectedEOF
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return n, err
+}
+
+// Seek behaves like specified by the io.Seeker interface
+func (d *DecryptReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += d.offset
+	case io.SeekEnd:
+		offset += d.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	d.offset = offset
+	return offset, nil
+}
+
+// IsAuthentic checks if the first package of the file can be decrypted and verified.
+// This does not check for complete file authentication.
+func (d *DecryptReadSeeker) IsAuthentic() bool {
+	if d.size == 0 {
+		return true
+	}
+	_, err := d.reader.ReadAt(make([]byte, 1), 0)
+	return err == nil || errors.Is(err, io.EOF)
+}
+
 // IsCorrectKey checks if the correct key is being used. This does not check for complete file authentication.
 func IsCorrectKey(encInfo models.EncryptionInfo, input *os.File) bool {
 	_, err := createDecryptReader(encInfo, input)
@@ -244,9 +364,17 @@ func CalculateEncryptedFilesize(size int64) int64 {
 
 // GetCipherFromFile loads the cipher from a file model
 func GetCipherFromFile(encInfo models.EncryptionInfo) ([]byte, error) {
+	if len(encInfo.Nonce) != nonceSize {
+		return []byte{}, errors.New("file key has an invalid nonce")
+	}
 	cipherFile, err := fileCipherDecrypt(encInfo.DecryptionKey, encInfo.Nonce)
 	if err != nil {
-		return []byte{}, err
+		// During a key rotation, files might still be encrypted with the previous master key
+		previousCipher, ok := getPreviousMasterCipher()
+		if !ok {
+			return []byte{}, err
+		}
+		return EncryptDecryptBytes(encInfo.DecryptionKey, previousCipher, encInfo.Nonce, false)
 	}
 	return cipherFile, nil
 }
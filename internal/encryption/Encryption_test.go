@@ -11,6 +11,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/secure-io/sio-go"
 	"golang.org/x/crypto/scrypt"
 )
 
@@ -210,3 +211,167 @@ func TestIsCorrectKey(t *testing.T) {
 	isCorrect := IsCorrectKey(*encInfo, file)
 	test.IsEqualBool(t, isCorrect, true)
 }
+
+func TestDecryptReadSeeker(t *testing.T) {
+	plaintext := make([]byte, 3*sio.BufSize+500)
+	_, err := rand.Read(plaintext)
+	test.IsNil(t, err)
+	var encrypted bytes.Buffer
+	encInfo := &models.EncryptionInfo{}
+	err = Encrypt(encInfo, bytes.NewReader(plaintext), &encrypted)
+	test.IsNil(t, err)
+	input := bytes.NewReader(encrypted.Bytes())
+
+	reader, err := GetDecryptReadSeeker(*encInfo, input, int64(len(plaintext)))
+	test.IsNil(t, err)
+	test.IsEqualBool(t, reader.IsAuthentic(), true)
+	result, err := io.ReadAll(reader)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, result, plaintext)
+
+	ranges := [][2]int64{{0, 10}, {100, sio.BufSize + 200}, {sio.BufSize, 2 * sio.BufSize}, {int64(len(plaintext)) - 20, int64(len(plaintext))}}
+	for _, r := range ranges {
+		pos, err := reader.Seek(r[0], io.SeekStart)
+		test.IsNil(t, err)
+		test.IsEqualInt64(t, pos, r[0])
+		result, err = io.ReadAll(io.LimitReader(reader, r[1]-r[0]))
+		test.IsNil(t, err)
+		test.IsEqualByteSlice(t, result, plaintext[r[0]:r[1]])
+	}
+	size, err := reader.Seek(0, io.SeekEnd)
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, size, int64(len(plaintext)))
+	n, err := reader.Read(make([]byte, 10))
+	test.IsEqualInt(t, n, 0)
+	test.IsEqualBool(t, err == io.EOF, true)
+	pos, err := reader.Seek(-10, io.SeekCurrent)
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, pos, size-10)
+	_, err = reader.Seek(-1, io.SeekStart)
+	test.IsNotNil(t, err)
+	_, err = reader.Seek(0, 5)
+	test.IsNotNil(t, err)
+
+	reader, err = GetDecryptReadSeeker(*encInfo, input, int64(len(plaintext))+10)
+	test.IsNil(t, err)
+	_, err = reader.Seek(int64(len(plaintext))-5, io.SeekStart)
+	test.IsNil(t, err)
+	_, err = io.ReadAll(reader)
+	test.IsEqualBool(t, err == io.ErrUnexpectedEOF, true)
+
+	corrupted := bytes.Clone(encrypted.Bytes())
+	corrupted[5] ^= 0xff
+	reader, err = GetDecryptReadSeeker(*encInfo, bytes.NewReader(corrupted), int64(len(plaintext)))
+	test.IsNil(t, err)
+	test.IsEqualBool(t, reader.IsAuthentic(), false)
+	emptyReader, err := GetDecryptReadSeeker(*encInfo, bytes.NewReader(corrupted), 0)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, emptyReader.IsAuthentic(), true)
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, err := GetRandomCipher()
+	test.IsNil(t, err)
+	storeMasterKey(oldKey)
+	plaintext := []byte("content that is encrypted before the key rotation")
+	var encrypted bytes.Buffer
+	encInfo := models.EncryptionInfo{}
+	err = Encrypt(&encInfo, bytes.NewReader(plaintext), &encrypted)
+	test.IsNil(t, err)
+	originalEncInfo := encInfo
+
+	err = StartKeyRotation([]byte("invalid"))
+	test.IsNotNil(t, err)
+	newKey, err := GetRandomCipher()
+	test.IsNil(t, err)
+	err = StartKeyRotation(newKey)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, getMasterCipher(), newKey)
+
+	// The file can still be decrypted with the previous key during the rotation
+	var decrypted bytes.Buffer
+	err = DecryptReader(encInfo, bytes.NewReader(encrypted.Bytes()), &decrypted)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, decrypted.Bytes(), plaintext)
+
+	isModified, err := RewrapFileKey(&encInfo)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isModified, true)
+	test.IsEqualBool(t, bytes.Equal(encInfo.DecryptionKey, originalEncInfo.DecryptionKey), false)
+	isModified, err = RewrapFileKey(&encInfo)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isModified, false)
+	isModified, err = RewrapFileKey(&models.EncryptionInfo{IsEncrypted: false})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isModified, false)
+
+	FinishKeyRotation()
+	decrypted.Reset()
+	err = DecryptReader(encInfo, bytes.NewReader(encrypted.Bytes()), &decrypted)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, decrypted.Bytes(), plaintext)
+	_, err = GetCipherFromFile(originalEncInfo)
+	test.IsNotNil(t, err)
+	_, err = RewrapFileKey(&originalEncInfo)
+	test.IsNotNil(t, err)
+
+	keyMutex.Lock()
+	ramCipher, encryptedKey = nil, nil
+	keyMutex.Unlock()
+	err = StartKeyRotation(newKey)
+	test.IsNotNil(t, err)
+	storeMasterKey(newKey)
+}
+
+func TestWrapMasterKey(t *testing.T) {
+	oldKey, err := GetRandomCipher()
+	test.IsNil(t, err)
+	storeMasterKey(oldKey)
+	plaintext := []byte("content that is encrypted before an interrupted key rotation")
+	var encrypted bytes.Buffer
+	encInfo := models.EncryptionInfo{}
+	err = Encrypt(&encInfo, bytes.NewReader(plaintext), &encrypted)
+	test.IsNil(t, err)
+
+	newKey, err := GetRandomCipher()
+	test.IsNil(t, err)
+	_, _, err = WrapMasterKey([]byte("invalid"))
+	test.IsNotNil(t, err)
+	wrappedKey, nonce, err := WrapMasterKey(newKey)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, bytes.Contains(wrappedKey, oldKey), false)
+
+	// After a restart, the previous key is loaded from the configuration
+	Init(models.Configuration{Encryption: models.Encryption{
+		Level:            LocalEncryptionStored,
+		Cipher:           newKey,
+		PreviousKey:      wrappedKey,
+		PreviousKeyNonce: nonce,
+	}})
+	test.IsEqualByteSlice(t, getMasterCipher(), newKey)
+	var decrypted bytes.Buffer
+	err = DecryptReader(encInfo, bytes.NewReader(encrypted.Bytes()), &decrypted)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, decrypted.Bytes(), plaintext)
+	isModified, err := RewrapFileKey(&encInfo)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isModified, true)
+	FinishKeyRotation()
+
+	err = loadPreviousMasterKey(wrappedKey, []byte("invalid"))
+	test.IsNotNil(t, err)
+	// The wrapped key can only be decrypted with the key it has been wrapped with
+	storeMasterKey(oldKey)
+	err = loadPreviousMasterKey(wrappedKey, nonce)
+	test.IsNotNil(t, err)
+	storeMasterKey(newKey)
+}
+
+func TestGetKeyFromPassword(t *testing.T) {
+	key, err := GetKeyFromPassword("password", "salt")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(key), blockSize)
+	expected, err := scrypt.Key([]byte("password"), []byte("salt"), 1048576, 8, 1, blockSize)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, key, expected)
+}
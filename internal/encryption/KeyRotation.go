@@ -0,0 +1,113 @@
+package encryption
+
+import (
+	"errors"
+
+	"github.com/forceu/gokapi/internal/models"
+)
+
+var previousEncryptedKey, previousRamCipher []byte
+
+// StartKeyRotation replaces the master key with newMasterKey. Until FinishKeyRotation is called,
+// the previous master key is kept in memory, so that files that have not been re-wrapped yet can still be decrypted.
+// New file keys are always encrypted with the new master key
+func StartKeyRotation(newMasterKey []byte) error {
+	if len(newMasterKey) != blockSize {
+		return errors.New("invalid master key length")
+	}
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+	if len(ramCipher) == 0 {
+		return errors.New("no master key has been loaded")
+	}
+	previousKey := unprotectKey(ramCipher, encryptedKey)
+	previousRamCipher, previousEncryptedKey = protectKey(previousKey)
+	ramCipher, encryptedKey = protectKey(newMasterKey)
+	return nil
+}
+
+// WrapMasterKey encrypts the current master key with newMasterKey. The result can be stored in the configuration,
+// so that the previous master key is still available after a restart if a key rotation has been interrupted
+func WrapMasterKey(newMasterKey []byte) ([]byte, []byte, error) {
+	if len(newMasterKey) != blockSize {
+		return nil, nil, errors.New("invalid master key length")
+	}
+	if !IsDecryptionAvailable() {
+		return nil, nil, errors.New("no master key has been loaded")
+	}
+	nonce, err := getRandomData(nonceSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrappedKey, err := EncryptDecryptBytes(getMasterCipher(), newMasterKey, nonce, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return wrappedKey, nonce, nil
+}
+
+// loadPreviousMasterKey decrypts a master key that has been encrypted with WrapMasterKey and keeps it in memory
+// as the previous master key, until FinishKeyRotation is called
+func loadPreviousMasterKey(wrappedKey, nonce []byte) error {
+	if len(nonce) != nonceSize {
+		return errors.New("invalid nonce")
+	}
+	previousKey, err := EncryptDecryptBytes(wrappedKey, getMasterCipher(), nonce, false)
+	if err != nil {
+		return err
+	}
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+	previousRamCipher, previousEncryptedKey = protectKey(previousKey)
+	return nil
+}
+
+// FinishKeyRotation removes the previous master key from memory
+func FinishKeyRotation() {
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+	previousRamCipher, previousEncryptedKey = nil, nil
+}
+
+func getPreviousMasterCipher() ([]byte, bool) {
+	keyMutex.RLock()
+	defer keyMutex.RUnlock()
+	if len(previousRamCipher) == 0 {
+		return nil, false
+	}
+	return unprotectKey(previousRamCipher, previousEncryptedKey), true
+}
+
+// RewrapFileKey encrypts the file key of encInfo with the current master key, if it is still encrypted with the
+// previous master key. The content of the file does not need to be changed. Returns true if encInfo was modified
+func RewrapFileKey(encInfo *models.EncryptionInfo) (bool, error) {
+	if !encInfo.IsEncrypted || encInfo.IsEndToEndEncrypted {
+		return false, nil
+	}
+	if len(encInfo.Nonce) != nonceSize {
+		return false, errors.New("file key has an invalid nonce")
+	}
+	_, err := fileCipherDecrypt(encInfo.DecryptionKey, encInfo.Nonce)
+	if err == nil {
+		return false, nil
+	}
+	previousCipher, ok := getPreviousMasterCipher()
+	if !ok {
+		return false, errors.New("file key cannot be decrypted with the current master key")
+	}
+	fileKey, err := EncryptDecryptBytes(encInfo.DecryptionKey, previousCipher, encInfo.Nonce, false)
+	if err != nil {
+		return false, errors.New("file key cannot be decrypted with the current or previous master key")
+	}
+	nonce, err := getRandomData(nonceSize)
+	if err != nil {
+		return false, err
+	}
+	wrappedKey, err := fileCipherEncrypt(fileKey, nonce)
+	if err != nil {
+		return false, err
+	}
+	encInfo.DecryptionKey = wrappedKey
+	encInfo.Nonce = nonce
+	return true, nil
+}
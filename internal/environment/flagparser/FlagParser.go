@@ -60,6 +60,10 @@ func ParseFlags() MainFlags {
 	installService := passedFlags.Bool("install-service", false, "Installs Gokapi as a systemd service")
 	uninstallService := passedFlags.Bool("uninstall-service", false, "Uninstalls the Gokapi systemd service")
 	deploymentPassword := passedFlags.String("deployment-password", "", "Sets a new password. This should only be used for non-interactive deployment")
+	rotateEncryptionKey := passedFlags.Bool("rotate-encryption-key", false, "Replaces the master encryption key without re-encrypting the stored files")
+	encryptExistingFiles := passedFlags.Bool("encrypt-existing-files", false, "Encrypts all stored files that have been uploaded before encryption was enabled")
+	scrub := passedFlags.Bool("scrub", false, "Verifies all stored files and searches for orphaned files")
+	scrubAction := passedFlags.String("scrub-action", "none", "Action for problems found with --scrub: none, quarantine or delete")
 
 	passedFlags.Usage = showUsage(passedFlags, aliases)
 	err := passedFlags.Parse(os.Args[1:])
@@ -72,22 +76,103 @@ func ParseFlags() MainFlags {
 	}
 
 	result := MainFlags{
-		ShowVersion:        *versionFlagShort || *versionFlagLong,
-		Reconfigure:        *reconfigureFlag,
-		CreateSsl:          *createSslFlag,
-		ConfigPath:         getAliasedString(configPathFlagLong, configPathFlagShort),
-		ConfigDir:          getAliasedString(configDirFlagLong, configDirFlagShort),
-		DataDir:            getAliasedString(dataDirFlagLong, dataDirFlagShort),
-		Port:               getAliasedInt(portFlagLong, portFlagShort),
-		DisableCorsCheck:   *disableCorsCheck,
-		InstallService:     *installService,
-		UninstallService:   *uninstallService,
-		DeploymentPassword: *deploymentPassword,
+		ShowVersion:          *versionFlagShort || *versionFlagLong,
+		Reconfigure:          *reconfigureFlag,
+		CreateSsl:            *createSslFlag,
+		ConfigPath:           getAliasedString(configPathFlagLong, configPathFlagShort),
+		ConfigDir:            getAliasedString(configDirFlagLong, configDirFlagShort),
+		DataDir:              getAliasedString(dataDirFlagLong, dataDirFlagShort),
+		Port:                 getAliasedInt(portFlagLong, portFlagShort),
+		DisableCorsCheck:     *disableCorsCheck,
+		InstallService:       *installService,
+		UninstallService:     *uninstallService,
+		DeploymentPassword:   *deploymentPassword,
+		RotateEncryptionKey:  *rotateEncryptionKey,
+		EncryptExistingFiles: *encryptExistingFiles,
+		Scrub:                *scrub,
+		ScrubAction:          *scrubAction,
+		AdminCommand:         getAdminCommand(passedFlags.Args()),
+		Backup:               getBackupFlags(passedFlags.Args()),
+		Restore:              getRestoreFlags(passedFlags.Args()),
 	}
 	result.setBoolValues()
 	return result
 }
 
+// adminCommands contains the command groups that are executed without starting the webserver
+var adminCommands = []string{"user", "apikey", "file", "filerequest"}
+
+// getAdminCommand returns the remaining arguments, if they start with an admin command
+func getAdminCommand(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	for _, command := range adminCommands {
+		if args[0] == command {
+			return args
+		}
+	}
+	return nil
+}
+
+func getBackupFlags(args []string) BackupFlags {
+	if len(args) == 0 || args[0] != "backup" {
+		return BackupFlags{}
+	}
+	backupFlags := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := backupFlags.String("output", "", "Path of the archive that is created")
+	includeData := backupFlags.Bool("include-data", false, "Includes the files stored in the data directory")
+
+	backupFlags.Usage = func() {
+		fmt.Println("Usage of backup:")
+		backupFlags.PrintDefaults()
+	}
+	err := backupFlags.Parse(args[1:])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *output == "" {
+		fmt.Println("No output path for the backup was passed")
+		os.Exit(1)
+	}
+	return BackupFlags{
+		DoBackup:    true,
+		Output:      *output,
+		IncludeData: *includeData,
+	}
+}
+
+func getRestoreFlags(args []string) RestoreFlags {
+	if len(args) == 0 || args[0] != "restore" {
+		return RestoreFlags{}
+	}
+	restoreFlags := flag.NewFlagSet("restore", flag.ExitOnError)
+	input := restoreFlags.String("input", "", "Path of the archive that is restored")
+	skipData := restoreFlags.Bool("skip-data", false, "Does not restore the files of the data directory, even if they are included")
+	force := restoreFlags.Bool("force", false, "Overwrites an existing configuration")
+
+	restoreFlags.Usage = func() {
+		fmt.Println("Usage of restore:")
+		restoreFlags.PrintDefaults()
+	}
+	err := restoreFlags.Parse(args[1:])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *input == "" {
+		fmt.Println("No input path for the restore was passed")
+		os.Exit(1)
+	}
+	return RestoreFlags{
+		DoRestore: true,
+		Input:     *input,
+		SkipData:  *skipData,
+		Force:     *force,
+	}
+}
+
 func parseMigration() (MainFlags, bool) {
 	if len(os.Args) > 1 && os.Args[1] == "migrate-database" {
 		migrateFlags := parseMigrateFlags(os.Args[2:])
@@ -133,6 +218,21 @@ func parseMigrateFlags(args []string) MigrateFlags {
 	}
 }
 
+// BackupFlags contains flags passed if a backup is requested
+type BackupFlags struct {
+	DoBackup    bool
+	Output      string
+	IncludeData bool
+}
+
+// RestoreFlags contains flags passed if a restore is requested
+type RestoreFlags struct {
+	DoRestore bool
+	Input     string
+	SkipData  bool
+	Force     bool
+}
+
 // MigrateFlags contains flags passed if migration is requested
 type MigrateFlags struct {
 	DoMigration bool
@@ -163,6 +263,18 @@ func showUsage(mainFlags flag.FlagSet, aliases []alias) func() {
 		fmt.Printf("\n%-30s %s\n", "migrate-database", "Migrate an old database to a new database (e.g. SQLite to Redis)")
 		fmt.Printf("%-30s %s\n", "--source", "Original database path")
 		fmt.Printf("%-30s %s\n", "--destination", "New database path")
+		fmt.Printf("\n%-30s %s\n", "backup", "Create a backup of the configuration, the database and optionally the stored files")
+		fmt.Printf("%-30s %s\n", "--output", "Path of the archive")
+		fmt.Printf("%-30s %s\n", "--include-data", "Include the files of the data directory")
+		fmt.Printf("\n%-30s %s\n", "restore", "Restore a backup into an empty instance")
+		fmt.Printf("%-30s %s\n", "--input", "Path of the archive")
+		fmt.Printf("%-30s %s\n", "--skip-data", "Do not restore the files of the data directory")
+		fmt.Printf("%-30s %s\n", "--force", "Overwrite an existing configuration")
+		fmt.Printf("\n%-30s %s\n", "user <command>", "Manage users without starting the server: list, create, delete, set-rank")
+		fmt.Printf("%-30s %s\n", "apikey <command>", "Manage API keys without starting the server: create, revoke")
+		fmt.Printf("%-30s %s\n", "file <command>", "Manage files without starting the server: list, delete, extend, release")
+		fmt.Printf("%-30s %s\n", "filerequest <command>", "Manage file requests without starting the server: list, delete")
+		fmt.Printf("%-30s %s\n", "", "Pass --help after a command to show its options")
 
 	}
 }
@@ -182,24 +294,31 @@ func getAliasedInt(flag1, flag2 *int) int {
 
 // MainFlags holds info for the parsed program arguments
 type MainFlags struct {
-	ConfigPath         string
-	ConfigDir          string
-	DataDir            string
-	DatabaseUrl        string
-	DeploymentPassword string
-	ShowVersion        bool
-	Reconfigure        bool
-	CreateSsl          bool
-	IsConfigPathSet    bool
-	IsConfigDirSet     bool
-	IsDataDirSet       bool
-	IsPortSet          bool
-	IsDatabaseUrlSet   bool
-	DisableCorsCheck   bool
-	InstallService     bool
-	UninstallService   bool
-	Port               int
-	Migration          MigrateFlags
+	ConfigPath           string
+	ConfigDir            string
+	DataDir              string
+	DatabaseUrl          string
+	DeploymentPassword   string
+	ShowVersion          bool
+	Reconfigure          bool
+	CreateSsl            bool
+	IsConfigPathSet      bool
+	IsConfigDirSet       bool
+	IsDataDirSet         bool
+	IsPortSet            bool
+	IsDatabaseUrlSet     bool
+	DisableCorsCheck     bool
+	InstallService       bool
+	UninstallService     bool
+	RotateEncryptionKey  bool
+	EncryptExistingFiles bool
+	Scrub                bool
+	ScrubAction          string
+	Port                 int
+	Migration            MigrateFlags
+	AdminCommand         []string
+	Backup               BackupFlags
+	Restore              RestoreFlags
 }
 
 func (mf *MainFlags) setBoolValues() {
@@ -128,6 +128,51 @@ func TestParseFlags(t *testing.T) {
 				test.IsEqualBool(t, flags.UninstallService, true)
 			},
 		},
+		{
+			name: "AdminCommand",
+			args: []string{"user", "list", "--json"},
+			assertion: func(flags MainFlags) {
+				test.IsEqualInt(t, len(flags.AdminCommand), 3)
+				test.IsEqualString(t, flags.AdminCommand[1], "list")
+			},
+		},
+		{
+			name: "AdminCommandWithConfigDir",
+			args: []string{"--config-dir", "/path/to/config/dir", "file", "list"},
+			assertion: func(flags MainFlags) {
+				test.IsEqualString(t, flags.ConfigDir, "/path/to/config/dir")
+				test.IsEqualInt(t, len(flags.AdminCommand), 2)
+				test.IsEqualString(t, flags.AdminCommand[0], "file")
+			},
+		},
+		{
+			name: "Backup",
+			args: []string{"backup", "--output", "/tmp/backup.tar", "--include-data"},
+			assertion: func(flags MainFlags) {
+				test.IsEqualBool(t, flags.Backup.DoBackup, true)
+				test.IsEqualString(t, flags.Backup.Output, "/tmp/backup.tar")
+				test.IsEqualBool(t, flags.Backup.IncludeData, true)
+				test.IsEqualBool(t, flags.Restore.DoRestore, false)
+			},
+		},
+		{
+			name: "Restore",
+			args: []string{"--config-dir", "/path/to/config/dir", "restore", "--input", "/tmp/backup.tar", "--force"},
+			assertion: func(flags MainFlags) {
+				test.IsEqualBool(t, flags.Restore.DoRestore, true)
+				test.IsEqualString(t, flags.Restore.Input, "/tmp/backup.tar")
+				test.IsEqualBool(t, flags.Restore.Force, true)
+				test.IsEqualBool(t, flags.Restore.SkipData, false)
+				test.IsEqualString(t, flags.ConfigDir, "/path/to/config/dir")
+			},
+		},
+		{
+			name: "NoAdminCommand",
+			args: []string{"--reconfigure", "unknown"},
+			assertion: func(flags MainFlags) {
+				test.IsEqualInt(t, len(flags.AdminCommand), 0)
+			},
+		},
 	}
 
 	for _, testCase := range tests {
@@ -170,6 +215,21 @@ func TestShowUsage(t *testing.T) {
 migrate-database               Migrate an old database to a new database (e.g. SQLite to Redis)
 --source                       Original database path
 --destination                  New database path
+
+backup                         Create a backup of the configuration, the database and optionally the stored files
+--output                       Path of the archive
+--include-data                 Include the files of the data directory
+
+restore                        Restore a backup into an empty instance
+--input                        Path of the archive
+--skip-data                    Do not restore the files of the data directory
+--force                        Overwrite an existing configuration
+
+user <command>                 Manage users without starting the server: list, create, delete, set-rank
+apikey <command>               Manage API keys without starting the server: create, revoke
+file <command>                 Manage files without starting the server: list, delete, extend, release
+filerequest <command>          Manage file requests without starting the server: list, delete
+                               Pass --help after a command to show its options
 `
 
 	test.IsEqualString(t, capturedOutput, expectedOutput)
@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/environment"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+type contextKey string
+
+const contextKeyApiKeyId contextKey = "apiKeyId"
+const contextKeyRecipient contextKey = "recipient"
+
+var auditLogEnabled = false
+var auditLogRetentionDays = 0
+
+// categories contains the category of the text log for each action
+var categories = map[string]string{
+	models.AuditActionStartup:            categoryInfo,
+	models.AuditActionShutdown:           categoryInfo,
+	models.AuditActionSetup:              categoryAuth,
+	models.AuditActionDeploymentPassword: categoryAuth,
+	models.AuditActionDeprecation:        categoryWarning,
+	models.AuditActionLogsDelete:         categoryWarning,
+	models.AuditActionKeyRotation:        categoryWarning,
+	models.AuditActionEncryptFiles:       categoryWarning,
+	models.AuditActionScrub:              categoryWarning,
+	models.AuditActionScrubResolve:       categoryWarning,
+	models.AuditActionFileUpload:         categoryUpload,
+	models.AuditActionFileScan:           categoryUpload,
+	models.AuditActionFileRelease:        categoryWarning,
+	models.AuditActionFileExpire:         categoryInfo,
+	models.AuditActionFileDownload:       categoryDownload,
+}
+
+// InitAuditLog enables storing all log entries as structured events in the database.
+// Must be called after the database has been connected
+func InitAuditLog() {
+	auditLogRetentionDays = environment.New().AuditLogRetentionDays
+	auditLogEnabled = true
+}
+
+// SetApiKeyId returns a copy of the request that contains the public ID of the API key
+// it was authorised with. The ID is then added to all audit events created for this request
+func SetApiKeyId(r *http.Request, publicId string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextKeyApiKeyId, publicId))
+}
+
+func getApiKeyId(r *http.Request) string {
+	publicId, ok := r.Context().Value(contextKeyApiKeyId).(string)
+	if !ok {
+		return ""
+	}
+	return publicId
+}
+
+// SetRecipientInRequest returns a copy of the request that contains the authenticated recipient
+// of a file that requires a login. The recipient is then added to the download log entry
+func SetRecipientInRequest(r *http.Request, recipient models.Recipient) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextKeyRecipient, recipient))
+}
+
+func getRecipient(r *http.Request) (models.Recipient, bool) {
+	recipient, ok := r.Context().Value(contextKeyRecipient).(models.Recipient)
+	return recipient, ok
+}
+
+// newAuditEvent returns a successful event for the action triggered by the user. If r is not nil,
+// the IP address and the API key of the request are added
+func newAuditEvent(action string, user models.User, r *http.Request, details string) models.AuditEvent {
+	event := models.AuditEvent{
+		Action:  action,
+		Result:  models.AuditResultSuccess,
+		UserId:  user.Id,
+		Details: details,
+	}
+	if r != nil {
+		event.Ip = GetIpAddress(r)
+		event.ApiKeyId = getApiKeyId(r)
+	}
+	return event
+}
+
+// createAuditEntry adds the details of the event to the text log and stores the event in the audit log
+func createAuditEntry(event models.AuditEvent, blocking bool) {
+	createLogEntry(getCategory(event.Action), event.Details, blocking)
+	saveAuditEvent(event)
+}
+
+func saveAuditEvent(event models.AuditEvent) {
+	if !auditLogEnabled {
+		return
+	}
+	event.Timestamp = time.Now().Unix()
+	database.SaveAuditEvent(event)
+}
+
+func getCategory(action string) string {
+	category, ok := categories[action]
+	if ok {
+		return category
+	}
+	if strings.HasPrefix(action, "auth.") || strings.HasPrefix(action, "user.") {
+		return categoryAuth
+	}
+	return categoryEdit
+}
+
+// CleanUpAuditLog deletes all audit events that are older than the configured retention period
+func CleanUpAuditLog() {
+	if !auditLogEnabled || auditLogRetentionDays == 0 {
+		return
+	}
+	cutoff := time.Now().Add(-time.Duration(auditLogRetentionDays) * 24 * time.Hour)
+	database.DeleteAuditEventsBefore(cutoff.Unix())
+}
+
+// getAuditLogText returns all audit events since the given timestamp in the format of the text log
+func getAuditLogText(since int64) string {
+	var output strings.Builder
+	events := database.QueryAuditEvents(models.AuditQuery{Since: since}).Events
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		output.WriteString(createLogFormatCustomTimestamp(getCategory(event.Action), event.Details, time.Unix(event.Timestamp, 0)))
+		output.WriteByte('\n')
+	}
+	return output.String()
+}
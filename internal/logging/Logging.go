@@ -11,9 +11,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/environment"
 	"github.com/forceu/gokapi/internal/environment/deprecation"
 	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging/mail"
+	"github.com/forceu/gokapi/internal/logging/metrics"
+	"github.com/forceu/gokapi/internal/logging/webhooks"
 	"github.com/forceu/gokapi/internal/models"
 )
 
@@ -28,6 +32,8 @@ const categoryAuth = "auth"
 const categoryWarning = "warning"
 
 var outputToStdout = false
+var outputToFile = true
+var forceBlocking = false
 var useCloudflare = false
 
 var parsedTrustedIPs []net.IP
@@ -38,10 +44,18 @@ func Init(filePath string) {
 	logPath = filePath + "/log.txt"
 	env := environment.New()
 	outputToStdout = env.LogToStdout
+	outputToFile = !env.DisableLogFile
 	useCloudflare = env.UseCloudFlare
 	parseTrustedProxies(env.TrustedProxies, !env.DisableDockerTrustedProxy)
 }
 
+// SetCommandLineMode disables the output to stdout and writes all entries synchronously.
+// Used for commands that print their result to stdout and exit afterwards
+func SetCommandLineMode() {
+	outputToStdout = false
+	forceBlocking = true
+}
+
 // parseTrustedProxies processes the raw strings into net.IP and net.IPNet objects
 func parseTrustedProxies(proxies []string, useDockerSubnet bool) {
 	parsedTrustedIPs = nil
@@ -94,8 +108,12 @@ func getDockerSubnet() (*net.IPNet, error) {
 	return nil, fmt.Errorf("no Docker subnet found")
 }
 
-// GetAll returns all log entries as a single string and if the log file exists
+// GetAll returns all log entries as a single string and if the log file exists.
+// If the log file is disabled, the entries are read from the audit log
 func GetAll() (string, bool) {
+	if !outputToFile && auditLogEnabled {
+		return getAuditLogText(0), true
+	}
 	exists, err := helper.FileExists(logPath)
 	helper.Check(err)
 	if exists {
@@ -106,8 +124,12 @@ func GetAll() (string, bool) {
 	return fmt.Sprintf("[%s] No log file found!", categoryWarning), false
 }
 
-// GetSince returns all log entries since a given timestamp
+// GetSince returns all log entries since a given timestamp.
+// If the log file is disabled, the entries are read from the audit log
 func GetSince(timestamp int64) string {
+	if !outputToFile && auditLogEnabled {
+		return getAuditLogText(timestamp)
+	}
 	exists, err := helper.FileExists(logPath)
 	helper.Check(err)
 	if !exists {
@@ -214,7 +236,10 @@ func createLogEntry(category, text string, blocking bool) {
 	if outputToStdout {
 		fmt.Println(output)
 	}
-	if blocking {
+	if !outputToFile {
+		return
+	}
+	if blocking || forceBlocking {
 		writeToFile(output)
 	} else {
 		go writeToFile(output)
@@ -230,59 +255,163 @@ func createLogFormatCustomTimestamp(category, text string, timestamp time.Time)
 
 // LogStartup adds a log entry to indicate that Gokapi has started. Non-blocking
 func LogStartup() {
-	createLogEntry(categoryInfo, "Gokapi started", false)
+	createAuditEntry(newAuditEvent(models.AuditActionStartup, models.User{}, nil, "Gokapi started"), false)
 }
 
 // LogShutdown adds a log entry to indicate that Gokapi is shutting down. Blocking call
 func LogShutdown() {
-	createLogEntry(categoryInfo, "Gokapi shutting down", true)
+	createAuditEntry(newAuditEvent(models.AuditActionShutdown, models.User{}, nil, "Gokapi shutting down"), true)
 }
 
 // LogSetup adds a log entry to indicate that the setup was run. Non-blocking
 func LogSetup() {
-	createLogEntry(categoryAuth, "Re-running Gokapi setup", false)
+	createAuditEntry(newAuditEvent(models.AuditActionSetup, models.User{}, nil, "Re-running Gokapi setup"), false)
 }
 
 // LogDeploymentPassword adds a log entry to indicate that a deployment password was set. Non-blocking
 func LogDeploymentPassword() {
-	createLogEntry(categoryAuth, "Setting new admin password", false)
+	createAuditEntry(newAuditEvent(models.AuditActionDeploymentPassword, models.User{}, nil, "Setting new admin password"), false)
+}
+
+// LogKeyRotation adds a log entry to indicate that the master encryption key was replaced. If user is empty,
+// the key was rotated from the command line. Blocking
+func LogKeyRotation(user models.User, r *http.Request, rewrappedKeys, failedFiles int) {
+	event := newAuditEvent(models.AuditActionKeyRotation, user, r, fmt.Sprintf("Master encryption key was rotated by %s, %d file keys re-encrypted, %d failed",
+		getInitiator(user), rewrappedKeys, failedFiles))
+	if failedFiles > 0 {
+		event.Result = models.AuditResultFailure
+	}
+	createAuditEntry(event, true)
+}
+
+// LogEncryptFiles adds a log entry to indicate that stored plaintext files were encrypted. If user is empty,
+// the files were encrypted from the command line. Blocking
+func LogEncryptFiles(user models.User, r *http.Request, encryptedFiles, failedFiles int) {
+	event := newAuditEvent(models.AuditActionEncryptFiles, user, r, fmt.Sprintf("Existing files were encrypted by %s, %d files encrypted, %d failed",
+		getInitiator(user), encryptedFiles, failedFiles))
+	if failedFiles > 0 {
+		event.Result = models.AuditResultFailure
+	}
+	createAuditEntry(event, true)
+}
+
+// LogScrubStart adds a log entry to indicate that a storage check was started by a user. Non-blocking
+func LogScrubStart(user models.User, r *http.Request, action string) {
+	createAuditEntry(newAuditEvent(models.AuditActionScrub, user, r, fmt.Sprintf("Storage check was started by %s (action: %s)",
+		getInitiator(user), action)), false)
+}
+
+// LogScrubFinished adds a log entry with the summary of a completed storage check. Blocking
+func LogScrubFinished(report models.ScrubReport) {
+	unresolved := 0
+	for _, finding := range report.Findings {
+		if finding.Resolution == "" {
+			unresolved++
+		}
+	}
+	event := newAuditEvent(models.AuditActionScrub, models.User{}, nil, fmt.Sprintf("Storage check finished, %d objects checked, %d problems found, %d unresolved (action: %s)",
+		report.CheckedObjects, len(report.Findings), unresolved, report.Action))
+	if unresolved > 0 || report.Error != "" {
+		event.Result = models.AuditResultFailure
+	}
+	createAuditEntry(event, true)
+}
+
+// LogScrubResolve adds a log entry to indicate that the findings of a storage check were resolved. Non-blocking
+func LogScrubResolve(user models.User, r *http.Request, action string, resolved int) {
+	createAuditEntry(newAuditEvent(models.AuditActionScrubResolve, user, r, fmt.Sprintf("%d findings of the storage check were resolved by %s (action: %s)",
+		resolved, getInitiator(user), action)), false)
+}
+
+func getInitiator(user models.User) string {
+	if user.Id == 0 {
+		return "command line"
+	}
+	return fmt.Sprintf("%s (user #%d)", user.Name, user.Id)
 }
 
 // LogUserDeletion adds a log entry to indicate that a user was deleted. Non-blocking
-func LogUserDeletion(modifiedUser, userEditor models.User) {
-	createLogEntry(categoryAuth, fmt.Sprintf("%s (#%d) was deleted by %s (user #%d)",
-		modifiedUser.Name, modifiedUser.Id, userEditor.Name, userEditor.Id), false)
+func LogUserDeletion(modifiedUser, userEditor models.User, r *http.Request) {
+	createAuditEntry(newAuditEvent(models.AuditActionUserDelete, userEditor, r, fmt.Sprintf("%s (#%d) was deleted by %s (user #%d)",
+		modifiedUser.Name, modifiedUser.Id, userEditor.Name, userEditor.Id)), false)
+	webhooks.Trigger(webhooks.Event{Name: models.WebhookEventUserDelete, User: &userEditor, ModifiedUser: &modifiedUser})
 }
 
 // LogUserEdit adds a log entry to indicate that a user was modified. Non-blocking
-func LogUserEdit(modifiedUser, userEditor models.User) {
-	createLogEntry(categoryAuth, fmt.Sprintf("%s (#%d) was modified by %s (user #%d)",
-		modifiedUser.Name, modifiedUser.Id, userEditor.Name, userEditor.Id), false)
+func LogUserEdit(modifiedUser, userEditor models.User, r *http.Request) {
+	createAuditEntry(newAuditEvent(models.AuditActionUserEdit, userEditor, r, fmt.Sprintf("%s (#%d) was modified by %s (user #%d)",
+		modifiedUser.Name, modifiedUser.Id, userEditor.Name, userEditor.Id)), false)
+	webhooks.Trigger(webhooks.Event{Name: models.WebhookEventUserEdit, User: &userEditor, ModifiedUser: &modifiedUser})
 }
 
 // LogUserCreation adds a log entry to indicate that a user was created. Non-blocking
-func LogUserCreation(modifiedUser, userEditor models.User) {
-	createLogEntry(categoryAuth, fmt.Sprintf("%s (#%d) was created by %s (user #%d)",
-		modifiedUser.Name, modifiedUser.Id, userEditor.Name, userEditor.Id), false)
+func LogUserCreation(modifiedUser, userEditor models.User, r *http.Request) {
+	createAuditEntry(newAuditEvent(models.AuditActionUserCreate, userEditor, r, fmt.Sprintf("%s (#%d) was created by %s (user #%d)",
+		modifiedUser.Name, modifiedUser.Id, userEditor.Name, userEditor.Id)), false)
+	webhooks.Trigger(webhooks.Event{Name: models.WebhookEventUserCreate, User: &userEditor, ModifiedUser: &modifiedUser})
 }
 
 // LogInvalidLogin adds a log entry to indicate that an invalid login was attempted. Non-blocking
 func LogInvalidLogin(username, ip string) {
-	createLogEntry(categoryAuth, fmt.Sprintf("Invalid login for user %s by IP %s", username, ip), false)
+	metrics.IncreaseFailedLogins()
+	event := newAuditEvent(models.AuditActionLogin, models.User{}, nil, fmt.Sprintf("Invalid login for user %s by IP %s", username, ip))
+	event.Result = models.AuditResultFailure
+	event.Ip = ip
+	createAuditEntry(event, false)
 }
 
-// LogValidLogin adds a log entry to indicate that a login was successful. Non-blocking
-func LogValidLogin(username string) {
-	createLogEntry(categoryAuth, fmt.Sprintf("%s logged in sucessfully", username), false)
+// LogInvalidTwoFactor adds a log entry to indicate that an invalid two-factor code was entered. Non-blocking
+func LogInvalidTwoFactor(username, ip string) {
+	metrics.IncreaseFailedLogins()
+	event := newAuditEvent(models.AuditActionTwoFactor, models.User{}, nil, fmt.Sprintf("Invalid two-factor code for user %s by IP %s", username, ip))
+	event.Result = models.AuditResultFailure
+	event.Ip = ip
+	createAuditEntry(event, false)
+}
+
+// LogTwoFactorReset adds a log entry to indicate that the two-factor authentication of a user was reset. Non-blocking
+func LogTwoFactorReset(modifiedUser, userEditor models.User, r *http.Request) {
+	createAuditEntry(newAuditEvent(models.AuditActionTwoFactorReset, userEditor, r, fmt.Sprintf("Two-factor authentication of %s (#%d) was reset by %s (user #%d)",
+		modifiedUser.Name, modifiedUser.Id, userEditor.Name, userEditor.Id)), false)
 }
 
-// LogDownload adds a log entry when a download was requested. Non-Blocking
-func LogDownload(file models.File, r *http.Request, saveIp bool) {
+// LogPasswordReset adds a log entry when a user has set a new password with a link that was sent by email. Non-blocking
+func LogPasswordReset(user models.User, r *http.Request) {
+	createAuditEntry(newAuditEvent(models.AuditActionPasswordReset, user, r, fmt.Sprintf("Password of %s (#%d) was reset with an email link",
+		user.Name, user.Id)), false)
+}
+
+// LogValidLogin adds a log entry to indicate that a login was successful. Non-blocking
+func LogValidLogin(user models.User, r *http.Request) {
+	createAuditEntry(newAuditEvent(models.AuditActionLogin, user, r, fmt.Sprintf("%s logged in sucessfully", user.Name)), false)
+}
+
+// LogDownload adds a log entry when a download was requested. If the file was downloaded through
+// a share link, shareLinkId contains the ID of the link. Non-Blocking
+func LogDownload(file models.File, shareLinkId string, r *http.Request, saveIp bool) {
+	metrics.IncreaseDownloads()
+	event := webhooks.Event{Name: models.WebhookEventDownload, File: &file, ShareLinkId: shareLinkId, UserAgent: sanitiseUserAgent(r)}
+	auditEvent := newAuditEvent(models.AuditActionFileDownload, models.User{}, nil, "")
+	auditEvent.FileId = file.Id
+	idInfo := "ID " + file.Id
+	if shareLinkId != "" {
+		idInfo = idInfo + ", share link " + shareLinkId
+	}
+	recipient, ok := getRecipient(r)
+	if ok {
+		auditEvent.UserId = recipient.UserId
+		idInfo = idInfo + ", downloaded by " + recipient.Name
+	}
 	if saveIp {
-		createLogEntry(categoryDownload, fmt.Sprintf("%s, IP %s, ID %s, Useragent %s", file.Name, GetIpAddress(r), file.Id, sanitiseUserAgent(r)), false)
+		event.Ip = GetIpAddress(r)
+		auditEvent.Ip = event.Ip
+		auditEvent.Details = fmt.Sprintf("%s, IP %s, %s, Useragent %s", file.Name, event.Ip, idInfo, event.UserAgent)
 	} else {
-		createLogEntry(categoryDownload, fmt.Sprintf("%s, ID %s, Useragent %s", file.Name, file.Id, sanitiseUserAgent(r)), false)
+		auditEvent.Details = fmt.Sprintf("%s, %s, Useragent %s", file.Name, idInfo, event.UserAgent)
 	}
+	createAuditEntry(auditEvent, false)
+	webhooks.Trigger(event)
+	mail.NotifyDownload(file)
 }
 
 var regexUserAgent = regexp.MustCompile(`[^A-Za-z0-9/. ;:+(|)_\-,]`)
@@ -292,60 +421,139 @@ func sanitiseUserAgent(r *http.Request) string {
 }
 
 // LogUpload adds a log entry when an upload was created. Non-Blocking
-func LogUpload(file models.File, user models.User, fr models.FileRequest) {
+func LogUpload(file models.File, user models.User, fr models.FileRequest, r *http.Request) {
+	metrics.IncreaseUploads()
+	event := newAuditEvent(models.AuditActionFileUpload, user, r, "")
+	event.FileId = file.Id
 	if fr.Id != "" {
-		createLogEntry(categoryUpload, fmt.Sprintf("%s, ID %s, uploaded to file request %s (%s), owned by %s (user #%d) ", file.Name, file.Id, fr.Id, fr.Name, user.Name, user.Id), false)
+		event.FileRequestId = fr.Id
+		event.Details = fmt.Sprintf("%s, ID %s, uploaded to file request %s (%s), owned by %s (user #%d) ", file.Name, file.Id, fr.Id, fr.Name, user.Name, user.Id)
+		createAuditEntry(event, false)
+		webhooks.Trigger(webhooks.Event{Name: models.WebhookEventFileRequestUpload, File: &file, FileRequest: &fr, User: &user})
+		mail.NotifyFileRequestUpload(file, fr, user)
+	} else {
+		event.Details = fmt.Sprintf("%s, ID %s, uploaded by %s (user #%d)", file.Name, file.Id, user.Name, user.Id)
+		createAuditEntry(event, false)
+		webhooks.Trigger(webhooks.Event{Name: models.WebhookEventUpload, File: &file, User: &user})
+	}
+}
+
+// LogPostProcessing adds a log entry with the verdict of the checks that were run after an upload. Non-Blocking
+func LogPostProcessing(file models.File, passed bool, processor, reason string) {
+	event := newAuditEvent(models.AuditActionFileScan, models.User{Id: file.UserId}, nil, "")
+	event.FileId = file.Id
+	event.FileRequestId = file.UploadRequestId
+	if passed {
+		event.Details = fmt.Sprintf("%s, ID %s, passed all checks after the upload", file.Name, file.Id)
 	} else {
-		createLogEntry(categoryUpload, fmt.Sprintf("%s, ID %s, uploaded by %s (user #%d)", file.Name, file.Id, user.Name, user.Id), false)
+		event.Result = models.AuditResultFailure
+		event.Details = fmt.Sprintf("%s, ID %s, has been quarantined, rejected by %s: %s", file.Name, file.Id, processor, reason)
 	}
+	createAuditEntry(event, false)
+}
+
+// LogRelease adds a log entry when a quarantined file was released. Non-Blocking
+func LogRelease(file models.File, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileRelease, user, r, fmt.Sprintf("%s, ID %s, released from quarantine by %s",
+		file.Name, file.Id, getInitiator(user)))
+	event.FileId = file.Id
+	event.FileRequestId = file.UploadRequestId
+	createAuditEntry(event, false)
 }
 
 // LogEdit adds a log entry when an upload was edited. Non-Blocking
-func LogEdit(file models.File, user models.User) {
-	createLogEntry(categoryEdit, fmt.Sprintf("%s, ID %s, edited by %s (user #%d)", file.Name, file.Id, user.Name, user.Id), false)
+func LogEdit(file models.File, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileEdit, user, r, fmt.Sprintf("%s, ID %s, edited by %s (user #%d)", file.Name, file.Id, user.Name, user.Id))
+	event.FileId = file.Id
+	createAuditEntry(event, false)
 }
 
 // LogCreateFileRequest adds a log entry when a file request was added. Non-Blocking
-func LogCreateFileRequest(fr models.FileRequest, user models.User) {
-	createLogEntry(categoryEdit, fmt.Sprintf("File request %s (%s) created by %s (user #%d)", fr.Id, fr.Name, user.Name, user.Id), false)
+func LogCreateFileRequest(fr models.FileRequest, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileRequestCreate, user, r, fmt.Sprintf("File request %s (%s) created by %s (user #%d)", fr.Id, fr.Name, user.Name, user.Id))
+	event.FileRequestId = fr.Id
+	createAuditEntry(event, false)
 }
 
 // LogEditFileRequest adds a log entry when a file request was edited. Non-Blocking
-func LogEditFileRequest(fr models.FileRequest, user models.User) {
-	createLogEntry(categoryEdit, fmt.Sprintf("File request %s (%s) created by %s (user #%d)", fr.Id, fr.Name, user.Name, user.Id), false)
+func LogEditFileRequest(fr models.FileRequest, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileRequestEdit, user, r, fmt.Sprintf("File request %s (%s) edited by %s (user #%d)", fr.Id, fr.Name, user.Name, user.Id))
+	event.FileRequestId = fr.Id
+	createAuditEntry(event, false)
 }
 
 // LogDeleteFileRequest adds a log entry when a file request was deleted. Non-Blocking
-func LogDeleteFileRequest(fr models.FileRequest, user models.User) {
-	createLogEntry(categoryEdit, fmt.Sprintf("File request %s (%s) and associated files deleted by %s (user #%d)", fr.Id, fr.Name, user.Name, user.Id), false)
+func LogDeleteFileRequest(fr models.FileRequest, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileRequestDelete, user, r, fmt.Sprintf("File request %s (%s) and associated files deleted by %s (user #%d)", fr.Id, fr.Name, user.Name, user.Id))
+	event.FileRequestId = fr.Id
+	createAuditEntry(event, false)
 }
 
 // LogReplace adds a log entry when an upload was replaced. Non-Blocking
-func LogReplace(originalFile, newContent models.File, user models.User) {
-	createLogEntry(categoryEdit, fmt.Sprintf("%s, ID %s had content replaced with %s (ID %s) by %s (user #%d)",
-		originalFile.Name, originalFile.Id, newContent.Name, newContent.Id, user.Name, user.Id), false)
+func LogReplace(originalFile, newContent models.File, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileReplace, user, r, fmt.Sprintf("%s, ID %s had content replaced with %s (ID %s) by %s (user #%d)",
+		originalFile.Name, originalFile.Id, newContent.Name, newContent.Id, user.Name, user.Id))
+	event.FileId = originalFile.Id
+	createAuditEntry(event, false)
+	webhooks.Trigger(webhooks.Event{Name: models.WebhookEventReplace, File: &originalFile, SourceFile: &newContent, User: &user})
+}
+
+// LogRollback adds a log entry when the content of a file was restored from a previous version. Non-Blocking
+func LogRollback(file models.File, version models.FileVersion, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileRollback, user, r, fmt.Sprintf("%s, ID %s was rolled back to version %d (%s) by %s (user #%d)",
+		file.Name, file.Id, version.Revision, version.Name, user.Name, user.Id))
+	event.FileId = file.Id
+	createAuditEntry(event, false)
+	versionContent := version.ToFile()
+	webhooks.Trigger(webhooks.Event{Name: models.WebhookEventReplace, File: &file, SourceFile: &versionContent, User: &user})
 }
 
 // LogDelete adds a log entry when an upload was deleted. Non-Blocking
-func LogDelete(file models.File, user models.User) {
-	createLogEntry(categoryEdit, fmt.Sprintf("%s, ID %s, deleted by %s (user #%d)", file.Name, file.Id, user.Name, user.Id), false)
+func LogDelete(file models.File, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileDelete, user, r, fmt.Sprintf("%s, ID %s, deleted by %s (user #%d)", file.Name, file.Id, user.Name, user.Id))
+	event.FileId = file.Id
+	createAuditEntry(event, false)
+	webhooks.Trigger(webhooks.Event{Name: models.WebhookEventDelete, File: &file, User: &user})
 }
 
 // LogRestore adds a log entry when the pending deletion of a file was cancelled and the file restored. Non-Blocking
-func LogRestore(file models.File, user models.User) {
-	createLogEntry(categoryEdit, fmt.Sprintf("%s, ID %s, restored by %s (user #%d)", file.Name, file.Id, user.Name, user.Id), false)
+func LogRestore(file models.File, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileRestore, user, r, fmt.Sprintf("%s, ID %s, restored by %s (user #%d)", file.Name, file.Id, user.Name, user.Id))
+	event.FileId = file.Id
+	createAuditEntry(event, false)
+	webhooks.Trigger(webhooks.Event{Name: models.WebhookEventRestore, File: &file, User: &user})
+}
+
+// LogExpiry adds a log entry when an expired upload was deleted. Non-Blocking
+func LogExpiry(file models.File) {
+	event := newAuditEvent(models.AuditActionFileExpire, models.User{Id: file.UserId}, nil, fmt.Sprintf("%s, ID %s, expired and deleted", file.Name, file.Id))
+	event.FileId = file.Id
+	createAuditEntry(event, false)
+	mail.NotifyExpiry(file)
+}
+
+// LogShare adds a log entry when the download link of a file was sent by email. Non-Blocking
+func LogShare(file models.File, recipients []string, user models.User, r *http.Request) {
+	event := newAuditEvent(models.AuditActionFileShare, user, r, fmt.Sprintf("%s, ID %s, download link sent to %s by %s (user #%d)",
+		file.Name, file.Id, strings.Join(recipients, ", "), user.Name, user.Id))
+	event.FileId = file.Id
+	createAuditEntry(event, false)
 }
 
 // LogDeprecation adds a log entry to indicate that a deprecated feature is being used. Blocking
 func LogDeprecation(dep deprecation.Deprecation) {
-	createLogEntry(categoryWarning, "Deprecated feature: "+dep.Name, true)
+	createAuditEntry(newAuditEvent(models.AuditActionDeprecation, models.User{}, nil, "Deprecated feature: "+dep.Name), true)
 	createLogEntry(categoryWarning, dep.Description, true)
 	createLogEntry(categoryWarning, "See "+dep.DocUrl+" for more information.", true)
 }
 
-// DeleteLogs removes all logs before the cutoff timestamp and inserts a new log that the user
+// DeleteLogs removes all logs and audit events before the cutoff timestamp and inserts a new log that the user
 // deleted the previous logs
 func DeleteLogs(userName string, userId int, cutoff int64, r *http.Request) {
+	deleteAuditEvents(userName, userId, cutoff, r)
+	if !outputToFile {
+		return
+	}
 	if cutoff == 0 {
 		deleteAllLogs(userName, userId, r)
 		return
@@ -372,6 +580,18 @@ func DeleteLogs(userName string, userId int, cutoff int64, r *http.Request) {
 	defer mutex.Unlock()
 }
 
+func deleteAuditEvents(userName string, userId int, cutoff int64, r *http.Request) {
+	if !auditLogEnabled {
+		return
+	}
+	if cutoff == 0 {
+		cutoff = time.Now().Unix()
+	}
+	database.DeleteAuditEventsBefore(cutoff + 1)
+	saveAuditEvent(newAuditEvent(models.AuditActionLogsDelete, models.User{Id: userId}, r,
+		fmt.Sprintf("Previous logs deleted by %s (user #%d) on %s. IP: %s", userName, userId, getDate(time.Now()), GetIpAddress(r))))
+}
+
 func parseTimeLogEntry(input string) (time.Time, error) {
 	const layout = "Mon, 02 Jan 2006 15:04:05 MST"
 	lineContent := strings.Split(input, "   [")
@@ -423,6 +643,20 @@ func isTrustedProxy(ip net.IP) bool {
 	return false
 }
 
+// IsTrustedProxyRequest returns true if the request was sent by a trusted proxy itself
+// and not forwarded on behalf of another client
+func IsTrustedProxyRequest(r *http.Request) bool {
+	if r.Header.Get("X-FORWARDED-FOR") != "" || r.Header.Get("X-REAL-IP") != "" || r.Header.Get("CF-Connecting-IP") != "" {
+		return false
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	netIP := net.ParseIP(ip)
+	return netIP != nil && isTrustedProxy(netIP)
+}
+
 // GetIpAddress returns the IP address of the requester
 func GetIpAddress(r *http.Request) string {
 
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
@@ -14,7 +15,13 @@ import (
 
 func TestMain(m *testing.M) {
 	testconfiguration.Create(false)
+	config, err := database.ParseUrl(testconfiguration.SqliteUrl, false)
+	if err != nil {
+		panic(err)
+	}
+	database.Connect(config)
 	exitVal := m.Run()
+	database.Close()
 	testconfiguration.Delete()
 	os.Exit(exitVal)
 }
@@ -45,6 +52,23 @@ func TestGetIpAddress(t *testing.T) {
 	test.IsEqualString(t, GetIpAddress(r), "3.3.3.3")
 }
 
+func TestIsTrustedProxyRequest(t *testing.T) {
+	Init("test")
+	r := httptest.NewRequest("GET", "/test", nil)
+	test.IsEqualBool(t, IsTrustedProxyRequest(r), false)
+	r.RemoteAddr = "127.0.0.1:1234"
+	test.IsEqualBool(t, IsTrustedProxyRequest(r), true)
+	r.RemoteAddr = "invalid"
+	test.IsEqualBool(t, IsTrustedProxyRequest(r), false)
+	r.RemoteAddr = "127.0.0.1"
+	test.IsEqualBool(t, IsTrustedProxyRequest(r), true)
+	r.Header.Add("X-FORWARDED-FOR", "127.0.0.1")
+	test.IsEqualBool(t, IsTrustedProxyRequest(r), false)
+	r.Header.Del("X-FORWARDED-FOR")
+	r.Header.Add("CF-Connecting-IP", "127.0.0.1")
+	test.IsEqualBool(t, IsTrustedProxyRequest(r), false)
+}
+
 func TestInit(t *testing.T) {
 	Init("test")
 	test.IsEqualString(t, logPath, "test/log.txt")
@@ -67,15 +91,78 @@ func TestAddDownload(t *testing.T) {
 	r.RemoteAddr = "127.0.0.1"
 	r.Header.Set("User-Agent", "testAgent")
 	r.Header.Add("X-REAL-IP", "1.1.1.1")
-	LogDownload(file, r, true)
+	LogDownload(file, "", r, true)
 	// Need sleep, as LogDownload() is non-blocking
 	time.Sleep(500 * time.Millisecond)
 	content, _ := os.ReadFile("test/log.txt")
 	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [download] testName, IP 1.1.1.1, ID testId, Useragent testAgent"), true)
 	r.Header.Add("X-REAL-IP", "2.2.2.2")
-	LogDownload(file, r, false)
+	LogDownload(file, "", r, false)
 	// Need sleep, as LogDownload() is non-blocking
 	time.Sleep(500 * time.Millisecond)
 	content, _ = os.ReadFile("test/log.txt")
 	test.IsEqualBool(t, strings.Contains(string(content), "2.2.2.2"), false)
+	LogDownload(file, "linkId", r, false)
+	// Need sleep, as LogDownload() is non-blocking
+	time.Sleep(500 * time.Millisecond)
+	content, _ = os.ReadFile("test/log.txt")
+	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [download] testName, ID testId, share link linkId, Useragent testAgent"), true)
+	LogDownload(file, "", SetRecipientInRequest(r, models.Recipient{Name: "recipient@example.com"}), false)
+	// Need sleep, as LogDownload() is non-blocking
+	time.Sleep(500 * time.Millisecond)
+	content, _ = os.ReadFile("test/log.txt")
+	test.IsEqualBool(t, strings.Contains(string(content), "UTC   [download] testName, ID testId, downloaded by recipient@example.com, Useragent testAgent"), true)
+}
+
+func TestAuditLog(t *testing.T) {
+	Init("test")
+	LogStartup()
+	test.IsEqualInt(t, len(database.QueryAuditEvents(models.AuditQuery{}).Events), 0)
+
+	InitAuditLog()
+	test.IsEqualInt(t, auditLogRetentionDays, 90)
+	r := httptest.NewRequest("GET", "/test", nil)
+	r.RemoteAddr = "127.0.0.2:1234"
+	user := models.User{Id: 5, Name: "auditUser"}
+	LogUpload(models.File{Id: "auditFile", Name: "audit.txt"}, user, models.FileRequest{}, SetApiKeyId(r, "publicKeyId"))
+	LogInvalidLogin("invalidUser", "127.0.0.3")
+	LogCreateFileRequest(models.FileRequest{Id: "auditRequest", Name: "request"}, user, nil)
+
+	events := database.QueryAuditEvents(models.AuditQuery{}).Events
+	test.IsEqualInt(t, len(events), 3)
+	test.IsEqualString(t, events[2].Action, models.AuditActionFileUpload)
+	test.IsEqualString(t, events[2].Result, models.AuditResultSuccess)
+	test.IsEqualInt(t, events[2].UserId, 5)
+	test.IsEqualString(t, events[2].ApiKeyId, "publicKeyId")
+	test.IsEqualString(t, events[2].Ip, "127.0.0.2")
+	test.IsEqualString(t, events[2].FileId, "auditFile")
+	test.IsEqualString(t, events[2].Details, "audit.txt, ID auditFile, uploaded by auditUser (user #5)")
+	test.IsEqualString(t, events[1].Action, models.AuditActionLogin)
+	test.IsEqualString(t, events[1].Result, models.AuditResultFailure)
+	test.IsEqualString(t, events[1].Ip, "127.0.0.3")
+	test.IsEqualString(t, events[0].FileRequestId, "auditRequest")
+	test.IsEqualString(t, events[0].Ip, "")
+	test.IsEqualString(t, getCategory(events[0].Action), categoryEdit)
+	test.IsEqualString(t, getCategory(events[1].Action), categoryAuth)
+	test.IsEqualString(t, getCategory(events[2].Action), categoryUpload)
+
+	outputToFile = false
+	logs, ok := GetAll()
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, strings.Contains(logs, "UTC   [upload] audit.txt, ID auditFile, uploaded by auditUser (user #5)\n"), true)
+	test.IsEqualBool(t, strings.Index(logs, "[upload]") < strings.Index(logs, "[auth]"), true)
+	test.IsEqualString(t, GetSince(time.Now().Unix()+10), "")
+
+	DeleteLogs("auditUser", 5, 0, r)
+	events = database.QueryAuditEvents(models.AuditQuery{}).Events
+	test.IsEqualInt(t, len(events), 1)
+	test.IsEqualString(t, events[0].Action, models.AuditActionLogsDelete)
+	test.IsEqualInt(t, events[0].UserId, 5)
+	outputToFile = true
+
+	database.SaveAuditEvent(models.AuditEvent{Timestamp: time.Now().Add(-91 * 24 * time.Hour).Unix(), Action: models.AuditActionStartup})
+	test.IsEqualInt(t, len(database.QueryAuditEvents(models.AuditQuery{}).Events), 2)
+	CleanUpAuditLog()
+	test.IsEqualInt(t, len(database.QueryAuditEvents(models.AuditQuery{}).Events), 1)
+	auditLogEnabled = false
 }
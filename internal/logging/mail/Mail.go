@@ -0,0 +1,200 @@
+package mail
+
+/**
+Sends emails with an SMTP server, e.g. to notify users about downloads or to send download links
+*/
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/environment"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+const (
+	// TlsModeStartTls upgrades the connection with the STARTTLS command. Sending fails if the server does not support it
+	TlsModeStartTls = "starttls"
+	// TlsModeImplicit connects with TLS from the start, usually on port 465
+	TlsModeImplicit = "tls"
+	// TlsModeNone sends all emails unencrypted
+	TlsModeNone = "none"
+)
+
+const (
+	// NotificationFileRequestUpload notifies the owner of a file request about a new upload
+	NotificationFileRequestUpload = "filerequest.upload"
+	// NotificationDownload notifies the uploader of a file about a download
+	NotificationDownload = "file.download"
+	// NotificationExpiry notifies the uploader of a file that it has expired
+	NotificationExpiry = "file.expire"
+)
+
+// queueSize is the number of emails that can be queued before new emails are discarded
+const queueSize = 100
+
+// maxRecipients is the maximum number of recipients a download link can be sent to at once
+const maxRecipients = 20
+
+// ErrorNotEnabled is returned if an email is sent, but no SMTP server has been configured
+var ErrorNotEnabled = errors.New("no SMTP server has been configured")
+
+// ErrorInvalidRecipient is returned if a recipient is not a valid email address
+var ErrorInvalidRecipient = errors.New("invalid email address")
+
+// ErrorTooManyRecipients is returned if a download link is sent to more than maxRecipients addresses
+var ErrorTooManyRecipients = errors.New("too many recipients")
+
+type smtpConfig struct {
+	Host      string
+	Port      int
+	TlsMode   string
+	Username  string
+	Password  string
+	From      *netmail.Address
+	TlsConfig *tls.Config
+}
+
+type message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+var config smtpConfig
+var notifications []string
+var serverUrl string
+var includeFilename bool
+var publicName string
+var isEnabled bool
+var mutex sync.RWMutex
+var queue chan message
+var startWorkerOnce sync.Once
+
+// Init reads the SMTP settings from the environment. Until Init is called, or if no SMTP server
+// has been set, all emails are discarded
+func Init(url string, useFilenameInUrl bool, name string) {
+	env := environment.New()
+	mutex.Lock()
+	defer mutex.Unlock()
+	isEnabled = false
+	serverUrl = url
+	includeFilename = useFilenameInUrl
+	publicName = name
+	notifications = env.SmtpNotifications
+	if env.SmtpHost == "" {
+		return
+	}
+	from, err := netmail.ParseAddress(env.SmtpFrom)
+	if err != nil {
+		fmt.Println("Warning: Emails are disabled, GOKAPI_SMTP_FROM is not a valid email address")
+		return
+	}
+	tlsMode := strings.ToLower(env.SmtpTls)
+	if tlsMode != TlsModeStartTls && tlsMode != TlsModeImplicit && tlsMode != TlsModeNone {
+		fmt.Println("Warning: Emails are disabled, GOKAPI_SMTP_TLS has to be either starttls, tls or none")
+		return
+	}
+	config = smtpConfig{
+		Host:      env.SmtpHost,
+		Port:      env.SmtpPort,
+		TlsMode:   tlsMode,
+		Username:  env.SmtpUsername,
+		Password:  env.SmtpPassword,
+		From:      from,
+		TlsConfig: &tls.Config{ServerName: env.SmtpHost, MinVersion: tls.VersionTLS12},
+	}
+	isEnabled = true
+	startWorkerOnce.Do(func() {
+		queue = make(chan message, queueSize)
+		go processQueue(queue)
+	})
+}
+
+// IsEnabled returns true if an SMTP server has been configured
+func IsEnabled() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return isEnabled
+}
+
+func isNotificationEnabled(notification string) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return isEnabled && slices.Contains(notifications, notification)
+}
+
+// getUserAddress returns the email address of a user. Users are identified by their email address
+// with OAuth2 and usually with LDAP, for other users the name is only used if it is a valid address
+func getUserAddress(user models.User) (string, bool) {
+	return parseAddress(user.Name)
+}
+
+// parseAddress returns the address if it is a single valid email address without a display name
+func parseAddress(address string) (string, bool) {
+	address = strings.TrimSpace(address)
+	parsed, err := netmail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return "", false
+	}
+	return parsed.Address, true
+}
+
+// ParseRecipients splits a comma-separated list of email addresses. Returns an error if one of the
+// addresses is invalid or if there are too many
+func ParseRecipients(list string) ([]string, error) {
+	result := make([]string, 0)
+	for _, entry := range strings.Split(list, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		address, ok := parseAddress(entry)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrorInvalidRecipient, strings.TrimSpace(entry))
+		}
+		if !slices.Contains(result, address) {
+			result = append(result, address)
+		}
+	}
+	if len(result) > maxRecipients {
+		return nil, ErrorTooManyRecipients
+	}
+	return result, nil
+}
+
+// enqueue adds the email to the queue. If the queue is full, the email is discarded, so that
+// a slow SMTP server cannot block uploads or downloads
+func enqueue(msg message) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if !isEnabled {
+		return
+	}
+	select {
+	case queue <- msg:
+	default:
+		fmt.Println("Warning: Email queue is full, discarding email to " + msg.To)
+	}
+}
+
+func processQueue(messages chan message) {
+	for msg := range messages {
+		mutex.RLock()
+		currentConfig := config
+		mutex.RUnlock()
+		err := send(currentConfig, msg)
+		if err != nil {
+			fmt.Println("Error: Unable to send email to " + msg.To + ": " + err.Error())
+		}
+	}
+}
+
+func getFileOwner(file models.File) (models.User, bool) {
+	return database.GetUser(file.UserId)
+}
@@ -0,0 +1,141 @@
+package mail
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/smtpstub"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+)
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	config, err := database.ParseUrl(testconfiguration.SqliteUrl, false)
+	if err != nil {
+		panic(err)
+	}
+	database.Connect(config)
+	exitVal := m.Run()
+	database.Close()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+// startServer starts an SMTP server without encryption and configures the package to use it
+func startServer(t *testing.T, notify string) *smtpstub.Server {
+	t.Helper()
+	server, err := smtpstub.Start(smtpstub.Config{})
+	test.IsNil(t, err)
+	t.Cleanup(func() {
+		mutex.Lock()
+		isEnabled = false
+		mutex.Unlock()
+		server.Close()
+	})
+	t.Setenv("GOKAPI_SMTP_HOST", server.Host())
+	t.Setenv("GOKAPI_SMTP_PORT", strconv.Itoa(server.Port()))
+	t.Setenv("GOKAPI_SMTP_TLS", "none")
+	t.Setenv("GOKAPI_SMTP_FROM", "Gokapi <gokapi@example.com>")
+	t.Setenv("GOKAPI_SMTP_NOTIFY", notify)
+	Init("http://gokapi.local/", false, "Gokapi Test")
+	test.IsEqualBool(t, IsEnabled(), true)
+	return server
+}
+
+func TestInit(t *testing.T) {
+	Init("http://gokapi.local/", false, "Gokapi Test")
+	test.IsEqualBool(t, IsEnabled(), false)
+
+	t.Setenv("GOKAPI_SMTP_HOST", "smtp.example.com")
+	t.Setenv("GOKAPI_SMTP_FROM", "invalid")
+	Init("http://gokapi.local/", false, "Gokapi Test")
+	test.IsEqualBool(t, IsEnabled(), false)
+
+	t.Setenv("GOKAPI_SMTP_FROM", "Gokapi <gokapi@example.com>")
+	t.Setenv("GOKAPI_SMTP_TLS", "ssl")
+	Init("http://gokapi.local/", false, "Gokapi Test")
+	test.IsEqualBool(t, IsEnabled(), false)
+
+	t.Setenv("GOKAPI_SMTP_TLS", "StartTLS")
+	t.Setenv("GOKAPI_SMTP_USERNAME", "user")
+	t.Setenv("GOKAPI_SMTP_PASSWORD", "secret")
+	Init("http://gokapi.local/", false, "Gokapi Test")
+	test.IsEqualBool(t, IsEnabled(), true)
+	test.IsEqualString(t, config.Host, "smtp.example.com")
+	test.IsEqualInt(t, config.Port, 587)
+	test.IsEqualString(t, config.TlsMode, TlsModeStartTls)
+	test.IsEqualString(t, config.Username, "user")
+	test.IsEqualString(t, config.Password, "secret")
+	test.IsEqualString(t, config.From.Address, "gokapi@example.com")
+	test.IsEqualString(t, config.TlsConfig.ServerName, "smtp.example.com")
+	test.IsEqualBool(t, isNotificationEnabled(NotificationDownload), true)
+	test.IsEqualBool(t, isNotificationEnabled("invalid"), false)
+
+	t.Setenv("GOKAPI_SMTP_NOTIFY", "file.expire")
+	Init("http://gokapi.local/", false, "Gokapi Test")
+	test.IsEqualBool(t, isNotificationEnabled(NotificationDownload), false)
+	test.IsEqualBool(t, isNotificationEnabled(NotificationExpiry), true)
+
+	t.Setenv("GOKAPI_SMTP_HOST", "")
+	Init("http://gokapi.local/", false, "Gokapi Test")
+	test.IsEqualBool(t, IsEnabled(), false)
+	test.IsEqualBool(t, isNotificationEnabled(NotificationExpiry), false)
+}
+
+func TestParseRecipients(t *testing.T) {
+	recipients, err := ParseRecipients("a@example.com, b@example.com,,a@example.com ")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(recipients), 2)
+	test.IsEqualString(t, recipients[0], "a@example.com")
+	test.IsEqualString(t, recipients[1], "b@example.com")
+
+	recipients, err = ParseRecipients("")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(recipients), 0)
+
+	_, err = ParseRecipients("a@example.com,invalid")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidRecipient), true)
+	test.IsEqualBool(t, strings.Contains(err.Error(), "invalid"), true)
+	_, err = ParseRecipients("Name <a@example.com>")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidRecipient), true)
+	_, err = ParseRecipients("a@example.com\r\nBcc: b@example.com")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidRecipient), true)
+
+	var list []string
+	for i := 0; i <= maxRecipients; i++ {
+		list = append(list, "user"+strconv.Itoa(i)+"@example.com")
+	}
+	_, err = ParseRecipients(strings.Join(list, ","))
+	test.IsEqual(t, err, ErrorTooManyRecipients)
+	recipients, err = ParseRecipients(strings.Join(list[1:], ","))
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(recipients), maxRecipients)
+}
+
+func TestGetUserAddress(t *testing.T) {
+	address, ok := getUserAddress(models.User{Name: " user@example.com"})
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, address, "user@example.com")
+	_, ok = getUserAddress(models.User{Name: "admin"})
+	test.IsEqualBool(t, ok, false)
+	_, ok = getUserAddress(models.User{Name: "User <user@example.com>"})
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestEnqueueDisabled(t *testing.T) {
+	server := startServer(t, "")
+	mutex.Lock()
+	isEnabled = false
+	mutex.Unlock()
+	enqueue(message{To: "user@example.com", Subject: "Test", Body: "Test"})
+	test.IsEqualInt(t, len(queue), 0)
+	time.Sleep(50 * time.Millisecond)
+	test.IsEqualInt(t, len(server.Messages()), 0)
+}
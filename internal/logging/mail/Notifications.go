@@ -0,0 +1,200 @@
+package mail
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// mailTemplate contains the subject and the body of an email, both are parsed as text/template
+type mailTemplate struct {
+	Subject *template.Template
+	Body    *template.Template
+}
+
+// mailData contains all values that can be used in the templates
+type mailData struct {
+	PublicName          string
+	ServerUrl           string
+	SenderName          string
+	Message             string
+	FileName            string
+	FileSize            string
+	DownloadUrl         string
+	ExpireAt            string
+	IsPasswordProtected bool
+	FileRequestName     string
+	Time                string
+	ResetUrl            string
+}
+
+const signature = `
+
+--
+{{.PublicName}}
+{{.ServerUrl}}`
+
+var templateDownloadLink = newTemplate("downloadLink",
+	`{{.SenderName}} shared the file {{.FileName}} with you`,
+	`Hello,
+
+{{.SenderName}} shared the file "{{.FileName}}" ({{.FileSize}}) with you.
+{{if .Message}}
+{{.Message}}
+{{end}}
+You can download it here: {{.DownloadUrl}}
+{{if .ExpireAt}}
+The file is available until {{.ExpireAt}} (UTC).{{end}}{{if .IsPasswordProtected}}
+The file is protected with a password, which you will receive separately.{{end}}`+signature)
+
+var templateFileRequestUpload = newTemplate("fileRequestUpload",
+	`New upload for your file request {{.FileRequestName}}`,
+	`Hello,
+
+the file "{{.FileName}}" ({{.FileSize}}) has been uploaded to your file request "{{.FileRequestName}}".
+
+You can view all uploaded files here: {{.ServerUrl}}filerequests`+signature)
+
+var templateDownload = newTemplate("download",
+	`Your file {{.FileName}} has been downloaded`,
+	`Hello,
+
+your file "{{.FileName}}" has been downloaded on {{.Time}} (UTC).`+signature)
+
+var templateExpiry = newTemplate("expiry",
+	`Your file {{.FileName}} has expired`,
+	`Hello,
+
+your file "{{.FileName}}" has expired and has been deleted. It can no longer be downloaded.`+signature)
+
+var templatePasswordReset = newTemplate("passwordReset",
+	`Reset your password for {{.PublicName}}`,
+	`Hello,
+
+a new password has been requested for your account. To set a new password, open the following link
+within the next hour:
+
+{{.ResetUrl}}
+
+If you did not request a new password, you can ignore this email.`+signature)
+
+func newTemplate(name, subject, body string) mailTemplate {
+	return mailTemplate{
+		Subject: template.Must(template.New(name + "Subject").Parse(subject)),
+		Body:    template.Must(template.New(name + "Body").Parse(body)),
+	}
+}
+
+// render returns the email for the recipient with the values of data
+func (t mailTemplate) render(to string, data mailData) message {
+	mutex.RLock()
+	data.PublicName = publicName
+	data.ServerUrl = serverUrl
+	mutex.RUnlock()
+	var subject, body bytes.Buffer
+	helper.Check(t.Subject.Execute(&subject, data))
+	helper.Check(t.Body.Execute(&body, data))
+	return message{To: to, Subject: subject.String(), Body: body.String()}
+}
+
+func newFileData(file models.File) mailData {
+	mutex.RLock()
+	url := serverUrl
+	useFilename := includeFilename
+	mutex.RUnlock()
+	output, err := file.ToFileApiOutput(url, useFilename)
+	helper.Check(err)
+	data := mailData{
+		FileName:            file.Name,
+		FileSize:            file.Size,
+		DownloadUrl:         output.UrlDownload,
+		IsPasswordProtected: output.IsPasswordProtected,
+	}
+	if !file.UnlimitedTime {
+		data.ExpireAt = output.ExpireAtString
+	}
+	return data
+}
+
+// SendDownloadLink sends the download link of the file to all recipients. The recipients
+// have to be parsed with ParseRecipients first. Non-Blocking
+func SendDownloadLink(file models.File, sender models.User, recipients []string, text string) error {
+	if !IsEnabled() {
+		return ErrorNotEnabled
+	}
+	data := newFileData(file)
+	data.SenderName = sender.Name
+	data.Message = text
+	for _, recipient := range recipients {
+		enqueue(templateDownloadLink.render(recipient, data))
+	}
+	return nil
+}
+
+// NotifyFileRequestUpload notifies the owner of a file request that a file has been uploaded. Non-Blocking
+func NotifyFileRequestUpload(file models.File, fileRequest models.FileRequest, owner models.User) {
+	if !isNotificationEnabled(NotificationFileRequestUpload) {
+		return
+	}
+	address, ok := getUserAddress(owner)
+	if !ok {
+		return
+	}
+	data := newFileData(file)
+	data.FileRequestName = fileRequest.Name
+	enqueue(templateFileRequestUpload.render(address, data))
+}
+
+// NotifyDownload notifies the uploader of a file that it has been downloaded. Files of
+// file requests are only downloaded by their owner, therefore no notification is sent. Non-Blocking
+func NotifyDownload(file models.File) {
+	if file.UploadRequestId != "" || !isNotificationEnabled(NotificationDownload) {
+		return
+	}
+	go func() {
+		owner, ok := getFileOwner(file)
+		if !ok {
+			return
+		}
+		address, ok := getUserAddress(owner)
+		if !ok {
+			return
+		}
+		data := newFileData(file)
+		data.Time = time.Now().UTC().Format("2006-01-02 15:04:05")
+		enqueue(templateDownload.render(address, data))
+	}()
+}
+
+// NotifyExpiry notifies the uploader of a file that it has expired. Non-Blocking
+func NotifyExpiry(file models.File) {
+	if !isNotificationEnabled(NotificationExpiry) {
+		return
+	}
+	owner, ok := getFileOwner(file)
+	if !ok {
+		return
+	}
+	address, ok := getUserAddress(owner)
+	if !ok {
+		return
+	}
+	enqueue(templateExpiry.render(address, newFileData(file)))
+}
+
+// SendPasswordReset sends the link for setting a new password to the user. Returns false if
+// the user has no valid email address. Non-Blocking
+func SendPasswordReset(user models.User, resetUrl string) bool {
+	if !IsEnabled() {
+		return false
+	}
+	address, ok := getUserAddress(user)
+	if !ok {
+		return false
+	}
+	enqueue(templatePasswordReset.render(address, mailData{ResetUrl: resetUrl}))
+	return true
+}
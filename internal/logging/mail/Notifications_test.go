@@ -0,0 +1,132 @@
+package mail
+
+import (
+	"io"
+	"mime/quotedprintable"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/smtpstub"
+)
+
+var testFile = models.File{
+	Id:                 "mailfile",
+	Name:               "report.pdf",
+	Size:               "3 MB",
+	UserId:             20,
+	UnlimitedDownloads: true,
+	ExpireAt:           time.Date(2030, 1, 2, 3, 4, 0, 0, time.UTC).Unix(),
+}
+
+// decodeBody returns the decoded body of a received email
+func decodeBody(t *testing.T, msg smtpstub.Message) string {
+	t.Helper()
+	_, body, found := strings.Cut(msg.Data, "\r\n\r\n")
+	test.IsEqualBool(t, found, true)
+	result, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+	test.IsNil(t, err)
+	return string(result)
+}
+
+func waitForMessage(t *testing.T, server *smtpstub.Server, count int) smtpstub.Message {
+	t.Helper()
+	messages := server.WaitForMessages(count, 5*time.Second)
+	test.IsEqualInt(t, len(messages), count)
+	return messages[count-1]
+}
+
+func TestSendDownloadLink(t *testing.T) {
+	err := SendDownloadLink(testFile, models.User{Name: "Sender"}, []string{"a@example.com"}, "")
+	test.IsEqual(t, err, ErrorNotEnabled)
+
+	server := startServer(t, "")
+	err = SendDownloadLink(testFile, models.User{Name: "Sender"}, []string{"a@example.com", "b@example.com"}, "Please review")
+	test.IsNil(t, err)
+	waitForMessage(t, server, 2)
+	messages := server.Messages()
+	test.IsEqualString(t, messages[0].To[0], "a@example.com")
+	test.IsEqualString(t, messages[1].To[0], "b@example.com")
+	body := decodeBody(t, messages[0])
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "Subject: Sender shared the file report.pdf with you\r\n"), true)
+	test.IsEqualBool(t, strings.Contains(body, "Sender shared the file \"report.pdf\" (3 MB) with you."), true)
+	test.IsEqualBool(t, strings.Contains(body, "Please review"), true)
+	test.IsEqualBool(t, strings.Contains(body, "http://gokapi.local/d?id=mailfile"), true)
+	test.IsEqualBool(t, strings.Contains(body, "available until 2030-01-02 03:04"), true)
+	test.IsEqualBool(t, strings.Contains(body, "password"), false)
+	test.IsEqualBool(t, strings.Contains(body, "Gokapi Test\r\nhttp://gokapi.local/"), true)
+
+	protectedFile := testFile
+	protectedFile.PasswordHash = "hash"
+	protectedFile.UnlimitedTime = true
+	err = SendDownloadLink(protectedFile, models.User{Name: "Sender"}, []string{"a@example.com"}, "")
+	test.IsNil(t, err)
+	body = decodeBody(t, waitForMessage(t, server, 3))
+	test.IsEqualBool(t, strings.Contains(body, "available until"), false)
+	test.IsEqualBool(t, strings.Contains(body, "protected with a password"), true)
+}
+
+func TestNotifications(t *testing.T) {
+	database.SaveUser(models.User{Id: 20, Name: "owner@example.com", UserLevel: models.UserLevelUser}, false)
+	database.SaveUser(models.User{Id: 21, Name: "owner", UserLevel: models.UserLevelUser}, false)
+	defer database.DeleteUser(20)
+	defer database.DeleteUser(21)
+	owner, ok := database.GetUser(20)
+	test.IsEqualBool(t, ok, true)
+
+	NotifyDownload(testFile)
+	NotifyExpiry(testFile)
+	NotifyFileRequestUpload(testFile, models.FileRequest{Name: "Invoices"}, owner)
+
+	server := startServer(t, "filerequest.upload,file.download,file.expire")
+	NotifyDownload(testFile)
+	msg := waitForMessage(t, server, 1)
+	test.IsEqualString(t, msg.To[0], "owner@example.com")
+	test.IsEqualBool(t, strings.Contains(msg.Data, "Subject: Your file report.pdf has been downloaded\r\n"), true)
+
+	NotifyExpiry(testFile)
+	msg = waitForMessage(t, server, 2)
+	test.IsEqualBool(t, strings.Contains(decodeBody(t, msg), "\"report.pdf\" has expired"), true)
+
+	NotifyFileRequestUpload(testFile, models.FileRequest{Name: "Invoices"}, owner)
+	msg = waitForMessage(t, server, 3)
+	body := decodeBody(t, msg)
+	test.IsEqualBool(t, strings.Contains(body, "uploaded to your file request \"Invoices\""), true)
+	test.IsEqualBool(t, strings.Contains(body, "http://gokapi.local/filerequests"), true)
+
+	// Files of file requests, users without a valid email address and unknown users are ignored
+	requestFile := testFile
+	requestFile.UploadRequestId = "request"
+	NotifyDownload(requestFile)
+	otherFile := testFile
+	otherFile.UserId = 21
+	NotifyDownload(otherFile)
+	NotifyExpiry(otherFile)
+	otherFile.UserId = 5000
+	NotifyExpiry(otherFile)
+	time.Sleep(100 * time.Millisecond)
+	test.IsEqualInt(t, len(server.Messages()), 3)
+
+	server2 := startServer(t, "file.expire")
+	NotifyDownload(testFile)
+	NotifyFileRequestUpload(testFile, models.FileRequest{Name: "Invoices"}, owner)
+	NotifyExpiry(testFile)
+	waitForMessage(t, server2, 1)
+	time.Sleep(100 * time.Millisecond)
+	test.IsEqualInt(t, len(server2.Messages()), 1)
+}
+
+func TestSendPasswordReset(t *testing.T) {
+	user := models.User{Id: 20, Name: "owner@example.com"}
+	test.IsEqualBool(t, SendPasswordReset(user, "http://gokapi.local/forgotpw?token=abc"), false)
+	server := startServer(t, "")
+	test.IsEqualBool(t, SendPasswordReset(models.User{Name: "admin"}, "http://gokapi.local/forgotpw?token=abc"), false)
+	test.IsEqualBool(t, SendPasswordReset(user, "http://gokapi.local/forgotpw?token=abc"), true)
+	msg := waitForMessage(t, server, 1)
+	test.IsEqualString(t, msg.To[0], "owner@example.com")
+	test.IsEqualBool(t, strings.Contains(msg.Data, "Subject: Reset your password for Gokapi Test\r\n"), true)
+	test.IsEqualBool(t, strings.Contains(decodeBody(t, msg), "http://gokapi.local/forgotpw?token=abc"), true)
+}
@@ -0,0 +1,115 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forceu/gokapi/internal/helper"
+)
+
+// smtpTimeout is the maximum time for sending a single email
+const smtpTimeout = 30 * time.Second
+
+// send delivers a single email to the SMTP server
+func send(cfg smtpConfig, msg message) error {
+	address := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	var err error
+	if cfg.TlsMode == TlsModeImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, cfg.TlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	err = conn.SetDeadline(time.Now().Add(smtpTimeout))
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.TlsMode == TlsModeStartTls {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("the server does not support STARTTLS")
+		}
+		err = client.StartTLS(cfg.TlsConfig)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host))
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(cfg.From.Address)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(msg.To)
+	if err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(buildMessage(cfg.From.String(), msg, time.Now()))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage returns the headers and the quoted-printable encoded body of a plain text email
+func buildMessage(from string, msg message, date time.Time) []byte {
+	var result bytes.Buffer
+	domain := "gokapi"
+	if index := strings.LastIndex(from, "@"); index != -1 {
+		domain = strings.Trim(from[index+1:], ">")
+	}
+	writeHeader(&result, "From", from)
+	writeHeader(&result, "To", msg.To)
+	writeHeader(&result, "Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader(&result, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&result, "Message-ID", "<"+helper.GenerateRandomString(30)+"@"+domain+">")
+	writeHeader(&result, "Auto-Submitted", "auto-generated")
+	writeHeader(&result, "MIME-Version", "1.0")
+	writeHeader(&result, "Content-Type", "text/plain; charset=utf-8")
+	writeHeader(&result, "Content-Transfer-Encoding", "quoted-printable")
+	result.WriteString("\r\n")
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	encoder := quotedprintable.NewWriter(&result)
+	_, err := encoder.Write([]byte(body))
+	helper.Check(err)
+	helper.Check(encoder.Close())
+	return result.Bytes()
+}
+
+// writeHeader adds a header line. Line breaks are removed from the value, so that no additional
+// headers can be injected
+func writeHeader(buffer *bytes.Buffer, name, value string) {
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	buffer.WriteString(name + ": " + value + "\r\n")
+}
@@ -0,0 +1,101 @@
+package mail
+
+import (
+	"crypto/tls"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/smtpstub"
+)
+
+func newTestConfig(t *testing.T, server *smtpstub.Server, tlsMode string) smtpConfig {
+	from, err := netmail.ParseAddress("Gokapi <gokapi@example.com>")
+	test.IsNil(t, err)
+	return smtpConfig{
+		Host:      server.Host(),
+		Port:      server.Port(),
+		TlsMode:   tlsMode,
+		From:      from,
+		TlsConfig: &tls.Config{ServerName: server.Host(), RootCAs: server.CertPool(), MinVersion: tls.VersionTLS12},
+	}
+}
+
+func TestSend(t *testing.T) {
+	server, err := smtpstub.Start(smtpstub.Config{})
+	test.IsNil(t, err)
+	defer server.Close()
+	cfg := newTestConfig(t, server, TlsModeNone)
+	err = send(cfg, message{To: "user@example.com", Subject: "Test subject", Body: "Test body\n.\nEnd"})
+	test.IsNil(t, err)
+	messages := server.Messages()
+	test.IsEqualInt(t, len(messages), 1)
+	test.IsEqualString(t, messages[0].From, "gokapi@example.com")
+	test.IsEqualInt(t, len(messages[0].To), 1)
+	test.IsEqualString(t, messages[0].To[0], "user@example.com")
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "Subject: Test subject\r\n"), true)
+	test.IsEqualBool(t, strings.HasSuffix(messages[0].Data, "Test body\r\n.\r\nEnd\r\n"), true)
+
+	err = send(newTestConfig(t, server, TlsModeStartTls), message{To: "user@example.com"})
+	test.IsNotNil(t, err)
+	test.IsEqualString(t, err.Error(), "the server does not support STARTTLS")
+
+	cfg.Port = 1
+	err = send(cfg, message{To: "user@example.com"})
+	test.IsNotNil(t, err)
+	test.IsEqualInt(t, len(server.Messages()), 1)
+}
+
+func TestSendStartTls(t *testing.T) {
+	server, err := smtpstub.Start(smtpstub.Config{StartTls: true, Username: "user", Password: "secret"})
+	test.IsNil(t, err)
+	defer server.Close()
+	cfg := newTestConfig(t, server, TlsModeStartTls)
+	err = send(cfg, message{To: "user@example.com", Subject: "Test", Body: "Test"})
+	test.IsNotNil(t, err)
+
+	cfg.Username = "user"
+	cfg.Password = "invalid"
+	err = send(cfg, message{To: "user@example.com", Subject: "Test", Body: "Test"})
+	test.IsNotNil(t, err)
+
+	cfg.Password = "secret"
+	err = send(cfg, message{To: "user@example.com", Subject: "Test", Body: "Test"})
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(server.Messages()), 1)
+
+	cfg.TlsConfig = &tls.Config{ServerName: server.Host(), MinVersion: tls.VersionTLS12}
+	err = send(cfg, message{To: "user@example.com", Subject: "Test", Body: "Test"})
+	test.IsNotNil(t, err)
+	test.IsEqualInt(t, len(server.Messages()), 1)
+}
+
+func TestSendImplicitTls(t *testing.T) {
+	server, err := smtpstub.Start(smtpstub.Config{ImplicitTls: true})
+	test.IsNil(t, err)
+	defer server.Close()
+	err = send(newTestConfig(t, server, TlsModeImplicit), message{To: "user@example.com", Subject: "Test", Body: "Test"})
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(server.Messages()), 1)
+}
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	result := string(buildMessage("Gokapi <gokapi@example.com>", message{
+		To:      "user@example.com\r\nBcc: other@example.com",
+		Subject: "Tëst\nBcc: other@example.com",
+		Body:    "Line 1\nLine 2 with ümlaut",
+	}, date))
+	headers, body, found := strings.Cut(result, "\r\n\r\n")
+	test.IsEqualBool(t, found, true)
+	test.IsEqualBool(t, strings.Contains(headers, "\r\nBcc:"), false)
+	test.IsEqualBool(t, strings.HasPrefix(headers, "From: Gokapi <gokapi@example.com>\r\n"), true)
+	test.IsEqualBool(t, strings.Contains(headers, "To: user@example.comBcc: other@example.com\r\n"), true)
+	test.IsEqualBool(t, strings.Contains(headers, "Subject: =?utf-8?q?"), true)
+	test.IsEqualBool(t, strings.Contains(headers, "Date: Wed, 01 May 2024 12:00:00 +0000\r\n"), true)
+	test.IsEqualBool(t, strings.Contains(headers, "@example.com>\r\n"), true)
+	test.IsEqualBool(t, strings.Contains(headers, "Content-Transfer-Encoding: quoted-printable"), true)
+	test.IsEqualString(t, body, "Line 1\r\nLine 2 with =C3=BCmlaut")
+}
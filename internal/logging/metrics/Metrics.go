@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// uploadProcessingBuckets are the upper bounds in seconds of the upload processing histogram
+var uploadProcessingBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+var uploads atomic.Uint64
+var downloads atomic.Uint64
+var bytesServed atomic.Uint64
+var failedLogins atomic.Uint64
+var chunkReservations atomic.Uint64
+var cleanupRuns atomic.Uint64
+var sseListeners atomic.Int64
+
+var rateLimiterWaits = make(map[string]uint64)
+var rateLimiterMutex sync.Mutex
+
+var uploadProcessing = newHistogram(uploadProcessingBuckets)
+
+type histogram struct {
+	mutex   sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(value float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, bucket := range h.buckets {
+		if value <= bucket {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum = h.sum + value
+}
+
+// IncreaseUploads increases the counter of finished uploads by one
+func IncreaseUploads() {
+	uploads.Add(1)
+}
+
+// IncreaseDownloads increases the counter of downloads by one
+func IncreaseDownloads() {
+	downloads.Add(1)
+}
+
+// AddBytesServed adds the size of a served file to the counter of served bytes
+func AddBytesServed(bytes uint64) {
+	bytesServed.Add(bytes)
+}
+
+// IncreaseFailedLogins increases the counter of failed logins by one
+func IncreaseFailedLogins() {
+	failedLogins.Add(1)
+}
+
+// IncreaseChunkReservations increases the counter of chunk reservations for file requests by one
+func IncreaseChunkReservations() {
+	chunkReservations.Add(1)
+}
+
+// IncreaseCleanupRuns increases the counter of cleanup runs by one
+func IncreaseCleanupRuns() {
+	cleanupRuns.Add(1)
+}
+
+// IncreaseRateLimiterWaits increases the counter of requests that were delayed by the given rate limiter
+func IncreaseRateLimiterWaits(limiter string) {
+	rateLimiterMutex.Lock()
+	rateLimiterWaits[limiter]++
+	rateLimiterMutex.Unlock()
+}
+
+// AddSseListener increases the number of active SSE listeners by one
+func AddSseListener() {
+	sseListeners.Add(1)
+}
+
+// RemoveSseListener decreases the number of active SSE listeners by one
+func RemoveSseListener() {
+	sseListeners.Add(-1)
+}
+
+// ObserveUploadProcessing records how long it took to process an uploaded file
+func ObserveUploadProcessing(duration time.Duration) {
+	uploadProcessing.observe(duration.Seconds())
+}
+
+// Write outputs all counters and histograms in the Prometheus text format
+func Write(w io.Writer) {
+	WriteCounter(w, "gokapi_uploads_total", "Number of finished uploads", float64(uploads.Load()))
+	WriteCounter(w, "gokapi_downloads_total", "Number of downloads", float64(downloads.Load()))
+	WriteCounter(w, "gokapi_served_bytes_total", "Size of all served files in bytes", float64(bytesServed.Load()))
+	WriteCounter(w, "gokapi_failed_logins_total", "Number of failed login attempts", float64(failedLogins.Load()))
+	writeRateLimiterWaits(w)
+	WriteGauge(w, "gokapi_sse_listeners", "Number of active SSE listeners", float64(sseListeners.Load()))
+	WriteCounter(w, "gokapi_chunk_reservations_total", "Number of chunk reservations for file requests", float64(chunkReservations.Load()))
+	WriteCounter(w, "gokapi_cleanup_runs_total", "Number of cleanup runs", float64(cleanupRuns.Load()))
+	writeHistogram(w, "gokapi_upload_processing_seconds", "Time it took to process an uploaded file in seconds", uploadProcessing)
+}
+
+// WriteGauge outputs a single gauge in the Prometheus text format
+func WriteGauge(w io.Writer, name, help string, value float64) {
+	writeHeader(w, name, help, "gauge")
+	_, _ = fmt.Fprintf(w, "%s %s\n", name, formatValue(value))
+}
+
+// WriteCounter outputs a single counter in the Prometheus text format
+func WriteCounter(w io.Writer, name, help string, value float64) {
+	writeHeader(w, name, help, "counter")
+	_, _ = fmt.Fprintf(w, "%s %s\n", name, formatValue(value))
+}
+
+func writeRateLimiterWaits(w io.Writer) {
+	const name = "gokapi_ratelimiter_waits_total"
+	writeHeader(w, name, "Number of requests that were delayed by a rate limiter", "counter")
+	rateLimiterMutex.Lock()
+	defer rateLimiterMutex.Unlock()
+	limiters := make([]string, 0, len(rateLimiterWaits))
+	for limiter := range rateLimiterWaits {
+		limiters = append(limiters, limiter)
+	}
+	sort.Strings(limiters)
+	for _, limiter := range limiters {
+		_, _ = fmt.Fprintf(w, "%s{limiter=%q} %d\n", name, limiter, rateLimiterWaits[limiter])
+	}
+}
+
+func writeHistogram(w io.Writer, name, help string, h *histogram) {
+	writeHeader(w, name, help, "histogram")
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, bucket := range h.buckets {
+		_, _ = fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, formatValue(bucket), h.counts[i])
+	}
+	_, _ = fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	_, _ = fmt.Fprintf(w, "%s_sum %s\n", name, formatValue(h.sum))
+	_, _ = fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+func writeHeader(w io.Writer, name, help, metricType string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
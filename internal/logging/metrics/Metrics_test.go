@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func getOutput() string {
+	var buf bytes.Buffer
+	Write(&buf)
+	return buf.String()
+}
+
+func TestCounters(t *testing.T) {
+	output := getOutput()
+	test.IsEqualBool(t, strings.Contains(output, "# TYPE gokapi_uploads_total counter\ngokapi_uploads_total 0\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "gokapi_ratelimiter_waits_total{"), false)
+
+	IncreaseUploads()
+	IncreaseUploads()
+	IncreaseDownloads()
+	AddBytesServed(1024)
+	AddBytesServed(2048)
+	IncreaseFailedLogins()
+	IncreaseChunkReservations()
+	IncreaseCleanupRuns()
+	IncreaseRateLimiterWaits("login")
+	IncreaseRateLimiterWaits("login")
+	IncreaseRateLimiterWaits("api")
+	AddSseListener()
+	AddSseListener()
+	RemoveSseListener()
+
+	output = getOutput()
+	test.IsEqualBool(t, strings.Contains(output, "\ngokapi_uploads_total 2\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "\ngokapi_downloads_total 1\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "\ngokapi_served_bytes_total 3072\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "\ngokapi_failed_logins_total 1\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "\ngokapi_chunk_reservations_total 1\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "\ngokapi_cleanup_runs_total 1\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "# TYPE gokapi_sse_listeners gauge\ngokapi_sse_listeners 1\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "gokapi_ratelimiter_waits_total{limiter=\"api\"} 1\n"+
+		"gokapi_ratelimiter_waits_total{limiter=\"login\"} 2\n"), true)
+}
+
+func TestHistogram(t *testing.T) {
+	ObserveUploadProcessing(200 * time.Millisecond)
+	ObserveUploadProcessing(3 * time.Second)
+	ObserveUploadProcessing(time.Hour)
+	output := getOutput()
+	test.IsEqualBool(t, strings.Contains(output, "# TYPE gokapi_upload_processing_seconds histogram\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "gokapi_upload_processing_seconds_bucket{le=\"0.1\"} 0\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "gokapi_upload_processing_seconds_bucket{le=\"0.25\"} 1\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "gokapi_upload_processing_seconds_bucket{le=\"5\"} 2\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "gokapi_upload_processing_seconds_bucket{le=\"300\"} 2\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "gokapi_upload_processing_seconds_bucket{le=\"+Inf\"} 3\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "gokapi_upload_processing_seconds_sum 3603.2\n"), true)
+	test.IsEqualBool(t, strings.Contains(output, "gokapi_upload_processing_seconds_count 3\n"), true)
+}
+
+func TestWriteGauge(t *testing.T) {
+	var buf bytes.Buffer
+	WriteGauge(&buf, "test_gauge", "Test value", 12.5)
+	test.IsEqualString(t, buf.String(), "# HELP test_gauge Test value\n# TYPE test_gauge gauge\ntest_gauge 12.5\n")
+}
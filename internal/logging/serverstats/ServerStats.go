@@ -2,12 +2,14 @@ package serverstats
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"sync"
 	"time"
 
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/logging/metrics"
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/mem"
@@ -133,3 +135,23 @@ func AddTraffic(bytes uint64) {
 		saveTraffic()
 	}
 }
+
+// WriteMetrics outputs the server statistics and all metrics in the Prometheus text format
+func WriteMetrics(w io.Writer) {
+	totalTraffic, trafficSince := GetCurrentTraffic()
+	memFree, memUsed, memTotal, _ := GetMemoryInfo()
+	diskFree, diskUsed, diskTotal, _ := GetDiskInfo()
+
+	metrics.WriteGauge(w, "gokapi_uptime_seconds", "Uptime of the server in seconds", float64(GetUptime()))
+	metrics.WriteGauge(w, "gokapi_traffic_bytes", "Traffic in bytes since the start of the recording", float64(totalTraffic))
+	metrics.WriteGauge(w, "gokapi_traffic_recording_since_seconds", "Unix timestamp of the start of the traffic recording", float64(trafficSince))
+	metrics.WriteGauge(w, "gokapi_cpu_usage_percent", "CPU usage in percent", float64(GetCpuUsage()))
+	metrics.WriteGauge(w, "gokapi_memory_free_bytes", "Free memory in bytes", float64(memFree))
+	metrics.WriteGauge(w, "gokapi_memory_used_bytes", "Used memory in bytes", float64(memUsed))
+	metrics.WriteGauge(w, "gokapi_memory_total_bytes", "Total memory in bytes", float64(memTotal))
+	metrics.WriteGauge(w, "gokapi_disk_free_bytes", "Free disk space of the data directory in bytes", float64(diskFree))
+	metrics.WriteGauge(w, "gokapi_disk_used_bytes", "Used disk space of the data directory in bytes", float64(diskUsed))
+	metrics.WriteGauge(w, "gokapi_disk_total_bytes", "Total disk space of the data directory in bytes", float64(diskTotal))
+	metrics.WriteGauge(w, "gokapi_files", "Number of files stored in the database", float64(GetTotalFiles()))
+	metrics.Write(w)
+}
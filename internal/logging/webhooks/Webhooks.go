@@ -0,0 +1,319 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/database/leader"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// HeaderSignature contains the HMAC-SHA256 signature of the request body, in the format sha256=<hex>
+const HeaderSignature = "X-Gokapi-Signature"
+
+// HeaderEvent contains the name of the event
+const HeaderEvent = "X-Gokapi-Event"
+
+// HeaderDelivery contains the ID of the delivery. It stays the same for retries
+const HeaderDelivery = "X-Gokapi-Delivery"
+
+// maxAttempts is the number of failed deliveries after which a delivery is discarded
+const maxAttempts = 10
+
+// maxBackoff is the maximum time between two attempts
+const maxBackoff = 6 * time.Hour
+
+// baseBackoff is the time before the first retry, it is doubled after every failed attempt
+const baseBackoff = 30 * time.Second
+
+// pollInterval is the interval in which the queue is checked for due deliveries
+const pollInterval = 15 * time.Second
+
+// deliveryBatchSize is the maximum number of deliveries that are processed in one run
+const deliveryBatchSize = 50
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+var isInitialised bool
+var serverUrl string
+var includeFilename bool
+var cachedWebhooks []models.Webhook
+var mutex sync.RWMutex
+var queueMutex sync.Mutex
+var wakeUp chan bool
+var stop chan bool
+
+// Event contains the information about an event that is sent to all subscribed webhooks.
+// All fields except Name are optional
+type Event struct {
+	Name         string
+	File         *models.File
+	SourceFile   *models.File
+	FileRequest  *models.FileRequest
+	User         *models.User
+	ModifiedUser *models.User
+	ShareLinkId  string
+	Ip           string
+	UserAgent    string
+}
+
+type payload struct {
+	Id           string                `json:"id"`
+	Event        string                `json:"event"`
+	Timestamp    int64                 `json:"timestamp"`
+	File         *models.FileApiOutput `json:"file,omitempty"`
+	SourceFile   *models.FileApiOutput `json:"sourceFile,omitempty"`
+	FileRequest  *fileRequestInfo      `json:"fileRequest,omitempty"`
+	User         *userInfo             `json:"user,omitempty"`
+	ModifiedUser *userInfo             `json:"modifiedUser,omitempty"`
+	ShareLinkId  string                `json:"shareLinkId,omitempty"`
+	Ip           string                `json:"ip,omitempty"`
+	UserAgent    string                `json:"userAgent,omitempty"`
+}
+
+type fileRequestInfo struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	UserId int    `json:"userId"`
+}
+
+type userInfo struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	UserLevel string `json:"userLevel"`
+}
+
+// Init loads all webhooks from the database and starts processing the delivery queue.
+// Until Init is called, events are discarded
+func Init(url string, useFilenameInUrl bool) {
+	mutex.Lock()
+	if isInitialised {
+		mutex.Unlock()
+		return
+	}
+	serverUrl = url
+	includeFilename = useFilenameInUrl
+	isInitialised = true
+	wakeUp = make(chan bool, 1)
+	stop = make(chan bool)
+	mutex.Unlock()
+	Reload()
+	go processQueueLoop(wakeUp, stop)
+}
+
+// Shutdown stops processing the delivery queue. Pending deliveries are kept in the database
+func Shutdown() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !isInitialised {
+		return
+	}
+	isInitialised = false
+	close(stop)
+}
+
+// Reload reads all webhooks from the database. Needs to be called after a webhook was modified.
+// Other instances reload the webhooks every time they check the queue
+func Reload() {
+	webhooks := database.GetAllWebhooks()
+	mutex.Lock()
+	cachedWebhooks = webhooks
+	mutex.Unlock()
+}
+
+// Trigger queues the event for all webhooks that are subscribed to it
+func Trigger(event Event) {
+	mutex.RLock()
+	if !isInitialised {
+		mutex.RUnlock()
+		return
+	}
+	wake := wakeUp
+	var targets []models.Webhook
+	for _, webhook := range cachedWebhooks {
+		if webhook.IsSubscribed(event.Name) {
+			targets = append(targets, webhook)
+		}
+	}
+	mutex.RUnlock()
+	if len(targets) == 0 {
+		return
+	}
+	for _, webhook := range targets {
+		deliveryId := helper.GenerateRandomString(20)
+		database.SaveWebhookDelivery(models.WebhookDelivery{
+			Id:          deliveryId,
+			WebhookId:   webhook.Id,
+			Event:       event.Name,
+			Payload:     createPayload(deliveryId, event),
+			NextAttempt: time.Now().Unix(),
+		})
+	}
+	select {
+	case wake <- true:
+	default:
+	}
+}
+
+func createPayload(deliveryId string, event Event) string {
+	result := payload{
+		Id:          deliveryId,
+		Event:       event.Name,
+		Timestamp:   time.Now().Unix(),
+		ShareLinkId: event.ShareLinkId,
+		Ip:          event.Ip,
+		UserAgent:   event.UserAgent,
+	}
+	mutex.RLock()
+	url := serverUrl
+	useFilename := includeFilename
+	mutex.RUnlock()
+	if event.File != nil {
+		output, err := event.File.ToFileApiOutput(url, useFilename)
+		helper.Check(err)
+		result.File = &output
+	}
+	if event.SourceFile != nil {
+		output, err := event.SourceFile.ToFileApiOutput(url, useFilename)
+		helper.Check(err)
+		result.SourceFile = &output
+	}
+	if event.FileRequest != nil && event.FileRequest.Id != "" {
+		result.FileRequest = &fileRequestInfo{
+			Id:     event.FileRequest.Id,
+			Name:   event.FileRequest.Name,
+			UserId: event.FileRequest.UserId,
+		}
+	}
+	result.User = toUserInfo(event.User)
+	result.ModifiedUser = toUserInfo(event.ModifiedUser)
+	output, err := json.Marshal(result)
+	helper.Check(err)
+	return string(output)
+}
+
+func toUserInfo(user *models.User) *userInfo {
+	if user == nil {
+		return nil
+	}
+	return &userInfo{
+		Id:        user.Id,
+		Name:      user.Name,
+		UserLevel: user.GetReadableUserLevel(),
+	}
+}
+
+func processQueueLoop(wakeUp, stop chan bool) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-wakeUp:
+		case <-ticker.C:
+			// The webhooks might have been modified by another instance
+			Reload()
+		}
+		processQueue()
+	}
+}
+
+// processQueue sends all deliveries that are due. If multiple instances share the database, only
+// the instance that runs the periodic cleanup sends the deliveries, so that they are not sent more than once
+func processQueue() {
+	if !leader.IsLeader() {
+		return
+	}
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	for {
+		deliveries := database.GetDueWebhookDeliveries(time.Now().Unix(), deliveryBatchSize)
+		for _, delivery := range deliveries {
+			processDelivery(delivery)
+		}
+		if len(deliveries) < deliveryBatchSize {
+			return
+		}
+	}
+}
+
+func processDelivery(delivery models.WebhookDelivery) {
+	// The webhook is read from the database, as it might have been modified by another instance
+	webhook, ok := database.GetWebhook(delivery.WebhookId)
+	if !ok {
+		database.DeleteWebhookDelivery(delivery.Id)
+		return
+	}
+	err := send(webhook, delivery)
+	if err == nil {
+		database.DeleteWebhookDelivery(delivery.Id)
+		return
+	}
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= maxAttempts {
+		fmt.Printf("Webhook %s: Discarding delivery %s for event %s after %d failed attempts. Last error: %s\n",
+			webhook.Url, delivery.Id, delivery.Event, delivery.Attempts, delivery.LastError)
+		database.DeleteWebhookDelivery(delivery.Id)
+		return
+	}
+	delivery.NextAttempt = time.Now().Add(getBackoff(delivery.Attempts)).Unix()
+	database.SaveWebhookDelivery(delivery)
+}
+
+// getBackoff returns the time to wait before the next attempt, after the given number of failed attempts
+func getBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return baseBackoff
+	}
+	backoff := baseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff = backoff * 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
+
+// Sign returns the value of the signature header for the given body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func send(webhook models.Webhook, delivery models.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Gokapi-Webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.Id)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, body))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("unexpected status code " + resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/database/leader"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+)
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	config, err := database.ParseUrl(testconfiguration.SqliteUrl, false)
+	if err != nil {
+		panic(err)
+	}
+	database.Connect(config)
+	exitVal := m.Run()
+	database.Close()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+type receivedRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+type testReceiver struct {
+	sync.Mutex
+	Server     *httptest.Server
+	Requests   []receivedRequest
+	StatusCode int
+}
+
+func newTestReceiver() *testReceiver {
+	receiver := &testReceiver{StatusCode: http.StatusOK}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.Lock()
+		receiver.Requests = append(receiver.Requests, receivedRequest{Header: r.Header, Body: body})
+		status := receiver.StatusCode
+		receiver.Unlock()
+		w.WriteHeader(status)
+	}))
+	return receiver
+}
+
+func (r *testReceiver) getRequests() []receivedRequest {
+	r.Lock()
+	defer r.Unlock()
+	return r.Requests
+}
+
+func (r *testReceiver) setStatusCode(code int) {
+	r.Lock()
+	r.StatusCode = code
+	r.Unlock()
+}
+
+func TestTriggerNotInitialised(t *testing.T) {
+	receiver := newTestReceiver()
+	defer receiver.Server.Close()
+	database.SaveWebhook(models.Webhook{Id: "notinit", Url: receiver.Server.URL, Secret: "secret", Events: models.WebhookEventUpload})
+	defer database.DeleteWebhook("notinit")
+
+	Trigger(Event{Name: models.WebhookEventUpload, File: &models.File{Id: "test"}})
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Unix()+1, 10)), 0)
+	test.IsEqualInt(t, len(receiver.getRequests()), 0)
+}
+
+func TestDelivery(t *testing.T) {
+	receiver := newTestReceiver()
+	defer receiver.Server.Close()
+	database.SaveWebhook(models.Webhook{Id: "hook1", Url: receiver.Server.URL, Secret: "secret1", Events: "file.upload,file.delete"})
+	database.SaveWebhook(models.Webhook{Id: "hook2", Url: receiver.Server.URL, Secret: "secret2", Events: "user.create"})
+	Init("http://gokapi.local/", false)
+	defer Shutdown()
+
+	file := models.File{Id: "file1", Name: "test.txt", Size: "3 B", SizeBytes: 3, UserId: 5, UnlimitedTime: true, UnlimitedDownloads: true}
+	user := models.User{Id: 5, Name: "Uploader", UserLevel: models.UserLevelUser}
+	Trigger(Event{Name: models.WebhookEventDownload, File: &file})
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Unix()+1, 10)), 0)
+
+	Trigger(Event{Name: models.WebhookEventUpload, File: &file, User: &user})
+	waitForRequests(t, receiver, 1)
+	requests := receiver.getRequests()
+	test.IsEqualInt(t, len(requests), 1)
+	request := requests[0]
+	test.IsEqualString(t, request.Header.Get(HeaderEvent), models.WebhookEventUpload)
+	test.IsEqualString(t, request.Header.Get(HeaderSignature), Sign("secret1", request.Body))
+	test.IsEqualString(t, request.Header.Get("Content-Type"), "application/json")
+
+	var result payload
+	err := json.Unmarshal(request.Body, &result)
+	test.IsNil(t, err)
+	test.IsEqualString(t, result.Id, request.Header.Get(HeaderDelivery))
+	test.IsEqualString(t, result.Event, models.WebhookEventUpload)
+	test.IsEqualString(t, result.File.Id, "file1")
+	test.IsEqualString(t, result.File.UrlDownload, "http://gokapi.local/d?id=file1")
+	test.IsEqualInt(t, result.User.Id, 5)
+	test.IsEqualString(t, result.User.Name, "Uploader")
+	test.IsEqualBool(t, result.ModifiedUser == nil, true)
+	test.IsEqualBool(t, result.FileRequest == nil, true)
+	test.IsEqualString(t, result.Ip, "")
+	test.IsEqualString(t, result.ShareLinkId, "")
+	waitForEmptyQueue(t)
+
+	Trigger(Event{Name: models.WebhookEventUserCreate, User: &user, ModifiedUser: &models.User{Id: 6, Name: "New"}})
+	waitForRequests(t, receiver, 2)
+	request = receiver.getRequests()[1]
+	test.IsEqualString(t, request.Header.Get(HeaderSignature), Sign("secret2", request.Body))
+	err = json.Unmarshal(request.Body, &result)
+	test.IsNil(t, err)
+	test.IsEqualString(t, result.ModifiedUser.Name, "New")
+	waitForEmptyQueue(t)
+
+	database.DeleteWebhook("hook1")
+	database.DeleteWebhook("hook2")
+	Reload()
+	Trigger(Event{Name: models.WebhookEventUpload, File: &file})
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Unix()+1, 10)), 0)
+}
+
+func TestCreatePayloadShareLink(t *testing.T) {
+	file := models.File{Id: "file1", Name: "test.txt", UnlimitedTime: true, UnlimitedDownloads: true}
+	var result payload
+	err := json.Unmarshal([]byte(createPayload("delivery1", Event{Name: models.WebhookEventDownload, File: &file, ShareLinkId: "link1"})), &result)
+	test.IsNil(t, err)
+	test.IsEqualString(t, result.Id, "delivery1")
+	test.IsEqualString(t, result.File.Id, "file1")
+	test.IsEqualString(t, result.ShareLinkId, "link1")
+}
+
+func TestFailedDelivery(t *testing.T) {
+	receiver := newTestReceiver()
+	defer receiver.Server.Close()
+	receiver.setStatusCode(http.StatusInternalServerError)
+	database.SaveWebhook(models.Webhook{Id: "failing", Url: receiver.Server.URL, Secret: "secret", Events: models.WebhookEventDelete})
+	defer database.DeleteWebhook("failing")
+	Reload()
+
+	delivery := models.WebhookDelivery{Id: "failingDelivery", WebhookId: "failing", Event: models.WebhookEventDelete, Payload: "{}", NextAttempt: time.Now().Unix()}
+	processDelivery(delivery)
+	test.IsEqualInt(t, len(receiver.getRequests()), 1)
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Unix(), 10)), 0)
+	deliveries := database.GetDueWebhookDeliveries(time.Now().Add(baseBackoff).Unix(), 10)
+	test.IsEqualInt(t, len(deliveries), 1)
+	test.IsEqualInt(t, deliveries[0].Attempts, 1)
+	test.IsEqualString(t, deliveries[0].LastError, "unexpected status code 500 Internal Server Error")
+
+	delivery = deliveries[0]
+	delivery.Attempts = maxAttempts - 1
+	processDelivery(delivery)
+	test.IsEqualInt(t, len(receiver.getRequests()), 2)
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Add(maxBackoff).Unix(), 10)), 0)
+
+	receiver.setStatusCode(http.StatusNoContent)
+	delivery.Attempts = 3
+	processDelivery(delivery)
+	test.IsEqualInt(t, len(receiver.getRequests()), 3)
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Add(maxBackoff).Unix(), 10)), 0)
+
+	delivery.WebhookId = "invalid"
+	database.SaveWebhookDelivery(delivery)
+	processDelivery(delivery)
+	test.IsEqualInt(t, len(receiver.getRequests()), 3)
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Add(maxBackoff).Unix(), 10)), 0)
+}
+
+func TestDeliveryWithoutCache(t *testing.T) {
+	receiver := newTestReceiver()
+	defer receiver.Server.Close()
+	mutex.Lock()
+	cachedWebhooks = nil
+	mutex.Unlock()
+	// The webhook has been added or modified by another instance
+	database.SaveWebhook(models.Webhook{Id: "uncached", Url: receiver.Server.URL, Secret: "newsecret", Events: models.WebhookEventDelete})
+	defer database.DeleteWebhook("uncached")
+
+	delivery := models.WebhookDelivery{Id: "uncachedDelivery", WebhookId: "uncached", Event: models.WebhookEventDelete, Payload: "{}", NextAttempt: time.Now().Unix()}
+	database.SaveWebhookDelivery(delivery)
+	processDelivery(delivery)
+	requests := receiver.getRequests()
+	test.IsEqualInt(t, len(requests), 1)
+	test.IsEqualString(t, requests[0].Header.Get(HeaderSignature), Sign("newsecret", requests[0].Body))
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Unix()+1, 10)), 0)
+}
+
+func TestQueueOnlyProcessedByLeader(t *testing.T) {
+	receiver := newTestReceiver()
+	defer receiver.Server.Close()
+	database.SaveWebhook(models.Webhook{Id: "leaderhook", Url: receiver.Server.URL, Secret: "secret", Events: models.WebhookEventDelete})
+	defer database.DeleteWebhook("leaderhook")
+	database.SaveWebhookDelivery(models.WebhookDelivery{Id: "leaderDelivery", WebhookId: "leaderhook", Event: models.WebhookEventDelete, Payload: "{}", NextAttempt: time.Now().Unix()})
+
+	// Another instance holds the lease
+	test.IsEqualBool(t, database.TryAcquireLease(models.Lease{Name: leader.LeaseCleanup, Owner: "otherinstance", ExpiresAt: time.Now().Add(time.Hour).Unix()}), true)
+	leader.Init()
+	processQueue()
+	test.IsEqualInt(t, len(receiver.getRequests()), 0)
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Unix()+1, 10)), 1)
+	leader.Shutdown()
+	database.ReleaseLease(leader.LeaseCleanup, "otherinstance")
+
+	processQueue()
+	test.IsEqualInt(t, len(receiver.getRequests()), 1)
+	test.IsEqualInt(t, len(database.GetDueWebhookDeliveries(time.Now().Unix()+1, 10)), 0)
+}
+
+func TestGetBackoff(t *testing.T) {
+	test.IsEqualInt64(t, int64(getBackoff(0)), int64(baseBackoff))
+	test.IsEqualInt64(t, int64(getBackoff(1)), int64(baseBackoff))
+	test.IsEqualInt64(t, int64(getBackoff(2)), int64(2*baseBackoff))
+	test.IsEqualInt64(t, int64(getBackoff(4)), int64(8*baseBackoff))
+	test.IsEqualInt64(t, int64(getBackoff(10)), int64(512*baseBackoff))
+	test.IsEqualInt64(t, int64(getBackoff(100)), int64(maxBackoff))
+}
+
+func TestSign(t *testing.T) {
+	test.IsEqualString(t, Sign("secret", []byte("body")), "sha256=dc46983557fea127b43af721467eb9b3fde2338fe3e14f51952aa8478c13d355")
+}
+
+func waitForRequests(t *testing.T, receiver *testReceiver, count int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if len(receiver.getRequests()) >= count {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Webhook was not called %d times", count)
+}
+
+func waitForEmptyQueue(t *testing.T) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if len(database.GetDueWebhookDeliveries(time.Now().Unix()+1, 10)) == 0 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("Webhook queue was not processed")
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// AccessRestriction limits the access to a file or a file request to authenticated users
+type AccessRestriction struct {
+	RequireLogin  bool     // If true, only authenticated users are granted access
+	AllowedUsers  []string // If not empty, only these users are granted access
+	AllowedGroups []string // If not empty, only members of these groups are granted access. Wildcards are supported
+}
+
+// NewAccessRestriction creates an AccessRestriction from comma-separated lists of users and groups
+func NewAccessRestriction(requireLogin bool, users, groups string) AccessRestriction {
+	return AccessRestriction{
+		RequireLogin:  requireLogin,
+		AllowedUsers:  splitAccessList(users),
+		AllowedGroups: splitAccessList(groups),
+	}
+}
+
+// HasAllowList returns true if access is only granted to specific users or groups.
+// Otherwise, all authenticated users are granted access
+func (a *AccessRestriction) HasAllowList() bool {
+	return len(a.AllowedUsers) > 0 || len(a.AllowedGroups) > 0
+}
+
+// IsAllowedUser returns true if the username is part of AllowedUsers. The comparison is case-insensitive
+func (a *AccessRestriction) IsAllowedUser(username string) bool {
+	return slices.ContainsFunc(a.AllowedUsers, func(allowedUser string) bool {
+		return strings.EqualFold(allowedUser, username)
+	})
+}
+
+// CleanAccessList removes whitespace, empty entries and duplicates from a comma-separated list of users or groups
+func CleanAccessList(input string) string {
+	return strings.Join(splitAccessList(input), ",")
+}
+
+func splitAccessList(input string) []string {
+	result := make([]string, 0)
+	for _, entry := range strings.Split(input, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" && !slices.Contains(result, entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// Recipient is an authenticated user, who accesses a file or a file request that requires a login
+type Recipient struct {
+	Name   string   // The username. For OAuth, this is the email address
+	UserId int      // The ID of the user, if the recipient is a registered user. Otherwise 0
+	Groups []string // The groups sent by the OAuth provider or the authentication proxy
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestNewAccessRestriction(t *testing.T) {
+	restriction := NewAccessRestriction(true, " Alice , bob,,alice,Alice", "")
+	test.IsEqualBool(t, restriction.RequireLogin, true)
+	test.IsEqualString(t, strings.Join(restriction.AllowedUsers, ","), "Alice,bob,alice")
+	test.IsEqualInt(t, len(restriction.AllowedGroups), 0)
+	test.IsEqualBool(t, restriction.HasAllowList(), true)
+	test.IsEqualBool(t, restriction.IsAllowedUser("ALICE"), true)
+	test.IsEqualBool(t, restriction.IsAllowedUser("carol"), false)
+
+	restriction = NewAccessRestriction(true, "", "staff, sales")
+	test.IsEqualBool(t, restriction.HasAllowList(), true)
+	test.IsEqualString(t, strings.Join(restriction.AllowedGroups, ","), "staff,sales")
+	test.IsEqualBool(t, restriction.IsAllowedUser("alice"), false)
+
+	restriction = NewAccessRestriction(false, " , ", "")
+	test.IsEqualBool(t, restriction.RequireLogin, false)
+	test.IsEqualBool(t, restriction.HasAllowList(), false)
+
+	test.IsEqualString(t, CleanAccessList(" staff,, sales ,staff"), "staff,sales")
+	test.IsEqualString(t, CleanAccessList(""), "")
+
+	file := File{RequireLogin: true, AllowedUsers: "alice", AllowedGroups: "staff"}
+	restriction = file.GetAccessRestriction()
+	test.IsEqualBool(t, restriction.RequireLogin, true)
+	test.IsEqualString(t, restriction.AllowedUsers[0], "alice")
+	test.IsEqualString(t, restriction.AllowedGroups[0], "staff")
+	request := FileRequest{RequireLogin: true, AllowedGroups: "staff"}
+	restriction = request.GetAccessRestriction()
+	test.IsEqualBool(t, restriction.RequireLogin, true)
+	test.IsEqualInt(t, len(restriction.AllowedUsers), 0)
+	test.IsEqualString(t, restriction.AllowedGroups[0], "staff")
+}
+
+func TestSessionGroups(t *testing.T) {
+	session := Session{}
+	test.IsEqualInt(t, len(session.GetGroups()), 0)
+	test.IsEqualBool(t, session.IsRecipientSession(), false)
+	session.Groups = "staff\nsales, europe"
+	test.IsEqualString(t, strings.Join(session.GetGroups(), "|"), "staff|sales, europe")
+	session.RecipientName = "recipient@example.com"
+	test.IsEqualBool(t, session.IsRecipientSession(), true)
+}
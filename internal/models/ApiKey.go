@@ -26,6 +26,8 @@ const (
 	ApiPermManageFileRequests
 	// ApiPermDownload is the permission required for downloading stored files without increasing the counter PERM_DOWNLOAD
 	ApiPermDownload
+	// ApiPermManageWebhooks is the permission required for creating and managing webhooks PERM_MANAGE_WEBHOOKS
+	ApiPermManageWebhooks
 )
 
 // ApiPermNone means no permission granted
@@ -73,6 +75,8 @@ func ApiPermissionFromString(permString string) (ApiPermission, error) {
 		return ApiPermManageFileRequests, nil
 	case "PERM_DOWNLOAD":
 		return ApiPermDownload, nil
+	case "PERM_MANAGE_WEBHOOKS":
+		return ApiPermManageWebhooks, nil
 	default:
 		return 0, errors.New("invalid permission")
 	}
@@ -151,6 +155,11 @@ func (key *ApiKey) HasPermissionDownload() bool {
 	return key.HasPermission(ApiPermDownload)
 }
 
+// HasPermissionManageWebhooks returns true if ApiPermManageWebhooks is granted
+func (key *ApiKey) HasPermissionManageWebhooks() bool {
+	return key.HasPermission(ApiPermManageWebhooks)
+}
+
 // ApiKeyOutput is the output used after a new key is created
 type ApiKeyOutput struct {
 	Result   string
@@ -127,6 +127,13 @@ func TestHasPPermissionDownload(t *testing.T) {
 	test.IsEqualBool(t, key.HasPermissionDownload(), true)
 }
 
+func TestHasPermissionManageWebhooks(t *testing.T) {
+	key := &ApiKey{}
+	test.IsEqualBool(t, key.HasPermissionManageWebhooks(), false)
+	key.GrantPermission(ApiPermManageWebhooks)
+	test.IsEqualBool(t, key.HasPermissionManageWebhooks(), true)
+}
+
 func TestApiPermAllNoApiMod(t *testing.T) {
 	key := &ApiKey{}
 	key.GrantPermission(ApiPermDefault)
@@ -154,6 +161,7 @@ func checkOnlyPermissionSet(t *testing.T, key *ApiKey, perm ApiPermission) {
 		{ApiPermManageLogs, "ApiPermManageLogs"},
 		{ApiPermManageFileRequests, "ApiPermManageFileRequests"},
 		{ApiPermDownload, "ApiPermDownload"},
+		{ApiPermManageWebhooks, "ApiPermManageWebhooks"},
 	}
 
 	for _, p := range allPermissions {
@@ -187,6 +195,7 @@ func TestSetIndividualPermissions(t *testing.T) {
 		{ApiPermManageLogs, "ApiPermManageLogs"},
 		{ApiPermManageFileRequests, "ApiPermManageFileRequests"},
 		{ApiPermDownload, "ApiPermDownload"},
+		{ApiPermManageWebhooks, "ApiPermManageWebhooks"},
 	}
 
 	for _, p := range permissions {
@@ -218,6 +227,7 @@ func TestSetCombinedPermissions(t *testing.T) {
 		ApiPermManageLogs,
 		ApiPermManageFileRequests,
 		ApiPermDownload,
+		ApiPermManageWebhooks,
 	}
 
 	// Test setting permissions in combination
@@ -294,6 +304,11 @@ func TestApiPermissionFromString(t *testing.T) {
 			input:    "PERM_DOWNLOAD",
 			wantPerm: ApiPermDownload,
 		},
+		{
+			name:     "PERM_MANAGE_WEBHOOKS",
+			input:    "PERM_MANAGE_WEBHOOKS",
+			wantPerm: ApiPermManageWebhooks,
+		},
 		{
 			name:      "invalid permission",
 			input:     "PERM_UNKNOWN",
@@ -0,0 +1,127 @@
+package models
+
+import "strings"
+
+const (
+	// AuditResultSuccess is set for actions that were completed
+	AuditResultSuccess = "success"
+	// AuditResultFailure is set for actions that were denied or failed
+	AuditResultFailure = "failure"
+)
+
+// Actions that are recorded in the audit log
+const (
+	AuditActionStartup            = "system.startup"
+	AuditActionShutdown           = "system.shutdown"
+	AuditActionSetup              = "system.setup"
+	AuditActionDeploymentPassword = "system.deploymentPassword"
+	AuditActionDeprecation        = "system.deprecation"
+	AuditActionLogsDelete         = "system.logsDelete"
+	AuditActionKeyRotation        = "system.keyRotation"
+	AuditActionEncryptFiles       = "system.encryptFiles"
+	AuditActionScrub              = "system.scrub"
+	AuditActionScrubResolve       = "system.scrubResolve"
+	AuditActionLogin              = "auth.login"
+	AuditActionTwoFactor          = "auth.twoFactor"
+	AuditActionTwoFactorReset     = "auth.twoFactorReset"
+	AuditActionPasswordReset      = "auth.passwordReset"
+	AuditActionUserCreate         = "user.create"
+	AuditActionUserEdit           = "user.edit"
+	AuditActionUserDelete         = "user.delete"
+	AuditActionFileUpload         = "file.upload"
+	AuditActionFileDownload       = "file.download"
+	AuditActionFileEdit           = "file.edit"
+	AuditActionFileReplace        = "file.replace"
+	AuditActionFileRollback       = "file.rollback"
+	AuditActionFileDelete         = "file.delete"
+	AuditActionFileRestore        = "file.restore"
+	AuditActionFileScan           = "file.scan"
+	AuditActionFileRelease        = "file.release"
+	AuditActionFileExpire         = "file.expire"
+	AuditActionFileShare          = "file.share"
+	AuditActionFileRequestCreate  = "filerequest.create"
+	AuditActionFileRequestEdit    = "filerequest.edit"
+	AuditActionFileRequestDelete  = "filerequest.delete"
+)
+
+// AuditEvent is a structured entry of the audit log
+type AuditEvent struct {
+	Id            int64  `json:"id" redis:"Id"`
+	Timestamp     int64  `json:"timestamp" redis:"Timestamp"`
+	Action        string `json:"action" redis:"Action"`
+	Result        string `json:"result" redis:"Result"`
+	UserId        int    `json:"userId" redis:"UserId"`               // The user who triggered the event, 0 if unknown
+	ApiKeyId      string `json:"apiKeyId" redis:"ApiKeyId"`           // The public ID of the API key, if the event was triggered through the API
+	Ip            string `json:"ip" redis:"Ip"`                       // The IP address of the requester, if known
+	FileId        string `json:"fileId" redis:"FileId"`               // The file the event refers to, if any
+	FileRequestId string `json:"fileRequestId" redis:"FileRequestId"` // The file request the event refers to, if any
+	Details       string `json:"details" redis:"Details"`             // Human-readable description of the event
+}
+
+// AuditQuery contains the filters and pagination parameters for requesting audit events.
+// The result is always sorted by ID in descending order, so that the latest event is returned first
+type AuditQuery struct {
+	UserId        int    // If not 0, only events triggered by this user are returned
+	ApiKeyId      string // If not empty, only events triggered with this API key are returned
+	Ip            string // If not empty, only events from this IP are returned
+	FileId        string // If not empty, only events for this file are returned
+	FileRequestId string // If not empty, only events for this file request are returned
+	Action        string // If not empty, only events with this action are returned. If it ends with ".", all actions starting with this prefix are returned
+	Result        string // If not empty, only events with this result are returned
+	Since         int64  // If not 0, only events at or after this timestamp are returned
+	Until         int64  // If not 0, only events before this timestamp are returned
+	Limit         int    // The maximum number of returned events. 0 returns all events
+	BeforeId      int64  // If not 0, only events with a lower ID are returned. Used for pagination
+}
+
+// AuditQueryResult is returned for an AuditQuery
+type AuditQueryResult struct {
+	Events  []AuditEvent // The events matching the query, latest first
+	HasMore bool         // True, if more events than Limit matched the query
+}
+
+// IsActionPrefix returns true if the action filter of the query matches all actions starting with it
+func (q *AuditQuery) IsActionPrefix() bool {
+	return strings.HasSuffix(q.Action, ".")
+}
+
+// Matches returns true if the event passes all filters of the query. Pagination is not evaluated
+func (q *AuditQuery) Matches(event AuditEvent) bool {
+	if q.UserId != 0 && event.UserId != q.UserId {
+		return false
+	}
+	if q.ApiKeyId != "" && event.ApiKeyId != q.ApiKeyId {
+		return false
+	}
+	if q.Ip != "" && event.Ip != q.Ip {
+		return false
+	}
+	if q.FileId != "" && event.FileId != q.FileId {
+		return false
+	}
+	if q.FileRequestId != "" && event.FileRequestId != q.FileRequestId {
+		return false
+	}
+	if q.Action != "" {
+		if q.IsActionPrefix() {
+			if !strings.HasPrefix(event.Action, q.Action) {
+				return false
+			}
+		} else if event.Action != q.Action {
+			return false
+		}
+	}
+	if q.Result != "" && event.Result != q.Result {
+		return false
+	}
+	if q.Since != 0 && event.Timestamp < q.Since {
+		return false
+	}
+	if q.Until != 0 && event.Timestamp >= q.Until {
+		return false
+	}
+	if q.BeforeId != 0 && event.Id >= q.BeforeId {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestAuditQueryMatches(t *testing.T) {
+	event := AuditEvent{Id: 10, Timestamp: 200, Action: AuditActionFileDelete, Result: AuditResultSuccess,
+		UserId: 2, ApiKeyId: "key", Ip: "127.0.0.1", FileId: "file", FileRequestId: "request"}
+	query := AuditQuery{}
+	test.IsEqualBool(t, query.Matches(event), true)
+	query = AuditQuery{UserId: 2, ApiKeyId: "key", Ip: "127.0.0.1", FileId: "file", FileRequestId: "request", Result: AuditResultSuccess}
+	test.IsEqualBool(t, query.Matches(event), true)
+	query = AuditQuery{UserId: 3}
+	test.IsEqualBool(t, query.Matches(event), false)
+	query = AuditQuery{ApiKeyId: "other"}
+	test.IsEqualBool(t, query.Matches(event), false)
+	query = AuditQuery{Ip: "127.0.0.2"}
+	test.IsEqualBool(t, query.Matches(event), false)
+	query = AuditQuery{FileId: "other"}
+	test.IsEqualBool(t, query.Matches(event), false)
+	query = AuditQuery{FileRequestId: "other"}
+	test.IsEqualBool(t, query.Matches(event), false)
+	query = AuditQuery{Result: AuditResultFailure}
+	test.IsEqualBool(t, query.Matches(event), false)
+
+	query = AuditQuery{Action: AuditActionFileDelete}
+	test.IsEqualBool(t, query.IsActionPrefix(), false)
+	test.IsEqualBool(t, query.Matches(event), true)
+	query = AuditQuery{Action: "file"}
+	test.IsEqualBool(t, query.Matches(event), false)
+	query = AuditQuery{Action: "file."}
+	test.IsEqualBool(t, query.IsActionPrefix(), true)
+	test.IsEqualBool(t, query.Matches(event), true)
+	query = AuditQuery{Action: "user."}
+	test.IsEqualBool(t, query.Matches(event), false)
+
+	query = AuditQuery{Since: 200, Until: 201}
+	test.IsEqualBool(t, query.Matches(event), true)
+	query = AuditQuery{Since: 201}
+	test.IsEqualBool(t, query.Matches(event), false)
+	query = AuditQuery{Until: 200}
+	test.IsEqualBool(t, query.Matches(event), false)
+	query = AuditQuery{BeforeId: 11}
+	test.IsEqualBool(t, query.Matches(event), true)
+	query = AuditQuery{BeforeId: 10}
+	test.IsEqualBool(t, query.Matches(event), false)
+}
@@ -16,6 +16,24 @@ type AuthenticationConfig struct {
 	OAuthRecheckInterval int      `json:"OAuthRecheckInterval"`
 	OAuthGroups          []string `json:"OAuthGroups"`
 	OnlyRegisteredUsers  bool     `json:"OnlyRegisteredUsers"`
+	// If true, admins and super admins have to set up two-factor authentication. Only used for internal authentication
+	RequireTwoFactorForAdmins bool `json:"RequireTwoFactorForAdmins"`
+	// URL of the LDAP server, e.g. ldaps://ldap.example.com:636
+	LdapUrl      string `json:"LdapUrl"`
+	LdapStartTls bool   `json:"LdapStartTls"`
+	// Optional service account that is used to search for users. If empty, the search is done anonymously
+	LdapBindDn       string `json:"LdapBindDn"`
+	LdapBindPassword string `json:"LdapBindPassword"`
+	// If set, the user DN is created from this template, e.g. uid={username},ou=people,dc=example,dc=com
+	// Otherwise, the user is searched with LdapSearchFilter below LdapSearchBase
+	LdapUserDnTemplate string   `json:"LdapUserDnTemplate"`
+	LdapSearchBase     string   `json:"LdapSearchBase"`
+	LdapSearchFilter   string   `json:"LdapSearchFilter"`
+	LdapGroupAttribute string   `json:"LdapGroupAttribute"`
+	LdapGroups         []string `json:"LdapGroups"`
+	// Header containing a comma-separated list of the groups of the user, if header authentication is used.
+	// Only used for files and file requests that are restricted to specific groups
+	HeaderGroupKey string `json:"HeaderGroupKey"`
 }
 
 const (
@@ -30,4 +48,10 @@ const (
 
 	// AuthenticationDisabled authentication ignores all internal authentication procedures. A reverse proxy needs to restrict access
 	AuthenticationDisabled
+
+	// AuthenticationLdap authentication verifies a user / password combination against an LDAP directory
+	AuthenticationLdap
 )
+
+// LdapUsernamePlaceholder is replaced with the username in LdapUserDnTemplate and LdapSearchFilter
+const LdapUsernamePlaceholder = "{username}"
@@ -8,6 +8,7 @@ type AwsConfig struct {
 	KeySecret     string `yaml:"KeySecret"`
 	Endpoint      string `yaml:"Endpoint"`
 	ProxyDownload bool   `yaml:"ProxyDownload"`
+	StreamUploads bool   `yaml:"StreamUploads"`
 }
 
 // IsAllProvided returns true if all required variables have been set for using AWS S3 / Backblaze
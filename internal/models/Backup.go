@@ -0,0 +1,44 @@
+package models
+
+// BackupManifest describes the content of a backup archive
+type BackupManifest struct {
+	FormatVersion int           `json:"formatVersion"` // The version of the archive layout
+	GokapiVersion string        `json:"gokapiVersion"` // The Gokapi version that created the backup
+	SchemaVersion int           `json:"schemaVersion"` // The schema version of the database that was backed up
+	ConfigVersion int           `json:"configVersion"` // The version of the stored configuration file
+	CreatedAt     int64         `json:"createdAt"`     // UTC timestamp of the creation of the backup
+	IncludesData  bool          `json:"includesData"`  // True if the files of the data directory are included
+	MissingBlobs  []string      `json:"missingBlobs"`  // Stored files that were referenced by the database, but could not be found
+	Entries       []BackupEntry `json:"entries"`       // All files of the archive, except for the manifest itself
+}
+
+// BackupEntry is a single file of a backup archive
+type BackupEntry struct {
+	Name   string `json:"name"`   // The path of the file inside the archive
+	Size   int64  `json:"size"`   // The size of the file in bytes
+	Sha256 string `json:"sha256"` // The hex encoded SHA256 checksum of the file
+}
+
+// DatabaseSnapshot contains the content of the database, except for temporary data like sessions or queued webhook deliveries
+type DatabaseSnapshot struct {
+	ApiKeys      []ApiKey      `json:"apiKeys"`
+	Users        []BackupUser  `json:"users"`
+	Files        []File        `json:"files"`
+	FileRequests []FileRequest `json:"fileRequests"`
+	Webhooks     []Webhook     `json:"webhooks"`
+	Bundles      []Bundle      `json:"bundles"`
+	ShareLinks   []ShareLink   `json:"shareLinks"`
+	FileVersions []FileVersion `json:"fileVersions"`
+	AuditEvents  []AuditEvent  `json:"auditEvents"` // In chronological order
+	StatTraffic  uint64        `json:"statTraffic"`
+	TrafficSince int64         `json:"trafficSince"` // 0 if traffic counting has not started yet
+}
+
+// BackupUser contains a user and all settings that are stored for the user
+type BackupUser struct {
+	User         User             `json:"user"`
+	PasswordHash string           `json:"passwordHash"` // Stored separately, as the password hash of User is not serialised
+	E2EInfo      E2EInfoEncrypted `json:"e2eInfo"`
+	Totp         *UserTotp        `json:"totp,omitempty"`
+	Quota        *UserQuota       `json:"quota,omitempty"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Bundle contains multiple files, that can be downloaded with a single link
+type Bundle struct {
+	Id                 string `json:"id" redis:"id"`                                 // The internal ID of the bundle
+	Name               string `json:"name" redis:"name"`                             // The given name for the bundle, also used for the zip file
+	FileIds            string `json:"fileids" redis:"fileids"`                       // Comma-separated list of the IDs of all files in the bundle
+	PasswordHash       string `json:"passwordhash" redis:"passwordhash"`             // The hash of the password (if the bundle is password-protected)
+	UserId             int    `json:"userid" redis:"userid"`                         // The user ID of the owner
+	ExpireAt           int64  `json:"expireat" redis:"expireat"`                     // UTC timestamp of the bundle expiry
+	CreationDate       int64  `json:"creationdate" redis:"creationdate"`             // The timestamp of the bundle creation
+	DownloadsRemaining int    `json:"downloadsremaining" redis:"downloadsremaining"` // The remaining downloads for this bundle
+	DownloadCount      int    `json:"downloadcount" redis:"downloadcount"`           // The number of times files of the bundle have been downloaded
+	UnlimitedDownloads bool   `json:"unlimiteddownloads" redis:"unlimiteddownloads"` // True if the downloads are not limited
+	UnlimitedTime      bool   `json:"unlimitedtime" redis:"unlimitedtime"`           // True if the bundle does not expire
+}
+
+// BundleApiOutput is the public representation of a bundle, hiding sensitive information
+type BundleApiOutput struct {
+	Id                  string   `json:"Id"`                  // The internal ID of the bundle
+	Name                string   `json:"Name"`                // The given name for the bundle
+	FileIds             []string `json:"FileIds"`             // The IDs of all files in the bundle
+	UrlDownload         string   `json:"UrlDownload"`         // The public URL of the bundle
+	ExpireAt            int64    `json:"ExpireAt"`            // UTC timestamp of the bundle expiry
+	CreationDate        int64    `json:"CreationDate"`        // UTC timestamp of the bundle creation
+	DownloadsRemaining  int      `json:"DownloadsRemaining"`  // The remaining downloads for this bundle
+	DownloadCount       int      `json:"DownloadCount"`       // The number of times files of the bundle have been downloaded
+	UnlimitedDownloads  bool     `json:"UnlimitedDownloads"`  // True if the downloads are not limited
+	UnlimitedTime       bool     `json:"UnlimitedTime"`       // True if the bundle does not expire
+	IsPasswordProtected bool     `json:"IsPasswordProtected"` // True if a password has to be entered before downloading
+	UserId              int      `json:"UserId"`              // The user ID of the owner
+}
+
+// GetFileIds returns the IDs of all files in the bundle
+func (b *Bundle) GetFileIds() []string {
+	if b.FileIds == "" {
+		return []string{}
+	}
+	return strings.Split(b.FileIds, ",")
+}
+
+// AddFiles adds the given file IDs to the bundle, if they are not part of it already
+func (b *Bundle) AddFiles(ids []string) {
+	fileIds := b.GetFileIds()
+	for _, id := range ids {
+		if id != "" && !slices.Contains(fileIds, id) {
+			fileIds = append(fileIds, id)
+		}
+	}
+	b.FileIds = strings.Join(fileIds, ",")
+}
+
+// RemoveFiles removes the given file IDs from the bundle
+func (b *Bundle) RemoveFiles(ids []string) {
+	fileIds := slices.DeleteFunc(b.GetFileIds(), func(id string) bool {
+		return slices.Contains(ids, id)
+	})
+	b.FileIds = strings.Join(fileIds, ",")
+}
+
+// ContainsFile returns true if the file with the given ID is part of the bundle
+func (b *Bundle) ContainsFile(id string) bool {
+	return slices.Contains(b.GetFileIds(), id)
+}
+
+// IsExpired returns true if the bundle has expired or no downloads are remaining
+func (b *Bundle) IsExpired() bool {
+	return (!b.UnlimitedTime && b.ExpireAt < time.Now().Unix()) ||
+		(!b.UnlimitedDownloads && b.DownloadsRemaining < 1)
+}
+
+// IsPasswordProtected returns true if a password has to be entered before downloading
+func (b *Bundle) IsPasswordProtected() bool {
+	return b.PasswordHash != ""
+}
+
+// GetUrl returns the public URL of the bundle
+func (b *Bundle) GetUrl(serverUrl string) string {
+	return serverUrl + "b?id=" + b.Id
+}
+
+// ToApiOutput returns the public representation of the bundle
+func (b *Bundle) ToApiOutput(serverUrl string) BundleApiOutput {
+	return BundleApiOutput{
+		Id:                  b.Id,
+		Name:                b.Name,
+		FileIds:             b.GetFileIds(),
+		UrlDownload:         b.GetUrl(serverUrl),
+		ExpireAt:            b.ExpireAt,
+		CreationDate:        b.CreationDate,
+		DownloadsRemaining:  b.DownloadsRemaining,
+		DownloadCount:       b.DownloadCount,
+		UnlimitedDownloads:  b.UnlimitedDownloads,
+		UnlimitedTime:       b.UnlimitedTime,
+		IsPasswordProtected: b.IsPasswordProtected(),
+		UserId:              b.UserId,
+	}
+}
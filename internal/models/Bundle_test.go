@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestBundle_Files(t *testing.T) {
+	bundle := Bundle{}
+	test.IsEqualInt(t, len(bundle.GetFileIds()), 0)
+	bundle.AddFiles([]string{"file1", "file2", "", "file1"})
+	test.IsEqualString(t, bundle.FileIds, "file1,file2")
+	bundle.AddFiles([]string{"file3", "file2"})
+	test.IsEqualString(t, bundle.FileIds, "file1,file2,file3")
+	test.IsEqualBool(t, bundle.ContainsFile("file2"), true)
+	test.IsEqualBool(t, bundle.ContainsFile("file4"), false)
+	bundle.RemoveFiles([]string{"file2", "file4"})
+	test.IsEqualString(t, bundle.FileIds, "file1,file3")
+	test.IsEqualBool(t, bundle.ContainsFile("file2"), false)
+	bundle.RemoveFiles([]string{"file1", "file3"})
+	test.IsEqualString(t, bundle.FileIds, "")
+	test.IsEqualInt(t, len(bundle.GetFileIds()), 0)
+}
+
+func TestBundle_IsExpired(t *testing.T) {
+	bundle := Bundle{UnlimitedTime: true, UnlimitedDownloads: true}
+	test.IsEqualBool(t, bundle.IsExpired(), false)
+	bundle.UnlimitedTime = false
+	test.IsEqualBool(t, bundle.IsExpired(), true)
+	bundle.ExpireAt = time.Now().Add(time.Hour).Unix()
+	test.IsEqualBool(t, bundle.IsExpired(), false)
+	bundle.UnlimitedDownloads = false
+	test.IsEqualBool(t, bundle.IsExpired(), true)
+	bundle.DownloadsRemaining = 1
+	test.IsEqualBool(t, bundle.IsExpired(), false)
+}
+
+func TestBundle_ToApiOutput(t *testing.T) {
+	bundle := Bundle{Id: "bundle1", Name: "Test", FileIds: "a,b", PasswordHash: "hash", UserId: 3, DownloadsRemaining: 2}
+	output := bundle.ToApiOutput("http://gokapi.local/")
+	test.IsEqualString(t, output.UrlDownload, "http://gokapi.local/b?id=bundle1")
+	test.IsEqualInt(t, len(output.FileIds), 2)
+	test.IsEqualBool(t, output.IsPasswordProtected, true)
+	test.IsEqualInt(t, output.UserId, 3)
+	test.IsEqualInt(t, output.DownloadsRemaining, 2)
+}
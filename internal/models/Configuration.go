@@ -32,6 +32,10 @@ type Encryption struct {
 	Salt         string
 	Checksum     string
 	ChecksumSalt string
+	// PreviousKey is the master key that was used before the current one, encrypted with the current master key.
+	// It is only set while a key rotation has not been completed yet
+	PreviousKey      []byte
+	PreviousKeyNonce []byte
 }
 
 // ToJson returns an indented Json representation
@@ -31,9 +31,22 @@ type File struct {
 	Encryption              EncryptionInfo `json:"Encryption" redis:"-"`                          // If the file is encrypted, this stores all info for decrypting
 	UnlimitedDownloads      bool           `json:"UnlimitedDownloads" redis:"UnlimitedDownloads"` // True if the uploader did not limit the downloads
 	UnlimitedTime           bool           `json:"UnlimitedTime" redis:"UnlimitedTime"`           // True if the uploader did not limit the time
+	ProcessingState         int            `json:"ProcessingState" redis:"ProcessingState"`       // Either FileStatePublished or FileStateQuarantined
+	QuarantineReason        string         `json:"QuarantineReason" redis:"QuarantineReason"`     // If the file has been quarantined after the upload, this contains the reason
+	PublishAt               int64          `json:"PublishAt" redis:"PublishAt"`                   // UTC timestamp from which on the file can be downloaded. 0 if it can be downloaded immediately
+	RequireLogin            bool           `json:"RequireLogin" redis:"RequireLogin"`             // True if the file can only be downloaded by authenticated users
+	AllowedUsers            string         `json:"AllowedUsers" redis:"AllowedUsers"`             // Comma-separated list of users that can download the file, if RequireLogin is set
+	AllowedGroups           string         `json:"AllowedGroups" redis:"AllowedGroups"`           // Comma-separated list of groups that can download the file, if RequireLogin is set
 	InternalRedisEncryption []byte         `redis:"EncryptionRedis"`                              // This field is an internal field, used to store the EncryptionInfo in a Redis Hashmap
 }
 
+const (
+	// FileStatePublished is the state of a file that can be downloaded
+	FileStatePublished = 0
+	// FileStateQuarantined is the state of a file that failed the post-processing and is held until an admin releases it
+	FileStateQuarantined = 1
+)
+
 // FileApiOutput will be displayed for public outputs from the ID, hiding sensitive information
 type FileApiOutput struct {
 	Id                           string `json:"Id"`                           // The internal ID of the file
@@ -59,6 +72,13 @@ type FileApiOutput struct {
 	IsSavedOnLocalStorage        bool   `json:"IsSavedOnLocalStorage"`        // True if the file does not use cloud storage
 	IsPendingDeletion            bool   `json:"IsPendingDeletion"`            // True if the file is about to be deleted
 	IsFileRequest                bool   `json:"IsFileRequest"`                // True if the file belongs to a file request
+	IsQuarantined                bool   `json:"IsQuarantined"`                // True if the file failed the checks after the upload and cannot be downloaded
+	QuarantineReason             string `json:"QuarantineReason"`             // If the file has been quarantined, this contains the reason
+	PublishAt                    int64  `json:"PublishAt"`                    // UTC timestamp from which on the file can be downloaded. 0 if it can be downloaded immediately
+	IsEmbargoed                  bool   `json:"IsEmbargoed"`                  // True if the file cannot be downloaded yet, as it has not been published
+	RequireLogin                 bool   `json:"RequireLogin"`                 // True if the file can only be downloaded by authenticated users
+	AllowedUsers                 string `json:"AllowedUsers"`                 // Comma-separated list of users that can download the file. If empty and no groups are set, all authenticated users can download the file
+	AllowedGroups                string `json:"AllowedGroups"`                // Comma-separated list of groups that can download the file. If empty and no users are set, all authenticated users can download the file
 	UploaderId                   int    `json:"UploaderId"`                   // The user ID of the uploader
 }
 
@@ -75,6 +95,21 @@ func (f *File) IsLocalStorage() bool {
 	return f.AwsBucket == ""
 }
 
+// IsHeld returns true if the file must not be downloaded, because it has been quarantined after the upload
+func (f *File) IsHeld() bool {
+	return f.ProcessingState != FileStatePublished
+}
+
+// IsEmbargoed returns true if the file must not be downloaded yet, because its publication time has not been reached
+func (f *File) IsEmbargoed() bool {
+	return f.PublishAt > time.Now().Unix()
+}
+
+// GetAccessRestriction returns the restriction for downloading the file
+func (f *File) GetAccessRestriction() AccessRestriction {
+	return NewAccessRestriction(f.RequireLogin, f.AllowedUsers, f.AllowedGroups)
+}
+
 // IsPendingForDeletion returns true if the file is pending to be deleted
 func (f *File) IsPendingForDeletion() bool {
 	return f.PendingDeletion != 0
@@ -101,6 +136,8 @@ func (f *File) ToFileApiOutput(serverUrl string, useFilenameInUrl bool) (FileApi
 		result.UploaderId = f.UserId
 	}
 	result.IsPendingDeletion = f.IsPendingForDeletion()
+	result.IsQuarantined = f.ProcessingState == FileStateQuarantined
+	result.IsEmbargoed = f.IsEmbargoed()
 	result.FileRequestId = f.UploadRequestId
 	result.ExpireAtString = time.Unix(f.ExpireAt, 0).UTC().Format("2006-01-02 15:04:05")
 
@@ -115,7 +152,7 @@ func getDownloadUrl(input FileApiOutput, serverUrl string, useFilename bool) str
 }
 
 func getHotlinkUrl(input FileApiOutput, serverUrl string, useFilename bool) string {
-	if input.RequiresClientSideDecryption || input.IsPasswordProtected {
+	if input.RequiresClientSideDecryption || input.IsPasswordProtected || input.RequireLogin {
 		return ""
 	}
 	if input.HotlinkId != "" {
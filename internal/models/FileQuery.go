@@ -0,0 +1,158 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FileSortUploadDate sorts files by their upload date
+	FileSortUploadDate = iota
+	// FileSortName sorts files by their filename
+	FileSortName
+	// FileSortSize sorts files by their size in bytes
+	FileSortSize
+	// FileSortExpiry sorts files by their expiry timestamp
+	FileSortExpiry
+	// FileSortDownloads sorts files by their download count
+	FileSortDownloads
+)
+
+const (
+	// FileFilterEncryptionAny does not filter by encryption
+	FileFilterEncryptionAny = iota
+	// FileFilterEncryptionOnly only returns encrypted files
+	FileFilterEncryptionOnly
+	// FileFilterEncryptionNone only returns files that are not encrypted
+	FileFilterEncryptionNone
+)
+
+// FileQuery contains the filters, sort order and pagination parameters for requesting a subset of all files.
+// An empty FileQuery returns all files, sorted by upload date in ascending order
+type FileQuery struct {
+	UserId              int    // If not 0, only files uploaded by this user are returned
+	NameContains        string // If not empty, only files that contain this string in their name (case-insensitive) are returned
+	ContentTypePrefix   string // If not empty, only files with a MIME type starting with this string (case-insensitive) are returned
+	FileRequestId       string // If not empty, only files uploaded for this file request are returned
+	IncludeFileRequests bool   // If false, files uploaded for a file request are not returned, unless FileRequestId is set
+	Encryption          int    // Either FileFilterEncryptionAny, FileFilterEncryptionOnly or FileFilterEncryptionNone
+	ExpiresAfter        int64  // If not 0, only files that expire after this timestamp or never expire are returned
+	ExpiresBefore       int64  // If not 0, only files with a limited time that expire before this timestamp are returned
+	HideExpiredAt       int64  // If not 0, files that are expired at this timestamp are not returned
+	SortBy              int    // The field the result is sorted by, one of the FileSort constants
+	SortDescending      bool   // If true, the result is sorted in descending order
+	Limit               int    // The maximum number of returned files. 0 returns all files
+	CursorId            string // If not empty, only files after the file with this ID are returned. Must be set together with the cursor value
+	CursorName          string // The name of the last file of the previous page, if sorted by name
+	CursorValue         int64  // The value of the sort field of the last file of the previous page, if not sorted by name
+}
+
+// FileQueryResult is returned for a FileQuery
+type FileQueryResult struct {
+	Files   []File // The files matching the query, sorted as requested
+	HasMore bool   // True, if more files than Limit matched the query
+}
+
+// HasCursor returns true if the query requests a page after a previous one
+func (q *FileQuery) HasCursor() bool {
+	return q.CursorId != ""
+}
+
+// Matches returns true if the file passes all filters of the query. Sorting and pagination are not evaluated
+func (q *FileQuery) Matches(file File) bool {
+	if q.UserId != 0 && file.UserId != q.UserId {
+		return false
+	}
+	if q.FileRequestId != "" {
+		if file.UploadRequestId != q.FileRequestId {
+			return false
+		}
+	} else if !q.IncludeFileRequests && file.IsFileRequest() {
+		return false
+	}
+	if q.NameContains != "" && !strings.Contains(strings.ToLower(file.Name), strings.ToLower(q.NameContains)) {
+		return false
+	}
+	if q.ContentTypePrefix != "" && !strings.HasPrefix(strings.ToLower(file.ContentType), strings.ToLower(q.ContentTypePrefix)) {
+		return false
+	}
+	switch q.Encryption {
+	case FileFilterEncryptionOnly:
+		if !file.Encryption.IsEncrypted {
+			return false
+		}
+	case FileFilterEncryptionNone:
+		if file.Encryption.IsEncrypted {
+			return false
+		}
+	}
+	if q.ExpiresAfter != 0 && !file.UnlimitedTime && file.ExpireAt <= q.ExpiresAfter {
+		return false
+	}
+	if q.ExpiresBefore != 0 && (file.UnlimitedTime || file.ExpireAt >= q.ExpiresBefore) {
+		return false
+	}
+	if q.HideExpiredAt != 0 {
+		if (file.ExpireAt < q.HideExpiredAt && !file.UnlimitedTime) ||
+			(file.DownloadsRemaining < 1 && !file.UnlimitedDownloads) {
+			return false
+		}
+	}
+	return true
+}
+
+// SortValue returns the numeric value of the field the query is sorted by.
+// Returns 0 if the query is sorted by name
+func (q *FileQuery) SortValue(file File) int64 {
+	switch q.SortBy {
+	case FileSortSize:
+		return file.SizeBytes
+	case FileSortExpiry:
+		return file.ExpireAt
+	case FileSortDownloads:
+		return int64(file.DownloadCount)
+	case FileSortName:
+		return 0
+	default:
+		return file.UploadDate
+	}
+}
+
+// GetCursor returns an opaque string that can be passed to SetCursor to request the files following the given file
+func (q *FileQuery) GetCursor(file File) string {
+	value := strconv.FormatInt(q.SortValue(file), 10)
+	if q.SortBy == FileSortName {
+		value = file.Name
+	}
+	raw := strconv.Itoa(q.SortBy) + ":" + strconv.FormatBool(q.SortDescending) + ":" + file.Id + ":" + value
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// SetCursor parses a cursor that was created with GetCursor. Returns an error, if the cursor is invalid
+// or was created for a different sort order
+func (q *FileQuery) SetCursor(cursor string) error {
+	errInvalid := errors.New("invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return errInvalid
+	}
+	parts := strings.SplitN(string(raw), ":", 4)
+	if len(parts) != 4 || parts[2] == "" {
+		return errInvalid
+	}
+	if parts[0] != strconv.Itoa(q.SortBy) || parts[1] != strconv.FormatBool(q.SortDescending) {
+		return errors.New("cursor does not match the requested sort order")
+	}
+	q.CursorId = parts[2]
+	if q.SortBy == FileSortName {
+		q.CursorName = parts[3]
+		return nil
+	}
+	q.CursorValue, err = strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return errInvalid
+	}
+	return nil
+}
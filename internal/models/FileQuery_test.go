@@ -0,0 +1,61 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestFileQueryMatches(t *testing.T) {
+	file := File{Id: "id", Name: "Holiday.JPG", ContentType: "image/jpeg", UserId: 2, ExpireAt: 200, DownloadsRemaining: 1}
+	query := FileQuery{}
+	test.IsEqualBool(t, query.Matches(file), true)
+	query = FileQuery{UserId: 3}
+	test.IsEqualBool(t, query.Matches(file), false)
+	query = FileQuery{NameContains: "day.jpg", ContentTypePrefix: "IMAGE/"}
+	test.IsEqualBool(t, query.Matches(file), true)
+	query = FileQuery{Encryption: FileFilterEncryptionOnly}
+	test.IsEqualBool(t, query.Matches(file), false)
+	query = FileQuery{Encryption: FileFilterEncryptionNone}
+	test.IsEqualBool(t, query.Matches(file), true)
+	query = FileQuery{ExpiresAfter: 100, ExpiresBefore: 300}
+	test.IsEqualBool(t, query.Matches(file), true)
+	query = FileQuery{ExpiresAfter: 200}
+	test.IsEqualBool(t, query.Matches(file), false)
+	query = FileQuery{HideExpiredAt: 201}
+	test.IsEqualBool(t, query.Matches(file), false)
+	query = FileQuery{HideExpiredAt: 200}
+	test.IsEqualBool(t, query.Matches(file), true)
+
+	file.UploadRequestId = "request"
+	query = FileQuery{}
+	test.IsEqualBool(t, query.Matches(file), false)
+	query = FileQuery{IncludeFileRequests: true}
+	test.IsEqualBool(t, query.Matches(file), true)
+	query = FileQuery{FileRequestId: "request"}
+	test.IsEqualBool(t, query.Matches(file), true)
+	query = FileQuery{FileRequestId: "other", IncludeFileRequests: true}
+	test.IsEqualBool(t, query.Matches(file), false)
+}
+
+func TestFileQueryCursor(t *testing.T) {
+	file := File{Id: "id", Name: "name:with:colons", SizeBytes: 500}
+	query := FileQuery{SortBy: FileSortName}
+	cursor := query.GetCursor(file)
+	test.IsNil(t, query.SetCursor(cursor))
+	test.IsEqualBool(t, query.HasCursor(), true)
+	test.IsEqualString(t, query.CursorId, "id")
+	test.IsEqualString(t, query.CursorName, "name:with:colons")
+
+	query = FileQuery{SortBy: FileSortSize, SortDescending: true}
+	cursor = query.GetCursor(file)
+	test.IsNil(t, query.SetCursor(cursor))
+	test.IsEqualString(t, query.CursorId, "id")
+	test.IsEqualInt64(t, query.CursorValue, 500)
+
+	query = FileQuery{SortBy: FileSortSize}
+	test.IsNotNil(t, query.SetCursor(cursor))
+	test.IsNotNil(t, query.SetCursor("invalid!"))
+	test.IsNotNil(t, query.SetCursor("aW52YWxpZA"))
+	test.IsEqualBool(t, query.HasCursor(), false)
+}
@@ -14,4 +14,8 @@ type UploadParameters struct {
 	Password            string
 	ExternalUrl         string
 	FileRequestId       string
+	PublishAt           int64  // UTC timestamp from which on the file can be downloaded. 0 if it can be downloaded immediately
+	RequireLogin        bool   // True if the file can only be downloaded by authenticated users
+	AllowedUsers        string // Comma-separated list of users that can download the file, if RequireLogin is set
+	AllowedGroups       string // Comma-separated list of groups that can download the file, if RequireLogin is set
 }
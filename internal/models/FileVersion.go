@@ -0,0 +1,93 @@
+package models
+
+// FileVersion is the previous content of a file that has been replaced. The stored content is kept,
+// so that it can be downloaded or restored later
+type FileVersion struct {
+	Id                      string         `json:"Id" redis:"Id"`                   // The ID of the version
+	FileId                  string         `json:"FileId" redis:"FileId"`           // The ID of the file the version belongs to
+	Revision                int            `json:"Revision" redis:"Revision"`       // Consecutive number of the version for the file, starting with 1
+	Name                    string         `json:"Name" redis:"Name"`               // The filename of the previous content
+	Size                    string         `json:"Size" redis:"Size"`               // Filesize in a human-readable format
+	SHA1                    string         `json:"SHA1" redis:"SHA1"`               // The hash of the content, used to locate the stored file
+	ContentType             string         `json:"ContentType" redis:"ContentType"` // The MIME type of the previous content
+	AwsBucket               string         `json:"AwsBucket" redis:"AwsBucket"`     // If the content is stored in the cloud, this is the bucket that is being used
+	SizeBytes               int64          `json:"SizeBytes" redis:"SizeBytes"`     // Filesize in bytes
+	ReplacedAt              int64          `json:"ReplacedAt" redis:"ReplacedAt"`   // UTC timestamp of the replacement
+	ReplacedBy              int            `json:"ReplacedBy" redis:"ReplacedBy"`   // The user ID of the user who replaced the content
+	Encryption              EncryptionInfo `json:"Encryption" redis:"-"`            // If the content is encrypted, this stores all info for decrypting
+	InternalRedisEncryption []byte         `json:"-" redis:"EncryptionRedis"`       // This field is an internal field, used to store the EncryptionInfo in a Redis Hashmap
+}
+
+// FileVersionApiOutput is the public representation of a file version, hiding sensitive information
+type FileVersionApiOutput struct {
+	Id          string `json:"Id"`          // The ID of the version
+	FileId      string `json:"FileId"`      // The ID of the file the version belongs to
+	Revision    int    `json:"Revision"`    // Consecutive number of the version for the file, starting with 1
+	Name        string `json:"Name"`        // The filename of the previous content
+	Size        string `json:"Size"`        // Filesize in a human-readable format
+	SHA1        string `json:"SHA1"`        // The hash of the content
+	ContentType string `json:"ContentType"` // The MIME type of the previous content
+	SizeBytes   int64  `json:"SizeBytes"`   // Filesize in bytes
+	ReplacedAt  int64  `json:"ReplacedAt"`  // UTC timestamp of the replacement
+	ReplacedBy  int    `json:"ReplacedBy"`  // The user ID of the user who replaced the content
+	IsEncrypted bool   `json:"IsEncrypted"` // True if the content is encrypted
+}
+
+// NewFileVersion returns a version containing the current content of the file
+func NewFileVersion(id string, revision int, file File, replacedAt int64, replacedBy int) FileVersion {
+	return FileVersion{
+		Id:          id,
+		FileId:      file.Id,
+		Revision:    revision,
+		Name:        file.Name,
+		Size:        file.Size,
+		SHA1:        file.SHA1,
+		ContentType: file.ContentType,
+		AwsBucket:   file.AwsBucket,
+		SizeBytes:   file.SizeBytes,
+		ReplacedAt:  replacedAt,
+		ReplacedBy:  replacedBy,
+		Encryption:  file.Encryption,
+	}
+}
+
+// ApplyTo replaces the content of the file with the content of the version
+func (v *FileVersion) ApplyTo(file *File) {
+	file.Name = v.Name
+	file.Size = v.Size
+	file.SHA1 = v.SHA1
+	file.ContentType = v.ContentType
+	file.AwsBucket = v.AwsBucket
+	file.SizeBytes = v.SizeBytes
+	file.Encryption = v.Encryption
+}
+
+// ToFile returns a file with the ID of the parent file and the content of the version.
+// It can be used to access the stored content of the version
+func (v *FileVersion) ToFile() File {
+	result := File{
+		Id:                 v.FileId,
+		UploadDate:         v.ReplacedAt,
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+	}
+	v.ApplyTo(&result)
+	return result
+}
+
+// ToApiOutput returns the public representation of the version
+func (v *FileVersion) ToApiOutput() FileVersionApiOutput {
+	return FileVersionApiOutput{
+		Id:          v.Id,
+		FileId:      v.FileId,
+		Revision:    v.Revision,
+		Name:        v.Name,
+		Size:        v.Size,
+		SHA1:        v.SHA1,
+		ContentType: v.ContentType,
+		SizeBytes:   v.SizeBytes,
+		ReplacedAt:  v.ReplacedAt,
+		ReplacedBy:  v.ReplacedBy,
+		IsEncrypted: v.Encryption.IsEncrypted,
+	}
+}
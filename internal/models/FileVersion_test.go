@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestFileVersion(t *testing.T) {
+	file := File{
+		Id:            "file1",
+		Name:          "old.txt",
+		Size:          "10 B",
+		SHA1:          "oldhash",
+		ContentType:   "text/plain",
+		AwsBucket:     "bucket",
+		SizeBytes:     10,
+		Encryption:    EncryptionInfo{IsEncrypted: true, DecryptionKey: []byte("key")},
+		DownloadCount: 3,
+	}
+	version := NewFileVersion("version1", 2, file, 1000, 5)
+	test.IsEqualString(t, version.FileId, "file1")
+	test.IsEqualString(t, version.SHA1, "oldhash")
+	test.IsEqualInt64(t, version.ReplacedAt, 1000)
+	test.IsEqualInt(t, version.ReplacedBy, 5)
+
+	current := File{Id: "file1", Name: "new.txt", SHA1: "newhash", DownloadCount: 7}
+	version.ApplyTo(&current)
+	test.IsEqualString(t, current.Name, "old.txt")
+	test.IsEqualString(t, current.SHA1, "oldhash")
+	test.IsEqualString(t, current.AwsBucket, "bucket")
+	test.IsEqualInt64(t, current.SizeBytes, 10)
+	test.IsEqualBool(t, current.Encryption.IsEncrypted, true)
+	test.IsEqualInt(t, current.DownloadCount, 7)
+
+	content := version.ToFile()
+	test.IsEqualString(t, content.Id, "file1")
+	test.IsEqualString(t, content.SHA1, "oldhash")
+	test.IsEqualInt64(t, content.UploadDate, 1000)
+	test.IsEqualBool(t, content.IsLocalStorage(), false)
+
+	output := version.ToApiOutput()
+	test.IsEqualString(t, output.Id, "version1")
+	test.IsEqualInt(t, output.Revision, 2)
+	test.IsEqualString(t, output.Name, "old.txt")
+	test.IsEqualBool(t, output.IsEncrypted, true)
+}
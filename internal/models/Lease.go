@@ -0,0 +1,22 @@
+package models
+
+// Lease is held by a single instance for a limited time. It is used to ensure that tasks,
+// such as the periodic cleanup, are only run by one of multiple instances sharing a database
+type Lease struct {
+	Name      string `json:"name" redis:"name"`           // The name of the task the lease is for
+	Owner     string `json:"owner" redis:"owner"`         // The ID of the instance that holds the lease
+	ExpiresAt int64  `json:"expiresAt" redis:"expiresat"` // Unix timestamp after which the lease can be acquired by another instance
+}
+
+// IsExpired returns true, if the lease is no longer valid at the given time
+func (l *Lease) IsExpired(timeNow int64) bool {
+	return l.ExpiresAt < timeNow
+}
+
+// LeaderStatus contains information about which instance currently runs a task
+type LeaderStatus struct {
+	InstanceId  string `json:"instanceId"`  // The ID of this instance
+	IsLeader    bool   `json:"isLeader"`    // True, if this instance currently holds the lease
+	Leader      string `json:"leader"`      // The ID of the instance holding the lease, empty if the lease has expired
+	LeaseExpiry int64  `json:"leaseExpiry"` // Unix timestamp when the lease expires, if it is not renewed
+}
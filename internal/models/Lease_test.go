@@ -0,0 +1,14 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestLeaseIsExpired(t *testing.T) {
+	lease := Lease{Name: "test", Owner: "instance", ExpiresAt: 100}
+	test.IsEqualBool(t, lease.IsExpired(99), false)
+	test.IsEqualBool(t, lease.IsExpired(100), false)
+	test.IsEqualBool(t, lease.IsExpired(101), true)
+}
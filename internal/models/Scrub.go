@@ -0,0 +1,63 @@
+package models
+
+// ScrubReport contains the result of a storage integrity check
+type ScrubReport struct {
+	StartedAt      int64          `json:"startedAt"`      // Unix timestamp of when the check was started
+	FinishedAt     int64          `json:"finishedAt"`     // Unix timestamp of when the check was finished, 0 if still running
+	IsRunning      bool           `json:"isRunning"`      // True, if the check has not finished yet
+	Action         string         `json:"action"`         // The action that was applied to the findings, see ScrubAction constants
+	CheckedObjects int            `json:"checkedObjects"` // The number of stored objects that were verified
+	CheckedBytes   int64          `json:"checkedBytes"`   // The number of bytes that were read for verification
+	Error          string         `json:"error"`          // Set, if the check could not be completed
+	Findings       []ScrubFinding `json:"findings"`       // All problems that were found
+}
+
+// ScrubFinding is a stored object that is either missing, corrupted or not referenced by any file
+type ScrubFinding struct {
+	Type       string   `json:"type"`       // The type of the problem, see ScrubFinding constants
+	Bucket     string   `json:"bucket"`     // The bucket the object is stored in, empty for the local data directory
+	Key        string   `json:"key"`        // The filename in the data directory or the key of the object
+	Size       int64    `json:"size"`       // The size of the stored object in bytes
+	FileIds    []string `json:"fileIds"`    // The IDs of all files that reference the object, empty for orphans
+	Details    string   `json:"details"`    // Additional information about the problem
+	Resolution string   `json:"resolution"` // Empty if unresolved, otherwise ScrubResolutionQuarantined or ScrubResolutionDeleted
+}
+
+// IsLocalStorage returns true if the object is stored in the local data directory
+func (f *ScrubFinding) IsLocalStorage() bool {
+	return f.Bucket == ""
+}
+
+const (
+	// ScrubFindingMissing is a stored object that is referenced by a file, but does not exist
+	ScrubFindingMissing = "missing"
+	// ScrubFindingHashMismatch is a stored object whose content does not match the SHA1 of the file
+	ScrubFindingHashMismatch = "hashMismatch"
+	// ScrubFindingDecryptionFailed is an encrypted object that cannot be decrypted
+	ScrubFindingDecryptionFailed = "decryptionFailed"
+	// ScrubFindingReadError is a stored object that could not be read
+	ScrubFindingReadError = "readError"
+	// ScrubFindingOrphan is a stored object that is not referenced by any file
+	ScrubFindingOrphan = "orphan"
+)
+
+const (
+	// ScrubActionNone only reports the findings
+	ScrubActionNone = "none"
+	// ScrubActionQuarantine moves orphaned and corrupted objects to the quarantine
+	ScrubActionQuarantine = "quarantine"
+	// ScrubActionDelete deletes orphaned objects and moves corrupted objects to the quarantine
+	ScrubActionDelete = "delete"
+)
+
+const (
+	// ScrubResolutionQuarantined is set if the object was moved to the quarantine
+	ScrubResolutionQuarantined = "quarantined"
+	// ScrubResolutionDeleted is set if the object was deleted
+	ScrubResolutionDeleted = "deleted"
+)
+
+// IsValidScrubAction returns true if action is one of the ScrubAction constants
+func IsValidScrubAction(action string) bool {
+	return action == ScrubActionNone || action == ScrubActionQuarantine || action == ScrubActionDelete
+}
@@ -1,8 +1,26 @@
 package models
 
+import "strings"
+
 // Session contains cookie parameter
 type Session struct {
-	RenewAt    int64 `redis:"renew_at"`
-	ValidUntil int64 `redis:"valid_until"`
-	UserId     int   `redis:"user_id"`
+	RenewAt       int64  `redis:"renew_at"`
+	ValidUntil    int64  `redis:"valid_until"`
+	UserId        int    `redis:"user_id"`        // The ID of the logged-in user. 0 for a recipient session
+	Groups        string `redis:"groups"`         // Newline-separated list of the groups sent by the OAuth provider
+	RecipientName string `redis:"recipient_name"` // If set, the session only grants access to files that require a login, for a user that is not registered
+}
+
+// GetGroups returns the groups of the user as a slice
+func (s *Session) GetGroups() []string {
+	if s.Groups == "" {
+		return []string{}
+	}
+	return strings.Split(s.Groups, "\n")
+}
+
+// IsRecipientSession returns true if the session was created for a user that is not registered
+// and that may only access files that require a login
+func (s *Session) IsRecipientSession() bool {
+	return s.RecipientName != ""
 }
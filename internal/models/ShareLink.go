@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// ShareLink is an additional public link to a file. Every link has its own limits and password
+// and can be revoked without affecting the file or other links
+type ShareLink struct {
+	Id                 string `json:"id" redis:"id"`                                 // The public ID of the link
+	FileId             string `json:"fileid" redis:"fileid"`                         // The ID of the file the link points to
+	Name               string `json:"name" redis:"name"`                             // An optional label, e.g. the recipient of the link
+	PasswordHash       string `json:"passwordhash" redis:"passwordhash"`             // The hash of the password (if the link is password-protected)
+	UserId             int    `json:"userid" redis:"userid"`                         // The user ID of the creator
+	ExpireAt           int64  `json:"expireat" redis:"expireat"`                     // UTC timestamp of the link expiry
+	CreationDate       int64  `json:"creationdate" redis:"creationdate"`             // The timestamp of the link creation
+	DownloadsRemaining int    `json:"downloadsremaining" redis:"downloadsremaining"` // The remaining downloads for this link
+	DownloadCount      int    `json:"downloadcount" redis:"downloadcount"`           // The number of times the file has been downloaded with this link
+	UnlimitedDownloads bool   `json:"unlimiteddownloads" redis:"unlimiteddownloads"` // True if the downloads are not limited
+	UnlimitedTime      bool   `json:"unlimitedtime" redis:"unlimitedtime"`           // True if the link does not expire
+}
+
+// ShareLinkApiOutput is the public representation of a share link, hiding sensitive information
+type ShareLinkApiOutput struct {
+	Id                  string `json:"Id"`                  // The public ID of the link
+	FileId              string `json:"FileId"`              // The ID of the file the link points to
+	Name                string `json:"Name"`                // An optional label, e.g. the recipient of the link
+	UrlDownload         string `json:"UrlDownload"`         // The public URL of the link
+	ExpireAt            int64  `json:"ExpireAt"`            // UTC timestamp of the link expiry
+	CreationDate        int64  `json:"CreationDate"`        // UTC timestamp of the link creation
+	DownloadsRemaining  int    `json:"DownloadsRemaining"`  // The remaining downloads for this link
+	DownloadCount       int    `json:"DownloadCount"`       // The number of times the file has been downloaded with this link
+	UnlimitedDownloads  bool   `json:"UnlimitedDownloads"`  // True if the downloads are not limited
+	UnlimitedTime       bool   `json:"UnlimitedTime"`       // True if the link does not expire
+	IsPasswordProtected bool   `json:"IsPasswordProtected"` // True if a password has to be entered before downloading
+	UserId              int    `json:"UserId"`              // The user ID of the creator
+}
+
+// IsExpired returns true if the link has expired or no downloads are remaining
+func (s *ShareLink) IsExpired() bool {
+	return (!s.UnlimitedTime && s.ExpireAt < time.Now().Unix()) ||
+		(!s.UnlimitedDownloads && s.DownloadsRemaining < 1)
+}
+
+// IsPasswordProtected returns true if a password has to be entered before downloading
+func (s *ShareLink) IsPasswordProtected() bool {
+	return s.PasswordHash != ""
+}
+
+// GetUrl returns the public URL of the link
+func (s *ShareLink) GetUrl(serverUrl string) string {
+	return serverUrl + "s?id=" + s.Id
+}
+
+// ToApiOutput returns the public representation of the link
+func (s *ShareLink) ToApiOutput(serverUrl string) ShareLinkApiOutput {
+	return ShareLinkApiOutput{
+		Id:                  s.Id,
+		FileId:              s.FileId,
+		Name:                s.Name,
+		UrlDownload:         s.GetUrl(serverUrl),
+		ExpireAt:            s.ExpireAt,
+		CreationDate:        s.CreationDate,
+		DownloadsRemaining:  s.DownloadsRemaining,
+		DownloadCount:       s.DownloadCount,
+		UnlimitedDownloads:  s.UnlimitedDownloads,
+		UnlimitedTime:       s.UnlimitedTime,
+		IsPasswordProtected: s.IsPasswordProtected(),
+		UserId:              s.UserId,
+	}
+}
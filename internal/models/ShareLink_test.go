@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestShareLink_IsExpired(t *testing.T) {
+	link := ShareLink{UnlimitedTime: true, UnlimitedDownloads: true}
+	test.IsEqualBool(t, link.IsExpired(), false)
+	link.UnlimitedTime = false
+	test.IsEqualBool(t, link.IsExpired(), true)
+	link.ExpireAt = time.Now().Add(time.Hour).Unix()
+	test.IsEqualBool(t, link.IsExpired(), false)
+	link.UnlimitedDownloads = false
+	test.IsEqualBool(t, link.IsExpired(), true)
+	link.DownloadsRemaining = 1
+	test.IsEqualBool(t, link.IsExpired(), false)
+}
+
+func TestShareLink_ToApiOutput(t *testing.T) {
+	link := ShareLink{Id: "link1", FileId: "file1", Name: "Alice", PasswordHash: "hash", UserId: 3, DownloadsRemaining: 2}
+	output := link.ToApiOutput("http://gokapi.local/")
+	test.IsEqualString(t, output.UrlDownload, "http://gokapi.local/s?id=link1")
+	test.IsEqualString(t, output.FileId, "file1")
+	test.IsEqualString(t, output.Name, "Alice")
+	test.IsEqualBool(t, output.IsPasswordProtected, true)
+	test.IsEqualInt(t, output.UserId, 3)
+	test.IsEqualInt(t, output.DownloadsRemaining, 2)
+	link.PasswordHash = ""
+	test.IsEqualBool(t, link.ToApiOutput("").IsPasswordProtected, false)
+}
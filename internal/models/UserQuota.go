@@ -0,0 +1,94 @@
+package models
+
+// QuotaUseDefault can be set for a limit of UserQuota to use the server default instead
+const QuotaUseDefault = -1
+
+// UserQuota contains the upload limits of a user. For each limit, 0 is unlimited and
+// QuotaUseDefault uses the default value set for the server
+type UserQuota struct {
+	UserId        int `json:"userId" redis:"userid"`               // The ID of the user
+	MaxStorageMb  int `json:"maxStorageMb" redis:"maxstoragemb"`   // The maximum size of all stored files in MB
+	MaxFiles      int `json:"maxFiles" redis:"maxfiles"`           // The maximum number of stored files
+	MaxExpiryDays int `json:"maxExpiryDays" redis:"maxexpirydays"` // The maximum number of days until a new upload expires
+	MaxDownloads  int `json:"maxDownloads" redis:"maxdownloads"`   // The maximum number of allowed downloads of a new upload
+}
+
+// QuotaUsage contains the storage that is currently used by a user
+type QuotaUsage struct {
+	StorageBytes int64 `json:"storageBytes"` // The size of all stored files in bytes
+	Files        int   `json:"files"`        // The number of stored files
+}
+
+// NewUserQuotaDefault returns a quota for the user that uses the server default for all limits
+func NewUserQuotaDefault(userId int) UserQuota {
+	return UserQuota{
+		UserId:        userId,
+		MaxStorageMb:  QuotaUseDefault,
+		MaxFiles:      QuotaUseDefault,
+		MaxExpiryDays: QuotaUseDefault,
+		MaxDownloads:  QuotaUseDefault,
+	}
+}
+
+// IsDefault returns true, if the server default is used for all limits
+func (q *UserQuota) IsDefault() bool {
+	return q.MaxStorageMb == QuotaUseDefault && q.MaxFiles == QuotaUseDefault &&
+		q.MaxExpiryDays == QuotaUseDefault && q.MaxDownloads == QuotaUseDefault
+}
+
+// WithDefaults returns a copy of the quota, where all limits set to QuotaUseDefault
+// are replaced with the limits of defaults
+func (q *UserQuota) WithDefaults(defaults UserQuota) UserQuota {
+	result := *q
+	if result.MaxStorageMb == QuotaUseDefault {
+		result.MaxStorageMb = defaults.MaxStorageMb
+	}
+	if result.MaxFiles == QuotaUseDefault {
+		result.MaxFiles = defaults.MaxFiles
+	}
+	if result.MaxExpiryDays == QuotaUseDefault {
+		result.MaxExpiryDays = defaults.MaxExpiryDays
+	}
+	if result.MaxDownloads == QuotaUseDefault {
+		result.MaxDownloads = defaults.MaxDownloads
+	}
+	return result
+}
+
+// ExceedsStorage returns true, if storing an additional file with the given size would exceed the storage limit
+func (q *UserQuota) ExceedsStorage(usage QuotaUsage, additionalBytes int64) bool {
+	if q.MaxStorageMb <= 0 {
+		return false
+	}
+	return usage.StorageBytes+additionalBytes > int64(q.MaxStorageMb)*1024*1024
+}
+
+// ExceedsFiles returns true, if storing an additional file would exceed the file count limit
+func (q *UserQuota) ExceedsFiles(usage QuotaUsage) bool {
+	if q.MaxFiles <= 0 {
+		return false
+	}
+	return usage.Files >= q.MaxFiles
+}
+
+// ExceedsExpiry returns true, if a file expiring at expireAt exceeds the expiry limit.
+// Files without an expiry always exceed an existing limit
+func (q *UserQuota) ExceedsExpiry(expireAt int64, unlimitedTime bool, timeNow int64) bool {
+	if q.MaxExpiryDays <= 0 {
+		return false
+	}
+	if unlimitedTime {
+		return true
+	}
+	// One minute is added to allow for the delay between requesting and processing the upload
+	return expireAt > timeNow+int64(q.MaxExpiryDays)*24*60*60+60
+}
+
+// ExceedsDownloads returns true, if the number of allowed downloads exceeds the download limit.
+// Files without a download limit always exceed an existing limit
+func (q *UserQuota) ExceedsDownloads(allowedDownloads int, unlimitedDownloads bool) bool {
+	if q.MaxDownloads <= 0 {
+		return false
+	}
+	return unlimitedDownloads || allowedDownloads > q.MaxDownloads
+}
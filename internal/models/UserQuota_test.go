@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestUserQuotaWithDefaults(t *testing.T) {
+	quota := NewUserQuotaDefault(5)
+	test.IsEqualInt(t, quota.UserId, 5)
+	test.IsEqualBool(t, quota.IsDefault(), true)
+	defaults := UserQuota{MaxStorageMb: 100, MaxFiles: 10, MaxExpiryDays: 7, MaxDownloads: 3}
+	result := quota.WithDefaults(defaults)
+	test.IsEqualInt(t, result.UserId, 5)
+	test.IsEqualInt(t, result.MaxStorageMb, 100)
+	test.IsEqualInt(t, result.MaxFiles, 10)
+	test.IsEqualInt(t, result.MaxExpiryDays, 7)
+	test.IsEqualInt(t, result.MaxDownloads, 3)
+
+	quota.MaxStorageMb = 0
+	quota.MaxDownloads = 20
+	test.IsEqualBool(t, quota.IsDefault(), false)
+	result = quota.WithDefaults(defaults)
+	test.IsEqualInt(t, result.MaxStorageMb, 0)
+	test.IsEqualInt(t, result.MaxFiles, 10)
+	test.IsEqualInt(t, result.MaxDownloads, 20)
+}
+
+func TestUserQuotaExceeds(t *testing.T) {
+	quota := UserQuota{}
+	usage := QuotaUsage{StorageBytes: 1024 * 1024 * 1024, Files: 1000}
+	test.IsEqualBool(t, quota.ExceedsStorage(usage, 1), false)
+	test.IsEqualBool(t, quota.ExceedsFiles(usage), false)
+	test.IsEqualBool(t, quota.ExceedsExpiry(0, true, 100), false)
+	test.IsEqualBool(t, quota.ExceedsDownloads(0, true), false)
+
+	quota = UserQuota{MaxStorageMb: 2, MaxFiles: 2, MaxExpiryDays: 1, MaxDownloads: 5}
+	usage = QuotaUsage{StorageBytes: 1024 * 1024, Files: 1}
+	test.IsEqualBool(t, quota.ExceedsStorage(usage, 1024*1024), false)
+	test.IsEqualBool(t, quota.ExceedsStorage(usage, 1024*1024+1), true)
+	test.IsEqualBool(t, quota.ExceedsFiles(usage), false)
+	usage.Files = 2
+	test.IsEqualBool(t, quota.ExceedsFiles(usage), true)
+
+	test.IsEqualBool(t, quota.ExceedsExpiry(100+24*60*60, false, 100), false)
+	test.IsEqualBool(t, quota.ExceedsExpiry(100+2*24*60*60, false, 100), true)
+	test.IsEqualBool(t, quota.ExceedsExpiry(0, true, 100), true)
+	test.IsEqualBool(t, quota.ExceedsDownloads(5, false), false)
+	test.IsEqualBool(t, quota.ExceedsDownloads(6, false), true)
+	test.IsEqualBool(t, quota.ExceedsDownloads(0, true), true)
+}
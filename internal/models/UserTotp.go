@@ -0,0 +1,30 @@
+package models
+
+import "strings"
+
+// UserTotp contains the TOTP two-factor authentication settings of a user
+type UserTotp struct {
+	UserId        int    `json:"userid" redis:"userid"`               // The ID of the user
+	Secret        string `json:"secret" redis:"secret"`               // The base32 encoded shared secret
+	RecoveryCodes string `json:"recoverycodes" redis:"recoverycodes"` // Comma-separated hashes of the unused recovery codes
+	IsConfirmed   bool   `json:"isconfirmed" redis:"isconfirmed"`     // False until the user entered a valid code after enrollment
+	LastUsedStep  int64  `json:"lastusedstep" redis:"lastusedstep"`   // The last time step that was used, so that a code cannot be reused
+}
+
+// IsEnabled returns true, if the enrollment has been completed and a code is required for login
+func (u *UserTotp) IsEnabled() bool {
+	return u.IsConfirmed && u.Secret != ""
+}
+
+// GetRecoveryCodeHashes returns the hashes of all unused recovery codes
+func (u *UserTotp) GetRecoveryCodeHashes() []string {
+	if u.RecoveryCodes == "" {
+		return []string{}
+	}
+	return strings.Split(u.RecoveryCodes, ",")
+}
+
+// SetRecoveryCodeHashes stores the hashes of the unused recovery codes
+func (u *UserTotp) SetRecoveryCodeHashes(hashes []string) {
+	u.RecoveryCodes = strings.Join(hashes, ",")
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestUserTotpIsEnabled(t *testing.T) {
+	totp := UserTotp{UserId: 1}
+	test.IsEqualBool(t, totp.IsEnabled(), false)
+	totp.Secret = "JBSWY3DPEHPK3PXP"
+	test.IsEqualBool(t, totp.IsEnabled(), false)
+	totp.IsConfirmed = true
+	test.IsEqualBool(t, totp.IsEnabled(), true)
+}
+
+func TestUserTotpRecoveryCodes(t *testing.T) {
+	totp := UserTotp{}
+	test.IsEqualInt(t, len(totp.GetRecoveryCodeHashes()), 0)
+	totp.SetRecoveryCodeHashes([]string{"hash1", "hash2"})
+	test.IsEqualString(t, totp.RecoveryCodes, "hash1,hash2")
+	hashes := totp.GetRecoveryCodeHashes()
+	test.IsEqualInt(t, len(hashes), 2)
+	test.IsEqualString(t, hashes[1], "hash2")
+	totp.SetRecoveryCodeHashes([]string{})
+	test.IsEqualString(t, totp.RecoveryCodes, "")
+}
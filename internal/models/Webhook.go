@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	// WebhookEventUpload is sent after a file was uploaded
+	WebhookEventUpload = "file.upload"
+	// WebhookEventDownload is sent after a download was started
+	WebhookEventDownload = "file.download"
+	// WebhookEventDelete is sent after a file was deleted
+	WebhookEventDelete = "file.delete"
+	// WebhookEventRestore is sent after the pending deletion of a file was cancelled
+	WebhookEventRestore = "file.restore"
+	// WebhookEventReplace is sent after the content of a file was replaced
+	WebhookEventReplace = "file.replace"
+	// WebhookEventFileRequestUpload is sent after a file was uploaded to a file request
+	WebhookEventFileRequestUpload = "filerequest.upload"
+	// WebhookEventUserCreate is sent after a user was created
+	WebhookEventUserCreate = "user.create"
+	// WebhookEventUserEdit is sent after a user was modified
+	WebhookEventUserEdit = "user.edit"
+	// WebhookEventUserDelete is sent after a user was deleted
+	WebhookEventUserDelete = "user.delete"
+)
+
+// WebhookEvents contains all events a webhook can subscribe to
+var WebhookEvents = []string{WebhookEventUpload, WebhookEventDownload, WebhookEventDelete, WebhookEventRestore,
+	WebhookEventReplace, WebhookEventFileRequestUpload, WebhookEventUserCreate, WebhookEventUserEdit, WebhookEventUserDelete}
+
+// Webhook contains the target and the subscribed events of a single webhook
+type Webhook struct {
+	Id           string `json:"id" redis:"id"`                     // The internal ID of the webhook
+	Url          string `json:"url" redis:"url"`                   // The URL the events are sent to
+	Secret       string `json:"secret,omitempty" redis:"secret"`   // The secret used for the HMAC signature. Only returned on creation
+	Events       string `json:"events" redis:"events"`             // Comma-separated list of subscribed events
+	UserId       int    `json:"userid" redis:"userid"`             // The user ID of the creator
+	CreationDate int64  `json:"creationdate" redis:"creationdate"` // The timestamp of the webhook creation
+}
+
+// WebhookDelivery is a single event that is queued to be sent to a webhook
+type WebhookDelivery struct {
+	Id          string `json:"id" redis:"id"`                   // The internal ID of the delivery, also sent to the receiver
+	WebhookId   string `json:"webhookid" redis:"webhookid"`     // The ID of the webhook the event is sent to
+	Event       string `json:"event" redis:"event"`             // The name of the event
+	Payload     string `json:"payload" redis:"payload"`         // The JSON payload that is sent
+	Attempts    int    `json:"attempts" redis:"attempts"`       // The number of failed delivery attempts
+	NextAttempt int64  `json:"nextattempt" redis:"nextattempt"` // The timestamp after which the next attempt is made
+	LastError   string `json:"lasterror" redis:"lasterror"`     // The error of the last failed attempt
+}
+
+// IsSubscribed returns true if the webhook is subscribed to the given event
+func (w *Webhook) IsSubscribed(event string) bool {
+	return slices.Contains(w.GetEvents(), event)
+}
+
+// GetEvents returns all subscribed events as a slice
+func (w *Webhook) GetEvents() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// SetEvents parses a comma-separated list of events. "all" subscribes to all events.
+// Returns an error if an invalid event was passed
+func (w *Webhook) SetEvents(events string) error {
+	var result []string
+	for _, event := range strings.Split(events, ",") {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event == "" {
+			continue
+		}
+		if event == "all" {
+			w.Events = strings.Join(WebhookEvents, ",")
+			return nil
+		}
+		if !slices.Contains(WebhookEvents, event) {
+			return errors.New("invalid event: " + event)
+		}
+		if !slices.Contains(result, event) {
+			result = append(result, event)
+		}
+	}
+	if len(result) == 0 {
+		return errors.New("no events provided")
+	}
+	w.Events = strings.Join(result, ",")
+	return nil
+}
+
+// SetUrl validates and sets the target URL. Only http and https URLs are allowed
+func (w *Webhook) SetUrl(target string) error {
+	parsedUrl, err := url.Parse(target)
+	if err != nil {
+		return errors.New("invalid URL provided")
+	}
+	if (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return errors.New("invalid URL provided, only http and https URLs are supported")
+	}
+	w.Url = parsedUrl.String()
+	return nil
+}
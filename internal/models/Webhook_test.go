@@ -0,0 +1,44 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestWebhook_SetEvents(t *testing.T) {
+	webhook := Webhook{}
+	test.IsEqualInt(t, len(webhook.GetEvents()), 0)
+	test.IsEqualBool(t, webhook.IsSubscribed(WebhookEventUpload), false)
+
+	err := webhook.SetEvents(" File.Upload, file.delete,file.upload,")
+	test.IsNil(t, err)
+	test.IsEqualString(t, webhook.Events, "file.upload,file.delete")
+	test.IsEqualBool(t, webhook.IsSubscribed(WebhookEventUpload), true)
+	test.IsEqualBool(t, webhook.IsSubscribed(WebhookEventDelete), true)
+	test.IsEqualBool(t, webhook.IsSubscribed(WebhookEventDownload), false)
+
+	err = webhook.SetEvents("all")
+	test.IsNil(t, err)
+	test.IsEqualString(t, webhook.Events, strings.Join(WebhookEvents, ","))
+
+	err = webhook.SetEvents("file.upload,invalid")
+	test.IsEqualString(t, err.Error(), "invalid event: invalid")
+	test.IsEqualString(t, webhook.Events, strings.Join(WebhookEvents, ","))
+	err = webhook.SetEvents(" , ")
+	test.IsEqualString(t, err.Error(), "no events provided")
+}
+
+func TestWebhook_SetUrl(t *testing.T) {
+	webhook := Webhook{}
+	test.IsNil(t, webhook.SetUrl("https://example.com/hook?token=1"))
+	test.IsEqualString(t, webhook.Url, "https://example.com/hook?token=1")
+	test.IsNil(t, webhook.SetUrl("http://127.0.0.1:8080"))
+	test.IsEqualString(t, webhook.Url, "http://127.0.0.1:8080")
+	test.IsNotNil(t, webhook.SetUrl("ftp://example.com"))
+	test.IsNotNil(t, webhook.SetUrl("https://"))
+	test.IsNotNil(t, webhook.SetUrl("example.com"))
+	test.IsNotNil(t, webhook.SetUrl("://invalid"))
+	test.IsEqualString(t, webhook.Url, "http://127.0.0.1:8080")
+}
@@ -22,16 +22,21 @@ import (
 
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/database/leader"
 	"github.com/forceu/gokapi/internal/encryption"
 	"github.com/forceu/gokapi/internal/environment"
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/logging/metrics"
 	"github.com/forceu/gokapi/internal/logging/serverstats"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/chunking/streamupload"
 	"github.com/forceu/gokapi/internal/storage/filesystem"
 	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/preview"
 	"github.com/forceu/gokapi/internal/storage/processingstatus"
+	"github.com/forceu/gokapi/internal/webserver/api/mutex/apimutex"
 	"github.com/forceu/gokapi/internal/webserver/downloadstatus"
 	"github.com/forceu/gokapi/internal/webserver/headers"
 	"github.com/forceu/gokapi/internal/webserver/sse"
@@ -47,6 +52,9 @@ var ErrorChunkTooSmall = errors.New("chunk is too small")
 // ErrorReplaceE2EFile is caused when an end-to-end encrypted file is replaced
 var ErrorReplaceE2EFile = errors.New("end-to-end encrypted files cannot be replaced")
 
+// ErrorPublishAfterExpiry is raised when a file would expire before it is published
+var ErrorPublishAfterExpiry = errors.New("the file would expire before it is published")
+
 // ErrorFileNotFound is raised when an invalid ID is passed or the file has expired
 var ErrorFileNotFound = errors.New("file not found")
 
@@ -60,6 +68,10 @@ func NewFile(fileContent io.Reader, fileHeader *multipart.FileHeader, userId int
 	if !isAllowedFileSize(fileHeader.Size) {
 		return models.File{}, ErrorFileTooLarge
 	}
+	err := checkUploadQuota(userId, fileHeader.Size, uploadRequest)
+	if err != nil {
+		return models.File{}, err
+	}
 	var hasBeenRenamed bool
 	reader, hash, tempFile, encInfo := generateHashAndEncrypt(fileContent, fileHeader)
 	defer deleteTempFile(tempFile, &hasBeenRenamed)
@@ -81,7 +93,8 @@ func NewFile(fileContent io.Reader, fileHeader *multipart.FileHeader, userId int
 				return models.File{}, err
 			}
 		}
-		database.SaveMetaData(file)
+		runPostProcessingOnStoredFile("", &file, userId)
+		saveNewFile(file)
 		return file, nil
 	}
 
@@ -104,7 +117,8 @@ func NewFile(fileContent io.Reader, fileHeader *multipart.FileHeader, userId int
 			err = os.Rename(tempFile.Name(), dataDir+"/"+file.SHA1)
 			helper.Check(err)
 			hasBeenRenamed = true
-			database.SaveMetaData(file)
+			runPostProcessingOnStoredFile("", &file, userId)
+			saveNewFile(file)
 			return file, nil
 		}
 		destinationFile, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
@@ -117,7 +131,8 @@ func NewFile(fileContent io.Reader, fileHeader *multipart.FileHeader, userId int
 			return models.File{}, err
 		}
 	}
-	database.SaveMetaData(file)
+	runPostProcessingOnStoredFile("", &file, userId)
+	saveNewFile(file)
 	return file, nil
 }
 
@@ -141,23 +156,16 @@ func validateChunkInfo(file *os.File, fileHeader chunking.FileHeader) error {
 	return nil
 }
 
-// GetUploadCounts returns the currently uploaded files per user
-func GetUploadCounts() map[int]int {
-	result := make(map[int]int)
-	timeNow := time.Now().Unix()
-	files := database.GetAllMetadata()
-	for _, file := range files {
-		if !IsExpiredFile(file, timeNow) {
-			result[file.UserId] = result[file.UserId] + 1
-		}
-	}
-	return result
-}
-
 // NewFileFromChunk creates a new file in the system after a chunk upload has fully completed. If a file with the same sha1 hash
 // already exists, it is deduplicated. This function gathers information about the file, creates an ID and saves
 // it into the global configuration.
 func NewFileFromChunk(chunkId string, fileHeader chunking.FileHeader, userId int, uploadRequest models.UploadParameters) (models.File, error) {
+	if streamupload.Exists(chunkId) {
+		return newFileFromStream(chunkId, fileHeader, userId, uploadRequest)
+	}
+	if streamupload.IsOnOtherInstance(chunkId) {
+		return models.File{}, streamupload.ErrorOtherInstance
+	}
 	file, err := chunking.GetFileByChunkId(chunkId)
 	if err != nil {
 		return models.File{}, err
@@ -168,6 +176,11 @@ func NewFileFromChunk(chunkId string, fileHeader chunking.FileHeader, userId int
 		_ = chunking.DeleteChunk(chunkId)
 		return models.File{}, err
 	}
+	err = checkUploadQuota(userId, fileHeader.Size, uploadRequest)
+	if err != nil {
+		_ = chunking.DeleteChunk(chunkId)
+		return models.File{}, err
+	}
 
 	processingstatus.Set(chunkId, processingstatus.StatusHashingOrEncrypting, models.File{}, userId, nil)
 	hash, err := getChunkFileHash(file, uploadRequest.IsEndToEndEncrypted)
@@ -175,6 +188,7 @@ func NewFileFromChunk(chunkId string, fileHeader chunking.FileHeader, userId int
 		return models.File{}, err
 	}
 	metaData := createNewMetaData(hash, fileHeader, userId, uploadRequest)
+	runPostProcessing(chunkId, &metaData, userId, file.Name())
 	fileExists := FileExists(metaData, configuration.Get().DataDir)
 	if fileExists {
 		fileExists = copyEncryptionInfo(&metaData)
@@ -212,11 +226,103 @@ func NewFileFromChunk(chunkId string, fileHeader chunking.FileHeader, userId int
 			return models.File{}, err
 		}
 	}
-	database.SaveMetaData(metaData)
-	processingstatus.Set(chunkId, processingstatus.StatusFinished, metaData, userId, nil)
+	saveNewFile(metaData)
+	setFinishedStatus(chunkId, metaData, userId)
 	return metaData, nil
 }
 
+// newFileFromStream creates a new file after all chunks of an upload have been streamed to the cloud storage.
+// The content has already been hashed and encrypted while it was received, therefore it only has to be
+// moved from its temporary object to the final location
+func newFileFromStream(chunkId string, fileHeader chunking.FileHeader, userId int, uploadRequest models.UploadParameters) (models.File, error) {
+	if !isAllowedFileSize(fileHeader.Size) {
+		streamupload.Abort(chunkId)
+		return models.File{}, ErrorFileTooLarge
+	}
+	err := checkUploadQuota(userId, fileHeader.Size, uploadRequest)
+	if err != nil {
+		streamupload.Abort(chunkId)
+		return models.File{}, err
+	}
+
+	processingstatus.Set(chunkId, processingstatus.StatusHashingOrEncrypting, models.File{}, userId, nil)
+	result, err := streamupload.Finish(chunkId)
+	if err != nil {
+		return models.File{}, err
+	}
+	tempObject := models.File{AwsBucket: result.Bucket, SHA1: result.Key}
+	if result.Size != fileHeader.Size {
+		_, _ = aws.DeleteObject(tempObject)
+		return models.File{}, errors.New("total filesize does not match")
+	}
+	hash := result.Hash
+	if uploadRequest.IsEndToEndEncrypted {
+		hash = "e2e-" + helper.GenerateRandomString(20)
+	}
+	metaData := createNewMetaData(hash, fileHeader, userId, uploadRequest)
+	metaData.Encryption = result.Encryption
+
+	processingstatus.Set(chunkId, processingstatus.StatusUploading, models.File{}, userId, nil)
+	if metaData.IsLocalStorage() {
+		err = moveStreamToLocalStorage(tempObject, &metaData)
+		if err == nil {
+			_, err = aws.DeleteObject(tempObject)
+		}
+	} else {
+		err = moveStreamToFinalObject(tempObject, result.StoredSize, &metaData)
+	}
+	if err != nil {
+		_, _ = aws.DeleteObject(tempObject)
+		return models.File{}, err
+	}
+	runPostProcessingOnStoredFile(chunkId, &metaData, userId)
+	saveNewFile(metaData)
+	setFinishedStatus(chunkId, metaData, userId)
+	return metaData, nil
+}
+
+// moveStreamToFinalObject moves the temporary object to the key of the file hash. If an object with the
+// same hash exists already, the temporary object is deleted instead
+func moveStreamToFinalObject(tempObject models.File, storedSize int64, metaData *models.File) error {
+	if FileExists(*metaData, configuration.Get().DataDir) {
+		previousEncryption, ok := getEncInfoFromExistingFile(metaData.SHA1)
+		if ok {
+			metaData.Encryption = previousEncryption
+			_, err := aws.DeleteObject(tempObject)
+			return err
+		}
+	}
+	return aws.MoveObject(tempObject.AwsBucket, tempObject.SHA1, metaData.SHA1, storedSize)
+}
+
+// moveStreamToLocalStorage downloads the temporary object, if the file has to be stored locally,
+// e.g. because pictures are always stored on the local disk
+func moveStreamToLocalStorage(tempObject models.File, metaData *models.File) error {
+	tempFile, err := os.CreateTemp(configuration.Get().DataDir, "upload")
+	if err != nil {
+		return err
+	}
+	err = aws.Stream(tempFile, tempObject)
+	if err != nil {
+		removeTempFile(tempFile)
+		return err
+	}
+	if FileExists(*metaData, configuration.Get().DataDir) {
+		previousEncryption, ok := getEncInfoFromExistingFile(metaData.SHA1)
+		if ok {
+			metaData.Encryption = previousEncryption
+			removeTempFile(tempFile)
+			return nil
+		}
+	}
+	return filesystem.GetLocal().MoveToFilesystem(tempFile, *metaData)
+}
+
+func removeTempFile(file *os.File) {
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+}
+
 // copyEncryptionInfo copies encryption info from an existing file,
 // if possible. If not possible due to incompatible encryption level,
 // the old file is removed.
@@ -311,6 +417,10 @@ func createNewMetaData(hash string, fileHeader chunking.FileHeader, userId int,
 		PasswordHash:       configuration.HashPassword(params.Password, false, ""),
 		UserId:             userId,
 		UploadRequestId:    params.FileRequestId,
+		PublishAt:          params.PublishAt,
+		RequireLogin:       params.RequireLogin,
+		AllowedUsers:       params.AllowedUsers,
+		AllowedGroups:      params.AllowedGroups,
 	}
 	if params.IsEndToEndEncrypted {
 		file.Encryption = models.EncryptionInfo{IsEndToEndEncrypted: true, IsEncrypted: true}
@@ -369,8 +479,9 @@ const (
 
 // ReplaceFile replaces the file content of fileId with the content of newFileContentId
 // If delete is true, the NEW file will be deleted.
+// The previous content is kept as a version, if versioning is enabled.
 // Replacing e2e encrypted files is NOT possible
-func ReplaceFile(fileId, newFileContentId string, delete bool) (models.File, error) {
+func ReplaceFile(fileId, newFileContentId string, delete bool, userId int) (models.File, error) {
 	file, ok := GetFile(fileId)
 	if !ok {
 		return models.File{}, ErrorFileNotFound
@@ -383,6 +494,7 @@ func ReplaceFile(fileId, newFileContentId string, delete bool) (models.File, err
 		return models.File{}, ErrorReplaceE2EFile
 	}
 
+	saveVersion(file, userId)
 	file.Name = newFileContent.Name
 	file.Size = newFileContent.Size
 	file.SHA1 = newFileContent.SHA1
@@ -391,6 +503,7 @@ func ReplaceFile(fileId, newFileContentId string, delete bool) (models.File, err
 	file.SizeBytes = newFileContent.SizeBytes
 	file.Encryption = newFileContent.Encryption
 	database.SaveMetaData(file)
+	pruneVersions(file.Id)
 	if delete {
 		DeleteFile(newFileContent.Id, false)
 	}
@@ -496,6 +609,26 @@ func generateHashAndEncrypt(fileContent io.Reader, fileHeader *multipart.FileHea
 	return tempFile, hash.Sum(nil), tempFile, encInfo
 }
 
+// IsStreamUploadEnabled returns true if chunked uploads are streamed directly to the cloud storage,
+// instead of being assembled on the local disk first
+func IsStreamUploadEnabled() bool {
+	return aws.IsStreamUploadEnabled()
+}
+
+// NewStreamedChunk passes a chunk to an upload that is streamed directly to the cloud storage
+func NewStreamedChunk(chunkContent io.Reader, fileHeader *multipart.FileHeader, info chunking.ChunkInfo, maxAllowedSize int64) error {
+	return streamupload.AddChunk(chunkContent, fileHeader.Size, info, maxAllowedSize, isEncryptionRequested())
+}
+
+// DeleteChunk deletes the chunk file or aborts the streamed upload of the given chunk ID
+func DeleteChunk(chunkId string) error {
+	if streamupload.Exists(chunkId) {
+		streamupload.Abort(chunkId)
+		return nil
+	}
+	return chunking.DeleteChunk(chunkId)
+}
+
 func isEncryptionRequested() bool {
 	switch configuration.Get().Encryption.Level {
 	case encryption.NoEncryption:
@@ -535,7 +668,7 @@ func IsAbleHotlink(file models.File) bool {
 	if file.RequiresClientDecryption() {
 		return false
 	}
-	if file.PasswordHash != "" {
+	if file.PasswordHash != "" || file.RequireLogin {
 		return false
 	}
 	if strings.Contains(strings.ToLower(file.ContentType), "image/svg") {
@@ -573,7 +706,7 @@ func isVideoFile(filename, contentType string) bool {
 	return helper.IsInArray(videoFileExtensions, extension)
 }
 
-// GetFile gets the file by id. Returns (empty File, false) if invalid / expired file
+// GetFile gets the file by id. Returns (empty File, false) if invalid / expired / quarantined file
 // or (file, true) if valid file
 func GetFile(id string) (models.File, bool) {
 	var emptyResult = models.File{}
@@ -584,7 +717,7 @@ func GetFile(id string) (models.File, bool) {
 	if !ok {
 		return emptyResult, false
 	}
-	if file.IsPendingForDeletion() {
+	if file.IsPendingForDeletion() || file.IsHeld() {
 		return emptyResult, false
 	}
 	if IsExpiredFile(file, time.Now().Unix()) {
@@ -599,6 +732,28 @@ func GetFile(id string) (models.File, bool) {
 	return file, true
 }
 
+// ChangePublishDate sets the time from which on the file can be downloaded, 0 publishes the file immediately.
+// If expiryFromPublish is true, the expiry is moved, so that the file is available for the same duration
+// after the new publication time as before
+func ChangePublishDate(file *models.File, publishAt int64, expiryFromPublish bool) error {
+	if publishAt < 0 {
+		return errors.New("invalid publication time")
+	}
+	if expiryFromPublish && !file.UnlimitedTime {
+		previousStart := max(file.PublishAt, file.UploadDate)
+		newStart := publishAt
+		if newStart == 0 {
+			newStart = time.Now().Unix()
+		}
+		file.ExpireAt = newStart + file.ExpireAt - previousStart
+	}
+	if !file.UnlimitedTime && publishAt >= file.ExpireAt {
+		return ErrorPublishAfterExpiry
+	}
+	file.PublishAt = publishAt
+	return nil
+}
+
 func checkIfValidAws(file models.File) bool {
 	return file.IsLocalStorage() || aws.IsAvailable()
 }
@@ -625,8 +780,23 @@ func ServeFile(file models.File, w http.ResponseWriter, r *http.Request, forceDo
 		database.IncreaseDownloadCount(file.Id, !file.UnlimitedDownloads)
 		go sse.PublishDownloadCount(file)
 	}
-	logging.LogDownload(file, r, configuration.Get().SaveIp)
+	serveFile(file, "", w, r, forceDownload, forceDecryption)
+}
+
+// ServeFileWithShareLink increases the download counter of the share link and serves the file to the browser.
+// The remaining downloads of the file itself are not changed
+func ServeFileWithShareLink(file models.File, link models.ShareLink, w http.ResponseWriter, r *http.Request, forceDownload bool) {
+	database.IncreaseShareLinkDownloadCount(link.Id, !link.UnlimitedDownloads)
+	file.DownloadCount = file.DownloadCount + 1
+	database.IncreaseDownloadCount(file.Id, false)
+	go sse.PublishDownloadCount(file)
+	serveFile(file, link.Id, w, r, forceDownload, false)
+}
+
+func serveFile(file models.File, shareLinkId string, w http.ResponseWriter, r *http.Request, forceDownload, forceDecryption bool) {
+	logging.LogDownload(file, shareLinkId, r, configuration.Get().SaveIp)
 	go serverstats.AddTraffic(uint64(file.SizeBytes))
+	metrics.AddBytesServed(uint64(file.SizeBytes))
 
 	if !file.IsLocalStorage() {
 		// If non-blocking, we are not setting a download complete status as there is no reliable way to
@@ -640,7 +810,7 @@ func ServeFile(file models.File, w http.ResponseWriter, r *http.Request, forceDo
 		}
 		return
 	}
-	fileHandler, _, err := getFileHandler(file, configuration.Get().DataDir)
+	fileHandler, file, err := openLocalContent(file)
 	defer fileHandler.Close()
 	if err != nil {
 		fmt.Println(err)
@@ -656,12 +826,17 @@ func ServeFile(file models.File, w http.ResponseWriter, r *http.Request, forceDo
 	statusId := downloadstatus.SetDownload(file)
 	headers.Write(file, w, forceDownload, false)
 	if file.Encryption.IsEncrypted && !file.RequiresClientDecryption() {
-		err = encryption.DecryptReader(file.Encryption, fileHandler, w)
-		if err != nil {
+		decryptReader, err := encryption.GetDecryptReadSeeker(file.Encryption, fileHandler, file.SizeBytes)
+		if err != nil || !decryptReader.IsAuthentic() {
 			_, _ = w.Write([]byte("Error decrypting file"))
-			fmt.Println(err)
+			if err != nil {
+				fmt.Println(err)
+			}
 			return
 		}
+		// The ETag and the upload date allow clients to resume downloads with If-Range
+		w.Header().Set("ETag", "\""+file.SHA1+"\"")
+		http.ServeContent(w, r, file.Name, time.Unix(file.UploadDate, 0), decryptReader)
 	} else {
 		http.ServeContent(w, r, file.Name, time.Now(), fileHandler)
 	}
@@ -710,8 +885,9 @@ func ServeFilesAsZip(files []models.File, filename string, w http.ResponseWriter
 		}
 		entryWriter, err := zipWriter.CreateHeader(header)
 		helper.Check(err)
-		logging.LogDownload(file, r, saveIp)
+		logging.LogDownload(file, "", r, saveIp)
 		go serverstats.AddTraffic(uint64(file.SizeBytes))
+		metrics.AddBytesServed(uint64(file.SizeBytes))
 		if !file.IsLocalStorage() {
 			statusId := downloadstatus.SetDownload(file)
 			err = aws.Stream(entryWriter, file)
@@ -724,7 +900,7 @@ func ServeFilesAsZip(files []models.File, filename string, w http.ResponseWriter
 			}
 			continue
 		}
-		fileHandler, _, err := getFileHandler(file, configuration.Get().DataDir)
+		fileHandler, file, err := openLocalContent(file)
 		if err != nil {
 			fmt.Println(err)
 			_, _ = w.Write([]byte("Error getting file handler"))
@@ -758,6 +934,37 @@ func ServeFilesAsZip(files []models.File, filename string, w http.ResponseWriter
 	}
 }
 
+// openLocalContent opens the stored content of a local file. If the file is not encrypted, but server-side
+// encryption is enabled, the encryption info is read again while the metadata lock is held. Otherwise, the content
+// could have been encrypted in the meantime by keyrotation.EncryptPlaintextFiles and would be served as plaintext
+func openLocalContent(file models.File) (*os.File, models.File, error) {
+	level := configuration.Get().Encryption.Level
+	if file.Encryption.IsEncrypted || level == encryption.NoEncryption || level == encryption.EndToEndEncryption {
+		fileHandler, _, err := getFileHandler(file, configuration.Get().DataDir)
+		return fileHandler, file, err
+	}
+	apimutex.Lock(apimutex.TypeMetaData, file.Id)
+	defer apimutex.Unlock(apimutex.TypeMetaData, file.Id)
+	file.Encryption = getStoredEncryption(file)
+	fileHandler, _, err := getFileHandler(file, configuration.Get().DataDir)
+	return fileHandler, file, err
+}
+
+// getStoredEncryption returns the encryption info that is currently saved in the database for the content of file.
+// The file might also be a previous version of a file
+func getStoredEncryption(file models.File) models.EncryptionInfo {
+	storedFile, ok := database.GetMetaDataById(file.Id)
+	if ok && storedFile.SHA1 == file.SHA1 && storedFile.AwsBucket == file.AwsBucket {
+		return storedFile.Encryption
+	}
+	for _, version := range database.GetFileVersions(file.Id) {
+		if version.SHA1 == file.SHA1 && version.AwsBucket == file.AwsBucket {
+			return version.Encryption
+		}
+	}
+	return file.Encryption
+}
+
 func getFileHandler(file models.File, dataDir string) (*os.File, int64, error) {
 	fileHandler, err := os.OpenFile(dataDir+"/"+file.SHA1, os.O_RDONLY, 0644)
 	if err != nil {
@@ -794,45 +1001,58 @@ func FileExists(file models.File, dataDir string) bool {
 // CleanUp removes expired files from the config and from the filesystem if they are not referenced by other files anymore
 // Will be called periodically or after a file has been manually deleted in the admin view.
 // If the parameter periodic is true, this function is recursive and calls itself every hour.
+// If multiple instances share the database, the periodic clean-up of the database is only run by the leader
 func CleanUp(periodic bool) {
+	metrics.IncreaseCleanupRuns()
 	downloadstatus.Clean()
+	streamupload.CleanUp()
+	cleanOldTempFiles()
+	if !periodic || leader.IsLeader() {
+		cleanUpDatabase()
+	}
+
+	if periodic {
+		go func() {
+			time.Sleep(time.Hour)
+			CleanUp(periodic)
+		}()
+	}
+}
+
+// cleanUpDatabase removes expired files and invalid entries from the database
+func cleanUpDatabase() {
 	timeNow := time.Now().Unix()
 	wasItemDeleted := false
 	for key, element := range database.GetAllMetadata() {
 		fileExists := FileExists(element, configuration.Get().DataDir)
 		if !fileExists || isExpiredFileWithoutDownload(element, timeNow) || isPendingToBeDeleted(element, timeNow) {
-			deleteFile := true
-			for _, secondLoopElement := range database.GetAllMetadata() {
-				if (element.Id != secondLoopElement.Id) && (element.SHA1 == secondLoopElement.SHA1) {
-					deleteFile = false
-					break
-				}
-			}
+			// Versions of the file are removed afterwards and delete their content themselves
+			deleteFile := !isContentReferenced(element, element.Id)
 			if deleteFile && fileExists {
 				deleteSource(element, configuration.Get().DataDir)
 			}
 			if element.HotlinkId != "" {
 				database.DeleteHotlink(element.HotlinkId)
 			}
+			if fileExists && !element.IsPendingForDeletion() && IsExpiredFile(element, timeNow) {
+				logging.LogExpiry(element)
+			}
 			database.DeleteMetaData(key)
 			wasItemDeleted = true
 		}
 	}
 	if wasItemDeleted {
-		CleanUp(false)
+		cleanUpDatabase()
+		return
 	}
-	cleanOldTempFiles()
 	cleanHotlinks()
 	cleanInvalidApiKeys()
 	cleanInvalidFileRequests()
+	cleanExpiredBundles()
+	cleanShareLinks()
+	cleanFileVersions()
+	logging.CleanUpAuditLog()
 	database.RunGarbageCollection()
-
-	if periodic {
-		go func() {
-			time.Sleep(time.Hour)
-			CleanUp(periodic)
-		}()
-	}
 }
 
 func getUserMap() map[int]models.User {
@@ -884,6 +1104,27 @@ func cleanInvalidFileRequests() {
 	}
 }
 
+// cleanExpiredBundles removes bundles that have expired or whose owner is not a valid user anymore
+func cleanExpiredBundles() {
+	users := getUserMap()
+	for _, bundle := range database.GetAllBundles() {
+		_, exists := users[bundle.UserId]
+		if !exists || bundle.IsExpired() {
+			database.DeleteBundle(bundle.Id)
+		}
+	}
+}
+
+// cleanShareLinks removes share links that have expired or whose file does not exist anymore
+func cleanShareLinks() {
+	for _, link := range database.GetAllShareLinks() {
+		_, fileExists := database.GetMetaDataById(link.FileId)
+		if !fileExists || link.IsExpired() {
+			database.DeleteShareLink(link.Id)
+		}
+	}
+}
+
 // cleanHotlinks removes hotlinks from the database where the file has expired
 func cleanHotlinks() {
 	hotlinks := database.GetAllHotlinks()
@@ -905,7 +1146,8 @@ func cleanOldTempFiles() {
 	for _, file := range tmpfiles {
 		if isOldTempFile(file) {
 			err = os.Remove(configuration.Get().DataDir + "/" + file.Name())
-			if err != nil {
+			// The data directory might be shared with other instances that removed the file already
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
 				fmt.Println(err)
 			}
 		}
@@ -951,7 +1193,7 @@ func isExpiredFileWithoutDownload(file models.File, timeNow int64) bool {
 	return IsExpiredFile(file, timeNow)
 }
 
-// deleteSource removes the source file from the file system or cloud storage.
+// deleteSource removes the source file and its thumbnail from the file system or cloud storage.
 func deleteSource(file models.File, dataDir string) {
 	var err error
 	if !file.IsLocalStorage() {
@@ -962,6 +1204,9 @@ func deleteSource(file models.File, dataDir string) {
 	if err != nil {
 		fmt.Println("Warning, cannot delete file " + file.Id + ": " + err.Error())
 	}
+	if preview.IsThumbnailSupported(file) {
+		preview.DeleteThumbnail(file)
+	}
 }
 
 // DeleteFile is called when an admin requests deletion of a file.
@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"archive/zip"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -373,6 +375,103 @@ func TestNewFile(t *testing.T) {
 	}
 }
 
+func createStreamedTestChunk(t *testing.T, content []byte, filename, contentType string) (string, chunking.FileHeader, models.UploadParameters) {
+	t.Helper()
+	header, request := createRawTestFile(content)
+	chunkId := helper.GenerateRandomString(15)
+	info := chunking.ChunkInfo{
+		TotalFilesizeBytes: int64(len(content)),
+		UUID:               chunkId,
+	}
+	err := NewStreamedChunk(bytes.NewReader(content), &header, info, 1024*1024)
+	test.IsNil(t, err)
+	return chunkId, chunking.FileHeader{
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        header.Size,
+	}, request
+}
+
+func TestNewFileFromStream(t *testing.T) {
+	if !aws.IsMockApi {
+		return
+	}
+	testconfiguration.EnableS3()
+	test.IsEqualBool(t, IsStreamUploadEnabled(), false)
+	ok := aws.Init(models.AwsConfig{StreamUploads: true})
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, IsStreamUploadEnabled(), true)
+	defer testconfiguration.DisableS3()
+
+	content := []byte("This is a file for stream testing purposes")
+	id, header, request := createStreamedTestChunk(t, content, "stream.dat", "text/plain")
+	test.FileDoesNotExist(t, "test/data/chunk-"+id)
+	file, err := NewFileFromChunk(id, header, 99, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.Name, "stream.dat")
+	test.IsEqualString(t, file.Size, "42 B")
+	test.IsEqualString(t, file.SHA1, "fddbdb65bdada014307b311fc05d106aeae9acba")
+	test.IsEqualString(t, file.AwsBucket, "gokapi-test")
+	test.IsEqualBool(t, file.Encryption.IsEncrypted, false)
+	test.FileDoesNotExist(t, "test/data/"+file.SHA1)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqual(t, file, retrievedFile)
+	exists, _, err := aws.FileExists(file)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	tempObject := models.File{AwsBucket: "gokapi-test", SHA1: "gokapi-upload-" + id}
+	exists, _, err = aws.FileExists(tempObject)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	var buffer bytes.Buffer
+	err = aws.Stream(&buffer, file)
+	test.IsNil(t, err)
+	test.IsEqualString(t, buffer.String(), string(content))
+
+	id, header, request = createStreamedTestChunk(t, content, "duplicate.dat", "text/plain")
+	duplicate, err := NewFileFromChunk(id, header, 99, request)
+	test.IsNil(t, err)
+	test.IsEqualString(t, duplicate.SHA1, file.SHA1)
+	test.IsEqualBool(t, duplicate.Id != file.Id, true)
+	exists, _, err = aws.FileExists(models.File{AwsBucket: "gokapi-test", SHA1: "gokapi-upload-" + id})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+
+	configuration.Get().PicturesAlwaysLocal = true
+	id, header, request = createStreamedTestChunk(t, content, "picture.jpg", "image/jpeg")
+	picture, err := NewFileFromChunk(id, header, 99, request)
+	configuration.Get().PicturesAlwaysLocal = false
+	test.IsNil(t, err)
+	test.IsEqualString(t, picture.AwsBucket, "")
+	test.IsEqualString(t, picture.SHA1, file.SHA1)
+	test.FileExists(t, "test/data/"+picture.SHA1)
+	localContent, err := os.ReadFile("test/data/" + picture.SHA1)
+	test.IsNil(t, err)
+	test.IsEqualString(t, string(localContent), string(content))
+	exists, _, err = aws.FileExists(models.File{AwsBucket: "gokapi-test", SHA1: "gokapi-upload-" + id})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	err = os.Remove("test/data/" + picture.SHA1)
+	test.IsNil(t, err)
+
+	id, header, request = createStreamedTestChunk(t, content, "invalid.dat", "text/plain")
+	header.Size = 10
+	_, err = NewFileFromChunk(id, header, 99, request)
+	test.IsNotNil(t, err)
+	exists, _, err = aws.FileExists(models.File{AwsBucket: "gokapi-test", SHA1: "gokapi-upload-" + id})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+
+	id, header, request = createStreamedTestChunk(t, content, "aborted.dat", "text/plain")
+	err = DeleteChunk(id)
+	test.IsNil(t, err)
+	_, err = NewFileFromChunk(id, header, 99, request)
+	test.IsNotNil(t, err)
+	err = DeleteChunk(id)
+	test.IsNotNil(t, err)
+}
+
 func TestNewFileFromChunk(t *testing.T) {
 	test.FileDoesNotExist(t, "test/data/6cca7a6905774e6d61a77dca3ad7a1f44581d6ab")
 	id, header, request, err := createTestChunk()
@@ -454,6 +553,53 @@ func TestNewFileFromChunk(t *testing.T) {
 	}
 }
 
+func TestQuota(t *testing.T) {
+	const userId = 250
+	database.SaveUser(models.User{Id: userId, Name: "quotauser", UserLevel: models.UserLevelUser}, false)
+	content := []byte("This is a file for quota testing purposes")
+	header, request := createRawTestFile(content)
+	request.ExpiryTimestamp = time.Now().Add(24 * time.Hour).Unix()
+	request.AllowedDownloads = 2
+	request.UnlimitedTime = false
+	request.UnlimitedDownload = false
+
+	quota := GetEffectiveQuota(models.User{Id: userId, UserLevel: models.UserLevelUser})
+	test.IsEqualInt(t, quota.MaxFiles, 0)
+	test.IsEqualInt(t, GetUserQuota(userId).MaxFiles, models.QuotaUseDefault)
+
+	SaveUserQuota(models.UserQuota{UserId: userId, MaxStorageMb: 1, MaxFiles: 1, MaxExpiryDays: 2, MaxDownloads: 3})
+	_, err := NewFile(bytes.NewReader(content), &header, userId, request)
+	test.IsNil(t, err)
+	usage := GetQuotaUsage(userId)
+	test.IsEqualInt(t, usage.Files, 1)
+	test.IsEqualInt64(t, usage.StorageBytes, int64(len(content)))
+	_, err = NewFile(bytes.NewReader(content), &header, userId, request)
+	test.IsEqualBool(t, errors.Is(err, ErrorQuotaFiles), true)
+	test.IsEqualBool(t, errors.Is(CheckQuota(userId, 0, 0), ErrorQuotaFiles), true)
+
+	SaveUserQuota(models.UserQuota{UserId: userId, MaxStorageMb: 1, MaxFiles: 0, MaxExpiryDays: 2, MaxDownloads: 3})
+	test.IsNil(t, CheckQuota(userId, 1024, 0))
+	test.IsEqualBool(t, errors.Is(CheckQuota(userId, 1024*1024, 0), ErrorQuotaStorage), true)
+
+	request.AllowedDownloads = 4
+	_, err = NewFile(bytes.NewReader(content), &header, userId, request)
+	test.IsEqualBool(t, errors.Is(err, ErrorQuotaDownloads), true)
+	request.AllowedDownloads = 3
+	request.UnlimitedTime = true
+	_, err = NewFile(bytes.NewReader(content), &header, userId, request)
+	test.IsEqualBool(t, errors.Is(err, ErrorQuotaExpiry), true)
+	request.FileRequestId = "quotarequest"
+	_, err = NewFile(bytes.NewReader(content), &header, userId, request)
+	test.IsNil(t, err)
+
+	SaveUserQuota(models.NewUserQuotaDefault(userId))
+	_, ok := database.GetUserQuota(userId)
+	test.IsEqualBool(t, ok, false)
+	quota = GetEffectiveQuota(models.User{Id: 1, UserLevel: models.UserLevelAdmin})
+	test.IsEqualInt(t, quota.MaxStorageMb, 0)
+	database.DeleteUser(userId)
+}
+
 func TestDuplicateFile(t *testing.T) {
 
 	tempFile, err := createTestFile()
@@ -623,6 +769,125 @@ func TestServeFile(t *testing.T) {
 	test.ResponseBodyContains(t, w, "Error decrypting file")
 }
 
+func TestServeFileEncryptedInMeantime(t *testing.T) {
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encryption.Init(models.Configuration{Encryption: models.Encryption{
+		Level:  encryption.LocalEncryptionStored,
+		Cipher: cipher,
+	}})
+	previousLevel := configuration.Get().Encryption.Level
+	configuration.Get().Encryption.Level = encryption.LocalEncryptionStored
+	defer func() { configuration.Get().Encryption.Level = previousLevel }()
+
+	content := []byte("This file has been encrypted after the metadata was read")
+	file := models.File{
+		Id:                 "encryptedInMeantime",
+		Name:               "meantime.txt",
+		SHA1:               "encryptedinmeantime1234567890",
+		SizeBytes:          int64(len(content)),
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+	}
+	storedFile := file
+	output, err := os.Create("test/data/" + file.SHA1)
+	test.IsNil(t, err)
+	err = encryption.Encrypt(&storedFile.Encryption, bytes.NewReader(content), output)
+	test.IsNil(t, err)
+	test.IsNil(t, output.Close())
+	defer os.Remove("test/data/" + file.SHA1)
+	database.SaveMetaData(storedFile)
+	defer database.DeleteMetaData(file.Id)
+
+	// file still contains the metadata from before the encryption
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	ServeFile(file, w, r, true, false, false)
+	test.IsEqualInt(t, w.Code, 200)
+	test.IsEqualByteSlice(t, w.Body.Bytes(), content)
+
+	w = httptest.NewRecorder()
+	ServeFilesAsZip([]models.File{file}, "meantime", w, r)
+	zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(zipReader.File), 1)
+	zippedFile, err := zipReader.File[0].Open()
+	test.IsNil(t, err)
+	zippedContent, err := io.ReadAll(zippedFile)
+	test.IsNil(t, err)
+	test.IsEqualByteSlice(t, zippedContent, content)
+}
+
+func TestServeFileEncryptedRange(t *testing.T) {
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encryption.Init(models.Configuration{Encryption: models.Encryption{
+		Level:  encryption.LocalEncryptionStored,
+		Cipher: cipher,
+	}})
+	content := make([]byte, 40000)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	file := models.File{
+		Id:                 "encryptedRangeTest",
+		Name:               "range.bin",
+		SHA1:               "encryptedrangetest1234567890",
+		SizeBytes:          int64(len(content)),
+		ContentType:        "application/octet-stream",
+		UploadDate:         1700000000,
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+	}
+	output, err := os.Create("test/data/" + file.SHA1)
+	test.IsNil(t, err)
+	err = encryption.Encrypt(&file.Encryption, bytes.NewReader(content), output)
+	test.IsNil(t, err)
+	test.IsNil(t, output.Close())
+	defer os.Remove("test/data/" + file.SHA1)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	ServeFile(file, w, r, true, false, false)
+	test.IsEqualInt(t, w.Code, 200)
+	test.IsEqualString(t, w.Result().Header.Get("Content-Length"), "40000")
+	test.IsEqualString(t, w.Result().Header.Get("Accept-Ranges"), "bytes")
+	test.IsEqualString(t, w.Result().Header.Get("ETag"), "\"encryptedrangetest1234567890\"")
+	test.IsEqualString(t, w.Result().Header.Get("Last-Modified"), "Tue, 14 Nov 2023 22:13:20 GMT")
+	test.IsEqualByteSlice(t, w.Body.Bytes(), content)
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Range", "bytes=16000-33000")
+	w = httptest.NewRecorder()
+	ServeFile(file, w, r, true, false, false)
+	test.IsEqualInt(t, w.Code, 206)
+	test.IsEqualString(t, w.Result().Header.Get("Content-Range"), "bytes 16000-33000/40000")
+	test.IsEqualString(t, w.Result().Header.Get("Content-Length"), "17001")
+	test.IsEqualByteSlice(t, w.Body.Bytes(), content[16000:33001])
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Range", "bytes=-100")
+	r.Header.Set("If-Range", "\"encryptedrangetest1234567890\"")
+	w = httptest.NewRecorder()
+	ServeFile(file, w, r, true, false, false)
+	test.IsEqualInt(t, w.Code, 206)
+	test.IsEqualByteSlice(t, w.Body.Bytes(), content[39900:])
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Range", "bytes=0-99")
+	r.Header.Set("If-Range", "\"outdated\"")
+	w = httptest.NewRecorder()
+	ServeFile(file, w, r, true, false, false)
+	test.IsEqualInt(t, w.Code, 200)
+	test.IsEqualByteSlice(t, w.Body.Bytes(), content)
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Range", "bytes=50000-")
+	w = httptest.NewRecorder()
+	ServeFile(file, w, r, true, false, false)
+	test.IsEqualInt(t, w.Code, 416)
+}
+
 func TestCleanUp(t *testing.T) {
 	files := database.GetAllMetadata()
 	downloadstatus.DeleteAll()
@@ -714,6 +979,42 @@ func TestCleanUp(t *testing.T) {
 	CleanUp(true)
 }
 
+func TestCleanExpiredBundles(t *testing.T) {
+	database.SaveBundle(models.Bundle{Id: "validBundle", UserId: 5, UnlimitedDownloads: true, UnlimitedTime: true})
+	database.SaveBundle(models.Bundle{Id: "expiredBundle", UserId: 5, UnlimitedDownloads: true, ExpireAt: 100})
+	database.SaveBundle(models.Bundle{Id: "noDownloadsBundle", UserId: 5, UnlimitedTime: true})
+	database.SaveBundle(models.Bundle{Id: "invalidUserBundle", UserId: 5000, UnlimitedDownloads: true, UnlimitedTime: true})
+	cleanExpiredBundles()
+	_, ok := database.GetBundle("validBundle")
+	test.IsEqualBool(t, ok, true)
+	_, ok = database.GetBundle("expiredBundle")
+	test.IsEqualBool(t, ok, false)
+	_, ok = database.GetBundle("noDownloadsBundle")
+	test.IsEqualBool(t, ok, false)
+	_, ok = database.GetBundle("invalidUserBundle")
+	test.IsEqualBool(t, ok, false)
+	database.DeleteBundle("validBundle")
+}
+
+func TestCleanShareLinks(t *testing.T) {
+	database.SaveMetaData(models.File{Id: "shareLinkFile", UnlimitedDownloads: true, UnlimitedTime: true})
+	database.SaveShareLink(models.ShareLink{Id: "validLink", FileId: "shareLinkFile", UnlimitedDownloads: true, UnlimitedTime: true})
+	database.SaveShareLink(models.ShareLink{Id: "expiredLink", FileId: "shareLinkFile", UnlimitedDownloads: true, ExpireAt: 100})
+	database.SaveShareLink(models.ShareLink{Id: "noDownloadsLink", FileId: "shareLinkFile", UnlimitedTime: true})
+	database.SaveShareLink(models.ShareLink{Id: "invalidFileLink", FileId: "invalidFile", UnlimitedDownloads: true, UnlimitedTime: true})
+	cleanShareLinks()
+	_, ok := database.GetShareLink("validLink")
+	test.IsEqualBool(t, ok, true)
+	_, ok = database.GetShareLink("expiredLink")
+	test.IsEqualBool(t, ok, false)
+	_, ok = database.GetShareLink("noDownloadsLink")
+	test.IsEqualBool(t, ok, false)
+	_, ok = database.GetShareLink("invalidFileLink")
+	test.IsEqualBool(t, ok, false)
+	database.DeleteShareLink("validLink")
+	database.DeleteMetaData("shareLinkFile")
+}
+
 func TestDeleteFile(t *testing.T) {
 	testconfiguration.Create(true)
 	configuration.Load()
@@ -866,14 +1167,14 @@ func TestReplaceFile(t *testing.T) {
 	database.SaveMetaData(e2eFile)
 	_, ok = database.GetMetaDataById(e2eFile.Id)
 	test.IsEqualBool(t, ok, true)
-	_, err := ReplaceFile("invalidfile", originalFile.Id, false)
+	_, err := ReplaceFile("invalidfile", originalFile.Id, false, 1)
 	test.IsNotNil(t, err)
-	_, err = ReplaceFile(originalFile.Id, "invalidfile", false)
+	_, err = ReplaceFile(originalFile.Id, "invalidfile", false, 1)
 	test.IsNotNil(t, err)
-	_, err = ReplaceFile(originalFile.Id, e2eFile.Id, false)
+	_, err = ReplaceFile(originalFile.Id, e2eFile.Id, false, 1)
 	test.IsNotNil(t, err)
 
-	_, err = ReplaceFile(originalFile.Id, newFile.Id, false)
+	_, err = ReplaceFile(originalFile.Id, newFile.Id, false, 1)
 	test.IsNil(t, err)
 	file, ok := GetFile(originalFile.Id)
 	test.IsEqualBool(t, ok, true)
@@ -887,9 +1188,40 @@ func TestReplaceFile(t *testing.T) {
 	_, ok = GetFile(newFile.Id)
 	test.IsEqualBool(t, ok, true)
 
-	_, err = ReplaceFile(originalFile.Id, newFile.Id, true)
+	_, err = ReplaceFile(originalFile.Id, newFile.Id, true, 1)
 	_, ok = GetFile(originalFile.Id)
 	test.IsEqualBool(t, ok, true)
 	_, ok = GetFile(newFile.Id)
 	test.IsEqualBool(t, ok, false)
 }
+
+func TestChangePublishDate(t *testing.T) {
+	now := time.Now().Unix()
+	file := models.File{UploadDate: now - 100, ExpireAt: now + 1000}
+	err := ChangePublishDate(&file, now+500, false)
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, file.PublishAt, now+500)
+	test.IsEqualInt64(t, file.ExpireAt, now+1000)
+
+	err = ChangePublishDate(&file, now+2000, false)
+	test.IsEqual(t, err, ErrorPublishAfterExpiry)
+	test.IsEqualInt64(t, file.PublishAt, now+500)
+
+	// The file was available for 500 seconds after its publication
+	err = ChangePublishDate(&file, now+2000, true)
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, file.PublishAt, now+2000)
+	test.IsEqualInt64(t, file.ExpireAt, now+2500)
+
+	err = ChangePublishDate(&file, 0, true)
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, file.PublishAt, 0)
+	test.IsEqualBool(t, file.ExpireAt >= now+500 && file.ExpireAt <= now+510, true)
+
+	file = models.File{UploadDate: now, UnlimitedTime: true}
+	err = ChangePublishDate(&file, now+2000, true)
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, file.ExpireAt, 0)
+	err = ChangePublishDate(&file, -1, false)
+	test.IsNotNil(t, err)
+}
@@ -0,0 +1,130 @@
+package storage
+
+/**
+Previous contents of replaced files, which can be downloaded or restored
+*/
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// ErrorVersionNotFound is raised when the requested version does not exist or does not belong to the file
+var ErrorVersionNotFound = errors.New("version not found")
+
+// GetFileVersions returns all stored versions of the file, latest first
+func GetFileVersions(fileId string) []models.FileVersion {
+	return database.GetFileVersions(fileId)
+}
+
+// GetFileVersion returns the version of the file, if it exists and the stored content is available
+func GetFileVersion(fileId, versionId string) (models.FileVersion, bool) {
+	version, ok := database.GetFileVersion(versionId)
+	if !ok || version.FileId != fileId {
+		return models.FileVersion{}, false
+	}
+	content := version.ToFile()
+	if !checkIfValidAws(content) || !FileExists(content, configuration.Get().DataDir) {
+		return models.FileVersion{}, false
+	}
+	return version, true
+}
+
+// ServeFileVersion serves the content of the version to the browser. The download counter of the file is not changed
+func ServeFileVersion(version models.FileVersion, w http.ResponseWriter, r *http.Request) {
+	content := version.ToFile()
+	serveFile(content, "", w, r, true, content.Encryption.IsEncrypted)
+}
+
+// RollbackFile restores the content of the given version. The current content is stored as a new version
+func RollbackFile(fileId, versionId string, userId int) (models.File, error) {
+	file, ok := GetFile(fileId)
+	if !ok {
+		return models.File{}, ErrorFileNotFound
+	}
+	version, ok := GetFileVersion(fileId, versionId)
+	if !ok {
+		return models.File{}, ErrorVersionNotFound
+	}
+	saveVersion(file, userId)
+	version.ApplyTo(&file)
+	database.SaveMetaData(file)
+	// The content is referenced by the file again, therefore only the entry is removed
+	database.DeleteFileVersion(version.Id)
+	pruneVersions(file.Id)
+	return file, nil
+}
+
+// saveVersion stores the current content of the file as a new version
+func saveVersion(file models.File, userId int) {
+	revision := 1
+	versions := database.GetFileVersions(file.Id)
+	if len(versions) > 0 {
+		revision = versions[0].Revision + 1
+	}
+	id := helper.GenerateRandomString(configuration.GetEnvironment().LengthId)
+	database.SaveFileVersion(models.NewFileVersion(id, revision, file, time.Now().Unix(), userId))
+}
+
+// pruneVersions deletes the oldest versions of the file, if more versions are stored than allowed
+func pruneVersions(fileId string) {
+	maxVersions := configuration.GetEnvironment().MaxFileVersions
+	versions := database.GetFileVersions(fileId)
+	for i := maxVersions; i < len(versions); i++ {
+		deleteVersion(versions[i])
+	}
+}
+
+// deleteVersion deletes the version and its stored content, if the content is not used by a file or another version
+func deleteVersion(version models.FileVersion) {
+	database.DeleteFileVersion(version.Id)
+	content := version.ToFile()
+	if isContentReferenced(content, "") {
+		return
+	}
+	if FileExists(content, configuration.Get().DataDir) {
+		deleteSource(content, configuration.Get().DataDir)
+	}
+}
+
+// isContentReferenced returns true if the stored content with the same hash in the same bucket is used by a
+// file other than the one with the ID ignoredFileId or by a stored version
+func isContentReferenced(content models.File, ignoredFileId string) bool {
+	for _, file := range database.GetAllMetadata() {
+		if file.Id != ignoredFileId && file.SHA1 == content.SHA1 && file.AwsBucket == content.AwsBucket {
+			return true
+		}
+	}
+	for _, version := range database.GetAllFileVersions() {
+		if version.SHA1 == content.SHA1 && version.AwsBucket == content.AwsBucket {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanFileVersions removes versions of files that do not exist anymore and versions
+// that exceed the number of allowed versions
+func cleanFileVersions() {
+	checkedFiles := make(map[string]bool)
+	for _, version := range database.GetAllFileVersions() {
+		if checkedFiles[version.FileId] {
+			continue
+		}
+		checkedFiles[version.FileId] = true
+		_, fileExists := database.GetMetaDataById(version.FileId)
+		if fileExists {
+			pruneVersions(version.FileId)
+			continue
+		}
+		for _, fileVersion := range database.GetFileVersions(version.FileId) {
+			deleteVersion(fileVersion)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func createVersionTestFile(t *testing.T, id, sha1 string) {
+	t.Helper()
+	err := os.WriteFile(configuration.Get().DataDir+"/"+sha1, []byte(sha1), 0600)
+	test.IsNil(t, err)
+	database.SaveMetaData(models.File{Id: id, Name: id + ".txt", SHA1: sha1, Size: "12 B", SizeBytes: 12,
+		UnlimitedDownloads: true, UnlimitedTime: true})
+}
+
+func TestFileVersions(t *testing.T) {
+	dataDir := configuration.Get().DataDir
+	maxVersions := configuration.GetEnvironment().MaxFileVersions
+	test.IsEqualInt(t, maxVersions, 5)
+	createVersionTestFile(t, "versionfile", "versiontest0")
+	for i := 1; i <= maxVersions+1; i++ {
+		createVersionTestFile(t, "versionnew"+strconv.Itoa(i), "versiontest"+strconv.Itoa(i))
+	}
+	createVersionTestFile(t, "versionother", "versiontestother")
+
+	test.IsEqualInt(t, len(GetFileVersions("versionfile")), 0)
+	_, err := ReplaceFile("versionfile", "versionnew1", true, 7)
+	test.IsNil(t, err)
+	versions := GetFileVersions("versionfile")
+	test.IsEqualInt(t, len(versions), 1)
+	test.IsEqualString(t, versions[0].SHA1, "versiontest0")
+	test.IsEqualString(t, versions[0].Name, "versionfile.txt")
+	test.IsEqualInt(t, versions[0].Revision, 1)
+	test.IsEqualInt(t, versions[0].ReplacedBy, 7)
+	version, ok := GetFileVersion("versionfile", versions[0].Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, version.Id, versions[0].Id)
+	_, ok = GetFileVersion("versionother", versions[0].Id)
+	test.IsEqualBool(t, ok, false)
+	_, ok = GetFileVersion("versionfile", "invalid")
+	test.IsEqualBool(t, ok, false)
+
+	_, err = RollbackFile("invalid", versions[0].Id, 8)
+	test.IsEqualBool(t, errors.Is(err, ErrorFileNotFound), true)
+	_, err = RollbackFile("versionfile", "invalid", 8)
+	test.IsEqualBool(t, errors.Is(err, ErrorVersionNotFound), true)
+	_, err = RollbackFile("versionother", versions[0].Id, 8)
+	test.IsEqualBool(t, errors.Is(err, ErrorVersionNotFound), true)
+
+	file, err := RollbackFile("versionfile", versions[0].Id, 8)
+	test.IsNil(t, err)
+	test.IsEqualString(t, file.SHA1, "versiontest0")
+	test.IsEqualString(t, file.Name, "versionfile.txt")
+	file, ok = GetFile("versionfile")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.SHA1, "versiontest0")
+	versions = GetFileVersions("versionfile")
+	test.IsEqualInt(t, len(versions), 1)
+	test.IsEqualString(t, versions[0].SHA1, "versiontest1")
+	test.IsEqualString(t, versions[0].Name, "versionnew1.txt")
+	test.IsEqualInt(t, versions[0].Revision, 2)
+	test.IsEqualInt(t, versions[0].ReplacedBy, 8)
+
+	// Only the latest versions are kept
+	for i := 2; i <= maxVersions+1; i++ {
+		_, err = ReplaceFile("versionfile", "versionnew"+strconv.Itoa(i), true, 7)
+		test.IsNil(t, err)
+	}
+	versions = GetFileVersions("versionfile")
+	test.IsEqualInt(t, len(versions), maxVersions)
+	test.IsEqualString(t, versions[0].SHA1, "versiontest"+strconv.Itoa(maxVersions))
+	test.IsEqualString(t, versions[len(versions)-1].SHA1, "versiontest0")
+	// The content of the removed version is still used by the replacing file, until it is cleaned up
+	test.FileExists(t, dataDir+"/versiontest1")
+	cleanUpDatabase()
+	test.FileDoesNotExist(t, dataDir+"/versiontest1")
+	test.FileExists(t, dataDir+"/versiontest0")
+	test.FileExists(t, dataDir+"/versiontest2")
+	test.IsEqualInt(t, len(GetFileVersions("versionfile")), maxVersions)
+
+	// A version is not available, if the content is missing
+	test.IsNil(t, os.Remove(dataDir+"/versiontest2"))
+	for _, fileVersion := range GetFileVersions("versionfile") {
+		_, ok = GetFileVersion("versionfile", fileVersion.Id)
+		test.IsEqualBool(t, ok, fileVersion.SHA1 != "versiontest2")
+	}
+
+	// All versions are removed together with the file
+	test.IsEqualBool(t, DeleteFile("versionfile", false), true)
+	cleanUpDatabase()
+	test.IsEqualInt(t, len(GetFileVersions("versionfile")), 0)
+	for i := 0; i <= maxVersions+1; i++ {
+		test.FileDoesNotExist(t, dataDir+"/versiontest"+strconv.Itoa(i))
+	}
+	test.FileExists(t, dataDir+"/versiontestother")
+	DeleteFile("versionother", false)
+}
+
+func TestIsContentReferenced(t *testing.T) {
+	database.SaveMetaData(models.File{Id: "referencedlocal", SHA1: "referencedhash"})
+	database.SaveMetaData(models.File{Id: "referencedcloud", SHA1: "referencedhash", AwsBucket: "bucket1"})
+	test.IsEqualBool(t, isContentReferenced(models.File{SHA1: "referencedhash"}, ""), true)
+	test.IsEqualBool(t, isContentReferenced(models.File{SHA1: "referencedhash"}, "referencedlocal"), false)
+	test.IsEqualBool(t, isContentReferenced(models.File{SHA1: "referencedhash", AwsBucket: "bucket1"}, ""), true)
+	test.IsEqualBool(t, isContentReferenced(models.File{SHA1: "referencedhash", AwsBucket: "bucket2"}, ""), false)
+	database.DeleteMetaData("referencedlocal")
+	database.DeleteMetaData("referencedcloud")
+
+	database.SaveFileVersion(models.FileVersion{Id: "referencedversion", FileId: "referencedfile", SHA1: "referencedhash", AwsBucket: "bucket1"})
+	test.IsEqualBool(t, isContentReferenced(models.File{SHA1: "referencedhash", AwsBucket: "bucket1"}, ""), true)
+	test.IsEqualBool(t, isContentReferenced(models.File{SHA1: "referencedhash", AwsBucket: "bucket2"}, ""), false)
+	test.IsEqualBool(t, isContentReferenced(models.File{SHA1: "referencedhash"}, ""), false)
+	database.DeleteFileVersion("referencedversion")
+}
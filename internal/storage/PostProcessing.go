@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/postprocess"
+	"github.com/forceu/gokapi/internal/storage/processingstatus"
+)
+
+// ErrorFileNotQuarantined is returned if a file is released that has not been quarantined
+var ErrorFileNotQuarantined = errors.New("file has not been quarantined")
+
+// runPostProcessing checks the plaintext content at contentPath with the post-processing pipeline, if required.
+// If the file is rejected, it is quarantined and cannot be downloaded until it is released
+func runPostProcessing(chunkId string, metaData *models.File, userId int, contentPath string) {
+	if !postprocess.IsRequired(*metaData) {
+		return
+	}
+	if chunkId != "" {
+		processingstatus.Set(chunkId, processingstatus.StatusPostProcessing, models.File{}, userId, nil)
+	}
+	verdict := postprocess.Run(contentPath)
+	applyVerdict(metaData, verdict)
+}
+
+// runPostProcessingOnStoredFile checks a file that has already been moved to its final location, if required.
+// This is the case for uploads that were never stored unencrypted on the local disk
+func runPostProcessingOnStoredFile(chunkId string, metaData *models.File, userId int) {
+	if !postprocess.IsRequired(*metaData) {
+		return
+	}
+	tempFile, err := createPlaintextCopy(*metaData)
+	if err != nil {
+		// The file cannot be published without being checked
+		applyVerdict(metaData, postprocess.Verdict{Reason: "the content could not be read: " + err.Error()})
+		return
+	}
+	defer removeTempFile(tempFile)
+	runPostProcessing(chunkId, metaData, userId, tempFile.Name())
+}
+
+func applyVerdict(metaData *models.File, verdict postprocess.Verdict) {
+	if verdict.Passed {
+		metaData.ProcessingState = models.FileStatePublished
+		metaData.QuarantineReason = ""
+	} else {
+		metaData.ProcessingState = models.FileStateQuarantined
+		metaData.QuarantineReason = verdict.Reason
+	}
+	logging.LogPostProcessing(*metaData, verdict.Passed, verdict.Processor, verdict.Reason)
+}
+
+// createPlaintextCopy writes the decrypted content of a stored file to a temporary file
+func createPlaintextCopy(file models.File) (*os.File, error) {
+	tempFile, err := os.CreateTemp(configuration.Get().DataDir, "upload")
+	if err != nil {
+		return nil, err
+	}
+	err = writePlaintext(file, tempFile)
+	if err != nil {
+		removeTempFile(tempFile)
+		return nil, err
+	}
+	return tempFile, nil
+}
+
+// writePlaintext writes the decrypted content of a stored file to output
+func writePlaintext(file models.File, output io.Writer) error {
+	if file.IsLocalStorage() {
+		return copyLocalPlaintext(file, output)
+	}
+	return aws.Stream(output, file)
+}
+
+func copyLocalPlaintext(file models.File, output io.Writer) error {
+	source, err := os.Open(configuration.Get().DataDir + "/" + file.SHA1)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	if file.Encryption.IsEncrypted {
+		return encryption.DecryptReader(file.Encryption, source, output)
+	}
+	_, err = io.Copy(output, source)
+	return err
+}
+
+// setFinishedStatus reports that a chunked upload has been completed, or that it has been quarantined
+func setFinishedStatus(chunkId string, file models.File, userId int) {
+	if file.ProcessingState == models.FileStateQuarantined {
+		processingstatus.Set(chunkId, processingstatus.StatusQuarantined, file, userId, errors.New(file.QuarantineReason))
+		return
+	}
+	processingstatus.Set(chunkId, processingstatus.StatusFinished, file, userId, nil)
+}
+
+// ReleaseFile publishes a file that has been quarantined after the upload
+func ReleaseFile(fileId string) (models.File, error) {
+	file, ok := database.GetMetaDataById(fileId)
+	if !ok {
+		return models.File{}, ErrorFileNotFound
+	}
+	if file.ProcessingState != models.FileStateQuarantined {
+		return models.File{}, ErrorFileNotQuarantined
+	}
+	file.ProcessingState = models.FileStatePublished
+	file.QuarantineReason = ""
+	database.SaveMetaData(file)
+	return file, nil
+}
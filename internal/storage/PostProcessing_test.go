@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/postprocess"
+	"github.com/forceu/gokapi/internal/storage/processingstatus"
+	"github.com/forceu/gokapi/internal/storage/processingstatus/pstatusdb"
+	"github.com/forceu/gokapi/internal/test"
+)
+
+// contentProcessor rejects all files that contain the given content
+type contentProcessor struct {
+	rejectedContent string
+	checkedContent  []string
+}
+
+func (c *contentProcessor) GetName() string {
+	return "content check"
+}
+
+func (c *contentProcessor) Process(_ context.Context, path string) (postprocess.Verdict, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return postprocess.Verdict{}, err
+	}
+	c.checkedContent = append(c.checkedContent, string(content))
+	if string(content) == c.rejectedContent {
+		return postprocess.Verdict{Reason: "forbidden content"}, nil
+	}
+	return postprocess.Verdict{Passed: true}, nil
+}
+
+func TestPostProcessingChunk(t *testing.T) {
+	processor := &contentProcessor{rejectedContent: "This file will be quarantined"}
+	postprocess.Register(processor)
+	defer postprocess.Init()
+
+	content := []byte("This file will be quarantined")
+	_, request := createRawTestFile(content)
+	chunkId := helper.GenerateRandomString(15)
+	test.IsNil(t, os.WriteFile("test/data/chunk-"+chunkId, content, 0600))
+	file, err := NewFileFromChunk(chunkId, chunking.FileHeader{Filename: "virus.dat", ContentType: "text/plain", Size: int64(len(content))}, 98, request)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(processor.checkedContent), 1)
+	test.IsEqualString(t, processor.checkedContent[0], string(content))
+	test.IsEqualInt(t, file.ProcessingState, models.FileStateQuarantined)
+	test.IsEqualString(t, file.QuarantineReason, "forbidden content")
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, retrievedFile.IsHeld(), true)
+	_, ok = GetFile(file.Id)
+	test.IsEqualBool(t, ok, false)
+	// The content is kept, so that the file can be released
+	test.FileExists(t, "test/data/"+file.SHA1)
+
+	statusFound := false
+	for _, status := range pstatusdb.GetAllForUser(98) {
+		if status.ChunkId == chunkId {
+			statusFound = true
+			test.IsEqualInt(t, status.CurrentStatus, processingstatus.StatusQuarantined)
+			test.IsEqualString(t, status.ErrorMessage, "forbidden content")
+		}
+	}
+	test.IsEqualBool(t, statusFound, true)
+
+	_, err = ReleaseFile("invalid")
+	test.IsEqual(t, err, ErrorFileNotFound)
+	releasedFile, err := ReleaseFile(file.Id)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, releasedFile.IsHeld(), false)
+	test.IsEmpty(t, releasedFile.QuarantineReason)
+	_, ok = GetFile(file.Id)
+	test.IsEqualBool(t, ok, true)
+	_, err = ReleaseFile(file.Id)
+	test.IsEqual(t, err, ErrorFileNotQuarantined)
+	DeleteFile(file.Id, false)
+}
+
+func TestPostProcessingNewFile(t *testing.T) {
+	processor := &contentProcessor{rejectedContent: "Quarantined content of a regular upload"}
+	postprocess.Register(processor)
+	defer postprocess.Init()
+
+	content := []byte("Quarantined content of a regular upload")
+	header, request := createRawTestFile(content)
+	file, err := NewFile(bytes.NewReader(content), &header, 98, request)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(processor.checkedContent), 1)
+	test.IsEqualString(t, processor.checkedContent[0], string(content))
+	test.IsEqualBool(t, file.IsHeld(), true)
+	_, ok := GetFile(file.Id)
+	test.IsEqualBool(t, ok, false)
+	DeleteFile(file.Id, false)
+
+	content = []byte("Accepted content of a regular upload")
+	header, request = createRawTestFile(content)
+	file, err = NewFile(bytes.NewReader(content), &header, 98, request)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(processor.checkedContent), 2)
+	test.IsEqualBool(t, file.IsHeld(), false)
+	_, ok = GetFile(file.Id)
+	test.IsEqualBool(t, ok, true)
+	DeleteFile(file.Id, false)
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/preview"
+)
+
+// saveNewFile stores the metadata of a new upload and creates its thumbnail, if required
+func saveNewFile(file models.File) {
+	database.SaveMetaData(file)
+	createThumbnail(file)
+}
+
+// IsThumbnailAvailable returns true, if a thumbnail can be shown for the file. If the thumbnail
+// has not been created yet, e.g. for files uploaded with a previous version, it is created now.
+// It is not attempted again, if the picture could not be decoded previously
+func IsThumbnailAvailable(file models.File) bool {
+	if configuration.GetEnvironment().DisablePreviews || !preview.IsThumbnailSupported(file) {
+		return false
+	}
+	if preview.ThumbnailExists(file) {
+		return true
+	}
+	if preview.IsThumbnailFailed(file) {
+		return false
+	}
+	return generateThumbnail(file) == nil
+}
+
+// ServeThumbnail serves the thumbnail of a picture. Returns false, if no thumbnail is available for the file
+func ServeThumbnail(file models.File, w http.ResponseWriter) bool {
+	if !IsThumbnailAvailable(file) {
+		return false
+	}
+	err := preview.ServeThumbnail(file, w)
+	return err == nil
+}
+
+// GetTextPreview returns the content of a text file, if it can be shown as a preview. Only files with
+// unlimited downloads are shown, as the preview would otherwise bypass the download limit
+func GetTextPreview(file models.File) (string, bool) {
+	env := configuration.GetEnvironment()
+	if env.DisablePreviews || !file.UnlimitedDownloads {
+		return "", false
+	}
+	if !preview.IsTextSupported(file, int64(env.PreviewMaxSizeKb)*1024) {
+		return "", false
+	}
+	var content bytes.Buffer
+	err := writePlaintext(file, &content)
+	if err != nil || !preview.IsText(content.Bytes()) {
+		return "", false
+	}
+	return content.String(), true
+}
+
+// createThumbnail creates the thumbnail of a new upload, if it is a picture. Errors are only printed,
+// as the upload itself is not affected
+func createThumbnail(file models.File) {
+	if configuration.GetEnvironment().DisablePreviews || file.IsHeld() || !preview.IsThumbnailSupported(file) {
+		return
+	}
+	if preview.ThumbnailExists(file) || preview.IsThumbnailFailed(file) {
+		return
+	}
+	err := generateThumbnail(file)
+	if err != nil {
+		fmt.Println("Warning, cannot create thumbnail for file " + file.Id + ": " + err.Error())
+	}
+}
+
+// generateThumbnail reads the stored content of the file and stores its thumbnail. If the content could be
+// read but not decoded, this is stored, so that creating the thumbnail is not attempted again
+func generateThumbnail(file models.File) error {
+	reader, writer := io.Pipe()
+	readResult := make(chan error, 1)
+	go func() {
+		err := writePlaintext(file, writer)
+		readResult <- err
+		_ = writer.CloseWithError(err)
+	}()
+	err := preview.CreateThumbnail(file, reader)
+	// Closing the reader stops writePlaintext, if the picture could not be decoded completely
+	_ = reader.Close()
+	readErr := <-readResult
+	if errors.Is(err, preview.ErrorInvalidPicture) && (readErr == nil || errors.Is(readErr, io.ErrClosedPipe)) {
+		failedErr := preview.SetThumbnailFailed(file)
+		if failedErr != nil {
+			fmt.Println("Warning, cannot store failed thumbnail creation for file " + file.Id + ": " + failedErr.Error())
+		}
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/preview"
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestThumbnails(t *testing.T) {
+	var content bytes.Buffer
+	test.IsNil(t, png.Encode(&content, image.NewGray(image.Rect(0, 0, 800, 600))))
+	_, request := createRawTestFile(content.Bytes())
+	chunkId := helper.GenerateRandomString(15)
+	test.IsNil(t, os.WriteFile("test/data/chunk-"+chunkId, content.Bytes(), 0600))
+	file, err := NewFileFromChunk(chunkId, chunking.FileHeader{Filename: "picture.png", ContentType: "image/png", Size: int64(content.Len())}, 98, request)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, preview.ThumbnailExists(file), true)
+	test.IsEqualBool(t, IsThumbnailAvailable(file), true)
+
+	w := httptest.NewRecorder()
+	test.IsEqualBool(t, ServeThumbnail(file, w), true)
+	test.IsEqualString(t, w.Header().Get("Content-Type"), "image/jpeg")
+	thumbnail, format, err := image.Decode(w.Body)
+	test.IsNil(t, err)
+	test.IsEqualString(t, format, "jpeg")
+	test.IsEqualInt(t, thumbnail.Bounds().Dx(), preview.ThumbnailSize)
+	test.IsEqualInt(t, thumbnail.Bounds().Dy(), 300)
+
+	// Thumbnails of existing files are created when they are requested
+	preview.DeleteThumbnail(file)
+	test.IsEqualBool(t, preview.ThumbnailExists(file), false)
+	test.IsEqualBool(t, IsThumbnailAvailable(file), true)
+	test.IsEqualBool(t, preview.ThumbnailExists(file), true)
+
+	deleteSource(file, configuration.Get().DataDir)
+	test.IsEqualBool(t, preview.ThumbnailExists(file), false)
+	test.IsEqualBool(t, IsThumbnailAvailable(file), false)
+	// A missing source is not stored as a failed thumbnail
+	test.IsEqualBool(t, preview.IsThumbnailFailed(file), false)
+
+	invalidPicture := models.File{Id: "invalidpicture", Name: "invalid.png", SHA1: "invalidpicturecontent", ContentType: "image/png"}
+	test.IsNil(t, os.WriteFile("test/data/invalidpicturecontent", []byte("not a picture"), 0600))
+	createThumbnail(invalidPicture)
+	test.IsEqualBool(t, preview.ThumbnailExists(invalidPicture), false)
+	test.IsEqualBool(t, preview.IsThumbnailFailed(invalidPicture), true)
+	test.IsEqualBool(t, ServeThumbnail(invalidPicture, httptest.NewRecorder()), false)
+
+	// Creating the thumbnail is not attempted again after it failed
+	test.IsNil(t, os.WriteFile("test/data/invalidpicturecontent", content.Bytes(), 0600))
+	test.IsEqualBool(t, IsThumbnailAvailable(invalidPicture), false)
+	test.IsEqualBool(t, preview.ThumbnailExists(invalidPicture), false)
+	preview.DeleteThumbnail(invalidPicture)
+	test.IsEqualBool(t, preview.IsThumbnailFailed(invalidPicture), false)
+	test.IsEqualBool(t, IsThumbnailAvailable(invalidPicture), true)
+	preview.DeleteThumbnail(invalidPicture)
+	test.IsNil(t, os.Remove("test/data/invalidpicturecontent"))
+}
+
+func TestGetTextPreview(t *testing.T) {
+	test.IsNil(t, os.WriteFile("test/data/textpreviewcontent", []byte("# Title\nText"), 0600))
+	file := models.File{Id: "textpreview", Name: "readme.md", SHA1: "textpreviewcontent", SizeBytes: 12, UnlimitedDownloads: true}
+	content, ok := GetTextPreview(file)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, content, "# Title\nText")
+
+	limitedFile := file
+	limitedFile.UnlimitedDownloads = false
+	_, ok = GetTextPreview(limitedFile)
+	test.IsEqualBool(t, ok, false)
+
+	largeFile := file
+	largeFile.SizeBytes = int64(configuration.GetEnvironment().PreviewMaxSizeKb)*1024 + 1
+	_, ok = GetTextPreview(largeFile)
+	test.IsEqualBool(t, ok, false)
+
+	test.IsNil(t, os.WriteFile("test/data/textpreviewcontent", []byte{0x48, 0x00, 0x49}, 0600))
+	_, ok = GetTextPreview(file)
+	test.IsEqualBool(t, ok, false)
+	test.IsNil(t, os.Remove("test/data/textpreviewcontent"))
+
+	missingFile := file
+	missingFile.SHA1 = "textpreviewmissing"
+	_, ok = GetTextPreview(missingFile)
+	test.IsEqualBool(t, ok, false)
+}
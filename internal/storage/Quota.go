@@ -0,0 +1,137 @@
+package storage
+
+/**
+Storage quotas and upload limits of non-admin users
+*/
+
+import (
+	"errors"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// ErrorQuotaStorage is raised when an upload would exceed the storage quota of the user
+var ErrorQuotaStorage = errors.New("storage quota exceeded")
+
+// ErrorQuotaFiles is raised when the user has already stored the maximum number of files
+var ErrorQuotaFiles = errors.New("maximum number of stored files reached")
+
+// ErrorQuotaExpiry is raised when the expiry of an upload exceeds the maximum allowed for the user
+var ErrorQuotaExpiry = errors.New("expiry exceeds the maximum allowed by the quota")
+
+// ErrorQuotaDownloads is raised when the allowed downloads of an upload exceed the maximum allowed for the user
+var ErrorQuotaDownloads = errors.New("allowed downloads exceed the maximum allowed by the quota")
+
+// GetDefaultQuota returns the limits for non-admin users without an individual quota
+func GetDefaultQuota() models.UserQuota {
+	env := configuration.GetEnvironment()
+	return models.UserQuota{
+		MaxStorageMb:  env.QuotaStorageMb,
+		MaxFiles:      env.QuotaFiles,
+		MaxExpiryDays: env.QuotaExpiryDays,
+		MaxDownloads:  env.QuotaDownloads,
+	}
+}
+
+// GetUserQuota returns the individual quota of the user. Limits that use the server default are not resolved
+func GetUserQuota(userId int) models.UserQuota {
+	quota, ok := database.GetUserQuota(userId)
+	if !ok {
+		return models.NewUserQuotaDefault(userId)
+	}
+	return quota
+}
+
+// SaveUserQuota stores the individual quota of the user. If all limits use the server default,
+// the quota is deleted instead
+func SaveUserQuota(quota models.UserQuota) {
+	if quota.IsDefault() {
+		database.DeleteUserQuota(quota.UserId)
+		return
+	}
+	database.SaveUserQuota(quota)
+}
+
+// GetEffectiveQuota returns the limits that are enforced for the user. Admins have no limits
+func GetEffectiveQuota(user models.User) models.UserQuota {
+	if user.IsAdmin() {
+		return models.UserQuota{UserId: user.Id}
+	}
+	quota := GetUserQuota(user.Id)
+	return quota.WithDefaults(GetDefaultQuota())
+}
+
+// GetQuotaUsages returns the size and number of the currently stored files per user
+func GetQuotaUsages() map[int]models.QuotaUsage {
+	result := make(map[int]models.QuotaUsage)
+	timeNow := time.Now().Unix()
+	for _, file := range database.GetAllMetadata() {
+		if IsExpiredFile(file, timeNow) {
+			continue
+		}
+		usage := result[file.UserId]
+		usage.StorageBytes = usage.StorageBytes + file.SizeBytes
+		usage.Files++
+		result[file.UserId] = usage
+	}
+	return result
+}
+
+// GetQuotaUsage returns the size and number of the currently stored files of the user
+func GetQuotaUsage(userId int) models.QuotaUsage {
+	return GetQuotaUsages()[userId]
+}
+
+// CheckQuota returns an error, if the user is not allowed to store another file with the given size.
+// additionalFiles are files that are not stored yet, but have already been accepted, e.g. reserved chunks
+func CheckQuota(userId int, sizeBytes int64, additionalFiles int) error {
+	user, ok := database.GetUser(userId)
+	if !ok {
+		return nil
+	}
+	quota := GetEffectiveQuota(user)
+	if quota.MaxStorageMb <= 0 && quota.MaxFiles <= 0 {
+		return nil
+	}
+	usage := GetQuotaUsage(userId)
+	usage.Files = usage.Files + additionalFiles
+	if quota.ExceedsFiles(usage) {
+		return ErrorQuotaFiles
+	}
+	if quota.ExceedsStorage(usage, sizeBytes) {
+		return ErrorQuotaStorage
+	}
+	return nil
+}
+
+// CheckExpiryQuota returns an error, if the user is not allowed to set the expiry or the number of allowed downloads
+func CheckExpiryQuota(userId int, expireAt int64, unlimitedTime bool, allowedDownloads int, unlimitedDownloads bool) error {
+	user, ok := database.GetUser(userId)
+	if !ok {
+		return nil
+	}
+	quota := GetEffectiveQuota(user)
+	if quota.ExceedsExpiry(expireAt, unlimitedTime, time.Now().Unix()) {
+		return ErrorQuotaExpiry
+	}
+	if quota.ExceedsDownloads(allowedDownloads, unlimitedDownloads) {
+		return ErrorQuotaDownloads
+	}
+	return nil
+}
+
+// checkUploadQuota returns an error, if the upload is not allowed by the quota of the user.
+// Expiry and download limits of uploads for file requests are set by the server and are not checked
+func checkUploadQuota(userId int, sizeBytes int64, params models.UploadParameters) error {
+	err := CheckQuota(userId, sizeBytes, 0)
+	if err != nil {
+		return err
+	}
+	if params.FileRequestId != "" {
+		return nil
+	}
+	return CheckExpiryQuota(userId, params.ExpiryTimestamp, params.UnlimitedTime, params.AllowedDownloads, params.UnlimitedDownload)
+}
@@ -0,0 +1,112 @@
+package bundle
+
+import (
+	"errors"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+)
+
+// ErrorFileNotFound is returned, if a file that is added to a bundle does not exist
+var ErrorFileNotFound = errors.New("file not found")
+
+// ErrorNoPermission is returned, if a file that is added to a bundle is owned by another user
+var ErrorNoPermission = errors.New("no permission to share file")
+
+// ErrorUnsupportedFile is returned, if a file requires client-side decryption, was uploaded for a file request or requires a login
+var ErrorUnsupportedFile = errors.New("encrypted files, files of file requests and files that require a login cannot be added to a bundle")
+
+// New creates a new bundle object. It is not stored yet.
+// If allowedDownloads or expiryDays is 0, the bundle has no download or time limit
+func New(user models.User, name string, allowedDownloads, expiryDays int, password string) models.Bundle {
+	result := models.Bundle{
+		Id:                 helper.GenerateRandomString(configuration.GetEnvironment().LengthId),
+		Name:               name,
+		UserId:             user.Id,
+		CreationDate:       time.Now().Unix(),
+		DownloadsRemaining: allowedDownloads,
+		UnlimitedDownloads: allowedDownloads == 0,
+		UnlimitedTime:      expiryDays == 0,
+	}
+	if !result.UnlimitedTime {
+		result.ExpireAt = time.Now().Add(time.Duration(expiryDays) * 24 * time.Hour).Unix()
+	}
+	if password != "" {
+		result.PasswordHash = configuration.HashPassword(password, false, "")
+	}
+	return result
+}
+
+// Get returns a bundle by its ID. Returns false, if the bundle does not exist or has expired
+func Get(id string) (models.Bundle, bool) {
+	if id == "" {
+		return models.Bundle{}, false
+	}
+	result, ok := database.GetBundle(id)
+	if !ok || result.IsExpired() {
+		return models.Bundle{}, false
+	}
+	return result, true
+}
+
+// GetFiles returns all files of the bundle that can be downloaded. Files that have not been published yet are not returned
+func GetFiles(bundle models.Bundle) []models.File {
+	result := make([]models.File, 0)
+	for _, id := range bundle.GetFileIds() {
+		file, ok := storage.GetFile(id)
+		if !ok || !isSupportedFile(file) || file.IsEmbargoed() {
+			continue
+		}
+		result = append(result, file)
+	}
+	return result
+}
+
+// GetFile returns the file with the given ID, if it is part of the bundle and can be downloaded
+func GetFile(bundle models.Bundle, fileId string) (models.File, bool) {
+	if !bundle.ContainsFile(fileId) {
+		return models.File{}, false
+	}
+	file, ok := storage.GetFile(fileId)
+	if !ok || !isSupportedFile(file) || file.IsEmbargoed() {
+		return models.File{}, false
+	}
+	return file, true
+}
+
+// CheckFiles returns an error, if any of the given files cannot be added to a bundle by the user
+func CheckFiles(fileIds []string, user models.User) error {
+	for _, id := range fileIds {
+		file, ok := storage.GetFile(id)
+		if !ok {
+			return ErrorFileNotFound
+		}
+		if file.UserId != user.Id && !user.HasPermission(models.UserPermEditOtherUploads) {
+			return ErrorNoPermission
+		}
+		if !isSupportedFile(file) {
+			return ErrorUnsupportedFile
+		}
+	}
+	return nil
+}
+
+// IsAllowedToEdit returns true, if the user is the owner of the bundle or is allowed to edit other uploads
+func IsAllowedToEdit(bundle models.Bundle, user models.User) bool {
+	return bundle.UserId == user.Id || user.HasPermission(models.UserPermEditOtherUploads)
+}
+
+// RegisterDownload increases the download counter of the bundle
+func RegisterDownload(bundle models.Bundle) {
+	database.IncreaseBundleDownloadCount(bundle.Id, !bundle.UnlimitedDownloads)
+}
+
+// Files can only be served as part of a bundle, if the server is able to decrypt them. Files that require
+// a login are not served either, as bundles are always public
+func isSupportedFile(file models.File) bool {
+	return !file.RequiresClientDecryption() && !file.IsFileRequest() && !file.RequireLogin
+}
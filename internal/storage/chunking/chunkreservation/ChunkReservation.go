@@ -4,7 +4,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
 	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging/metrics"
 )
 
 var reservedChunks = make(map[string]map[string]reservation)
@@ -13,6 +15,7 @@ var runGcOnce = sync.Once{}
 
 const timeReservationWithoutUpload = 4 * 60
 const timeReservationWithUpload = 23 * 60 * 60
+const sharedGroupPrefix = "chunkres:"
 
 type reservation struct {
 	Uuid   string
@@ -21,6 +24,14 @@ type reservation struct {
 
 // GetCount returns the number of chunks reserved for the given file request
 func GetCount(id string) int {
+	if sharedstate.IsEnabled() {
+		entries, err := sharedstate.Get().GetGroupEntries(sharedGroupPrefix + id)
+		if err != nil {
+			sharedstate.PrintError(err)
+			return 0
+		}
+		return len(entries)
+	}
 	reservationMutex.RLock()
 	defer reservationMutex.RUnlock()
 	length := len(reservedChunks[id])
@@ -29,10 +40,18 @@ func GetCount(id string) int {
 
 // New creates a new chunk reservation
 func New(id string) string {
+	uuid := helper.GenerateRandomString(32)
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Get().SetGroupEntry(sharedGroupPrefix+id, uuid, []byte(uuid), timeReservationWithoutUpload*time.Second)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		metrics.IncreaseChunkReservations()
+		return uuid
+	}
 	reservationMutex.Lock()
 	defer reservationMutex.Unlock()
 
-	uuid := helper.GenerateRandomString(32)
 	if reservedChunks[id] == nil {
 		reservedChunks[id] = make(map[string]reservation)
 	}
@@ -41,12 +60,20 @@ func New(id string) string {
 		Expiry: time.Now().Unix() + timeReservationWithoutUpload,
 	}
 
+	metrics.IncreaseChunkReservations()
 	runGcOnce.Do(func() { go cleanUp(true) })
 	return uuid
 }
 
 // SetComplete marks a chunk as complete or cancelled
 func SetComplete(id, uuid string) {
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Get().DeleteGroupEntry(sharedGroupPrefix+id, uuid)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return
+	}
 	reservationMutex.Lock()
 	delete(reservedChunks[id], uuid)
 	reservationMutex.Unlock()
@@ -54,6 +81,22 @@ func SetComplete(id, uuid string) {
 
 // SetUploading marks a chunk as uploading and extends the reservation time
 func SetUploading(id string, uuid string) bool {
+	if sharedstate.IsEnabled() {
+		entries, err := sharedstate.Get().GetGroupEntries(sharedGroupPrefix + id)
+		if err != nil {
+			sharedstate.PrintError(err)
+			return false
+		}
+		_, ok := entries[uuid]
+		if !ok {
+			return false
+		}
+		err = sharedstate.Get().SetGroupEntry(sharedGroupPrefix+id, uuid, []byte(uuid), timeReservationWithUpload*time.Second)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return true
+	}
 	reservationMutex.Lock()
 	defer reservationMutex.Unlock()
 
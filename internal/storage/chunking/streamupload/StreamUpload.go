@@ -0,0 +1,455 @@
+package streamupload
+
+/**
+Streams chunked uploads directly into S3 multipart uploads. Each chunk is stored on the local disk
+only until it has been hashed and encrypted, so that the complete file never has to be stored there
+*/
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+)
+
+// minPartSize is the minimum size of a part before it is uploaded. Larger parts are used,
+// if the file would otherwise consist of more parts than S3 allows
+const minPartSize = 8 * 1024 * 1024
+
+// maxIdleTime is the time after which an upload without any new chunks is aborted
+const maxIdleTime = 24 * time.Hour
+
+// objectKeyPrefix is the prefix of the temporary object key, until the hash of the file is known
+const objectKeyPrefix = "gokapi-upload-"
+
+// ErrorOtherInstance is returned, if the upload has been started on another instance. The progress of an
+// upload is only kept in the memory of that instance, therefore all chunks have to be sent to it
+var ErrorOtherInstance = errors.New("the upload has been started on another instance, all chunks of an upload have to be sent to the same instance")
+
+var uploads = make(map[string]*upload)
+var uploadsMutex sync.Mutex
+
+// Result contains information about a completed upload. The content is stored in the object Key
+// and has to be moved or deleted by the caller
+type Result struct {
+	Bucket     string
+	Key        string
+	Hash       string
+	Encryption models.EncryptionInfo
+	Size       int64
+	StoredSize int64
+}
+
+type upload struct {
+	mutex       sync.Mutex
+	id          string
+	totalSize   int64
+	nextOffset  int64
+	storedSize  int64
+	lastUpdate  time.Time
+	isEncrypted bool
+	encryption  models.EncryptionInfo
+	hash        hash.Hash
+	encrypter   io.WriteCloser
+	writer      io.Writer
+	partSize    int
+	buffer      bytes.Buffer
+	multipart   *aws.MultipartUpload
+	pending     map[int64]pendingChunk
+	isFinished  bool
+}
+
+// pendingChunk is a chunk that has been stored on the local disk completely and is waiting to be written,
+// until all previous chunks have been written
+type pendingChunk struct {
+	path string
+	size int64
+}
+
+// Exists returns true if there is a streamed upload in progress for the given chunk ID
+func Exists(id string) bool {
+	_, ok := getUpload(id)
+	return ok
+}
+
+// IsOnOtherInstance returns true if the upload with the given chunk ID is in progress on another instance
+func IsOnOtherInstance(id string) bool {
+	if !sharedstate.IsEnabled() || Exists(id) {
+		return false
+	}
+	isLocked, err := sharedstate.Get().IsLocked(getSharedLockName(id))
+	if err != nil {
+		sharedstate.PrintError(err)
+		return false
+	}
+	return isLocked
+}
+
+// AddChunk adds a chunk to the upload with the given ID and starts a new upload, if it is the first chunk.
+// Every chunk is stored on the local disk completely, before it is passed to the hash and the encrypter.
+// If a chunk cannot be received or uploaded, an error is returned and the chunk can be sent again
+func AddChunk(content io.Reader, size int64, info chunking.ChunkInfo, maxAllowedSize int64, encrypt bool) error {
+	if info.Offset+size > info.TotalFilesizeBytes {
+		return errors.New("chunksize will be bigger than total filesize from this offset")
+	}
+	u, err := getOrCreateUpload(info, maxAllowedSize, encrypt)
+	if err != nil {
+		return err
+	}
+	u.mutex.Lock()
+	if u.isFinished {
+		u.mutex.Unlock()
+		return errors.New("upload has already been completed or aborted")
+	}
+	u.lastUpdate = time.Now()
+	if info.Offset < u.nextOffset {
+		defer u.mutex.Unlock()
+		if info.Offset+size <= u.nextOffset {
+			// Chunk has been sent again, e.g. because of a retry. A part that could not be uploaded is retried
+			return u.writePendingChunks()
+		}
+		return errors.New("chunk overlaps with data that has already been received")
+	}
+	_, isDuplicate := u.pending[info.Offset]
+	if isDuplicate {
+		defer u.mutex.Unlock()
+		return u.writePendingChunks()
+	}
+	u.mutex.Unlock()
+
+	// Writing the chunk to disk is done without holding the lock, so that other chunks are not blocked by it.
+	// If the connection is interrupted, nothing has been passed to the hash yet and the chunk can be sent again
+	chunk, err := savePendingChunk(content, size, info)
+	if err != nil {
+		return err
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	_, isDuplicate = u.pending[info.Offset]
+	if u.isFinished || isDuplicate || info.Offset < u.nextOffset {
+		_ = os.Remove(chunk.path)
+		return nil
+	}
+	u.pending[info.Offset] = chunk
+	return u.writePendingChunks()
+}
+
+// Finish completes the upload with the given ID, after all chunks have been received.
+// The returned object has to be moved or deleted by the caller
+func Finish(id string) (Result, error) {
+	u, ok := removeUpload(id)
+	if !ok {
+		return Result{}, errors.New("upload does not exist")
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.isFinished {
+		return Result{}, errors.New("upload has already been completed or aborted")
+	}
+	if u.nextOffset != u.totalSize {
+		u.abort()
+		return Result{}, errors.New("upload is incomplete, only " + strconv.FormatInt(u.nextOffset, 10) +
+			" of " + strconv.FormatInt(u.totalSize, 10) + " bytes have been received")
+	}
+	err := u.complete()
+	if err != nil {
+		u.abort()
+		return Result{}, err
+	}
+	releaseOwnership(u.id)
+	if u.isEncrypted {
+		u.hash.Write([]byte(configuration.Get().Authentication.SaltFiles))
+	}
+	return Result{
+		Bucket:     u.multipart.Bucket,
+		Key:        u.multipart.Key,
+		Hash:       hex.EncodeToString(u.hash.Sum(nil)),
+		Encryption: u.encryption,
+		Size:       u.totalSize,
+		StoredSize: u.storedSize,
+	}, nil
+}
+
+// Abort cancels the upload with the given ID and deletes all data that has been received so far
+func Abort(id string) {
+	u, ok := removeUpload(id)
+	if !ok {
+		return
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.abort()
+}
+
+// CleanUp aborts all uploads that have not received a new chunk for 24 hours
+func CleanUp() {
+	uploadsMutex.Lock()
+	idleUploads := make([]string, 0)
+	for id, u := range uploads {
+		u.mutex.Lock()
+		if time.Since(u.lastUpdate) > maxIdleTime {
+			idleUploads = append(idleUploads, id)
+		}
+		u.mutex.Unlock()
+	}
+	uploadsMutex.Unlock()
+	for _, id := range idleUploads {
+		Abort(id)
+	}
+}
+
+func getUpload(id string) (*upload, bool) {
+	uploadsMutex.Lock()
+	defer uploadsMutex.Unlock()
+	u, ok := uploads[id]
+	return u, ok
+}
+
+func removeUpload(id string) (*upload, bool) {
+	uploadsMutex.Lock()
+	defer uploadsMutex.Unlock()
+	u, ok := uploads[id]
+	if ok {
+		delete(uploads, id)
+	}
+	return u, ok
+}
+
+func getOrCreateUpload(info chunking.ChunkInfo, maxAllowedSize int64, encrypt bool) (*upload, error) {
+	uploadsMutex.Lock()
+	defer uploadsMutex.Unlock()
+	existing, ok := uploads[info.UUID]
+	if ok {
+		if existing.totalSize != info.TotalFilesizeBytes {
+			return nil, errors.New("declared file size does not match the size of the upload")
+		}
+		return existing, nil
+	}
+	if maxAllowedSize <= 0 {
+		return nil, errors.New("invalid maxAllowedSize")
+	}
+	maxSizeBytes := min(int64(configuration.Get().MaxFileSizeMB)*1024*1024, maxAllowedSize)
+	if info.TotalFilesizeBytes > maxSizeBytes {
+		return nil, errors.New("declared file size exceeds the maximum allowed size")
+	}
+	if sharedstate.IsEnabled() {
+		isOwner, err := sharedstate.Get().TryLock(getSharedLockName(info.UUID), maxIdleTime)
+		if err != nil {
+			return nil, err
+		}
+		if !isOwner {
+			return nil, ErrorOtherInstance
+		}
+	}
+	multipart, err := aws.StartMultipartUpload(aws.GetDefaultBucketName(), objectKeyPrefix+info.UUID)
+	if err != nil {
+		releaseOwnership(info.UUID)
+		return nil, err
+	}
+	newUpload := &upload{
+		id:          info.UUID,
+		totalSize:   info.TotalFilesizeBytes,
+		lastUpdate:  time.Now(),
+		isEncrypted: encrypt,
+		hash:        sha1.New(),
+		multipart:   multipart,
+		pending:     make(map[int64]pendingChunk),
+	}
+	storedSize := info.TotalFilesizeBytes
+	if encrypt {
+		newUpload.encrypter, err = encryption.EncryptWriter(&newUpload.encryption, newUpload)
+		if err != nil {
+			_ = multipart.Abort()
+			releaseOwnership(info.UUID)
+			return nil, err
+		}
+		newUpload.writer = io.MultiWriter(newUpload.hash, newUpload.encrypter)
+		storedSize = encryption.CalculateEncryptedFilesize(storedSize)
+	} else {
+		newUpload.writer = io.MultiWriter(newUpload.hash, newUpload)
+	}
+	newUpload.partSize = getPartSize(storedSize)
+	uploads[info.UUID] = newUpload
+	return newUpload, nil
+}
+
+// getPartSize returns the part size that is required to upload a file of the given size
+// without exceeding the maximum amount of parts
+func getPartSize(storedSize int64) int {
+	// The last part is usually smaller, therefore a few parts are kept in reserve
+	requiredSize := storedSize/(aws.MaxPartCount-10) + 1
+	return int(max(minPartSize, requiredSize))
+}
+
+// getSharedLockName returns the name of the shared lock, which marks the instance that handles the upload
+func getSharedLockName(id string) string {
+	return "streamupload:" + id
+}
+
+// releaseOwnership releases the shared lock of the upload, after it has been completed or aborted
+func releaseOwnership(id string) {
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Get().Unlock(getSharedLockName(id))
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+	}
+}
+
+// Write is called with the (encrypted) content of the upload. The content is buffered, until
+// uploadFullPart uploads it as the next part
+func (u *upload) Write(p []byte) (int, error) {
+	u.buffer.Write(p)
+	u.storedSize = u.storedSize + int64(len(p))
+	return len(p), nil
+}
+
+// uploadFullPart uploads the buffered content, once it has reached the part size
+func (u *upload) uploadFullPart() error {
+	if u.buffer.Len() < u.partSize {
+		return nil
+	}
+	return u.uploadPart()
+}
+
+// uploadPart uploads the buffered content as the next part. If the upload fails, the content
+// is kept in the buffer, so that it can be uploaded again
+func (u *upload) uploadPart() error {
+	err := u.multipart.UploadPart(u.buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	u.buffer.Reset()
+	return nil
+}
+
+// write passes the next chunk to the hash and the multipart upload. Must only be called
+// with the content for the current offset
+func (u *upload) write(content io.Reader, size int64) error {
+	written, err := io.CopyN(u.writer, content, size)
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return errors.New("chunk is smaller than the declared size")
+	}
+	u.nextOffset = u.nextOffset + size
+	return nil
+}
+
+// writePendingChunks writes all chunks that have been received and are now next in line.
+// Before a chunk is written, the previous part is uploaded if it is complete. If that fails,
+// the chunk is kept and the upload of the part is retried, once a chunk is sent again
+func (u *upload) writePendingChunks() error {
+	for {
+		err := u.uploadFullPart()
+		if err != nil {
+			return err
+		}
+		chunk, ok := u.pending[u.nextOffset]
+		if !ok {
+			return nil
+		}
+		delete(u.pending, u.nextOffset)
+		err = u.writePendingChunk(chunk)
+		if err != nil {
+			// Parts of the chunk might already have been passed to the hash
+			u.abort()
+			return err
+		}
+	}
+}
+
+func (u *upload) writePendingChunk(chunk pendingChunk) error {
+	file, err := os.Open(chunk.path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(chunk.path)
+	}()
+	return u.write(file, chunk.size)
+}
+
+func savePendingChunk(content io.Reader, size int64, info chunking.ChunkInfo) (pendingChunk, error) {
+	file, err := os.CreateTemp(configuration.Get().DataDir, "chunk-"+info.UUID+"-"+strconv.FormatInt(info.Offset, 10)+"-")
+	if err != nil {
+		return pendingChunk{}, err
+	}
+	path := file.Name()
+	written, err := io.CopyN(file, content, size)
+	closeErr := file.Close()
+	if err == nil && written != size {
+		err = errors.New("chunk is smaller than the declared size")
+	}
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return pendingChunk{}, err
+	}
+	return pendingChunk{path: path, size: size}, nil
+}
+
+// complete uploads the remaining data and assembles the parts into the final object
+func (u *upload) complete() error {
+	if u.encrypter != nil {
+		err := u.encrypter.Close()
+		if err != nil {
+			return err
+		}
+	}
+	// S3 requires at least one part, even if the file is empty
+	if u.buffer.Len() > 0 || u.multipart.GetPartCount() == 0 {
+		err := u.uploadPart()
+		if err != nil {
+			return err
+		}
+	}
+	err := u.multipart.Complete()
+	if err != nil {
+		return err
+	}
+	u.isFinished = true
+	return nil
+}
+
+// abort cancels the multipart upload and removes all pending chunks. The lock of the
+// upload has to be held by the caller
+func (u *upload) abort() {
+	if u.isFinished {
+		return
+	}
+	u.isFinished = true
+	uploadsMutex.Lock()
+	if uploads[u.id] == u {
+		delete(uploads, u.id)
+	}
+	uploadsMutex.Unlock()
+	releaseOwnership(u.id)
+	err := u.multipart.Abort()
+	if err != nil {
+		fmt.Println("Could not abort multipart upload " + u.id + ": " + err.Error())
+	}
+	for _, chunk := range u.pending {
+		_ = os.Remove(chunk.path)
+	}
+	u.pending = make(map[int64]pendingChunk)
+	u.buffer.Reset()
+}
@@ -0,0 +1,365 @@
+//go:build test && awsmock
+
+package streamupload
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate/redisstate"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+	"golang.org/x/sync/errgroup"
+)
+
+const chunkSize = 6 * 1024 * 1024
+const maxAllowedSize = 100 * 1024 * 1024
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	configuration.Load()
+	testconfiguration.EnableS3()
+	aws.Init(models.AwsConfig{StreamUploads: true})
+	exitVal := m.Run()
+	testconfiguration.DisableS3()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+func getRandomContent(t *testing.T, size int) []byte {
+	t.Helper()
+	content := make([]byte, size)
+	_, err := rand.Read(content)
+	test.IsNil(t, err)
+	return content
+}
+
+func addChunk(t *testing.T, id string, content []byte, offset int, encrypt bool) error {
+	t.Helper()
+	end := min(offset+chunkSize, len(content))
+	info := chunking.ChunkInfo{
+		TotalFilesizeBytes: int64(len(content)),
+		Offset:             int64(offset),
+		UUID:               id,
+	}
+	return AddChunk(bytes.NewReader(content[offset:end]), int64(end-offset), info, maxAllowedSize, encrypt)
+}
+
+func getStoredContent(t *testing.T, result Result, encrypted bool) []byte {
+	t.Helper()
+	file := models.File{AwsBucket: result.Bucket, SHA1: result.Key}
+	if encrypted {
+		file.Encryption = result.Encryption
+	}
+	var buffer bytes.Buffer
+	err := aws.Stream(&buffer, file)
+	test.IsNil(t, err)
+	return buffer.Bytes()
+}
+
+func countPendingChunks(t *testing.T, id string) int {
+	t.Helper()
+	entries, err := os.ReadDir(configuration.Get().DataDir)
+	test.IsNil(t, err)
+	count := 0
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "chunk-"+id+"-") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestUploadInOrder(t *testing.T) {
+	content := getRandomContent(t, 20*1024*1024)
+	for offset := 0; offset < len(content); offset = offset + chunkSize {
+		test.IsNil(t, addChunk(t, "inorder-upload", content, offset, false))
+	}
+	test.IsEqualBool(t, Exists("inorder-upload"), true)
+	result, err := Finish("inorder-upload")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, Exists("inorder-upload"), false)
+	hash := sha1.Sum(content)
+	test.IsEqualString(t, result.Hash, hex.EncodeToString(hash[:]))
+	test.IsEqualString(t, result.Key, "gokapi-upload-inorder-upload")
+	test.IsEqualString(t, result.Bucket, "gokapi-test")
+	test.IsEqualInt64(t, result.Size, int64(len(content)))
+	test.IsEqualInt64(t, result.StoredSize, int64(len(content)))
+	test.IsEqualBool(t, result.Encryption.IsEncrypted, false)
+	test.IsEqualBool(t, bytes.Equal(getStoredContent(t, result, false), content), true)
+
+	_, err = Finish("inorder-upload")
+	test.IsNotNil(t, err)
+}
+
+func TestUploadOutOfOrder(t *testing.T) {
+	content := getRandomContent(t, 20*1024*1024)
+	test.IsNil(t, addChunk(t, "outoforder-upload", content, chunkSize, false))
+	test.IsNil(t, addChunk(t, "outoforder-upload", content, 3*chunkSize, false))
+	test.IsEqualInt(t, countPendingChunks(t, "outoforder-upload"), 2)
+	// Duplicate chunks are ignored
+	test.IsNil(t, addChunk(t, "outoforder-upload", content, chunkSize, false))
+	test.IsEqualInt(t, countPendingChunks(t, "outoforder-upload"), 2)
+
+	test.IsNil(t, addChunk(t, "outoforder-upload", content, 0, false))
+	test.IsEqualInt(t, countPendingChunks(t, "outoforder-upload"), 1)
+	test.IsNil(t, addChunk(t, "outoforder-upload", content, 0, false))
+	test.IsNil(t, addChunk(t, "outoforder-upload", content, 2*chunkSize, false))
+	test.IsEqualInt(t, countPendingChunks(t, "outoforder-upload"), 0)
+
+	result, err := Finish("outoforder-upload")
+	test.IsNil(t, err)
+	hash := sha1.Sum(content)
+	test.IsEqualString(t, result.Hash, hex.EncodeToString(hash[:]))
+	test.IsEqualBool(t, bytes.Equal(getStoredContent(t, result, false), content), true)
+}
+
+func TestUploadParallel(t *testing.T) {
+	content := getRandomContent(t, 20*1024*1024+500)
+	var group errgroup.Group
+	for offset := 0; offset < len(content); offset = offset + chunkSize {
+		group.Go(func() error {
+			return addChunk(t, "parallel-upload", content, offset, false)
+		})
+	}
+	test.IsNil(t, group.Wait())
+	test.IsEqualInt(t, countPendingChunks(t, "parallel-upload"), 0)
+	result, err := Finish("parallel-upload")
+	test.IsNil(t, err)
+	hash := sha1.Sum(content)
+	test.IsEqualString(t, result.Hash, hex.EncodeToString(hash[:]))
+	test.IsEqualBool(t, bytes.Equal(getStoredContent(t, result, false), content), true)
+}
+
+func TestUploadEncrypted(t *testing.T) {
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encryption.Init(models.Configuration{Encryption: models.Encryption{
+		Level:  encryption.FullEncryptionStored,
+		Cipher: cipher,
+	}})
+	content := getRandomContent(t, 13*1024*1024)
+	for offset := 0; offset < len(content); offset = offset + chunkSize {
+		test.IsNil(t, addChunk(t, "encrypted-upload", content, offset, true))
+	}
+	result, err := Finish("encrypted-upload")
+	test.IsNil(t, err)
+	test.IsEqualBool(t, result.Encryption.IsEncrypted, true)
+	test.IsEqualInt64(t, result.Size, int64(len(content)))
+	test.IsEqualInt64(t, result.StoredSize, encryption.CalculateEncryptedFilesize(int64(len(content))))
+	hash := sha1.Sum(append(content, []byte(configuration.Get().Authentication.SaltFiles)...))
+	test.IsEqualString(t, result.Hash, hex.EncodeToString(hash[:]))
+
+	stored := getStoredContent(t, result, false)
+	test.IsEqualInt64(t, int64(len(stored)), result.StoredSize)
+	test.IsEqualBool(t, bytes.Equal(stored[:100], content[:100]), false)
+	test.IsEqualBool(t, bytes.Equal(getStoredContent(t, result, true), content), true)
+}
+
+func TestUploadEmptyFile(t *testing.T) {
+	test.IsNil(t, addChunk(t, "empty-upload", []byte{}, 0, false))
+	result, err := Finish("empty-upload")
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, result.Size, 0)
+	test.IsEqualString(t, result.Hash, "da39a3ee5e6b4b0d3255bfef95601890afd80709")
+}
+
+func TestInvalidChunks(t *testing.T) {
+	content := getRandomContent(t, 20*1024*1024)
+	info := chunking.ChunkInfo{TotalFilesizeBytes: 10, UUID: "invalid-upload"}
+	err := AddChunk(bytes.NewReader(content), 20, info, maxAllowedSize, false)
+	test.IsNotNil(t, err)
+	err = AddChunk(bytes.NewReader(content), 10, info, 0, false)
+	test.IsNotNil(t, err)
+	info.TotalFilesizeBytes = maxAllowedSize + 1
+	err = AddChunk(bytes.NewReader(content), 10, info, maxAllowedSize, false)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, Exists("invalid-upload"), false)
+
+	test.IsNil(t, addChunk(t, "invalid-upload", content, 0, false))
+	info.TotalFilesizeBytes = 10
+	err = AddChunk(bytes.NewReader(content), 10, info, maxAllowedSize, false)
+	test.IsNotNil(t, err)
+
+	info = chunking.ChunkInfo{TotalFilesizeBytes: int64(len(content)), Offset: chunkSize - 10, UUID: "invalid-upload"}
+	err = AddChunk(bytes.NewReader(content), 20, info, maxAllowedSize, false)
+	test.IsNotNil(t, err)
+
+	info.Offset = 2 * chunkSize
+	err = AddChunk(bytes.NewReader(content[:10]), 20, info, maxAllowedSize, false)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, Exists("invalid-upload"), true)
+	test.IsEqualInt(t, countPendingChunks(t, "invalid-upload"), 0)
+
+	info.Offset = 0
+	err = AddChunk(bytes.NewReader(content[:10]), 20, info, maxAllowedSize, false)
+	test.IsNil(t, err)
+	info.Offset = chunkSize
+	err = AddChunk(bytes.NewReader(content[:10]), 20, info, maxAllowedSize, false)
+	test.IsNotNil(t, err)
+	// The upload is kept, so that the chunk can be sent again
+	test.IsEqualBool(t, Exists("invalid-upload"), true)
+	test.IsEqualInt(t, countPendingChunks(t, "invalid-upload"), 0)
+	Abort("invalid-upload")
+}
+
+// interruptedReader returns an error after the content has been read, like a connection that is interrupted
+type interruptedReader struct {
+	content io.Reader
+}
+
+func (r *interruptedReader) Read(p []byte) (int, error) {
+	n, err := r.content.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestResendInterruptedChunk(t *testing.T) {
+	content := getRandomContent(t, 20*1024*1024)
+	test.IsNil(t, addChunk(t, "interrupted-upload", content, 0, false))
+	info := chunking.ChunkInfo{TotalFilesizeBytes: int64(len(content)), Offset: chunkSize, UUID: "interrupted-upload"}
+	err := AddChunk(&interruptedReader{bytes.NewReader(content[chunkSize : chunkSize+100])}, chunkSize, info, maxAllowedSize, false)
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, Exists("interrupted-upload"), true)
+	test.IsEqualInt(t, countPendingChunks(t, "interrupted-upload"), 0)
+
+	for offset := chunkSize; offset < len(content); offset = offset + chunkSize {
+		test.IsNil(t, addChunk(t, "interrupted-upload", content, offset, false))
+	}
+	result, err := Finish("interrupted-upload")
+	test.IsNil(t, err)
+	hash := sha1.Sum(content)
+	test.IsEqualString(t, result.Hash, hex.EncodeToString(hash[:]))
+	test.IsEqualBool(t, bytes.Equal(getStoredContent(t, result, false), content), true)
+}
+
+func TestUploadPartError(t *testing.T) {
+	content := getRandomContent(t, 20*1024*1024)
+	test.IsNil(t, addChunk(t, "parterror-upload", content, 0, false))
+	aws.SetUploadPartError(errors.New("service unavailable"))
+	// The second chunk fills the first part, which cannot be uploaded
+	test.IsNotNil(t, addChunk(t, "parterror-upload", content, chunkSize, false))
+	test.IsNotNil(t, addChunk(t, "parterror-upload", content, chunkSize, false))
+	test.IsNotNil(t, addChunk(t, "parterror-upload", content, 2*chunkSize, false))
+	test.IsEqualBool(t, Exists("parterror-upload"), true)
+	test.IsEqualBool(t, aws.IsMultipartUploadPending("mock-gokapi-upload-parterror-upload"), true)
+
+	aws.SetUploadPartError(nil)
+	test.IsNil(t, addChunk(t, "parterror-upload", content, chunkSize, false))
+	test.IsEqualInt(t, countPendingChunks(t, "parterror-upload"), 0)
+	test.IsNil(t, addChunk(t, "parterror-upload", content, 3*chunkSize, false))
+	result, err := Finish("parterror-upload")
+	test.IsNil(t, err)
+	hash := sha1.Sum(content)
+	test.IsEqualString(t, result.Hash, hex.EncodeToString(hash[:]))
+	test.IsEqualBool(t, bytes.Equal(getStoredContent(t, result, false), content), true)
+}
+
+func TestUploadOnOtherInstance(t *testing.T) {
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	defer sharedstate.Close()
+	otherInstance, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	defer otherInstance.Close()
+
+	content := getRandomContent(t, 10*1024*1024)
+	isLocked, err := otherInstance.TryLock(getSharedLockName("foreign-upload"), time.Minute)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, true)
+	err = addChunk(t, "foreign-upload", content, 0, false)
+	test.IsEqualBool(t, errors.Is(err, ErrorOtherInstance), true)
+	test.IsEqualBool(t, IsOnOtherInstance("foreign-upload"), true)
+	test.IsEqualBool(t, Exists("foreign-upload"), false)
+
+	test.IsNil(t, addChunk(t, "shared-upload", content, 0, false))
+	isLocked, err = otherInstance.IsLocked(getSharedLockName("shared-upload"))
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, true)
+	test.IsEqualBool(t, IsOnOtherInstance("shared-upload"), false)
+	test.IsNil(t, addChunk(t, "shared-upload", content, chunkSize, false))
+	_, err = Finish("shared-upload")
+	test.IsNil(t, err)
+	isLocked, err = otherInstance.IsLocked(getSharedLockName("shared-upload"))
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, false)
+
+	test.IsNil(t, addChunk(t, "shared-aborted-upload", content, 0, false))
+	Abort("shared-aborted-upload")
+	isLocked, err = otherInstance.IsLocked(getSharedLockName("shared-aborted-upload"))
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, false)
+}
+
+func TestFinishIncomplete(t *testing.T) {
+	content := getRandomContent(t, 20*1024*1024)
+	test.IsNil(t, addChunk(t, "incomplete-upload", content, 0, false))
+	test.IsNil(t, addChunk(t, "incomplete-upload", content, 2*chunkSize, false))
+	test.IsEqualInt(t, countPendingChunks(t, "incomplete-upload"), 1)
+	test.IsEqualBool(t, aws.IsMultipartUploadPending("mock-gokapi-upload-incomplete-upload"), true)
+	_, err := Finish("incomplete-upload")
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, Exists("incomplete-upload"), false)
+	test.IsEqualInt(t, countPendingChunks(t, "incomplete-upload"), 0)
+	test.IsEqualBool(t, aws.IsMultipartUploadPending("mock-gokapi-upload-incomplete-upload"), false)
+
+	_, err = Finish("invalid")
+	test.IsNotNil(t, err)
+}
+
+func TestAbort(t *testing.T) {
+	content := getRandomContent(t, 20*1024*1024)
+	test.IsNil(t, addChunk(t, "aborted-upload", content, 0, false))
+	test.IsNil(t, addChunk(t, "aborted-upload", content, 2*chunkSize, false))
+	test.IsEqualInt(t, countPendingChunks(t, "aborted-upload"), 1)
+	Abort("aborted-upload")
+	Abort("aborted-upload")
+	test.IsEqualBool(t, Exists("aborted-upload"), false)
+	test.IsEqualInt(t, countPendingChunks(t, "aborted-upload"), 0)
+	test.IsEqualBool(t, aws.IsMultipartUploadPending("mock-gokapi-upload-aborted-upload"), false)
+}
+
+func TestCleanUp(t *testing.T) {
+	content := getRandomContent(t, 20*1024*1024)
+	test.IsNil(t, addChunk(t, "idle-upload", content, 0, false))
+	test.IsNil(t, addChunk(t, "active-upload", content, 0, false))
+	CleanUp()
+	test.IsEqualBool(t, Exists("idle-upload"), true)
+	test.IsEqualBool(t, Exists("active-upload"), true)
+
+	u, ok := getUpload("idle-upload")
+	test.IsEqualBool(t, ok, true)
+	u.lastUpdate = time.Now().Add(-25 * time.Hour)
+	CleanUp()
+	test.IsEqualBool(t, Exists("idle-upload"), false)
+	test.IsEqualBool(t, Exists("active-upload"), true)
+	Abort("active-upload")
+}
+
+func TestGetPartSize(t *testing.T) {
+	test.IsEqualInt(t, getPartSize(0), minPartSize)
+	test.IsEqualInt(t, getPartSize(50*1024*1024), minPartSize)
+	size := int64(200) * 1024 * 1024 * 1024
+	partSize := getPartSize(size)
+	test.IsEqualBool(t, partSize > minPartSize, true)
+	test.IsEqualBool(t, size/int64(partSize) < aws.MaxPartCount, true)
+}
@@ -3,6 +3,7 @@
 package aws
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -61,6 +62,12 @@ func IsAvailable() bool {
 	return isCorrectLogin
 }
 
+// IsStreamUploadEnabled returns true if chunked uploads shall be streamed directly into
+// multipart uploads instead of being assembled on the local disk first
+func IsStreamUploadEnabled() bool {
+	return isCorrectLogin && awsConfig.StreamUploads
+}
+
 // LogOut resets the credentials
 func LogOut() {
 	awsConfig = models.AwsConfig{}
@@ -295,6 +302,171 @@ func DeleteObject(file models.File) (bool, error) {
 	return true, nil
 }
 
+// ListObjects returns all objects that are stored in the bucket
+func ListObjects(bucket string) ([]StoredObject, error) {
+	svc := s3.New(createSession())
+	result := make([]StoredObject, 0)
+	err := svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			storedObject := StoredObject{
+				Key:  aws.StringValue(object.Key),
+				Size: aws.Int64Value(object.Size),
+			}
+			if object.LastModified != nil {
+				storedObject.LastModified = object.LastModified.Unix()
+			}
+			result = append(result, storedObject)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StartMultipartUpload initiates a new multipart upload for the given key
+func StartMultipartUpload(bucket, key string) (*MultipartUpload, error) {
+	svc := s3.New(createSession())
+	result, err := svc.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MultipartUpload{
+		Bucket:   bucket,
+		Key:      key,
+		UploadId: *result.UploadId,
+	}, nil
+}
+
+// UploadPart uploads the next part of the multipart upload. Every part apart from the
+// last one has to be at least MinPartSize bytes long
+func (u *MultipartUpload) UploadPart(content []byte) error {
+	svc := s3.New(createSession())
+	partNumber := u.nextPartNumber()
+	result, err := svc.UploadPart(&s3.UploadPartInput{
+		Bucket:        aws.String(u.Bucket),
+		Key:           aws.String(u.Key),
+		UploadId:      aws.String(u.UploadId),
+		PartNumber:    aws.Int64(partNumber),
+		Body:          bytes.NewReader(content),
+		ContentLength: aws.Int64(int64(len(content))),
+	})
+	if err != nil {
+		return err
+	}
+	u.parts = append(u.parts, completedPart{PartNumber: partNumber, ETag: aws.StringValue(result.ETag)})
+	return nil
+}
+
+// Complete assembles all uploaded parts into the final object
+func (u *MultipartUpload) Complete() error {
+	svc := s3.New(createSession())
+	_, err := svc.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.Bucket),
+		Key:             aws.String(u.Key),
+		UploadId:        aws.String(u.UploadId),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: u.getCompletedParts()},
+	})
+	return err
+}
+
+// Abort cancels the multipart upload and deletes all parts that have been uploaded
+func (u *MultipartUpload) Abort() error {
+	svc := s3.New(createSession())
+	_, err := svc.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(u.Bucket),
+		Key:      aws.String(u.Key),
+		UploadId: aws.String(u.UploadId),
+	})
+	return err
+}
+
+func (u *MultipartUpload) getCompletedParts() []*s3.CompletedPart {
+	result := make([]*s3.CompletedPart, len(u.parts))
+	for i, part := range u.parts {
+		result[i] = &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(part.PartNumber),
+		}
+	}
+	return result
+}
+
+// maxCopyObjectSize is the largest object that can be copied with a single CopyObject request
+const maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+// copyPartSize is the size of each part if an object has to be copied with a multipart copy
+const copyPartSize = 1024 * 1024 * 1024
+
+// MoveObject copies an object to a new key on the server side and deletes the source object afterwards.
+// Objects larger than 5GB are copied with a multipart copy
+func MoveObject(bucket, sourceKey, destinationKey string, size int64) error {
+	var err error
+	if size <= maxCopyObjectSize {
+		err = copyObject(bucket, sourceKey, destinationKey)
+	} else {
+		err = copyObjectMultipart(bucket, sourceKey, destinationKey, size)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = DeleteObject(models.File{AwsBucket: bucket, SHA1: sourceKey})
+	return err
+}
+
+func getCopySource(bucket, key string) string {
+	return bucket + "/" + url.PathEscape(key)
+}
+
+func copyObject(bucket, sourceKey, destinationKey string) error {
+	svc := s3.New(createSession())
+	_, err := svc.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(destinationKey),
+		CopySource: aws.String(getCopySource(bucket, sourceKey)),
+	})
+	return err
+}
+
+func copyObjectMultipart(bucket, sourceKey, destinationKey string, size int64) error {
+	upload, err := StartMultipartUpload(bucket, destinationKey)
+	if err != nil {
+		return err
+	}
+	svc := s3.New(createSession())
+	for offset := int64(0); offset < size; offset = offset + copyPartSize {
+		end := min(offset+copyPartSize, size) - 1
+		partNumber := upload.nextPartNumber()
+		result, err := svc.UploadPartCopy(&s3.UploadPartCopyInput{
+			Bucket:          aws.String(bucket),
+			Key:             aws.String(destinationKey),
+			UploadId:        aws.String(upload.UploadId),
+			PartNumber:      aws.Int64(partNumber),
+			CopySource:      aws.String(getCopySource(bucket, sourceKey)),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+		})
+		if err != nil {
+			_ = upload.Abort()
+			return err
+		}
+		upload.parts = append(upload.parts, completedPart{
+			PartNumber: partNumber,
+			ETag:       aws.StringValue(result.CopyPartResult.ETag),
+		})
+	}
+	err = upload.Complete()
+	if err != nil {
+		_ = upload.Abort()
+	}
+	return err
+}
+
 // IsCorsCorrectlySet returns true if CORS rules allow download from Gokapi
 func IsCorsCorrectlySet(bucket, gokapiUrl string) (bool, error) {
 	sess := createSession()
@@ -10,12 +10,22 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/forceu/gokapi/internal/encryption"
 	"github.com/forceu/gokapi/internal/models"
 )
 
 var uploadedFiles []models.File
 var isCorrectLogin bool
+var isStreamUploadEnabled bool
+
+// storedContent contains the content of objects that have been created by a multipart upload
+var storedContent = make(map[string][]byte)
+var pendingParts = make(map[string][]byte)
+var lastPartSizes = make(map[string]int)
+var mutexContent sync.Mutex
+var uploadPartError error
 
 const (
 	region     = "mock-region-1"
@@ -35,6 +45,7 @@ func Init(config models.AwsConfig) bool {
 	if !isValidCredentials() {
 		return false
 	}
+	isStreamUploadEnabled = config.StreamUploads
 	Upload(bytes.NewReader([]byte("test")), models.File{
 		Id:        "awsTest1234567890123",
 		Name:      "aws Test File",
@@ -55,9 +66,16 @@ func IsValidLogin(config models.AwsConfig) (bool, error) {
 	return isValidCredentials(), nil
 }
 
+// IsStreamUploadEnabled returns true if chunked uploads shall be streamed directly into
+// multipart uploads instead of being assembled on the local disk first
+func IsStreamUploadEnabled() bool {
+	return isCorrectLogin && isStreamUploadEnabled
+}
+
 // LogOut resets the credentials
 func LogOut() {
 	isCorrectLogin = false
+	isStreamUploadEnabled = false
 }
 
 // AddBucketName adds the bucket name to the file to be stored
@@ -157,7 +175,12 @@ func FileExists(file models.File) (bool, int64, error) {
 	if !isUploaded(file) {
 		return false, 0, nil
 	}
-
+	mutexContent.Lock()
+	content, ok := storedContent[file.SHA1]
+	mutexContent.Unlock()
+	if ok {
+		return true, int64(len(content)), nil
+	}
 	return true, 10000, nil
 }
 
@@ -174,6 +197,9 @@ func DeleteObject(file models.File) (bool, error) {
 		}
 	}
 	uploadedFiles = buffer
+	mutexContent.Lock()
+	delete(storedContent, file.SHA1)
+	mutexContent.Unlock()
 
 	return true, nil
 }
@@ -207,6 +233,16 @@ func Stream(writer io.Writer, file models.File) error {
 		return errors.New("invalid credentials / invalid bucket / invalid region")
 	}
 
+	mutexContent.Lock()
+	content, ok := storedContent[file.SHA1]
+	mutexContent.Unlock()
+	if ok {
+		if file.Encryption.IsEncrypted {
+			return encryption.DecryptReader(file.Encryption, bytes.NewReader(content), writer)
+		}
+		_, err := writer.Write(content)
+		return err
+	}
 	if isUploaded(file) {
 		data, err := os.Open("data/" + file.SHA1)
 		if err != nil {
@@ -218,3 +254,121 @@ func Stream(writer io.Writer, file models.File) error {
 	}
 	return errors.New("file not found")
 }
+
+// ListObjects returns all objects that are stored in the bucket
+func ListObjects(bucket string) ([]StoredObject, error) {
+	if !isValidCredentials() {
+		return nil, errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	result := make([]StoredObject, 0)
+	for _, element := range uploadedFiles {
+		if element.AwsBucket != bucket {
+			continue
+		}
+		_, size, _ := FileExists(element)
+		result = append(result, StoredObject{Key: element.SHA1, Size: size})
+	}
+	return result, nil
+}
+
+// StartMultipartUpload initiates a new multipart upload for the given key
+func StartMultipartUpload(bucket, key string) (*MultipartUpload, error) {
+	if !isValidCredentials() {
+		return nil, errors.New("invalid credentials / invalid bucket / invalid region")
+	}
+	upload := MultipartUpload{
+		Bucket:   bucket,
+		Key:      key,
+		UploadId: "mock-" + key,
+	}
+	mutexContent.Lock()
+	pendingParts[upload.UploadId] = []byte{}
+	mutexContent.Unlock()
+	return &upload, nil
+}
+
+// UploadPart uploads the next part of the multipart upload. Every part apart from the
+// last one has to be at least MinPartSize bytes long
+func (u *MultipartUpload) UploadPart(content []byte) error {
+	mutexContent.Lock()
+	defer mutexContent.Unlock()
+	existing, ok := pendingParts[u.UploadId]
+	if !ok {
+		return errors.New("upload not found")
+	}
+	if uploadPartError != nil {
+		return uploadPartError
+	}
+	if len(u.parts) > 0 && lastPartSizes[u.UploadId] < MinPartSize {
+		return errors.New("previous part is too small")
+	}
+	pendingParts[u.UploadId] = append(existing, content...)
+	lastPartSizes[u.UploadId] = len(content)
+	u.parts = append(u.parts, completedPart{PartNumber: u.nextPartNumber(), ETag: "etag"})
+	return nil
+}
+
+// Complete assembles all uploaded parts into the final object
+func (u *MultipartUpload) Complete() error {
+	mutexContent.Lock()
+	content, ok := pendingParts[u.UploadId]
+	if !ok {
+		mutexContent.Unlock()
+		return errors.New("upload not found")
+	}
+	delete(pendingParts, u.UploadId)
+	delete(lastPartSizes, u.UploadId)
+	storedContent[u.Key] = content
+	mutexContent.Unlock()
+	_, err := Upload(bytes.NewReader(content), models.File{SHA1: u.Key, AwsBucket: u.Bucket})
+	return err
+}
+
+// Abort cancels the multipart upload and deletes all parts that have been uploaded
+func (u *MultipartUpload) Abort() error {
+	mutexContent.Lock()
+	defer mutexContent.Unlock()
+	delete(pendingParts, u.UploadId)
+	delete(lastPartSizes, u.UploadId)
+	return nil
+}
+
+// IsMultipartUploadPending returns true if the multipart upload has neither been completed nor aborted
+func IsMultipartUploadPending(uploadId string) bool {
+	mutexContent.Lock()
+	defer mutexContent.Unlock()
+	_, ok := pendingParts[uploadId]
+	return ok
+}
+
+// SetUploadPartError lets all further calls of UploadPart fail with err, until it is called with nil
+func SetUploadPartError(err error) {
+	mutexContent.Lock()
+	defer mutexContent.Unlock()
+	uploadPartError = err
+}
+
+// MoveObject copies an object to a new key on the server side and deletes the source object afterwards.
+// Objects larger than 5GB are copied with a multipart copy
+func MoveObject(bucket, sourceKey, destinationKey string, size int64) error {
+	source := models.File{SHA1: sourceKey, AwsBucket: bucket}
+	if !isUploaded(source) {
+		return errors.New("file not found")
+	}
+	mutexContent.Lock()
+	content := storedContent[sourceKey]
+	mutexContent.Unlock()
+	_, err := DeleteObject(models.File{SHA1: destinationKey, AwsBucket: bucket})
+	if err != nil {
+		return err
+	}
+	mutexContent.Lock()
+	storedContent[destinationKey] = content
+	mutexContent.Unlock()
+	_, err = Upload(bytes.NewReader(content), models.File{SHA1: destinationKey, AwsBucket: bucket})
+	if err != nil {
+		return err
+	}
+	_, err = DeleteObject(source)
+	return err
+}
@@ -48,6 +48,12 @@ func download(writer io.WriterAt, file models.File) (int64, error) {
 	return 0, errors.New(errorString)
 }
 
+// IsStreamUploadEnabled returns true if chunked uploads shall be streamed directly into
+// multipart uploads instead of being assembled on the local disk first
+func IsStreamUploadEnabled() bool {
+	return false
+}
+
 // LogOut resets the credentials
 func LogOut() {
 }
@@ -88,3 +94,35 @@ func GetDefaultBucketName() string {
 func Stream(writer io.Writer, file models.File) error {
 	return errors.New(errorString)
 }
+
+// ListObjects returns all objects that are stored in the bucket
+func ListObjects(bucket string) ([]StoredObject, error) {
+	return nil, errors.New(errorString)
+}
+
+// StartMultipartUpload initiates a new multipart upload for the given key
+func StartMultipartUpload(bucket, key string) (*MultipartUpload, error) {
+	return nil, errors.New(errorString)
+}
+
+// UploadPart uploads the next part of the multipart upload. Every part apart from the
+// last one has to be at least MinPartSize bytes long
+func (u *MultipartUpload) UploadPart(content []byte) error {
+	return errors.New(errorString)
+}
+
+// Complete assembles all uploaded parts into the final object
+func (u *MultipartUpload) Complete() error {
+	return errors.New(errorString)
+}
+
+// Abort cancels the multipart upload and deletes all parts that have been uploaded
+func (u *MultipartUpload) Abort() error {
+	return errors.New(errorString)
+}
+
+// MoveObject copies an object to a new key on the server side and deletes the source object afterwards.
+// Objects larger than 5GB are copied with a multipart copy
+func MoveObject(bucket, sourceKey, destinationKey string, size int64) error {
+	return errors.New(errorString)
+}
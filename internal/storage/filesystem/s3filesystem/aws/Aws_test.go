@@ -3,6 +3,7 @@
 package aws
 
 import (
+	"bytes"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -189,6 +190,42 @@ func TestDeleteObject(t *testing.T) {
 	test.IsEqualBool(t, result, true)
 	test.IsNil(t, err)
 }
+func TestMultipartUpload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), MinPartSize/10+1)
+	upload, err := StartMultipartUpload(testFile.AwsBucket, "multipart")
+	test.IsNil(t, err)
+	test.IsNotEmpty(t, upload.UploadId)
+	test.IsNil(t, upload.UploadPart(content))
+	test.IsNil(t, upload.UploadPart([]byte("end")))
+	test.IsEqualInt(t, upload.GetPartCount(), 2)
+	test.IsNil(t, upload.Complete())
+	multipartFile := models.File{AwsBucket: testFile.AwsBucket, SHA1: "multipart"}
+	var buffer bytes.Buffer
+	test.IsNil(t, Stream(&buffer, multipartFile))
+	test.IsEqualString(t, buffer.String(), string(content)+"end")
+
+	upload, err = StartMultipartUpload(testFile.AwsBucket, "aborted")
+	test.IsNil(t, err)
+	test.IsNil(t, upload.UploadPart(content))
+	test.IsNil(t, upload.Abort())
+	result, _, err := FileExists(models.File{AwsBucket: testFile.AwsBucket, SHA1: "aborted"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, result, false)
+
+	err = MoveObject(testFile.AwsBucket, "multipart", "moved", int64(buffer.Len()))
+	test.IsNil(t, err)
+	result, _, err = FileExists(multipartFile)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, result, false)
+	movedFile := models.File{AwsBucket: testFile.AwsBucket, SHA1: "moved"}
+	buffer.Reset()
+	test.IsNil(t, Stream(&buffer, movedFile))
+	test.IsEqualString(t, buffer.String(), string(content)+"end")
+	_, err = DeleteObject(movedFile)
+	test.IsNil(t, err)
+	test.IsNotNil(t, MoveObject(testFile.AwsBucket, "invalid", "moved", 10))
+}
+
 func TestLogOut(t *testing.T) {
 	test.IsEqualBool(t, isCorrectLogin, true)
 	LogOut()
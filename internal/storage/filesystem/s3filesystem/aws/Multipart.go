@@ -0,0 +1,29 @@
+package aws
+
+// MultipartUpload is an S3 multipart upload that has been started, but has not been completed yet
+type MultipartUpload struct {
+	Bucket   string
+	Key      string
+	UploadId string
+	parts    []completedPart
+}
+
+type completedPart struct {
+	PartNumber int64
+	ETag       string
+}
+
+// MinPartSize is the minimum size of every part of a multipart upload, apart from the last one
+const MinPartSize = 5 * 1024 * 1024
+
+// MaxPartCount is the maximum amount of parts a multipart upload can consist of
+const MaxPartCount = 10000
+
+// GetPartCount returns the amount of parts that have been uploaded so far
+func (u *MultipartUpload) GetPartCount() int {
+	return len(u.parts)
+}
+
+func (u *MultipartUpload) nextPartNumber() int64 {
+	return int64(len(u.parts) + 1)
+}
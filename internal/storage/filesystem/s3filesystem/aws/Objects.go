@@ -0,0 +1,8 @@
+package aws
+
+// StoredObject contains information about an object in a bucket
+type StoredObject struct {
+	Key          string
+	Size         int64
+	LastModified int64 // Unix timestamp
+}
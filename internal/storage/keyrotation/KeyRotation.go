@@ -0,0 +1,398 @@
+package keyrotation
+
+/**
+Rotates the master encryption key and encrypts files that have been stored in plaintext,
+before encryption was enabled
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/environment"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/storage/preview"
+	"github.com/forceu/gokapi/internal/webserver/api/mutex/apimutex"
+)
+
+// ErrorNotSupported is returned, if the encryption level does not use a master key
+var ErrorNotSupported = errors.New("server-side encryption is not enabled")
+
+// ErrorInProgress is returned, if a key rotation or file encryption is already running
+var ErrorInProgress = errors.New("a key rotation or file encryption is already in progress")
+
+// ErrorKeyNotLoaded is returned, if the current master key has not been loaded into memory
+var ErrorKeyNotLoaded = errors.New("the master key has not been loaded")
+
+// ErrorConfigNotSaved is returned, if the configuration with the new key could not be saved
+var ErrorConfigNotSaved = errors.New("could not save the configuration")
+
+// ErrorMultipleInstances is returned, if the key is rotated while the server is running together with other instances
+var ErrorMultipleInstances = errors.New("the key cannot be rotated while multiple instances are running, stop all instances and run Gokapi with --rotate-encryption-key instead")
+
+var runningMutex sync.Mutex
+
+// Result contains the number of files that were modified
+type Result struct {
+	RewrappedKeys  int      `json:"rewrappedKeys"`  // The number of files, where the file key was encrypted with the new master key
+	EncryptedFiles int      `json:"encryptedFiles"` // The number of stored files that were encrypted
+	FailedFiles    []string `json:"failedFiles"`    // The IDs of the files that could not be processed
+}
+
+// RequiresPassword returns true if the encryption level requires a new password for a key rotation
+func RequiresPassword() bool {
+	level := configuration.Get().Encryption.Level
+	return level == encryption.LocalEncryptionInput || level == encryption.FullEncryptionInput
+}
+
+// RotateMasterKey generates a new master key and encrypts the file keys of all files with it.
+// The content of the files is not modified. For encryption levels that derive the master key from
+// a password, newPassword is used for the new key, otherwise it is ignored.
+// The new key is saved before any file key is modified. Until all file keys have been re-encrypted,
+// the previous key is stored in the configuration as well, encrypted with the new key
+func RotateMasterKey(newPassword string) (Result, error) {
+	if !isServerSideEncryption() {
+		return Result{}, ErrorNotSupported
+	}
+	if !encryption.IsDecryptionAvailable() {
+		return Result{}, ErrorKeyNotLoaded
+	}
+	if !runningMutex.TryLock() {
+		return Result{}, ErrorInProgress
+	}
+	defer runningMutex.Unlock()
+
+	newConfig, newKey, err := generateNewMasterKey(newPassword)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{FailedFiles: make([]string, 0)}
+	// Otherwise the master key of the unfinished rotation would be lost
+	if isRotationUnfinished() {
+		err = finishRotation(&result)
+		if err != nil {
+			return result, err
+		}
+	}
+	newConfig.PreviousKey, newConfig.PreviousKeyNonce, err = encryption.WrapMasterKey(newKey)
+	if err != nil {
+		return Result{}, err
+	}
+	err = configuration.SetEncryption(newConfig)
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrorConfigNotSaved, err)
+	}
+	err = encryption.StartKeyRotation(newKey)
+	if err != nil {
+		return Result{}, err
+	}
+	err = finishRotation(&result)
+	return result, err
+}
+
+// RotateMasterKeyOnline rotates the master key like RotateMasterKey while the server is running. Other instances
+// would still encrypt new files with the previous key, which is removed after the rotation. Therefore, this is
+// refused if state is shared with other instances
+func RotateMasterKeyOnline(newPassword string) (Result, error) {
+	if sharedstate.IsEnabled() {
+		return Result{}, ErrorMultipleInstances
+	}
+	return RotateMasterKey(newPassword)
+}
+
+// ResumeKeyRotation re-encrypts the remaining file keys of a key rotation that has been interrupted,
+// e.g. because the server was stopped. Nothing is done if no key rotation is unfinished
+func ResumeKeyRotation() (Result, error) {
+	if !isServerSideEncryption() || !isRotationUnfinished() {
+		return Result{FailedFiles: make([]string, 0)}, nil
+	}
+	if !encryption.IsDecryptionAvailable() {
+		return Result{}, ErrorKeyNotLoaded
+	}
+	if !runningMutex.TryLock() {
+		return Result{}, ErrorInProgress
+	}
+	defer runningMutex.Unlock()
+
+	result := Result{FailedFiles: make([]string, 0)}
+	err := finishRotation(&result)
+	return result, err
+}
+
+func isRotationUnfinished() bool {
+	return len(configuration.Get().Encryption.PreviousKey) != 0
+}
+
+// finishRotation re-encrypts all file keys with the current master key and removes the previous master key
+// from the configuration afterwards. If the configuration cannot be saved, the previous key is kept
+func finishRotation(result *Result) error {
+	rewrapAllFiles(result)
+	// Files that have been uploaded while the first pass was running might still use the previous key
+	rewrapAllFiles(result)
+	newConfig := configuration.Get().Encryption
+	newConfig.PreviousKey = nil
+	newConfig.PreviousKeyNonce = nil
+	err := configuration.SetEncryption(newConfig)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrorConfigNotSaved, err)
+	}
+	encryption.FinishKeyRotation()
+	return nil
+}
+
+func isServerSideEncryption() bool {
+	level := configuration.Get().Encryption.Level
+	return level != encryption.NoEncryption && level != encryption.EndToEndEncryption
+}
+
+func generateNewMasterKey(newPassword string) (models.Encryption, []byte, error) {
+	result := models.Encryption{Level: configuration.Get().Encryption.Level}
+	if !RequiresPassword() {
+		cipher, err := encryption.GetRandomCipher()
+		if err != nil {
+			return models.Encryption{}, nil, err
+		}
+		result.Cipher = cipher
+		return result, cipher, nil
+	}
+	minLength := environment.New().MinLengthPassword
+	if len(newPassword) < minLength {
+		return models.Encryption{}, nil, errors.New("password is less than " + strconv.Itoa(minLength) + " characters long")
+	}
+	result.Salt = helper.GenerateRandomString(30)
+	result.ChecksumSalt = helper.GenerateRandomString(30)
+	result.Checksum = encryption.PasswordChecksum(newPassword, result.ChecksumSalt)
+	key, err := encryption.GetKeyFromPassword(newPassword, result.Salt)
+	if err != nil {
+		return models.Encryption{}, nil, err
+	}
+	return result, key, nil
+}
+
+func rewrapAllFiles(result *Result) {
+	for _, file := range database.GetAllMetadata() {
+		// The file is read again, in case it has been modified in the meantime
+		file, ok := database.GetMetaDataById(file.Id)
+		if !ok {
+			continue
+		}
+		isModified, err := encryption.RewrapFileKey(&file.Encryption)
+		if err != nil {
+			fmt.Println("Could not re-encrypt key of file " + file.Id + ": " + err.Error())
+			result.addFailedFile(file.Id)
+			continue
+		}
+		if isModified {
+			database.SaveMetaData(file)
+			result.RewrappedKeys++
+		}
+	}
+	for _, version := range database.GetAllFileVersions() {
+		version, ok := database.GetFileVersion(version.Id)
+		if !ok {
+			continue
+		}
+		isModified, err := encryption.RewrapFileKey(&version.Encryption)
+		if err != nil {
+			fmt.Println("Could not re-encrypt key of version " + version.Id + " of file " + version.FileId + ": " + err.Error())
+			result.addFailedFile(version.FileId)
+			continue
+		}
+		if isModified {
+			database.SaveFileVersion(version)
+			result.RewrappedKeys++
+		}
+	}
+}
+
+func (r *Result) addFailedFile(id string) {
+	for _, failedId := range r.FailedFiles {
+		if failedId == id {
+			return
+		}
+	}
+	r.FailedFiles = append(r.FailedFiles, id)
+}
+
+// EncryptPlaintextFiles encrypts all stored files that are not encrypted yet, e.g. because they have
+// been uploaded before encryption was enabled. For local encryption levels, only files on the
+// local storage are encrypted. End-to-end encrypted files are not modified
+func EncryptPlaintextFiles() (Result, error) {
+	if !isServerSideEncryption() {
+		return Result{}, ErrorNotSupported
+	}
+	if !encryption.IsDecryptionAvailable() {
+		return Result{}, ErrorKeyNotLoaded
+	}
+	if !runningMutex.TryLock() {
+		return Result{}, ErrorInProgress
+	}
+	defer runningMutex.Unlock()
+
+	result := Result{FailedFiles: make([]string, 0)}
+	isProcessed := make(map[string]bool)
+	for _, file := range getAllStoredContent() {
+		storageKey := file.AwsBucket + "/" + file.SHA1
+		if !requiresEncryption(file) || isProcessed[storageKey] {
+			continue
+		}
+		isProcessed[storageKey] = true
+		err := encryptStoredFile(file)
+		if err != nil {
+			fmt.Println("Could not encrypt file " + file.Id + ": " + err.Error())
+			result.addFailedFile(file.Id)
+			continue
+		}
+		result.EncryptedFiles++
+	}
+	return result, nil
+}
+
+// getAllStoredContent returns all files and the content of all file versions
+func getAllStoredContent() []models.File {
+	result := make([]models.File, 0)
+	for _, file := range database.GetAllMetadata() {
+		result = append(result, file)
+	}
+	for _, version := range database.GetAllFileVersions() {
+		result = append(result, version.ToFile())
+	}
+	return result
+}
+
+func requiresEncryption(file models.File) bool {
+	if file.Encryption.IsEncrypted || file.SHA1 == "" {
+		return false
+	}
+	switch configuration.Get().Encryption.Level {
+	case encryption.LocalEncryptionStored, encryption.LocalEncryptionInput:
+		return file.IsLocalStorage()
+	case encryption.FullEncryptionStored, encryption.FullEncryptionInput:
+		return file.IsLocalStorage() || aws.IsAvailable()
+	default:
+		return false
+	}
+}
+
+// updateMetadata sets the encryption info for all files and versions that share the content of file
+func updateMetadata(file models.File, encInfo models.EncryptionInfo) {
+	for _, otherFile := range database.GetAllMetadata() {
+		if otherFile.SHA1 != file.SHA1 || otherFile.AwsBucket != file.AwsBucket || otherFile.Encryption.IsEncrypted {
+			continue
+		}
+		otherFile.Encryption = encInfo
+		// Encrypted files in the cloud are decrypted by the client, therefore they cannot be hotlinked
+		if otherFile.RequiresClientDecryption() && otherFile.HotlinkId != "" {
+			database.DeleteHotlink(otherFile.HotlinkId)
+			otherFile.HotlinkId = ""
+		}
+		database.SaveMetaData(otherFile)
+	}
+	for _, version := range database.GetAllFileVersions() {
+		if version.SHA1 != file.SHA1 || version.AwsBucket != file.AwsBucket || version.Encryption.IsEncrypted {
+			continue
+		}
+		version.Encryption = encInfo
+		database.SaveFileVersion(version)
+	}
+}
+
+// encryptStoredFile encrypts the content of the file and replaces the stored file with the encrypted version.
+// While the content is replaced, the metadata of all files that share the content is locked, so that
+// the encrypted content is never served together with outdated encryption info
+func encryptStoredFile(file models.File) error {
+	dataDir := configuration.Get().DataDir
+	tempFile, err := os.CreateTemp(dataDir, "upload")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	encInfo := models.EncryptionInfo{IsEncrypted: true}
+	if file.IsLocalStorage() {
+		err = encryptLocalFile(file, dataDir, tempFile, &encInfo)
+	} else {
+		err = encryptCloudFile(file, tempFile, &encInfo)
+	}
+	if err != nil {
+		return err
+	}
+
+	ids := getIdsSharingContent(file)
+	apimutex.LockAll(apimutex.TypeMetaData, ids)
+	defer apimutex.UnlockAll(apimutex.TypeMetaData, ids)
+	if file.IsLocalStorage() {
+		err = os.Rename(tempFile.Name(), dataDir+"/"+file.SHA1)
+	} else {
+		_, err = aws.Upload(tempFile, file)
+	}
+	if err != nil {
+		return err
+	}
+	// Thumbnails are not created for encrypted files, as they would be stored unencrypted
+	preview.DeleteThumbnail(file)
+	updateMetadata(file, encInfo)
+	return nil
+}
+
+// getIdsSharingContent returns the IDs of all files that use the content of file, either directly or for a previous version
+func getIdsSharingContent(file models.File) []string {
+	result := make([]string, 0)
+	for _, otherFile := range database.GetAllMetadata() {
+		if otherFile.SHA1 == file.SHA1 && otherFile.AwsBucket == file.AwsBucket {
+			result = append(result, otherFile.Id)
+		}
+	}
+	for _, version := range database.GetAllFileVersions() {
+		if version.SHA1 == file.SHA1 && version.AwsBucket == file.AwsBucket {
+			result = append(result, version.FileId)
+		}
+	}
+	return result
+}
+
+// encryptLocalFile writes the encrypted content of the file to tempFile
+func encryptLocalFile(file models.File, dataDir string, tempFile *os.File, encInfo *models.EncryptionInfo) error {
+	source, err := os.Open(dataDir + "/" + file.SHA1)
+	if err != nil {
+		return err
+	}
+	err = encryption.Encrypt(encInfo, source, tempFile)
+	_ = source.Close()
+	if err != nil {
+		return err
+	}
+	return tempFile.Close()
+}
+
+// encryptCloudFile writes the encrypted content of the file to tempFile and rewinds it for the upload
+func encryptCloudFile(file models.File, tempFile *os.File, encInfo *models.EncryptionInfo) error {
+	// The writer would close the underlying file otherwise, which is still required for the upload
+	writer, err := encryption.EncryptWriter(encInfo, struct{ io.Writer }{tempFile})
+	if err != nil {
+		return err
+	}
+	err = aws.Stream(writer, file)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	_, err = tempFile.Seek(0, io.SeekStart)
+	return err
+}
@@ -0,0 +1,254 @@
+//go:build test && awsmock
+
+package keyrotation
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate/redisstate"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+)
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	configuration.Load()
+	configuration.ConnectDatabase()
+	for _, file := range database.GetAllMetadata() {
+		database.DeleteMetaData(file.Id)
+	}
+	testconfiguration.EnableS3()
+	aws.Init(models.AwsConfig{})
+	exitVal := m.Run()
+	testconfiguration.DisableS3()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+const localContent = "This file was uploaded before encryption was enabled"
+const cloudContent = "This file was uploaded to the cloud before encryption was enabled"
+const versionContent = "This is the previous content of a replaced file"
+
+func createPlaintextFiles(t *testing.T) {
+	t.Helper()
+	err := os.WriteFile(configuration.Get().DataDir+"/keyrotationlocal", []byte(localContent), 0600)
+	test.IsNil(t, err)
+	upload, err := aws.StartMultipartUpload("gokapi-test", "keyrotationcloud")
+	test.IsNil(t, err)
+	test.IsNil(t, upload.UploadPart([]byte(cloudContent)))
+	test.IsNil(t, upload.Complete())
+
+	err = os.WriteFile(configuration.Get().DataDir+"/keyrotationversion", []byte(versionContent), 0600)
+	test.IsNil(t, err)
+	database.SaveMetaData(models.File{Id: "keyrotLocal1", Name: "local1.txt", SHA1: "keyrotationlocal", SizeBytes: int64(len(localContent))})
+	database.SaveFileVersion(models.FileVersion{Id: "keyrotVersion1", FileId: "keyrotLocal1", SHA1: "keyrotationversion",
+		SizeBytes: int64(len(versionContent))})
+	database.SaveFileVersion(models.FileVersion{Id: "keyrotVersion2", FileId: "keyrotLocal2", SHA1: "keyrotationlocal",
+		SizeBytes: int64(len(localContent))})
+	database.SaveMetaData(models.File{Id: "keyrotLocal2", Name: "local2.txt", SHA1: "keyrotationlocal", SizeBytes: int64(len(localContent))})
+	database.SaveMetaData(models.File{Id: "keyrotCloud", Name: "cloud.jpg", SHA1: "keyrotationcloud", AwsBucket: "gokapi-test",
+		SizeBytes: int64(len(cloudContent)), HotlinkId: "keyrotHotlink.jpg"})
+	database.SaveHotlink(models.File{Id: "keyrotCloud", HotlinkId: "keyrotHotlink.jpg"})
+	database.SaveMetaData(models.File{Id: "keyrotE2e", Name: "Encrypted file", SHA1: "e2e-keyrotation",
+		Encryption: models.EncryptionInfo{IsEncrypted: true, IsEndToEndEncrypted: true}})
+}
+
+func readLocalFile(t *testing.T, file models.File) string {
+	t.Helper()
+	content, err := os.ReadFile(configuration.Get().DataDir + "/" + file.SHA1)
+	test.IsNil(t, err)
+	if !file.Encryption.IsEncrypted {
+		return string(content)
+	}
+	var decrypted bytes.Buffer
+	err = encryption.DecryptReader(file.Encryption, bytes.NewReader(content), &decrypted)
+	test.IsNil(t, err)
+	return decrypted.String()
+}
+
+func getVersion(t *testing.T, id string) models.File {
+	t.Helper()
+	version, ok := database.GetFileVersion(id)
+	test.IsEqualBool(t, ok, true)
+	return version.ToFile()
+}
+
+func getFile(t *testing.T, id string) models.File {
+	t.Helper()
+	file, ok := database.GetMetaDataById(id)
+	test.IsEqualBool(t, ok, true)
+	return file
+}
+
+func setEncryptionLevel(t *testing.T, level int) {
+	t.Helper()
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	configuration.Get().Encryption = models.Encryption{Level: level, Cipher: cipher}
+	encryption.Init(*configuration.Get())
+}
+
+func TestNotSupported(t *testing.T) {
+	configuration.Get().Encryption = models.Encryption{Level: encryption.NoEncryption}
+	_, err := RotateMasterKey("")
+	test.IsEqualBool(t, err == ErrorNotSupported, true)
+	_, err = EncryptPlaintextFiles()
+	test.IsEqualBool(t, err == ErrorNotSupported, true)
+	configuration.Get().Encryption = models.Encryption{Level: encryption.EndToEndEncryption}
+	_, err = RotateMasterKey("")
+	test.IsEqualBool(t, err == ErrorNotSupported, true)
+}
+
+func TestRotateMasterKeyOnline(t *testing.T) {
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	_, err = RotateMasterKeyOnline("")
+	test.IsEqualBool(t, errors.Is(err, ErrorMultipleInstances), true)
+	sharedstate.Close()
+	configuration.Get().Encryption = models.Encryption{Level: encryption.NoEncryption}
+	_, err = RotateMasterKeyOnline("")
+	test.IsEqualBool(t, errors.Is(err, ErrorNotSupported), true)
+}
+
+func TestEncryptPlaintextFiles(t *testing.T) {
+	createPlaintextFiles(t)
+
+	setEncryptionLevel(t, encryption.LocalEncryptionStored)
+	result, err := EncryptPlaintextFiles()
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.EncryptedFiles, 2)
+	test.IsEqualInt(t, len(result.FailedFiles), 0)
+	local1 := getFile(t, "keyrotLocal1")
+	local2 := getFile(t, "keyrotLocal2")
+	test.IsEqualBool(t, local1.Encryption.IsEncrypted, true)
+	test.IsEqualBool(t, local2.Encryption.IsEncrypted, true)
+	test.IsEqualString(t, readLocalFile(t, local1), localContent)
+	test.IsEqualString(t, readLocalFile(t, local2), localContent)
+	// Versions are encrypted as well, including content that is shared with a file
+	test.IsEqualString(t, readLocalFile(t, getVersion(t, "keyrotVersion1")), versionContent)
+	test.IsEqualString(t, readLocalFile(t, getVersion(t, "keyrotVersion2")), localContent)
+	test.IsEqualBool(t, getVersion(t, "keyrotVersion2").Encryption.IsEncrypted, true)
+	// Files in the cloud are not encrypted with local encryption
+	test.IsEqualBool(t, getFile(t, "keyrotCloud").Encryption.IsEncrypted, false)
+
+	configuration.Get().Encryption.Level = encryption.FullEncryptionStored
+	runningMutex.Lock()
+	_, err = EncryptPlaintextFiles()
+	test.IsEqualBool(t, err == ErrorInProgress, true)
+	runningMutex.Unlock()
+	result, err = EncryptPlaintextFiles()
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.EncryptedFiles, 1)
+	cloudFile := getFile(t, "keyrotCloud")
+	test.IsEqualBool(t, cloudFile.Encryption.IsEncrypted, true)
+	test.IsEqualString(t, cloudFile.HotlinkId, "")
+	_, ok := database.GetHotlink("keyrotHotlink.jpg")
+	test.IsEqualBool(t, ok, false)
+	e2eFile := getFile(t, "keyrotE2e")
+	test.IsEqualInt(t, len(e2eFile.Encryption.DecryptionKey), 0)
+
+	result, err = EncryptPlaintextFiles()
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.EncryptedFiles, 0)
+}
+
+func TestRotateMasterKey(t *testing.T) {
+	// The files of the previous test are encrypted with the current key
+	oldConfig := configuration.Get().Encryption
+	oldEncryption := getFile(t, "keyrotLocal1").Encryption
+
+	runningMutex.Lock()
+	_, err := RotateMasterKey("")
+	test.IsEqualBool(t, err == ErrorInProgress, true)
+	runningMutex.Unlock()
+
+	result, err := RotateMasterKey("")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.RewrappedKeys, 5)
+	test.IsEqualInt(t, len(result.FailedFiles), 0)
+	test.IsEqualBool(t, bytes.Equal(configuration.Get().Encryption.Cipher, oldConfig.Cipher), false)
+	test.IsEqualInt(t, configuration.Get().Encryption.Level, encryption.FullEncryptionStored)
+	local1 := getFile(t, "keyrotLocal1")
+	test.IsEqualBool(t, bytes.Equal(local1.Encryption.DecryptionKey, oldEncryption.DecryptionKey), false)
+	test.IsEqualString(t, readLocalFile(t, local1), localContent)
+	test.IsEqualString(t, readLocalFile(t, getFile(t, "keyrotLocal2")), localContent)
+	test.IsEqualString(t, readLocalFile(t, getVersion(t, "keyrotVersion1")), versionContent)
+	// The previous key is not available anymore after the rotation
+	_, err = encryption.GetCipherFromFile(oldEncryption)
+	test.IsNotNil(t, err)
+
+	configuration.Get().Encryption.Level = encryption.FullEncryptionInput
+	_, err = RotateMasterKey("short")
+	test.IsNotNil(t, err)
+	result, err = RotateMasterKey("newEncryptionPassword")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.RewrappedKeys, 5)
+	newConfig := configuration.Get().Encryption
+	test.IsEqualInt(t, len(newConfig.Cipher), 0)
+	test.IsEqualString(t, newConfig.Checksum, encryption.PasswordChecksum("newEncryptionPassword", newConfig.ChecksumSalt))
+	test.IsEqualString(t, readLocalFile(t, getFile(t, "keyrotLocal1")), localContent)
+	test.IsEqualBool(t, RequiresPassword(), true)
+	test.IsEqualInt(t, len(newConfig.PreviousKey), 0)
+	test.IsEqualInt(t, len(newConfig.PreviousKeyNonce), 0)
+}
+
+func TestResumeKeyRotation(t *testing.T) {
+	result, err := ResumeKeyRotation()
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.RewrappedKeys, 0)
+
+	// Simulates a restart after the new key has been saved, but before any file key was re-encrypted
+	oldEncryption := getFile(t, "keyrotLocal1").Encryption
+	newKey, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	previousKey, nonce, err := encryption.WrapMasterKey(newKey)
+	test.IsNil(t, err)
+	configuration.Get().Encryption = models.Encryption{Level: encryption.FullEncryptionStored, Cipher: newKey,
+		PreviousKey: previousKey, PreviousKeyNonce: nonce}
+	encryption.Init(*configuration.Get())
+	test.IsEqualString(t, readLocalFile(t, getFile(t, "keyrotLocal1")), localContent)
+
+	runningMutex.Lock()
+	_, err = ResumeKeyRotation()
+	test.IsEqualBool(t, err == ErrorInProgress, true)
+	runningMutex.Unlock()
+	result, err = ResumeKeyRotation()
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.RewrappedKeys, 5)
+	test.IsEqualInt(t, len(result.FailedFiles), 0)
+	test.IsEqualInt(t, len(configuration.Get().Encryption.PreviousKey), 0)
+	test.IsEqualBool(t, bytes.Equal(configuration.Get().Encryption.Cipher, newKey), true)
+	test.IsEqualString(t, readLocalFile(t, getFile(t, "keyrotLocal1")), localContent)
+	test.IsEqualString(t, readLocalFile(t, getVersion(t, "keyrotVersion1")), versionContent)
+	_, err = encryption.GetCipherFromFile(oldEncryption)
+	test.IsNotNil(t, err)
+
+	// A rotation started with an unfinished rotation completes the unfinished one first
+	oldEncryption = getFile(t, "keyrotLocal1").Encryption
+	olderKey, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	previousKey, nonce, err = encryption.WrapMasterKey(olderKey)
+	test.IsNil(t, err)
+	configuration.Get().Encryption = models.Encryption{Level: encryption.FullEncryptionStored, Cipher: olderKey,
+		PreviousKey: previousKey, PreviousKeyNonce: nonce}
+	encryption.Init(*configuration.Get())
+	result, err = RotateMasterKey("")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, result.RewrappedKeys, 10)
+	test.IsEqualInt(t, len(configuration.Get().Encryption.PreviousKey), 0)
+	test.IsEqualString(t, readLocalFile(t, getFile(t, "keyrotLocal1")), localContent)
+	_, err = encryption.GetCipherFromFile(oldEncryption)
+	test.IsNotNil(t, err)
+}
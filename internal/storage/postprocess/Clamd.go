@@ -0,0 +1,121 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// clamdChunkSize is the size of the chunks that are sent to clamd. Must be smaller than StreamMaxLength of clamd
+const clamdChunkSize = 64 * 1024
+
+type clamdProcessor struct {
+	network string
+	address string
+}
+
+// NewClamdProcessor returns a processor that sends the file to clamd with the INSTREAM command.
+// The address can either be "tcp://host:port", "unix:///path/to/socket" or "host:port"
+func NewClamdProcessor(address string) Processor {
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		return &clamdProcessor{network: "unix", address: strings.TrimPrefix(address, "unix://")}
+	case strings.HasPrefix(address, "tcp://"):
+		return &clamdProcessor{network: "tcp", address: strings.TrimPrefix(address, "tcp://")}
+	default:
+		return &clamdProcessor{network: "tcp", address: address}
+	}
+}
+
+// GetName returns the name of the processor
+func (c *clamdProcessor) GetName() string {
+	return "clamd"
+}
+
+// Process streams the file to clamd and returns a failed verdict if a virus was found
+func (c *clamdProcessor) Process(ctx context.Context, path string) (Verdict, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer file.Close()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return Verdict{}, err
+		}
+	}
+
+	_, err = conn.Write([]byte("zINSTREAM\x00"))
+	if err != nil {
+		return Verdict{}, err
+	}
+	err = sendInstream(conn, file)
+	// clamd might reply and close the connection before the stream is complete, e.g. if the size limit was exceeded
+	response, readErr := io.ReadAll(conn)
+	if len(response) > 0 {
+		return parseClamdResponse(response)
+	}
+	if err != nil {
+		return Verdict{}, err
+	}
+	if readErr != nil {
+		return Verdict{}, readErr
+	}
+	return parseClamdResponse(response)
+}
+
+// sendInstream sends the content in chunks, each prefixed with its length. A chunk with a length of zero
+// marks the end of the stream
+func sendInstream(conn net.Conn, content io.Reader) error {
+	buffer := make([]byte, clamdChunkSize)
+	sizeHeader := make([]byte, 4)
+	for {
+		n, err := content.Read(buffer)
+		if n > 0 {
+			binary.BigEndian.PutUint32(sizeHeader, uint32(n))
+			_, writeErr := conn.Write(append(sizeHeader, buffer[:n]...))
+			if writeErr != nil {
+				return writeErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	binary.BigEndian.PutUint32(sizeHeader, 0)
+	_, err := conn.Write(sizeHeader)
+	return err
+}
+
+// parseClamdResponse parses responses like "stream: OK" or "stream: Eicar-Signature FOUND"
+func parseClamdResponse(response []byte) (Verdict, error) {
+	result := strings.TrimSpace(string(bytes.TrimRight(response, "\x00")))
+	result = strings.TrimPrefix(result, "stream: ")
+	switch {
+	case result == "OK":
+		return Verdict{Passed: true}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Reason: "virus found: " + strings.TrimSuffix(result, " FOUND")}, nil
+	case result == "":
+		return Verdict{}, errors.New("clamd did not send a response")
+	default:
+		return Verdict{}, errors.New("clamd returned an error: " + result)
+	}
+}
@@ -0,0 +1,76 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxReasonLength is the maximum length of the output of a command that is used as the reason for a rejection
+const maxReasonLength = 200
+
+type commandProcessor struct {
+	command   string
+	arguments []string
+}
+
+// NewCommandProcessor returns a processor that runs an external command. The command is split at whitespace
+// and the path of the file is appended as the last argument. The file is rejected if the command exits
+// with a code other than 0, the first line of its output is used as the reason
+func NewCommandProcessor(command string) Processor {
+	fields := strings.Fields(command)
+	result := &commandProcessor{}
+	if len(fields) > 0 {
+		result.command = fields[0]
+		result.arguments = fields[1:]
+	}
+	return result
+}
+
+// GetName returns the name of the processor
+func (c *commandProcessor) GetName() string {
+	return "command " + c.command
+}
+
+// Process runs the command and returns a failed verdict if it did not exit with code 0
+func (c *commandProcessor) Process(ctx context.Context, path string) (Verdict, error) {
+	if c.command == "" {
+		return Verdict{}, errors.New("no command has been set")
+	}
+	arguments := append(append([]string{}, c.arguments...), path)
+	cmd := exec.CommandContext(ctx, c.command, arguments...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Child processes of the command might keep the output open after the command has been killed
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if err == nil {
+		return Verdict{Passed: true}, nil
+	}
+	if ctx.Err() != nil {
+		return Verdict{}, ctx.Err()
+	}
+	var exitError *exec.ExitError
+	if !errors.As(err, &exitError) {
+		return Verdict{}, err
+	}
+	reason := getFirstLine(output.String())
+	if reason == "" {
+		reason = fmt.Sprintf("exit code %d", exitError.ExitCode())
+	}
+	return Verdict{Reason: reason}, nil
+}
+
+func getFirstLine(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	line = strings.TrimSpace(line)
+	if len(line) > maxReasonLength {
+		line = line[:maxReasonLength]
+	}
+	return line
+}
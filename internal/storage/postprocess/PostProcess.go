@@ -0,0 +1,99 @@
+package postprocess
+
+/**
+Checks new uploads with a pipeline of processors, e.g. an antivirus scanner, before they are published
+*/
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/forceu/gokapi/internal/environment"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// Processor checks the content of a new upload
+type Processor interface {
+	// GetName returns the name of the processor, which is shown in the audit log
+	GetName() string
+	// Process checks the plaintext content of the file at the given path. Returns an error
+	// if the check could not be completed
+	Process(ctx context.Context, path string) (Verdict, error)
+}
+
+// Verdict is the result of checking an upload
+type Verdict struct {
+	Passed    bool   // True if the file may be published
+	Processor string // The name of the processor that rejected the file, if any
+	Reason    string // The reason for rejecting the file, if any
+}
+
+var processors []Processor
+var timeout = 300 * time.Second
+var guestUploadsOnly bool
+var mutex sync.RWMutex
+
+// Init creates the processors that are configured with environment variables
+func Init() {
+	env := environment.New()
+	mutex.Lock()
+	processors = make([]Processor, 0)
+	timeout = time.Duration(env.PostProcessTimeoutSeconds) * time.Second
+	guestUploadsOnly = env.PostProcessGuestUploadsOnly
+	mutex.Unlock()
+	if env.PostProcessClamd != "" {
+		Register(NewClamdProcessor(env.PostProcessClamd))
+	}
+	if env.PostProcessCommand != "" {
+		Register(NewCommandProcessor(env.PostProcessCommand))
+	}
+}
+
+// Register appends a processor to the pipeline
+func Register(processor Processor) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	processors = append(processors, processor)
+}
+
+// IsEnabled returns true if at least one processor is registered
+func IsEnabled() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return len(processors) > 0
+}
+
+// IsRequired returns true if the file has to be checked before it is published.
+// The content of end-to-end encrypted files is not known to the server and cannot be checked
+func IsRequired(file models.File) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if len(processors) == 0 || file.Encryption.IsEndToEndEncrypted {
+		return false
+	}
+	return !guestUploadsOnly || file.UploadRequestId != ""
+}
+
+// Run checks the plaintext content at the given path with all processors in the order they were registered.
+// Processing stops at the first processor that rejects the file. If a check cannot be completed, e.g.
+// because the scanner is not reachable, the file is rejected as well
+func Run(path string) Verdict {
+	mutex.RLock()
+	pipeline := processors
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	mutex.RUnlock()
+	defer cancel()
+
+	for _, processor := range pipeline {
+		verdict, err := processor.Process(ctx, path)
+		if err != nil {
+			verdict = Verdict{Reason: "the check could not be completed: " + err.Error()}
+		}
+		if !verdict.Passed {
+			verdict.Processor = processor.GetName()
+			return verdict
+		}
+	}
+	return Verdict{Passed: true}
+}
@@ -0,0 +1,261 @@
+//go:build test
+
+package postprocess
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+)
+
+type fakeProcessor struct {
+	name    string
+	verdict Verdict
+	err     error
+	calls   int
+}
+
+func (f *fakeProcessor) GetName() string {
+	return f.name
+}
+
+func (f *fakeProcessor) Process(_ context.Context, _ string) (Verdict, error) {
+	f.calls++
+	return f.verdict, f.err
+}
+
+func resetProcessors() {
+	mutex.Lock()
+	processors = make([]Processor, 0)
+	timeout = 300 * time.Second
+	guestUploadsOnly = false
+	mutex.Unlock()
+}
+
+func createTestFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "upload")
+	test.IsNil(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestInit(t *testing.T) {
+	t.Setenv("GOKAPI_POSTPROCESS_CLAMD", "tcp://127.0.0.1:3310")
+	t.Setenv("GOKAPI_POSTPROCESS_COMMAND", "/usr/bin/scan --strict")
+	t.Setenv("GOKAPI_POSTPROCESS_TIMEOUT", "20")
+	t.Setenv("GOKAPI_POSTPROCESS_GUEST_UPLOADS_ONLY", "true")
+	Init()
+	defer resetProcessors()
+	test.IsEqualBool(t, IsEnabled(), true)
+	test.IsEqualInt(t, len(processors), 2)
+	test.IsEqualString(t, processors[0].GetName(), "clamd")
+	test.IsEqualString(t, processors[1].GetName(), "command /usr/bin/scan")
+	test.IsEqual(t, timeout, 20*time.Second)
+	test.IsEqualBool(t, guestUploadsOnly, true)
+
+	os.Unsetenv("GOKAPI_POSTPROCESS_CLAMD")
+	os.Unsetenv("GOKAPI_POSTPROCESS_COMMAND")
+	Init()
+	test.IsEqualBool(t, IsEnabled(), false)
+}
+
+func TestIsRequired(t *testing.T) {
+	resetProcessors()
+	defer resetProcessors()
+	file := models.File{Id: "test"}
+	test.IsEqualBool(t, IsRequired(file), false)
+	Register(&fakeProcessor{name: "fake"})
+	test.IsEqualBool(t, IsRequired(file), true)
+	file.Encryption.IsEndToEndEncrypted = true
+	test.IsEqualBool(t, IsRequired(file), false)
+	file.Encryption.IsEndToEndEncrypted = false
+	guestUploadsOnly = true
+	test.IsEqualBool(t, IsRequired(file), false)
+	file.UploadRequestId = "request"
+	test.IsEqualBool(t, IsRequired(file), true)
+}
+
+func TestRun(t *testing.T) {
+	resetProcessors()
+	defer resetProcessors()
+	test.IsEqualBool(t, Run("invalid").Passed, true)
+
+	first := &fakeProcessor{name: "first", verdict: Verdict{Passed: true}}
+	second := &fakeProcessor{name: "second", verdict: Verdict{Reason: "rejected"}}
+	third := &fakeProcessor{name: "third", verdict: Verdict{Passed: true}}
+	Register(first)
+	Register(second)
+	Register(third)
+	verdict := Run("invalid")
+	test.IsEqualBool(t, verdict.Passed, false)
+	test.IsEqualString(t, verdict.Processor, "second")
+	test.IsEqualString(t, verdict.Reason, "rejected")
+	test.IsEqualInt(t, first.calls, 1)
+	test.IsEqualInt(t, third.calls, 0)
+
+	second.verdict = Verdict{Passed: true}
+	test.IsEqualBool(t, Run("invalid").Passed, true)
+	test.IsEqualInt(t, third.calls, 1)
+
+	second.err = errors.New("scanner unreachable")
+	verdict = Run("invalid")
+	test.IsEqualBool(t, verdict.Passed, false)
+	test.IsEqualString(t, verdict.Processor, "second")
+	test.IsEqualString(t, verdict.Reason, "the check could not be completed: scanner unreachable")
+}
+
+// startFakeClamd accepts a single connection, reads an INSTREAM request and replies with response
+func startFakeClamd(t *testing.T, response string) (string, chan []byte) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.IsNil(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		command := make([]byte, len("zINSTREAM\x00"))
+		_, err = io.ReadFull(conn, command)
+		if err != nil || string(command) != "zINSTREAM\x00" {
+			received <- nil
+			return
+		}
+		var content []byte
+		sizeHeader := make([]byte, 4)
+		for {
+			_, err = io.ReadFull(conn, sizeHeader)
+			if err != nil {
+				received <- nil
+				return
+			}
+			size := binary.BigEndian.Uint32(sizeHeader)
+			if size == 0 {
+				break
+			}
+			chunk := make([]byte, size)
+			_, err = io.ReadFull(conn, chunk)
+			if err != nil {
+				received <- nil
+				return
+			}
+			content = append(content, chunk...)
+		}
+		_, _ = conn.Write([]byte(response))
+		received <- content
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestClamdProcessor(t *testing.T) {
+	content := strings.Repeat("a", clamdChunkSize*2+10)
+	path := createTestFile(t, content)
+
+	address, received := startFakeClamd(t, "stream: OK\x00")
+	processor := NewClamdProcessor("tcp://" + address)
+	test.IsEqualString(t, processor.GetName(), "clamd")
+	verdict, err := processor.Process(context.Background(), path)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, verdict.Passed, true)
+	test.IsEqualString(t, string(<-received), content)
+
+	address, _ = startFakeClamd(t, "stream: Eicar-Signature FOUND\x00")
+	verdict, err = NewClamdProcessor(address).Process(context.Background(), path)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, verdict.Passed, false)
+	test.IsEqualString(t, verdict.Reason, "virus found: Eicar-Signature")
+
+	address, _ = startFakeClamd(t, "INSTREAM size limit exceeded. ERROR\x00")
+	_, err = NewClamdProcessor(address).Process(context.Background(), path)
+	test.IsNotNil(t, err)
+
+	_, err = NewClamdProcessor(address).Process(context.Background(), "invalid")
+	test.IsNotNil(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.IsNil(t, err)
+	closedAddress := listener.Addr().String()
+	test.IsNil(t, listener.Close())
+	_, err = NewClamdProcessor(closedAddress).Process(context.Background(), path)
+	test.IsNotNil(t, err)
+}
+
+func TestNewClamdProcessor(t *testing.T) {
+	processor := NewClamdProcessor("unix:///run/clamav/clamd.ctl").(*clamdProcessor)
+	test.IsEqualString(t, processor.network, "unix")
+	test.IsEqualString(t, processor.address, "/run/clamav/clamd.ctl")
+	processor = NewClamdProcessor("tcp://clamav:3310").(*clamdProcessor)
+	test.IsEqualString(t, processor.network, "tcp")
+	test.IsEqualString(t, processor.address, "clamav:3310")
+	processor = NewClamdProcessor("clamav:3310").(*clamdProcessor)
+	test.IsEqualString(t, processor.network, "tcp")
+	test.IsEqualString(t, processor.address, "clamav:3310")
+}
+
+func TestParseClamdResponse(t *testing.T) {
+	verdict, err := parseClamdResponse([]byte("stream: OK\x00"))
+	test.IsNil(t, err)
+	test.IsEqualBool(t, verdict.Passed, true)
+	verdict, err = parseClamdResponse([]byte("stream: Win.Test.EICAR_HDB-1 FOUND\n"))
+	test.IsNil(t, err)
+	test.IsEqualBool(t, verdict.Passed, false)
+	test.IsEqualString(t, verdict.Reason, "virus found: Win.Test.EICAR_HDB-1")
+	_, err = parseClamdResponse([]byte("\x00"))
+	test.IsNotNil(t, err)
+	_, err = parseClamdResponse([]byte("stream: Can't allocate memory ERROR\x00"))
+	test.IsNotNil(t, err)
+}
+
+func TestCommandProcessor(t *testing.T) {
+	path := createTestFile(t, "content")
+
+	processor := NewCommandProcessor("true")
+	test.IsEqualString(t, processor.GetName(), "command true")
+	verdict, err := processor.Process(context.Background(), path)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, verdict.Passed, true)
+
+	verdict, err = NewCommandProcessor("false").Process(context.Background(), path)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, verdict.Passed, false)
+	test.IsEqualString(t, verdict.Reason, "exit code 1")
+
+	script := filepath.Join(t.TempDir(), "check.sh")
+	test.IsNil(t, os.WriteFile(script, []byte("#!/bin/sh\nif [ \"$1\" = \"--strict\" ] && [ \"$(cat \"$2\")\" = \"content\" ]; then\n echo \"forbidden content\"\n echo \"second line\"\n exit 3\nfi\nexit 0\n"), 0700))
+	verdict, err = NewCommandProcessor(script+"  --strict").Process(context.Background(), path)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, verdict.Passed, false)
+	test.IsEqualString(t, verdict.Reason, "forbidden content")
+	verdict, err = NewCommandProcessor(script).Process(context.Background(), path)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, verdict.Passed, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	slowScript := filepath.Join(t.TempDir(), "slow.sh")
+	test.IsNil(t, os.WriteFile(slowScript, []byte("#!/bin/sh\nsleep 5\n"), 0700))
+	_, err = NewCommandProcessor(slowScript).Process(ctx, path)
+	test.IsNotNil(t, err)
+
+	_, err = NewCommandProcessor("/invalid/command").Process(context.Background(), path)
+	test.IsNotNil(t, err)
+	_, err = NewCommandProcessor("").Process(context.Background(), path)
+	test.IsNotNil(t, err)
+}
+
+func TestGetFirstLine(t *testing.T) {
+	test.IsEqualString(t, getFirstLine("\n  first line \nsecond line"), "first line")
+	test.IsEqualString(t, getFirstLine(""), "")
+	test.IsEqualInt(t, len(getFirstLine(strings.Repeat("a", 500))), maxReasonLength)
+}
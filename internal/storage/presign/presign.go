@@ -1,18 +1,33 @@
 package presign
 
 import (
+	"encoding/json"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
 )
 
+const sharedKeyPrefix = "presign:"
+
 var presignedUrls = make(map[string]models.Presign)
 var mutex sync.RWMutex
 var startCleanupOnce sync.Once
 
 // Save saves the presigned url
 func Save(presign models.Presign) {
+	if sharedstate.IsEnabled() {
+		value, err := json.Marshal(presign)
+		helper.Check(err)
+		err = sharedstate.Get().Set(sharedKeyPrefix+presign.Id, value, time.Until(time.Unix(presign.Expiry, 0)))
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return
+	}
 	mutex.Lock()
 	presignedUrls[presign.Id] = presign
 	mutex.Unlock()
@@ -21,9 +36,7 @@ func Save(presign models.Presign) {
 
 // Get returns the presigned url with the given ID or false if not a valid ID
 func Get(id string) (models.Presign, bool) {
-	mutex.RLock()
-	defer mutex.RUnlock()
-	result, ok := presignedUrls[id]
+	result, ok := getPresign(id)
 	if !ok {
 		return models.Presign{}, false
 	}
@@ -33,8 +46,39 @@ func Get(id string) (models.Presign, bool) {
 	return result, true
 }
 
+func getPresign(id string) (models.Presign, bool) {
+	if sharedstate.IsEnabled() {
+		value, ok, err := sharedstate.Get().Get(sharedKeyPrefix + id)
+		if err != nil {
+			sharedstate.PrintError(err)
+			return models.Presign{}, false
+		}
+		if !ok {
+			return models.Presign{}, false
+		}
+		var result models.Presign
+		err = json.Unmarshal(value, &result)
+		if err != nil {
+			fmt.Println("Could not read presigned URL from shared state: " + err.Error())
+			return models.Presign{}, false
+		}
+		return result, true
+	}
+	mutex.RLock()
+	defer mutex.RUnlock()
+	result, ok := presignedUrls[id]
+	return result, ok
+}
+
 // Delete deletes the presigned url with the given ID
 func Delete(id string) {
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Get().Delete(sharedKeyPrefix + id)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return
+	}
 	mutex.Lock()
 	delete(presignedUrls, id)
 	mutex.Unlock()
@@ -0,0 +1,245 @@
+package preview
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// maxInlineLength is the maximum length of inline code, emphasis and links. The end of an element is only
+// searched within this length, so that rendering paragraphs with many unmatched markers is not slow
+const maxInlineLength = 1000
+
+// RenderMarkdown converts a Markdown document to HTML. Only a safe subset is supported: headings,
+// paragraphs, lists, quotes, code blocks, horizontal rules, emphasis with asterisks, inline code
+// and links. HTML in the document is escaped and links are only created for http, https and mailto URLs
+func RenderMarkdown(input string) string {
+	renderer := markdownRenderer{}
+	for _, line := range strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n") {
+		renderer.processLine(line)
+	}
+	if renderer.isInCodeBlock {
+		renderer.output.WriteString("</code></pre>\n")
+	}
+	renderer.closeBlock()
+	return renderer.output.String()
+}
+
+type markdownRenderer struct {
+	output        strings.Builder
+	paragraph     []string // The lines of the current paragraph
+	quote         []string // The lines of the current quote
+	listTag       string   // "ul" or "ol", if a list is open
+	isInCodeBlock bool
+}
+
+func (m *markdownRenderer) processLine(line string) {
+	trimmed := strings.TrimSpace(line)
+	if m.isInCodeBlock {
+		if strings.HasPrefix(trimmed, "```") {
+			m.output.WriteString("</code></pre>\n")
+			m.isInCodeBlock = false
+			return
+		}
+		m.output.WriteString(html.EscapeString(line) + "\n")
+		return
+	}
+	if strings.HasPrefix(trimmed, "```") {
+		m.closeBlock()
+		m.output.WriteString("<pre><code>")
+		m.isInCodeBlock = true
+		return
+	}
+	if trimmed == "" {
+		m.closeBlock()
+		return
+	}
+	if isHorizontalRule(trimmed) {
+		m.closeBlock()
+		m.output.WriteString("<hr>\n")
+		return
+	}
+	level, heading := getHeading(trimmed)
+	if level > 0 {
+		m.closeBlock()
+		tag := "h" + strconv.Itoa(level)
+		m.output.WriteString("<" + tag + ">" + renderInline(heading) + "</" + tag + ">\n")
+		return
+	}
+	if strings.HasPrefix(trimmed, ">") {
+		if m.quote == nil {
+			m.closeBlock()
+		}
+		m.quote = append(m.quote, strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+		return
+	}
+	listTag, item := getListItem(trimmed)
+	if listTag != "" {
+		if m.listTag != listTag {
+			m.closeBlock()
+			m.listTag = listTag
+			m.output.WriteString("<" + listTag + ">\n")
+		}
+		m.output.WriteString("<li>" + renderInline(item) + "</li>\n")
+		return
+	}
+	if m.paragraph == nil {
+		m.closeBlock()
+	}
+	m.paragraph = append(m.paragraph, trimmed)
+}
+
+// closeBlock writes the end of the current paragraph, quote or list
+func (m *markdownRenderer) closeBlock() {
+	if m.paragraph != nil {
+		m.output.WriteString("<p>" + renderInline(strings.Join(m.paragraph, " ")) + "</p>\n")
+		m.paragraph = nil
+	}
+	if m.quote != nil {
+		m.output.WriteString("<blockquote><p>" + renderInline(strings.Join(m.quote, " ")) + "</p></blockquote>\n")
+		m.quote = nil
+	}
+	if m.listTag != "" {
+		m.output.WriteString("</" + m.listTag + ">\n")
+		m.listTag = ""
+	}
+}
+
+func isHorizontalRule(line string) bool {
+	line = strings.ReplaceAll(line, " ", "")
+	if len(line) < 3 || !strings.ContainsRune("-*_", rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// getHeading returns the level and the text of a heading, or level 0 if the line is not a heading
+func getHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+	if level < len(line) && line[level] != ' ' {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(line[level:])
+}
+
+// getListItem returns the tag of the list and the text of the item, or an empty tag if the line is not a list item
+func getListItem(line string) (string, string) {
+	if len(line) > 1 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return "ul", strings.TrimSpace(line[2:])
+	}
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits+1 >= len(line) {
+		return "", ""
+	}
+	if (line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' ' {
+		return "ol", strings.TrimSpace(line[digits+2:])
+	}
+	return "", ""
+}
+
+// renderInline converts inline code, emphasis and links to HTML and escapes all other text
+func renderInline(text string) string {
+	var result strings.Builder
+	for len(text) > 0 {
+		next := strings.IndexAny(text, "`*[")
+		if next == -1 {
+			result.WriteString(html.EscapeString(text))
+			break
+		}
+		result.WriteString(html.EscapeString(text[:next]))
+		text = text[next:]
+		output, length := renderInlineElement(text)
+		if length == 0 {
+			result.WriteString(html.EscapeString(text[:1]))
+			text = text[1:]
+			continue
+		}
+		result.WriteString(output)
+		text = text[length:]
+	}
+	return result.String()
+}
+
+// renderInlineElement renders the element at the start of text. Returns the HTML and the number of
+// characters that were used, or 0 if text does not start with a complete element
+func renderInlineElement(text string) (string, int) {
+	switch {
+	case text[0] == '`':
+		end := strings.IndexByte(getSearchArea(text)[1:], '`')
+		if end > 0 {
+			return "<code>" + html.EscapeString(text[1:end+1]) + "</code>", end + 2
+		}
+	case strings.HasPrefix(text, "**"):
+		content, ok := getEmphasis(text, "**")
+		if ok {
+			return "<strong>" + renderInline(content) + "</strong>", len(content) + 4
+		}
+	case text[0] == '*':
+		content, ok := getEmphasis(text, "*")
+		if ok {
+			return "<em>" + renderInline(content) + "</em>", len(content) + 2
+		}
+	case text[0] == '[':
+		return renderLink(text)
+	}
+	return "", 0
+}
+
+// getEmphasis returns the text between the marker at the start of text and the next marker.
+// The text must not start or end with a space
+func getEmphasis(text, marker string) (string, bool) {
+	end := strings.Index(getSearchArea(text)[len(marker):], marker)
+	if end <= 0 {
+		return "", false
+	}
+	content := text[len(marker) : len(marker)+end]
+	if strings.HasPrefix(content, " ") || strings.HasSuffix(content, " ") {
+		return "", false
+	}
+	return content, true
+}
+
+// renderLink renders a link in the format [label](url). If the URL does not use an allowed scheme,
+// only the label is returned
+func renderLink(text string) (string, int) {
+	labelEnd := strings.Index(getSearchArea(text), "](")
+	if labelEnd == -1 {
+		return "", 0
+	}
+	urlEnd := strings.IndexByte(getSearchArea(text[labelEnd:]), ')')
+	if urlEnd == -1 {
+		return "", 0
+	}
+	label := text[1:labelEnd]
+	if strings.ContainsRune(label, ']') {
+		return "", 0
+	}
+	url := strings.TrimSpace(text[labelEnd+2 : labelEnd+urlEnd])
+	length := labelEnd + urlEnd + 1
+	if !isAllowedLink(url) {
+		return renderInline(label), length
+	}
+	return "<a href=\"" + html.EscapeString(url) + "\" target=\"_blank\" rel=\"nofollow noopener noreferrer\">" + renderInline(label) + "</a>", length
+}
+
+// getSearchArea returns the start of text, in which the end of an inline element is searched
+func getSearchArea(text string) string {
+	if len(text) > maxInlineLength {
+		return text[:maxInlineLength]
+	}
+	return text
+}
+
+func isAllowedLink(url string) bool {
+	url = strings.ToLower(url)
+	return strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "mailto:")
+}
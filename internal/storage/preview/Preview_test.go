@@ -0,0 +1,121 @@
+//go:build test
+
+package preview
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func TestIsThumbnailSupported(t *testing.T) {
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "a.jpg", SHA1: "hash", ContentType: "image/jpeg"}), true)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "a", SHA1: "hash", ContentType: "image/WEBP"}), true)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "a.png", SHA1: "hash", ContentType: "application/octet-stream"}), true)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "a.gif", SHA1: "hash"}), true)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "a.png", SHA1: "hash", ContentType: "text/plain"}), false)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "a.svg", SHA1: "hash", ContentType: "image/svg+xml"}), false)
+	test.IsEqualBool(t, IsThumbnailSupported(models.File{Name: "a.jpg", ContentType: "image/jpeg"}), false)
+	encrypted := models.File{Name: "a.jpg", SHA1: "hash", ContentType: "image/jpeg"}
+	encrypted.Encryption.IsEncrypted = true
+	test.IsEqualBool(t, IsThumbnailSupported(encrypted), false)
+}
+
+func TestGetThumbnailDimensions(t *testing.T) {
+	width, height := getThumbnailDimensions(100, 50)
+	test.IsEqualInt(t, width, 100)
+	test.IsEqualInt(t, height, 50)
+	width, height = getThumbnailDimensions(1600, 1200)
+	test.IsEqualInt(t, width, ThumbnailSize)
+	test.IsEqualInt(t, height, 300)
+	width, height = getThumbnailDimensions(1000, 2000)
+	test.IsEqualInt(t, width, 200)
+	test.IsEqualInt(t, height, ThumbnailSize)
+	width, height = getThumbnailDimensions(10000, 1)
+	test.IsEqualInt(t, width, ThumbnailSize)
+	test.IsEqualInt(t, height, 1)
+}
+
+func TestGenerateThumbnail(t *testing.T) {
+	picture := image.NewNRGBA(image.Rect(0, 0, 800, 200))
+	for x := 0; x < 800; x++ {
+		picture.Set(x, 10, color.NRGBA{R: 255, A: 255})
+	}
+	encoders := map[string]func(*bytes.Buffer) error{
+		"png":  func(b *bytes.Buffer) error { return png.Encode(b, picture) },
+		"jpeg": func(b *bytes.Buffer) error { return jpeg.Encode(b, picture, nil) },
+		"gif":  func(b *bytes.Buffer) error { return gif.Encode(b, picture, nil) },
+	}
+	for name, encode := range encoders {
+		var input, output bytes.Buffer
+		test.IsNil(t, encode(&input))
+		err := generateThumbnail(&input, &output)
+		test.IsNil(t, err)
+		result, format, err := image.Decode(&output)
+		test.IsNil(t, err)
+		test.IsEqualString(t, format, "jpeg")
+		test.IsEqualInt(t, result.Bounds().Dx(), ThumbnailSize)
+		test.IsEqualInt(t, result.Bounds().Dy(), 100)
+		if t.Failed() {
+			t.Fatal("Thumbnail for " + name + " could not be created")
+		}
+	}
+
+	var output bytes.Buffer
+	err := generateThumbnail(bytes.NewBufferString("not a picture"), &output)
+	test.IsNotNil(t, err)
+
+	var largePicture bytes.Buffer
+	test.IsNil(t, png.Encode(&largePicture, image.NewGray(image.Rect(0, 0, 10000, 5001))))
+	err = generateThumbnail(&largePicture, &output)
+	test.IsEqual(t, err, ErrorPictureTooLarge)
+}
+
+func TestIsTextSupported(t *testing.T) {
+	test.IsEqualBool(t, IsTextSupported(models.File{Name: "a", ContentType: "text/plain", SizeBytes: 10}, 10), true)
+	test.IsEqualBool(t, IsTextSupported(models.File{Name: "a.md", ContentType: "application/octet-stream", SizeBytes: 10}, 10), true)
+	test.IsEqualBool(t, IsTextSupported(models.File{Name: "a.txt", ContentType: "text/plain", SizeBytes: 11}, 10), false)
+	test.IsEqualBool(t, IsTextSupported(models.File{Name: "a.bin", ContentType: "application/octet-stream", SizeBytes: 10}, 10), false)
+	encrypted := models.File{Name: "a.txt", ContentType: "text/plain", SizeBytes: 10}
+	encrypted.Encryption.IsEncrypted = true
+	test.IsEqualBool(t, IsTextSupported(encrypted, 10), false)
+
+	test.IsEqualBool(t, IsMarkdown(models.File{Name: "README.MD"}), true)
+	test.IsEqualBool(t, IsMarkdown(models.File{Name: "readme", ContentType: "text/markdown; charset=utf-8"}), true)
+	test.IsEqualBool(t, IsMarkdown(models.File{Name: "readme.txt", ContentType: "text/plain"}), false)
+
+	test.IsEqualBool(t, IsText([]byte("Hello wörld\n")), true)
+	test.IsEqualBool(t, IsText([]byte{0x48, 0x00, 0x49}), false)
+	test.IsEqualBool(t, IsText([]byte{0xff, 0xfe}), false)
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	test.IsEqualString(t, RenderMarkdown("# Title\nSome *text* with **bold**\nand `<code>`"),
+		"<h1>Title</h1>\n<p>Some <em>text</em> with <strong>bold</strong> and <code>&lt;code&gt;</code></p>\n")
+	test.IsEqualString(t, RenderMarkdown("- one\n- two\n\n1. first\n2) second"),
+		"<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n")
+	test.IsEqualString(t, RenderMarkdown("> quoted\n> text\n***\n```\n<script>alert(1)</script>\n```"),
+		"<blockquote><p>quoted text</p></blockquote>\n<hr>\n<pre><code>&lt;script&gt;alert(1)&lt;/script&gt;\n</code></pre>\n")
+	test.IsEqualString(t, RenderMarkdown("[Gokapi](https://github.com/Forceu/Gokapi) [bad](javascript:alert(1)) [text]"),
+		"<p><a href=\"https://github.com/Forceu/Gokapi\" target=\"_blank\" rel=\"nofollow noopener noreferrer\">Gokapi</a> bad) [text]</p>\n")
+	test.IsEqualString(t, RenderMarkdown("<b onclick=\"x\">5 * 3 * 2</b>\n#no heading\n####### no heading"),
+		"<p>&lt;b onclick=&#34;x&#34;&gt;5 * 3 * 2&lt;/b&gt; #no heading ####### no heading</p>\n")
+	test.IsEqualString(t, RenderMarkdown("```\nunclosed"), "<pre><code>unclosed\n</code></pre>\n")
+	test.IsEqualString(t, RenderMarkdown(""), "")
+	longText := strings.Repeat("a", maxInlineLength)
+	test.IsEqualString(t, RenderMarkdown("*"+longText+"*"), "<p>*"+longText+"*</p>\n")
+
+	// Paragraphs with many unmatched markers must not take quadratic time
+	start := time.Now()
+	RenderMarkdown(strings.Repeat("[*`", 100000))
+	test.IsEqualBool(t, time.Since(start) < 5*time.Second, true)
+}
@@ -0,0 +1,40 @@
+package preview
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+var textExtensions = []string{".txt", ".md", ".markdown", ".log", ".csv", ".json", ".xml", ".yml", ".yaml", ".ini", ".conf"}
+var markdownExtensions = []string{".md", ".markdown"}
+
+// IsTextSupported returns true, if the content of the file can be shown as a text preview.
+// Only unencrypted files up to the size of maxSizeBytes are supported
+func IsTextSupported(file models.File, maxSizeBytes int64) bool {
+	if file.Encryption.IsEncrypted || file.SizeBytes > maxSizeBytes {
+		return false
+	}
+	contentType := strings.ToLower(file.ContentType)
+	if strings.HasPrefix(contentType, "text/") {
+		return true
+	}
+	return helper.IsInArray(textExtensions, strings.ToLower(filepath.Ext(file.Name)))
+}
+
+// IsMarkdown returns true, if the file is a Markdown document
+func IsMarkdown(file models.File) bool {
+	if strings.HasPrefix(strings.ToLower(file.ContentType), "text/markdown") {
+		return true
+	}
+	return helper.IsInArray(markdownExtensions, strings.ToLower(filepath.Ext(file.Name)))
+}
+
+// IsText returns true, if the content is valid UTF-8 and does not contain binary data
+func IsText(content []byte) bool {
+	return utf8.Valid(content) && !bytes.ContainsRune(content, 0)
+}
@@ -0,0 +1,229 @@
+package preview
+
+/**
+Creates thumbnails of pictures and previews of text files, which are shown on the download page
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Required to decode GIF pictures
+	"image/jpeg"
+	_ "image/png" // Required to decode PNG pictures
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Required to decode WebP pictures
+)
+
+// ThumbnailSize is the maximum width and height of a thumbnail in pixels
+const ThumbnailSize = 400
+
+// maxPixels is the maximum number of pixels of a picture that is decoded, to limit the memory usage
+const maxPixels = 50 * 1000 * 1000
+
+// thumbnailSuffix is appended to the hash of the file to get the name of the stored thumbnail
+const thumbnailSuffix = ".thumb"
+
+// failedSuffix is appended to the hash of the file to get the name of the empty marker, which is
+// stored if no thumbnail can be created for the content
+const failedSuffix = ".thumbfailed"
+
+// ErrorPictureTooLarge is returned if a picture has too many pixels for creating a thumbnail
+var ErrorPictureTooLarge = errors.New("picture is too large for creating a thumbnail")
+
+// ErrorInvalidPicture is returned if the picture could not be decoded. Other errors are wrapped by it
+var ErrorInvalidPicture = errors.New("cannot create thumbnail of picture")
+
+var thumbnailContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+var thumbnailExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
+
+// IsThumbnailSupported returns true, if a thumbnail can be created for the file. Thumbnails are not
+// created for encrypted files, as they would be stored unencrypted
+func IsThumbnailSupported(file models.File) bool {
+	if file.Encryption.IsEncrypted || file.SHA1 == "" {
+		return false
+	}
+	contentType := strings.ToLower(file.ContentType)
+	if helper.IsInArray(thumbnailContentTypes, contentType) {
+		return true
+	}
+	if contentType != "" && contentType != "application/octet-stream" {
+		return false
+	}
+	return helper.IsInArray(thumbnailExtensions, strings.ToLower(filepath.Ext(file.Name)))
+}
+
+// getThumbnailObject returns a file that points to the stored thumbnail. The thumbnail is
+// stored next to the content of the file, either in the data directory or in the same bucket
+func getThumbnailObject(file models.File) models.File {
+	return getObject(file, thumbnailSuffix, "image/jpeg")
+}
+
+// getFailedObject returns a file that points to the marker, which is stored if no thumbnail can be created
+func getFailedObject(file models.File) models.File {
+	return getObject(file, failedSuffix, "application/octet-stream")
+}
+
+func getObject(file models.File, suffix, contentType string) models.File {
+	return models.File{
+		Id:          file.Id,
+		Name:        file.Name,
+		SHA1:        file.SHA1 + suffix,
+		AwsBucket:   file.AwsBucket,
+		ContentType: contentType,
+	}
+}
+
+// ThumbnailExists returns true, if a thumbnail has been stored for the content of the file
+func ThumbnailExists(file models.File) bool {
+	return objectExists(getThumbnailObject(file))
+}
+
+// IsThumbnailFailed returns true, if creating the thumbnail failed previously, as the content of the
+// file could not be decoded
+func IsThumbnailFailed(file models.File) bool {
+	return objectExists(getFailedObject(file))
+}
+
+// SetThumbnailFailed stores that no thumbnail can be created for the content of the file,
+// so that it is not attempted again for every request
+func SetThumbnailFailed(file models.File) error {
+	return storeObject(getFailedObject(file), &bytes.Buffer{})
+}
+
+func objectExists(object models.File) bool {
+	if !object.IsLocalStorage() {
+		exists, _, err := aws.FileExists(object)
+		return err == nil && exists
+	}
+	exists, err := helper.FileExists(configuration.Get().DataDir + "/" + object.SHA1)
+	return err == nil && exists
+}
+
+// CreateThumbnail creates a thumbnail of the picture that is read from source and stores it
+// for the content of the file. If the picture cannot be decoded, ErrorInvalidPicture is returned
+func CreateThumbnail(file models.File, source io.Reader) error {
+	var buffer bytes.Buffer
+	err := generateThumbnail(source, &buffer)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrorInvalidPicture, err)
+	}
+	return storeObject(getThumbnailObject(file), &buffer)
+}
+
+func storeObject(object models.File, content *bytes.Buffer) error {
+	if !object.IsLocalStorage() {
+		_, err := aws.Upload(content, object)
+		return err
+	}
+	dataDir := configuration.Get().DataDir
+	// The object is renamed after writing, so that other instances never read an incomplete file
+	tempFile, err := os.CreateTemp(dataDir, "upload")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tempFile, content)
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), dataDir+"/"+object.SHA1)
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+	}
+	return err
+}
+
+// ServeThumbnail writes the stored thumbnail of the file to the client
+func ServeThumbnail(file models.File, w http.ResponseWriter) error {
+	thumbnail := getThumbnailObject(file)
+	if !thumbnail.IsLocalStorage() {
+		var buffer bytes.Buffer
+		err := aws.Stream(&buffer, thumbnail)
+		if err != nil {
+			return err
+		}
+		writeThumbnailHeaders(w, int64(buffer.Len()))
+		_, _ = w.Write(buffer.Bytes())
+		return nil
+	}
+	content, err := os.Open(configuration.Get().DataDir + "/" + thumbnail.SHA1)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	size, err := helper.GetFileSize(content)
+	if err != nil {
+		return err
+	}
+	writeThumbnailHeaders(w, size)
+	_, _ = io.Copy(w, content)
+	return nil
+}
+
+func writeThumbnailHeaders(w http.ResponseWriter, size int64) {
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+}
+
+// DeleteThumbnail removes the stored thumbnail of the file and the marker of a failed creation, if they exist
+func DeleteThumbnail(file models.File) {
+	for _, object := range []models.File{getThumbnailObject(file), getFailedObject(file)} {
+		if !object.IsLocalStorage() {
+			_, _ = aws.DeleteObject(object)
+			continue
+		}
+		err := os.Remove(configuration.Get().DataDir + "/" + object.SHA1)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Println("Warning, cannot delete thumbnail of file " + file.Id + ": " + err.Error())
+		}
+	}
+}
+
+// generateThumbnail decodes the picture, scales it down to fit ThumbnailSize and writes it as a JPEG.
+// Transparent areas are filled with white
+func generateThumbnail(input io.Reader, output io.Writer) error {
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(input, &header))
+	if err != nil {
+		return err
+	}
+	if config.Width*config.Height > maxPixels {
+		return ErrorPictureTooLarge
+	}
+	source, _, err := image.Decode(io.MultiReader(&header, input))
+	if err != nil {
+		return err
+	}
+	width, height := getThumbnailDimensions(source.Bounds().Dx(), source.Bounds().Dy())
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(result, result.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(result, result.Bounds(), source, source.Bounds(), draw.Over, nil)
+	return jpeg.Encode(output, result, &jpeg.Options{Quality: 85})
+}
+
+// getThumbnailDimensions returns the size of the thumbnail, keeping the aspect ratio.
+// Pictures that are smaller than ThumbnailSize are not enlarged
+func getThumbnailDimensions(width, height int) (int, int) {
+	if width <= ThumbnailSize && height <= ThumbnailSize {
+		return max(width, 1), max(height, 1)
+	}
+	if width >= height {
+		return ThumbnailSize, max(height*ThumbnailSize/width, 1)
+	}
+	return max(width*ThumbnailSize/height, 1), ThumbnailSize
+}
@@ -18,6 +18,12 @@ const StatusFinished = 2
 // StatusError indicates that there was an error during the upload
 const StatusError = 3
 
+// StatusPostProcessing indicates that the file is checked by the post-processing pipeline, e.g. scanned for viruses
+const StatusPostProcessing = 4
+
+// StatusQuarantined indicates that the file failed the post-processing and has been quarantined
+const StatusQuarantined = 5
+
 // Set sets the status for an id
 func Set(id string, status int, file models.File, userId int, err error) {
 	newStatus := models.UploadStatus{
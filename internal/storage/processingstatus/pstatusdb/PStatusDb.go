@@ -1,12 +1,20 @@
 package pstatusdb
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
 )
 
+const statusLifetime = 24 * time.Hour
+const sharedGroupPrefix = "pstatus:"
+
 var statusMap = make(map[string]models.UploadStatus)
 var statusMutex sync.RWMutex
 var startCleanupOnce sync.Once
@@ -24,6 +32,15 @@ func getAll() []models.UploadStatus {
 
 // GetAllForUser returns all UploadStatus that were created in the last 24 hours for a user
 func GetAllForUser(userId int) []models.UploadStatus {
+	if sharedstate.IsEnabled() {
+		result := make([]models.UploadStatus, 0)
+		for _, status := range getAllShared(userId) {
+			if status.IsForUser(userId) {
+				result = append(result, status)
+			}
+		}
+		return result
+	}
 	statusMutex.RLock()
 	result := make([]models.UploadStatus, 0)
 	for _, status := range statusMap {
@@ -37,9 +54,13 @@ func GetAllForUser(userId int) []models.UploadStatus {
 
 // Set saves the upload status for 24 hours
 func Set(status models.UploadStatus) {
+	if sharedstate.IsEnabled() {
+		setShared(status)
+		return
+	}
 	statusMutex.Lock()
 	oldStatus, ok := statusMap[status.ChunkId]
-	if ok && oldStatus.CurrentStatus > status.CurrentStatus {
+	if ok && isOutdated(oldStatus, status) {
 		statusMutex.Unlock()
 		return
 	}
@@ -49,8 +70,64 @@ func Set(status models.UploadStatus) {
 	startCleanupOnce.Do(func() { go doGarbageCollection(true) })
 }
 
+// statusStages maps the status values of the processingstatus package to the stage of the upload they belong to.
+// Post-processing (4) can happen either before or after the file is moved to the data filesystem (1), and
+// a quarantined file (5) is as final as an error (3)
+var statusStages = map[int]int{0: 0, 1: 1, 4: 1, 2: 2, 3: 3, 5: 3}
+
+// isOutdated returns true if newStatus belongs to an earlier stage of the upload than oldStatus
+func isOutdated(oldStatus, newStatus models.UploadStatus) bool {
+	return statusStages[oldStatus.CurrentStatus] > statusStages[newStatus.CurrentStatus]
+}
+
+// getAllShared returns all UploadStatus of the user that are stored in the shared state
+func getAllShared(userId int) map[string]models.UploadStatus {
+	result := make(map[string]models.UploadStatus)
+	entries, err := sharedstate.Get().GetGroupEntries(sharedGroupPrefix + strconv.Itoa(userId))
+	if err != nil {
+		sharedstate.PrintError(err)
+		return result
+	}
+	for chunkId, value := range entries {
+		var status models.UploadStatus
+		err = json.Unmarshal(value, &status)
+		if err != nil {
+			fmt.Println("Could not read upload status " + chunkId + " from shared state: " + err.Error())
+			continue
+		}
+		result[chunkId] = status
+	}
+	return result
+}
+
+// setShared saves the upload status in the shared state, so that it is available on all instances
+func setShared(status models.UploadStatus) {
+	group := sharedGroupPrefix + strconv.Itoa(status.UserId)
+	err := sharedstate.Lock(group, 10*time.Second)
+	if err != nil {
+		sharedstate.PrintError(err)
+		return
+	}
+	defer func() {
+		if err := sharedstate.Unlock(group); err != nil {
+			sharedstate.PrintError(err)
+		}
+	}()
+	oldStatus, ok := getAllShared(status.UserId)[status.ChunkId]
+	if ok && isOutdated(oldStatus, status) {
+		return
+	}
+	status.Creation = time.Now().Unix()
+	value, err := json.Marshal(status)
+	helper.Check(err)
+	err = sharedstate.Get().SetGroupEntry(group, status.ChunkId, value, statusLifetime)
+	if err != nil {
+		sharedstate.PrintError(err)
+	}
+}
+
 func deleteAllExpiredStatus() {
-	cutOff := time.Now().Add(-24 * time.Hour).Unix()
+	cutOff := time.Now().Add(-statusLifetime).Unix()
 	statusMutex.Lock()
 	newStatusMap := make(map[string]models.UploadStatus)
 	for _, status := range statusMap {
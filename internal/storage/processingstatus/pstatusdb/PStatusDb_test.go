@@ -4,6 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate/redisstate"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/test"
 )
@@ -46,6 +49,18 @@ func TestSetStatus(t *testing.T) {
 	test.IsEqualInt(t, status.CurrentStatus, 3)
 	test.IsEqualString(t, status.FileId, "testfile")
 	test.IsEqualString(t, status.ErrorMessage, "test")
+
+	// Post-processing may be reported before or after moving the file, but never after it has finished
+	const postProcessId = "testchunkpostprocess"
+	Set(models.UploadStatus{ChunkId: postProcessId, CurrentStatus: 4})
+	Set(models.UploadStatus{ChunkId: postProcessId, CurrentStatus: 1})
+	status, _ = getStatus(postProcessId)
+	test.IsEqualInt(t, status.CurrentStatus, 1)
+	Set(models.UploadStatus{ChunkId: postProcessId, CurrentStatus: 5, ErrorMessage: "virus found"})
+	Set(models.UploadStatus{ChunkId: postProcessId, CurrentStatus: 2})
+	status, _ = getStatus(postProcessId)
+	test.IsEqualInt(t, status.CurrentStatus, 5)
+	delete(statusMap, postProcessId)
 }
 
 func TestGarbageCollection(t *testing.T) {
@@ -73,3 +88,39 @@ func getStatus(id string) (models.UploadStatus, bool) {
 	}
 	return models.UploadStatus{}, false
 }
+
+func TestSharedState(t *testing.T) {
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	defer sharedstate.Close()
+	otherInstance, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	defer otherInstance.Close()
+
+	test.IsEqualInt(t, len(GetAllForUser(5)), 0)
+	Set(models.UploadStatus{
+		ChunkId:       "sharedchunk",
+		CurrentStatus: 1,
+		FileId:        "sharedfile",
+		UserId:        5,
+	})
+	entries, err := otherInstance.GetGroupEntries("pstatus:5")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(entries), 1)
+	Set(models.UploadStatus{
+		ChunkId:       "sharedchunk",
+		CurrentStatus: 0,
+		UserId:        5,
+	})
+	statusList := GetAllForUser(5)
+	test.IsEqualInt(t, len(statusList), 1)
+	test.IsEqualString(t, statusList[0].FileId, "sharedfile")
+	test.IsEqualInt(t, statusList[0].CurrentStatus, 1)
+	test.IsEqualInt(t, len(GetAllForUser(6)), 0)
+	_, ok := statusMap["sharedchunk"]
+	test.IsEqualBool(t, ok, false)
+	mRedis.FastForward(25 * time.Hour)
+	test.IsEqualInt(t, len(GetAllForUser(5)), 0)
+}
@@ -0,0 +1,105 @@
+package scrub
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+)
+
+// Resolve applies the action to all unresolved findings of the last completed check.
+// Returns the updated report and the number of findings that were resolved
+func Resolve(action string) (models.ScrubReport, int, error) {
+	if !models.IsValidScrubAction(action) {
+		return models.ScrubReport{}, 0, ErrorInvalidAction
+	}
+	if !tryLock() {
+		return models.ScrubReport{}, 0, ErrorInProgress
+	}
+	defer unlock()
+	report, ok := GetReport()
+	if !ok || report.IsRunning {
+		return models.ScrubReport{}, 0, ErrorNoReport
+	}
+	resolved := applyAction(action, &report)
+	saveReport(report)
+	return report, resolved, nil
+}
+
+// applyAction quarantines or deletes orphaned objects and quarantines corrupted objects.
+// Objects that are referenced by a file are never deleted. Returns the number of resolved findings
+func applyAction(action string, report *models.ScrubReport) int {
+	if action == models.ScrubActionNone {
+		return 0
+	}
+	resolved := 0
+	// Files might have been uploaded since the check was run
+	referenced := getReferencedObjects()
+	for i := range report.Findings {
+		finding := &report.Findings[i]
+		if finding.Resolution != "" {
+			continue
+		}
+		var err error
+		switch finding.Type {
+		case models.ScrubFindingOrphan:
+			if referenced[getStorageKey(finding.Bucket, finding.Key)] != nil {
+				continue
+			}
+			if action == models.ScrubActionDelete {
+				err = deleteObject(finding)
+			} else {
+				err = quarantineObject(finding)
+			}
+		case models.ScrubFindingHashMismatch, models.ScrubFindingDecryptionFailed:
+			err = quarantineObject(finding)
+		default:
+			continue
+		}
+		if err != nil {
+			fmt.Println("Could not resolve storage check finding for " + finding.Key + ": " + err.Error())
+			continue
+		}
+		resolved++
+	}
+	return resolved
+}
+
+func quarantineObject(finding *models.ScrubFinding) error {
+	if finding.IsLocalStorage() {
+		quarantineDir := configuration.Get().DataDir + "/" + QuarantineDir
+		err := os.MkdirAll(quarantineDir, 0770)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(configuration.Get().DataDir+"/"+finding.Key, quarantineDir+"/"+finding.Key)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := aws.MoveObject(finding.Bucket, finding.Key, QuarantineDir+"/"+finding.Key, finding.Size)
+		if err != nil {
+			return err
+		}
+	}
+	finding.Resolution = models.ScrubResolutionQuarantined
+	return nil
+}
+
+func deleteObject(finding *models.ScrubFinding) error {
+	if finding.IsLocalStorage() {
+		err := os.Remove(configuration.Get().DataDir + "/" + finding.Key)
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err := aws.DeleteObject(models.File{AwsBucket: finding.Bucket, SHA1: finding.Key})
+		if err != nil {
+			return err
+		}
+	}
+	finding.Resolution = models.ScrubResolutionDeleted
+	return nil
+}
@@ -0,0 +1,62 @@
+package scrub
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/database/leader"
+	"github.com/forceu/gokapi/internal/models"
+)
+
+// LeaseScrub is the name of the lease that stores when the next scheduled check is due
+const LeaseScrub = "scrub"
+
+// scheduleCheckInterval is the interval in which is checked, if a scheduled check is due
+const scheduleCheckInterval = time.Hour
+
+// Init starts the scheduled checks, if they are enabled with GOKAPI_SCRUB_INTERVAL_HOURS.
+// If multiple instances share the database, only the leader runs the check
+func Init() {
+	env := configuration.GetEnvironment()
+	if env.ScrubIntervalHours == 0 {
+		return
+	}
+	action := env.ScrubAction
+	if !models.IsValidScrubAction(action) {
+		fmt.Println("Warning: Invalid value for GOKAPI_SCRUB_ACTION, storage check findings will only be reported")
+		action = models.ScrubActionNone
+	}
+	interval := time.Duration(env.ScrubIntervalHours) * time.Hour
+	go func() {
+		for {
+			runIfDue(interval, action)
+			time.Sleep(scheduleCheckInterval)
+		}
+	}()
+}
+
+// runIfDue runs the check, if the last scheduled check was run more than interval ago.
+// The time of the next check is stored as the expiry of a lease, so that it is kept after
+// a restart and shared between instances
+func runIfDue(interval time.Duration, action string) bool {
+	if !leader.IsLeader() {
+		return false
+	}
+	timeNow := time.Now().Unix()
+	lease, ok := database.GetLease(LeaseScrub)
+	if ok && !lease.IsExpired(timeNow) {
+		return false
+	}
+	nextRun := models.Lease{Name: LeaseScrub, Owner: LeaseScrub, ExpiresAt: timeNow + int64(interval.Seconds())}
+	if !database.TryAcquireLease(nextRun) {
+		return false
+	}
+	_, err := Run(action)
+	if err != nil {
+		fmt.Println("Could not run scheduled storage check: " + err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,488 @@
+package scrub
+
+/**
+Verifies that all stored objects match their metadata and finds objects that are not referenced by any file
+*/
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+)
+
+// ErrorInProgress is returned, if a check is already running on this or another instance
+var ErrorInProgress = errors.New("a storage check is already in progress")
+
+// ErrorInvalidAction is returned, if an unknown action was passed
+var ErrorInvalidAction = errors.New("invalid action, must be one of none, quarantine or delete")
+
+// ErrorNoReport is returned, if findings should be resolved, but no completed report exists
+var ErrorNoReport = errors.New("no completed storage check report exists")
+
+// QuarantineDir is the subdirectory of the data directory or the key prefix in a bucket,
+// where quarantined objects are moved to
+const QuarantineDir = "quarantine"
+
+// orphanMinAge is the minimum age of an unreferenced object before it is reported as an orphan.
+// This prevents reporting objects that have just been stored, but whose metadata has not been saved yet
+const orphanMinAge = 24 * time.Hour
+
+const sharedLockName = "scrub"
+const sharedReportKey = "scrub:report"
+const sharedLockDuration = 24 * time.Hour
+const sharedReportLifetime = 90 * 24 * time.Hour
+
+var runningMutex sync.Mutex
+var reportMutex sync.RWMutex
+var lastReport models.ScrubReport
+var hasReport bool
+
+// Run verifies all stored objects and searches for orphans. Afterwards the action is applied
+// to the findings. The function blocks until the check is completed
+func Run(action string) (models.ScrubReport, error) {
+	if !models.IsValidScrubAction(action) {
+		return models.ScrubReport{}, ErrorInvalidAction
+	}
+	if !tryLock() {
+		return models.ScrubReport{}, ErrorInProgress
+	}
+	defer unlock()
+	return run(action), nil
+}
+
+// Start runs the check in the background. Returns an error if a check is already running
+func Start(action string) error {
+	if !models.IsValidScrubAction(action) {
+		return ErrorInvalidAction
+	}
+	if !tryLock() {
+		return ErrorInProgress
+	}
+	go func() {
+		defer unlock()
+		run(action)
+	}()
+	return nil
+}
+
+// GetReport returns the report of the last check. Returns false if no check has been run yet
+func GetReport() (models.ScrubReport, bool) {
+	if sharedstate.IsEnabled() {
+		content, ok, err := sharedstate.Get().Get(sharedReportKey)
+		if err != nil {
+			sharedstate.PrintError(err)
+			return models.ScrubReport{}, false
+		}
+		if !ok {
+			return models.ScrubReport{}, false
+		}
+		var report models.ScrubReport
+		err = json.Unmarshal(content, &report)
+		if err != nil {
+			fmt.Println("Could not read storage check report: " + err.Error())
+			return models.ScrubReport{}, false
+		}
+		return report, true
+	}
+	reportMutex.RLock()
+	defer reportMutex.RUnlock()
+	return lastReport, hasReport
+}
+
+func saveReport(report models.ScrubReport) {
+	if report.Findings == nil {
+		report.Findings = make([]models.ScrubFinding, 0)
+	}
+	if sharedstate.IsEnabled() {
+		content, err := json.Marshal(report)
+		if err != nil {
+			fmt.Println("Could not save storage check report: " + err.Error())
+			return
+		}
+		err = sharedstate.Get().Set(sharedReportKey, content, sharedReportLifetime)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return
+	}
+	reportMutex.Lock()
+	lastReport = report
+	hasReport = true
+	reportMutex.Unlock()
+}
+
+func tryLock() bool {
+	if !runningMutex.TryLock() {
+		return false
+	}
+	if !sharedstate.IsEnabled() {
+		return true
+	}
+	isLocked, err := sharedstate.Get().TryLock(sharedLockName, sharedLockDuration)
+	if err != nil {
+		sharedstate.PrintError(err)
+	}
+	if !isLocked {
+		runningMutex.Unlock()
+		return false
+	}
+	return true
+}
+
+func unlock() {
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Get().Unlock(sharedLockName)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+	}
+	runningMutex.Unlock()
+}
+
+func run(action string) models.ScrubReport {
+	report := models.ScrubReport{
+		StartedAt: time.Now().Unix(),
+		IsRunning: true,
+		Action:    action,
+		Findings:  make([]models.ScrubFinding, 0),
+	}
+	saveReport(report)
+
+	objects := getReferencedObjects()
+	storageKeys := make([]string, 0, len(objects))
+	for storageKey := range objects {
+		storageKeys = append(storageKeys, storageKey)
+	}
+	sort.Strings(storageKeys)
+	for _, storageKey := range storageKeys {
+		verifyObject(objects[storageKey], &report)
+	}
+	err := findOrphans(objects, &report)
+	if err != nil {
+		fmt.Println("Could not search for orphaned objects: " + err.Error())
+		report.Error = err.Error()
+	}
+	applyAction(action, &report)
+
+	report.IsRunning = false
+	report.FinishedAt = time.Now().Unix()
+	saveReport(report)
+	logging.LogScrubFinished(report)
+	return report
+}
+
+// storedObject is an object in the data directory or in a bucket and all files that reference it
+type storedObject struct {
+	Bucket string
+	Key    string
+	Files  []models.File
+}
+
+func getStorageKey(bucket, key string) string {
+	return bucket + "/" + key
+}
+
+func (o *storedObject) getFileIds() []string {
+	result := make([]string, len(o.Files))
+	for i, file := range o.Files {
+		result[i] = file.Id
+	}
+	return result
+}
+
+func (o *storedObject) newFinding(findingType, details string) models.ScrubFinding {
+	return models.ScrubFinding{
+		Type:    findingType,
+		Bucket:  o.Bucket,
+		Key:     o.Key,
+		FileIds: o.getFileIds(),
+		Details: details,
+	}
+}
+
+// getReferencedObjects returns all stored objects that are referenced by at least one file or file version
+func getReferencedObjects() map[string]*storedObject {
+	result := make(map[string]*storedObject)
+	files := make([]models.File, 0)
+	for _, file := range database.GetAllMetadata() {
+		files = append(files, file)
+	}
+	for _, version := range database.GetAllFileVersions() {
+		files = append(files, version.ToFile())
+	}
+	for _, file := range files {
+		if file.SHA1 == "" {
+			continue
+		}
+		storageKey := getStorageKey(file.AwsBucket, file.SHA1)
+		object, ok := result[storageKey]
+		if !ok {
+			object = &storedObject{Bucket: file.AwsBucket, Key: file.SHA1}
+			result[storageKey] = object
+		}
+		object.Files = append(object.Files, file)
+	}
+	return result
+}
+
+// verifyObject checks that the object exists and that its content matches the SHA1 of the file
+func verifyObject(object *storedObject, report *models.ScrubReport) {
+	file := object.Files[0]
+	if !file.IsLocalStorage() && !aws.IsAvailable() {
+		return
+	}
+	rawFile := file
+	rawFile.Encryption.IsEncrypted = false
+
+	var reader io.ReadCloser
+	var size int64
+	var finding *models.ScrubFinding
+	if file.IsLocalStorage() {
+		reader, size, finding = openLocalObject(object)
+	} else {
+		reader, size, finding = openCloudObject(object, rawFile)
+	}
+	if finding != nil {
+		report.Findings = append(report.Findings, *finding)
+		return
+	}
+	defer reader.Close()
+	report.CheckedObjects++
+
+	// The content of end-to-end encrypted files is not known to the server and cannot be verified
+	if file.Encryption.IsEndToEndEncrypted {
+		return
+	}
+	if file.Encryption.IsEncrypted && !encryption.IsDecryptionAvailable() {
+		return
+	}
+	if file.Encryption.IsEncrypted && file.IsLocalStorage() {
+		localFile := reader.(*os.File)
+		if !encryption.IsCorrectKey(file.Encryption, localFile) {
+			finding := object.newFinding(models.ScrubFindingDecryptionFailed, "the file cannot be decrypted with the current key")
+			finding.Size = size
+			report.Findings = append(report.Findings, finding)
+			return
+		}
+		_, err := localFile.Seek(0, io.SeekStart)
+		if err != nil {
+			finding := object.newFinding(models.ScrubFindingReadError, err.Error())
+			finding.Size = size
+			report.Findings = append(report.Findings, finding)
+			return
+		}
+	}
+	findingType, details := verifyContent(file, reader, report)
+	if findingType != "" {
+		finding := object.newFinding(findingType, details)
+		finding.Size = size
+		report.Findings = append(report.Findings, finding)
+	}
+}
+
+func openLocalObject(object *storedObject) (io.ReadCloser, int64, *models.ScrubFinding) {
+	file, err := os.Open(configuration.Get().DataDir + "/" + object.Key)
+	if err != nil {
+		findingType := models.ScrubFindingReadError
+		if errors.Is(err, os.ErrNotExist) {
+			findingType = models.ScrubFindingMissing
+		}
+		finding := object.newFinding(findingType, err.Error())
+		return nil, 0, &finding
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		finding := object.newFinding(models.ScrubFindingReadError, err.Error())
+		return nil, 0, &finding
+	}
+	return file, info.Size(), nil
+}
+
+// openCloudObject returns a reader for the content of the object as it is stored in the bucket
+func openCloudObject(object *storedObject, rawFile models.File) (io.ReadCloser, int64, *models.ScrubFinding) {
+	exists, size, err := aws.FileExists(rawFile)
+	if err != nil {
+		finding := object.newFinding(models.ScrubFindingReadError, err.Error())
+		return nil, 0, &finding
+	}
+	if !exists {
+		finding := object.newFinding(models.ScrubFindingMissing, "the object does not exist in the bucket")
+		return nil, 0, &finding
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(aws.Stream(pipeWriter, rawFile))
+	}()
+	return pipeReader, size, nil
+}
+
+// errorRecordingReader keeps the first error that occurred while reading from the stored object,
+// so that read errors can be distinguished from decryption errors
+type errorRecordingReader struct {
+	reader io.Reader
+	read   int64
+	err    error
+}
+
+func (r *errorRecordingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read = r.read + int64(n)
+	if err != nil && !errors.Is(err, io.EOF) && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// verifyContent hashes the plaintext of the stored object. Returns the type of the finding and
+// details, or an empty string if the content matches
+func verifyContent(file models.File, reader io.Reader, report *models.ScrubReport) (string, string) {
+	recorder := &errorRecordingReader{reader: reader}
+	hasher := sha1.New()
+	var err error
+	if file.Encryption.IsEncrypted {
+		err = encryption.DecryptReader(file.Encryption, recorder, hasher)
+	} else {
+		_, err = io.Copy(hasher, recorder)
+	}
+	report.CheckedBytes = report.CheckedBytes + recorder.read
+	if recorder.err != nil {
+		return models.ScrubFindingReadError, recorder.err.Error()
+	}
+	if err != nil {
+		if file.Encryption.IsEncrypted {
+			return models.ScrubFindingDecryptionFailed, err.Error()
+		}
+		return models.ScrubFindingReadError, err.Error()
+	}
+	if isMatchingHash(hasher, file.SHA1) {
+		return "", ""
+	}
+	return models.ScrubFindingHashMismatch, "the content does not match the stored SHA1 hash"
+}
+
+// isMatchingHash returns true if the hash matches the expected SHA1. If encryption was requested
+// during the upload, the salt for files has been appended before calculating the hash
+func isMatchingHash(hasher hash.Hash, expected string) bool {
+	if hex.EncodeToString(hasher.Sum(nil)) == expected {
+		return true
+	}
+	hasher.Write([]byte(configuration.Get().Authentication.SaltFiles))
+	return hex.EncodeToString(hasher.Sum(nil)) == expected
+}
+
+// isBlobName returns true if the name has the format that Gokapi uses for stored objects.
+// Other files in the data directory or bucket are never reported as orphans
+func isBlobName(name string) bool {
+	if strings.HasPrefix(name, "e2e-") {
+		return len(name) == 24
+	}
+	if len(name) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+// findOrphans searches the data directory and all used buckets for objects that are not referenced by any file
+func findOrphans(referenced map[string]*storedObject, report *models.ScrubReport) error {
+	minModTime := time.Now().Add(-orphanMinAge).Unix()
+	err := findLocalOrphans(referenced, minModTime, report)
+	if err != nil {
+		return err
+	}
+	if !aws.IsAvailable() {
+		return nil
+	}
+	for _, bucket := range getUsedBuckets(referenced) {
+		err = findCloudOrphans(bucket, referenced, minModTime, report)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findLocalOrphans(referenced map[string]*storedObject, minModTime int64, report *models.ScrubReport) error {
+	entries, err := os.ReadDir(configuration.Get().DataDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isBlobName(entry.Name()) {
+			continue
+		}
+		if referenced[getStorageKey("", entry.Name())] != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Unix() > minModTime {
+			continue
+		}
+		report.Findings = append(report.Findings, models.ScrubFinding{
+			Type:    models.ScrubFindingOrphan,
+			Key:     entry.Name(),
+			Size:    info.Size(),
+			FileIds: make([]string, 0),
+			Details: "the file is not referenced by any upload",
+		})
+	}
+	return nil
+}
+
+func getUsedBuckets(referenced map[string]*storedObject) []string {
+	result := make([]string, 0)
+	isAdded := make(map[string]bool)
+	addBucket := func(bucket string) {
+		if bucket != "" && !isAdded[bucket] {
+			isAdded[bucket] = true
+			result = append(result, bucket)
+		}
+	}
+	addBucket(aws.GetDefaultBucketName())
+	for _, object := range referenced {
+		addBucket(object.Bucket)
+	}
+	return result
+}
+
+func findCloudOrphans(bucket string, referenced map[string]*storedObject, minModTime int64, report *models.ScrubReport) error {
+	objects, err := aws.ListObjects(bucket)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if !isBlobName(object.Key) || referenced[getStorageKey(bucket, object.Key)] != nil {
+			continue
+		}
+		if object.LastModified > minModTime {
+			continue
+		}
+		report.Findings = append(report.Findings, models.ScrubFinding{
+			Type:    models.ScrubFindingOrphan,
+			Bucket:  bucket,
+			Key:     object.Key,
+			Size:    object.Size,
+			FileIds: make([]string, 0),
+			Details: "the object is not referenced by any upload",
+		})
+	}
+	return nil
+}
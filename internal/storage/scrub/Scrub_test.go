@@ -0,0 +1,263 @@
+//go:build test && awsmock
+
+package scrub
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/encryption"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage/filesystem/s3filesystem/aws"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+)
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	configuration.Load()
+	configuration.ConnectDatabase()
+	for _, file := range database.GetAllMetadata() {
+		database.DeleteMetaData(file.Id)
+	}
+	cipher, err := encryption.GetRandomCipher()
+	if err != nil {
+		panic(err)
+	}
+	configuration.Get().Encryption = models.Encryption{Level: encryption.LocalEncryptionStored, Cipher: cipher}
+	encryption.Init(*configuration.Get())
+	testconfiguration.EnableS3()
+	aws.Init(models.AwsConfig{})
+	exitVal := m.Run()
+	testconfiguration.DisableS3()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+const bucket = "gokapi-test"
+
+func getHash(content string, useSalt bool) string {
+	hash := sha1.New()
+	hash.Write([]byte(content))
+	if useSalt {
+		hash.Write([]byte(configuration.Get().Authentication.SaltFiles))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func writeLocalFile(t *testing.T, name string, content []byte, isOld bool) {
+	t.Helper()
+	path := configuration.Get().DataDir + "/" + name
+	err := os.WriteFile(path, content, 0600)
+	test.IsNil(t, err)
+	if isOld {
+		oldTime := time.Now().Add(-48 * time.Hour)
+		test.IsNil(t, os.Chtimes(path, oldTime, oldTime))
+	}
+}
+
+func writeCloudFile(t *testing.T, key string, content []byte) {
+	t.Helper()
+	upload, err := aws.StartMultipartUpload(bucket, key)
+	test.IsNil(t, err)
+	test.IsNil(t, upload.UploadPart(content))
+	test.IsNil(t, upload.Complete())
+}
+
+func encrypt(t *testing.T, content string) (models.EncryptionInfo, []byte) {
+	t.Helper()
+	encInfo := models.EncryptionInfo{IsEncrypted: true}
+	var output bytes.Buffer
+	err := encryption.Encrypt(&encInfo, bytes.NewReader([]byte(content)), &output)
+	test.IsNil(t, err)
+	return encInfo, output.Bytes()
+}
+
+var (
+	hashLocalValid     = getHash("local valid", false)
+	hashLocalEncrypted = getHash("local encrypted", true)
+	hashLocalCorrupted = getHash("local corrupted", false)
+	hashLocalWrongKey  = getHash("local wrong key", true)
+	hashLocalMissing   = getHash("local missing", false)
+	hashLocalOrphan    = getHash("local orphan", false)
+	hashLocalNew       = getHash("local new", false)
+	hashLocalVersion   = getHash("local version", false)
+	hashCloudValid     = getHash("cloud valid", false)
+	hashCloudCorrupted = getHash("cloud corrupted", false)
+	hashCloudMissing   = getHash("cloud missing", false)
+	hashCloudOrphan    = getHash("cloud orphan", false)
+	e2eKey             = "e2e-scrubtest12345678901"
+)
+
+func createTestFiles(t *testing.T) {
+	t.Helper()
+	writeLocalFile(t, hashLocalValid, []byte("local valid"), false)
+	database.SaveMetaData(models.File{Id: "scrubLocalValid1", SHA1: hashLocalValid})
+	database.SaveMetaData(models.File{Id: "scrubLocalValid2", SHA1: hashLocalValid})
+
+	encInfo, encrypted := encrypt(t, "local encrypted")
+	writeLocalFile(t, hashLocalEncrypted, encrypted, false)
+	database.SaveMetaData(models.File{Id: "scrubLocalEncrypted", SHA1: hashLocalEncrypted, Encryption: encInfo})
+
+	writeLocalFile(t, hashLocalCorrupted, []byte("local c0rrupted"), false)
+	database.SaveMetaData(models.File{Id: "scrubLocalCorrupted", SHA1: hashLocalCorrupted})
+
+	encInfo, encrypted = encrypt(t, "local wrong key")
+	otherKey, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	encInfo.DecryptionKey = otherKey
+	writeLocalFile(t, hashLocalWrongKey, encrypted, false)
+	database.SaveMetaData(models.File{Id: "scrubLocalWrongKey", SHA1: hashLocalWrongKey, Encryption: encInfo})
+
+	database.SaveMetaData(models.File{Id: "scrubLocalMissing", SHA1: hashLocalMissing})
+
+	writeLocalFile(t, hashLocalVersion, []byte("local version"), true)
+	database.SaveFileVersion(models.FileVersion{Id: "scrubVersion", FileId: "scrubLocalValid1", SHA1: hashLocalVersion})
+
+	writeLocalFile(t, e2eKey, []byte("unknown encrypted content"), false)
+	database.SaveMetaData(models.File{Id: "scrubE2e", SHA1: e2eKey,
+		Encryption: models.EncryptionInfo{IsEncrypted: true, IsEndToEndEncrypted: true}})
+
+	writeLocalFile(t, hashLocalOrphan, []byte("local orphan"), true)
+	writeLocalFile(t, hashLocalNew, []byte("local new"), false)
+	writeLocalFile(t, "notablob.txt", []byte("not a blob"), true)
+
+	writeCloudFile(t, hashCloudValid, []byte("cloud valid"))
+	database.SaveMetaData(models.File{Id: "scrubCloudValid", SHA1: hashCloudValid, AwsBucket: bucket})
+	writeCloudFile(t, hashCloudCorrupted, []byte("cloud c0rrupted"))
+	database.SaveMetaData(models.File{Id: "scrubCloudCorrupted", SHA1: hashCloudCorrupted, AwsBucket: bucket})
+	database.SaveMetaData(models.File{Id: "scrubCloudMissing", SHA1: hashCloudMissing, AwsBucket: bucket})
+	writeCloudFile(t, hashCloudOrphan, []byte("cloud orphan"))
+}
+
+func getFindings(report models.ScrubReport) map[string]models.ScrubFinding {
+	result := make(map[string]models.ScrubFinding)
+	for _, finding := range report.Findings {
+		result[finding.Key] = finding
+	}
+	return result
+}
+
+func TestIsBlobName(t *testing.T) {
+	test.IsEqualBool(t, isBlobName(hashLocalValid), true)
+	test.IsEqualBool(t, isBlobName(e2eKey), true)
+	test.IsEqualBool(t, isBlobName("e2e-short"), false)
+	test.IsEqualBool(t, isBlobName("gokapi.sqlite"), false)
+	test.IsEqualBool(t, isBlobName("x341354656543213246465465465432456898794"), false)
+}
+
+func TestRun(t *testing.T) {
+	_, ok := GetReport()
+	test.IsEqualBool(t, ok, false)
+	_, err := Run("invalid")
+	test.IsEqualBool(t, err == ErrorInvalidAction, true)
+	runningMutex.Lock()
+	_, err = Run(models.ScrubActionNone)
+	test.IsEqualBool(t, err == ErrorInProgress, true)
+	runningMutex.Unlock()
+
+	createTestFiles(t)
+	report, err := Run(models.ScrubActionNone)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, report.IsRunning, false)
+	test.IsEqualString(t, report.Error, "")
+	test.IsEqualInt(t, report.CheckedObjects, 8)
+	findings := getFindings(report)
+	test.IsEqualInt(t, len(findings), 7)
+	test.IsEqualString(t, findings[hashLocalCorrupted].Type, models.ScrubFindingHashMismatch)
+	test.IsEqualString(t, findings[hashLocalCorrupted].FileIds[0], "scrubLocalCorrupted")
+	test.IsEqualString(t, findings[hashLocalWrongKey].Type, models.ScrubFindingDecryptionFailed)
+	test.IsEqualString(t, findings[hashLocalMissing].Type, models.ScrubFindingMissing)
+	test.IsEqualString(t, findings[hashLocalOrphan].Type, models.ScrubFindingOrphan)
+	test.IsEqualString(t, findings[hashLocalOrphan].Bucket, "")
+	test.IsEqualString(t, findings[hashCloudCorrupted].Type, models.ScrubFindingHashMismatch)
+	test.IsEqualString(t, findings[hashCloudMissing].Type, models.ScrubFindingMissing)
+	test.IsEqualString(t, findings[hashCloudOrphan].Type, models.ScrubFindingOrphan)
+	test.IsEqualString(t, findings[hashCloudOrphan].Bucket, bucket)
+	for _, finding := range report.Findings {
+		test.IsEqualString(t, finding.Resolution, "")
+	}
+	storedReport, ok := GetReport()
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, len(storedReport.Findings), 7)
+}
+
+func TestResolve(t *testing.T) {
+	dataDir := configuration.Get().DataDir
+	_, _, err := Resolve("invalid")
+	test.IsEqualBool(t, err == ErrorInvalidAction, true)
+
+	// A file referencing the orphan has been uploaded since the check was run
+	database.SaveMetaData(models.File{Id: "scrubCloudOrphan", SHA1: hashCloudOrphan, AwsBucket: bucket})
+	report, resolved, err := Resolve(models.ScrubActionQuarantine)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, resolved, 4)
+	findings := getFindings(report)
+	test.IsEqualString(t, findings[hashLocalOrphan].Resolution, models.ScrubResolutionQuarantined)
+	test.IsEqualString(t, findings[hashLocalCorrupted].Resolution, models.ScrubResolutionQuarantined)
+	test.IsEqualString(t, findings[hashLocalWrongKey].Resolution, models.ScrubResolutionQuarantined)
+	test.IsEqualString(t, findings[hashCloudCorrupted].Resolution, models.ScrubResolutionQuarantined)
+	test.IsEqualString(t, findings[hashCloudOrphan].Resolution, "")
+	test.IsEqualString(t, findings[hashLocalMissing].Resolution, "")
+	test.FileDoesNotExist(t, dataDir+"/"+hashLocalOrphan)
+	test.FileExists(t, dataDir+"/"+QuarantineDir+"/"+hashLocalOrphan)
+	test.FileExists(t, dataDir+"/"+QuarantineDir+"/"+hashLocalCorrupted)
+	exists, _, err := aws.FileExists(models.File{AwsBucket: bucket, SHA1: QuarantineDir + "/" + hashCloudCorrupted})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, true)
+	exists, _, err = aws.FileExists(models.File{AwsBucket: bucket, SHA1: hashCloudCorrupted})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+
+	// Resolved findings are not processed again
+	_, resolved, err = Resolve(models.ScrubActionDelete)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, resolved, 0)
+}
+
+func TestRunWithDelete(t *testing.T) {
+	dataDir := configuration.Get().DataDir
+	database.DeleteMetaData("scrubCloudOrphan")
+	writeLocalFile(t, hashLocalOrphan, []byte("local orphan"), true)
+	report, err := Run(models.ScrubActionDelete)
+	test.IsNil(t, err)
+	findings := getFindings(report)
+	test.IsEqualString(t, report.Action, models.ScrubActionDelete)
+	test.IsEqualString(t, findings[hashLocalOrphan].Resolution, models.ScrubResolutionDeleted)
+	test.IsEqualString(t, findings[hashCloudOrphan].Resolution, models.ScrubResolutionDeleted)
+	test.FileDoesNotExist(t, dataDir+"/"+hashLocalOrphan)
+	exists, _, err := aws.FileExists(models.File{AwsBucket: bucket, SHA1: hashCloudOrphan})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, exists, false)
+	// Files that are referenced are never deleted
+	test.FileExists(t, dataDir+"/"+hashLocalValid)
+	test.FileExists(t, dataDir+"/"+hashLocalVersion)
+}
+
+func TestStart(t *testing.T) {
+	err := Start("invalid")
+	test.IsEqualBool(t, err == ErrorInvalidAction, true)
+	err = Start(models.ScrubActionNone)
+	test.IsNil(t, err)
+	runningMutex.Lock()
+	report, ok := GetReport()
+	runningMutex.Unlock()
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, report.IsRunning, false)
+	test.IsEqualString(t, report.Action, models.ScrubActionNone)
+}
+
+func TestRunIfDue(t *testing.T) {
+	database.ReleaseLease(LeaseScrub, LeaseScrub)
+	test.IsEqualBool(t, runIfDue(time.Hour, models.ScrubActionNone), true)
+	test.IsEqualBool(t, runIfDue(time.Hour, models.ScrubActionNone), false)
+	lease, ok := database.GetLease(LeaseScrub)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, lease.ExpiresAt > time.Now().Add(50*time.Minute).Unix(), true)
+}
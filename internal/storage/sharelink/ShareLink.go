@@ -0,0 +1,96 @@
+package sharelink
+
+import (
+	"errors"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+)
+
+// ErrorFileNotFound is returned, if the file for a new share link does not exist
+var ErrorFileNotFound = errors.New("file not found")
+
+// ErrorNoPermission is returned, if the file for a new share link is owned by another user
+var ErrorNoPermission = errors.New("no permission to share file")
+
+// ErrorUnsupportedFile is returned, if the file is end-to-end encrypted or was uploaded for a file request
+var ErrorUnsupportedFile = errors.New("share links cannot be created for end-to-end encrypted files or files of file requests")
+
+// New creates a new share link object for the file. It is not stored yet.
+// If allowedDownloads or expiryDays is 0, the link has no download or time limit
+func New(file models.File, user models.User, name string, allowedDownloads, expiryDays int, password string) models.ShareLink {
+	result := models.ShareLink{
+		Id:                 helper.GenerateRandomString(configuration.GetEnvironment().LengthId),
+		FileId:             file.Id,
+		Name:               name,
+		UserId:             user.Id,
+		CreationDate:       time.Now().Unix(),
+		DownloadsRemaining: allowedDownloads,
+		UnlimitedDownloads: allowedDownloads == 0,
+		UnlimitedTime:      expiryDays == 0,
+	}
+	if !result.UnlimitedTime {
+		result.ExpireAt = time.Now().Add(time.Duration(expiryDays) * 24 * time.Hour).Unix()
+	}
+	if password != "" {
+		result.PasswordHash = configuration.HashPassword(password, false, "")
+	}
+	return result
+}
+
+// Get returns a share link and its file by the ID of the link.
+// Returns false, if the link does not exist, has expired or the file cannot be downloaded anymore
+func Get(id string) (models.ShareLink, models.File, bool) {
+	if id == "" {
+		return models.ShareLink{}, models.File{}, false
+	}
+	link, ok := database.GetShareLink(id)
+	if !ok || link.IsExpired() {
+		return models.ShareLink{}, models.File{}, false
+	}
+	file, ok := storage.GetFile(link.FileId)
+	if !ok || !isSupportedFile(file) {
+		return models.ShareLink{}, models.File{}, false
+	}
+	return link, file, true
+}
+
+// GetAllForFile returns all share links of a file that have not expired
+func GetAllForFile(fileId string) []models.ShareLink {
+	result := make([]models.ShareLink, 0)
+	for _, link := range database.GetAllShareLinks() {
+		if link.FileId == fileId && !link.IsExpired() {
+			result = append(result, link)
+		}
+	}
+	return result
+}
+
+// GetFileForNewLink returns the file with the given ID, if the user is allowed to create a share link for it
+func GetFileForNewLink(fileId string, user models.User) (models.File, error) {
+	file, ok := storage.GetFile(fileId)
+	if !ok {
+		return models.File{}, ErrorFileNotFound
+	}
+	if file.UserId != user.Id && !user.HasPermission(models.UserPermEditOtherUploads) {
+		return models.File{}, ErrorNoPermission
+	}
+	if !isSupportedFile(file) {
+		return models.File{}, ErrorUnsupportedFile
+	}
+	return file, nil
+}
+
+// IsAllowedToEdit returns true, if the user created the share link or is allowed to edit other uploads
+func IsAllowedToEdit(link models.ShareLink, user models.User) bool {
+	return link.UserId == user.Id || user.HasPermission(models.UserPermEditOtherUploads)
+}
+
+// End-to-end encrypted files cannot be shared, as the key is only part of the original link
+func isSupportedFile(file models.File) bool {
+	return !file.Encryption.IsEndToEndEncrypted && !file.IsFileRequest()
+}
@@ -0,0 +1,239 @@
+//go:build test
+
+package ldapstub
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// Entry is an object of the directory. If Password is set, a simple bind with the DN is possible
+type Entry struct {
+	Dn         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server is a minimal in-process LDAP server that supports simple binds and searches with
+// equality, presence, and, or and not filters. It is only meant for testing
+type Server struct {
+	listener    net.Listener
+	entries     []Entry
+	waitGroup   sync.WaitGroup
+	mutex       sync.Mutex
+	connections []net.Conn
+}
+
+// Start starts a new server on a random local port
+func Start(entries []Entry) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	server := &Server{listener: listener, entries: entries}
+	server.waitGroup.Add(1)
+	go server.acceptConnections()
+	return server, nil
+}
+
+// Url returns the URL of the server, e.g. ldap://127.0.0.1:12345
+func (s *Server) Url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// Close stops the server and closes all open connections
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.mutex.Lock()
+	for _, conn := range s.connections {
+		_ = conn.Close()
+	}
+	s.mutex.Unlock()
+	s.waitGroup.Wait()
+}
+
+func (s *Server) acceptConnections() {
+	defer s.waitGroup.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.connections = append(s.connections, conn)
+		s.mutex.Unlock()
+		s.waitGroup.Add(1)
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *Server) handleConnection(conn net.Conn) {
+	defer s.waitGroup.Done()
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageId, ok := packet.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+		operation := packet.Children[1]
+		switch operation.Tag {
+		case goldap.ApplicationBindRequest:
+			s.handleBind(conn, messageId, operation)
+		case goldap.ApplicationSearchRequest:
+			s.handleSearch(conn, messageId, operation)
+		case goldap.ApplicationUnbindRequest:
+			return
+		default:
+			writeResult(conn, messageId, goldap.ApplicationExtendedResponse, goldap.LDAPResultProtocolError)
+		}
+	}
+}
+
+func (s *Server) handleBind(conn net.Conn, messageId int64, operation *ber.Packet) {
+	if len(operation.Children) < 3 {
+		writeResult(conn, messageId, goldap.ApplicationBindResponse, goldap.LDAPResultProtocolError)
+		return
+	}
+	dn, _ := operation.Children[1].Value.(string)
+	password := operation.Children[2].Data.String()
+	if dn == "" && password == "" {
+		writeResult(conn, messageId, goldap.ApplicationBindResponse, goldap.LDAPResultSuccess)
+		return
+	}
+	for _, entry := range s.entries {
+		if entry.Password != "" && strings.EqualFold(entry.Dn, dn) && entry.Password == password {
+			writeResult(conn, messageId, goldap.ApplicationBindResponse, goldap.LDAPResultSuccess)
+			return
+		}
+	}
+	writeResult(conn, messageId, goldap.ApplicationBindResponse, goldap.LDAPResultInvalidCredentials)
+}
+
+func (s *Server) handleSearch(conn net.Conn, messageId int64, operation *ber.Packet) {
+	if len(operation.Children) < 8 {
+		writeResult(conn, messageId, goldap.ApplicationSearchResultDone, goldap.LDAPResultProtocolError)
+		return
+	}
+	baseDn, _ := operation.Children[0].Value.(string)
+	scope, _ := operation.Children[1].Value.(int64)
+	filter := operation.Children[6]
+	var attributes []string
+	for _, attribute := range operation.Children[7].Children {
+		name, _ := attribute.Value.(string)
+		attributes = append(attributes, name)
+	}
+	for _, entry := range s.entries {
+		if !isInScope(entry.Dn, baseDn, scope) || !matchesFilter(entry, filter) {
+			continue
+		}
+		_, _ = conn.Write(createSearchEntry(messageId, entry, attributes).Bytes())
+	}
+	writeResult(conn, messageId, goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess)
+}
+
+func isInScope(dn, baseDn string, scope int64) bool {
+	dn = strings.ToLower(dn)
+	baseDn = strings.ToLower(baseDn)
+	if scope == goldap.ScopeBaseObject {
+		return dn == baseDn
+	}
+	return dn == baseDn || strings.HasSuffix(dn, ","+baseDn)
+}
+
+func matchesFilter(entry Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchesFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchesFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return len(filter.Children) == 1 && !matchesFilter(entry, filter.Children[0])
+	case goldap.FilterPresent:
+		name := filter.Data.String()
+		if strings.EqualFold(name, "objectClass") {
+			return true
+		}
+		return len(getAttribute(entry, name)) > 0
+	case goldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name := filter.Children[0].Data.String()
+		value := filter.Children[1].Data.String()
+		for _, attributeValue := range getAttribute(entry, name) {
+			if strings.EqualFold(attributeValue, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func getAttribute(entry Entry, name string) []string {
+	for key, values := range entry.Attributes {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func createSearchEntry(messageId int64, entry Entry, attributes []string) *ber.Packet {
+	envelope := newEnvelope(messageId)
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.Dn, "DN"))
+	attributeList := ber.NewSequence("Attributes")
+	for _, name := range attributes {
+		values := getAttribute(entry, name)
+		if len(values) == 0 {
+			continue
+		}
+		attribute := ber.NewSequence("Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		valueSet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			valueSet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(valueSet)
+		attributeList.AppendChild(attribute)
+	}
+	result.AppendChild(attributeList)
+	envelope.AppendChild(result)
+	return envelope
+}
+
+func writeResult(conn net.Conn, messageId int64, tag ber.Tag, resultCode uint16) {
+	envelope := newEnvelope(messageId)
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	envelope.AppendChild(result)
+	_, _ = conn.Write(envelope.Bytes())
+}
+
+func newEnvelope(messageId int64) *ber.Packet {
+	envelope := ber.NewSequence("LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageId, "Message ID"))
+	return envelope
+}
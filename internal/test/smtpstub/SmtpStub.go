@@ -0,0 +1,313 @@
+//go:build test
+
+package smtpstub
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config sets the features of the server
+type Config struct {
+	// StartTls announces the STARTTLS extension
+	StartTls bool
+	// ImplicitTls expects a TLS handshake directly after connecting
+	ImplicitTls bool
+	// Username and Password are required for AUTH PLAIN, if set. Otherwise, no authentication is required
+	Username string
+	Password string
+}
+
+// Message is an email that was received by the server
+type Message struct {
+	From string
+	To   []string
+	Data string
+}
+
+// Server is a minimal in-process SMTP server that supports EHLO, STARTTLS, AUTH PLAIN, MAIL, RCPT
+// and DATA. It is only meant for testing
+type Server struct {
+	listener    net.Listener
+	config      Config
+	tlsConfig   *tls.Config
+	certPool    *x509.CertPool
+	waitGroup   sync.WaitGroup
+	mutex       sync.Mutex
+	connections []net.Conn
+	messages    []Message
+}
+
+// Start starts a new server on a random local port
+func Start(config Config) (*Server, error) {
+	tlsConfig, certPool, err := newSelfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	server := &Server{listener: listener, config: config, tlsConfig: tlsConfig, certPool: certPool}
+	server.waitGroup.Add(1)
+	go server.acceptConnections()
+	return server, nil
+}
+
+// Host returns the IP address the server listens on
+func (s *Server) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port the server listens on
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// CertPool returns a pool containing the self-signed certificate of the server
+func (s *Server) CertPool() *x509.CertPool {
+	return s.certPool
+}
+
+// Messages returns all emails that were received so far
+func (s *Server) Messages() []Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := make([]Message, len(s.messages))
+	copy(result, s.messages)
+	return result
+}
+
+// WaitForMessages waits until at least count emails were received or the timeout is reached.
+// Returns all received emails
+func (s *Server) WaitForMessages(count int, timeout time.Duration) []Message {
+	deadline := time.Now().Add(timeout)
+	for {
+		messages := s.Messages()
+		if len(messages) >= count || time.Now().After(deadline) {
+			return messages
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Close stops the server and closes all open connections
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.mutex.Lock()
+	for _, conn := range s.connections {
+		_ = conn.Close()
+	}
+	s.mutex.Unlock()
+	s.waitGroup.Wait()
+}
+
+func (s *Server) acceptConnections() {
+	defer s.waitGroup.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		if s.config.ImplicitTls {
+			conn = tls.Server(conn, s.tlsConfig)
+		}
+		s.mutex.Lock()
+		s.connections = append(s.connections, conn)
+		s.mutex.Unlock()
+		s.waitGroup.Add(1)
+		go s.handleConnection(conn)
+	}
+}
+
+// session contains the state of a single connection
+type session struct {
+	conn            net.Conn
+	reader          *bufio.Reader
+	isTls           bool
+	isAuthenticated bool
+	from            string
+	to              []string
+}
+
+func (s *Server) handleConnection(conn net.Conn) {
+	defer s.waitGroup.Done()
+	defer func() { _ = conn.Close() }()
+	state := &session{conn: conn, reader: bufio.NewReader(conn), isTls: s.config.ImplicitTls}
+	state.reply("220 localhost ESMTP stub")
+	for {
+		line, err := state.reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command, argument, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			s.handleHello(state)
+		case "STARTTLS":
+			if !s.config.StartTls || state.isTls {
+				state.reply("502 Command not implemented")
+				continue
+			}
+			state.reply("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			s.mutex.Lock()
+			s.connections = append(s.connections, tlsConn)
+			s.mutex.Unlock()
+			state.conn = tlsConn
+			state.reader = bufio.NewReader(tlsConn)
+			state.isTls = true
+		case "AUTH":
+			s.handleAuth(state, argument)
+		case "MAIL":
+			if s.config.Username != "" && !state.isAuthenticated {
+				state.reply("530 Authentication required")
+				continue
+			}
+			state.from = extractAddress(argument)
+			state.to = nil
+			state.reply("250 OK")
+		case "RCPT":
+			if state.from == "" {
+				state.reply("503 Bad sequence of commands")
+				continue
+			}
+			state.to = append(state.to, extractAddress(argument))
+			state.reply("250 OK")
+		case "DATA":
+			if len(state.to) == 0 {
+				state.reply("503 Bad sequence of commands")
+				continue
+			}
+			state.reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := readData(state.reader)
+			if err != nil {
+				return
+			}
+			s.mutex.Lock()
+			s.messages = append(s.messages, Message{From: state.from, To: state.to, Data: data})
+			s.mutex.Unlock()
+			state.from = ""
+			state.to = nil
+			state.reply("250 OK")
+		case "RSET":
+			state.from = ""
+			state.to = nil
+			state.reply("250 OK")
+		case "NOOP":
+			state.reply("250 OK")
+		case "QUIT":
+			state.reply("221 Bye")
+			return
+		default:
+			state.reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *Server) handleHello(state *session) {
+	lines := []string{"250-localhost"}
+	if s.config.StartTls && !state.isTls {
+		lines = append(lines, "250-STARTTLS")
+	}
+	if s.config.Username != "" {
+		lines = append(lines, "250-AUTH PLAIN")
+	}
+	lines = append(lines, "250 8BITMIME")
+	state.reply(strings.Join(lines, "\r\n"))
+}
+
+func (s *Server) handleAuth(state *session, argument string) {
+	mechanism, credentials, _ := strings.Cut(argument, " ")
+	if s.config.Username == "" || !strings.EqualFold(mechanism, "PLAIN") {
+		state.reply("504 Unrecognized authentication type")
+		return
+	}
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		state.reply("501 Invalid credentials")
+		return
+	}
+	parts := strings.Split(string(decoded), "\x00")
+	if len(parts) != 3 || parts[1] != s.config.Username || parts[2] != s.config.Password {
+		state.reply("535 Authentication failed")
+		return
+	}
+	state.isAuthenticated = true
+	state.reply("235 Authentication successful")
+}
+
+func (state *session) reply(line string) {
+	_, _ = state.conn.Write([]byte(line + "\r\n"))
+}
+
+// extractAddress returns the address of a MAIL FROM:<address> or RCPT TO:<address> argument
+func extractAddress(argument string) string {
+	start := strings.Index(argument, "<")
+	end := strings.Index(argument, ">")
+	if start == -1 || end < start {
+		return ""
+	}
+	return argument[start+1 : end]
+}
+
+// readData reads the message until a line that only contains a dot and removes the dot-stuffing
+func readData(reader *bufio.Reader) (string, error) {
+	var result strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == ".\r\n" {
+			return result.String(), nil
+		}
+		result.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+func newSelfSignedCertificate() (*tls.Config, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return tlsConfig, pool, nil
+}
@@ -30,18 +30,25 @@ import (
 	"github.com/forceu/gokapi/internal/environment"
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/logging/mail"
+	"github.com/forceu/gokapi/internal/logging/metrics"
 	"github.com/forceu/gokapi/internal/logging/serverstats"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/bundle"
 	"github.com/forceu/gokapi/internal/storage/filerequest"
 	"github.com/forceu/gokapi/internal/storage/presign"
+	"github.com/forceu/gokapi/internal/storage/preview"
+	"github.com/forceu/gokapi/internal/storage/sharelink"
 	"github.com/forceu/gokapi/internal/webserver/api"
 	"github.com/forceu/gokapi/internal/webserver/authentication"
 	"github.com/forceu/gokapi/internal/webserver/authentication/csrftoken"
 	"github.com/forceu/gokapi/internal/webserver/authentication/downloadPasswordToken"
 	"github.com/forceu/gokapi/internal/webserver/authentication/oauth"
+	"github.com/forceu/gokapi/internal/webserver/authentication/passwordreset"
 	"github.com/forceu/gokapi/internal/webserver/authentication/sessionmanager"
 	"github.com/forceu/gokapi/internal/webserver/authentication/tokengeneration"
+	"github.com/forceu/gokapi/internal/webserver/authentication/twofactor"
 	"github.com/forceu/gokapi/internal/webserver/errorHandling"
 	"github.com/forceu/gokapi/internal/webserver/favicon"
 	"github.com/forceu/gokapi/internal/webserver/fileupload"
@@ -106,9 +113,13 @@ func Start() {
 	mux.HandleFunc("/admin", requireLogin(showAdminMenu, true, false))
 	mux.HandleFunc("/api/", processApi)
 	mux.HandleFunc("/apiKeys", requireLogin(showApiAdmin, true, false))
+	mux.HandleFunc("/b", showBundle)
+	mux.HandleFunc("/bundleDownload", downloadBundle)
 	mux.HandleFunc("/changePassword", requireLogin(changePassword, true, true))
+	mux.HandleFunc("/twoFactor", requireLogin(showTwoFactor, true, true))
 	mux.HandleFunc("/d", showDownload)
 	mux.HandleFunc("/downloadFile", downloadFile)
+	mux.HandleFunc("/downloadShare", downloadShareLink)
 	mux.HandleFunc("/downloadPresigned", requireLogin(downloadPresigned, false, false))
 	mux.HandleFunc("/e2eSetup", requireLogin(showE2ESetup, true, false))
 	mux.HandleFunc("/error", showError)
@@ -120,7 +131,10 @@ func Start() {
 	mux.HandleFunc("/login", showLogin)
 	mux.HandleFunc("/logs", requireLogin(showLogs, true, false))
 	mux.HandleFunc("/logout", doLogout)
+	mux.HandleFunc("/metrics", showMetrics)
 	mux.HandleFunc("/publicUpload", showPublicUpload)
+	mux.HandleFunc("/s", showShareLink)
+	mux.HandleFunc("/thumbnail", showThumbnail)
 	mux.HandleFunc("/uploadChunk", requireLogin(uploadChunk, false, false))
 	mux.HandleFunc("/uploadStatus", requireLogin(sse.GetStatusSSE, false, false))
 	mux.HandleFunc("/users", requireLogin(showUserAdmin, true, false))
@@ -252,6 +266,12 @@ func redirectFromFilename(w http.ResponseWriter, r *http.Request) {
 		redirect(w, r, "../../error")
 		return
 	}
+	// The redirect page shows the name of the file, which must not be revealed before the publication
+	// or to users that are not logged in
+	if file.IsEmbargoed() || file.RequireLogin {
+		redirect(w, r, "../../d?id="+file.Id)
+		return
+	}
 
 	config := configuration.Get()
 	err := templateFolder.ExecuteTemplate(w, "redirect_filename", redirectValues{
@@ -360,6 +380,75 @@ func changePassword(w http.ResponseWriter, r *http.Request) {
 	helper.CheckIgnoreTimeout(err)
 }
 
+// Handling of /twoFactor
+// Allows the user to set up or disable two-factor authentication. Only available with internal authentication
+func showTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.GetUserFromRequest(r)
+	if err != nil {
+		panic(err)
+	}
+	config := configuration.Get()
+	if config.Authentication.Method != models.AuthenticationInternal {
+		redirect(w, r, "admin")
+		return
+	}
+	view := twoFactorView{
+		PublicName:    config.PublicName,
+		CustomContent: customStaticInfo,
+		IsRequired:    config.Authentication.RequireTwoFactorForAdmins && user.IsAdmin(),
+	}
+	_ = r.ParseForm()
+	if r.Method == http.MethodPost {
+		if !csrftoken.IsValid(csrftoken.TypeLogin, r.PostForm.Get("csrf-token")) {
+			view.ErrorMessage = "Form was not submitted completely"
+		} else {
+			code := r.PostForm.Get("code")
+			switch r.PostForm.Get("action") {
+			case "enroll":
+				_, err = twofactor.StartEnrollment(user)
+				if err != nil {
+					view.ErrorMessage = firstLetterUpper(err.Error())
+				}
+			case "confirm":
+				view.RecoveryCodes, err = twofactor.ConfirmEnrollment(user.Id, code)
+				if err != nil {
+					view.ErrorMessage = firstLetterUpper(err.Error())
+				}
+			case "disable":
+				if view.IsRequired {
+					view.ErrorMessage = "Two-factor authentication is required for admins"
+				} else if !twofactor.Verify(user.Id, code) {
+					view.ErrorMessage = firstLetterUpper(twofactor.ErrorInvalidCode.Error())
+				} else {
+					twofactor.Disable(user.Id)
+				}
+			}
+		}
+	}
+	view.IsEnabled = twofactor.IsEnabled(user.Id)
+	view.ProvisioningUri, view.IsPendingEnrollment = twofactor.GetPendingEnrollment(user)
+	view.RemainingRecoveryCodes = twofactor.GetRemainingRecoveryCodes(user.Id)
+	view.CsrfToken = csrftoken.Generate(csrftoken.TypeLogin)
+	err = templateFolder.ExecuteTemplate(w, "twofactor", view)
+	helper.CheckIgnoreTimeout(err)
+}
+
+// A view containing parameters for the two-factor authentication setup
+type twoFactorView struct {
+	IsAdminView            bool
+	IsDownloadView         bool
+	IsEnabled              bool
+	IsPendingEnrollment    bool
+	IsRequired             bool
+	PublicName             string
+	ProvisioningUri        string
+	ErrorMessage           string
+	CsrfToken              string
+	RecoveryCodes          []string
+	RemainingRecoveryCodes int
+	CustomContent          customStatic
+}
+
 // validateNewPassword validates the new password and returns the new password hash if the password is valid.
 // If the password is not valid, it returns an error message and an empty string.
 // If the password is valid, it returns the hash as a string and true.
@@ -412,10 +501,95 @@ func showError(w http.ResponseWriter, r *http.Request) {
 }
 
 // Handling of /forgotpw
+// If emails are enabled and internal authentication is used, a link for setting a new password can be requested
 func forgotPassword(w http.ResponseWriter, r *http.Request) {
-	err := templateFolder.ExecuteTemplate(w, "forgotpw", genericView{
+	view := genericView{
 		PublicName:    configuration.Get().PublicName,
-		CustomContent: customStaticInfo})
+		CustomContent: customStaticInfo,
+		IsMailEnabled: mail.IsEnabled() && configuration.Get().Authentication.Method == models.AuthenticationInternal,
+	}
+	if !view.IsMailEnabled {
+		err := templateFolder.ExecuteTemplate(w, "forgotpw", view)
+		helper.CheckIgnoreTimeout(err)
+		return
+	}
+	_ = r.ParseForm()
+	resetToken := r.Form.Get("token")
+	if resetToken != "" {
+		resetPassword(w, r, resetToken)
+		return
+	}
+	if r.Method == http.MethodPost {
+		if !csrftoken.IsValid(csrftoken.TypeLogin, r.PostForm.Get("csrf-token")) {
+			view.ErrorMessage = "Form was not submitted completely"
+		} else {
+			ratelimiter.WaitOnLogin(logging.GetIpAddress(r))
+			user, ok := database.GetUserByName(strings.TrimSpace(r.PostForm.Get("username")))
+			if ok {
+				sendPasswordReset(user)
+			}
+			// The same message is shown for unknown users, so that it cannot be used to find out if a user exists
+			view.InfoMessage = "If an account with a valid email address exists for this username, an email with a link for setting a new password has been sent."
+		}
+	}
+	view.CsrfToken = csrftoken.Generate(csrftoken.TypeLogin)
+	err := templateFolder.ExecuteTemplate(w, "forgotpw", view)
+	helper.CheckIgnoreTimeout(err)
+}
+
+// sendPasswordReset sends an email with a link for setting a new password to the user
+func sendPasswordReset(user models.User) {
+	token, err := passwordreset.Create(user.Id)
+	if err != nil {
+		fmt.Println("Could not create password reset link: " + err.Error())
+		return
+	}
+	mail.SendPasswordReset(user, configuration.Get().ServerUrl+"forgotpw?token="+token)
+}
+
+// resetPassword sets a new password for the user of a password reset link
+func resetPassword(w http.ResponseWriter, r *http.Request, resetToken string) {
+	var errMessage string
+	userId, ok := passwordreset.GetUserId(resetToken)
+	var user models.User
+	if ok {
+		user, ok = database.GetUser(userId)
+	}
+	if !ok {
+		ratelimiter.WaitOnLogin(logging.GetIpAddress(r))
+		errorHandling.RedirectToErrorPage(w, r, "Invalid link",
+			"This link for setting a new password is invalid or has expired. Please request a new one.", errorHandling.WidthDefault)
+		return
+	}
+	if r.Method == http.MethodPost {
+		pwHash, isValid, err := validateNewPassword(r.PostForm.Get("newpw"), user, r.PostForm.Get("csrf-token"))
+		if err != nil {
+			errMessage = firstLetterUpper(err.Error())
+		}
+		if isValid {
+			// The token is consumed before the password is saved, so that parallel requests cannot use it twice
+			consumedUserId, isConsumed := passwordreset.Consume(resetToken)
+			if !isConsumed || consumedUserId != user.Id {
+				errorHandling.RedirectToErrorPage(w, r, "Invalid link",
+					"This link for setting a new password is invalid or has expired. Please request a new one.", errorHandling.WidthDefault)
+				return
+			}
+			user.Password = pwHash
+			user.ResetPassword = false
+			database.SaveUser(user, false)
+			database.DeleteAllSessionsByUser(user.Id)
+			logging.LogPasswordReset(user, r)
+			redirect(w, r, "login")
+			return
+		}
+	}
+	err := templateFolder.ExecuteTemplate(w, "changepw",
+		genericView{PublicName: configuration.Get().PublicName,
+			MinPasswordLength: configuration.GetEnvironment().MinLengthPassword,
+			ErrorMessage:      errMessage,
+			ResetToken:        resetToken,
+			CustomContent:     customStaticInfo,
+			CsrfToken:         csrftoken.Generate(csrftoken.TypeLogin)})
 	helper.CheckIgnoreTimeout(err)
 }
 
@@ -474,6 +648,40 @@ func processApi(w http.ResponseWriter, r *http.Request) {
 	api.Process(w, r)
 }
 
+// Handling of /metrics
+// Outputs the server statistics in the Prometheus text format. Access requires either the token
+// set with GOKAPI_METRICS_TOKEN or a request by a trusted proxy, if GOKAPI_METRICS_TRUSTED_PROXIES is set
+func showMetrics(w http.ResponseWriter, r *http.Request) {
+	env := configuration.GetEnvironment()
+	if env.MetricsToken == "" && !env.MetricsAllowTrustedProxies {
+		http.NotFound(w, r)
+		return
+	}
+	if !isMetricsAccessAllowed(r, env.MetricsToken, env.MetricsAllowTrustedProxies) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", metrics.ContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	serverstats.WriteMetrics(w)
+}
+
+func isMetricsAccessAllowed(r *http.Request, token string, allowTrustedProxies bool) bool {
+	if allowTrustedProxies && logging.IsTrustedProxyRequest(r) {
+		return true
+	}
+	if token == "" {
+		return false
+	}
+	requestToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok && helper.IsEqualStringConstantTime(requestToken, token) {
+		return true
+	}
+	ratelimiter.WaitOnApiAuthentication(logging.GetIpAddress(r))
+	return false
+}
+
 // Handling of /login
 // Shows a login form. If not authenticated, client needs to wait for three seconds.
 // If correct, a new session is created and the user is redirected to the admin menu
@@ -484,7 +692,8 @@ func showLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if ok {
-		redirect(w, r, "admin")
+		target, _ := authentication.GetLoginRedirect(w, r)
+		redirect(w, r, target)
 		return
 	}
 	if configuration.Get().Authentication.Method == models.AuthenticationHeader {
@@ -510,46 +719,92 @@ func showLogin(w http.ResponseWriter, r *http.Request) {
 	user := r.PostForm.Get("username")
 	pw := r.PostForm.Get("password")
 	csfr := r.PostForm.Get("csrf-token")
-	failedLogin := false
-	failedCsrf := false
+	totpToken := r.PostForm.Get("totp-token")
+	view := LoginView{
+		User:          user,
+		IsAdminView:   false,
+		IsLdapAuth:    configuration.Get().Authentication.Method == models.AuthenticationLdap,
+		PublicName:    configuration.Get().PublicName,
+		CustomContent: customStaticInfo,
+	}
+	if totpToken != "" {
+		showLoginTwoFactor(w, r, view, totpToken)
+		return
+	}
 	if pw != "" && user != "" {
 		ip := logging.GetIpAddress(r)
 		ratelimiter.WaitOnLogin(ip)
 		retrievedUser, validCredentials, validCsfr := authentication.IsCorrectUsernameAndPassword(user, pw, csfr)
 		if validCredentials {
-			logging.LogValidLogin(user)
+			if twofactor.IsEnabled(retrievedUser.Id) {
+				view.IsTwoFactorView = true
+				view.TwoFactorToken = twofactor.CreatePendingLogin(retrievedUser.Id)
+				err = templateFolder.ExecuteTemplate(w, "login", view)
+				helper.CheckIgnoreTimeout(err)
+				return
+			}
+			logging.LogValidLogin(retrievedUser, r)
 			sessionmanager.CreateSession(w, false, 0, retrievedUser.Id)
-			redirect(w, r, "admin")
+			target, _ := authentication.GetLoginRedirect(w, r)
+			redirect(w, r, target)
 			return
 		}
 		if validCsfr {
 			logging.LogInvalidLogin(user, ip)
 		}
-		failedCsrf = !validCsfr
-		failedLogin = true
+		view.IsFailedCsfr = !validCsfr
+		view.IsFailedLogin = true
 	}
-	err = templateFolder.ExecuteTemplate(w, "login", LoginView{
-		IsFailedLogin: failedLogin,
-		IsFailedCsfr:  failedCsrf,
-		User:          user,
-		IsAdminView:   false,
-		PublicName:    configuration.Get().PublicName,
-		CustomContent: customStaticInfo,
-		CsrfToken:     csrftoken.Generate(csrftoken.TypeLogin),
-	})
+	view.CsrfToken = csrftoken.Generate(csrftoken.TypeLogin)
+	err = templateFolder.ExecuteTemplate(w, "login", view)
+	helper.CheckIgnoreTimeout(err)
+}
+
+// showLoginTwoFactor handles the second step of the login, after the user entered the correct password.
+// If the submitted code is correct, a new session is created and the user is redirected to the admin menu
+// or to the download page that requested the login
+func showLoginTwoFactor(w http.ResponseWriter, r *http.Request, view LoginView, totpToken string) {
+	ip := logging.GetIpAddress(r)
+	ratelimiter.WaitOnLogin(ip)
+	userId, ok := twofactor.CompletePendingLogin(totpToken, r.PostForm.Get("totp-code"))
+	if ok {
+		retrievedUser, userExists := database.GetUser(userId)
+		if userExists {
+			logging.LogValidLogin(retrievedUser, r)
+			sessionmanager.CreateSession(w, false, 0, retrievedUser.Id)
+			target, _ := authentication.GetLoginRedirect(w, r)
+			redirect(w, r, target)
+			return
+		}
+	}
+	logging.LogInvalidTwoFactor(view.User, ip)
+	view.IsFailedLogin = true
+	// If there were too many failed attempts or the login took too long, the password has to be entered again
+	if twofactor.IsValidPendingLogin(totpToken) {
+		view.IsTwoFactorView = true
+		view.TwoFactorToken = totpToken
+	} else {
+		view.IsFailedTwoFactor = true
+		view.CsrfToken = csrftoken.Generate(csrftoken.TypeLogin)
+	}
+	err := templateFolder.ExecuteTemplate(w, "login", view)
 	helper.CheckIgnoreTimeout(err)
 }
 
 // LoginView contains variables for the login template
 type LoginView struct {
-	IsFailedLogin  bool
-	IsFailedCsfr   bool
-	IsAdminView    bool
-	IsDownloadView bool
-	User           string
-	PublicName     string
-	CsrfToken      string
-	CustomContent  customStatic
+	IsFailedLogin     bool
+	IsFailedCsfr      bool
+	IsFailedTwoFactor bool
+	IsTwoFactorView   bool
+	IsLdapAuth        bool
+	IsAdminView       bool
+	IsDownloadView    bool
+	User              string
+	PublicName        string
+	CsrfToken         string
+	TwoFactorToken    string
+	CustomContent     customStatic
 }
 
 // Handling of /d
@@ -563,6 +818,13 @@ func showDownload(w http.ResponseWriter, r *http.Request) {
 		redirectOnIncorrectId(w, r, "error")
 		return
 	}
+	if file.IsEmbargoed() {
+		showEmbargo(w, file)
+		return
+	}
+	if !checkAccessRestriction(w, r, file.GetAccessRestriction(), "d?id="+file.Id) {
+		return
+	}
 
 	config := configuration.Get()
 
@@ -570,6 +832,8 @@ func showDownload(w http.ResponseWriter, r *http.Request) {
 		Name:               file.Name,
 		Size:               file.Size,
 		Id:                 file.Id,
+		PageUrl:            "d?id=" + file.Id,
+		DownloadUrl:        "downloadFile?id=" + file.Id,
 		IsDownloadView:     true,
 		EndToEndEncryption: file.Encryption.IsEndToEndEncrypted,
 		PublicName:         config.PublicName,
@@ -621,10 +885,243 @@ func showDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	view.addPreview(file, "thumbnail?id="+file.Id)
 	err := templateFolder.ExecuteTemplate(w, "download", view)
 	helper.CheckIgnoreTimeout(err)
 }
 
+// Handling of /b
+// Checks if a bundle exists for the submitted ID
+// If it exists, a list of all files is shown, or a password needs to be entered.
+func showBundle(w http.ResponseWriter, r *http.Request) {
+	addNoCacheHeader(w)
+	bundleId := queryUrl(w, r, "id", errorHandling.TypeFileNotFound)
+	savedBundle, ok := bundle.Get(bundleId)
+	if !ok {
+		redirectOnIncorrectId(w, r, "error")
+		return
+	}
+
+	config := configuration.Get()
+	view := DownloadView{
+		Name:           savedBundle.Name,
+		Id:             savedBundle.Id,
+		IsDownloadView: true,
+		PageUrl:        "b?id=" + savedBundle.Id,
+		PublicName:     config.PublicName,
+		BaseUrl:        config.ServerUrl,
+		UsesHttps:      configuration.UsesHttps(),
+		CustomContent:  customStaticInfo,
+	}
+	if view.Name == "" {
+		view.Name = "Shared files"
+	}
+
+	if savedBundle.IsPasswordProtected() && !checkPasswordView(w, r, view, savedBundle.PasswordHash) {
+		return
+	}
+
+	var totalSize int64
+	for _, file := range bundle.GetFiles(savedBundle) {
+		totalSize += file.SizeBytes
+		view.BundleFiles = append(view.BundleFiles, bundleFileView{
+			Id:   file.Id,
+			Name: file.Name,
+			Size: file.Size,
+		})
+	}
+	view.Size = helper.ByteCountSI(totalSize)
+
+	err := templateFolder.ExecuteTemplate(w, "bundle", view)
+	helper.CheckIgnoreTimeout(err)
+}
+
+// Handling of /s
+// Checks if a share link exists for the submitted ID
+// If it exists, a download form is shown, or a password needs to be entered.
+func showShareLink(w http.ResponseWriter, r *http.Request) {
+	addNoCacheHeader(w)
+	linkId := queryUrl(w, r, "id", errorHandling.TypeFileNotFound)
+	link, file, ok := sharelink.Get(linkId)
+	if !ok {
+		redirectOnIncorrectId(w, r, "error")
+		return
+	}
+	if file.IsEmbargoed() {
+		showEmbargo(w, file)
+		return
+	}
+	if !checkAccessRestriction(w, r, file.GetAccessRestriction(), "s?id="+link.Id) {
+		return
+	}
+
+	config := configuration.Get()
+	view := DownloadView{
+		Name:           file.Name,
+		Size:           file.Size,
+		Id:             link.Id,
+		PageUrl:        "s?id=" + link.Id,
+		DownloadUrl:    "downloadShare?id=" + link.Id,
+		IsDownloadView: true,
+		PublicName:     config.PublicName,
+		BaseUrl:        config.ServerUrl,
+		UsesHttps:      configuration.UsesHttps(),
+		CustomContent:  customStaticInfo,
+	}
+	if file.RequiresClientDecryption() {
+		view.ClientSideDecryption = true
+		cipher, err := encryption.GetCipherFromFile(file.Encryption)
+		helper.Check(err)
+		view.Cipher = base64.StdEncoding.EncodeToString(cipher)
+	}
+
+	if link.IsPasswordProtected() && !checkPasswordView(w, r, view, link.PasswordHash) {
+		return
+	}
+	view.addPreview(file, "thumbnail?share="+link.Id)
+	err := templateFolder.ExecuteTemplate(w, "download", view)
+	helper.CheckIgnoreTimeout(err)
+}
+
+// showEmbargo shows a countdown until the file is published and can be downloaded.
+// The name of the file is not shown before the publication
+func showEmbargo(w http.ResponseWriter, file models.File) {
+	config := configuration.Get()
+	err := templateFolder.ExecuteTemplate(w, "embargo", DownloadView{
+		Name:           "Not available yet",
+		PublishAt:      file.PublishAt,
+		IsDownloadView: true,
+		PublicName:     config.PublicName,
+		BaseUrl:        config.ServerUrl,
+		UsesHttps:      configuration.UsesHttps(),
+		CustomContent:  customStaticInfo,
+	})
+	helper.CheckIgnoreTimeout(err)
+}
+
+// checkAccessRestriction returns true, if the file or file request does not require a login or the authenticated
+// user is allowed to access it. If the user is not logged in, the login page is shown and the user is redirected
+// to target afterwards. Otherwise, an error page is shown
+func checkAccessRestriction(w http.ResponseWriter, r *http.Request, restriction models.AccessRestriction, target string) bool {
+	if !restriction.RequireLogin {
+		return true
+	}
+	recipient, ok := authentication.GetRecipient(w, r)
+	if !ok {
+		if configuration.Get().Authentication.Method == models.AuthenticationHeader {
+			errorHandling.RedirectToErrorPage(w, r, "Unauthorised",
+				"No login information was sent from the authentication provider.", errorHandling.WidthDefault)
+			return false
+		}
+		if configuration.Get().Authentication.Method == models.AuthenticationDisabled {
+			errorHandling.RedirectToErrorPage(w, r, "Unauthorised",
+				"This page requires a login, but authentication is disabled on this server.", errorHandling.WidthDefault)
+			return false
+		}
+		authentication.SetLoginRedirect(w, target)
+		redirect(w, r, "login")
+		return false
+	}
+	if !authentication.IsAccessGranted(restriction, recipient) {
+		errorHandling.RedirectToErrorPage(w, r, "Access denied",
+			"You are logged in as "+recipient.Name+", but you are not allowed to access this page.", errorHandling.WidthDefault)
+		return false
+	}
+	return true
+}
+
+// getGrantedRequest returns a copy of the request that contains the authenticated recipient for the download log.
+// Returns false, if the file requires a login and the user is not logged in or is not allowed to access it
+func getGrantedRequest(w http.ResponseWriter, r *http.Request, file models.File) (*http.Request, bool) {
+	if !file.RequireLogin {
+		return r, true
+	}
+	recipient, ok := authentication.GetRecipient(w, r)
+	if !ok || !authentication.IsAccessGranted(file.GetAccessRestriction(), recipient) {
+		return r, false
+	}
+	return logging.SetRecipientInRequest(r, recipient), true
+}
+
+// addPreview adds the thumbnail of a picture or the content of a text file to the download page, if available
+func (view *DownloadView) addPreview(file models.File, thumbnailUrl string) {
+	if storage.IsThumbnailAvailable(file) {
+		view.ThumbnailUrl = thumbnailUrl
+		return
+	}
+	content, ok := storage.GetTextPreview(file)
+	if !ok {
+		return
+	}
+	if preview.IsMarkdown(file) {
+		view.PreviewHtml = template.HTML(preview.RenderMarkdown(content))
+		return
+	}
+	view.PreviewText = content
+}
+
+// Handling of /thumbnail
+// Serves the thumbnail of a picture that is shown on the download page of a file or a share link
+func showThumbnail(w http.ResponseWriter, r *http.Request) {
+	file, ok := getPreviewFile(w, r)
+	if !ok || !storage.ServeThumbnail(file, w) {
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// getPreviewFile returns the file of the download page that requested the thumbnail. Returns false, if the
+// file does not exist, the download page is password protected and no valid password cookie was sent or
+// the user is not allowed to access the file
+func getPreviewFile(w http.ResponseWriter, r *http.Request) (models.File, bool) {
+	query := r.URL.Query()
+	if query.Has("share") {
+		link, file, ok := sharelink.Get(query.Get("share"))
+		if !ok || file.IsEmbargoed() || (link.IsPasswordProtected() && !hasValidPwCookie(r, link.Id)) {
+			return models.File{}, false
+		}
+		_, ok = getGrantedRequest(w, r, file)
+		return file, ok
+	}
+	file, ok := storage.GetFile(query.Get("id"))
+	if !ok || file.IsFileRequest() || file.IsEmbargoed() || (file.PasswordHash != "" && !isValidPwCookie(r, file)) {
+		return models.File{}, false
+	}
+	_, ok = getGrantedRequest(w, r, file)
+	return file, ok
+}
+
+// checkPasswordView returns true, if a valid password cookie for the bundle or share link of the view was sent.
+// Otherwise, the password form is shown. If a correct password was submitted, a cookie is set and the user
+// is redirected to the page of the view.
+func checkPasswordView(w http.ResponseWriter, r *http.Request, view DownloadView, passwordHash string) bool {
+	if hasValidPwCookie(r, view.Id) {
+		return true
+	}
+	_ = r.ParseForm()
+	enteredPassword := r.PostForm.Get("password")
+	view.IsPasswordView = true
+	if enteredPassword == "" {
+		err := templateFolder.ExecuteTemplate(w, "download_password", view)
+		helper.CheckIgnoreTimeout(err)
+		return false
+	}
+
+	ip := logging.GetIpAddress(r)
+	ratelimiter.WaitOnDownloadPassword(ip)
+
+	isValid, _ := configuration.VerifyPassword(enteredPassword, passwordHash, configuration.Get().Authentication.SaltFiles)
+	if isValid {
+		writePwCookie(w, view.Id)
+		// redirect so that there is no post data to be resent if user refreshes page
+		redirect(w, r, view.PageUrl)
+		return false
+	}
+	view.IsFailedLogin = true
+	err := templateFolder.ExecuteTemplate(w, "download_password", view)
+	helper.CheckIgnoreTimeout(err)
+	return false
+}
+
 // Handling of /h/ and /hotlink/
 // Hotlinks an image or returns a static error image if image has expired
 func showHotlink(w http.ResponseWriter, r *http.Request) {
@@ -637,6 +1134,10 @@ func showHotlink(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write(imageExpiredPicture)
 		return
 	}
+	if file.IsEmbargoed() || file.RequireLogin {
+		redirect(w, r, "../d?id="+file.Id)
+		return
+	}
 	storage.ServeFile(file, w, r, false, true, false)
 }
 
@@ -727,9 +1228,23 @@ type DownloadView struct {
 	ClientSideDecryption bool
 	EndToEndEncryption   bool
 	UsesHttps            bool
+	PageUrl              string
+	DownloadUrl          string
+	ThumbnailUrl         string
+	PreviewText          string
+	PublishAt            int64
+	PreviewHtml          template.HTML
+	BundleFiles          []bundleFileView
 	CustomContent        customStatic
 }
 
+// bundleFileView contains the information of a single file, that is displayed on the bundle page
+type bundleFileView struct {
+	Id   string
+	Name string
+	Size string
+}
+
 type e2ESetupView struct {
 	IsAdminView    bool
 	IsDownloadView bool
@@ -852,11 +1367,13 @@ func (u *AdminView) convertGlobalConfig(view int, user models.User) *AdminView {
 		_, u.DiskUsage, u.DiskTotal, u.DiskUsagePercent = serverstats.GetDiskInfo()
 		u.CpuLoad = serverstats.GetCpuUsage()
 	case ViewUsers:
-		uploadCounts := storage.GetUploadCounts()
+		usages := storage.GetQuotaUsages()
 		u.Users = make([]userInfo, 0)
 		for _, userEntry := range database.GetAllUsers() {
 			userWithUploads := userInfo{
-				UploadCount: uploadCounts[userEntry.Id],
+				UploadCount: usages[userEntry.Id].Files,
+				StorageUsed: helper.ByteCountSI(usages[userEntry.Id].StorageBytes),
+				Quota:       storage.GetEffectiveQuota(userEntry),
 				User:        userEntry,
 			}
 			// Otherwise the user is not shown as online, if /users is opened as first page
@@ -950,6 +1467,8 @@ func sortApiKeys(input []models.ApiKey) []models.ApiKey {
 
 type userInfo struct {
 	UploadCount int
+	StorageUsed string
+	Quota       models.UserQuota
 	User        models.User
 }
 
@@ -975,6 +1494,9 @@ func showPublicUpload(w http.ResponseWriter, r *http.Request) {
 		errorHandling.RedirectGenericErrorPage(w, r, errorHandling.TypeInvalidFileRequest)
 		return
 	}
+	if !checkAccessRestriction(w, r, request.GetAccessRestriction(), "publicUpload?id="+request.Id+"&key="+apiKey) {
+		return
+	}
 
 	config := configuration.Get()
 
@@ -1063,6 +1585,58 @@ func downloadPresigned(w http.ResponseWriter, r *http.Request) {
 	storage.ServeFilesAsZip(files, presignedUrl.Filename, w, r)
 }
 
+// Handling of /bundleDownload
+// Outputs a single file of the bundle or all files as a zip archive and reduces the download remaining count for the bundle
+func downloadBundle(w http.ResponseWriter, r *http.Request) {
+	addNoCacheHeader(w)
+	bundleId := queryUrl(w, r, "id", errorHandling.TypeFileNotFound)
+	savedBundle, ok := bundle.Get(bundleId)
+	if !ok {
+		redirectOnIncorrectId(w, r, "error")
+		return
+	}
+	if savedBundle.IsPasswordProtected() && !hasValidPwCookie(r, savedBundle.Id) {
+		redirect(w, r, "b?id="+savedBundle.Id)
+		return
+	}
+	fileId := r.URL.Query().Get("file")
+	if fileId != "" {
+		file, ok := bundle.GetFile(savedBundle, fileId)
+		if !ok {
+			redirectOnIncorrectId(w, r, "error")
+			return
+		}
+		bundle.RegisterDownload(savedBundle)
+		storage.ServeFile(file, w, r, true, false, false)
+		return
+	}
+	files := bundle.GetFiles(savedBundle)
+	if len(files) == 0 {
+		redirectOnIncorrectId(w, r, "error")
+		return
+	}
+	bundle.RegisterDownload(savedBundle)
+	storage.ServeFilesAsZip(files, savedBundle.Name, w, r)
+}
+
+// Handling of /downloadShare
+// Outputs the file of the share link to the user and reduces the download remaining count for the link
+func downloadShareLink(w http.ResponseWriter, r *http.Request) {
+	addNoCacheHeader(w)
+	linkId := queryUrl(w, r, "id", errorHandling.TypeFileNotFound)
+	link, file, ok := sharelink.Get(linkId)
+	if !ok {
+		redirectOnIncorrectId(w, r, "error")
+		return
+	}
+	grantedRequest, isGranted := getGrantedRequest(w, r, file)
+	if file.IsEmbargoed() || (link.IsPasswordProtected() && !hasValidPwCookie(r, link.Id)) || !isGranted {
+		redirect(w, r, "s?id="+link.Id)
+		return
+	}
+	storage.ServeFileWithShareLink(file, link, w, grantedRequest, true)
+}
+
 func serveFile(id string, isRootUrl bool, w http.ResponseWriter, r *http.Request) {
 	addNoCacheHeader(w)
 	savedFile, ok := storage.GetFile(id)
@@ -1074,20 +1648,22 @@ func serveFile(id string, isRootUrl bool, w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
-	if savedFile.PasswordHash != "" {
-		if !(isValidPwCookie(r, savedFile)) {
-			if isRootUrl {
-				redirect(w, r, "d?id="+savedFile.Id)
-			} else {
-				redirect(w, r, "../../d?id="+savedFile.Id)
-			}
-			return
+	// The download page asks for the password or the login, or shows a countdown, if the file has not been published yet
+	grantedRequest, isGranted := getGrantedRequest(w, r, savedFile)
+	if savedFile.IsEmbargoed() || (savedFile.PasswordHash != "" && !isValidPwCookie(r, savedFile)) || !isGranted {
+		if isRootUrl {
+			redirect(w, r, "d?id="+savedFile.Id)
+		} else {
+			redirect(w, r, "../../d?id="+savedFile.Id)
 		}
+		return
 	}
-	storage.ServeFile(savedFile, w, r, true, true, false)
+	storage.ServeFile(savedFile, w, grantedRequest, true, true, false)
 }
 
-func requireLogin(next http.HandlerFunc, isUiCall, isPwChangeView bool) http.HandlerFunc {
+// requireLogin only calls next, if the user is logged in. If isAccountSetupView is false, the user is
+// redirected to change the password or to set up two-factor authentication first, if required
+func requireLogin(next http.HandlerFunc, isUiCall, isAccountSetupView bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		addNoCacheHeader(w)
 		user, isLoggedIn, err := authentication.IsAuthenticated(w, r)
@@ -1096,11 +1672,15 @@ func requireLogin(next http.HandlerFunc, isUiCall, isPwChangeView bool) http.Han
 			return
 		}
 		if isLoggedIn {
-			if user.ResetPassword && isUiCall && configuration.Get().Authentication.Method == models.AuthenticationInternal {
-				if !isPwChangeView {
+			if isUiCall && !isAccountSetupView {
+				if user.ResetPassword && configuration.Get().Authentication.Method == models.AuthenticationInternal {
 					redirect(w, r, "changePassword")
 					return
 				}
+				if twofactor.IsEnrollmentRequired(user) {
+					redirect(w, r, "twoFactor")
+					return
+				}
 			}
 			r = authentication.SetUserInRequest(r, user)
 			next.ServeHTTP(w, r)
@@ -1128,22 +1708,32 @@ func newAdminButtonContext(file models.FileApiOutput, user models.User) adminBut
 
 // Write a cookie if the user has entered a correct password for a password-protected file
 func writeFilePwCookie(w http.ResponseWriter, file models.File) {
+	writePwCookie(w, file.Id)
+}
+
+// Checks if a cookie contains the correct token for a password-protected file
+func isValidPwCookie(r *http.Request, file models.File) bool {
+	return hasValidPwCookie(r, file.Id)
+}
+
+// Write a cookie if the user has entered a correct password for a password-protected file, bundle or share link
+func writePwCookie(w http.ResponseWriter, id string) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     "p" + file.Id,
-		Value:    downloadPasswordToken.Generate(file.Id),
+		Name:     "p" + id,
+		Value:    downloadPasswordToken.Generate(id),
 		Expires:  time.Now().Add(5 * time.Minute),
 		HttpOnly: true,
 		SameSite: http.SameSiteStrictMode,
 	})
 }
 
-// Checks if a cookie contains the correct token for a password-protected file
-func isValidPwCookie(r *http.Request, file models.File) bool {
-	cookie, err := r.Cookie("p" + file.Id)
+// Checks if a cookie contains the correct token for a password-protected file, bundle or share link
+func hasValidPwCookie(r *http.Request, id string) bool {
+	cookie, err := r.Cookie("p" + id)
 	if err != nil {
 		return false
 	}
-	return downloadPasswordToken.IsValid(cookie.Value, file.Id)
+	return downloadPasswordToken.IsValid(cookie.Value, id)
 }
 
 // Adds a header to disable external caching
@@ -1174,6 +1764,9 @@ type genericView struct {
 	ErrorCardWidth    string
 	ErrorId           int
 	MinPasswordLength int
+	IsMailEnabled     bool
+	InfoMessage       string
+	ResetToken        string
 	CustomContent     customStatic
 }
 
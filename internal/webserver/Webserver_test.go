@@ -4,23 +4,36 @@ package webserver
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"html/template"
+	"image"
+	"image/png"
+	"io"
+	"mime/quotedprintable"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/logging/mail"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage/processingstatus"
 	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/smtpstub"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
 	"github.com/forceu/gokapi/internal/webserver/authentication"
 	"github.com/forceu/gokapi/internal/webserver/authentication/csrftoken"
+	"github.com/forceu/gokapi/internal/webserver/authentication/twofactor"
 	"github.com/forceu/gokapi/internal/webserver/ratelimiter"
 )
 
@@ -267,6 +280,103 @@ func TestForgotPw(t *testing.T) {
 	})
 }
 
+func TestForgotPwMail(t *testing.T) {
+	const forgotUrl = "http://localhost:53843/forgotpw"
+	server, err := smtpstub.Start(smtpstub.Config{})
+	test.IsNil(t, err)
+	t.Cleanup(func() {
+		mail.Init(configuration.Get().ServerUrl, false, "")
+		server.Close()
+	})
+	t.Setenv("GOKAPI_SMTP_HOST", server.Host())
+	t.Setenv("GOKAPI_SMTP_PORT", strconv.Itoa(server.Port()))
+	t.Setenv("GOKAPI_SMTP_TLS", mail.TlsModeNone)
+	t.Setenv("GOKAPI_SMTP_FROM", "gokapi@example.com")
+	mail.Init(configuration.Get().ServerUrl, false, "Gokapi")
+
+	database.SaveUser(models.User{
+		Name:      "reset@example.com",
+		UserLevel: models.UserLevelUser,
+		Password:  configuration.HashPassword("oldpassword", false, ""),
+	}, true)
+	user, ok := database.GetUserByName("reset@example.com")
+	test.IsEqualBool(t, ok, true)
+	defer database.DeleteUser(user.Id)
+	database.SaveSession("resetsession", models.Session{RenewAt: 2147483645, ValidUntil: 2147483646, UserId: user.Id})
+
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             forgotUrl,
+		IsHtml:          true,
+		RequiredContent: []string{"name=\"username\"", "Send link"},
+		ExcludedContent: []string{"--reconfigure"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             forgotUrl,
+		IsHtml:          true,
+		Method:          "POST",
+		PostValues:      []test.PostBody{{"username", "reset@example.com"}, {"csrf-token", "invalid"}},
+		RequiredContent: []string{"Form was not submitted completely"},
+	})
+	// Unknown users get the same response, but no email
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             forgotUrl,
+		IsHtml:          true,
+		Method:          "POST",
+		PostValues:      []test.PostBody{{"username", "unknown@example.com"}, {"csrf-token", csrftoken.Generate(csrftoken.TypeLogin)}},
+		RequiredContent: []string{"If an account with a valid email address exists"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             forgotUrl,
+		IsHtml:          true,
+		Method:          "POST",
+		PostValues:      []test.PostBody{{"username", "reset@example.com"}, {"csrf-token", csrftoken.Generate(csrftoken.TypeLogin)}},
+		RequiredContent: []string{"If an account with a valid email address exists"},
+	})
+	messages := server.WaitForMessages(1, 5*time.Second)
+	test.IsEqualInt(t, len(messages), 1)
+	test.IsEqualString(t, messages[0].To[0], "reset@example.com")
+	_, body, _ := strings.Cut(messages[0].Data, "\r\n\r\n")
+	decodedBody, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+	test.IsNil(t, err)
+	matches := regexp.MustCompile(`forgotpw\?token=(\w+)`).FindStringSubmatch(string(decodedBody))
+	test.IsEqualInt(t, len(matches), 2)
+	token := matches[1]
+
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:                forgotUrl + "?token=invalid",
+		RedirectUrl:        "error",
+		IgnoreRedirectParm: true,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             forgotUrl + "?token=" + token,
+		IsHtml:          true,
+		RequiredContent: []string{"Please enter a new password", "name=\"token\" value=\"" + token + "\""},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             forgotUrl,
+		IsHtml:          true,
+		Method:          "POST",
+		PostValues:      []test.PostBody{{"token", token}, {"newpw", "short"}, {"csrf-token", csrftoken.Generate(csrftoken.TypeLogin)}},
+		RequiredContent: []string{"Password is too short"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         forgotUrl,
+		Method:      "POST",
+		RedirectUrl: "login",
+		PostValues:  []test.PostBody{{"token", token}, {"newpw", "newpassword"}, {"csrf-token", csrftoken.Generate(csrftoken.TypeLogin)}},
+	})
+	user, _ = database.GetUser(user.Id)
+	isCorrect, _ := configuration.VerifyPassword("newpassword", user.Password, "")
+	test.IsEqualBool(t, isCorrect, true)
+	_, ok = database.GetSession("resetsession")
+	test.IsEqualBool(t, ok, false)
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:                forgotUrl + "?token=" + token,
+		RedirectUrl:        "error",
+		IgnoreRedirectParm: true,
+	})
+}
+
 func TestLoginCorrect(t *testing.T) {
 	t.Parallel()
 	test.HttpPageResult(t, test.HttpTestConfig{
@@ -312,6 +422,106 @@ func TestLoginIncorrectUsername(t *testing.T) {
 	})
 }
 
+func TestLoginTwoFactor(t *testing.T) {
+	const loginUrl = "http://localhost:53843/login"
+	database.SaveUser(models.User{
+		Name:      "twofactor",
+		UserLevel: models.UserLevelAdmin,
+		Password:  configuration.HashPassword("twofactorpw", false, ""),
+	}, true)
+	user, ok := database.GetUserByName("twofactor")
+	test.IsEqualBool(t, ok, true)
+	defer database.DeleteUser(user.Id)
+	recoveryHash := sha256.Sum256([]byte("ABCDEFGH"))
+	totp := models.UserTotp{UserId: user.Id, Secret: "JBSWY3DPEHPK3PXP", IsConfirmed: true}
+	totp.SetRecoveryCodeHashes([]string{hex.EncodeToString(recoveryHash[:])})
+	database.SaveUserTotp(totp)
+
+	// Correct credentials show the form for the second factor instead of logging in
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             loginUrl,
+		IsHtml:          true,
+		Method:          "POST",
+		PostValues:      postValues("twofactor", "twofactorpw", csrftoken.Generate(csrftoken.TypeLogin)),
+		RequiredContent: []string{"name=\"totp-token\"", "name=\"totp-code\""},
+		ExcludedContent: []string{"id=\"uname_hidden\""},
+	})
+
+	token := twofactor.CreatePendingLogin(user.Id)
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             loginUrl,
+		IsHtml:          true,
+		Method:          "POST",
+		PostValues:      []test.PostBody{{"username", "twofactor"}, {"totp-token", token}, {"totp-code", "000000"}},
+		RequiredContent: []string{"Incorrect code!", token},
+	})
+	cookies := test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         loginUrl,
+		Method:      "POST",
+		RedirectUrl: "admin",
+		PostValues:  []test.PostBody{{"username", "twofactor"}, {"totp-token", token}, {"totp-code", "abcd-efgh"}},
+	})
+	test.IsNotEqualString(t, cookieValue(cookies, "session_token"), "")
+	test.IsEqualInt(t, twofactor.GetRemainingRecoveryCodes(user.Id), 0)
+
+	// A used token requires entering the password again
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             loginUrl,
+		IsHtml:          true,
+		Method:          "POST",
+		PostValues:      []test.PostBody{{"username", "twofactor"}, {"totp-token", token}, {"totp-code", "abcd-efgh"}},
+		RequiredContent: []string{"Too many incorrect codes or the login expired", "id=\"uname_hidden\""},
+	})
+
+	// Admins without two-factor authentication are redirected, if it is required
+	database.DeleteUserTotp(user.Id)
+	database.SaveSession("twofactorsession", models.Session{
+		RenewAt:    2147483645,
+		ValidUntil: 2147483646,
+		UserId:     user.Id,
+	})
+	sessionCookie := []test.Cookie{{Name: "session_token", Value: "twofactorsession"}}
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://localhost:53843/admin",
+		IsHtml:          true,
+		Cookies:         sessionCookie,
+		RequiredContent: []string{"Downloads remaining"},
+	})
+	configuration.Get().Authentication.RequireTwoFactorForAdmins = true
+	defer func() { configuration.Get().Authentication.RequireTwoFactorForAdmins = false }()
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://localhost:53843/admin",
+		Cookies:     sessionCookie,
+		RedirectUrl: "twoFactor",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://localhost:53843/twoFactor",
+		IsHtml:          true,
+		Cookies:         sessionCookie,
+		RequiredContent: []string{"Two-factor authentication is required", "value=\"enroll\""},
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	// Disabled, as neither a token nor access by trusted proxies is set
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:        "http://localhost:53843/metrics",
+		ResultCode: http.StatusNotFound,
+	})
+
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	test.IsEqualBool(t, isMetricsAccessAllowed(r, "", false), false)
+	test.IsEqualBool(t, isMetricsAccessAllowed(r, "secrettoken", false), false)
+	r.Header.Set("Authorization", "Bearer invalid")
+	test.IsEqualBool(t, isMetricsAccessAllowed(r, "secrettoken", false), false)
+	r.Header.Set("Authorization", "secrettoken")
+	test.IsEqualBool(t, isMetricsAccessAllowed(r, "secrettoken", false), false)
+	r.Header.Set("Authorization", "Bearer secrettoken")
+	test.IsEqualBool(t, isMetricsAccessAllowed(r, "secrettoken", false), true)
+	test.IsEqualBool(t, isMetricsAccessAllowed(r, "", false), false)
+}
+
 func TestLogout(t *testing.T) {
 	t.Parallel()
 	test.HttpPageResult(t, test.HttpTestConfig{
@@ -364,6 +574,224 @@ func TestDownloadHotlink(t *testing.T) {
 	})
 }
 
+func TestDownloadPreview(t *testing.T) {
+	t.Parallel()
+	// Text files with unlimited downloads are shown as a preview
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=unlimitedDownload",
+		IsHtml:          true,
+		RequiredContent: []string{"<pre class=\"file-preview text-start mb-4\">def</pre>"},
+		ExcludedContent: []string{"download-thumbnail"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=unlimitedTime",
+		IsHtml:          true,
+		RequiredContent: []string{"unlimitedTime"},
+		ExcludedContent: []string{"file-preview", "download-thumbnail"},
+	})
+}
+
+func TestShowThumbnail(t *testing.T) {
+	t.Parallel()
+	var picture bytes.Buffer
+	test.IsNil(t, png.Encode(&picture, image.NewGray(image.Rect(0, 0, 20, 10))))
+	test.IsNil(t, os.WriteFile(configuration.Get().DataDir+"/thumbnailtestpicture", picture.Bytes(), 0600))
+	database.SaveMetaData(models.File{
+		Id:                 "thumbnailTest",
+		Name:               "picture.png",
+		Size:               "1 KB",
+		SHA1:               "thumbnailtestpicture",
+		ExpireAt:           2147483646,
+		ContentType:        "image/png",
+		UnlimitedDownloads: true,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=thumbnailTest",
+		IsHtml:          true,
+		RequiredContent: []string{"<img src=\"./thumbnail?id=thumbnailTest\" class=\"download-thumbnail mb-4\""},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url: "http://127.0.0.1:53843/thumbnail?id=thumbnailTest",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:        "http://127.0.0.1:53843/thumbnail?id=unlimitedDownload",
+		ResultCode: http.StatusNotFound,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:        "http://127.0.0.1:53843/thumbnail?id=invalid",
+		ResultCode: http.StatusNotFound,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:        "http://127.0.0.1:53843/thumbnail?share=invalid",
+		ResultCode: http.StatusNotFound,
+	})
+}
+
+func TestDownloadEmbargo(t *testing.T) {
+	t.Parallel()
+	test.IsNil(t, os.WriteFile(configuration.Get().DataDir+"/embargotestcontent", []byte("embargoed"), 0600))
+	file := models.File{
+		Id:                 "embargoTestFile",
+		Name:               "release.png",
+		Size:               "9 B",
+		SHA1:               "embargotestcontent",
+		HotlinkId:          "embargoTestHotlink",
+		ExpireAt:           2147483646,
+		ContentType:        "image/png",
+		UnlimitedDownloads: true,
+		PublishAt:          time.Now().Add(time.Hour).Unix(),
+	}
+	database.SaveMetaData(file)
+	database.SaveHotlink(file)
+	database.SaveShareLink(models.ShareLink{Id: "embargoTestShare", FileId: file.Id, UnlimitedDownloads: true, UnlimitedTime: true})
+
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=embargoTestFile",
+		IsHtml:          true,
+		RequiredContent: []string{"Not available yet", strconv.FormatInt(file.PublishAt, 10)},
+		ExcludedContent: []string{"release.png", "downloadFile?id="},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/downloadFile?id=embargoTestFile",
+		RedirectUrl: "d?id=embargoTestFile",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/dh/embargoTestFile/release.png",
+		RedirectUrl: "/d?id=embargoTestFile",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/h/embargoTestHotlink",
+		RedirectUrl: "/d?id=embargoTestFile",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/d/embargoTestFile/release.png",
+		RedirectUrl: "/d?id=embargoTestFile",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/s?id=embargoTestShare",
+		IsHtml:          true,
+		RequiredContent: []string{"Not available yet"},
+		ExcludedContent: []string{"release.png"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/downloadShare?id=embargoTestShare",
+		RedirectUrl: "s?id=embargoTestShare",
+	})
+
+	file.PublishAt = time.Now().Add(-time.Minute).Unix()
+	database.SaveMetaData(file)
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=embargoTestFile",
+		IsHtml:          true,
+		RequiredContent: []string{"release.png", "downloadFile?id=embargoTestFile"},
+		ExcludedContent: []string{"Not available yet"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/h/embargoTestHotlink",
+		RequiredContent: []string{"embargoed"},
+	})
+}
+
+func TestDownloadRequireLogin(t *testing.T) {
+	t.Parallel()
+	test.IsNil(t, os.WriteFile(configuration.Get().DataDir+"/restrictedtestcontent", []byte("restricted"), 0600))
+	file := models.File{
+		Id:                 "restrictedTestFile",
+		Name:               "restricted.png",
+		Size:               "10 B",
+		SHA1:               "restrictedtestcontent",
+		HotlinkId:          "restrictedTestHotlink",
+		ExpireAt:           2147483646,
+		ContentType:        "image/png",
+		UnlimitedDownloads: true,
+		RequireLogin:       true,
+		AllowedUsers:       "user",
+	}
+	database.SaveMetaData(file)
+	database.SaveHotlink(file)
+	database.SaveShareLink(models.ShareLink{Id: "restrictedTestShare", FileId: file.Id, UnlimitedDownloads: true, UnlimitedTime: true})
+	sessionCookie := []test.Cookie{{Name: "session_token", Value: "validsession"}}
+
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/d?id=restrictedTestFile",
+		RedirectUrl: "login",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/s?id=restrictedTestShare",
+		RedirectUrl: "login",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/downloadFile?id=restrictedTestFile",
+		RedirectUrl: "d?id=restrictedTestFile",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/downloadShare?id=restrictedTestShare",
+		RedirectUrl: "s?id=restrictedTestShare",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/h/restrictedTestHotlink",
+		RedirectUrl: "/d?id=restrictedTestFile",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/d/restrictedTestFile/restricted.png",
+		RedirectUrl: "/d?id=restrictedTestFile",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:        "http://127.0.0.1:53843/thumbnail?id=restrictedTestFile",
+		ResultCode: http.StatusNotFound,
+	})
+
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=restrictedTestFile",
+		IsHtml:          true,
+		RequiredContent: []string{"restricted.png", "downloadFile?id=restrictedTestFile"},
+		Cookies:         sessionCookie,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadFile?id=restrictedTestFile",
+		RequiredContent: []string{"restricted"},
+		Cookies:         sessionCookie,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadShare?id=restrictedTestShare",
+		RequiredContent: []string{"restricted"},
+		Cookies:         sessionCookie,
+	})
+
+	file.AllowedUsers = "test"
+	database.SaveMetaData(file)
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/d?id=restrictedTestFile",
+		IsHtml:          true,
+		RequiredContent: []string{"Access denied"},
+		ExcludedContent: []string{"restricted.png"},
+		Cookies:         sessionCookie,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/downloadFile?id=restrictedTestFile",
+		RedirectUrl: "d?id=restrictedTestFile",
+		Cookies:     sessionCookie,
+	})
+}
+
+func TestLoginRedirectAfterLogin(t *testing.T) {
+	t.Parallel()
+	test.HttpPostRequest(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/login",
+		ResultCode:  http.StatusTemporaryRedirect,
+		RedirectUrl: "d?id=restrictedTestFile",
+		PostValues:  postValues("user", "useruser", csrftoken.Generate(csrftoken.TypeLogin)),
+		Cookies:     []test.Cookie{{Name: authentication.CookieLoginRedirect, Value: "d?id=restrictedTestFile"}},
+	})
+	test.HttpPostRequest(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/login",
+		ResultCode:  http.StatusTemporaryRedirect,
+		RedirectUrl: "admin",
+		PostValues:  postValues("user", "useruser", csrftoken.Generate(csrftoken.TypeLogin)),
+		Cookies:     []test.Cookie{{Name: authentication.CookieLoginRedirect, Value: "https://example.com"}},
+	})
+}
+
 func TestDownloadNoPassword(t *testing.T) {
 	t.Parallel()
 	// Show download page
@@ -463,6 +891,222 @@ func TestDownloadCorrectPassword(t *testing.T) {
 	})
 }
 
+func TestBundle(t *testing.T) {
+	t.Parallel()
+	database.SaveBundle(models.Bundle{
+		Id:                 "bundleTest1234567890",
+		Name:               "TestBundle",
+		FileIds:            "unlimitedDownload,unlimitedTime,invalidFile",
+		ExpireAt:           2147483646,
+		DownloadsRemaining: 2,
+	})
+	// Show bundle page
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/b?id=bundleTest1234567890",
+		IsHtml:          true,
+		RequiredContent: []string{"TestBundle", "unlimitedDownload", "unlimitedTime", "Download all"},
+		ExcludedContent: []string{"invalidFile"},
+	})
+	// Download single file
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/bundleDownload?id=bundleTest1234567890&file=unlimitedTime",
+		RequiredContent: []string{"def"},
+	})
+	// Download file that is not part of the bundle
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:                "http://127.0.0.1:53843/bundleDownload?id=bundleTest1234567890&file=e4TjE7CokWK0giiLNxDL",
+		IgnoreRedirectParm: true,
+		RedirectUrl:        "error",
+	})
+	// Download as zip
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/bundleDownload?id=bundleTest1234567890",
+		RequiredContent: []string{"unlimitedDownload", "unlimitedTime"},
+	})
+	bundle, ok := database.GetBundle("bundleTest1234567890")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, bundle.DownloadCount, 2)
+	test.IsEqualInt(t, bundle.DownloadsRemaining, 0)
+	// Show expired bundle page
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:                "http://127.0.0.1:53843/b?id=bundleTest1234567890",
+		IgnoreRedirectParm: true,
+		RedirectUrl:        "error",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:                "http://127.0.0.1:53843/bundleDownload?id=bundleTest1234567890",
+		IgnoreRedirectParm: true,
+		RedirectUrl:        "error",
+	})
+}
+
+func TestBundlePassword(t *testing.T) {
+	t.Parallel()
+	database.SaveBundle(models.Bundle{
+		Id:                 "bundlePassword123456",
+		Name:               "PasswordBundle",
+		FileIds:            "unlimitedDownload",
+		PasswordHash:       configuration.HashPassword("123", false, ""),
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/b?id=bundlePassword123456",
+		IsHtml:          true,
+		RequiredContent: []string{"Password required", "./b?id=bundlePassword123456"},
+		ExcludedContent: []string{"unlimitedDownload"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/b?id=bundlePassword123456",
+		IsHtml:          true,
+		RequiredContent: []string{"Incorrect password!"},
+		Method:          "POST",
+		PostValues:      []test.PostBody{{"password", "incorrect"}},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/bundleDownload?id=bundlePassword123456",
+		RedirectUrl: "b?id=bundlePassword123456",
+		Cookies:     []test.Cookie{{"pbundlePassword123456", "invalid"}},
+	})
+	cookies := test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/b?id=bundlePassword123456",
+		RedirectUrl: "b?id=bundlePassword123456",
+		Method:      "POST",
+		PostValues:  []test.PostBody{{"password", "123"}},
+	})
+	pwCookie := ""
+	for _, cookie := range cookies {
+		if (*cookie).Name == "pbundlePassword123456" {
+			pwCookie = (*cookie).Value
+			break
+		}
+	}
+	if pwCookie == "" {
+		t.Error("Cookie not set")
+	}
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/b?id=bundlePassword123456",
+		IsHtml:          true,
+		RequiredContent: []string{"PasswordBundle", "unlimitedDownload"},
+		Cookies:         []test.Cookie{{"pbundlePassword123456", pwCookie}},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/bundleDownload?id=bundlePassword123456&file=unlimitedDownload",
+		RequiredContent: []string{"def"},
+		Cookies:         []test.Cookie{{"pbundlePassword123456", pwCookie}},
+	})
+}
+
+func TestShareLink(t *testing.T) {
+	t.Parallel()
+	database.SaveShareLink(models.ShareLink{
+		Id:                 "shareLinkTest1234567",
+		FileId:             "unlimitedTime",
+		ExpireAt:           2147483646,
+		DownloadsRemaining: 1,
+	})
+	database.SaveShareLink(models.ShareLink{
+		Id:                 "shareLinkTest7654321",
+		FileId:             "unlimitedTime",
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/s?id=shareLinkTest1234567",
+		IsHtml:          true,
+		RequiredContent: []string{"unlimitedTime", "./downloadShare?id=shareLinkTest1234567"},
+		ExcludedContent: []string{"downloadFile?id="},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadShare?id=shareLinkTest1234567",
+		RequiredContent: []string{"def"},
+	})
+	link, ok := database.GetShareLink("shareLinkTest1234567")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, link.DownloadCount, 1)
+	test.IsEqualInt(t, link.DownloadsRemaining, 0)
+	// The link has no downloads remaining, other links of the file are not affected
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:                "http://127.0.0.1:53843/s?id=shareLinkTest1234567",
+		IgnoreRedirectParm: true,
+		RedirectUrl:        "error",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:                "http://127.0.0.1:53843/downloadShare?id=shareLinkTest1234567",
+		IgnoreRedirectParm: true,
+		RedirectUrl:        "error",
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadShare?id=shareLinkTest7654321",
+		RequiredContent: []string{"def"},
+	})
+	link, ok = database.GetShareLink("shareLinkTest7654321")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, link.DownloadCount, 1)
+	// Revoked link
+	database.DeleteShareLink("shareLinkTest7654321")
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:                "http://127.0.0.1:53843/downloadShare?id=shareLinkTest7654321",
+		IgnoreRedirectParm: true,
+		RedirectUrl:        "error",
+	})
+}
+
+func TestShareLinkPassword(t *testing.T) {
+	t.Parallel()
+	database.SaveShareLink(models.ShareLink{
+		Id:                 "shareLinkPassword123",
+		FileId:             "unlimitedDownload",
+		PasswordHash:       configuration.HashPassword("123", false, ""),
+		UnlimitedDownloads: true,
+		UnlimitedTime:      true,
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/s?id=shareLinkPassword123",
+		IsHtml:          true,
+		RequiredContent: []string{"Password required", "./s?id=shareLinkPassword123"},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/s?id=shareLinkPassword123",
+		IsHtml:          true,
+		RequiredContent: []string{"Incorrect password!"},
+		Method:          "POST",
+		PostValues:      []test.PostBody{{"password", "incorrect"}},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/downloadShare?id=shareLinkPassword123",
+		RedirectUrl: "s?id=shareLinkPassword123",
+		Cookies:     []test.Cookie{{"pshareLinkPassword123", "invalid"}},
+	})
+	cookies := test.HttpPageResult(t, test.HttpTestConfig{
+		Url:         "http://127.0.0.1:53843/s?id=shareLinkPassword123",
+		RedirectUrl: "s?id=shareLinkPassword123",
+		Method:      "POST",
+		PostValues:  []test.PostBody{{"password", "123"}},
+	})
+	pwCookie := ""
+	for _, cookie := range cookies {
+		if (*cookie).Name == "pshareLinkPassword123" {
+			pwCookie = (*cookie).Value
+			break
+		}
+	}
+	if pwCookie == "" {
+		t.Error("Cookie not set")
+	}
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/s?id=shareLinkPassword123",
+		IsHtml:          true,
+		RequiredContent: []string{"unlimitedDownload", "./downloadShare?id=shareLinkPassword123"},
+		Cookies:         []test.Cookie{{"pshareLinkPassword123", pwCookie}},
+	})
+	test.HttpPageResult(t, test.HttpTestConfig{
+		Url:             "http://127.0.0.1:53843/downloadShare?id=shareLinkPassword123",
+		RequiredContent: []string{"def"},
+		Cookies:         []test.Cookie{{"pshareLinkPassword123", pwCookie}},
+	})
+}
+
 func TestPostUploadNoAuth(t *testing.T) {
 	t.Parallel()
 	test.HttpPostUploadRequest(t, test.HttpTestConfig{
@@ -12,23 +12,33 @@ import (
 
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/database/leader"
 	"github.com/forceu/gokapi/internal/encryption"
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/logging/mail"
 	"github.com/forceu/gokapi/internal/logging/serverstats"
+	"github.com/forceu/gokapi/internal/logging/webhooks"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/bundle"
 	"github.com/forceu/gokapi/internal/storage/chunking"
 	"github.com/forceu/gokapi/internal/storage/chunking/chunkreservation"
 	"github.com/forceu/gokapi/internal/storage/filerequest"
+	"github.com/forceu/gokapi/internal/storage/keyrotation"
 	"github.com/forceu/gokapi/internal/storage/presign"
+	"github.com/forceu/gokapi/internal/storage/scrub"
+	"github.com/forceu/gokapi/internal/storage/sharelink"
 	"github.com/forceu/gokapi/internal/webserver/api/mutex/apimutex"
 	"github.com/forceu/gokapi/internal/webserver/api/mutex/e2emutex"
+	"github.com/forceu/gokapi/internal/webserver/authentication"
 	"github.com/forceu/gokapi/internal/webserver/authentication/downloadPasswordToken"
+	"github.com/forceu/gokapi/internal/webserver/authentication/twofactor"
 	"github.com/forceu/gokapi/internal/webserver/authentication/users"
 	"github.com/forceu/gokapi/internal/webserver/errorHandling/errorcodes"
 	"github.com/forceu/gokapi/internal/webserver/fileupload"
 	"github.com/forceu/gokapi/internal/webserver/ratelimiter"
+	"github.com/forceu/gokapi/internal/webserver/tus"
 )
 
 // LengthPublicId is the length of the public ID used for API keys
@@ -52,7 +62,8 @@ func Process(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	}
 	var user models.User
-	user, ok = isAuthorisedForApi(r, routing)
+	var apiKey models.ApiKey
+	user, apiKey, ok = isAuthorisedForApi(r, routing)
 	if !ok {
 		sendError(w, http.StatusUnauthorized, errorcodes.InvalidApiKey, "Unauthorized")
 		return
@@ -66,7 +77,7 @@ func Process(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	parser := routing.RequestParser.New()
-	err := parser.ParseRequest(r)
+	err := parser.ParseRequest(logging.SetApiKeyId(r, apiKey.PublicId))
 	if err != nil {
 		sendError(w, http.StatusBadRequest, errorcodes.CannotParse, err.Error())
 		return
@@ -111,6 +122,34 @@ func apiEditFile(w http.ResponseWriter, r requestParser, user models.User) {
 			file.UnlimitedTime = false
 		}
 	}
+	isExpiryChange := request.UnlimitedExpiry || request.ExpiryTimestamp != 0
+	if request.IsPublishAtSet {
+		// An expiry that has been passed explicitly is not moved
+		moveExpiry := request.ExpiryFromPublish && !isExpiryChange
+		err := storage.ChangePublishDate(&file, request.PublishAt, moveExpiry)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, errorcodes.InvalidUserInput, err.Error())
+			return
+		}
+		isExpiryChange = isExpiryChange || moveExpiry
+	}
+	if request.IsRequireLoginSet {
+		file.RequireLogin = request.RequireLogin
+	}
+	if request.IsAllowedUsersSet {
+		file.AllowedUsers = models.CleanAccessList(request.AllowedUsers)
+	}
+	if request.IsAllowedGroupsSet {
+		file.AllowedGroups = models.CleanAccessList(request.AllowedGroups)
+	}
+	isLimitChange := request.UnlimitedDownloads || request.AllowedDownloads != 0 || isExpiryChange
+	if isLimitChange && file.UploadRequestId == "" {
+		err := storage.CheckExpiryQuota(user.Id, file.ExpireAt, file.UnlimitedTime, file.DownloadsRemaining, file.UnlimitedDownloads)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, errorcodes.QuotaExceeded, err.Error())
+			return
+		}
+	}
 
 	if !request.KeepPassword {
 		file.PasswordHash = configuration.HashPassword(request.Password, false, "")
@@ -125,10 +164,16 @@ func apiEditFile(w http.ResponseWriter, r requestParser, user models.User) {
 	}
 
 	database.SaveMetaData(file)
-	logging.LogEdit(file, user)
+	logging.LogEdit(file, user, request.Request)
 	outputFileApiInfo(w, file)
 }
 
+// NewApiKey generates and saves a new API key for the user that is not associated with a file request.
+// If defaultPermissions is false, the key is created without any permissions
+func NewApiKey(userId int, friendlyName string, defaultPermissions bool) models.ApiKey {
+	return generateNewKey(defaultPermissions, userId, friendlyName, "")
+}
+
 // generateNewKey generates and saves a new API key
 func generateNewKey(defaultPermissions bool, userId int, friendlyName, filerequstId string) models.ApiKey {
 	if friendlyName == "" {
@@ -206,6 +251,11 @@ func apiModifyApiKey(w http.ResponseWriter, r requestParser, user models.User) {
 			sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "Insufficient user permission for owner to set this API permission")
 			return
 		}
+	case models.ApiPermManageWebhooks:
+		if !apiKeyOwner.IsAdmin() {
+			sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "Insufficient user permission for owner to set this API permission")
+			return
+		}
 	default:
 		// do nothing
 	}
@@ -279,7 +329,7 @@ func apiCreateUser(w http.ResponseWriter, r requestParser, user models.User) {
 		}
 		return
 	}
-	logging.LogUserCreation(newUser, user)
+	logging.LogUserCreation(newUser, user, request.Request)
 	_, _ = w.Write([]byte(newUser.ToJson()))
 }
 
@@ -338,7 +388,7 @@ func apiDeleteFile(w http.ResponseWriter, r requestParser, user models.User) {
 		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to delete this file")
 		return
 	}
-	logging.LogDelete(file, user)
+	logging.LogDelete(file, user, request.Request)
 	if request.DelaySeconds == 0 {
 		_ = storage.DeleteFile(request.Id, true)
 	} else {
@@ -365,7 +415,7 @@ func apiRestoreFile(w http.ResponseWriter, r requestParser, user models.User) {
 		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Invalid file ID provided or file has already been deleted.")
 		return
 	}
-	logging.LogRestore(file, user)
+	logging.LogRestore(file, user, request.Request)
 	outputFileJson(w, file)
 }
 
@@ -385,7 +435,7 @@ func apiChunkReserve(w http.ResponseWriter, r requestParser, _ models.User) {
 	if !ok {
 		panic("invalid parameter passed")
 	}
-	fileRequest, ok, status, errorCode, errorMsg := checkFileRequestAndApiKey(request.Id, request.ApiKey)
+	fileRequest, ok, status, errorCode, errorMsg := checkFileRequestAndApiKey(w, request.Request, request.Id, request.ApiKey)
 	if !ok {
 		sendError(w, status, errorCode, errorMsg)
 		return
@@ -398,6 +448,11 @@ func apiChunkReserve(w http.ResponseWriter, r requestParser, _ models.User) {
 		sendError(w, http.StatusTooManyRequests, errorcodes.RateLimited, "Too many reservations for this file request. Please wait a few seconds before reserving a new uuid.")
 		return
 	}
+	err := storage.CheckQuota(fileRequest.UserId, 0, chunkreservation.GetCount(fileRequest.Id))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, errorcodes.QuotaExceeded, err.Error())
+		return
+	}
 	uuid := chunkreservation.New(fileRequest.Id)
 	result, err := json.Marshal(struct {
 		Result string `json:"Result"`
@@ -412,13 +467,13 @@ func apiChunkUnreserve(w http.ResponseWriter, r requestParser, _ models.User) {
 	if !ok {
 		panic("invalid parameter passed")
 	}
-	fileRequest, ok, status, errorCode, errorMsg := checkFileRequestAndApiKey(request.Id, request.ApiKey)
+	fileRequest, ok, status, errorCode, errorMsg := checkFileRequestAndApiKey(w, request.Request, request.Id, request.ApiKey)
 	if !ok {
 		sendError(w, status, errorCode, errorMsg)
 		return
 	}
 	chunkreservation.SetComplete(fileRequest.Id, request.Uuid)
-	_ = chunking.DeleteChunk(request.Uuid)
+	_ = storage.DeleteChunk(request.Uuid)
 	_, _ = w.Write([]byte(`{"Result":"OK"}`))
 }
 
@@ -427,7 +482,7 @@ func apiChunkUploadRequestAdd(w http.ResponseWriter, r requestParser, user model
 	if !ok {
 		panic("invalid parameter passed")
 	}
-	fileRequest, ok, status, errorCode, errorMsg := checkFileRequestAndApiKey(request.FileRequestId, request.ApiKey)
+	fileRequest, ok, status, errorCode, errorMsg := checkFileRequestAndApiKey(w, request.Request, request.FileRequestId, request.ApiKey)
 	if !ok {
 		sendError(w, status, errorCode, errorMsg)
 		return
@@ -445,7 +500,10 @@ func apiChunkUploadRequestAdd(w http.ResponseWriter, r requestParser, user model
 	}
 }
 
-func checkFileRequestAndApiKey(fileRequestId, apiKey string) (models.FileRequest, bool, int, int, string) {
+// checkFileRequestAndApiKey returns true, if files can be uploaded to the file request with the given key.
+// If the file request requires a login, the user has to be authenticated through the request and be allowed
+// to access the file request
+func checkFileRequestAndApiKey(w http.ResponseWriter, r *http.Request, fileRequestId, apiKey string) (models.FileRequest, bool, int, int, string) {
 	fileRequest, ok := filerequest.Get(fileRequestId)
 	if !ok {
 		return models.FileRequest{}, false, http.StatusNotFound, errorcodes.NotFound, "FileRequest does not exist with the given ID"
@@ -459,6 +517,12 @@ func checkFileRequestAndApiKey(fileRequestId, apiKey string) (models.FileRequest
 	if !fileRequest.IsUnlimitedFiles() && fileRequest.UploadedFiles >= fileRequest.MaxFiles {
 		return models.FileRequest{}, false, http.StatusUnauthorized, errorcodes.CannotUploadMoreFiles, "Max file count has already been reached for this file request"
 	}
+	if fileRequest.RequireLogin {
+		recipient, ok := authentication.GetRecipient(w, r)
+		if !ok || !authentication.IsAccessGranted(fileRequest.GetAccessRestriction(), recipient) {
+			return models.FileRequest{}, false, http.StatusUnauthorized, errorcodes.NoPermission, "This file request requires a login by an authorised user"
+		}
+	}
 	return fileRequest, true, 0, 0, ""
 }
 
@@ -479,6 +543,14 @@ func processNewChunk(w http.ResponseWriter, request chunkParams, maxFileSizeMb i
 	return http.StatusOK, 0, ""
 }
 
+func apiTus(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramTus)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	tus.Process(w, request.Request, user)
+}
+
 func apiChunkComplete(w http.ResponseWriter, r requestParser, user models.User) {
 	request, ok := r.(*paramChunkComplete)
 	if !ok {
@@ -492,27 +564,47 @@ func apiChunkComplete(w http.ResponseWriter, r requestParser, user models.User)
 		request.IsE2E,
 		request.FileSize,
 		"")
+	if request.IsPublishAtSet {
+		err := fileupload.SetPublishDate(&uploadParams, request.PublishAt, request.ExpiryFromPublish)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, errorcodes.InvalidUserInput, err.Error())
+			return
+		}
+	}
+	fileupload.SetAccessRestriction(&uploadParams, request.RequireLogin, request.AllowedUsers, request.AllowedGroups)
+	if len(request.Recipients) > 0 && !mail.IsEnabled() {
+		sendError(w, http.StatusBadRequest, errorcodes.NotEnabled, "No SMTP server has been configured")
+		return
+	}
+	completeAndShare := func(w http.ResponseWriter) {
+		file, ok := doBlockingPartCompleteChunk(w, request.Request, request.Uuid, request.FileHeader, user, uploadParams)
+		if ok {
+			sendDownloadLink(file, user, request.Recipients, request.Message, request.Request)
+		}
+	}
 	if request.IsNonBlocking {
-		go doBlockingPartCompleteChunk(nil, request.Uuid, request.FileHeader, user, uploadParams)
+		go completeAndShare(nil)
 		_, _ = io.WriteString(w, "{\"result\":\"OK\"}")
 		return
 	}
-	doBlockingPartCompleteChunk(w, request.Uuid, request.FileHeader, user, uploadParams)
+	completeAndShare(w)
 }
 
-func doBlockingPartCompleteChunk(w http.ResponseWriter, uuid string, fileHeader chunking.FileHeader, user models.User, uploadParameters models.UploadParameters) {
+// doBlockingPartCompleteChunk stores the uploaded chunk as a new file. Returns false if the file could not be created
+func doBlockingPartCompleteChunk(w http.ResponseWriter, r *http.Request, uuid string, fileHeader chunking.FileHeader, user models.User, uploadParameters models.UploadParameters) (models.File, bool) {
 	file, err := fileupload.CompleteChunk(uuid, fileHeader, user.Id, uploadParameters)
 	if err != nil {
-		_ = chunking.DeleteChunk(uuid)
+		_ = storage.DeleteChunk(uuid)
 		sendError(w, http.StatusBadRequest, errorcodes.UnspecifiedError, err.Error())
-		return
+		return models.File{}, false
 	}
 	if uploadParameters.FileRequestId != "" {
 		chunkreservation.SetComplete(uploadParameters.FileRequestId, uuid)
 	}
 	fr, _ := filerequest.Get(uploadParameters.FileRequestId)
-	logging.LogUpload(file, user, fr)
+	logging.LogUpload(file, user, fr, r)
 	outputFileJson(w, file)
+	return file, true
 }
 
 func apiChunkUploadRequestComplete(w http.ResponseWriter, r requestParser, user models.User) {
@@ -520,7 +612,7 @@ func apiChunkUploadRequestComplete(w http.ResponseWriter, r requestParser, user
 	if !ok {
 		panic("invalid parameter passed")
 	}
-	fileRequest, ok, status, errorCode, errorMsg := checkFileRequestAndApiKey(request.FileRequestId, request.ApiKey)
+	fileRequest, ok, status, errorCode, errorMsg := checkFileRequestAndApiKey(w, request.Request, request.FileRequestId, request.ApiKey)
 	if !ok {
 		sendError(w, status, errorCode, errorMsg)
 		return
@@ -529,11 +621,11 @@ func apiChunkUploadRequestComplete(w http.ResponseWriter, r requestParser, user
 		0, "", true, true,
 		false, request.FileSize, fileRequest.Id)
 	if request.IsNonBlocking {
-		go doBlockingPartCompleteChunk(nil, request.Uuid, request.FileHeader, user, uploadParams)
+		go doBlockingPartCompleteChunk(nil, request.Request, request.Uuid, request.FileHeader, user, uploadParams)
 		_, _ = io.WriteString(w, "{\"result\":\"OK\"}")
 		return
 	}
-	doBlockingPartCompleteChunk(w, request.Uuid, request.FileHeader, user, uploadParams)
+	doBlockingPartCompleteChunk(w, request.Request, request.Uuid, request.FileHeader, user, uploadParams)
 }
 
 func apiVersionInfo(w http.ResponseWriter, _ requestParser, _ models.User) {
@@ -566,29 +658,49 @@ func apiList(w http.ResponseWriter, r requestParser, user models.User) {
 	if !ok {
 		panic("invalid parameter passed")
 	}
-	validFiles := getFilesForUser(user, request.ShowFileRequests)
+	query := request.Query
+	if !user.HasPermission(models.UserPermListOtherUploads) {
+		if query.UserId != 0 && query.UserId != user.Id {
+			sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to view files of other users")
+			return
+		}
+		query.UserId = user.Id
+	}
+	validFiles, nextCursor := queryFiles(query)
+	if nextCursor != "" {
+		w.Header().Set("X-Next-Cursor", nextCursor)
+	}
 	result, err := json.Marshal(validFiles)
 	helper.Check(err)
 	_, _ = w.Write(result)
 }
 
 func getFilesForUser(user models.User, includeUploadRequests bool) []models.FileApiOutput {
+	query := models.FileQuery{IncludeFileRequests: includeUploadRequests}
+	if !user.HasPermission(models.UserPermListOtherUploads) {
+		query.UserId = user.Id
+	}
+	validFiles, _ := queryFiles(query)
+	return validFiles
+}
+
+// queryFiles returns all files that match the query and are not expired. If there are more results
+// than the query limit, a cursor for requesting the next page is returned as well
+func queryFiles(query models.FileQuery) ([]models.FileApiOutput, string) {
 	var validFiles []models.FileApiOutput
-	timeNow := time.Now().Unix()
+	var nextCursor string
+	query.HideExpiredAt = time.Now().Unix()
 	config := configuration.Get()
-	for _, element := range database.GetAllMetadata() {
-		if !includeUploadRequests && element.IsFileRequest() {
-			continue
-		}
-		if element.UserId == user.Id || user.HasPermission(models.UserPermListOtherUploads) {
-			if !storage.IsExpiredFile(element, timeNow) {
-				file, err := element.ToFileApiOutput(config.ServerUrl, config.IncludeFilename)
-				helper.Check(err)
-				validFiles = append(validFiles, file)
-			}
-		}
+	queryResult := database.QueryMetaData(query)
+	for _, element := range queryResult.Files {
+		file, err := element.ToFileApiOutput(config.ServerUrl, config.IncludeFilename)
+		helper.Check(err)
+		validFiles = append(validFiles, file)
 	}
-	return validFiles
+	if queryResult.HasMore && len(queryResult.Files) > 0 {
+		nextCursor = query.GetCursor(queryResult.Files[len(queryResult.Files)-1])
+	}
+	return validFiles, nextCursor
 }
 
 func apiListSingle(w http.ResponseWriter, r requestParser, user models.User) {
@@ -763,11 +875,59 @@ func apiChangeFileOwner(w http.ResponseWriter, r requestParser, user models.User
 	outputFileApiInfo(w, file)
 }
 
+func apiSendLink(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramFilesSendLink)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	if !mail.IsEnabled() {
+		sendError(w, http.StatusBadRequest, errorcodes.NotEnabled, "No SMTP server has been configured")
+		return
+	}
+	file, ok := storage.GetFile(request.Id)
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Invalid id provided.")
+		return
+	}
+	if file.UserId != user.Id && !user.HasPermission(models.UserPermEditOtherUploads) {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to share this file")
+		return
+	}
+	if file.IsFileRequest() {
+		sendError(w, http.StatusBadRequest, errorcodes.UnsupportedFile, "Cannot share a file request upload")
+		return
+	}
+	if file.Encryption.IsEndToEndEncrypted {
+		sendError(w, http.StatusBadRequest, errorcodes.EndToEndNotSupported, "Download links of end-to-end encrypted files cannot be sent by email")
+		return
+	}
+	sendDownloadLink(file, user, request.Recipients, request.Message, request.Request)
+	_, _ = io.WriteString(w, "{\"result\":\"OK\"}")
+}
+
+// sendDownloadLink sends the download link of the file to the recipients, if the file can be downloaded
+func sendDownloadLink(file models.File, user models.User, recipients []string, message string, r *http.Request) {
+	if len(recipients) == 0 || file.IsHeld() {
+		return
+	}
+	err := mail.SendDownloadLink(file, user, recipients, message)
+	if err != nil {
+		fmt.Println("Could not send download link: " + err.Error())
+		return
+	}
+	logging.LogShare(file, recipients, user, r)
+}
+
 func apiReplaceFile(w http.ResponseWriter, r requestParser, user models.User) {
 	request, ok := r.(*paramFilesReplace)
 	if !ok {
 		panic("invalid parameter passed")
 	}
+	// The file with the new content is locked as well, as it might be deleted
+	lockedIds := []string{request.Id, request.IdNewContent}
+	apimutex.LockAll(apimutex.TypeMetaData, lockedIds)
+	defer apimutex.UnlockAll(apimutex.TypeMetaData, lockedIds)
+
 	fileOriginal, ok := storage.GetFile(request.Id)
 	if !ok {
 		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Invalid id provided.")
@@ -797,7 +957,7 @@ func apiReplaceFile(w http.ResponseWriter, r requestParser, user models.User) {
 		return
 	}
 
-	modifiedFile, err := storage.ReplaceFile(request.Id, request.IdNewContent, request.DeleteNewFile)
+	modifiedFile, err := storage.ReplaceFile(request.Id, request.IdNewContent, request.DeleteNewFile, user.Id)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrorReplaceE2EFile):
@@ -809,7 +969,86 @@ func apiReplaceFile(w http.ResponseWriter, r requestParser, user models.User) {
 		}
 		return
 	}
-	logging.LogReplace(fileOriginal, modifiedFile, user)
+	logging.LogReplace(fileOriginal, modifiedFile, user, request.Request)
+	outputFileApiInfo(w, modifiedFile)
+}
+
+func apiListFileVersions(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramFilesVersionsList)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	file, ok := storage.GetFile(request.Id)
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Invalid id provided.")
+		return
+	}
+	if file.UserId != user.Id && !user.HasPermission(models.UserPermListOtherUploads) {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to view this file")
+		return
+	}
+	result := make([]models.FileVersionApiOutput, 0)
+	for _, version := range storage.GetFileVersions(file.Id) {
+		result = append(result, version.ToApiOutput())
+	}
+	output, err := json.Marshal(result)
+	helper.Check(err)
+	_, _ = w.Write(output)
+}
+
+func apiDownloadFileVersion(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramFilesVersionsDownload)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	file, statusCode, errCode, errMessage := checkDownloadAllowed(request.Id, user)
+	if statusCode != 0 {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		sendError(w, statusCode, errCode, errMessage)
+		return
+	}
+	version, ok := storage.GetFileVersion(file.Id, request.VersionId)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "version not found")
+		return
+	}
+	storage.ServeFileVersion(version, w, request.WebRequest)
+}
+
+func apiRollbackFile(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramFilesVersionsRollback)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	apimutex.Lock(apimutex.TypeMetaData, request.Id)
+	defer apimutex.Unlock(apimutex.TypeMetaData, request.Id)
+
+	file, ok := storage.GetFile(request.Id)
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Invalid id provided.")
+		return
+	}
+	if file.UserId != user.Id && !user.HasPermission(models.UserPermReplaceOtherUploads) {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to replace this file")
+		return
+	}
+	version, ok := storage.GetFileVersion(file.Id, request.VersionId)
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Invalid version id provided.")
+		return
+	}
+	modifiedFile, err := storage.RollbackFile(file.Id, version.Id, user.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrorFileNotFound), errors.Is(err, storage.ErrorVersionNotFound):
+			sendError(w, http.StatusNotFound, errorcodes.NotFound, err.Error())
+		default:
+			sendError(w, http.StatusBadRequest, errorcodes.InvalidUserInput, err.Error())
+		}
+		return
+	}
+	logging.LogRollback(modifiedFile, version, user, request.Request)
 	outputFileApiInfo(w, modifiedFile)
 }
 
@@ -851,11 +1090,21 @@ func apiModifyUser(w http.ResponseWriter, r requestParser, user models.User) {
 		sendError(w, http.StatusBadRequest, errorcodes.ResourceCanNotBeEdited, "Cannot modify yourself")
 		return
 	}
-	if request.GrantPermission && !user.HasPermission(request.Permission) {
+	if request.IsQuotaChange && !user.IsAdmin() {
+		sendError(w, http.StatusUnauthorized, errorcodes.AdminOnly, "Only admins can change quotas")
+		return
+	}
+	if request.IsPermissionChange && request.GrantPermission && !user.HasPermission(request.Permission) {
 		sendError(w, http.StatusBadRequest, errorcodes.NoPermission, "Cannot grant rights the user does not have")
 		return
 	}
-	logging.LogUserEdit(userEdit, user)
+	logging.LogUserEdit(userEdit, user, request.Request)
+	if request.IsQuotaChange {
+		modifyUserQuota(userEdit.Id, request)
+	}
+	if !request.IsPermissionChange {
+		return
+	}
 	if request.GrantPermission {
 		if !userEdit.HasPermission(request.Permission) {
 			userEdit.GrantPermission(request.Permission)
@@ -866,8 +1115,26 @@ func apiModifyUser(w http.ResponseWriter, r requestParser, user models.User) {
 	if userEdit.HasPermission(request.Permission) {
 		userEdit.RemovePermission(request.Permission)
 		database.SaveUser(userEdit, false)
-		updateApiKeyPermsOnUserPermChange(userEdit.Id, request.Permission)
+		users.UpdateApiKeyPermsOnUserPermChange(userEdit.Id, request.Permission)
+	}
+}
+
+// modifyUserQuota changes all limits of the user quota that were passed in the request
+func modifyUserQuota(userId int, request *paramUserModify) {
+	quota := storage.GetUserQuota(userId)
+	if request.foundHeaders["quotaStorageMb"] {
+		quota.MaxStorageMb = request.QuotaStorageMb
+	}
+	if request.foundHeaders["quotaFiles"] {
+		quota.MaxFiles = request.QuotaFiles
+	}
+	if request.foundHeaders["quotaExpiryDays"] {
+		quota.MaxExpiryDays = request.QuotaExpiryDays
 	}
+	if request.foundHeaders["quotaDownloads"] {
+		quota.MaxDownloads = request.QuotaDownloads
+	}
+	storage.SaveUserQuota(quota)
 }
 
 func apiChangeUserRank(w http.ResponseWriter, r requestParser, user models.User) {
@@ -896,47 +1163,12 @@ func apiChangeUserRank(w http.ResponseWriter, r requestParser, user models.User)
 		return
 	}
 
-	userEdit.UserLevel = request.NewRank
-	switch request.NewRank {
-	case models.UserLevelAdmin:
-		userEdit.Permissions = models.UserPermissionAll
-	case models.UserLevelUser:
-		userEdit.Permissions = models.UserPermissionNone
-		updateApiKeyPermsOnUserPermChange(userEdit.Id, models.UserPermReplaceUploads)
-		updateApiKeyPermsOnUserPermChange(userEdit.Id, models.UserPermManageUsers)
-		updateApiKeyPermsOnUserPermChange(userEdit.Id, models.UserPermManageLogs)
-		updateApiKeyPermsOnUserPermChange(userEdit.Id, models.UserPermGuestUploads)
-	default:
+	userEdit, err := users.SetRank(userEdit, request.NewRank)
+	if err != nil {
 		sendError(w, http.StatusBadRequest, errorcodes.InvalidUserInput, "invalid rank sent")
 		return
 	}
-	logging.LogUserEdit(userEdit, user)
-	database.SaveUser(userEdit, false)
-}
-
-func updateApiKeyPermsOnUserPermChange(userId int, userPerm models.UserPermission) {
-	var affectedPermission models.ApiPermission
-	switch userPerm {
-	case models.UserPermManageUsers:
-		affectedPermission = models.ApiPermManageUsers
-	case models.UserPermReplaceUploads:
-		affectedPermission = models.ApiPermReplace
-	case models.UserPermManageLogs:
-		affectedPermission = models.ApiPermManageLogs
-	case models.UserPermGuestUploads:
-		affectedPermission = models.ApiPermManageFileRequests
-	default:
-		return
-	}
-	for _, apiKey := range database.GetAllApiKeys() {
-		if apiKey.UserId != userId {
-			continue
-		}
-		if apiKey.HasPermission(affectedPermission) {
-			apiKey.RemovePermission(affectedPermission)
-			database.SaveApiKey(apiKey)
-		}
-	}
+	logging.LogUserEdit(userEdit, user, request.Request)
 }
 
 func apiResetPassword(w http.ResponseWriter, r requestParser, user models.User) {
@@ -972,6 +1204,32 @@ func apiResetPassword(w http.ResponseWriter, r requestParser, user models.User)
 	_, _ = w.Write(result)
 }
 
+func apiResetTwoFactor(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramUserResetTwoFactor)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	if !user.IsAdmin() {
+		sendError(w, http.StatusUnauthorized, errorcodes.AdminOnly, "Only admins can reset two-factor authentication")
+		return
+	}
+	userToEdit, ok := isValidUserForEditing(w, request.Id)
+	if !ok {
+		return
+	}
+	if userToEdit.IsSuperAdmin() {
+		sendError(w, http.StatusBadRequest, errorcodes.ResourceCanNotBeEdited, "Cannot reset two-factor authentication of super admin")
+		return
+	}
+	if userToEdit.IsSameUser(user.Id) {
+		sendError(w, http.StatusBadRequest, errorcodes.ResourceCanNotBeEdited, "Cannot reset two-factor authentication of yourself")
+		return
+	}
+	twofactor.Disable(userToEdit.Id)
+	logging.LogTwoFactorReset(userToEdit, user, request.Request)
+	_, _ = w.Write([]byte("{\"result\":\"OK\"}"))
+}
+
 func apiDeleteUser(w http.ResponseWriter, r requestParser, user models.User) {
 	request, ok := r.(*paramUserDelete)
 	if !ok {
@@ -989,8 +1247,13 @@ func apiDeleteUser(w http.ResponseWriter, r requestParser, user models.User) {
 		sendError(w, http.StatusBadRequest, errorcodes.ResourceCanNotBeEdited, "Cannot delete yourself")
 		return
 	}
-	logging.LogUserDeletion(userToDelete, user)
+	logging.LogUserDeletion(userToDelete, user, request.Request)
+	DeleteUser(userToDelete, user.Id, request.DeleteFiles)
+}
 
+// DeleteUser removes the user with all sessions, API keys and settings. If deleteFiles is true, all files and
+// file requests of the user are deleted as well, otherwise they are transferred to the user with the ID newOwnerId
+func DeleteUser(userToDelete models.User, newOwnerId int, deleteFiles bool) {
 	database.DeleteAllSessionsByUser(userToDelete.Id)
 
 	for _, apiKey := range database.GetAllApiKeys() {
@@ -1003,10 +1266,10 @@ func apiDeleteUser(w http.ResponseWriter, r requestParser, user models.User) {
 
 	for _, fRequest := range database.GetAllFileRequests() {
 		if fRequest.UserId == userToDelete.Id {
-			if request.DeleteFiles {
+			if deleteFiles {
 				filerequest.Delete(fRequest)
 			} else {
-				fRequest.UserId = user.Id
+				fRequest.UserId = newOwnerId
 				database.SaveFileRequest(fRequest)
 			}
 		}
@@ -1014,14 +1277,16 @@ func apiDeleteUser(w http.ResponseWriter, r requestParser, user models.User) {
 
 	for _, file := range database.GetAllMetadata() {
 		if file.UserId == userToDelete.Id {
-			if request.DeleteFiles {
+			if deleteFiles {
 				database.DeleteMetaData(file.Id)
 			} else {
-				file.UserId = user.Id
+				file.UserId = newOwnerId
 				database.SaveMetaData(file)
 			}
 		}
 	}
+	twofactor.Disable(userToDelete.Id)
+	database.DeleteUserQuota(userToDelete.Id)
 	database.DeleteUser(userToDelete.Id)
 }
 
@@ -1052,23 +1317,39 @@ func apiLogsGet(w http.ResponseWriter, r requestParser, _ models.User) {
 	_, _ = w.Write(resultJson)
 }
 
+func apiLogsQuery(w http.ResponseWriter, r requestParser, _ models.User) {
+	request, ok := r.(*paramLogsQuery)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	queryResult := database.QueryAuditEvents(request.Query)
+	if queryResult.HasMore && len(queryResult.Events) > 0 {
+		w.Header().Set("X-Next-Cursor", strconv.FormatInt(queryResult.Events[len(queryResult.Events)-1].Id, 10))
+	}
+	result, err := json.Marshal(queryResult.Events)
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
 func apiLogSystemStatus(w http.ResponseWriter, _ requestParser, _ models.User) {
 	result := struct {
-		Uptime                int64  `json:"uptime"`
-		TrafficRecordingSince int64  `json:"trafficRecordingSince"`
-		CpuLoad               int    `json:"cpuLoad"`
-		MemoryUsagePercentage int    `json:"memoryUsagePercentage"`
-		DiskUsagePercentage   int    `json:"diskUsagePercentage"`
-		ActiveFiles           int    `json:"activeFiles"`
-		MemoryUsed            uint64 `json:"memoryUsed"`
-		MemoryTotal           uint64 `json:"memoryTotal"`
-		DiskUsed              uint64 `json:"diskUsed"`
-		DiskTotal             uint64 `json:"diskTotal"`
-		DataServed            uint64 `json:"dataServed"`
+		Uptime                int64               `json:"uptime"`
+		TrafficRecordingSince int64               `json:"trafficRecordingSince"`
+		CpuLoad               int                 `json:"cpuLoad"`
+		MemoryUsagePercentage int                 `json:"memoryUsagePercentage"`
+		DiskUsagePercentage   int                 `json:"diskUsagePercentage"`
+		ActiveFiles           int                 `json:"activeFiles"`
+		MemoryUsed            uint64              `json:"memoryUsed"`
+		MemoryTotal           uint64              `json:"memoryTotal"`
+		DiskUsed              uint64              `json:"diskUsed"`
+		DiskTotal             uint64              `json:"diskTotal"`
+		DataServed            uint64              `json:"dataServed"`
+		CleanupLeader         models.LeaderStatus `json:"cleanupLeader"`
 	}{
-		Uptime:      serverstats.GetUptime(),
-		CpuLoad:     serverstats.GetCpuUsage(),
-		ActiveFiles: serverstats.GetTotalFiles(),
+		Uptime:        serverstats.GetUptime(),
+		CpuLoad:       serverstats.GetCpuUsage(),
+		ActiveFiles:   serverstats.GetTotalFiles(),
+		CleanupLeader: leader.GetStatus(),
 	}
 	result.DataServed, result.TrafficRecordingSince = serverstats.GetCurrentTraffic()
 	_, result.MemoryUsed, result.MemoryTotal, result.MemoryUsagePercentage = serverstats.GetMemoryInfo()
@@ -1082,6 +1363,117 @@ func apiLogSystemStatus(w http.ResponseWriter, _ requestParser, _ models.User) {
 	_, _ = w.Write(resultJson)
 }
 
+func apiEncryptionRotateKey(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramEncryptionRotateKey)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	if !user.IsSuperAdmin() {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "Only the super admin can rotate the encryption key")
+		return
+	}
+	result, err := keyrotation.RotateMasterKeyOnline(request.NewPassword)
+	if err != nil {
+		sendKeyRotationError(w, err)
+		return
+	}
+	logging.LogKeyRotation(user, request.Request, result.RewrappedKeys, len(result.FailedFiles))
+	resultJson, err := json.Marshal(result)
+	helper.Check(err)
+	_, _ = w.Write(resultJson)
+}
+
+func apiEncryptionEncryptFiles(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramEncryptionEncryptFiles)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	if !user.IsSuperAdmin() {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "Only the super admin can encrypt existing files")
+		return
+	}
+	result, err := keyrotation.EncryptPlaintextFiles()
+	if err != nil {
+		sendKeyRotationError(w, err)
+		return
+	}
+	logging.LogEncryptFiles(user, request.Request, result.EncryptedFiles, len(result.FailedFiles))
+	resultJson, err := json.Marshal(result)
+	helper.Check(err)
+	_, _ = w.Write(resultJson)
+}
+
+func sendKeyRotationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, keyrotation.ErrorInProgress), errors.Is(err, keyrotation.ErrorMultipleInstances):
+		sendError(w, http.StatusConflict, errorcodes.UnspecifiedError, err.Error())
+	case errors.Is(err, keyrotation.ErrorKeyNotLoaded), errors.Is(err, keyrotation.ErrorConfigNotSaved):
+		sendError(w, http.StatusInternalServerError, errorcodes.InternalServer, err.Error())
+	default:
+		sendError(w, http.StatusBadRequest, errorcodes.InvalidUserInput, err.Error())
+	}
+}
+
+func apiScrubStart(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramScrubStart)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	if request.Action != models.ScrubActionNone && !user.IsSuperAdmin() {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "Only the super admin can quarantine or delete stored files")
+		return
+	}
+	err := scrub.Start(request.Action)
+	if err != nil {
+		sendScrubError(w, err)
+		return
+	}
+	logging.LogScrubStart(user, request.Request, request.Action)
+	_, _ = w.Write([]byte(`{"Result":"OK"}`))
+}
+
+func apiScrubReport(w http.ResponseWriter, _ requestParser, _ models.User) {
+	report, ok := scrub.GetReport()
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, scrub.ErrorNoReport.Error())
+		return
+	}
+	resultJson, err := json.Marshal(report)
+	helper.Check(err)
+	_, _ = w.Write(resultJson)
+}
+
+func apiScrubResolve(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramScrubResolve)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	if !user.IsSuperAdmin() {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "Only the super admin can quarantine or delete stored files")
+		return
+	}
+	report, resolved, err := scrub.Resolve(request.Action)
+	if err != nil {
+		sendScrubError(w, err)
+		return
+	}
+	logging.LogScrubResolve(user, request.Request, request.Action, resolved)
+	resultJson, err := json.Marshal(report)
+	helper.Check(err)
+	_, _ = w.Write(resultJson)
+}
+
+func sendScrubError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, scrub.ErrorInProgress):
+		sendError(w, http.StatusConflict, errorcodes.UnspecifiedError, err.Error())
+	case errors.Is(err, scrub.ErrorNoReport):
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, err.Error())
+	default:
+		sendError(w, http.StatusBadRequest, errorcodes.InvalidUserInput, err.Error())
+	}
+}
+
 func apiLogResetTraffic(w http.ResponseWriter, _ requestParser, _ models.User) {
 	serverstats.ClearTraffic()
 	_, _ = w.Write([]byte(`{"Result":"OK"}`))
@@ -1144,7 +1536,7 @@ func apiURequestDelete(w http.ResponseWriter, r requestParser, user models.User)
 		return
 	}
 	filerequest.Delete(uploadRequest)
-	logging.LogDeleteFileRequest(uploadRequest, user)
+	logging.LogDeleteFileRequest(uploadRequest, user, request.Request)
 	_, _ = w.Write([]byte("{\"result\":\"OK\"}"))
 }
 
@@ -1219,12 +1611,21 @@ func apiURequestSave(w http.ResponseWriter, r requestParser, user models.User) {
 	if request.IsNotesSet {
 		uploadRequest.Notes = request.Notes
 	}
+	if request.IsRequireLoginSet {
+		uploadRequest.RequireLogin = request.RequireLogin
+	}
+	if request.IsAllowedUsersSet {
+		uploadRequest.AllowedUsers = models.CleanAccessList(request.AllowedUsers)
+	}
+	if request.IsAllowedGroupsSet {
+		uploadRequest.AllowedGroups = models.CleanAccessList(request.AllowedGroups)
+	}
 	database.SaveFileRequest(uploadRequest)
 	uploadRequest, ok = filerequest.Get(uploadRequest.Id)
 	if isNewRequest {
-		logging.LogCreateFileRequest(uploadRequest, user)
+		logging.LogCreateFileRequest(uploadRequest, user, request.Request)
 	} else {
-		logging.LogEditFileRequest(uploadRequest, user)
+		logging.LogEditFileRequest(uploadRequest, user, request.Request)
 	}
 	result, err := json.Marshal(uploadRequest)
 	helper.Check(err)
@@ -1263,18 +1664,277 @@ func apiUploadRequestListSingle(w http.ResponseWriter, r requestParser, user mod
 	_, _ = w.Write(result)
 }
 
-func isAuthorisedForApi(r *http.Request, routing apiRoute) (models.User, bool) {
+func apiWebhooksList(w http.ResponseWriter, _ requestParser, _ models.User) {
+	webhooks := database.GetAllWebhooks()
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	result, err := json.Marshal(webhooks)
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiWebhooksAdd(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramWebhookAdd)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	webhook := request.Webhook
+	webhook.Id = helper.GenerateRandomString(15)
+	webhook.Secret = helper.GenerateRandomString(40)
+	webhook.UserId = user.Id
+	webhook.CreationDate = time.Now().Unix()
+	database.SaveWebhook(webhook)
+	webhooks.Reload()
+	result, err := json.Marshal(webhook)
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiWebhooksModify(w http.ResponseWriter, r requestParser, _ models.User) {
+	request, ok := r.(*paramWebhookModify)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	apimutex.Lock(apimutex.TypeWebhook, request.Id)
+	defer apimutex.Unlock(apimutex.TypeWebhook, request.Id)
+
+	webhook, ok := database.GetWebhook(request.Id)
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Webhook does not exist with the given ID")
+		return
+	}
+	if request.IsUrlSet {
+		webhook.Url = request.Changes.Url
+	}
+	if request.IsEventsSet {
+		webhook.Events = request.Changes.Events
+	}
+	if request.RegenerateSecret {
+		webhook.Secret = helper.GenerateRandomString(40)
+	}
+	database.SaveWebhook(webhook)
+	webhooks.Reload()
+	if !request.RegenerateSecret {
+		webhook.Secret = ""
+	}
+	result, err := json.Marshal(webhook)
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiWebhooksDelete(w http.ResponseWriter, r requestParser, _ models.User) {
+	request, ok := r.(*paramWebhookDelete)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	_, ok = database.GetWebhook(request.Id)
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Webhook does not exist with the given ID")
+		return
+	}
+	database.DeleteWebhook(request.Id)
+	webhooks.Reload()
+	_, _ = w.Write([]byte("{\"result\":\"OK\"}"))
+}
+
+func apiBundleList(w http.ResponseWriter, _ requestParser, user models.User) {
+	serverUrl := configuration.Get().ServerUrl
+	result := make([]models.BundleApiOutput, 0)
+	for _, savedBundle := range database.GetAllBundles() {
+		if savedBundle.IsExpired() {
+			continue
+		}
+		if savedBundle.UserId != user.Id && !user.HasPermission(models.UserPermListOtherUploads) {
+			continue
+		}
+		result = append(result, savedBundle.ToApiOutput(serverUrl))
+	}
+	output, err := json.Marshal(result)
+	helper.Check(err)
+	_, _ = w.Write(output)
+}
+
+func apiBundleCreate(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramBundleCreate)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	if !sendErrorOnInvalidBundleFiles(w, request.Ids, user) {
+		return
+	}
+	newBundle := bundle.New(user, request.Name, request.AllowedDownloads, request.ExpiryDays, request.Password)
+	newBundle.AddFiles(request.Ids)
+	database.SaveBundle(newBundle)
+	outputBundle(w, newBundle)
+}
+
+func apiBundleAddFiles(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramBundleModifyFiles)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	apimutex.Lock(apimutex.TypeBundle, request.Id)
+	defer apimutex.Unlock(apimutex.TypeBundle, request.Id)
+
+	savedBundle, ok := getBundleForEdit(w, request.Id, user)
+	if !ok {
+		return
+	}
+	if !sendErrorOnInvalidBundleFiles(w, request.Ids, user) {
+		return
+	}
+	savedBundle.AddFiles(request.Ids)
+	database.SaveBundle(savedBundle)
+	outputBundle(w, savedBundle)
+}
+
+func apiBundleRemoveFiles(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramBundleModifyFiles)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	apimutex.Lock(apimutex.TypeBundle, request.Id)
+	defer apimutex.Unlock(apimutex.TypeBundle, request.Id)
+
+	savedBundle, ok := getBundleForEdit(w, request.Id, user)
+	if !ok {
+		return
+	}
+	savedBundle.RemoveFiles(request.Ids)
+	database.SaveBundle(savedBundle)
+	outputBundle(w, savedBundle)
+}
+
+func apiBundleDelete(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramBundleDelete)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	_, ok = getBundleForEdit(w, request.Id, user)
+	if !ok {
+		return
+	}
+	database.DeleteBundle(request.Id)
+	_, _ = w.Write([]byte("{\"result\":\"OK\"}"))
+}
+
+// getBundleForEdit returns the bundle with the given ID, if the user is allowed to edit it.
+// Otherwise, an error is sent and false is returned
+func getBundleForEdit(w http.ResponseWriter, id string, user models.User) (models.Bundle, bool) {
+	savedBundle, ok := bundle.Get(id)
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Bundle does not exist with the given ID")
+		return models.Bundle{}, false
+	}
+	if !bundle.IsAllowedToEdit(savedBundle, user) {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to edit this bundle")
+		return models.Bundle{}, false
+	}
+	return savedBundle, true
+}
+
+// sendErrorOnInvalidBundleFiles returns true, if all files can be added to a bundle.
+// Otherwise, an error is sent and false is returned
+func sendErrorOnInvalidBundleFiles(w http.ResponseWriter, fileIds []string, user models.User) bool {
+	err := bundle.CheckFiles(fileIds, user)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, bundle.ErrorFileNotFound):
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "A file with such an ID could not be found")
+	case errors.Is(err, bundle.ErrorNoPermission):
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to share this file")
+	default:
+		sendError(w, http.StatusBadRequest, errorcodes.UnsupportedFile, err.Error())
+	}
+	return false
+}
+
+func outputBundle(w http.ResponseWriter, savedBundle models.Bundle) {
+	result, err := json.Marshal(savedBundle.ToApiOutput(configuration.Get().ServerUrl))
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiShareLinkList(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramShareLinkList)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	file, ok := storage.GetFile(request.FileId)
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "A file with such an ID could not be found")
+		return
+	}
+	if file.UserId != user.Id && !user.HasPermission(models.UserPermListOtherUploads) {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to view this file")
+		return
+	}
+	serverUrl := configuration.Get().ServerUrl
+	result := make([]models.ShareLinkApiOutput, 0)
+	for _, link := range sharelink.GetAllForFile(file.Id) {
+		result = append(result, link.ToApiOutput(serverUrl))
+	}
+	output, err := json.Marshal(result)
+	helper.Check(err)
+	_, _ = w.Write(output)
+}
+
+func apiShareLinkCreate(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramShareLinkCreate)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	file, err := sharelink.GetFileForNewLink(request.FileId, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, sharelink.ErrorFileNotFound):
+			sendError(w, http.StatusNotFound, errorcodes.NotFound, "A file with such an ID could not be found")
+		case errors.Is(err, sharelink.ErrorNoPermission):
+			sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to share this file")
+		default:
+			sendError(w, http.StatusBadRequest, errorcodes.UnsupportedFile, err.Error())
+		}
+		return
+	}
+	link := sharelink.New(file, user, request.Name, request.AllowedDownloads, request.ExpiryDays, request.Password)
+	database.SaveShareLink(link)
+	result, err := json.Marshal(link.ToApiOutput(configuration.Get().ServerUrl))
+	helper.Check(err)
+	_, _ = w.Write(result)
+}
+
+func apiShareLinkDelete(w http.ResponseWriter, r requestParser, user models.User) {
+	request, ok := r.(*paramShareLinkDelete)
+	if !ok {
+		panic("invalid parameter passed")
+	}
+	link, ok := database.GetShareLink(request.Id)
+	if !ok {
+		sendError(w, http.StatusNotFound, errorcodes.NotFound, "Share link does not exist with the given ID")
+		return
+	}
+	if !sharelink.IsAllowedToEdit(link, user) {
+		sendError(w, http.StatusUnauthorized, errorcodes.NoPermission, "No permission to delete this share link")
+		return
+	}
+	database.DeleteShareLink(link.Id)
+	_, _ = w.Write([]byte("{\"result\":\"OK\"}"))
+}
+
+func isAuthorisedForApi(r *http.Request, routing apiRoute) (models.User, models.ApiKey, bool) {
 	keyId := r.Header.Get("apikey")
 	ratelimiter.WaitOnApiAuthentication(logging.GetIpAddress(r))
 	user, apiKey, ok := isValidApiKey(keyId, true, routing.ApiPerm)
 	if !ok {
-		return models.User{}, false
+		return models.User{}, models.ApiKey{}, false
 	}
 	// Returns false if a public upload key is used for non-public api call or vice versa
 	if routing.IsFileRequestApi != apiKey.IsUploadRequestKey() {
-		return models.User{}, false
+		return models.User{}, models.ApiKey{}, false
 	}
-	return user, true
+	return user, apiKey, true
 }
 
 func sendError(w http.ResponseWriter, statusCode, errorCode int, errorMessage string) {
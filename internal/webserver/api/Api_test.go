@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"io"
 	"mime/multipart"
@@ -9,17 +10,27 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/database/leader"
+	"github.com/forceu/gokapi/internal/encryption"
 	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/logging/mail"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/keyrotation"
+	"github.com/forceu/gokapi/internal/storage/scrub"
 	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/smtpstub"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
+	"github.com/forceu/gokapi/internal/webserver/errorHandling/errorcodes"
 	"github.com/forceu/gokapi/internal/webserver/ratelimiter"
 )
 
@@ -378,6 +389,8 @@ func TestUserChangeRank(t *testing.T) {
 	user, ok := database.GetUser(idAdmin)
 	test.IsEqualBool(t, ok, true)
 	test.IsEqual(t, user.UserLevel, models.UserLevelAdmin)
+	webhookKey := generateNewKey(false, idAdmin, "", "")
+	setPermissionApikey(t, webhookKey.Id, models.ApiPermManageWebhooks)
 	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{{
 		Name:  headerUserId,
 		Value: strconv.Itoa(idAdmin),
@@ -390,6 +403,10 @@ func TestUserChangeRank(t *testing.T) {
 	user, ok = database.GetUser(idAdmin)
 	test.IsEqualBool(t, ok, true)
 	test.IsEqual(t, user.UserLevel, models.UserLevelUser)
+	webhookKey, ok = database.GetApiKey(webhookKey.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, webhookKey.HasPermissionManageWebhooks(), false)
+	database.DeleteApiKey(webhookKey.Id)
 	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{
 		Name:  headerUserId,
 		Value: strconv.Itoa(idAdmin),
@@ -632,6 +649,104 @@ func TestUserPasswordReset(t *testing.T) {
 	apiResetPassword(w, &paramAuthCreate{}, models.User{Id: 7})
 }
 
+func TestUserResetTwoFactor(t *testing.T) {
+	const apiUrl = "/user/resetTwoFactor"
+	const headerUserId = "userid"
+
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageUsers)
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idAdmin),
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"Only admins can reset two-factor authentication","ErrorCode":3}`)
+
+	adminKey := generateNewKey(false, idSuperAdmin, "", "")
+	setPermissionApikey(t, adminKey.Id, models.ApiPermManageUsers)
+	totp := models.UserTotp{UserId: idAdmin, Secret: "JBSWY3DPEHPK3PXP", IsConfirmed: true}
+	database.SaveUserTotp(totp)
+	w, r = getRecorder(apiUrl, adminKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idAdmin),
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyIs(t, w, `{"result":"OK"}`)
+	_, ok := database.GetUserTotp(idAdmin)
+	test.IsEqualBool(t, ok, false)
+
+	w, r = getRecorder(apiUrl, adminKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idSuperAdmin),
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"Cannot reset two-factor authentication of super admin","ErrorCode":19}`)
+
+	w, r = getRecorder(apiUrl, adminKey.Id, []test.Header{{
+		Name:  headerUserId,
+		Value: strconv.Itoa(idInvalidUser),
+	}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+
+	defer test.ExpectPanic(t)
+	apiResetTwoFactor(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestUserModifyQuota(t *testing.T) {
+	const apiUrl = "/user/modify"
+	const headerUserId = "userid"
+	const headerQuotaFiles = "quotaFiles"
+
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageUsers)
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: headerUserId, Value: strconv.Itoa(idAdmin)},
+		{Name: headerQuotaFiles, Value: "5"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"Only admins can change quotas","ErrorCode":3}`)
+
+	adminKey := generateNewKey(false, idSuperAdmin, "", "")
+	setPermissionApikey(t, adminKey.Id, models.ApiPermManageUsers)
+	validHeaders := []test.Header{{Name: headerUserId, Value: strconv.Itoa(idUser)}}
+	invalidParameter := []invalidParameterValue{
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid value in header quotaFiles supplied","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "-2",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid quota value","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+	}
+	testInvalidParameters(t, apiUrl, adminKey.Id, validHeaders, headerQuotaFiles, invalidParameter)
+
+	database.DeleteUserQuota(idUser)
+	w, r = getRecorder(apiUrl, adminKey.Id, []test.Header{{Name: headerUserId, Value: strconv.Itoa(idUser)},
+		{Name: headerQuotaFiles, Value: "5"}, {Name: "quotaStorageMb", Value: "0"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	quota, ok := database.GetUserQuota(idUser)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, quota.MaxFiles, 5)
+	test.IsEqualInt(t, quota.MaxStorageMb, 0)
+	test.IsEqualInt(t, quota.MaxExpiryDays, models.QuotaUseDefault)
+	test.IsEqualInt(t, quota.MaxDownloads, models.QuotaUseDefault)
+	user, ok := database.GetUser(idUser)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, user.Permissions == models.UserPermissionNone, true)
+
+	w, r = getRecorder(apiUrl, adminKey.Id, []test.Header{{Name: headerUserId, Value: strconv.Itoa(idUser)},
+		{Name: headerQuotaFiles, Value: "-1"}, {Name: "quotaStorageMb", Value: "-1"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	_, ok = database.GetUserQuota(idUser)
+	test.IsEqualBool(t, ok, false)
+}
+
 func testUserModifyCall(t *testing.T, apiKey string, userId int, permission string, grant bool) {
 	const apiUrl = "/user/modify"
 	const headerUserId = "userid"
@@ -782,6 +897,7 @@ func getAvailableApiPermissions() []models.ApiPermission {
 		models.ApiPermManageLogs,
 		models.ApiPermManageFileRequests,
 		models.ApiPermDownload,
+		models.ApiPermManageWebhooks,
 	}
 	return result
 }
@@ -806,6 +922,7 @@ func getApiPermMap(t *testing.T) map[models.ApiPermission]string {
 	result[models.ApiPermManageLogs] = "PERM_MANAGE_LOGS"
 	result[models.ApiPermManageFileRequests] = "PERM_MANAGE_FILE_REQUESTS"
 	result[models.ApiPermDownload] = "PERM_DOWNLOAD"
+	result[models.ApiPermManageWebhooks] = "PERM_MANAGE_WEBHOOKS"
 
 	sum := 0
 	for perm := range result {
@@ -994,6 +1111,11 @@ func TestApikeyModify(t *testing.T) {
 			ErrorMessage: `{"Result":"error","ErrorMessage":"Insufficient user permission for owner to set this API permission","ErrorCode":6}`,
 			StatusCode:   401,
 		},
+		{
+			Value:        "PERM_MANAGE_WEBHOOKS",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Insufficient user permission for owner to set this API permission","ErrorCode":6}`,
+			StatusCode:   401,
+		},
 	}
 	testInvalidParameters(t, apiUrl, apiKey.Id, validHeaders, headerPermission, invalidParameter)
 
@@ -1003,6 +1125,10 @@ func TestApikeyModify(t *testing.T) {
 	grantUserPermission(t, idUser, models.UserPermGuestUploads)
 
 	for permissionUint, permissionString := range getApiPermMap(t) {
+		if permissionUint == models.ApiPermManageWebhooks {
+			// Can only be granted to API keys of admins, tested below
+			continue
+		}
 		test.IsEqualBool(t, retrievedApiKey.HasPermission(permissionUint), false)
 		testApiModifyCall(t, apiKey.Id, retrievedApiKey.Id, permissionString, true)
 		retrievedApiKey, ok = database.GetApiKey("modifyTest")
@@ -1017,6 +1143,19 @@ func TestApikeyModify(t *testing.T) {
 	removeUserPermission(t, idUser, models.UserPermManageUsers)
 	removeUserPermission(t, idUser, models.UserPermManageLogs)
 	removeUserPermission(t, idUser, models.UserPermGuestUploads)
+
+	retrievedApiKey.UserId = idAdmin
+	database.SaveApiKey(retrievedApiKey)
+	grantUserPermission(t, idUser, models.UserPermManageApiKeys)
+	testApiModifyCall(t, apiKey.Id, retrievedApiKey.Id, "PERM_MANAGE_WEBHOOKS", true)
+	retrievedApiKey, ok = database.GetApiKey("modifyTest")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, retrievedApiKey.HasPermissionManageWebhooks(), true)
+	testApiModifyCall(t, apiKey.Id, retrievedApiKey.Id, "PERM_MANAGE_WEBHOOKS", false)
+	retrievedApiKey, ok = database.GetApiKey("modifyTest")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, retrievedApiKey.HasPermissionManageWebhooks(), false)
+	removeUserPermission(t, idUser, models.UserPermManageApiKeys)
 }
 
 func testApiModifyCall(t *testing.T, apiKey, targetKey string, permission string, grant bool) {
@@ -1263,6 +1402,49 @@ func TestList(t *testing.T) {
 	test.IsNil(t, err)
 	test.IsEqualInt(t, len(result), 1)
 	test.IsEqualString(t, result[0].Name, "newTestFileName")
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "uploader", Value: strconv.Itoa(idAdmin)}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+
+	grantUserPermission(t, idUser, models.UserPermListOtherUploads)
+	var allIds []string
+	cursor := ""
+	for i := 0; i < 3; i++ {
+		headers := []test.Header{{Name: "limit", Value: "5"}, {Name: "sortBy", Value: "name"}, {Name: "sortOrder", Value: "asc"}}
+		if cursor != "" {
+			headers = append(headers, test.Header{Name: "cursor", Value: cursor})
+		}
+		w, r = getRecorder(apiUrl, apiKey.Id, headers)
+		Process(w, r)
+		test.IsEqualInt(t, w.Code, 200)
+		result = []models.FileApiOutput{}
+		err = json.Unmarshal(w.Body.Bytes(), &result)
+		test.IsNil(t, err)
+		for _, file := range result {
+			allIds = append(allIds, file.Id)
+		}
+		cursor = w.Header().Get("X-Next-Cursor")
+	}
+	test.IsEqualInt(t, len(allIds), 12)
+	test.IsEqualString(t, cursor, "")
+	slices.Sort(allIds)
+	test.IsEqualInt(t, len(slices.Compact(allIds)), 12)
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "name", Value: "newTestFileName"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(result), 1)
+
+	for _, header := range []test.Header{{Name: "limit", Value: "0"}, {Name: "limit", Value: "1001"},
+		{Name: "sortBy", Value: "invalid"}, {Name: "sortOrder", Value: "invalid"}, {Name: "cursor", Value: "invalid"}} {
+		w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{header})
+		Process(w, r)
+		test.IsEqualInt(t, w.Code, 400)
+	}
+	removeUserPermission(t, idUser, models.UserPermListOtherUploads)
 }
 
 func TestListSingle(t *testing.T) {
@@ -1512,6 +1694,34 @@ func TestChunkUpload(t *testing.T) {
 	apiChunkAdd(w, &paramAuthCreate{}, models.User{Id: 7})
 }
 
+func TestTus(t *testing.T) {
+	apiKey := testAuthorisation(t, "/api/tus/", models.ApiPermUpload)
+	w, r := test.GetRecorder("POST", "/api/tus/", nil, []test.Header{
+		{Name: "apikey", Value: apiKey.Id},
+		{Name: "Tus-Resumable", Value: "1.0.0"},
+		{Name: "Upload-Length", Value: "4"},
+		{Name: "Upload-Metadata", Value: "filename dHVzLnR4dA=="}},
+		nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, http.StatusCreated)
+	test.IsEqualString(t, w.Header().Get("Content-Type"), "")
+	location := w.Header().Get("Location")
+	test.IsNotEqualString(t, location, "")
+
+	w, r = test.GetRecorder("PATCH", "/api/tus/"+filepath.Base(location), nil, []test.Header{
+		{Name: "apikey", Value: apiKey.Id},
+		{Name: "Tus-Resumable", Value: "1.0.0"},
+		{Name: "Upload-Offset", Value: "0"},
+		{Name: "Content-Type", Value: "application/offset+octet-stream"}},
+		bytes.NewBufferString("test"))
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	file, ok := database.GetMetaDataById(w.Header().Get("X-Gokapi-File-Id"))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.Name, "tus.txt")
+	test.IsEqualInt(t, file.UserId, idUser)
+}
+
 func TestChunkComplete(t *testing.T) {
 	apiKey := generateNewKey(false, idUser, "", "")
 	apiKey.GrantPermission(models.ApiPermUpload)
@@ -1552,6 +1762,325 @@ func TestChunkComplete(t *testing.T) {
 	apiChunkComplete(w, &paramAuthCreate{}, models.User{Id: 7})
 }
 
+// enableMail starts a local SMTP server, which is used until the end of the test
+func enableMail(t *testing.T) *smtpstub.Server {
+	t.Helper()
+	server, err := smtpstub.Start(smtpstub.Config{})
+	test.IsNil(t, err)
+	t.Cleanup(func() {
+		mail.Init(configuration.Get().ServerUrl, false, "")
+		server.Close()
+	})
+	t.Setenv("GOKAPI_SMTP_HOST", server.Host())
+	t.Setenv("GOKAPI_SMTP_PORT", strconv.Itoa(server.Port()))
+	t.Setenv("GOKAPI_SMTP_TLS", mail.TlsModeNone)
+	t.Setenv("GOKAPI_SMTP_FROM", "gokapi@example.com")
+	mail.Init(configuration.Get().ServerUrl, false, "Gokapi")
+	return server
+}
+
+func TestSendLink(t *testing.T) {
+	const apiUrl = "/files/sendLink"
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermEdit)
+	validHeaders := []test.Header{{Name: "id", Value: idFileUser}, {Name: "recipients", Value: "a@example.com"}}
+
+	w, r := getRecorder(apiUrl, apiKey.Id, validHeaders)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"No SMTP server has been configured","ErrorCode":21}`)
+
+	server := enableMail(t)
+	testInvalidParameters(t, apiUrl, apiKey.Id, validHeaders[:1], "recipients", []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header recipients is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        " , ",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"no recipients provided","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "a@example.com,invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid email address: invalid","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+	})
+	testInvalidParameters(t, apiUrl, apiKey.Id, validHeaders[1:], "id", []invalidParameterValue{
+		{
+			Value:        "invalidFile",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Invalid id provided.","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+		{
+			Value:        idFileAdmin,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"No permission to share this file","ErrorCode":6}`,
+			StatusCode:   401,
+		},
+	})
+	test.IsEqualInt(t, len(server.Messages()), 0)
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: idFileUser},
+		{Name: "recipients", Value: "a@example.com, b@example.com"},
+		{Name: "message", Value: "base64:" + base64.StdEncoding.EncodeToString([]byte("Here is the file"))}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyIs(t, w, `{"result":"OK"}`)
+	messages := server.WaitForMessages(2, 5*time.Second)
+	test.IsEqualInt(t, len(messages), 2)
+	test.IsEqualString(t, messages[0].To[0], "a@example.com")
+	test.IsEqualString(t, messages[1].To[0], "b@example.com")
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "Here is the file"), true)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, idFileUser), true)
+
+	e2eFile := models.File{Id: "sendLinkE2E", Name: "e2e", SHA1: "e017693e4a04a59d0b0f400fe98177fe7ee13cf7", UnlimitedDownloads: true, UnlimitedTime: true, UserId: idUser}
+	e2eFile.Encryption.IsEndToEndEncrypted = true
+	database.SaveMetaData(e2eFile)
+	defer database.DeleteMetaData(e2eFile.Id)
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: e2eFile.Id}, {Name: "recipients", Value: "a@example.com"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"Download links of end-to-end encrypted files cannot be sent by email","ErrorCode":17}`)
+
+	requestFile := models.File{Id: "sendLinkRequest", Name: "request", SHA1: "e017693e4a04a59d0b0f400fe98177fe7ee13cf7", UnlimitedDownloads: true, UnlimitedTime: true, UserId: idUser, UploadRequestId: "request"}
+	database.SaveMetaData(requestFile)
+	defer database.DeleteMetaData(requestFile.Id)
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: requestFile.Id}, {Name: "recipients", Value: "a@example.com"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"Cannot share a file request upload","ErrorCode":18}`)
+	test.IsEqualInt(t, len(server.Messages()), 2)
+
+	defer test.ExpectPanic(t)
+	apiSendLink(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestChunkCompleteRecipients(t *testing.T) {
+	apiKey := generateNewKey(false, idUser, "", "")
+	apiKey.GrantPermission(models.ApiPermUpload)
+	database.SaveApiKey(apiKey)
+	test.IsNil(t, os.WriteFile("test/data/chunk-recipientsupload", []byte("recipients"), 0600))
+	headers := []test.Header{
+		{Name: "apikey", Value: apiKey.Id},
+		{Name: "uuid", Value: "recipientsupload"},
+		{Name: "filename", Value: "recipients.txt"},
+		{Name: "filesize", Value: "10"},
+		{Name: "recipients", Value: "c@example.com"},
+		{Name: "message", Value: "New upload"}}
+
+	w, r := test.GetRecorder("POST", "/api/chunk/complete", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"No SMTP server has been configured","ErrorCode":21}`)
+
+	server := enableMail(t)
+	w, r = test.GetRecorder("POST", "/api/chunk/complete", nil, append(headers, test.Header{Name: "isE2E", Value: "true"},
+		test.Header{Name: "realsize", Value: "10"}), nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"download links of end-to-end encrypted files cannot be sent by email","ErrorCode":4}`)
+
+	w, r = test.GetRecorder("POST", "/api/chunk/complete", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	messages := server.WaitForMessages(1, 5*time.Second)
+	test.IsEqualInt(t, len(messages), 1)
+	test.IsEqualString(t, messages[0].To[0], "c@example.com")
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "New upload"), true)
+	test.IsEqualBool(t, strings.Contains(messages[0].Data, "recipients.txt"), true)
+}
+
+func TestChunkCompletePublishAt(t *testing.T) {
+	apiKey := generateNewKey(false, idUser, "", "")
+	apiKey.GrantPermission(models.ApiPermUpload)
+	database.SaveApiKey(apiKey)
+	test.IsNil(t, os.WriteFile("test/data/chunk-publishupload", []byte("publish"), 0600))
+	publishAt := time.Now().Add(48 * time.Hour).Unix()
+	headers := []test.Header{
+		{Name: "apikey", Value: apiKey.Id},
+		{Name: "uuid", Value: "publishupload"},
+		{Name: "filename", Value: "publish.txt"},
+		{Name: "filesize", Value: "7"},
+		{Name: "expiryDays", Value: "1"},
+		{Name: "publishAt", Value: strconv.FormatInt(publishAt, 10)}}
+
+	w, r := test.GetRecorder("POST", "/api/chunk/complete", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"the file would expire before it is published","ErrorCode":10}`)
+
+	w, r = test.GetRecorder("POST", "/api/chunk/complete", nil, append(headers, test.Header{Name: "expiryFromPublish", Value: "true"}), nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	result := struct {
+		FileInfo models.FileApiOutput `json:"FileInfo"`
+	}{}
+	response, err := io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	test.IsNil(t, json.Unmarshal(response, &result))
+	test.IsEqualInt64(t, result.FileInfo.PublishAt, publishAt)
+	test.IsEqualInt64(t, result.FileInfo.ExpireAt, publishAt+24*60*60)
+	test.IsEqualBool(t, result.FileInfo.IsEmbargoed, true)
+	file, ok := database.GetMetaDataById(result.FileInfo.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt64(t, file.PublishAt, publishAt)
+}
+
+func TestFilesModifyPublishAt(t *testing.T) {
+	const apiUrl = "/files/modify"
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermEdit)
+	now := time.Now().Unix()
+	file := models.File{Id: "publishModify", Name: "publish.txt", SHA1: "publishmodifycontent", UploadDate: now - 100,
+		ExpireAt: now + 1000, UnlimitedDownloads: true, UserId: idUser}
+	database.SaveMetaData(file)
+	defer database.DeleteMetaData(file.Id)
+
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: file.Id}, {Name: "originalPassword", Value: "true"},
+		{Name: "publishAt", Value: strconv.FormatInt(now+2000, 10)}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"the file would expire before it is published","ErrorCode":10}`)
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: file.Id}, {Name: "originalPassword", Value: "true"},
+		{Name: "publishAt", Value: strconv.FormatInt(now+2000, 10)}, {Name: "expiryFromPublish", Value: "true"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt64(t, retrievedFile.PublishAt, now+2000)
+	test.IsEqualInt64(t, retrievedFile.ExpireAt, now+3100)
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: file.Id}, {Name: "originalPassword", Value: "true"},
+		{Name: "publishAt", Value: "0"}, {Name: "expiryTimestamp", Value: strconv.FormatInt(now+5000, 10)},
+		{Name: "expiryFromPublish", Value: "true"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	retrievedFile, ok = database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt64(t, retrievedFile.PublishAt, 0)
+	test.IsEqualInt64(t, retrievedFile.ExpireAt, now+5000)
+	test.IsEqualString(t, retrievedFile.PasswordHash, "")
+}
+
+func TestFilesModifyAccessRestriction(t *testing.T) {
+	const apiUrl = "/files/modify"
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermEdit)
+	file := models.File{Id: "restrictModify", Name: "restrict.png", SHA1: "restrictmodifycontent", ContentType: "image/png",
+		HotlinkId: "restrictModifyHotlink", UnlimitedTime: true, UnlimitedDownloads: true, UserId: idUser}
+	database.SaveMetaData(file)
+	database.SaveHotlink(file)
+	defer database.DeleteMetaData(file.Id)
+
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: file.Id}, {Name: "originalPassword", Value: "true"},
+		{Name: "requireLogin", Value: "true"}, {Name: "allowedUsers", Value: " alice, bob ,alice"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	retrievedFile, ok := database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, retrievedFile.RequireLogin, true)
+	test.IsEqualString(t, retrievedFile.AllowedUsers, "alice,bob")
+	test.IsEqualString(t, retrievedFile.AllowedGroups, "")
+	test.IsEqualString(t, retrievedFile.HotlinkId, "")
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: file.Id}, {Name: "originalPassword", Value: "true"},
+		{Name: "allowedGroups", Value: "base64:" + base64.StdEncoding.EncodeToString([]byte("staff"))}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	retrievedFile, _ = database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, retrievedFile.RequireLogin, true)
+	test.IsEqualString(t, retrievedFile.AllowedUsers, "alice,bob")
+	test.IsEqualString(t, retrievedFile.AllowedGroups, "staff")
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: file.Id}, {Name: "originalPassword", Value: "true"},
+		{Name: "requireLogin", Value: "false"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	retrievedFile, _ = database.GetMetaDataById(file.Id)
+	test.IsEqualBool(t, retrievedFile.RequireLogin, false)
+	test.IsNotEqualString(t, retrievedFile.HotlinkId, "")
+}
+
+func TestChunkCompleteAccessRestriction(t *testing.T) {
+	apiKey := generateNewKey(false, idUser, "", "")
+	apiKey.GrantPermission(models.ApiPermUpload)
+	database.SaveApiKey(apiKey)
+	test.IsNil(t, os.WriteFile("test/data/chunk-restrictupload", []byte("restrict"), 0600))
+	headers := []test.Header{
+		{Name: "apikey", Value: apiKey.Id},
+		{Name: "uuid", Value: "restrictupload"},
+		{Name: "filename", Value: "restrict.txt"},
+		{Name: "filesize", Value: "8"},
+		{Name: "requireLogin", Value: "true"},
+		{Name: "allowedGroups", Value: "staff,,sales"}}
+
+	w, r := test.GetRecorder("POST", "/api/chunk/complete", nil, headers, nil)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	result := struct {
+		FileInfo models.FileApiOutput `json:"FileInfo"`
+	}{}
+	response, err := io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	test.IsNil(t, json.Unmarshal(response, &result))
+	test.IsEqualBool(t, result.FileInfo.RequireLogin, true)
+	test.IsEqualString(t, result.FileInfo.AllowedGroups, "staff,sales")
+	file, ok := database.GetMetaDataById(result.FileInfo.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, file.RequireLogin, true)
+	test.IsEqualString(t, file.AllowedUsers, "")
+	test.IsEqualString(t, file.AllowedGroups, "staff,sales")
+}
+
+func TestCheckFileRequestAccessRestriction(t *testing.T) {
+	fileRequest := models.FileRequest{Id: "restrictedRequest", ApiKey: "restrictedRequestKey", UserId: idUser,
+		RequireLogin: true, AllowedUsers: "user"}
+	database.SaveFileRequest(fileRequest)
+	defer database.DeleteFileRequest(fileRequest)
+
+	r := httptest.NewRequest("POST", "/api/uploadrequest/chunk/reserve", nil)
+	_, ok, status, errorCode, _ := checkFileRequestAndApiKey(httptest.NewRecorder(), r, fileRequest.Id, fileRequest.ApiKey)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualInt(t, status, http.StatusUnauthorized)
+	test.IsEqualInt(t, errorCode, errorcodes.NoPermission)
+
+	r.AddCookie(&http.Cookie{Name: "session_token", Value: "validsession"})
+	_, ok, _, _, _ = checkFileRequestAndApiKey(httptest.NewRecorder(), r, fileRequest.Id, fileRequest.ApiKey)
+	test.IsEqualBool(t, ok, true)
+
+	fileRequest.AllowedUsers = "test"
+	database.SaveFileRequest(fileRequest)
+	_, ok, _, _, _ = checkFileRequestAndApiKey(httptest.NewRecorder(), r, fileRequest.Id, fileRequest.ApiKey)
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestUploadRequestSaveAccessRestriction(t *testing.T) {
+	const apiUrl = "/uploadrequest/save"
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageFileRequests)
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "name", Value: "Restricted"},
+		{Name: "maxfiles", Value: "1"}, {Name: "maxsize", Value: "1"}, {Name: "requireLogin", Value: "true"}, {Name: "allowedUsers", Value: "alice "}, {Name: "allowedGroups", Value: "staff"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var result models.FileRequest
+	response, err := io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	test.IsNil(t, json.Unmarshal(response, &result))
+	defer database.DeleteFileRequest(result)
+	test.IsEqualBool(t, result.RequireLogin, true)
+	test.IsEqualString(t, result.AllowedUsers, "alice")
+	test.IsEqualString(t, result.AllowedGroups, "staff")
+
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{{Name: "id", Value: result.Id}, {Name: "allowedUsers", Value: ""}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	savedRequest, ok := database.GetFileRequest(result.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, savedRequest.RequireLogin, true)
+	test.IsEqualString(t, savedRequest.AllowedUsers, "")
+	test.IsEqualString(t, savedRequest.AllowedGroups, "staff")
+	test.IsEqualString(t, savedRequest.Name, "Restricted")
+}
+
 func TestMinorFunctions(t *testing.T) {
 	outputFileJson(nil, models.File{})
 	sendError(nil, 0, 0, "none")
@@ -1696,3 +2225,884 @@ func TestFileReplace(t *testing.T) {
 	_, ok = storage.GetFile(newFile.Id)
 	test.IsEqualBool(t, ok, false)
 }
+
+func TestFileVersions(t *testing.T) {
+	const urlList = "/files/versions/list"
+	const urlDownload = "/files/versions/download"
+	const urlRollback = "/files/versions/rollback"
+	versionFile := models.File{Id: "versionApiFile", Name: "old.txt", Size: "3 B", SizeBytes: 3, SHA1: "versionapicontent1",
+		ContentType: "text/plain", UnlimitedDownloads: true, UnlimitedTime: true, UserId: idUser}
+	newContent := models.File{Id: "versionApiNew", Name: "new.txt", Size: "3 B", SizeBytes: 3, SHA1: "versionapicontent2",
+		ContentType: "text/plain", UnlimitedDownloads: true, UnlimitedTime: true, UserId: idUser}
+	test.IsNil(t, os.WriteFile("test/data/"+versionFile.SHA1, []byte("old"), 0600))
+	test.IsNil(t, os.WriteFile("test/data/"+newContent.SHA1, []byte("new"), 0600))
+	database.SaveMetaData(versionFile)
+	database.SaveMetaData(newContent)
+	defer database.DeleteMetaData(versionFile.Id)
+	_, err := storage.ReplaceFile(versionFile.Id, newContent.Id, true, idUser)
+	test.IsNil(t, err)
+	versions := storage.GetFileVersions(versionFile.Id)
+	test.IsEqualInt(t, len(versions), 1)
+	versionId := versions[0].Id
+
+	apiKeyList := testAuthorisation(t, urlList, models.ApiPermView)
+	testInvalidParameters(t, urlList, apiKeyList.Id, []test.Header{}, "id", []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header id is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalidFile",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Invalid id provided.","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+		{
+			Value:        idFileAdmin,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"No permission to view this file","ErrorCode":6}`,
+			StatusCode:   401,
+		},
+	})
+	w, r := getRecorder(urlList, apiKeyList.Id, []test.Header{{Name: "id", Value: idFileUser}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyIs(t, w, "[]")
+	w, r = getRecorder(urlList, apiKeyList.Id, []test.Header{{Name: "id", Value: versionFile.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var result []models.FileVersionApiOutput
+	response, err := io.ReadAll(w.Result().Body)
+	test.IsNil(t, err)
+	test.IsNil(t, json.Unmarshal(response, &result))
+	test.IsEqualInt(t, len(result), 1)
+	test.IsEqualString(t, result[0].Id, versionId)
+	test.IsEqualString(t, result[0].Name, "old.txt")
+	test.IsEqualInt(t, result[0].Revision, 1)
+	test.IsEqualInt(t, result[0].ReplacedBy, idUser)
+
+	apiKeyDownload := testAuthorisation(t, urlDownload, models.ApiPermDownload)
+	validHeaders := []test.Header{{Name: "id", Value: versionFile.Id}, {Name: "versionId", Value: versionId}}
+	testInvalidParameters(t, urlDownload, apiKeyDownload.Id, validHeaders[1:], "id", []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header id is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalidFile",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"file not found","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+		{
+			Value:        idFileAdmin,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"no permission to download file","ErrorCode":6}`,
+			StatusCode:   401,
+		},
+	})
+	testInvalidParameters(t, urlDownload, apiKeyDownload.Id, validHeaders[:1], "versionId", []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header versionId is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalidVersion",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"version not found","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+	})
+	w, r = getRecorder(urlDownload, apiKeyDownload.Id, validHeaders)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyIs(t, w, "old")
+
+	apiKeyRollback := testAuthorisation(t, urlRollback, models.ApiPermReplace)
+	testInvalidParameters(t, urlRollback, apiKeyRollback.Id, validHeaders[1:], "id", []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header id is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalidFile",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Invalid id provided.","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+		{
+			Value:        idFileAdmin,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"No permission to replace this file","ErrorCode":6}`,
+			StatusCode:   401,
+		},
+	})
+	testInvalidParameters(t, urlRollback, apiKeyRollback.Id, validHeaders[:1], "versionId", []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header versionId is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalidVersion",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Invalid version id provided.","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+	})
+	w, r = getRecorder(urlRollback, apiKeyRollback.Id, validHeaders)
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	file, ok := database.GetMetaDataById(versionFile.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.Name, "old.txt")
+	test.IsEqualString(t, file.SHA1, versionFile.SHA1)
+	versions = storage.GetFileVersions(versionFile.Id)
+	test.IsEqualInt(t, len(versions), 1)
+	test.IsEqualString(t, versions[0].Name, "new.txt")
+	test.IsEqualInt(t, versions[0].Revision, 2)
+	for _, version := range versions {
+		database.DeleteFileVersion(version.Id)
+	}
+
+	defer test.ExpectPanic(t)
+	apiListFileVersions(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestFileVersionsInvalidParameter(t *testing.T) {
+	w := httptest.NewRecorder()
+	defer test.ExpectPanic(t)
+	apiDownloadFileVersion(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestFileRollbackInvalidParameter(t *testing.T) {
+	w := httptest.NewRecorder()
+	defer test.ExpectPanic(t)
+	apiRollbackFile(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+// ## /encryption ##
+
+func TestEncryptionRotateKey(t *testing.T) {
+	const apiUrl = "/encryption/rotateKey"
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageUsers)
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+
+	superAdminKey := generateNewKey(false, idSuperAdmin, "", "")
+	setPermissionApikey(t, superAdminKey.Id, models.ApiPermManageUsers)
+	adminKey := generateNewKey(false, idAdmin, "", "")
+	setPermissionApikey(t, adminKey.Id, models.ApiPermManageUsers)
+	w, r = getRecorder(apiUrl, adminKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"Only the super admin can rotate the encryption key","ErrorCode":6}`)
+	w, r = getRecorder(apiUrl, superAdminKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"server-side encryption is not enabled","ErrorCode":10}`)
+
+	originalConfig := configuration.Get().Encryption
+	cipher, err := encryption.GetRandomCipher()
+	test.IsNil(t, err)
+	configuration.Get().Encryption = models.Encryption{Level: encryption.LocalEncryptionStored, Cipher: cipher}
+	encryption.Init(*configuration.Get())
+	w, r = getRecorder(apiUrl, superAdminKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var result keyrotation.Result
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, bytes.Equal(configuration.Get().Encryption.Cipher, cipher), false)
+
+	configuration.Get().Encryption.Level = encryption.LocalEncryptionInput
+	w, r = getRecorder(apiUrl, superAdminKey.Id, []test.Header{{Name: "newPassword", Value: "short"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"password is less than 8 characters long","ErrorCode":10}`)
+	configuration.Get().Encryption = originalConfig
+
+	defer test.ExpectPanic(t)
+	apiEncryptionRotateKey(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestEncryptionEncryptFiles(t *testing.T) {
+	const apiUrl = "/encryption/encryptFiles"
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageUsers)
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+
+	superAdminKey := generateNewKey(false, idSuperAdmin, "", "")
+	setPermissionApikey(t, superAdminKey.Id, models.ApiPermManageUsers)
+	adminKey := generateNewKey(false, idAdmin, "", "")
+	setPermissionApikey(t, adminKey.Id, models.ApiPermManageUsers)
+	w, r = getRecorder(apiUrl, adminKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"Only the super admin can encrypt existing files","ErrorCode":6}`)
+	w, r = getRecorder(apiUrl, superAdminKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"server-side encryption is not enabled","ErrorCode":10}`)
+
+	defer test.ExpectPanic(t)
+	apiEncryptionEncryptFiles(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+// ## /scrub ##
+
+func waitForScrub(t *testing.T) models.ScrubReport {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		report, ok := scrub.GetReport()
+		if ok && !report.IsRunning {
+			return report
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("storage check did not finish")
+	return models.ScrubReport{}
+}
+
+func TestScrub(t *testing.T) {
+	testAuthorisation(t, "/scrub/report", models.ApiPermManageLogs)
+	adminKey := generateNewKey(false, idAdmin, "", "")
+	setPermissionApikey(t, adminKey.Id, models.ApiPermManageLogs)
+	superAdminKey := generateNewKey(false, idSuperAdmin, "", "")
+	setPermissionApikey(t, superAdminKey.Id, models.ApiPermManageLogs)
+	w, r := getRecorder("/scrub/report", adminKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"no completed storage check report exists","ErrorCode":5}`)
+	w, r = getRecorder("/scrub/resolve", superAdminKey.Id, []test.Header{{Name: "action", Value: "none"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 404)
+
+	w, r = getRecorder("/scrub/start", adminKey.Id, []test.Header{{Name: "action", Value: "invalid"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"invalid action, must be one of none, quarantine or delete","ErrorCode":4}`)
+	w, r = getRecorder("/scrub/start", adminKey.Id, []test.Header{{Name: "action", Value: "delete"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"Only the super admin can quarantine or delete stored files","ErrorCode":6}`)
+	w, r = getRecorder("/scrub/resolve", adminKey.Id, []test.Header{{Name: "action", Value: "delete"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	w, r = getRecorder("/scrub/resolve", adminKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 400)
+
+	w, r = getRecorder("/scrub/start", adminKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyIs(t, w, `{"Result":"OK"}`)
+	expectedReport := waitForScrub(t)
+	w, r = getRecorder("/scrub/report", adminKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var report models.ScrubReport
+	err := json.Unmarshal(w.Body.Bytes(), &report)
+	test.IsNil(t, err)
+	test.IsEqualString(t, report.Action, models.ScrubActionNone)
+	test.IsEqualInt(t, report.CheckedObjects, expectedReport.CheckedObjects)
+	test.IsEqualInt(t, len(report.Findings), len(expectedReport.Findings))
+
+	w, r = getRecorder("/scrub/resolve", superAdminKey.Id, []test.Header{{Name: "action", Value: "none"}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+
+	defer test.ExpectPanic(t)
+	apiScrubStart(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestScrubResolvePanic(t *testing.T) {
+	defer test.ExpectPanic(t)
+	apiScrubResolve(httptest.NewRecorder(), &paramAuthCreate{}, models.User{Id: 7})
+}
+
+// ## /webhooks ##
+
+func TestWebhooks(t *testing.T) {
+	const headerUrl = "url"
+	const headerEvents = "events"
+	const headerId = "id"
+
+	apiKeyUser := testAuthorisation(t, "/webhooks/add", models.ApiPermManageWebhooks)
+	w, r := getRecorder("/webhooks/list", apiKeyUser.Id, []test.Header{{}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"Unauthorized","ErrorCode":3}`)
+
+	apiKey := generateNewKey(false, idAdmin, "", "")
+	setPermissionApikey(t, apiKey.Id, models.ApiPermManageWebhooks)
+
+	w, r = getRecorder("/webhooks/list", apiKey.Id, []test.Header{{}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyIs(t, w, "[]")
+
+	testInvalidParameters(t, "/webhooks/add", apiKey.Id, []test.Header{{Name: headerEvents, Value: "file.upload"}}, headerUrl, []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header url is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "ftp://example.com",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid URL provided, only http and https URLs are supported","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+	})
+	testInvalidParameters(t, "/webhooks/add", apiKey.Id, []test.Header{{Name: headerUrl, Value: "https://example.com"}}, headerEvents, []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header events is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "file.upload,invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid event: invalid","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+	})
+
+	w, r = getRecorder("/webhooks/add", apiKey.Id, []test.Header{
+		{Name: headerUrl, Value: "https://example.com/hook"},
+		{Name: headerEvents, Value: "file.upload, file.delete"},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var webhook models.Webhook
+	err := json.Unmarshal(w.Body.Bytes(), &webhook)
+	test.IsNil(t, err)
+	test.IsEqualString(t, webhook.Url, "https://example.com/hook")
+	test.IsEqualString(t, webhook.Events, "file.upload,file.delete")
+	test.IsEqualInt(t, webhook.UserId, idAdmin)
+	test.IsEqualInt(t, len(webhook.Secret), 40)
+	stored, ok := database.GetWebhook(webhook.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, stored.Secret, webhook.Secret)
+
+	w, r = getRecorder("/webhooks/list", apiKey.Id, []test.Header{{}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var webhooks []models.Webhook
+	err = json.Unmarshal(w.Body.Bytes(), &webhooks)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(webhooks), 1)
+	test.IsEqualString(t, webhooks[0].Id, webhook.Id)
+	test.IsEqualString(t, webhooks[0].Secret, "")
+
+	testInvalidParameters(t, "/webhooks/modify", apiKey.Id, []test.Header{{}}, headerId, []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header id is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Webhook does not exist with the given ID","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+	})
+	w, r = getRecorder("/webhooks/modify", apiKey.Id, []test.Header{
+		{Name: headerId, Value: webhook.Id},
+		{Name: headerEvents, Value: "all"},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	stored, ok = database.GetWebhook(webhook.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, stored.Url, "https://example.com/hook")
+	test.IsEqualInt(t, len(stored.GetEvents()), len(models.WebhookEvents))
+	test.IsEqualString(t, stored.Secret, webhook.Secret)
+
+	w, r = getRecorder("/webhooks/modify", apiKey.Id, []test.Header{
+		{Name: headerId, Value: webhook.Id},
+		{Name: headerUrl, Value: "http://127.0.0.1:8080/hook"},
+		{Name: "regenerateSecret", Value: "true"},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var modified models.Webhook
+	err = json.Unmarshal(w.Body.Bytes(), &modified)
+	test.IsNil(t, err)
+	stored, ok = database.GetWebhook(webhook.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, stored.Url, "http://127.0.0.1:8080/hook")
+	test.IsEqualBool(t, stored.Secret != webhook.Secret, true)
+	test.IsEqualString(t, modified.Secret, stored.Secret)
+
+	testInvalidParameters(t, "/webhooks/delete", apiKey.Id, []test.Header{{}}, headerId, []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header id is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Webhook does not exist with the given ID","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+	})
+	w, r = getRecorder("/webhooks/delete", apiKey.Id, []test.Header{{Name: headerId, Value: webhook.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	_, ok = database.GetWebhook(webhook.Id)
+	test.IsEqualBool(t, ok, false)
+
+	defer test.ExpectPanic(t)
+	apiWebhooksAdd(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestBundles(t *testing.T) {
+	const headerId = "id"
+	const headerFileIds = "fileIds"
+	const idBundleFileUser = "bundleFileUser"
+	const idBundleFileAdmin = "bundleFileAdmin"
+	const idBundleFileE2E = "bundleFileE2E"
+
+	database.SaveMetaData(models.File{Id: idBundleFileUser, Name: "userFile", SHA1: "e017693e4a04a59d0b0f400fe98177fe7ee13cf7",
+		UnlimitedDownloads: true, UnlimitedTime: true, UserId: idUser})
+	database.SaveMetaData(models.File{Id: idBundleFileAdmin, Name: "adminFile", SHA1: "e017693e4a04a59d0b0f400fe98177fe7ee13cf7",
+		UnlimitedDownloads: true, UnlimitedTime: true, UserId: idAdmin})
+	database.SaveMetaData(models.File{Id: idBundleFileE2E, Name: "e2eFile", SHA1: "e017693e4a04a59d0b0f400fe98177fe7ee13cf7",
+		UnlimitedDownloads: true, UnlimitedTime: true, UserId: idUser,
+		Encryption: models.EncryptionInfo{IsEncrypted: true, IsEndToEndEncrypted: true}})
+
+	testAuthorisation(t, "/bundle/create", models.ApiPermEdit)
+	testAuthorisation(t, "/bundle/list", models.ApiPermView)
+
+	apiKeyUser := generateNewKey(false, idUser, "", "")
+	setPermissionApikey(t, apiKeyUser.Id, models.ApiPermEdit)
+	setPermissionApikey(t, apiKeyUser.Id, models.ApiPermView)
+	apiKeyAdmin := generateNewKey(false, idAdmin, "", "")
+	setPermissionApikey(t, apiKeyAdmin.Id, models.ApiPermEdit)
+	setPermissionApikey(t, apiKeyAdmin.Id, models.ApiPermView)
+
+	w, r := getRecorder("/bundle/list", apiKeyUser.Id, []test.Header{{}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyIs(t, w, "[]")
+
+	testInvalidParameters(t, "/bundle/create", apiKeyUser.Id, []test.Header{{}}, headerFileIds, []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header fileIds is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        " , ",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"no file IDs submitted","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"A file with such an ID could not be found","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+		{
+			Value:        idBundleFileAdmin,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"No permission to share this file","ErrorCode":6}`,
+			StatusCode:   401,
+		},
+		{
+			Value:        idBundleFileE2E,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"encrypted files, files of file requests and files that require a login cannot be added to a bundle","ErrorCode":18}`,
+			StatusCode:   400,
+		},
+	})
+	testInvalidParameters(t, "/bundle/create", apiKeyUser.Id, []test.Header{{Name: headerFileIds, Value: idBundleFileUser}}, "allowedDownloads", []invalidParameterValue{
+		{
+			Value:        "-1",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid value for allowedDownloads","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+	})
+
+	w, r = getRecorder("/bundle/create", apiKeyUser.Id, []test.Header{
+		{Name: headerFileIds, Value: idBundleFileUser + "," + idBundleFileUser},
+		{Name: "name", Value: "base64:" + base64.StdEncoding.EncodeToString([]byte("Test Bundle"))},
+		{Name: "password", Value: "secret"},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var result models.BundleApiOutput
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	test.IsNil(t, err)
+	test.IsEqualString(t, result.Name, "Test Bundle")
+	test.IsEqualInt(t, len(result.FileIds), 1)
+	test.IsEqualString(t, result.FileIds[0], idBundleFileUser)
+	test.IsEqualInt(t, result.DownloadsRemaining, 1)
+	test.IsEqualBool(t, result.UnlimitedDownloads, false)
+	test.IsEqualBool(t, result.UnlimitedTime, false)
+	test.IsEqualBool(t, result.IsPasswordProtected, true)
+	test.IsEqualInt(t, result.UserId, idUser)
+	test.IsEqualString(t, result.UrlDownload, configuration.Get().ServerUrl+"b?id="+result.Id)
+	stored, ok := database.GetBundle(result.Id)
+	test.IsEqualBool(t, ok, true)
+	isValid, _ := configuration.VerifyPassword("secret", stored.PasswordHash, "")
+	test.IsEqualBool(t, isValid, true)
+
+	w, r = getRecorder("/bundle/list", apiKeyUser.Id, []test.Header{{}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var bundles []models.BundleApiOutput
+	err = json.Unmarshal(w.Body.Bytes(), &bundles)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(bundles), 1)
+	w, r = getRecorder("/bundle/list", apiKeyAdmin.Id, []test.Header{{}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err = json.Unmarshal(w.Body.Bytes(), &bundles)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(bundles), 1)
+
+	testInvalidParameters(t, "/bundle/addFiles", apiKeyUser.Id, []test.Header{{Name: headerFileIds, Value: idBundleFileUser}}, headerId, []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header id is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Bundle does not exist with the given ID","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+	})
+	w, r = getRecorder("/bundle/addFiles", apiKeyUser.Id, []test.Header{
+		{Name: headerId, Value: result.Id},
+		{Name: headerFileIds, Value: idBundleFileAdmin},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+
+	// Admin has the permission to edit other uploads
+	w, r = getRecorder("/bundle/addFiles", apiKeyAdmin.Id, []test.Header{
+		{Name: headerId, Value: result.Id},
+		{Name: headerFileIds, Value: idBundleFileAdmin},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	stored, _ = database.GetBundle(result.Id)
+	test.IsEqualString(t, stored.FileIds, idBundleFileUser+","+idBundleFileAdmin)
+
+	w, r = getRecorder("/bundle/removeFiles", apiKeyUser.Id, []test.Header{
+		{Name: headerId, Value: result.Id},
+		{Name: headerFileIds, Value: idBundleFileUser},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	stored, _ = database.GetBundle(result.Id)
+	test.IsEqualString(t, stored.FileIds, idBundleFileAdmin)
+
+	w, r = getRecorder("/bundle/create", apiKeyAdmin.Id, []test.Header{
+		{Name: headerFileIds, Value: idBundleFileAdmin},
+		{Name: "allowedDownloads", Value: "0"},
+		{Name: "expiryDays", Value: "0"},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var resultAdmin models.BundleApiOutput
+	err = json.Unmarshal(w.Body.Bytes(), &resultAdmin)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, resultAdmin.UnlimitedDownloads, true)
+	test.IsEqualBool(t, resultAdmin.UnlimitedTime, true)
+	test.IsEqualBool(t, resultAdmin.IsPasswordProtected, false)
+
+	w, r = getRecorder("/bundle/list", apiKeyUser.Id, []test.Header{{}})
+	Process(w, r)
+	err = json.Unmarshal(w.Body.Bytes(), &bundles)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(bundles), 1)
+	w, r = getRecorder("/bundle/list", apiKeyAdmin.Id, []test.Header{{}})
+	Process(w, r)
+	err = json.Unmarshal(w.Body.Bytes(), &bundles)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(bundles), 2)
+
+	w, r = getRecorder("/bundle/delete", apiKeyUser.Id, []test.Header{{Name: headerId, Value: resultAdmin.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 401)
+	test.ResponseBodyIs(t, w, `{"Result":"error","ErrorMessage":"No permission to edit this bundle","ErrorCode":6}`)
+	w, r = getRecorder("/bundle/delete", apiKeyUser.Id, []test.Header{{Name: headerId, Value: result.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	_, ok = database.GetBundle(result.Id)
+	test.IsEqualBool(t, ok, false)
+	w, r = getRecorder("/bundle/delete", apiKeyAdmin.Id, []test.Header{{Name: headerId, Value: resultAdmin.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+
+	defer test.ExpectPanic(t)
+	apiBundleCreate(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestShareLinks(t *testing.T) {
+	const headerFileId = "fileId"
+	const idLinkFileUser = "linkFileUser"
+	const idLinkFileAdmin = "linkFileAdmin"
+	const idLinkFileE2E = "linkFileE2E"
+
+	database.SaveMetaData(models.File{Id: idLinkFileUser, Name: "userFile", SHA1: "e017693e4a04a59d0b0f400fe98177fe7ee13cf7",
+		UnlimitedDownloads: true, UnlimitedTime: true, UserId: idUser})
+	database.SaveMetaData(models.File{Id: idLinkFileAdmin, Name: "adminFile", SHA1: "e017693e4a04a59d0b0f400fe98177fe7ee13cf7",
+		UnlimitedDownloads: true, UnlimitedTime: true, UserId: idAdmin})
+	database.SaveMetaData(models.File{Id: idLinkFileE2E, Name: "e2eFile", SHA1: "e017693e4a04a59d0b0f400fe98177fe7ee13cf7",
+		UnlimitedDownloads: true, UnlimitedTime: true, UserId: idUser,
+		Encryption: models.EncryptionInfo{IsEncrypted: true, IsEndToEndEncrypted: true}})
+
+	testAuthorisation(t, "/sharelink/create", models.ApiPermEdit)
+	testAuthorisation(t, "/sharelink/list", models.ApiPermView)
+	testAuthorisation(t, "/sharelink/delete", models.ApiPermEdit)
+
+	apiKeyUser := generateNewKey(false, idUser, "", "")
+	setPermissionApikey(t, apiKeyUser.Id, models.ApiPermEdit)
+	setPermissionApikey(t, apiKeyUser.Id, models.ApiPermView)
+	apiKeyAdmin := generateNewKey(false, idAdmin, "", "")
+	setPermissionApikey(t, apiKeyAdmin.Id, models.ApiPermEdit)
+	setPermissionApikey(t, apiKeyAdmin.Id, models.ApiPermView)
+
+	testInvalidParameters(t, "/sharelink/list", apiKeyUser.Id, []test.Header{{}}, headerFileId, []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header fileId is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"A file with such an ID could not be found","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+		{
+			Value:        idLinkFileAdmin,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"No permission to view this file","ErrorCode":6}`,
+			StatusCode:   401,
+		},
+	})
+	w, r := getRecorder("/sharelink/list", apiKeyUser.Id, []test.Header{{Name: headerFileId, Value: idLinkFileUser}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyIs(t, w, "[]")
+
+	testInvalidParameters(t, "/sharelink/create", apiKeyUser.Id, []test.Header{{}}, headerFileId, []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header fileId is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"A file with such an ID could not be found","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+		{
+			Value:        idLinkFileAdmin,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"No permission to share this file","ErrorCode":6}`,
+			StatusCode:   401,
+		},
+		{
+			Value:        idLinkFileE2E,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"share links cannot be created for end-to-end encrypted files or files of file requests","ErrorCode":18}`,
+			StatusCode:   400,
+		},
+	})
+	testInvalidParameters(t, "/sharelink/create", apiKeyUser.Id, []test.Header{{Name: headerFileId, Value: idLinkFileUser}}, "expiryDays", []invalidParameterValue{
+		{
+			Value:        "-1",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"invalid value for expiryDays","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+	})
+
+	w, r = getRecorder("/sharelink/create", apiKeyUser.Id, []test.Header{
+		{Name: headerFileId, Value: idLinkFileUser},
+		{Name: "name", Value: "base64:" + base64.StdEncoding.EncodeToString([]byte("Customer A"))},
+		{Name: "password", Value: "secret"},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var result models.ShareLinkApiOutput
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	test.IsNil(t, err)
+	test.IsEqualString(t, result.Name, "Customer A")
+	test.IsEqualString(t, result.FileId, idLinkFileUser)
+	test.IsEqualInt(t, result.DownloadsRemaining, 1)
+	test.IsEqualBool(t, result.UnlimitedDownloads, false)
+	test.IsEqualBool(t, result.UnlimitedTime, false)
+	test.IsEqualBool(t, result.IsPasswordProtected, true)
+	test.IsEqualInt(t, result.UserId, idUser)
+	test.IsEqualString(t, result.UrlDownload, configuration.Get().ServerUrl+"s?id="+result.Id)
+	stored, ok := database.GetShareLink(result.Id)
+	test.IsEqualBool(t, ok, true)
+	isValid, _ := configuration.VerifyPassword("secret", stored.PasswordHash, "")
+	test.IsEqualBool(t, isValid, true)
+
+	// Admin has the permission to edit other uploads
+	w, r = getRecorder("/sharelink/create", apiKeyAdmin.Id, []test.Header{
+		{Name: headerFileId, Value: idLinkFileUser},
+		{Name: "allowedDownloads", Value: "0"},
+		{Name: "expiryDays", Value: "0"},
+	})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	var resultAdmin models.ShareLinkApiOutput
+	err = json.Unmarshal(w.Body.Bytes(), &resultAdmin)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, resultAdmin.UnlimitedDownloads, true)
+	test.IsEqualBool(t, resultAdmin.UnlimitedTime, true)
+	test.IsEqualBool(t, resultAdmin.IsPasswordProtected, false)
+	test.IsEqualInt(t, resultAdmin.UserId, idAdmin)
+
+	var links []models.ShareLinkApiOutput
+	w, r = getRecorder("/sharelink/list", apiKeyUser.Id, []test.Header{{Name: headerFileId, Value: idLinkFileUser}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err = json.Unmarshal(w.Body.Bytes(), &links)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(links), 2)
+
+	testInvalidParameters(t, "/sharelink/delete", apiKeyUser.Id, []test.Header{{}}, "id", []invalidParameterValue{
+		{
+			Value:        "",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"header id is required","ErrorCode":4}`,
+			StatusCode:   400,
+		},
+		{
+			Value:        "invalid",
+			ErrorMessage: `{"Result":"error","ErrorMessage":"Share link does not exist with the given ID","ErrorCode":5}`,
+			StatusCode:   404,
+		},
+		{
+			Value:        resultAdmin.Id,
+			ErrorMessage: `{"Result":"error","ErrorMessage":"No permission to delete this share link","ErrorCode":6}`,
+			StatusCode:   401,
+		},
+	})
+	w, r = getRecorder("/sharelink/delete", apiKeyUser.Id, []test.Header{{Name: "id", Value: result.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	_, ok = database.GetShareLink(result.Id)
+	test.IsEqualBool(t, ok, false)
+	// Revoking a link does not affect the file or other links
+	_, ok = database.GetShareLink(resultAdmin.Id)
+	test.IsEqualBool(t, ok, true)
+	_, ok = database.GetMetaDataById(idLinkFileUser)
+	test.IsEqualBool(t, ok, true)
+	w, r = getRecorder("/sharelink/delete", apiKeyAdmin.Id, []test.Header{{Name: "id", Value: resultAdmin.Id}})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+
+	defer test.ExpectPanic(t)
+	apiShareLinkCreate(w, &paramAuthCreate{}, models.User{Id: 7})
+}
+
+func TestLogsQuery(t *testing.T) {
+	const apiUrl = "/logs/query"
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageLogs)
+	database.DeleteAuditEventsBefore(time.Now().Unix() + 1)
+	logging.InitAuditLog()
+
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	test.ResponseBodyIs(t, w, "[]")
+
+	apiKeyUsers := testAuthorisation(t, "/user/create", models.ApiPermManageUsers)
+	w, r = getRecorder("/user/create", apiKeyUsers.Id, []test.Header{{Name: "username", Value: "auditUser"}})
+	r.RemoteAddr = "10.0.0.5:1234"
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	database.SaveAuditEvent(models.AuditEvent{Timestamp: 100, Action: models.AuditActionFileDelete, Result: models.AuditResultSuccess, UserId: idAdmin, FileId: "auditFile"})
+	database.SaveAuditEvent(models.AuditEvent{Timestamp: 200, Action: models.AuditActionLogin, Result: models.AuditResultFailure, Ip: "10.0.0.6"})
+
+	var result []models.AuditEvent
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(result), 3)
+	test.IsEqualString(t, w.Header().Get("X-Next-Cursor"), "")
+	test.IsEqualString(t, result[2].Action, models.AuditActionUserCreate)
+	test.IsEqualString(t, result[2].Result, models.AuditResultSuccess)
+	test.IsEqualString(t, result[2].ApiKeyId, apiKeyUsers.PublicId)
+	test.IsEqualString(t, result[2].Ip, "10.0.0.5")
+	test.IsEqualInt(t, result[2].UserId, idUser)
+
+	queries := []struct {
+		Header   test.Header
+		Expected int
+	}{
+		{test.Header{Name: "userId", Value: strconv.Itoa(idAdmin)}, 1},
+		{test.Header{Name: "apiKeyId", Value: apiKeyUsers.PublicId}, 1},
+		{test.Header{Name: "ip", Value: "10.0.0.6"}, 1},
+		{test.Header{Name: "fileId", Value: "auditFile"}, 1},
+		{test.Header{Name: "action", Value: "user."}, 1},
+		{test.Header{Name: "action", Value: models.AuditActionLogin}, 1},
+		{test.Header{Name: "result", Value: models.AuditResultFailure}, 1},
+		{test.Header{Name: "since", Value: "150"}, 2},
+		{test.Header{Name: "until", Value: "150"}, 1},
+	}
+	for _, query := range queries {
+		w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{query.Header})
+		Process(w, r)
+		test.IsEqualInt(t, w.Code, 200)
+		result = []models.AuditEvent{}
+		err = json.Unmarshal(w.Body.Bytes(), &result)
+		test.IsNil(t, err)
+		test.IsEqualInt(t, len(result), query.Expected)
+	}
+
+	var allIds []int64
+	cursor := ""
+	for i := 0; i < 3; i++ {
+		headers := []test.Header{{Name: "limit", Value: "1"}}
+		if cursor != "" {
+			headers = append(headers, test.Header{Name: "cursor", Value: cursor})
+		}
+		w, r = getRecorder(apiUrl, apiKey.Id, headers)
+		Process(w, r)
+		test.IsEqualInt(t, w.Code, 200)
+		result = []models.AuditEvent{}
+		err = json.Unmarshal(w.Body.Bytes(), &result)
+		test.IsNil(t, err)
+		test.IsEqualInt(t, len(result), 1)
+		allIds = append(allIds, result[0].Id)
+		cursor = w.Header().Get("X-Next-Cursor")
+	}
+	test.IsEqualString(t, cursor, "")
+	test.IsEqualBool(t, allIds[0] > allIds[1] && allIds[1] > allIds[2], true)
+
+	for _, header := range []test.Header{{Name: "limit", Value: "0"}, {Name: "limit", Value: "1001"},
+		{Name: "result", Value: "invalid"}, {Name: "cursor", Value: "-1"}, {Name: "cursor", Value: "invalid"}} {
+		w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{header})
+		Process(w, r)
+		test.IsEqualInt(t, w.Code, 400)
+	}
+}
+
+func TestLogsSystemStatus(t *testing.T) {
+	const apiUrl = "/logs/systemStatus"
+	apiKey := testAuthorisation(t, apiUrl, models.ApiPermManageLogs)
+	var result struct {
+		CleanupLeader models.LeaderStatus `json:"cleanupLeader"`
+	}
+	w, r := getRecorder(apiUrl, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, result.CleanupLeader.IsLeader, true)
+	test.IsEmpty(t, result.CleanupLeader.Leader)
+
+	leader.Init()
+	defer leader.Shutdown()
+	w, r = getRecorder(apiUrl, apiKey.Id, []test.Header{})
+	Process(w, r)
+	test.IsEqualInt(t, w.Code, 200)
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, result.CleanupLeader.IsLeader, true)
+	test.IsNotEmpty(t, result.CleanupLeader.InstanceId)
+	test.IsEqualString(t, result.CleanupLeader.Leader, result.CleanupLeader.InstanceId)
+}
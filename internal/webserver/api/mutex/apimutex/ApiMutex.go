@@ -2,16 +2,29 @@ package apimutex
 
 import (
 	"hash/fnv"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
 )
 
+// sharedLockDuration is the time after which a lock that is shared with other instances is
+// released automatically, in case the instance holding it is terminated. It is renewed while the lock is held
+const sharedLockDuration = 30 * time.Second
+
 const numStripes = 256
 
 var stripes [numStripes]sync.Mutex
 
 func getStripe(objectType int, key string) *sync.Mutex {
+	return &stripes[getStripeIndex(objectType, key)]
+}
+
+func getStripeIndex(objectType int, key string) uint32 {
 	switch objectType {
-	case TypeUser, TypeApiKey, TypeMetaData:
+	case TypeUser, TypeApiKey, TypeMetaData, TypeWebhook, TypeBundle:
 		// valid
 	default:
 		panic("invalid object type")
@@ -20,19 +33,99 @@ func getStripe(objectType int, key string) *sync.Mutex {
 	h := fnv.New32a()
 	_, _ = h.Write([]byte{byte(objectType)})
 	_, _ = h.Write([]byte(key))
-	return &stripes[h.Sum32()%numStripes]
+	return h.Sum32() % numStripes
 }
 
 const (
 	TypeUser = iota
 	TypeApiKey
 	TypeMetaData
+	TypeWebhook
+	TypeBundle
 )
 
 func Lock(objectType int, key string) {
 	getStripe(objectType, key).Lock()
+	if sharedstate.IsEnabled() {
+		lockShared(getSharedName(objectType, key))
+	}
 }
 
 func Unlock(objectType int, key string) {
+	if sharedstate.IsEnabled() {
+		unlockShared(getSharedName(objectType, key))
+	}
 	getStripe(objectType, key).Unlock()
 }
+
+// lockShared acquires the lock that is shared with other instances. If the shared state cannot be reached,
+// only the local lock is held
+func lockShared(name string) {
+	err := sharedstate.Lock(name, sharedLockDuration)
+	if err != nil {
+		sharedstate.PrintError(err)
+	}
+}
+
+func unlockShared(name string) {
+	err := sharedstate.Unlock(name)
+	if err != nil {
+		sharedstate.PrintError(err)
+	}
+}
+
+func getSharedName(objectType int, key string) string {
+	return "apimutex:" + strconv.Itoa(objectType) + ":" + key
+}
+
+// LockAll locks multiple keys of the same type at once. Calling Lock for each key instead could deadlock,
+// as several keys might share the same stripe. The stripes are always locked in the same order
+func LockAll(objectType int, keys []string) {
+	for _, index := range getStripeIndices(objectType, keys) {
+		stripes[index].Lock()
+	}
+	if sharedstate.IsEnabled() {
+		for _, key := range getUniqueKeys(keys) {
+			lockShared(getSharedName(objectType, key))
+		}
+	}
+}
+
+// UnlockAll unlocks keys that have been locked with LockAll
+func UnlockAll(objectType int, keys []string) {
+	if sharedstate.IsEnabled() {
+		for _, key := range getUniqueKeys(keys) {
+			unlockShared(getSharedName(objectType, key))
+		}
+	}
+	for _, index := range getStripeIndices(objectType, keys) {
+		stripes[index].Unlock()
+	}
+}
+
+func getStripeIndices(objectType int, keys []string) []uint32 {
+	isAdded := make(map[uint32]bool)
+	result := make([]uint32, 0, len(keys))
+	for _, key := range keys {
+		index := getStripeIndex(objectType, key)
+		if !isAdded[index] {
+			isAdded[index] = true
+			result = append(result, index)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+func getUniqueKeys(keys []string) []string {
+	isAdded := make(map[string]bool)
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !isAdded[key] {
+			isAdded[key] = true
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -148,3 +148,33 @@ func TestIndependentTypesDoNotNecessarilyBlock(t *testing.T) {
 
 	<-done
 }
+
+// TestLockAllWithSharedStripe verifies that LockAll does not deadlock if keys share a stripe
+// and that the keys are locked until UnlockAll is called.
+func TestLockAllWithSharedStripe(t *testing.T) {
+	keyA := "0"
+	keyB := ""
+	for i := 1; i < 10000; i++ {
+		candidate := fmt.Sprintf("%d", i)
+		if getStripe(TypeMetaData, candidate) == getStripe(TypeMetaData, keyA) {
+			keyB = candidate
+			break
+		}
+	}
+	test.IsNotEqualString(t, keyB, "")
+
+	LockAll(TypeMetaData, []string{keyA, keyB, keyA, "other"})
+	acquired := make(chan struct{})
+	go func() {
+		Lock(TypeMetaData, keyB)
+		close(acquired)
+		Unlock(TypeMetaData, keyB)
+	}()
+	select {
+	case <-acquired:
+		t.Error("Lock acquired while LockAll was still held")
+	default:
+	}
+	UnlockAll(TypeMetaData, []string{keyA, keyB, keyA, "other"})
+	<-acquired
+}
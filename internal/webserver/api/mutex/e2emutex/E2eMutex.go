@@ -1,8 +1,11 @@
 package e2emutex
 
 import (
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
 )
 
 const autoUnlockDuration = 30 * time.Second
@@ -11,6 +14,7 @@ var mutexMap = make(map[int]*timedMutex)
 var globalMutex sync.Mutex
 
 type timedMutex struct {
+	sharedName string
 	mutex      sync.Mutex
 	timer      *time.Timer
 	isLocked   bool
@@ -31,7 +35,19 @@ func Unlock(userId int) {
 
 // IsLocked returns true if the mutex for the given user is locked
 func IsLocked(userId int) bool {
-	return getMutex(userId).isLocked
+	m := getMutex(userId)
+	if m.isLocked {
+		return true
+	}
+	if !sharedstate.IsEnabled() {
+		return false
+	}
+	isLocked, err := sharedstate.Get().IsLocked(m.sharedName)
+	if err != nil {
+		sharedstate.PrintError(err)
+		return false
+	}
+	return isLocked
 }
 
 func getMutex(userId int) *timedMutex {
@@ -40,7 +56,7 @@ func getMutex(userId int) *timedMutex {
 
 	m, ok := mutexMap[userId]
 	if !ok {
-		m = &timedMutex{}
+		m = &timedMutex{sharedName: "e2emutex:" + strconv.Itoa(userId)}
 		mutexMap[userId] = m
 	}
 	return m
@@ -48,6 +64,12 @@ func getMutex(userId int) *timedMutex {
 
 func (t *timedMutex) lock() {
 	t.mutex.Lock()
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Lock(t.sharedName, autoUnlockDuration)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+	}
 
 	t.stateMutex.Lock()
 	t.isLocked = true
@@ -70,6 +92,12 @@ func (t *timedMutex) unlock() {
 		t.timer = nil
 	}
 
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Unlock(t.sharedName)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+	}
 	t.isLocked = false
 	t.mutex.Unlock()
 }
@@ -420,6 +420,7 @@ var validApiPermissionScopes = []string{
 	"MANAGE_LOGS",
 	"MANAGE_FILE_REQUESTS",
 	"DOWNLOAD",
+	"MANAGE_WEBHOOKS",
 	"SPECIFIC_GUEST_API_KEY",
 }
 
@@ -435,6 +436,7 @@ var apiPermToScope = map[models.ApiPermission]string{
 	models.ApiPermManageLogs:         "MANAGE_LOGS",
 	models.ApiPermManageFileRequests: "MANAGE_FILE_REQUESTS",
 	models.ApiPermDownload:           "DOWNLOAD",
+	models.ApiPermManageWebhooks:     "MANAGE_WEBHOOKS",
 }
 
 // validateSecurityScopes checks two things for every non-e2e route:
@@ -9,9 +9,11 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/forceu/gokapi/internal/logging/mail"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage"
 	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/storage/scrub"
 )
 
 type apiRoute struct {
@@ -91,6 +93,14 @@ var routes = []apiRoute{
 		execution:     apiChunkComplete,
 		RequestParser: &paramChunkComplete{},
 	},
+	{
+		Url:            "/tus/",
+		ApiPerm:        models.ApiPermUpload,
+		execution:      apiTus,
+		NoJsonResponse: true,
+		HasWildcard:    true,
+		RequestParser:  &paramTus{},
+	},
 	{
 		Url:           "/files/add",
 		ApiPerm:       models.ApiPermUpload,
@@ -115,12 +125,37 @@ var routes = []apiRoute{
 		execution:     apiEditFile,
 		RequestParser: &paramFilesModify{},
 	},
+	{
+		Url:           "/files/sendLink",
+		ApiPerm:       models.ApiPermEdit,
+		execution:     apiSendLink,
+		RequestParser: &paramFilesSendLink{},
+	},
 	{
 		Url:           "/files/replace",
 		ApiPerm:       models.ApiPermReplace,
 		execution:     apiReplaceFile,
 		RequestParser: &paramFilesReplace{},
 	},
+	{
+		Url:           "/files/versions/list",
+		ApiPerm:       models.ApiPermView,
+		execution:     apiListFileVersions,
+		RequestParser: &paramFilesVersionsList{},
+	},
+	{
+		Url:            "/files/versions/download",
+		ApiPerm:        models.ApiPermDownload,
+		NoJsonResponse: true,
+		execution:      apiDownloadFileVersion,
+		RequestParser:  &paramFilesVersionsDownload{},
+	},
+	{
+		Url:           "/files/versions/rollback",
+		ApiPerm:       models.ApiPermReplace,
+		execution:     apiRollbackFile,
+		RequestParser: &paramFilesVersionsRollback{},
+	},
 	{
 		Url:           "/files/restore",
 		ApiPerm:       models.ApiPermDelete,
@@ -181,6 +216,12 @@ var routes = []apiRoute{
 		execution:     apiResetPassword,
 		RequestParser: &paramUserResetPw{},
 	},
+	{
+		Url:           "/user/resetTwoFactor",
+		ApiPerm:       models.ApiPermManageUsers,
+		execution:     apiResetTwoFactor,
+		RequestParser: &paramUserResetTwoFactor{},
+	},
 	{
 		Url:           "/uploadrequest/list",
 		ApiPerm:       models.ApiPermManageFileRequests,
@@ -260,6 +301,123 @@ var routes = []apiRoute{
 		execution:     apiLogsGet,
 		RequestParser: &paramLogsGet{},
 	},
+	{
+		Url:           "/logs/query",
+		ApiPerm:       models.ApiPermManageLogs,
+		execution:     apiLogsQuery,
+		RequestParser: &paramLogsQuery{},
+	},
+	{
+		Url:           "/encryption/rotateKey",
+		ApiPerm:       models.ApiPermManageUsers,
+		AdminOnly:     true,
+		execution:     apiEncryptionRotateKey,
+		RequestParser: &paramEncryptionRotateKey{},
+	},
+	{
+		Url:           "/encryption/encryptFiles",
+		ApiPerm:       models.ApiPermManageUsers,
+		AdminOnly:     true,
+		execution:     apiEncryptionEncryptFiles,
+		RequestParser: &paramEncryptionEncryptFiles{},
+	},
+	{
+		Url:           "/scrub/start",
+		ApiPerm:       models.ApiPermManageLogs,
+		AdminOnly:     true,
+		execution:     apiScrubStart,
+		RequestParser: &paramScrubStart{},
+	},
+	{
+		Url:           "/scrub/report",
+		ApiPerm:       models.ApiPermManageLogs,
+		AdminOnly:     true,
+		execution:     apiScrubReport,
+		RequestParser: nil,
+	},
+	{
+		Url:           "/scrub/resolve",
+		ApiPerm:       models.ApiPermManageLogs,
+		AdminOnly:     true,
+		execution:     apiScrubResolve,
+		RequestParser: &paramScrubResolve{},
+	},
+	{
+		Url:           "/webhooks/list",
+		ApiPerm:       models.ApiPermManageWebhooks,
+		AdminOnly:     true,
+		execution:     apiWebhooksList,
+		RequestParser: nil,
+	},
+	{
+		Url:           "/webhooks/add",
+		ApiPerm:       models.ApiPermManageWebhooks,
+		AdminOnly:     true,
+		execution:     apiWebhooksAdd,
+		RequestParser: &paramWebhookAdd{},
+	},
+	{
+		Url:           "/webhooks/modify",
+		ApiPerm:       models.ApiPermManageWebhooks,
+		AdminOnly:     true,
+		execution:     apiWebhooksModify,
+		RequestParser: &paramWebhookModify{},
+	},
+	{
+		Url:           "/webhooks/delete",
+		ApiPerm:       models.ApiPermManageWebhooks,
+		AdminOnly:     true,
+		execution:     apiWebhooksDelete,
+		RequestParser: &paramWebhookDelete{},
+	},
+	{
+		Url:           "/bundle/list",
+		ApiPerm:       models.ApiPermView,
+		execution:     apiBundleList,
+		RequestParser: nil,
+	},
+	{
+		Url:           "/bundle/create",
+		ApiPerm:       models.ApiPermEdit,
+		execution:     apiBundleCreate,
+		RequestParser: &paramBundleCreate{},
+	},
+	{
+		Url:           "/bundle/addFiles",
+		ApiPerm:       models.ApiPermEdit,
+		execution:     apiBundleAddFiles,
+		RequestParser: &paramBundleModifyFiles{},
+	},
+	{
+		Url:           "/bundle/removeFiles",
+		ApiPerm:       models.ApiPermEdit,
+		execution:     apiBundleRemoveFiles,
+		RequestParser: &paramBundleModifyFiles{},
+	},
+	{
+		Url:           "/bundle/delete",
+		ApiPerm:       models.ApiPermEdit,
+		execution:     apiBundleDelete,
+		RequestParser: &paramBundleDelete{},
+	},
+	{
+		Url:           "/sharelink/list",
+		ApiPerm:       models.ApiPermView,
+		execution:     apiShareLinkList,
+		RequestParser: &paramShareLinkList{},
+	},
+	{
+		Url:           "/sharelink/create",
+		ApiPerm:       models.ApiPermEdit,
+		execution:     apiShareLinkCreate,
+		RequestParser: &paramShareLinkCreate{},
+	},
+	{
+		Url:           "/sharelink/delete",
+		ApiPerm:       models.ApiPermEdit,
+		execution:     apiShareLinkDelete,
+		RequestParser: &paramShareLinkDelete{},
+	},
 	{
 		Url:           "/e2e/get", // not published in API documentation
 		ApiPerm:       models.ApiPermUpload,
@@ -305,12 +463,72 @@ type requestParser interface {
 	New() requestParser
 }
 
+// maxListLimit is the maximum number of files that can be requested per page with /files/list
+const maxListLimit = 1000
+
 type paramFilesListAll struct {
-	ShowFileRequests bool `header:"showFileRequests"`
+	ShowFileRequests bool   `header:"showFileRequests"`
+	Limit            int    `header:"limit"`
+	Cursor           string `header:"cursor"`
+	SortBy           string `header:"sortBy"`
+	SortOrder        string `header:"sortOrder"`
+	Uploader         int    `header:"uploader"`
+	Name             string `header:"name" supportBase64:"true"`
+	ContentType      string `header:"contentType"`
+	ExpiresAfter     int64  `header:"expiresAfter"`
+	ExpiresBefore    int64  `header:"expiresBefore"`
+	FileRequestId    string `header:"fileRequestId"`
+	Encrypted        bool   `header:"encrypted"`
+	Query            models.FileQuery
 	foundHeaders     map[string]bool
 }
 
 func (p *paramFilesListAll) ProcessParameter(_ *http.Request) error {
+	p.Query = models.FileQuery{
+		UserId:              p.Uploader,
+		NameContains:        p.Name,
+		ContentTypePrefix:   p.ContentType,
+		FileRequestId:       p.FileRequestId,
+		IncludeFileRequests: p.ShowFileRequests,
+		ExpiresAfter:        p.ExpiresAfter,
+		ExpiresBefore:       p.ExpiresBefore,
+		SortDescending:      true,
+	}
+	if p.foundHeaders["limit"] && (p.Limit < 1 || p.Limit > maxListLimit) {
+		return errors.New("invalid limit, must be between 1 and " + strconv.Itoa(maxListLimit))
+	}
+	p.Query.Limit = p.Limit
+	if p.foundHeaders["encrypted"] {
+		p.Query.Encryption = models.FileFilterEncryptionNone
+		if p.Encrypted {
+			p.Query.Encryption = models.FileFilterEncryptionOnly
+		}
+	}
+	switch strings.ToLower(p.SortBy) {
+	case "", "uploaddate":
+		p.Query.SortBy = models.FileSortUploadDate
+	case "name":
+		p.Query.SortBy = models.FileSortName
+	case "size":
+		p.Query.SortBy = models.FileSortSize
+	case "expiry":
+		p.Query.SortBy = models.FileSortExpiry
+	case "downloads":
+		p.Query.SortBy = models.FileSortDownloads
+	default:
+		return errors.New("invalid sortBy value supplied")
+	}
+	switch strings.ToLower(p.SortOrder) {
+	case "", "desc":
+		p.Query.SortDescending = true
+	case "asc":
+		p.Query.SortDescending = false
+	default:
+		return errors.New("invalid sortOrder value supplied")
+	}
+	if p.Cursor != "" {
+		return p.Query.SetCursor(p.Cursor)
+	}
 	return nil
 }
 
@@ -419,13 +637,24 @@ type paramFilesModify struct {
 	ExpiryTimestamp    int64  `header:"expiryTimestamp"`
 	Password           string `header:"password"`
 	KeepPassword       bool   `header:"originalPassword"`
+	PublishAt          int64  `header:"publishAt"`
+	ExpiryFromPublish  bool   `header:"expiryFromPublish"`
+	RequireLogin       bool   `header:"requireLogin"`
+	AllowedUsers       string `header:"allowedUsers" supportBase64:"true"`
+	AllowedGroups      string `header:"allowedGroups" supportBase64:"true"`
 	UnlimitedDownloads bool
 	UnlimitedExpiry    bool
 	IsPasswordSet      bool
+	IsPublishAtSet     bool
+	IsRequireLoginSet  bool
+	IsAllowedUsersSet  bool
+	IsAllowedGroupsSet bool
+	Request            *http.Request
 	foundHeaders       map[string]bool
 }
 
-func (p *paramFilesModify) ProcessParameter(_ *http.Request) error {
+func (p *paramFilesModify) ProcessParameter(r *http.Request) error {
+	p.Request = r
 	if p.foundHeaders["allowedDownloads"] && p.AllowedDownloads == 0 {
 		p.UnlimitedDownloads = true
 	}
@@ -433,6 +662,32 @@ func (p *paramFilesModify) ProcessParameter(_ *http.Request) error {
 		p.UnlimitedExpiry = true
 	}
 	p.IsPasswordSet = p.foundHeaders["password"]
+	p.IsPublishAtSet = p.foundHeaders["publishAt"]
+	p.IsRequireLoginSet = p.foundHeaders["requireLogin"]
+	p.IsAllowedUsersSet = p.foundHeaders["allowedUsers"]
+	p.IsAllowedGroupsSet = p.foundHeaders["allowedGroups"]
+	return nil
+}
+
+type paramFilesSendLink struct {
+	Id            string `header:"id" required:"true"`
+	RecipientList string `header:"recipients" required:"true" supportBase64:"true"`
+	Message       string `header:"message" supportBase64:"true"`
+	Recipients    []string
+	Request       *http.Request
+	foundHeaders  map[string]bool
+}
+
+func (p *paramFilesSendLink) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	var err error
+	p.Recipients, err = mail.ParseRecipients(p.RecipientList)
+	if err != nil {
+		return err
+	}
+	if len(p.Recipients) == 0 {
+		return errors.New("no recipients provided")
+	}
 	return nil
 }
 
@@ -440,25 +695,68 @@ type paramFilesReplace struct {
 	Id            string `header:"id" required:"true"`
 	IdNewContent  string `header:"idNewContent" required:"true"`
 	DeleteNewFile bool   `header:"deleteNewFile"`
+	Request       *http.Request
 	foundHeaders  map[string]bool
 }
 
-func (p *paramFilesReplace) ProcessParameter(_ *http.Request) error { return nil }
+func (p *paramFilesReplace) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
+
+type paramFilesVersionsList struct {
+	Id           string `header:"id" required:"true"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramFilesVersionsList) ProcessParameter(_ *http.Request) error { return nil }
+
+type paramFilesVersionsDownload struct {
+	Id           string `header:"id" required:"true"`
+	VersionId    string `header:"versionId" required:"true"`
+	WebRequest   *http.Request
+	foundHeaders map[string]bool
+}
+
+func (p *paramFilesVersionsDownload) ProcessParameter(r *http.Request) error {
+	p.WebRequest = r
+	return nil
+}
+
+type paramFilesVersionsRollback struct {
+	Id           string `header:"id" required:"true"`
+	VersionId    string `header:"versionId" required:"true"`
+	Request      *http.Request
+	foundHeaders map[string]bool
+}
+
+func (p *paramFilesVersionsRollback) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
 
 type paramFilesDelete struct {
 	Id           string `header:"id" required:"true"`
 	DelaySeconds int    `header:"delay"`
+	Request      *http.Request
 	foundHeaders map[string]bool
 }
 
-func (p *paramFilesDelete) ProcessParameter(_ *http.Request) error { return nil }
+func (p *paramFilesDelete) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
 
 type paramFilesRestore struct {
 	Id           string `header:"id" required:"true"`
+	Request      *http.Request
 	foundHeaders map[string]bool
 }
 
-func (p *paramFilesRestore) ProcessParameter(_ *http.Request) error { return nil }
+func (p *paramFilesRestore) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
 
 type paramAuthCreate struct {
 	FriendlyName     string `header:"friendlyName"`
@@ -511,19 +809,25 @@ func (p *paramAuthDelete) ProcessParameter(_ *http.Request) error { return nil }
 
 type paramUserCreate struct {
 	Username     string `header:"username" required:"true"`
+	Request      *http.Request
 	foundHeaders map[string]bool
 }
 
-func (p *paramUserCreate) ProcessParameter(_ *http.Request) error { return nil }
+func (p *paramUserCreate) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
 
 type paramUserChangeRank struct {
 	Id           int    `header:"userid" required:"true"`
 	newRankRaw   string `header:"newRank" required:"true"`
 	NewRank      models.UserRank
+	Request      *http.Request
 	foundHeaders map[string]bool
 }
 
-func (p *paramUserChangeRank) ProcessParameter(_ *http.Request) error {
+func (p *paramUserChangeRank) ProcessParameter(r *http.Request) error {
+	p.Request = r
 	switch strings.ToLower(p.newRankRaw) {
 	case "admin":
 		p.NewRank = models.UserLevelAdmin
@@ -538,21 +842,52 @@ func (p *paramUserChangeRank) ProcessParameter(_ *http.Request) error {
 type paramUserDelete struct {
 	Id           int  `header:"userid" required:"true"`
 	DeleteFiles  bool `header:"deleteFiles"`
+	Request      *http.Request
 	foundHeaders map[string]bool
 }
 
-func (p *paramUserDelete) ProcessParameter(_ *http.Request) error { return nil }
+func (p *paramUserDelete) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
 
 type paramUserModify struct {
 	Id                 int `header:"userid" required:"true"`
 	Permission         models.UserPermission
-	permissionRaw      string `header:"userpermission" required:"true"`
-	permissionModifier string `header:"permissionModifier" required:"true"`
+	permissionRaw      string `header:"userpermission"`
+	permissionModifier string `header:"permissionModifier"`
 	GrantPermission    bool
+	IsPermissionChange bool
+	QuotaStorageMb     int `header:"quotaStorageMb"`
+	QuotaFiles         int `header:"quotaFiles"`
+	QuotaExpiryDays    int `header:"quotaExpiryDays"`
+	QuotaDownloads     int `header:"quotaDownloads"`
+	IsQuotaChange      bool
+	Request            *http.Request
 	foundHeaders       map[string]bool
 }
 
-func (p *paramUserModify) ProcessParameter(_ *http.Request) error {
+func (p *paramUserModify) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	for _, header := range []string{"quotaStorageMb", "quotaFiles", "quotaExpiryDays", "quotaDownloads"} {
+		if p.foundHeaders[header] {
+			p.IsQuotaChange = true
+		}
+	}
+	if p.QuotaStorageMb < models.QuotaUseDefault || p.QuotaFiles < models.QuotaUseDefault ||
+		p.QuotaExpiryDays < models.QuotaUseDefault || p.QuotaDownloads < models.QuotaUseDefault {
+		return errors.New("invalid quota value")
+	}
+	p.IsPermissionChange = p.permissionRaw != "" || p.permissionModifier != "" || !p.IsQuotaChange
+	if !p.IsPermissionChange {
+		return nil
+	}
+	if p.permissionRaw == "" {
+		return errors.New("header userpermission is required")
+	}
+	if p.permissionModifier == "" {
+		return errors.New("header permissionModifier is required")
+	}
 	switch strings.ToUpper(p.permissionRaw) {
 	case "PERM_REPLACE":
 		p.Permission = models.UserPermReplaceUploads
@@ -594,6 +929,17 @@ type paramUserResetPw struct {
 
 func (p *paramUserResetPw) ProcessParameter(_ *http.Request) error { return nil }
 
+type paramUserResetTwoFactor struct {
+	Id           int `header:"userid"  required:"true"`
+	Request      *http.Request
+	foundHeaders map[string]bool
+}
+
+func (p *paramUserResetTwoFactor) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
+
 type paramE2eStore struct {
 	EncryptedInfo models.E2EInfoEncrypted
 	foundHeaders  map[string]bool
@@ -641,6 +987,269 @@ func (p *paramLogsGet) ProcessParameter(_ *http.Request) error {
 	return nil
 }
 
+// defaultLogsQueryLimit is the number of audit events returned by /logs/query, if no limit was requested
+const defaultLogsQueryLimit = 100
+
+type paramLogsQuery struct {
+	UserId        int    `header:"userId"`
+	ApiKeyId      string `header:"apiKeyId"`
+	Ip            string `header:"ip"`
+	FileId        string `header:"fileId"`
+	FileRequestId string `header:"fileRequestId"`
+	Action        string `header:"action"`
+	Result        string `header:"result"`
+	Since         int64  `header:"since"`
+	Until         int64  `header:"until"`
+	Limit         int    `header:"limit"`
+	Cursor        int64  `header:"cursor"`
+	Query         models.AuditQuery
+	foundHeaders  map[string]bool
+}
+
+func (p *paramLogsQuery) ProcessParameter(_ *http.Request) error {
+	if !p.foundHeaders["limit"] {
+		p.Limit = defaultLogsQueryLimit
+	}
+	if p.Limit < 1 || p.Limit > maxListLimit {
+		return errors.New("invalid limit, must be between 1 and " + strconv.Itoa(maxListLimit))
+	}
+	switch p.Result {
+	case "", models.AuditResultSuccess, models.AuditResultFailure:
+	default:
+		return errors.New("invalid result value supplied")
+	}
+	if p.Cursor < 0 {
+		return errors.New("invalid cursor supplied")
+	}
+	p.Query = models.AuditQuery{
+		UserId:        p.UserId,
+		ApiKeyId:      p.ApiKeyId,
+		Ip:            p.Ip,
+		FileId:        p.FileId,
+		FileRequestId: p.FileRequestId,
+		Action:        p.Action,
+		Result:        p.Result,
+		Since:         p.Since,
+		Until:         p.Until,
+		Limit:         p.Limit,
+		BeforeId:      p.Cursor,
+	}
+	return nil
+}
+
+type paramWebhookAdd struct {
+	Url          string `header:"url" required:"true" supportBase64:"true"`
+	Events       string `header:"events" required:"true"`
+	Webhook      models.Webhook
+	foundHeaders map[string]bool
+}
+
+func (p *paramWebhookAdd) ProcessParameter(_ *http.Request) error {
+	p.Webhook = models.Webhook{}
+	err := p.Webhook.SetUrl(p.Url)
+	if err != nil {
+		return err
+	}
+	return p.Webhook.SetEvents(p.Events)
+}
+
+type paramWebhookModify struct {
+	Id               string `header:"id" required:"true"`
+	Url              string `header:"url" supportBase64:"true"`
+	Events           string `header:"events"`
+	RegenerateSecret bool   `header:"regenerateSecret"`
+	Changes          models.Webhook
+	IsUrlSet         bool
+	IsEventsSet      bool
+	foundHeaders     map[string]bool
+}
+
+func (p *paramWebhookModify) ProcessParameter(_ *http.Request) error {
+	p.Changes = models.Webhook{}
+	p.IsUrlSet = p.foundHeaders["url"]
+	p.IsEventsSet = p.foundHeaders["events"]
+	if p.IsUrlSet {
+		err := p.Changes.SetUrl(p.Url)
+		if err != nil {
+			return err
+		}
+	}
+	if p.IsEventsSet {
+		return p.Changes.SetEvents(p.Events)
+	}
+	return nil
+}
+
+type paramEncryptionRotateKey struct {
+	NewPassword  string `header:"newPassword" supportBase64:"true"`
+	Request      *http.Request
+	foundHeaders map[string]bool
+}
+
+func (p *paramEncryptionRotateKey) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
+
+type paramEncryptionEncryptFiles struct {
+	Request      *http.Request
+	foundHeaders map[string]bool
+}
+
+func (p *paramEncryptionEncryptFiles) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
+
+type paramScrubStart struct {
+	Action       string `header:"action"`
+	Request      *http.Request
+	foundHeaders map[string]bool
+}
+
+func (p *paramScrubStart) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	if p.Action == "" {
+		p.Action = models.ScrubActionNone
+	}
+	if !models.IsValidScrubAction(p.Action) {
+		return scrub.ErrorInvalidAction
+	}
+	return nil
+}
+
+type paramScrubResolve struct {
+	Action       string `header:"action" required:"true"`
+	Request      *http.Request
+	foundHeaders map[string]bool
+}
+
+func (p *paramScrubResolve) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	if !models.IsValidScrubAction(p.Action) {
+		return scrub.ErrorInvalidAction
+	}
+	return nil
+}
+
+type paramWebhookDelete struct {
+	Id           string `header:"id" required:"true"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramWebhookDelete) ProcessParameter(_ *http.Request) error {
+	return nil
+}
+
+type paramBundleCreate struct {
+	Name             string `header:"name" supportBase64:"true"`
+	FileIds          string `header:"fileIds" required:"true"`
+	AllowedDownloads int    `header:"allowedDownloads"`
+	ExpiryDays       int    `header:"expiryDays"`
+	Password         string `header:"password" supportBase64:"true"`
+	Ids              []string
+	foundHeaders     map[string]bool
+}
+
+func (p *paramBundleCreate) ProcessParameter(_ *http.Request) error {
+	if p.AllowedDownloads < 0 {
+		return errors.New("invalid value for allowedDownloads")
+	}
+	if p.ExpiryDays < 0 {
+		return errors.New("invalid value for expiryDays")
+	}
+	if !p.foundHeaders["allowedDownloads"] {
+		p.AllowedDownloads = 1
+	}
+	if !p.foundHeaders["expiryDays"] {
+		p.ExpiryDays = 14
+	}
+	p.Ids = parseIdList(p.FileIds)
+	if len(p.Ids) == 0 {
+		return errors.New("no file IDs submitted")
+	}
+	return nil
+}
+
+type paramBundleModifyFiles struct {
+	Id           string `header:"id" required:"true"`
+	FileIds      string `header:"fileIds" required:"true"`
+	Ids          []string
+	foundHeaders map[string]bool
+}
+
+func (p *paramBundleModifyFiles) ProcessParameter(_ *http.Request) error {
+	p.Ids = parseIdList(p.FileIds)
+	if len(p.Ids) == 0 {
+		return errors.New("no file IDs submitted")
+	}
+	return nil
+}
+
+type paramBundleDelete struct {
+	Id           string `header:"id" required:"true"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramBundleDelete) ProcessParameter(_ *http.Request) error {
+	return nil
+}
+
+type paramShareLinkList struct {
+	FileId       string `header:"fileId" required:"true"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramShareLinkList) ProcessParameter(_ *http.Request) error {
+	return nil
+}
+
+type paramShareLinkCreate struct {
+	FileId           string `header:"fileId" required:"true"`
+	Name             string `header:"name" supportBase64:"true"`
+	AllowedDownloads int    `header:"allowedDownloads"`
+	ExpiryDays       int    `header:"expiryDays"`
+	Password         string `header:"password" supportBase64:"true"`
+	foundHeaders     map[string]bool
+}
+
+func (p *paramShareLinkCreate) ProcessParameter(_ *http.Request) error {
+	if p.AllowedDownloads < 0 {
+		return errors.New("invalid value for allowedDownloads")
+	}
+	if p.ExpiryDays < 0 {
+		return errors.New("invalid value for expiryDays")
+	}
+	if !p.foundHeaders["allowedDownloads"] {
+		p.AllowedDownloads = 1
+	}
+	if !p.foundHeaders["expiryDays"] {
+		p.ExpiryDays = 14
+	}
+	return nil
+}
+
+type paramShareLinkDelete struct {
+	Id           string `header:"id" required:"true"`
+	foundHeaders map[string]bool
+}
+
+func (p *paramShareLinkDelete) ProcessParameter(_ *http.Request) error {
+	return nil
+}
+
+// parseIdList splits a comma-separated list of IDs and removes empty entries and duplicates
+func parseIdList(input string) []string {
+	result := make([]string, 0)
+	for _, id := range strings.Split(input, ",") {
+		id = strings.TrimSpace(id)
+		if id != "" && !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
 type paramChunkAdd struct {
 	Request *http.Request
 }
@@ -654,6 +1263,15 @@ func (p *paramChunkAdd) GetRequest() *http.Request {
 	return p.Request
 }
 
+type paramTus struct {
+	Request *http.Request
+}
+
+func (p *paramTus) ProcessParameter(r *http.Request) error {
+	p.Request = r
+	return nil
+}
+
 type paramChunkUploadRequestAdd struct {
 	Request       *http.Request
 	FileRequestId string `header:"fileRequestId" required:"true"`
@@ -680,13 +1298,24 @@ type paramChunkComplete struct {
 	Password           string `header:"password"`
 	IsE2E              bool   `header:"isE2E" unpublished:"true"` // not published in API documentation
 	IsNonBlocking      bool   `header:"nonblocking"`
+	RecipientList      string `header:"recipients" supportBase64:"true"`
+	Message            string `header:"message" supportBase64:"true"`
+	PublishAt          int64  `header:"publishAt"`
+	ExpiryFromPublish  bool   `header:"expiryFromPublish"`
+	RequireLogin       bool   `header:"requireLogin"`
+	AllowedUsers       string `header:"allowedUsers" supportBase64:"true"`
+	AllowedGroups      string `header:"allowedGroups" supportBase64:"true"`
+	Recipients         []string
 	UnlimitedDownloads bool
 	UnlimitedTime      bool
+	IsPublishAtSet     bool
 	FileHeader         chunking.FileHeader
+	Request            *http.Request
 	foundHeaders       map[string]bool
 }
 
-func (p *paramChunkComplete) ProcessParameter(_ *http.Request) error {
+func (p *paramChunkComplete) ProcessParameter(r *http.Request) error {
+	p.Request = r
 
 	if !p.foundHeaders["realsize"] {
 		if !p.IsE2E {
@@ -719,6 +1348,15 @@ func (p *paramChunkComplete) ProcessParameter(_ *http.Request) error {
 	if p.ContentType == "" {
 		p.ContentType = "application/octet-stream"
 	}
+	var err error
+	p.Recipients, err = mail.ParseRecipients(p.RecipientList)
+	if err != nil {
+		return err
+	}
+	if len(p.Recipients) > 0 && p.IsE2E {
+		return errors.New("download links of end-to-end encrypted files cannot be sent by email")
+	}
+	p.IsPublishAtSet = p.foundHeaders["publishAt"]
 	p.FileHeader = chunking.FileHeader{
 		Filename:    p.FileName,
 		ContentType: p.ContentType,
@@ -730,10 +1368,12 @@ func (p *paramChunkComplete) ProcessParameter(_ *http.Request) error {
 type paramChunkReserve struct {
 	Id           string `header:"id" required:"true"`
 	ApiKey       string `header:"apikey" unpublished:"true"` // not published in API documentation
+	Request      *http.Request
 	foundHeaders map[string]bool
 }
 
-func (p *paramChunkReserve) ProcessParameter(_ *http.Request) error {
+func (p *paramChunkReserve) ProcessParameter(r *http.Request) error {
+	p.Request = r
 	return nil
 }
 
@@ -741,10 +1381,12 @@ type paramChunkUnreserve struct {
 	Id           string `header:"id" required:"true"`
 	Uuid         string `header:"uuid" required:"true"`
 	ApiKey       string `header:"apikey" unpublished:"true"` // not published in API documentation
+	Request      *http.Request
 	foundHeaders map[string]bool
 }
 
-func (p *paramChunkUnreserve) ProcessParameter(_ *http.Request) error {
+func (p *paramChunkUnreserve) ProcessParameter(r *http.Request) error {
+	p.Request = r
 	return nil
 }
 
@@ -757,10 +1399,12 @@ type paramChunkUploadRequestComplete struct {
 	IsNonBlocking bool   `header:"nonblocking"`
 	ApiKey        string `header:"apikey" unpublished:"true"` // not published in API documentation
 	FileHeader    chunking.FileHeader
+	Request       *http.Request
 	foundHeaders  map[string]bool
 }
 
-func (p *paramChunkUploadRequestComplete) ProcessParameter(_ *http.Request) error {
+func (p *paramChunkUploadRequestComplete) ProcessParameter(r *http.Request) error {
+	p.Request = r
 	if p.ContentType == "" {
 		p.ContentType = "application/octet-stream"
 	}
@@ -774,30 +1418,39 @@ func (p *paramChunkUploadRequestComplete) ProcessParameter(_ *http.Request) erro
 
 type paramURequestDelete struct {
 	Id           string `header:"id" required:"true"`
+	Request      *http.Request
 	foundHeaders map[string]bool
 }
 
-func (p *paramURequestDelete) ProcessParameter(_ *http.Request) error {
+func (p *paramURequestDelete) ProcessParameter(r *http.Request) error {
+	p.Request = r
 	return nil
 }
 
 type paramURequestSave struct {
-	Id            string `header:"id"`
-	Name          string `header:"name" supportBase64:"true"`
-	Notes         string `header:"notes" supportBase64:"true"`
-	Expiry        int64  `header:"expiry"`
-	MaxFiles      int    `header:"maxfiles"`
-	MaxSizeMb     int    `header:"maxsize"`
-	IsNameSet     bool
-	IsExpirySet   bool
-	IsMaxFilesSet bool
-	IsMaxSizeSet  bool
-	IsNotesSet    bool
-
-	foundHeaders map[string]bool
+	Id                 string `header:"id"`
+	Name               string `header:"name" supportBase64:"true"`
+	Notes              string `header:"notes" supportBase64:"true"`
+	Expiry             int64  `header:"expiry"`
+	MaxFiles           int    `header:"maxfiles"`
+	MaxSizeMb          int    `header:"maxsize"`
+	RequireLogin       bool   `header:"requireLogin"`
+	AllowedUsers       string `header:"allowedUsers" supportBase64:"true"`
+	AllowedGroups      string `header:"allowedGroups" supportBase64:"true"`
+	IsNameSet          bool
+	IsExpirySet        bool
+	IsMaxFilesSet      bool
+	IsMaxSizeSet       bool
+	IsNotesSet         bool
+	IsRequireLoginSet  bool
+	IsAllowedUsersSet  bool
+	IsAllowedGroupsSet bool
+	Request            *http.Request
+	foundHeaders       map[string]bool
 }
 
-func (p *paramURequestSave) ProcessParameter(_ *http.Request) error {
+func (p *paramURequestSave) ProcessParameter(r *http.Request) error {
+	p.Request = r
 	if p.foundHeaders["name"] {
 		p.IsNameSet = true
 	}
@@ -813,6 +1466,15 @@ func (p *paramURequestSave) ProcessParameter(_ *http.Request) error {
 	if p.foundHeaders["notes"] {
 		p.IsNotesSet = true
 	}
+	if p.foundHeaders["requireLogin"] {
+		p.IsRequireLoginSet = true
+	}
+	if p.foundHeaders["allowedUsers"] {
+		p.IsAllowedUsersSet = true
+	}
+	if p.foundHeaders["allowedGroups"] {
+		p.IsAllowedGroupsSet = true
+	}
 	return nil
 }
 
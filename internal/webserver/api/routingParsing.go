@@ -31,6 +31,138 @@ func (p *paramFilesListAll) ParseRequest(r *http.Request) error {
 		}
 	}
 
+	// RequestParser header value "limit", required: false
+	exists, err = checkHeaderExists(r, "limit", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["limit"] = exists
+	if exists {
+		p.Limit, err = parseHeaderInt(r, "limit")
+		if err != nil {
+			return fmt.Errorf("invalid value in header limit supplied")
+		}
+	}
+
+	// RequestParser header value "cursor", required: false
+	exists, err = checkHeaderExists(r, "cursor", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["cursor"] = exists
+	if exists {
+		p.Cursor = r.Header.Get("cursor")
+	}
+
+	// RequestParser header value "sortBy", required: false
+	exists, err = checkHeaderExists(r, "sortBy", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["sortBy"] = exists
+	if exists {
+		p.SortBy = r.Header.Get("sortBy")
+	}
+
+	// RequestParser header value "sortOrder", required: false
+	exists, err = checkHeaderExists(r, "sortOrder", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["sortOrder"] = exists
+	if exists {
+		p.SortOrder = r.Header.Get("sortOrder")
+	}
+
+	// RequestParser header value "uploader", required: false
+	exists, err = checkHeaderExists(r, "uploader", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["uploader"] = exists
+	if exists {
+		p.Uploader, err = parseHeaderInt(r, "uploader")
+		if err != nil {
+			return fmt.Errorf("invalid value in header uploader supplied")
+		}
+	}
+
+	// RequestParser header value "name", required: false, has base64support
+	exists, err = checkHeaderExists(r, "name", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["name"] = exists
+	if exists {
+		p.Name = r.Header.Get("name")
+		if strings.HasPrefix(p.Name, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.Name, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.Name = string(decoded)
+		}
+	}
+
+	// RequestParser header value "contentType", required: false
+	exists, err = checkHeaderExists(r, "contentType", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["contentType"] = exists
+	if exists {
+		p.ContentType = r.Header.Get("contentType")
+	}
+
+	// RequestParser header value "expiresAfter", required: false
+	exists, err = checkHeaderExists(r, "expiresAfter", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["expiresAfter"] = exists
+	if exists {
+		p.ExpiresAfter, err = parseHeaderInt64(r, "expiresAfter")
+		if err != nil {
+			return fmt.Errorf("invalid value in header expiresAfter supplied")
+		}
+	}
+
+	// RequestParser header value "expiresBefore", required: false
+	exists, err = checkHeaderExists(r, "expiresBefore", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["expiresBefore"] = exists
+	if exists {
+		p.ExpiresBefore, err = parseHeaderInt64(r, "expiresBefore")
+		if err != nil {
+			return fmt.Errorf("invalid value in header expiresBefore supplied")
+		}
+	}
+
+	// RequestParser header value "fileRequestId", required: false
+	exists, err = checkHeaderExists(r, "fileRequestId", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["fileRequestId"] = exists
+	if exists {
+		p.FileRequestId = r.Header.Get("fileRequestId")
+	}
+
+	// RequestParser header value "encrypted", required: false
+	exists, err = checkHeaderExists(r, "encrypted", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["encrypted"] = exists
+	if exists {
+		p.Encrypted, err = parseHeaderBool(r, "encrypted")
+		if err != nil {
+			return fmt.Errorf("invalid value in header encrypted supplied")
+		}
+	}
+
 	return p.ProcessParameter(r)
 }
 
@@ -358,6 +490,79 @@ func (p *paramFilesModify) ParseRequest(r *http.Request) error {
 		}
 	}
 
+	// RequestParser header value "publishAt", required: false
+	exists, err = checkHeaderExists(r, "publishAt", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["publishAt"] = exists
+	if exists {
+		p.PublishAt, err = parseHeaderInt64(r, "publishAt")
+		if err != nil {
+			return fmt.Errorf("invalid value in header publishAt supplied")
+		}
+	}
+
+	// RequestParser header value "expiryFromPublish", required: false
+	exists, err = checkHeaderExists(r, "expiryFromPublish", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["expiryFromPublish"] = exists
+	if exists {
+		p.ExpiryFromPublish, err = parseHeaderBool(r, "expiryFromPublish")
+		if err != nil {
+			return fmt.Errorf("invalid value in header expiryFromPublish supplied")
+		}
+	}
+
+	// RequestParser header value "requireLogin", required: false
+	exists, err = checkHeaderExists(r, "requireLogin", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["requireLogin"] = exists
+	if exists {
+		p.RequireLogin, err = parseHeaderBool(r, "requireLogin")
+		if err != nil {
+			return fmt.Errorf("invalid value in header requireLogin supplied")
+		}
+	}
+
+	// RequestParser header value "allowedUsers", required: false, has base64support
+	exists, err = checkHeaderExists(r, "allowedUsers", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedUsers"] = exists
+	if exists {
+		p.AllowedUsers = r.Header.Get("allowedUsers")
+		if strings.HasPrefix(p.AllowedUsers, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.AllowedUsers, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.AllowedUsers = string(decoded)
+		}
+	}
+
+	// RequestParser header value "allowedGroups", required: false, has base64support
+	exists, err = checkHeaderExists(r, "allowedGroups", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedGroups"] = exists
+	if exists {
+		p.AllowedGroups = r.Header.Get("allowedGroups")
+		if strings.HasPrefix(p.AllowedGroups, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.AllowedGroups, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.AllowedGroups = string(decoded)
+		}
+	}
+
 	return p.ProcessParameter(r)
 }
 
@@ -366,6 +571,65 @@ func (p *paramFilesModify) New() requestParser {
 	return &paramFilesModify{}
 }
 
+// ParseRequest reads r and saves the passed header values in the paramFilesSendLink struct
+// In the end, ProcessParameter() is called
+func (p *paramFilesSendLink) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	// RequestParser header value "recipients", required: true, has base64support
+	exists, err = checkHeaderExists(r, "recipients", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["recipients"] = exists
+	if exists {
+		p.RecipientList = r.Header.Get("recipients")
+		if strings.HasPrefix(p.RecipientList, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.RecipientList, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.RecipientList = string(decoded)
+		}
+	}
+
+	// RequestParser header value "message", required: false, has base64support
+	exists, err = checkHeaderExists(r, "message", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["message"] = exists
+	if exists {
+		p.Message = r.Header.Get("message")
+		if strings.HasPrefix(p.Message, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.Message, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.Message = string(decoded)
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramFilesSendLink struct
+func (p *paramFilesSendLink) New() requestParser {
+	return &paramFilesSendLink{}
+}
+
 // ParseRequest reads r and saves the passed header values in the paramFilesReplace struct
 // In the end, ProcessParameter() is called
 func (p *paramFilesReplace) ParseRequest(r *http.Request) error {
@@ -414,6 +678,101 @@ func (p *paramFilesReplace) New() requestParser {
 	return &paramFilesReplace{}
 }
 
+// ParseRequest reads r and saves the passed header values in the paramFilesVersionsList struct
+// In the end, ProcessParameter() is called
+func (p *paramFilesVersionsList) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramFilesVersionsList struct
+func (p *paramFilesVersionsList) New() requestParser {
+	return &paramFilesVersionsList{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramFilesVersionsDownload struct
+// In the end, ProcessParameter() is called
+func (p *paramFilesVersionsDownload) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	// RequestParser header value "versionId", required: true
+	exists, err = checkHeaderExists(r, "versionId", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["versionId"] = exists
+	if exists {
+		p.VersionId = r.Header.Get("versionId")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramFilesVersionsDownload struct
+func (p *paramFilesVersionsDownload) New() requestParser {
+	return &paramFilesVersionsDownload{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramFilesVersionsRollback struct
+// In the end, ProcessParameter() is called
+func (p *paramFilesVersionsRollback) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	// RequestParser header value "versionId", required: true
+	exists, err = checkHeaderExists(r, "versionId", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["versionId"] = exists
+	if exists {
+		p.VersionId = r.Header.Get("versionId")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramFilesVersionsRollback struct
+func (p *paramFilesVersionsRollback) New() requestParser {
+	return &paramFilesVersionsRollback{}
+}
+
 // ParseRequest reads r and saves the passed header values in the paramFilesDelete struct
 // In the end, ProcessParameter() is called
 func (p *paramFilesDelete) ParseRequest(r *http.Request) error {
@@ -744,8 +1103,8 @@ func (p *paramUserModify) ParseRequest(r *http.Request) error {
 		}
 	}
 
-	// RequestParser header value "userpermission", required: true
-	exists, err = checkHeaderExists(r, "userpermission", true, true)
+	// RequestParser header value "userpermission", required: false
+	exists, err = checkHeaderExists(r, "userpermission", false, true)
 	if err != nil {
 		return err
 	}
@@ -754,8 +1113,8 @@ func (p *paramUserModify) ParseRequest(r *http.Request) error {
 		p.permissionRaw = r.Header.Get("userpermission")
 	}
 
-	// RequestParser header value "permissionModifier", required: true
-	exists, err = checkHeaderExists(r, "permissionModifier", true, true)
+	// RequestParser header value "permissionModifier", required: false
+	exists, err = checkHeaderExists(r, "permissionModifier", false, true)
 	if err != nil {
 		return err
 	}
@@ -764,120 +1123,845 @@ func (p *paramUserModify) ParseRequest(r *http.Request) error {
 		p.permissionModifier = r.Header.Get("permissionModifier")
 	}
 
-	return p.ProcessParameter(r)
-}
+	// RequestParser header value "quotaStorageMb", required: false
+	exists, err = checkHeaderExists(r, "quotaStorageMb", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["quotaStorageMb"] = exists
+	if exists {
+		p.QuotaStorageMb, err = parseHeaderInt(r, "quotaStorageMb")
+		if err != nil {
+			return fmt.Errorf("invalid value in header quotaStorageMb supplied")
+		}
+	}
+
+	// RequestParser header value "quotaFiles", required: false
+	exists, err = checkHeaderExists(r, "quotaFiles", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["quotaFiles"] = exists
+	if exists {
+		p.QuotaFiles, err = parseHeaderInt(r, "quotaFiles")
+		if err != nil {
+			return fmt.Errorf("invalid value in header quotaFiles supplied")
+		}
+	}
+
+	// RequestParser header value "quotaExpiryDays", required: false
+	exists, err = checkHeaderExists(r, "quotaExpiryDays", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["quotaExpiryDays"] = exists
+	if exists {
+		p.QuotaExpiryDays, err = parseHeaderInt(r, "quotaExpiryDays")
+		if err != nil {
+			return fmt.Errorf("invalid value in header quotaExpiryDays supplied")
+		}
+	}
+
+	// RequestParser header value "quotaDownloads", required: false
+	exists, err = checkHeaderExists(r, "quotaDownloads", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["quotaDownloads"] = exists
+	if exists {
+		p.QuotaDownloads, err = parseHeaderInt(r, "quotaDownloads")
+		if err != nil {
+			return fmt.Errorf("invalid value in header quotaDownloads supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
 
 // New returns a new instance of paramUserModify struct
 func (p *paramUserModify) New() requestParser {
 	return &paramUserModify{}
 }
 
-// ParseRequest reads r and saves the passed header values in the paramUserResetPw struct
+// ParseRequest reads r and saves the passed header values in the paramUserResetPw struct
+// In the end, ProcessParameter() is called
+func (p *paramUserResetPw) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "userid", required: true
+	exists, err = checkHeaderExists(r, "userid", true, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["userid"] = exists
+	if exists {
+		p.Id, err = parseHeaderInt(r, "userid")
+		if err != nil {
+			return fmt.Errorf("invalid value in header userid supplied")
+		}
+	}
+
+	// RequestParser header value "generateNewPassword", required: false
+	exists, err = checkHeaderExists(r, "generateNewPassword", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["generateNewPassword"] = exists
+	if exists {
+		p.NewPassword, err = parseHeaderBool(r, "generateNewPassword")
+		if err != nil {
+			return fmt.Errorf("invalid value in header generateNewPassword supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramUserResetPw struct
+func (p *paramUserResetPw) New() requestParser {
+	return &paramUserResetPw{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramUserResetTwoFactor struct
+// In the end, ProcessParameter() is called
+func (p *paramUserResetTwoFactor) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "userid", required: true
+	exists, err = checkHeaderExists(r, "userid", true, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["userid"] = exists
+	if exists {
+		p.Id, err = parseHeaderInt(r, "userid")
+		if err != nil {
+			return fmt.Errorf("invalid value in header userid supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramUserResetTwoFactor struct
+func (p *paramUserResetTwoFactor) New() requestParser {
+	return &paramUserResetTwoFactor{}
+}
+
+// ParseRequest parses the header file. As paramE2eStore has no fields with the
+// tag header, this method does nothing, except calling ProcessParameter()
+func (p *paramE2eStore) ParseRequest(r *http.Request) error {
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramE2eStore struct
+func (p *paramE2eStore) New() requestParser {
+	return &paramE2eStore{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramLogsDelete struct
+// In the end, ProcessParameter() is called
+func (p *paramLogsDelete) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "timestamp", required: false
+	exists, err = checkHeaderExists(r, "timestamp", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["timestamp"] = exists
+	if exists {
+		p.Timestamp, err = parseHeaderInt64(r, "timestamp")
+		if err != nil {
+			return fmt.Errorf("invalid value in header timestamp supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramLogsDelete struct
+func (p *paramLogsDelete) New() requestParser {
+	return &paramLogsDelete{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramLogsGet struct
+// In the end, ProcessParameter() is called
+func (p *paramLogsGet) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "timestamp", required: false
+	exists, err = checkHeaderExists(r, "timestamp", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["timestamp"] = exists
+	if exists {
+		p.Timestamp, err = parseHeaderInt64(r, "timestamp")
+		if err != nil {
+			return fmt.Errorf("invalid value in header timestamp supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramLogsGet struct
+func (p *paramLogsGet) New() requestParser {
+	return &paramLogsGet{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramLogsQuery struct
+// In the end, ProcessParameter() is called
+func (p *paramLogsQuery) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "userId", required: false
+	exists, err = checkHeaderExists(r, "userId", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["userId"] = exists
+	if exists {
+		p.UserId, err = parseHeaderInt(r, "userId")
+		if err != nil {
+			return fmt.Errorf("invalid value in header userId supplied")
+		}
+	}
+
+	// RequestParser header value "apiKeyId", required: false
+	exists, err = checkHeaderExists(r, "apiKeyId", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["apiKeyId"] = exists
+	if exists {
+		p.ApiKeyId = r.Header.Get("apiKeyId")
+	}
+
+	// RequestParser header value "ip", required: false
+	exists, err = checkHeaderExists(r, "ip", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["ip"] = exists
+	if exists {
+		p.Ip = r.Header.Get("ip")
+	}
+
+	// RequestParser header value "fileId", required: false
+	exists, err = checkHeaderExists(r, "fileId", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["fileId"] = exists
+	if exists {
+		p.FileId = r.Header.Get("fileId")
+	}
+
+	// RequestParser header value "fileRequestId", required: false
+	exists, err = checkHeaderExists(r, "fileRequestId", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["fileRequestId"] = exists
+	if exists {
+		p.FileRequestId = r.Header.Get("fileRequestId")
+	}
+
+	// RequestParser header value "action", required: false
+	exists, err = checkHeaderExists(r, "action", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["action"] = exists
+	if exists {
+		p.Action = r.Header.Get("action")
+	}
+
+	// RequestParser header value "result", required: false
+	exists, err = checkHeaderExists(r, "result", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["result"] = exists
+	if exists {
+		p.Result = r.Header.Get("result")
+	}
+
+	// RequestParser header value "since", required: false
+	exists, err = checkHeaderExists(r, "since", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["since"] = exists
+	if exists {
+		p.Since, err = parseHeaderInt64(r, "since")
+		if err != nil {
+			return fmt.Errorf("invalid value in header since supplied")
+		}
+	}
+
+	// RequestParser header value "until", required: false
+	exists, err = checkHeaderExists(r, "until", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["until"] = exists
+	if exists {
+		p.Until, err = parseHeaderInt64(r, "until")
+		if err != nil {
+			return fmt.Errorf("invalid value in header until supplied")
+		}
+	}
+
+	// RequestParser header value "limit", required: false
+	exists, err = checkHeaderExists(r, "limit", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["limit"] = exists
+	if exists {
+		p.Limit, err = parseHeaderInt(r, "limit")
+		if err != nil {
+			return fmt.Errorf("invalid value in header limit supplied")
+		}
+	}
+
+	// RequestParser header value "cursor", required: false
+	exists, err = checkHeaderExists(r, "cursor", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["cursor"] = exists
+	if exists {
+		p.Cursor, err = parseHeaderInt64(r, "cursor")
+		if err != nil {
+			return fmt.Errorf("invalid value in header cursor supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramLogsQuery struct
+func (p *paramLogsQuery) New() requestParser {
+	return &paramLogsQuery{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramWebhookAdd struct
+// In the end, ProcessParameter() is called
+func (p *paramWebhookAdd) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "url", required: true, has base64support
+	exists, err = checkHeaderExists(r, "url", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["url"] = exists
+	if exists {
+		p.Url = r.Header.Get("url")
+		if strings.HasPrefix(p.Url, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.Url, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.Url = string(decoded)
+		}
+	}
+
+	// RequestParser header value "events", required: true
+	exists, err = checkHeaderExists(r, "events", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["events"] = exists
+	if exists {
+		p.Events = r.Header.Get("events")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramWebhookAdd struct
+func (p *paramWebhookAdd) New() requestParser {
+	return &paramWebhookAdd{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramWebhookModify struct
+// In the end, ProcessParameter() is called
+func (p *paramWebhookModify) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	// RequestParser header value "url", required: false, has base64support
+	exists, err = checkHeaderExists(r, "url", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["url"] = exists
+	if exists {
+		p.Url = r.Header.Get("url")
+		if strings.HasPrefix(p.Url, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.Url, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.Url = string(decoded)
+		}
+	}
+
+	// RequestParser header value "events", required: false
+	exists, err = checkHeaderExists(r, "events", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["events"] = exists
+	if exists {
+		p.Events = r.Header.Get("events")
+	}
+
+	// RequestParser header value "regenerateSecret", required: false
+	exists, err = checkHeaderExists(r, "regenerateSecret", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["regenerateSecret"] = exists
+	if exists {
+		p.RegenerateSecret, err = parseHeaderBool(r, "regenerateSecret")
+		if err != nil {
+			return fmt.Errorf("invalid value in header regenerateSecret supplied")
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramWebhookModify struct
+func (p *paramWebhookModify) New() requestParser {
+	return &paramWebhookModify{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramEncryptionRotateKey struct
+// In the end, ProcessParameter() is called
+func (p *paramEncryptionRotateKey) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "newPassword", required: false, has base64support
+	exists, err = checkHeaderExists(r, "newPassword", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["newPassword"] = exists
+	if exists {
+		p.NewPassword = r.Header.Get("newPassword")
+		if strings.HasPrefix(p.NewPassword, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.NewPassword, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.NewPassword = string(decoded)
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramEncryptionRotateKey struct
+func (p *paramEncryptionRotateKey) New() requestParser {
+	return &paramEncryptionRotateKey{}
+}
+
+// ParseRequest parses the header file. As paramEncryptionEncryptFiles has no fields with the
+// tag header, this method does nothing, except calling ProcessParameter()
+func (p *paramEncryptionEncryptFiles) ParseRequest(r *http.Request) error {
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramEncryptionEncryptFiles struct
+func (p *paramEncryptionEncryptFiles) New() requestParser {
+	return &paramEncryptionEncryptFiles{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramScrubStart struct
+// In the end, ProcessParameter() is called
+func (p *paramScrubStart) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "action", required: false
+	exists, err = checkHeaderExists(r, "action", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["action"] = exists
+	if exists {
+		p.Action = r.Header.Get("action")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramScrubStart struct
+func (p *paramScrubStart) New() requestParser {
+	return &paramScrubStart{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramScrubResolve struct
+// In the end, ProcessParameter() is called
+func (p *paramScrubResolve) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "action", required: true
+	exists, err = checkHeaderExists(r, "action", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["action"] = exists
+	if exists {
+		p.Action = r.Header.Get("action")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramScrubResolve struct
+func (p *paramScrubResolve) New() requestParser {
+	return &paramScrubResolve{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramWebhookDelete struct
+// In the end, ProcessParameter() is called
+func (p *paramWebhookDelete) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramWebhookDelete struct
+func (p *paramWebhookDelete) New() requestParser {
+	return &paramWebhookDelete{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramBundleCreate struct
+// In the end, ProcessParameter() is called
+func (p *paramBundleCreate) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "name", required: false, has base64support
+	exists, err = checkHeaderExists(r, "name", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["name"] = exists
+	if exists {
+		p.Name = r.Header.Get("name")
+		if strings.HasPrefix(p.Name, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.Name, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.Name = string(decoded)
+		}
+	}
+
+	// RequestParser header value "fileIds", required: true
+	exists, err = checkHeaderExists(r, "fileIds", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["fileIds"] = exists
+	if exists {
+		p.FileIds = r.Header.Get("fileIds")
+	}
+
+	// RequestParser header value "allowedDownloads", required: false
+	exists, err = checkHeaderExists(r, "allowedDownloads", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedDownloads"] = exists
+	if exists {
+		p.AllowedDownloads, err = parseHeaderInt(r, "allowedDownloads")
+		if err != nil {
+			return fmt.Errorf("invalid value in header allowedDownloads supplied")
+		}
+	}
+
+	// RequestParser header value "expiryDays", required: false
+	exists, err = checkHeaderExists(r, "expiryDays", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["expiryDays"] = exists
+	if exists {
+		p.ExpiryDays, err = parseHeaderInt(r, "expiryDays")
+		if err != nil {
+			return fmt.Errorf("invalid value in header expiryDays supplied")
+		}
+	}
+
+	// RequestParser header value "password", required: false, has base64support
+	exists, err = checkHeaderExists(r, "password", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["password"] = exists
+	if exists {
+		p.Password = r.Header.Get("password")
+		if strings.HasPrefix(p.Password, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.Password, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.Password = string(decoded)
+		}
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramBundleCreate struct
+func (p *paramBundleCreate) New() requestParser {
+	return &paramBundleCreate{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramBundleModifyFiles struct
 // In the end, ProcessParameter() is called
-func (p *paramUserResetPw) ParseRequest(r *http.Request) error {
+func (p *paramBundleModifyFiles) ParseRequest(r *http.Request) error {
 	var err error
 	var exists bool
 	p.foundHeaders = make(map[string]bool)
 
-	// RequestParser header value "userid", required: true
-	exists, err = checkHeaderExists(r, "userid", true, false)
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
 	if err != nil {
 		return err
 	}
-	p.foundHeaders["userid"] = exists
+	p.foundHeaders["id"] = exists
 	if exists {
-		p.Id, err = parseHeaderInt(r, "userid")
-		if err != nil {
-			return fmt.Errorf("invalid value in header userid supplied")
-		}
+		p.Id = r.Header.Get("id")
 	}
 
-	// RequestParser header value "generateNewPassword", required: false
-	exists, err = checkHeaderExists(r, "generateNewPassword", false, false)
+	// RequestParser header value "fileIds", required: true
+	exists, err = checkHeaderExists(r, "fileIds", true, true)
 	if err != nil {
 		return err
 	}
-	p.foundHeaders["generateNewPassword"] = exists
+	p.foundHeaders["fileIds"] = exists
 	if exists {
-		p.NewPassword, err = parseHeaderBool(r, "generateNewPassword")
-		if err != nil {
-			return fmt.Errorf("invalid value in header generateNewPassword supplied")
-		}
+		p.FileIds = r.Header.Get("fileIds")
 	}
 
 	return p.ProcessParameter(r)
 }
 
-// New returns a new instance of paramUserResetPw struct
-func (p *paramUserResetPw) New() requestParser {
-	return &paramUserResetPw{}
+// New returns a new instance of paramBundleModifyFiles struct
+func (p *paramBundleModifyFiles) New() requestParser {
+	return &paramBundleModifyFiles{}
 }
 
-// ParseRequest parses the header file. As paramE2eStore has no fields with the
-// tag header, this method does nothing, except calling ProcessParameter()
-func (p *paramE2eStore) ParseRequest(r *http.Request) error {
+// ParseRequest reads r and saves the passed header values in the paramBundleDelete struct
+// In the end, ProcessParameter() is called
+func (p *paramBundleDelete) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
 	return p.ProcessParameter(r)
 }
 
-// New returns a new instance of paramE2eStore struct
-func (p *paramE2eStore) New() requestParser {
-	return &paramE2eStore{}
+// New returns a new instance of paramBundleDelete struct
+func (p *paramBundleDelete) New() requestParser {
+	return &paramBundleDelete{}
 }
 
-// ParseRequest reads r and saves the passed header values in the paramLogsDelete struct
+// ParseRequest reads r and saves the passed header values in the paramShareLinkList struct
 // In the end, ProcessParameter() is called
-func (p *paramLogsDelete) ParseRequest(r *http.Request) error {
+func (p *paramShareLinkList) ParseRequest(r *http.Request) error {
 	var err error
 	var exists bool
 	p.foundHeaders = make(map[string]bool)
 
-	// RequestParser header value "timestamp", required: false
-	exists, err = checkHeaderExists(r, "timestamp", false, false)
+	// RequestParser header value "fileId", required: true
+	exists, err = checkHeaderExists(r, "fileId", true, true)
 	if err != nil {
 		return err
 	}
-	p.foundHeaders["timestamp"] = exists
+	p.foundHeaders["fileId"] = exists
 	if exists {
-		p.Timestamp, err = parseHeaderInt64(r, "timestamp")
-		if err != nil {
-			return fmt.Errorf("invalid value in header timestamp supplied")
-		}
+		p.FileId = r.Header.Get("fileId")
 	}
 
 	return p.ProcessParameter(r)
 }
 
-// New returns a new instance of paramLogsDelete struct
-func (p *paramLogsDelete) New() requestParser {
-	return &paramLogsDelete{}
+// New returns a new instance of paramShareLinkList struct
+func (p *paramShareLinkList) New() requestParser {
+	return &paramShareLinkList{}
 }
 
-// ParseRequest reads r and saves the passed header values in the paramLogsGet struct
+// ParseRequest reads r and saves the passed header values in the paramShareLinkCreate struct
 // In the end, ProcessParameter() is called
-func (p *paramLogsGet) ParseRequest(r *http.Request) error {
+func (p *paramShareLinkCreate) ParseRequest(r *http.Request) error {
 	var err error
 	var exists bool
 	p.foundHeaders = make(map[string]bool)
 
-	// RequestParser header value "timestamp", required: false
-	exists, err = checkHeaderExists(r, "timestamp", false, false)
+	// RequestParser header value "fileId", required: true
+	exists, err = checkHeaderExists(r, "fileId", true, true)
 	if err != nil {
 		return err
 	}
-	p.foundHeaders["timestamp"] = exists
+	p.foundHeaders["fileId"] = exists
 	if exists {
-		p.Timestamp, err = parseHeaderInt64(r, "timestamp")
+		p.FileId = r.Header.Get("fileId")
+	}
+
+	// RequestParser header value "name", required: false, has base64support
+	exists, err = checkHeaderExists(r, "name", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["name"] = exists
+	if exists {
+		p.Name = r.Header.Get("name")
+		if strings.HasPrefix(p.Name, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.Name, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.Name = string(decoded)
+		}
+	}
+
+	// RequestParser header value "allowedDownloads", required: false
+	exists, err = checkHeaderExists(r, "allowedDownloads", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedDownloads"] = exists
+	if exists {
+		p.AllowedDownloads, err = parseHeaderInt(r, "allowedDownloads")
 		if err != nil {
-			return fmt.Errorf("invalid value in header timestamp supplied")
+			return fmt.Errorf("invalid value in header allowedDownloads supplied")
+		}
+	}
+
+	// RequestParser header value "expiryDays", required: false
+	exists, err = checkHeaderExists(r, "expiryDays", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["expiryDays"] = exists
+	if exists {
+		p.ExpiryDays, err = parseHeaderInt(r, "expiryDays")
+		if err != nil {
+			return fmt.Errorf("invalid value in header expiryDays supplied")
+		}
+	}
+
+	// RequestParser header value "password", required: false, has base64support
+	exists, err = checkHeaderExists(r, "password", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["password"] = exists
+	if exists {
+		p.Password = r.Header.Get("password")
+		if strings.HasPrefix(p.Password, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.Password, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.Password = string(decoded)
 		}
 	}
 
 	return p.ProcessParameter(r)
 }
 
-// New returns a new instance of paramLogsGet struct
-func (p *paramLogsGet) New() requestParser {
-	return &paramLogsGet{}
+// New returns a new instance of paramShareLinkCreate struct
+func (p *paramShareLinkCreate) New() requestParser {
+	return &paramShareLinkCreate{}
+}
+
+// ParseRequest reads r and saves the passed header values in the paramShareLinkDelete struct
+// In the end, ProcessParameter() is called
+func (p *paramShareLinkDelete) ParseRequest(r *http.Request) error {
+	var err error
+	var exists bool
+	p.foundHeaders = make(map[string]bool)
+
+	// RequestParser header value "id", required: true
+	exists, err = checkHeaderExists(r, "id", true, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["id"] = exists
+	if exists {
+		p.Id = r.Header.Get("id")
+	}
+
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramShareLinkDelete struct
+func (p *paramShareLinkDelete) New() requestParser {
+	return &paramShareLinkDelete{}
 }
 
 // ParseRequest parses the header file. As paramChunkAdd has no fields with the
@@ -891,6 +1975,17 @@ func (p *paramChunkAdd) New() requestParser {
 	return &paramChunkAdd{}
 }
 
+// ParseRequest parses the header file. As paramTus has no fields with the
+// tag header, this method does nothing, except calling ProcessParameter()
+func (p *paramTus) ParseRequest(r *http.Request) error {
+	return p.ProcessParameter(r)
+}
+
+// New returns a new instance of paramTus struct
+func (p *paramTus) New() requestParser {
+	return &paramTus{}
+}
+
 // ParseRequest reads r and saves the passed header values in the paramChunkUploadRequestAdd struct
 // In the end, ProcessParameter() is called
 func (p *paramChunkUploadRequestAdd) ParseRequest(r *http.Request) error {
@@ -1058,6 +2153,113 @@ func (p *paramChunkComplete) ParseRequest(r *http.Request) error {
 		}
 	}
 
+	// RequestParser header value "recipients", required: false, has base64support
+	exists, err = checkHeaderExists(r, "recipients", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["recipients"] = exists
+	if exists {
+		p.RecipientList = r.Header.Get("recipients")
+		if strings.HasPrefix(p.RecipientList, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.RecipientList, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.RecipientList = string(decoded)
+		}
+	}
+
+	// RequestParser header value "message", required: false, has base64support
+	exists, err = checkHeaderExists(r, "message", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["message"] = exists
+	if exists {
+		p.Message = r.Header.Get("message")
+		if strings.HasPrefix(p.Message, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.Message, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.Message = string(decoded)
+		}
+	}
+
+	// RequestParser header value "publishAt", required: false
+	exists, err = checkHeaderExists(r, "publishAt", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["publishAt"] = exists
+	if exists {
+		p.PublishAt, err = parseHeaderInt64(r, "publishAt")
+		if err != nil {
+			return fmt.Errorf("invalid value in header publishAt supplied")
+		}
+	}
+
+	// RequestParser header value "expiryFromPublish", required: false
+	exists, err = checkHeaderExists(r, "expiryFromPublish", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["expiryFromPublish"] = exists
+	if exists {
+		p.ExpiryFromPublish, err = parseHeaderBool(r, "expiryFromPublish")
+		if err != nil {
+			return fmt.Errorf("invalid value in header expiryFromPublish supplied")
+		}
+	}
+
+	// RequestParser header value "requireLogin", required: false
+	exists, err = checkHeaderExists(r, "requireLogin", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["requireLogin"] = exists
+	if exists {
+		p.RequireLogin, err = parseHeaderBool(r, "requireLogin")
+		if err != nil {
+			return fmt.Errorf("invalid value in header requireLogin supplied")
+		}
+	}
+
+	// RequestParser header value "allowedUsers", required: false, has base64support
+	exists, err = checkHeaderExists(r, "allowedUsers", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedUsers"] = exists
+	if exists {
+		p.AllowedUsers = r.Header.Get("allowedUsers")
+		if strings.HasPrefix(p.AllowedUsers, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.AllowedUsers, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.AllowedUsers = string(decoded)
+		}
+	}
+
+	// RequestParser header value "allowedGroups", required: false, has base64support
+	exists, err = checkHeaderExists(r, "allowedGroups", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedGroups"] = exists
+	if exists {
+		p.AllowedGroups = r.Header.Get("allowedGroups")
+		if strings.HasPrefix(p.AllowedGroups, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.AllowedGroups, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.AllowedGroups = string(decoded)
+		}
+	}
+
 	return p.ProcessParameter(r)
 }
 
@@ -1359,6 +2561,53 @@ func (p *paramURequestSave) ParseRequest(r *http.Request) error {
 		}
 	}
 
+	// RequestParser header value "requireLogin", required: false
+	exists, err = checkHeaderExists(r, "requireLogin", false, false)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["requireLogin"] = exists
+	if exists {
+		p.RequireLogin, err = parseHeaderBool(r, "requireLogin")
+		if err != nil {
+			return fmt.Errorf("invalid value in header requireLogin supplied")
+		}
+	}
+
+	// RequestParser header value "allowedUsers", required: false, has base64support
+	exists, err = checkHeaderExists(r, "allowedUsers", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedUsers"] = exists
+	if exists {
+		p.AllowedUsers = r.Header.Get("allowedUsers")
+		if strings.HasPrefix(p.AllowedUsers, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.AllowedUsers, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.AllowedUsers = string(decoded)
+		}
+	}
+
+	// RequestParser header value "allowedGroups", required: false, has base64support
+	exists, err = checkHeaderExists(r, "allowedGroups", false, true)
+	if err != nil {
+		return err
+	}
+	p.foundHeaders["allowedGroups"] = exists
+	if exists {
+		p.AllowedGroups = r.Header.Get("allowedGroups")
+		if strings.HasPrefix(p.AllowedGroups, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p.AllowedGroups, "base64:"))
+			if err != nil {
+				return err
+			}
+			p.AllowedGroups = string(decoded)
+		}
+	}
+
 	return p.ProcessParameter(r)
 }
 
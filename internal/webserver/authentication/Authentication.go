@@ -10,12 +10,14 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/webserver/authentication/csrftoken"
+	"github.com/forceu/gokapi/internal/webserver/authentication/ldap"
 	"github.com/forceu/gokapi/internal/webserver/authentication/sessionmanager"
 	"github.com/forceu/gokapi/internal/webserver/authentication/users"
 	"github.com/forceu/gokapi/internal/webserver/errorHandling"
@@ -26,6 +28,9 @@ type userNameContext string
 // CookieOauth is the cookie name used for login
 const CookieOauth = "state"
 
+// CookieLoginRedirect is the cookie name used to store the page that is shown after logging in
+const CookieLoginRedirect = "login_redirect"
+
 const userNameContextKey userNameContext = "userName"
 
 var authSettings models.AuthenticationConfig
@@ -73,6 +78,11 @@ func checkAuthConfig(config models.AuthenticationConfig) error {
 		return nil
 	case models.AuthenticationDisabled:
 		return nil
+	case models.AuthenticationLdap:
+		if len(config.Username) < 3 {
+			return errors.New("username too short")
+		}
+		return ldap.CheckConfig(config)
 	default:
 		return errors.New("unknown authentication selected")
 	}
@@ -107,6 +117,11 @@ func IsAuthenticated(w http.ResponseWriter, r *http.Request) (models.User, bool,
 		if ok {
 			return user, true, nil
 		}
+	case models.AuthenticationLdap:
+		user, ok := isGrantedSession(w, r)
+		if ok {
+			return user, true, nil
+		}
 	case models.AuthenticationHeader:
 		user, ok, err := isGrantedHeader(r)
 		if err != nil {
@@ -230,21 +245,98 @@ func CheckOauthUserAndRedirect(w http.ResponseWriter, r *http.Request, userInfo
 			return err
 		}
 	}
+	target, isRecipientLogin := GetLoginRedirect(w, r)
 	if isValidOauthUser(userInfo, groups) {
 		user, ok, errCreate := getOrCreateUser(userInfo.Email)
 		if errCreate != nil {
 			return errCreate
 		}
 		if ok {
-			sessionmanager.CreateSession(w, true, authSettings.OAuthRecheckInterval, user.Id)
-			http.Redirect(w, r, "admin", http.StatusTemporaryRedirect)
+			sessionmanager.CreateOauthSession(w, authSettings.OAuthRecheckInterval, user.Id, groups)
+			http.Redirect(w, r, target, http.StatusTemporaryRedirect)
 			return nil
 		}
 	}
+	// Users that are not allowed to use the admin menu can still log in to access files that require a login
+	if isRecipientLogin && userInfo.Subject != "" && userInfo.Email != "" {
+		sessionmanager.CreateRecipientSession(w, authSettings.OAuthRecheckInterval, userInfo.Email, groups)
+		http.Redirect(w, r, target, http.StatusTemporaryRedirect)
+		return nil
+	}
 	errorHandling.RedirectGenericErrorPage(w, r, errorHandling.TypeOAuthNotAuthorised)
 	return nil
 }
 
+// GetRecipient returns the identity of the user, to check if access to a file or file request that requires
+// a login is granted. In contrast to IsAuthenticated, users that are not registered are accepted as well, if
+// they were authenticated by the OAuth provider or the authentication proxy
+func GetRecipient(w http.ResponseWriter, r *http.Request) (models.Recipient, bool) {
+	switch authSettings.Method {
+	case models.AuthenticationInternal, models.AuthenticationOAuth2, models.AuthenticationLdap:
+		return sessionmanager.GetRecipient(w, r, authSettings.Method == models.AuthenticationOAuth2, authSettings.OAuthRecheckInterval)
+	case models.AuthenticationHeader:
+		userName := r.Header.Get(authSettings.HeaderKey)
+		if authSettings.HeaderKey == "" || userName == "" {
+			return models.Recipient{}, false
+		}
+		result := models.Recipient{Name: userName, Groups: []string{}}
+		if authSettings.HeaderGroupKey != "" {
+			result.Groups = models.NewAccessRestriction(true, "", r.Header.Get(authSettings.HeaderGroupKey)).AllowedGroups
+		}
+		user, ok := database.GetUserByName(userName)
+		if ok {
+			result.Name = user.Name
+			result.UserId = user.Id
+		}
+		return result, true
+	case models.AuthenticationDisabled:
+		// Visitors cannot be identified, therefore files that require a login are not accessible
+		return models.Recipient{}, false
+	}
+	return models.Recipient{}, false
+}
+
+// IsAccessGranted returns true if the recipient is allowed to access a file or file request with the given
+// restriction. If no users or groups are set, all authenticated users are granted access
+func IsAccessGranted(restriction models.AccessRestriction, recipient models.Recipient) bool {
+	if !restriction.HasAllowList() {
+		return true
+	}
+	if restriction.IsAllowedUser(recipient.Name) {
+		return true
+	}
+	return isGroupInArray(recipient.Groups, restriction.AllowedGroups)
+}
+
+// Only pages of files and file requests are allowed as a target, so that the cookie cannot be used for an open redirect
+var regexLoginRedirect = regexp.MustCompile(`^(d|s|publicUpload)\?[A-Za-z0-9_\-=&]+$`)
+
+// SetLoginRedirect stores the page that is shown after the next successful login
+func SetLoginRedirect(w http.ResponseWriter, target string) {
+	c := &http.Cookie{
+		Name:     CookieLoginRedirect,
+		Value:    target,
+		MaxAge:   int(time.Hour.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, c)
+}
+
+// GetLoginRedirect returns the page that is shown after a successful login and removes the stored page.
+// Returns "admin" and false, if no page has been stored
+func GetLoginRedirect(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(CookieLoginRedirect)
+	if err != nil {
+		return "admin", false
+	}
+	http.SetCookie(w, &http.Cookie{Name: CookieLoginRedirect, Value: "", MaxAge: -1})
+	if !regexLoginRedirect.MatchString(cookie.Value) {
+		return "admin", false
+	}
+	return cookie.Value, true
+}
+
 func getOrCreateUser(username string) (models.User, bool, error) {
 	user, ok := database.GetUserByName(username)
 	if ok {
@@ -281,6 +373,7 @@ func isGrantedSession(w http.ResponseWriter, r *http.Request) (models.User, bool
 }
 
 // IsCorrectUsernameAndPassword checks if a provided username and password is correct
+// If LDAP authentication is used, the credentials are verified by the LDAP server
 // Returns true if the user is authenticated, false otherwise
 // Second return value is false if CSRF token is invalid, true otherwise
 // Migrates legacy passwords to the new format
@@ -288,6 +381,10 @@ func IsCorrectUsernameAndPassword(username, password, userCsrfToken string) (mod
 	if !csrftoken.IsValid(csrftoken.TypeLogin, userCsrfToken) {
 		return models.User{}, false, false
 	}
+	if authSettings.Method == models.AuthenticationLdap {
+		user, ok := isCorrectLdapUser(username, password)
+		return user, ok, true
+	}
 	user, ok := database.GetUserByName(username)
 	if !ok {
 		return models.User{}, false, true
@@ -303,9 +400,31 @@ func IsCorrectUsernameAndPassword(username, password, userCsrfToken string) (mod
 	return user, true, true
 }
 
+// isCorrectLdapUser binds to the LDAP server with the provided credentials and returns the user, if the
+// credentials are valid and the user is member of an authorised group. A new user is created on the first login
+func isCorrectLdapUser(username, password string) (models.User, bool) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	groups, err := ldap.Authenticate(authSettings, username, password)
+	if err != nil {
+		if !errors.Is(err, ldap.ErrorInvalidCredentials) {
+			fmt.Println("LDAP authentication failed: " + err.Error())
+		}
+		return models.User{}, false
+	}
+	if len(authSettings.LdapGroups) > 0 && !isGroupInArray(groups, authSettings.LdapGroups) {
+		return models.User{}, false
+	}
+	user, ok, err := getOrCreateUser(username)
+	if err != nil {
+		fmt.Println("Could not create user: " + err.Error())
+		return models.User{}, false
+	}
+	return user, ok
+}
+
 // Logout logs the user out and removes the session
 func Logout(w http.ResponseWriter, r *http.Request) {
-	if authSettings.Method == models.AuthenticationInternal || authSettings.Method == models.AuthenticationOAuth2 {
+	if usesSessions() {
 		sessionmanager.LogoutSession(w, r)
 	}
 	if authSettings.Method == models.AuthenticationOAuth2 {
@@ -317,5 +436,12 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 
 // IsLogoutAvailable returns true if a logout button should be shown with the current form of authentication
 func IsLogoutAvailable() bool {
-	return authSettings.Method == models.AuthenticationInternal || authSettings.Method == models.AuthenticationOAuth2
+	return usesSessions()
+}
+
+// usesSessions returns true if the authentication method creates a session cookie after logging in
+func usesSessions() bool {
+	return authSettings.Method == models.AuthenticationInternal ||
+		authSettings.Method == models.AuthenticationOAuth2 ||
+		authSettings.Method == models.AuthenticationLdap
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/ldapstub"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
 	"github.com/forceu/gokapi/internal/webserver/authentication/csrftoken"
 	"github.com/forceu/gokapi/internal/webserver/authentication/sessionmanager"
@@ -72,6 +73,19 @@ func TestIsValid(t *testing.T) {
 	config.OAuthRecheckInterval = 1
 	err = checkAuthConfig(config)
 	test.IsNil(t, err)
+
+	config.Method = models.AuthenticationLdap
+	err = checkAuthConfig(config)
+	test.IsNotNil(t, err)
+	config.LdapUrl = "ldap://localhost"
+	err = checkAuthConfig(config)
+	test.IsNotNil(t, err)
+	config.LdapUserDnTemplate = "uid={username},dc=example,dc=com"
+	err = checkAuthConfig(config)
+	test.IsNil(t, err)
+	config.Username = "2s"
+	err = checkAuthConfig(config)
+	test.IsNotNil(t, err)
 }
 
 func TestIsCorrectUsernameAndPassword(t *testing.T) {
@@ -98,6 +112,65 @@ func TestIsCorrectUsernameAndPassword(t *testing.T) {
 	test.IsEqualBool(t, csfrOk, false)
 }
 
+func TestIsCorrectUsernameAndPasswordLdap(t *testing.T) {
+	server, err := ldapstub.Start([]ldapstub.Entry{
+		{
+			Dn:         "uid=test,ou=people,dc=example,dc=com",
+			Password:   "ldappw",
+			Attributes: map[string][]string{"memberOf": {"cn=gokapi,ou=groups,dc=example,dc=com"}},
+		},
+		{
+			Dn:       "uid=ldapuser,ou=people,dc=example,dc=com",
+			Password: "ldappw",
+		},
+	})
+	test.IsNil(t, err)
+	defer server.Close()
+	Init(models.AuthenticationConfig{
+		Method:             models.AuthenticationLdap,
+		Username:           "test",
+		LdapUrl:            server.Url(),
+		LdapUserDnTemplate: "uid={username},ou=people,dc=example,dc=com",
+		LdapGroupAttribute: "memberOf",
+	})
+	defer Init(modelUserPW)
+
+	// The password of the directory is used, not the internal one
+	_, ok, csfrOk := IsCorrectUsernameAndPassword("test", "adminadmin", csrftoken.Generate(csrftoken.TypeLogin))
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualBool(t, csfrOk, true)
+	user, ok, _ := IsCorrectUsernameAndPassword("Test", "ldappw", csrftoken.Generate(csrftoken.TypeLogin))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, user.Id, 5)
+	_, ok, csfrOk = IsCorrectUsernameAndPassword("test", "ldappw", "invalidToken")
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualBool(t, csfrOk, false)
+
+	// Unknown users are created on the first login
+	_, ok = database.GetUserByName("ldapuser")
+	test.IsEqualBool(t, ok, false)
+	user, ok, _ = IsCorrectUsernameAndPassword("ldapuser", "ldappw", csrftoken.Generate(csrftoken.TypeLogin))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, user.Name, "ldapuser")
+	test.IsEqualBool(t, user.IsAdmin(), false)
+	defer database.DeleteUser(user.Id)
+
+	// Only members of the allowed groups can log in
+	authSettings.LdapGroups = []string{"gok*"}
+	_, ok, _ = IsCorrectUsernameAndPassword("ldapuser", "ldappw", csrftoken.Generate(csrftoken.TypeLogin))
+	test.IsEqualBool(t, ok, false)
+	_, ok, _ = IsCorrectUsernameAndPassword("test", "ldappw", csrftoken.Generate(csrftoken.TypeLogin))
+	test.IsEqualBool(t, ok, true)
+	authSettings.LdapGroups = nil
+
+	authSettings.OnlyRegisteredUsers = true
+	_, ok, _ = IsCorrectUsernameAndPassword("ldapuser", "ldappw", csrftoken.Generate(csrftoken.TypeLogin))
+	test.IsEqualBool(t, ok, true)
+	database.DeleteUser(user.Id)
+	_, ok, _ = IsCorrectUsernameAndPassword("ldapuser", "ldappw", csrftoken.Generate(csrftoken.TypeLogin))
+	test.IsEqualBool(t, ok, false)
+}
+
 func TestIsAuthenticated(t *testing.T) {
 	testAuthSession(t)
 	testAuthHeader(t)
@@ -184,6 +257,8 @@ func TestIsLogoutAvailable(t *testing.T) {
 	test.IsEqualBool(t, IsLogoutAvailable(), true)
 	authSettings.Method = models.AuthenticationOAuth2
 	test.IsEqualBool(t, IsLogoutAvailable(), true)
+	authSettings.Method = models.AuthenticationLdap
+	test.IsEqualBool(t, IsLogoutAvailable(), true)
 	authSettings.Method = models.AuthenticationHeader
 	test.IsEqualBool(t, IsLogoutAvailable(), false)
 	authSettings.Method = models.AuthenticationDisabled
@@ -400,6 +475,139 @@ func TestCheckOauthUser(t *testing.T) {
 	test.IsNotNil(t, err)
 }
 
+func TestCheckOauthRecipient(t *testing.T) {
+	Init(modelOauth)
+	authSettings.OAuthGroups = []string{"admins"}
+	authSettings.OAuthGroupScope = "groups"
+	info := OAuthUserInfo{
+		Subject:    "recipientsubject",
+		Email:      "recipient@test.com",
+		ClaimsSent: testInfo{Output: []byte(`{"email":"recipient@test.com","groups":["staff","sales"],"sub":"recipientsubject"}`)},
+	}
+	// Without a pending download, users that are not allowed to use the admin menu cannot log in
+	w, err := getOauthUserOutput(t, info)
+	test.IsNil(t, err)
+	test.ResponseIsRedirect(t, w, "error", true)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: CookieLoginRedirect, Value: "d?id=restrictedfile"})
+	err = CheckOauthUserAndRedirect(w, r, info)
+	test.IsNil(t, err)
+	test.ResponseIsRedirect(t, w, "d?id=restrictedfile", false)
+	var sessionCookie string
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session_token" {
+			sessionCookie = cookie.Value
+		}
+	}
+	test.IsNotEqualString(t, sessionCookie, "")
+	w, r = test.GetRecorder("GET", "/", []test.Cookie{{Name: "session_token", Value: sessionCookie}}, nil, nil)
+	_, ok, _ := IsAuthenticated(w, r)
+	test.IsEqualBool(t, ok, false)
+	recipient, ok := GetRecipient(w, r)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, recipient.Name, "recipient@test.com")
+	test.IsEqualInt(t, recipient.UserId, 0)
+	test.IsEqualInt(t, len(recipient.Groups), 2)
+	_, ok = database.GetUserByName("recipient@test.com")
+	test.IsEqualBool(t, ok, false)
+
+	// Users that are allowed to use the admin menu are returned to the download as well
+	info.Email = "admin@test.com"
+	info.ClaimsSent = testInfo{Output: []byte(`{"email":"admin@test.com","groups":["admins"],"sub":"recipientsubject"}`)}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: CookieLoginRedirect, Value: "publicUpload?id=request&key=apikey"})
+	err = CheckOauthUserAndRedirect(w, r, info)
+	test.IsNil(t, err)
+	test.ResponseIsRedirect(t, w, "publicUpload?id=request&key=apikey", false)
+	authSettings.OAuthGroups = []string{}
+	authSettings.OAuthGroupScope = ""
+}
+
+func TestGetRecipient(t *testing.T) {
+	w, r := test.GetRecorder("GET", "/", nil, nil, nil)
+	Init(modelUserPW)
+	_, ok := GetRecipient(w, r)
+	test.IsEqualBool(t, ok, false)
+	w, r = test.GetRecorder("GET", "/", []test.Cookie{{Name: "session_token", Value: "validsession"}}, nil, nil)
+	recipient, ok := GetRecipient(w, r)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, recipient.UserId, 7)
+
+	Init(modelHeader)
+	w, r = test.GetRecorder("GET", "/", nil, nil, nil)
+	_, ok = GetRecipient(w, r)
+	test.IsEqualBool(t, ok, false)
+	authSettings.OnlyRegisteredUsers = true
+	authSettings.HeaderGroupKey = "testGroups"
+	w, r = test.GetRecorder("GET", "/", nil, []test.Header{{Name: "testHeader", Value: "unregisteredRecipient"},
+		{Name: "testGroups", Value: "staff, sales"}}, nil)
+	recipient, ok = GetRecipient(w, r)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, recipient.Name, "unregisteredRecipient")
+	test.IsEqualInt(t, recipient.UserId, 0)
+	test.IsEqualInt(t, len(recipient.Groups), 2)
+	test.IsEqualString(t, recipient.Groups[1], "sales")
+	_, ok = database.GetUserByName("unregisteredRecipient")
+	test.IsEqualBool(t, ok, false)
+	authSettings.OnlyRegisteredUsers = false
+	authSettings.HeaderGroupKey = ""
+
+	// Without authentication, every visitor would otherwise be granted access to restricted files
+	Init(modelDisabled)
+	_, ok = GetRecipient(w, r)
+	test.IsEqualBool(t, ok, false)
+	w, r = test.GetRecorder("GET", "/", []test.Cookie{{Name: "session_token", Value: "validsession"}}, nil, nil)
+	_, ok = GetRecipient(w, r)
+	test.IsEqualBool(t, ok, false)
+
+	authSettings.Method = -1
+	_, ok = GetRecipient(w, r)
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestIsAccessGranted(t *testing.T) {
+	recipient := models.Recipient{Name: "Alice@example.com", Groups: []string{"staff", "sales-europe"}}
+	test.IsEqualBool(t, IsAccessGranted(models.NewAccessRestriction(true, "", ""), recipient), true)
+	test.IsEqualBool(t, IsAccessGranted(models.NewAccessRestriction(true, "alice@example.com", ""), recipient), true)
+	test.IsEqualBool(t, IsAccessGranted(models.NewAccessRestriction(true, "bob@example.com", ""), recipient), false)
+	test.IsEqualBool(t, IsAccessGranted(models.NewAccessRestriction(true, "bob@example.com", "Staff"), recipient), true)
+	test.IsEqualBool(t, IsAccessGranted(models.NewAccessRestriction(true, "", "sales-*"), recipient), true)
+	test.IsEqualBool(t, IsAccessGranted(models.NewAccessRestriction(true, "", "admins,sales-us"), recipient), false)
+	test.IsEqualBool(t, IsAccessGranted(models.NewAccessRestriction(true, "", "admins"), models.Recipient{Name: "admins"}), false)
+}
+
+func TestLoginRedirect(t *testing.T) {
+	w, r := test.GetRecorder("GET", "/", nil, nil, nil)
+	target, ok := GetLoginRedirect(w, r)
+	test.IsEqualBool(t, ok, false)
+	test.IsEqualString(t, target, "admin")
+
+	w = httptest.NewRecorder()
+	SetLoginRedirect(w, "s?id=sharelink")
+	cookies := w.Result().Cookies()
+	test.IsEqualInt(t, len(cookies), 1)
+	test.IsEqualString(t, cookies[0].Name, CookieLoginRedirect)
+	test.IsEqualString(t, cookies[0].Value, "s?id=sharelink")
+
+	for _, invalidTarget := range []string{"https://example.com", "//example.com", "/d?id=test", "admin?id=test", "d?id=<script>"} {
+		w, r = test.GetRecorder("GET", "/", []test.Cookie{{Name: CookieLoginRedirect, Value: invalidTarget}}, nil, nil)
+		target, ok = GetLoginRedirect(w, r)
+		test.IsEqualBool(t, ok, false)
+		test.IsEqualString(t, target, "admin")
+	}
+	w, r = test.GetRecorder("GET", "/", []test.Cookie{{Name: CookieLoginRedirect, Value: "d?id=testfile"}}, nil, nil)
+	target, ok = GetLoginRedirect(w, r)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, target, "d?id=testfile")
+	// The stored page is removed after it has been used
+	cookies = w.Result().Cookies()
+	test.IsEqualInt(t, len(cookies), 1)
+	test.IsEqualInt(t, cookies[0].MaxAge, -1)
+}
+
 var modelUserPW = models.AuthenticationConfig{
 	Method:    models.AuthenticationInternal,
 	SaltAdmin: testconfiguration.SaltAdmin,
@@ -0,0 +1,171 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/forceu/gokapi/internal/models"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+const dialTimeout = 10 * time.Second
+
+// ErrorInvalidCredentials is returned, if the user does not exist or the password is incorrect
+var ErrorInvalidCredentials = errors.New("invalid username or password")
+
+// CheckConfig returns an error, if the LDAP configuration is incomplete
+func CheckConfig(config models.AuthenticationConfig) error {
+	if config.LdapUrl == "" {
+		return errors.New("ldap server url was not set")
+	}
+	if config.LdapUserDnTemplate != "" {
+		if !strings.Contains(config.LdapUserDnTemplate, models.LdapUsernamePlaceholder) {
+			return errors.New("ldap user dn template does not contain " + models.LdapUsernamePlaceholder)
+		}
+	} else {
+		if config.LdapSearchBase == "" {
+			return errors.New("ldap search base was not set")
+		}
+		if !strings.Contains(config.LdapSearchFilter, models.LdapUsernamePlaceholder) {
+			return errors.New("ldap search filter does not contain " + models.LdapUsernamePlaceholder)
+		}
+	}
+	if len(config.LdapGroups) > 0 && config.LdapGroupAttribute == "" {
+		return errors.New("ldap group attribute was not set")
+	}
+	return nil
+}
+
+// Authenticate verifies the username and password by binding to the LDAP server.
+// Returns the groups of the user, if the credentials are valid
+func Authenticate(config models.AuthenticationConfig, username, password string) ([]string, error) {
+	// An empty password would result in an unauthenticated bind, which succeeds on most servers
+	if username == "" || password == "" {
+		return nil, ErrorInvalidCredentials
+	}
+	conn, err := connect(config)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var groupValues []string
+	var userDn string
+	if config.LdapUserDnTemplate != "" {
+		userDn = strings.ReplaceAll(config.LdapUserDnTemplate, models.LdapUsernamePlaceholder, goldap.EscapeDN(username))
+	} else {
+		userDn, groupValues, err = searchUser(conn, config, username)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = conn.Bind(userDn, password)
+	if err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrorInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if config.LdapUserDnTemplate != "" && config.LdapGroupAttribute != "" {
+		groupValues, err = readGroupAttribute(conn, config, userDn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parseGroups(groupValues), nil
+}
+
+func connect(config models.AuthenticationConfig) (*goldap.Conn, error) {
+	conn, err := goldap.DialURL(config.LdapUrl, goldap.DialWithDialer(&net.Dialer{Timeout: dialTimeout}))
+	if err != nil {
+		return nil, err
+	}
+	if config.LdapStartTls {
+		serverUrl, err := url.Parse(config.LdapUrl)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		err = conn.StartTLS(&tls.Config{ServerName: serverUrl.Hostname()})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// searchUser returns the DN and the group attribute of the user, binding with the service account first if set
+func searchUser(conn *goldap.Conn, config models.AuthenticationConfig, username string) (string, []string, error) {
+	if config.LdapBindDn != "" {
+		err := conn.Bind(config.LdapBindDn, config.LdapBindPassword)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not bind with service account: %w", err)
+		}
+	}
+	var attributes []string
+	if config.LdapGroupAttribute != "" {
+		attributes = []string{config.LdapGroupAttribute}
+	} else {
+		attributes = []string{"dn"}
+	}
+	filter := strings.ReplaceAll(config.LdapSearchFilter, models.LdapUsernamePlaceholder, goldap.EscapeFilter(username))
+	request := goldap.NewSearchRequest(config.LdapSearchBase, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		2, int(dialTimeout.Seconds()), false, filter, attributes, nil)
+	result, err := conn.Search(request)
+	if err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+			return "", nil, ErrorInvalidCredentials
+		}
+		return "", nil, err
+	}
+	// If the filter matches more than one entry, it is not clear which user is meant
+	if len(result.Entries) != 1 {
+		return "", nil, ErrorInvalidCredentials
+	}
+	entry := result.Entries[0]
+	var groups []string
+	if config.LdapGroupAttribute != "" {
+		groups = entry.GetAttributeValues(config.LdapGroupAttribute)
+	}
+	return entry.DN, groups, nil
+}
+
+// readGroupAttribute reads the group attribute of the user entry after a successful bind
+func readGroupAttribute(conn *goldap.Conn, config models.AuthenticationConfig, userDn string) ([]string, error) {
+	request := goldap.NewSearchRequest(userDn, goldap.ScopeBaseObject, goldap.NeverDerefAliases,
+		1, int(dialTimeout.Seconds()), false, "(objectClass=*)", []string{config.LdapGroupAttribute}, nil)
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) == 0 {
+		return []string{}, nil
+	}
+	return result.Entries[0].GetAttributeValues(config.LdapGroupAttribute), nil
+}
+
+// parseGroups returns the groups as they were sent by the server. If a group is a DN
+// (e.g. cn=admins,ou=groups,dc=example,dc=com), the value of the first RDN (admins) is added as well
+func parseGroups(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+		dn, err := goldap.ParseDN(value)
+		if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+			continue
+		}
+		name := dn.RDNs[0].Attributes[0].Value
+		if name != "" && name != value {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,171 @@
+package ldap
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/ldapstub"
+)
+
+var server *ldapstub.Server
+
+func TestMain(m *testing.M) {
+	var err error
+	server, err = ldapstub.Start([]ldapstub.Entry{
+		{
+			Dn:       "cn=service,dc=example,dc=com",
+			Password: "servicepw",
+		},
+		{
+			Dn:       "uid=alice,ou=people,dc=example,dc=com",
+			Password: "alicepw",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"alice"},
+				"memberOf":    {"cn=gokapi,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"},
+			},
+		},
+		{
+			Dn:       "uid=bob,ou=people,dc=example,dc=com",
+			Password: "bobpw",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"bob"},
+			},
+		},
+		{
+			Dn:       "uid=bob,ou=external,dc=example,dc=com",
+			Password: "bobpw",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"bob"},
+			},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	exitVal := m.Run()
+	server.Close()
+	os.Exit(exitVal)
+}
+
+func getTemplateConfig() models.AuthenticationConfig {
+	return models.AuthenticationConfig{
+		Method:             models.AuthenticationLdap,
+		LdapUrl:            server.Url(),
+		LdapUserDnTemplate: "uid={username},ou=people,dc=example,dc=com",
+		LdapGroupAttribute: "memberOf",
+	}
+}
+
+func getSearchConfig() models.AuthenticationConfig {
+	return models.AuthenticationConfig{
+		Method:             models.AuthenticationLdap,
+		LdapUrl:            server.Url(),
+		LdapBindDn:         "cn=service,dc=example,dc=com",
+		LdapBindPassword:   "servicepw",
+		LdapSearchBase:     "ou=people,dc=example,dc=com",
+		LdapSearchFilter:   "(&(objectClass=person)(uid={username}))",
+		LdapGroupAttribute: "memberOf",
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	test.IsNil(t, CheckConfig(getTemplateConfig()))
+	test.IsNil(t, CheckConfig(getSearchConfig()))
+
+	config := getTemplateConfig()
+	config.LdapUrl = ""
+	test.IsNotNil(t, CheckConfig(config))
+	config = getTemplateConfig()
+	config.LdapUserDnTemplate = "uid=test,dc=example,dc=com"
+	test.IsNotNil(t, CheckConfig(config))
+	config = getTemplateConfig()
+	config.LdapGroups = []string{"gokapi"}
+	config.LdapGroupAttribute = ""
+	test.IsNotNil(t, CheckConfig(config))
+
+	config = getSearchConfig()
+	config.LdapSearchBase = ""
+	test.IsNotNil(t, CheckConfig(config))
+	config = getSearchConfig()
+	config.LdapSearchFilter = "(uid=test)"
+	test.IsNotNil(t, CheckConfig(config))
+}
+
+func TestAuthenticateWithTemplate(t *testing.T) {
+	config := getTemplateConfig()
+	groups, err := Authenticate(config, "alice", "alicepw")
+	test.IsNil(t, err)
+	test.IsEqual(t, groups, []string{"cn=gokapi,ou=groups,dc=example,dc=com", "gokapi",
+		"cn=staff,ou=groups,dc=example,dc=com", "staff"})
+
+	groups, err = Authenticate(config, "bob", "bobpw")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(groups), 0)
+
+	_, err = Authenticate(config, "alice", "invalid")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), true)
+	_, err = Authenticate(config, "alice", "")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), true)
+	_, err = Authenticate(config, "", "alicepw")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), true)
+	_, err = Authenticate(config, "carol", "alicepw")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), true)
+
+	config.LdapGroupAttribute = ""
+	groups, err = Authenticate(config, "alice", "alicepw")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(groups), 0)
+}
+
+func TestAuthenticateWithSearch(t *testing.T) {
+	config := getSearchConfig()
+	groups, err := Authenticate(config, "alice", "alicepw")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(groups), 4)
+	test.IsEqualString(t, groups[1], "gokapi")
+
+	_, err = Authenticate(config, "alice", "bobpw")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), true)
+	_, err = Authenticate(config, "carol", "carolpw")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), true)
+	// Special characters of the username must not be interpreted as part of the filter
+	_, err = Authenticate(config, "*", "alicepw")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), true)
+
+	// If the filter matches multiple users, the login is refused
+	config.LdapSearchBase = "dc=example,dc=com"
+	_, err = Authenticate(config, "bob", "bobpw")
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), true)
+
+	config = getSearchConfig()
+	config.LdapBindDn = ""
+	config.LdapBindPassword = ""
+	_, err = Authenticate(config, "alice", "alicepw")
+	test.IsNil(t, err)
+
+	config = getSearchConfig()
+	config.LdapBindPassword = "invalid"
+	_, err = Authenticate(config, "alice", "alicepw")
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), false)
+}
+
+func TestAuthenticateInvalidServer(t *testing.T) {
+	config := getTemplateConfig()
+	config.LdapUrl = "ldap://127.0.0.1:1"
+	_, err := Authenticate(config, "alice", "alicepw")
+	test.IsNotNil(t, err)
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidCredentials), false)
+}
+
+func TestParseGroups(t *testing.T) {
+	test.IsEqual(t, parseGroups([]string{"admins", "CN=Domain Users,CN=Users,DC=example,DC=com"}),
+		[]string{"admins", "CN=Domain Users,CN=Users,DC=example,DC=com", "Domain Users"})
+	test.IsEqualInt(t, len(parseGroups(nil)), 0)
+}
@@ -0,0 +1,129 @@
+package passwordreset
+
+/**
+Tokens for password reset links that are sent by email
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/helper"
+)
+
+// ttl is the time a reset link is valid
+const ttl = time.Hour
+
+const sharedKeyPrefix = "pwreset:"
+
+type resetToken struct {
+	UserId int   `json:"u"`
+	Expiry int64 `json:"e"`
+}
+
+var tokens = make(map[string]resetToken)
+var mutex sync.Mutex
+var startCleanupOnce sync.Once
+
+// Create returns a new token for the user, which can be used once to set a new password.
+// Returns an error, if the token cannot be stored in the shared state
+func Create(userId int) (string, error) {
+	id := helper.GenerateRandomString(40)
+	token := resetToken{
+		UserId: userId,
+		Expiry: time.Now().Add(ttl).Unix(),
+	}
+	if sharedstate.IsEnabled() {
+		value, err := json.Marshal(token)
+		helper.Check(err)
+		err = sharedstate.Get().Set(sharedKeyPrefix+id, value, ttl)
+		if err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+	mutex.Lock()
+	tokens[id] = token
+	mutex.Unlock()
+	startCleanupOnce.Do(func() { go cleanUp(true) })
+	return id, nil
+}
+
+// GetUserId returns the ID of the user the token was created for or false if the token
+// is invalid or has expired
+func GetUserId(id string) (int, bool) {
+	if id == "" {
+		return 0, false
+	}
+	token, ok := getToken(id, false)
+	if !ok || token.Expiry < time.Now().Unix() {
+		return 0, false
+	}
+	return token.UserId, true
+}
+
+// Consume returns the ID of the user the token was created for and deletes the token in the same
+// operation, so that it can only be used once. Returns false if the token is invalid, has expired
+// or has already been used
+func Consume(id string) (int, bool) {
+	if id == "" {
+		return 0, false
+	}
+	token, ok := getToken(id, true)
+	if !ok || token.Expiry < time.Now().Unix() {
+		return 0, false
+	}
+	return token.UserId, true
+}
+
+// getToken returns the token with the given id. If doDelete is true, the token is deleted as well
+func getToken(id string, doDelete bool) (resetToken, bool) {
+	if sharedstate.IsEnabled() {
+		var value []byte
+		var ok bool
+		var err error
+		if doDelete {
+			value, ok, err = sharedstate.Get().GetAndDelete(sharedKeyPrefix + id)
+		} else {
+			value, ok, err = sharedstate.Get().Get(sharedKeyPrefix + id)
+		}
+		if err != nil {
+			sharedstate.PrintError(err)
+			return resetToken{}, false
+		}
+		if !ok {
+			return resetToken{}, false
+		}
+		var result resetToken
+		err = json.Unmarshal(value, &result)
+		if err != nil {
+			fmt.Println("Could not read password reset token from shared state: " + err.Error())
+			return resetToken{}, false
+		}
+		return result, true
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	result, ok := tokens[id]
+	if ok && doDelete {
+		delete(tokens, id)
+	}
+	return result, ok
+}
+
+func cleanUp(periodic bool) {
+	mutex.Lock()
+	for id, token := range tokens {
+		if token.Expiry < time.Now().Unix() {
+			delete(tokens, id)
+		}
+	}
+	mutex.Unlock()
+	if periodic {
+		time.Sleep(time.Hour)
+		go cleanUp(true)
+	}
+}
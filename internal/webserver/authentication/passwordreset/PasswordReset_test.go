@@ -0,0 +1,110 @@
+package passwordreset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate/redisstate"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+)
+
+func createToken(t *testing.T, userId int) string {
+	t.Helper()
+	token, err := Create(userId)
+	test.IsNil(t, err)
+	return token
+}
+
+func TestCreate(t *testing.T) {
+	startCleanupOnce.Do(func() {
+		// Do nothing
+	})
+	token := createToken(t, 5)
+	test.IsEqualInt(t, len(token), 40)
+	test.IsNotEqualString(t, token, createToken(t, 5))
+	userId, ok := GetUserId(token)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, userId, 5)
+
+	_, ok = GetUserId("invalid")
+	test.IsEqualBool(t, ok, false)
+	_, ok = GetUserId("")
+	test.IsEqualBool(t, ok, false)
+
+	userId, ok = Consume(token)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, userId, 5)
+	_, ok = GetUserId(token)
+	test.IsEqualBool(t, ok, false)
+	_, ok = Consume(token)
+	test.IsEqualBool(t, ok, false)
+	_, ok = Consume("")
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestExpiry(t *testing.T) {
+	token := createToken(t, 6)
+	mutex.Lock()
+	expiredToken := tokens[token]
+	expiredToken.Expiry = time.Now().Add(-time.Second).Unix()
+	tokens[token] = expiredToken
+	mutex.Unlock()
+	_, ok := GetUserId(token)
+	test.IsEqualBool(t, ok, false)
+	_, ok = Consume(token)
+	test.IsEqualBool(t, ok, false)
+	token = createToken(t, 6)
+	mutex.Lock()
+	expiredToken = tokens[token]
+	expiredToken.Expiry = time.Now().Add(-time.Second).Unix()
+	tokens[token] = expiredToken
+	mutex.Unlock()
+	cleanUp(false)
+	mutex.Lock()
+	_, ok = tokens[token]
+	mutex.Unlock()
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestSharedState(t *testing.T) {
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	defer sharedstate.Close()
+	otherInstance, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	defer otherInstance.Close()
+
+	token := createToken(t, 7)
+	_, ok, _ := otherInstance.Get(sharedKeyPrefix + token)
+	test.IsEqualBool(t, ok, true)
+	userId, ok := GetUserId(token)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, userId, 7)
+	userId, ok = Consume(token)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, userId, 7)
+	_, ok, _ = otherInstance.Get(sharedKeyPrefix + token)
+	test.IsEqualBool(t, ok, false)
+	_, ok = Consume(token)
+	test.IsEqualBool(t, ok, false)
+
+	token = createToken(t, 7)
+	mRedis.FastForward(2 * time.Hour)
+	_, ok = GetUserId(token)
+	test.IsEqualBool(t, ok, false)
+
+	// Malformed entries are treated as missing
+	test.IsNil(t, otherInstance.Set(sharedKeyPrefix+"malformed", []byte("{invalid"), time.Minute))
+	_, ok = GetUserId("malformed")
+	test.IsEqualBool(t, ok, false)
+
+	// The token is not returned, if it cannot be stored
+	mRedis.Close()
+	_, err = Create(7)
+	test.IsNotNil(t, err)
+}
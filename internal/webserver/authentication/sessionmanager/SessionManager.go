@@ -6,6 +6,7 @@ Manages the sessions for the admin user or to access password-protected files
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/forceu/gokapi/internal/configuration/database"
@@ -21,21 +22,47 @@ const lengthSessionId = 60
 // If a valid session is found, useSession will be called
 // Returns true if authenticated, otherwise false
 func IsValidSession(w http.ResponseWriter, r *http.Request, isOauth bool, OAuthRecheckInterval int) (models.User, bool) {
-	cookie, err := r.Cookie("session_token")
-	if err == nil {
-		sessionString := cookie.Value
-		if sessionString != "" {
-			session, ok := database.GetSession(sessionString)
-			if ok {
-				user, userExists := database.GetUser(session.UserId)
-				if !userExists {
-					return user, false
-				}
-				return user, useSession(w, sessionString, session, isOauth, OAuthRecheckInterval)
-			}
+	sessionString, session, ok := getSession(r)
+	if !ok || session.IsRecipientSession() {
+		return models.User{}, false
+	}
+	user, userExists := database.GetUser(session.UserId)
+	if !userExists {
+		return user, false
+	}
+	return user, useSession(w, sessionString, session, isOauth, OAuthRecheckInterval)
+}
+
+// GetRecipient returns the identity of the user, if a valid session token is submitted. In contrast to
+// IsValidSession, recipient sessions of users that are not registered are accepted as well
+func GetRecipient(w http.ResponseWriter, r *http.Request, isOauth bool, OAuthRecheckInterval int) (models.Recipient, bool) {
+	sessionString, session, ok := getSession(r)
+	if !ok {
+		return models.Recipient{}, false
+	}
+	result := models.Recipient{Name: session.RecipientName, Groups: session.GetGroups()}
+	if !session.IsRecipientSession() {
+		user, userExists := database.GetUser(session.UserId)
+		if !userExists {
+			return models.Recipient{}, false
 		}
+		result.Name = user.Name
+		result.UserId = user.Id
+	}
+	if !useSession(w, sessionString, session, isOauth, OAuthRecheckInterval) {
+		return models.Recipient{}, false
 	}
-	return models.User{}, false
+	return result, true
+}
+
+// getSession returns the session of the submitted session token
+func getSession(r *http.Request) (string, models.Session, bool) {
+	cookie, err := r.Cookie("session_token")
+	if err != nil || cookie.Value == "" {
+		return "", models.Session{}, false
+	}
+	session, ok := database.GetSession(cookie.Value)
+	return cookie.Value, session, ok
 }
 
 // useSession checks if a session is still valid. It Changes the session string
@@ -48,27 +75,43 @@ func useSession(w http.ResponseWriter, id string, session models.Session, isOaut
 		return false
 	}
 	if session.RenewAt < time.Now().Unix() {
-		CreateSession(w, isOauth, OAuthRecheckInterval, session.UserId)
+		saveNewSession(w, session, isOauth, OAuthRecheckInterval)
 		database.DeleteSession(id)
 	}
-	go database.UpdateUserLastOnline(session.UserId)
+	if !session.IsRecipientSession() {
+		go database.UpdateUserLastOnline(session.UserId)
+	}
 	return true
 }
 
 // CreateSession creates a new session - called after login with correct username / password
 // If sessions parameter is nil, it will be loaded from config
 func CreateSession(w http.ResponseWriter, isOauth bool, OAuthRecheckInterval int, userId int) {
+	saveNewSession(w, models.Session{UserId: userId}, isOauth, OAuthRecheckInterval)
+}
+
+// CreateOauthSession creates a new session after an OAuth login and stores the groups sent by the provider
+func CreateOauthSession(w http.ResponseWriter, OAuthRecheckInterval int, userId int, groups []string) {
+	saveNewSession(w, models.Session{UserId: userId, Groups: strings.Join(groups, "\n")}, true, OAuthRecheckInterval)
+}
+
+// CreateRecipientSession creates a new session for a user that is not registered. The session only
+// grants access to files and file requests that require a login
+func CreateRecipientSession(w http.ResponseWriter, OAuthRecheckInterval int, name string, groups []string) {
+	saveNewSession(w, models.Session{RecipientName: name, Groups: strings.Join(groups, "\n")}, true, OAuthRecheckInterval)
+}
+
+// saveNewSession stores the user information of the session with a new session ID and expiry
+func saveNewSession(w http.ResponseWriter, session models.Session, isOauth bool, OAuthRecheckInterval int) {
 	timeExpiry := time.Now().Add(cookieLifeAdmin)
 	if isOauth {
 		timeExpiry = time.Now().Add(time.Duration(OAuthRecheckInterval) * time.Hour)
 	}
 
 	sessionString := helper.GenerateRandomString(lengthSessionId)
-	database.SaveSession(sessionString, models.Session{
-		RenewAt:    time.Now().Add(12 * time.Hour).Unix(),
-		ValidUntil: timeExpiry.Unix(),
-		UserId:     userId,
-	})
+	session.RenewAt = time.Now().Add(12 * time.Hour).Unix()
+	session.ValidUntil = timeExpiry.Unix()
+	database.SaveSession(sessionString, session)
 	writeSessionCookie(w, sessionString, timeExpiry)
 }
 
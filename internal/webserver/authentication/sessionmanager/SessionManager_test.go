@@ -100,6 +100,41 @@ func TestCreateSession(t *testing.T) {
 	test.IsEqualBool(t, isEqual, true)
 }
 
+func TestGetRecipient(t *testing.T) {
+	_, ok := GetRecipient(getRecorder(nil))
+	test.IsEqualBool(t, ok, false)
+	_, ok = GetRecipient(getRecorder([]test.Cookie{{Name: "session_token", Value: "expiredsession"}}))
+	test.IsEqualBool(t, ok, false)
+	_, ok = GetRecipient(getRecorder([]test.Cookie{{Name: "session_token", Value: "validSessionInvalidUser"}}))
+	test.IsEqualBool(t, ok, false)
+
+	w, _, _, _ := getRecorder(nil)
+	CreateOauthSession(w, 20, 5, []string{"staff", "sales"})
+	cookies := w.Result().Cookies()
+	test.IsEqualInt(t, len(cookies), 1)
+	recipient, ok := GetRecipient(getRecorder([]test.Cookie{{Name: "session_token", Value: cookies[0].Value}}))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, recipient.UserId, 5)
+	test.IsEqualInt(t, len(recipient.Groups), 2)
+	test.IsEqualString(t, recipient.Groups[1], "sales")
+	user, ok := IsValidSession(getRecorder([]test.Cookie{{Name: "session_token", Value: cookies[0].Value}}))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, user.Name, recipient.Name)
+
+	w, _, _, _ = getRecorder(nil)
+	CreateRecipientSession(w, 20, "recipient@example.com", []string{"staff"})
+	cookies = w.Result().Cookies()
+	test.IsEqualInt(t, len(cookies), 1)
+	recipient, ok = GetRecipient(getRecorder([]test.Cookie{{Name: "session_token", Value: cookies[0].Value}}))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, recipient.Name, "recipient@example.com")
+	test.IsEqualInt(t, recipient.UserId, 0)
+	test.IsEqualString(t, recipient.Groups[0], "staff")
+	// A recipient session does not grant access to the admin menu
+	_, ok = IsValidSession(getRecorder([]test.Cookie{{Name: "session_token", Value: cookies[0].Value}}))
+	test.IsEqualBool(t, ok, false)
+}
+
 func TestLogoutSession(t *testing.T) {
 	user, ok := IsValidSession(getRecorder([]test.Cookie{{
 		Name:  "session_token",
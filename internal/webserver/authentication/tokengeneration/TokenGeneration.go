@@ -28,6 +28,9 @@ func Generate(user models.User, permission models.ApiPermission) (string, int64,
 	if containsApiPermission(permission, models.ApiPermManageFileRequests) && !user.HasPermissionCreateFileRequests() {
 		return "", 0, errors.New("user does not have permission to generate a token with PERM_MANAGE_FILE_REQUESTS")
 	}
+	if containsApiPermission(permission, models.ApiPermManageWebhooks) && !user.IsAdmin() {
+		return "", 0, errors.New("user does not have permission to generate a token with PERM_MANAGE_WEBHOOKS")
+	}
 
 	key := models.ApiKey{
 		Id:           helper.GenerateRandomString(api.LengthApiKey),
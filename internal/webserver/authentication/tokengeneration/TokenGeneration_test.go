@@ -50,6 +50,19 @@ func TestGenerate(t *testing.T) {
 		test.IsEqualBool(t, err != nil, true)
 		test.IsEqualString(t, err.Error(), "user does not have permission to generate a token with PERM_MANAGE_LOGS")
 	})
+	t.Run("Fail on missing admin rank for PERM_MANAGE_WEBHOOKS", func(t *testing.T) {
+		regularUser := testUser
+		regularUser.UserLevel = models.UserLevelUser
+		_, _, err := Generate(regularUser, models.ApiPermManageWebhooks)
+		test.IsEqualBool(t, err != nil, true)
+		test.IsEqualString(t, err.Error(), "user does not have permission to generate a token with PERM_MANAGE_WEBHOOKS")
+
+		adminUser := testUser
+		adminUser.UserLevel = models.UserLevelAdmin
+		token, _, err := Generate(adminUser, models.ApiPermManageWebhooks)
+		test.IsNil(t, err)
+		test.IsEqualBool(t, len(token) > 0, true)
+	})
 
 	t.Run("Success with elevated permissions", func(t *testing.T) {
 		// Grant user the necessary permission
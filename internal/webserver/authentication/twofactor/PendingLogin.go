@@ -0,0 +1,170 @@
+package twofactor
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/helper"
+)
+
+const sharedKeyPrefix = "pendinglogin:"
+
+// sharedLockDuration is the time after which the lock of a pending login is released automatically,
+// in case the instance holding it is terminated
+const sharedLockDuration = 10 * time.Second
+
+// A pending login is created after the user entered the correct password and is waiting for the second factor
+type pendingLogin struct {
+	UserId   int   `json:"u"`
+	Attempts int   `json:"a"`
+	Expiry   int64 `json:"e"`
+}
+
+var pendingLogins = make(map[string]pendingLogin)
+
+// CreatePendingLogin stores that the user has entered the correct password and returns a token,
+// which has to be submitted together with the second factor
+func CreatePendingLogin(userId int) string {
+	token := helper.GenerateRandomString(40)
+	login := pendingLogin{
+		UserId: userId,
+		Expiry: time.Now().Add(pendingLoginTtl).Unix(),
+	}
+	if sharedstate.IsEnabled() {
+		err := saveSharedPendingLogin(token, login)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return token
+	}
+	mutex.Lock()
+	cleanupPendingLogins()
+	pendingLogins[token] = login
+	mutex.Unlock()
+	return token
+}
+
+// IsValidPendingLogin returns true, if the token is valid and has not expired
+func IsValidPendingLogin(token string) bool {
+	var login pendingLogin
+	var ok bool
+	if sharedstate.IsEnabled() {
+		login, ok = getSharedPendingLogin(token)
+	} else {
+		mutex.Lock()
+		login, ok = pendingLogins[token]
+		mutex.Unlock()
+	}
+	return ok && login.Expiry > time.Now().Unix() && login.Attempts < maxLoginTries
+}
+
+// CompletePendingLogin verifies the second factor for a pending login and returns the user ID if it is valid.
+// The token is removed after a successful login or after too many failed attempts
+func CompletePendingLogin(token, code string) (int, bool) {
+	login, ok := addLoginAttempt(token)
+	if !ok {
+		return 0, false
+	}
+	if !Verify(login.UserId, code) {
+		if login.Attempts >= maxLoginTries {
+			deletePendingLogin(token)
+		}
+		return 0, false
+	}
+	deletePendingLogin(token)
+	return login.UserId, true
+}
+
+// addLoginAttempt increases the number of attempts of the pending login before the code is verified, so that
+// parallel requests cannot exceed maxLoginTries. Returns false if the token is invalid, has expired or
+// no attempts are left
+func addLoginAttempt(token string) (pendingLogin, bool) {
+	if sharedstate.IsEnabled() {
+		lockName := sharedKeyPrefix + "lock:" + token
+		err := sharedstate.Lock(lockName, sharedLockDuration)
+		if err != nil {
+			sharedstate.PrintError(err)
+			return pendingLogin{}, false
+		}
+		defer func() {
+			if err := sharedstate.Unlock(lockName); err != nil {
+				sharedstate.PrintError(err)
+			}
+		}()
+		login, ok := getSharedPendingLogin(token)
+		if !ok || login.Expiry < time.Now().Unix() || login.Attempts >= maxLoginTries {
+			deletePendingLogin(token)
+			return pendingLogin{}, false
+		}
+		login.Attempts++
+		// The attempt is only allowed if it has been counted
+		err = saveSharedPendingLogin(token, login)
+		if err != nil {
+			sharedstate.PrintError(err)
+			return pendingLogin{}, false
+		}
+		return login, true
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	login, ok := pendingLogins[token]
+	if !ok || login.Expiry < time.Now().Unix() || login.Attempts >= maxLoginTries {
+		delete(pendingLogins, token)
+		return pendingLogin{}, false
+	}
+	login.Attempts++
+	pendingLogins[token] = login
+	return login, true
+}
+
+func deletePendingLogin(token string) {
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Get().Delete(sharedKeyPrefix + token)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return
+	}
+	mutex.Lock()
+	delete(pendingLogins, token)
+	mutex.Unlock()
+}
+
+func getSharedPendingLogin(token string) (pendingLogin, bool) {
+	value, ok, err := sharedstate.Get().Get(sharedKeyPrefix + token)
+	if err != nil {
+		sharedstate.PrintError(err)
+		return pendingLogin{}, false
+	}
+	if !ok {
+		return pendingLogin{}, false
+	}
+	var result pendingLogin
+	err = json.Unmarshal(value, &result)
+	if err != nil {
+		fmt.Println("Could not read pending login from shared state: " + err.Error())
+		return pendingLogin{}, false
+	}
+	return result, true
+}
+
+func saveSharedPendingLogin(token string, login pendingLogin) error {
+	value, err := json.Marshal(login)
+	helper.Check(err)
+	ttl := time.Until(time.Unix(login.Expiry, 0))
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	return sharedstate.Get().Set(sharedKeyPrefix+token, value, ttl)
+}
+
+// cleanupPendingLogins removes expired tokens, mutex needs to be locked
+func cleanupPendingLogins() {
+	for token, login := range pendingLogins {
+		if login.Expiry < time.Now().Unix() {
+			delete(pendingLogins, token)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package twofactor
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/webserver/api/mutex/apimutex"
+)
+
+// Parameters as recommended by RFC 6238 and supported by all common authenticator apps
+const (
+	period          = 30
+	digits          = 6
+	allowedSkew     = 1
+	secretLength    = 20
+	recoveryCodes   = 10
+	maxLoginTries   = 5
+	pendingLoginTtl = 5 * time.Minute
+)
+
+// ErrorAlreadyEnabled is returned, if an enrollment is started for a user that already uses two-factor authentication
+var ErrorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+// ErrorInvalidCode is returned, if the entered code is not valid
+var ErrorInvalidCode = errors.New("the entered code is not valid")
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var mutex sync.Mutex
+
+// lockUser prevents the two-factor data of the user from being modified concurrently, also by other instances.
+// Otherwise, a code or recovery code could be used more than once
+func lockUser(userId int) func() {
+	idStr := strconv.Itoa(userId)
+	apimutex.Lock(apimutex.TypeUser, idStr)
+	mutex.Lock()
+	return func() {
+		mutex.Unlock()
+		apimutex.Unlock(apimutex.TypeUser, idStr)
+	}
+}
+
+// IsEnabled returns true, if the user has completed the enrollment for two-factor authentication
+func IsEnabled(userId int) bool {
+	totp, ok := database.GetUserTotp(userId)
+	return ok && totp.IsEnabled()
+}
+
+// IsEnrollmentRequired returns true, if the user has to set up two-factor authentication before
+// being able to use Gokapi
+func IsEnrollmentRequired(user models.User) bool {
+	authConfig := configuration.Get().Authentication
+	if authConfig.Method != models.AuthenticationInternal || !authConfig.RequireTwoFactorForAdmins {
+		return false
+	}
+	return user.IsAdmin() && !IsEnabled(user.Id)
+}
+
+// StartEnrollment generates a new secret for the user, which is stored unconfirmed until
+// ConfirmEnrollment is called with a valid code. Returns the provisioning URI for authenticator apps
+func StartEnrollment(user models.User) (string, error) {
+	unlock := lockUser(user.Id)
+	defer unlock()
+	if IsEnabled(user.Id) {
+		return "", ErrorAlreadyEnabled
+	}
+	secret := generateSecret()
+	database.SaveUserTotp(models.UserTotp{
+		UserId: user.Id,
+		Secret: secret,
+	})
+	return GetProvisioningUri(secret, user.Name), nil
+}
+
+// GetPendingEnrollment returns the provisioning URI, if an enrollment was started but not confirmed yet
+func GetPendingEnrollment(user models.User) (string, bool) {
+	totp, ok := database.GetUserTotp(user.Id)
+	if !ok || totp.IsEnabled() || totp.Secret == "" {
+		return "", false
+	}
+	return GetProvisioningUri(totp.Secret, user.Name), true
+}
+
+// ConfirmEnrollment enables two-factor authentication for the user, if the code is valid for the secret
+// generated by StartEnrollment. Returns the recovery codes in plain text, which are only shown once
+func ConfirmEnrollment(userId int, code string) ([]string, error) {
+	unlock := lockUser(userId)
+	defer unlock()
+	totp, ok := database.GetUserTotp(userId)
+	if !ok || totp.Secret == "" {
+		return nil, errors.New("two-factor authentication has not been set up")
+	}
+	if totp.IsEnabled() {
+		return nil, ErrorAlreadyEnabled
+	}
+	step, ok := validateCode(totp.Secret, code, time.Now(), totp.LastUsedStep)
+	if !ok {
+		return nil, ErrorInvalidCode
+	}
+	codes, hashes := generateRecoveryCodes()
+	totp.IsConfirmed = true
+	totp.LastUsedStep = step
+	totp.SetRecoveryCodeHashes(hashes)
+	database.SaveUserTotp(totp)
+	return codes, nil
+}
+
+// Verify returns true, if the code is a valid TOTP code or an unused recovery code for the user.
+// Every TOTP code and every recovery code can only be used once
+func Verify(userId int, code string) bool {
+	unlock := lockUser(userId)
+	defer unlock()
+	totp, ok := database.GetUserTotp(userId)
+	if !ok || !totp.IsEnabled() {
+		return false
+	}
+	step, ok := validateCode(totp.Secret, code, time.Now(), totp.LastUsedStep)
+	if ok {
+		totp.LastUsedStep = step
+		database.SaveUserTotp(totp)
+		return true
+	}
+	hashes, ok := useRecoveryCode(totp.GetRecoveryCodeHashes(), code)
+	if ok {
+		totp.SetRecoveryCodeHashes(hashes)
+		database.SaveUserTotp(totp)
+		return true
+	}
+	return false
+}
+
+// GetRemainingRecoveryCodes returns the number of recovery codes that have not been used yet
+func GetRemainingRecoveryCodes(userId int) int {
+	totp, ok := database.GetUserTotp(userId)
+	if !ok {
+		return 0
+	}
+	return len(totp.GetRecoveryCodeHashes())
+}
+
+// Disable removes two-factor authentication for the user
+func Disable(userId int) {
+	unlock := lockUser(userId)
+	defer unlock()
+	database.DeleteUserTotp(userId)
+}
+
+// GetProvisioningUri returns the otpauth:// URI that is used by authenticator apps to add the account
+func GetProvisioningUri(secret, accountName string) string {
+	issuer := configuration.Get().PublicName
+	if issuer == "" {
+		issuer = "Gokapi"
+	}
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(digits))
+	values.Set("period", fmt.Sprint(period))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+func generateSecret() string {
+	secret := make([]byte, secretLength)
+	_, err := rand.Read(secret)
+	helper.Check(err)
+	return base32NoPadding.EncodeToString(secret)
+}
+
+// generateCode calculates the code for the time step as defined in RFC 4226 and RFC 6238
+func generateCode(secret string, step int64) (string, error) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, value%1000000), nil
+}
+
+// validateCode returns the time step of the code, if it is valid at the given time. To allow for clock drift,
+// the previous and next code are accepted as well. Codes of a step that is not newer than lastUsedStep are rejected
+func validateCode(secret, code string, now time.Time, lastUsedStep int64) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != digits {
+		return 0, false
+	}
+	currentStep := now.Unix() / period
+	for step := currentStep - allowedSkew; step <= currentStep+allowedSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		expected, err := generateCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if helper.IsEqualStringConstantTime(expected, code) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// generateRecoveryCodes returns new recovery codes in plain text and their hashes
+func generateRecoveryCodes() ([]string, []string) {
+	codes := make([]string, recoveryCodes)
+	hashes := make([]string, recoveryCodes)
+	for i := range codes {
+		random := make([]byte, 5)
+		_, err := rand.Read(random)
+		helper.Check(err)
+		code := base32NoPadding.EncodeToString(random)
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes
+}
+
+// Recovery codes have enough entropy, so that a fast hash can be used
+func hashRecoveryCode(code string) string {
+	normalised := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalised))
+	return hex.EncodeToString(hash[:])
+}
+
+// useRecoveryCode returns the remaining hashes, if the code matches one of the hashes
+func useRecoveryCode(hashes []string, code string) ([]string, bool) {
+	if code == "" {
+		return hashes, false
+	}
+	hashedCode := hashRecoveryCode(code)
+	for i, hash := range hashes {
+		if helper.IsEqualStringConstantTime(hash, hashedCode) {
+			result := make([]string, 0, len(hashes)-1)
+			result = append(result, hashes[:i]...)
+			return append(result, hashes[i+1:]...), true
+		}
+	}
+	return hashes, false
+}
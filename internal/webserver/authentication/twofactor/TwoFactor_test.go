@@ -0,0 +1,303 @@
+package twofactor
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate/redisstate"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+)
+
+// Test secret of RFC 6238, "12345678901234567890" encoded as base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	configuration.Load()
+	configuration.ConnectDatabase()
+	exitVal := m.Run()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+func getCurrentCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := generateCode(secret, time.Now().Unix()/period)
+	test.IsNil(t, err)
+	return code
+}
+
+func TestGenerateCode(t *testing.T) {
+	// Test vectors of RFC 6238, truncated to 6 digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for timestamp, expected := range vectors {
+		code, err := generateCode(rfcSecret, timestamp/period)
+		test.IsNil(t, err)
+		test.IsEqualString(t, code, expected)
+	}
+	_, err := generateCode("invalid!", 1)
+	test.IsNotNil(t, err)
+}
+
+func TestValidateCode(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step, ok := validateCode(rfcSecret, "081804", now, 0)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt64(t, step, 1111111109/period)
+	_, ok = validateCode(rfcSecret, "081 804", now, 0)
+	test.IsEqualBool(t, ok, true)
+	// Previous and next time step are accepted
+	_, ok = validateCode(rfcSecret, "081804", now.Add(period*time.Second), 0)
+	test.IsEqualBool(t, ok, true)
+	_, ok = validateCode(rfcSecret, "081804", now.Add(-period*time.Second), 0)
+	test.IsEqualBool(t, ok, true)
+	_, ok = validateCode(rfcSecret, "081804", now.Add(3*period*time.Second), 0)
+	test.IsEqualBool(t, ok, false)
+	// Code cannot be reused
+	_, ok = validateCode(rfcSecret, "081804", now, step)
+	test.IsEqualBool(t, ok, false)
+	_, ok = validateCode(rfcSecret, "000000", now, 0)
+	test.IsEqualBool(t, ok, false)
+	_, ok = validateCode(rfcSecret, "12345", now, 0)
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestGetProvisioningUri(t *testing.T) {
+	uri := GetProvisioningUri("JBSWY3DPEHPK3PXP", "test user")
+	parsed, err := url.Parse(uri)
+	test.IsNil(t, err)
+	test.IsEqualString(t, parsed.Scheme, "otpauth")
+	test.IsEqualString(t, parsed.Host, "totp")
+	test.IsEqualBool(t, strings.HasSuffix(parsed.Path, ":test user"), true)
+	test.IsEqualString(t, parsed.Query().Get("secret"), "JBSWY3DPEHPK3PXP")
+	test.IsEqualString(t, parsed.Query().Get("digits"), "6")
+	test.IsEqualString(t, parsed.Query().Get("period"), "30")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes := generateRecoveryCodes()
+	test.IsEqualInt(t, len(codes), recoveryCodes)
+	test.IsEqualInt(t, len(hashes), recoveryCodes)
+	test.IsEqualInt(t, len(codes[0]), 9)
+	remaining, ok := useRecoveryCode(hashes, strings.ToLower(strings.ReplaceAll(codes[3], "-", "")))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, len(remaining), recoveryCodes-1)
+	_, ok = useRecoveryCode(remaining, codes[3])
+	test.IsEqualBool(t, ok, false)
+	_, ok = useRecoveryCode(remaining, "")
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestEnrollment(t *testing.T) {
+	user := models.User{Id: 5, Name: "Test", UserLevel: models.UserLevelSuperAdmin}
+	test.IsEqualBool(t, IsEnabled(user.Id), false)
+	_, ok := GetPendingEnrollment(user)
+	test.IsEqualBool(t, ok, false)
+	_, err := ConfirmEnrollment(user.Id, "123456")
+	test.IsNotNil(t, err)
+
+	uri, err := StartEnrollment(user)
+	test.IsNil(t, err)
+	pendingUri, ok := GetPendingEnrollment(user)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, pendingUri, uri)
+	test.IsEqualBool(t, IsEnabled(user.Id), false)
+	stored, _ := database.GetUserTotp(user.Id)
+
+	_, err = ConfirmEnrollment(user.Id, "invalid")
+	test.IsEqualBool(t, err == ErrorInvalidCode, true)
+	enrollmentCode := getCurrentCode(t, stored.Secret)
+	codes, err := ConfirmEnrollment(user.Id, enrollmentCode)
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(codes), recoveryCodes)
+	test.IsEqualBool(t, IsEnabled(user.Id), true)
+	test.IsEqualInt(t, GetRemainingRecoveryCodes(user.Id), recoveryCodes)
+	_, err = StartEnrollment(user)
+	test.IsEqualBool(t, err == ErrorAlreadyEnabled, true)
+	_, err = ConfirmEnrollment(user.Id, "123456")
+	test.IsEqualBool(t, err == ErrorAlreadyEnabled, true)
+
+	// The code used for the enrollment cannot be used again
+	test.IsEqualBool(t, Verify(user.Id, enrollmentCode), false)
+	test.IsEqualBool(t, Verify(user.Id, codes[0]), true)
+	test.IsEqualBool(t, Verify(user.Id, codes[0]), false)
+	test.IsEqualInt(t, GetRemainingRecoveryCodes(user.Id), recoveryCodes-1)
+	test.IsEqualBool(t, Verify(7, codes[1]), false)
+
+	Disable(user.Id)
+	test.IsEqualBool(t, IsEnabled(user.Id), false)
+	test.IsEqualBool(t, Verify(user.Id, codes[1]), false)
+	test.IsEqualInt(t, GetRemainingRecoveryCodes(user.Id), 0)
+}
+
+func TestIsEnrollmentRequired(t *testing.T) {
+	admin := models.User{Id: 5, UserLevel: models.UserLevelSuperAdmin}
+	user := models.User{Id: 7, UserLevel: models.UserLevelUser}
+	test.IsEqualBool(t, IsEnrollmentRequired(admin), false)
+	configuration.Get().Authentication.RequireTwoFactorForAdmins = true
+	defer func() { configuration.Get().Authentication.RequireTwoFactorForAdmins = false }()
+	test.IsEqualBool(t, IsEnrollmentRequired(admin), true)
+	test.IsEqualBool(t, IsEnrollmentRequired(user), false)
+	database.SaveUserTotp(models.UserTotp{UserId: admin.Id, Secret: rfcSecret, IsConfirmed: true})
+	test.IsEqualBool(t, IsEnrollmentRequired(admin), false)
+	database.DeleteUserTotp(admin.Id)
+	configuration.Get().Authentication.Method = models.AuthenticationOAuth2
+	defer func() { configuration.Get().Authentication.Method = models.AuthenticationInternal }()
+	test.IsEqualBool(t, IsEnrollmentRequired(admin), false)
+}
+
+func TestPendingLogin(t *testing.T) {
+	database.SaveUserTotp(models.UserTotp{UserId: 7, Secret: rfcSecret, IsConfirmed: true})
+	defer database.DeleteUserTotp(7)
+
+	token := CreatePendingLogin(7)
+	test.IsEqualBool(t, IsValidPendingLogin(token), true)
+	test.IsEqualBool(t, IsValidPendingLogin("invalid"), false)
+	_, ok := CompletePendingLogin("invalid", getCurrentCode(t, rfcSecret))
+	test.IsEqualBool(t, ok, false)
+	_, ok = CompletePendingLogin(token, "000000")
+	test.IsEqualBool(t, ok, false)
+	userId, ok := CompletePendingLogin(token, getCurrentCode(t, rfcSecret))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, userId, 7)
+	test.IsEqualBool(t, IsValidPendingLogin(token), false)
+
+	// Token is invalidated after too many failed attempts
+	token = CreatePendingLogin(7)
+	for i := 0; i < maxLoginTries; i++ {
+		_, ok = CompletePendingLogin(token, "invalid")
+		test.IsEqualBool(t, ok, false)
+	}
+	test.IsEqualBool(t, IsValidPendingLogin(token), false)
+
+	// The attempt is counted before the code is verified, so that parallel requests cannot exceed the limit
+	token = CreatePendingLogin(7)
+	mutex.Lock()
+	login := pendingLogins[token]
+	login.Attempts = maxLoginTries
+	pendingLogins[token] = login
+	mutex.Unlock()
+	_, ok = CompletePendingLogin(token, getCurrentCode(t, rfcSecret))
+	test.IsEqualBool(t, ok, false)
+	token = CreatePendingLogin(7)
+	var wg sync.WaitGroup
+	for i := 0; i < maxLoginTries*3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = CompletePendingLogin(token, "invalid")
+		}()
+	}
+	wg.Wait()
+	test.IsEqualBool(t, IsValidPendingLogin(token), false)
+
+	// Expired tokens are removed
+	mutex.Lock()
+	pendingLogins["expired"] = pendingLogin{UserId: 7, Expiry: 100}
+	mutex.Unlock()
+	test.IsEqualBool(t, IsValidPendingLogin("expired"), false)
+	CreatePendingLogin(7)
+	mutex.Lock()
+	_, ok = pendingLogins["expired"]
+	mutex.Unlock()
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestPendingLoginSharedState(t *testing.T) {
+	database.SaveUserTotp(models.UserTotp{UserId: 8, Secret: rfcSecret, IsConfirmed: true})
+	defer database.DeleteUserTotp(8)
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	defer sharedstate.Close()
+	otherInstance, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	defer otherInstance.Close()
+
+	token := CreatePendingLogin(8)
+	_, ok, _ := otherInstance.Get(sharedKeyPrefix + token)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, IsValidPendingLogin(token), true)
+	_, ok = CompletePendingLogin(token, "invalid")
+	test.IsEqualBool(t, ok, false)
+	login, ok := getSharedPendingLogin(token)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, login.Attempts, 1)
+	userId, ok := CompletePendingLogin(token, getCurrentCode(t, rfcSecret))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, userId, 8)
+	test.IsEqualBool(t, IsValidPendingLogin(token), false)
+
+	token = CreatePendingLogin(8)
+	for i := 0; i < maxLoginTries; i++ {
+		_, ok = CompletePendingLogin(token, "invalid")
+		test.IsEqualBool(t, ok, false)
+	}
+	test.IsEqualBool(t, IsValidPendingLogin(token), false)
+	_, ok, _ = otherInstance.Get(sharedKeyPrefix + token)
+	test.IsEqualBool(t, ok, false)
+
+	token = CreatePendingLogin(8)
+	mRedis.FastForward(pendingLoginTtl + time.Second)
+	test.IsEqualBool(t, IsValidPendingLogin(token), false)
+
+	// Malformed entries are treated as missing
+	test.IsNil(t, otherInstance.Set(sharedKeyPrefix+"malformed", []byte("{invalid"), time.Minute))
+	test.IsEqualBool(t, IsValidPendingLogin("malformed"), false)
+	_, ok = CompletePendingLogin("malformed", getCurrentCode(t, rfcSecret))
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestVerifySharedLock(t *testing.T) {
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	defer sharedstate.Close()
+	otherInstance, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	defer otherInstance.Close()
+
+	database.SaveUserTotp(models.UserTotp{UserId: 9, Secret: rfcSecret, IsConfirmed: true})
+	defer database.DeleteUserTotp(9)
+	isAcquired, err := otherInstance.TryLock("apimutex:0:9", time.Minute)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isAcquired, true)
+
+	code := getCurrentCode(t, rfcSecret)
+	result := make(chan bool, 1)
+	go func() {
+		result <- Verify(9, code)
+	}()
+	select {
+	case <-result:
+		t.Fatal("code was verified while another instance held the lock")
+	case <-time.After(300 * time.Millisecond):
+	}
+	test.IsNil(t, otherInstance.Unlock("apimutex:0:9"))
+	select {
+	case ok := <-result:
+		test.IsEqualBool(t, ok, true)
+	case <-time.After(5 * time.Second):
+		t.Fatal("code was not verified after the lock was released")
+	}
+	// The code must not be accepted a second time
+	test.IsEqualBool(t, Verify(9, code), false)
+}
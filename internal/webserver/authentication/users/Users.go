@@ -16,6 +16,9 @@ var ErrorNameToShort = errors.New("username too short")
 // ErrorUserExists is returned when the user already exists
 var ErrorUserExists = errors.New("user already exists")
 
+// ErrorInvalidRank is returned when a user cannot be assigned the requested rank
+var ErrorInvalidRank = errors.New("invalid rank")
+
 // Create creates a new user and returns an error if the user already exists or the username is too short
 func Create(name string) (models.User, error) {
 	if len(name) < minLengthUser {
@@ -39,3 +42,56 @@ func Create(name string) (models.User, error) {
 	}
 	return newUser, nil
 }
+
+// SetRank changes the rank of the user and saves it. The permissions of the user are reset to the default
+// permissions of the rank and API permissions that are no longer granted are removed from the user's API keys
+func SetRank(user models.User, rank models.UserRank) (models.User, error) {
+	switch rank {
+	case models.UserLevelAdmin:
+		user.Permissions = models.UserPermissionAll
+	case models.UserLevelUser:
+		user.Permissions = models.UserPermissionNone
+		UpdateApiKeyPermsOnUserPermChange(user.Id, models.UserPermReplaceUploads)
+		UpdateApiKeyPermsOnUserPermChange(user.Id, models.UserPermManageUsers)
+		UpdateApiKeyPermsOnUserPermChange(user.Id, models.UserPermManageLogs)
+		UpdateApiKeyPermsOnUserPermChange(user.Id, models.UserPermGuestUploads)
+		RemoveApiKeyPermission(user.Id, models.ApiPermManageWebhooks)
+	default:
+		return models.User{}, ErrorInvalidRank
+	}
+	user.UserLevel = rank
+	database.SaveUser(user, false)
+	return user, nil
+}
+
+// UpdateApiKeyPermsOnUserPermChange removes the API permission that depends on the user permission
+// from all API keys of the user
+func UpdateApiKeyPermsOnUserPermChange(userId int, userPerm models.UserPermission) {
+	var affectedPermission models.ApiPermission
+	switch userPerm {
+	case models.UserPermManageUsers:
+		affectedPermission = models.ApiPermManageUsers
+	case models.UserPermReplaceUploads:
+		affectedPermission = models.ApiPermReplace
+	case models.UserPermManageLogs:
+		affectedPermission = models.ApiPermManageLogs
+	case models.UserPermGuestUploads:
+		affectedPermission = models.ApiPermManageFileRequests
+	default:
+		return
+	}
+	RemoveApiKeyPermission(userId, affectedPermission)
+}
+
+// RemoveApiKeyPermission removes the API permission from all API keys of the user
+func RemoveApiKeyPermission(userId int, permission models.ApiPermission) {
+	for _, apiKey := range database.GetAllApiKeys() {
+		if apiKey.UserId != userId {
+			continue
+		}
+		if apiKey.HasPermission(permission) {
+			apiKey.RemovePermission(permission)
+			database.SaveApiKey(apiKey)
+		}
+	}
+}
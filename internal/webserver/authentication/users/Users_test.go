@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
@@ -43,3 +44,38 @@ func TestCreate(t *testing.T) {
 		test.IsEqualBool(t, errors.Is(err, ErrorUserExists), true)
 	})
 }
+
+func TestSetRank(t *testing.T) {
+	testconfiguration.Create(false)
+	configuration.Load()
+	configuration.ConnectDatabase()
+	defer testconfiguration.Delete()
+
+	user, err := Create("rankuser")
+	test.IsNil(t, err)
+	database.SaveApiKey(models.ApiKey{
+		Id:          "rankuserkey",
+		Permissions: models.ApiPermView | models.ApiPermManageLogs | models.ApiPermManageWebhooks,
+		UserId:      user.Id,
+	})
+
+	user, err = SetRank(user, models.UserLevelAdmin)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, user.IsAdmin(), true)
+	test.IsEqualBool(t, user.HasPermission(models.UserPermManageLogs), true)
+
+	user, err = SetRank(user, models.UserLevelUser)
+	test.IsNil(t, err)
+	storedUser, ok := database.GetUser(user.Id)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, storedUser.IsAdmin(), false)
+	test.IsEqualBool(t, storedUser.Permissions == models.UserPermissionNone, true)
+	key, ok := database.GetApiKey("rankuserkey")
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualBool(t, key.HasPermission(models.ApiPermView), true)
+	test.IsEqualBool(t, key.HasPermission(models.ApiPermManageLogs), false)
+	test.IsEqualBool(t, key.HasPermission(models.ApiPermManageWebhooks), false)
+
+	_, err = SetRank(user, models.UserLevelSuperAdmin)
+	test.IsEqualBool(t, errors.Is(err, ErrorInvalidRank), true)
+}
@@ -1,18 +1,35 @@
 package downloadstatus
 
 import (
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
 	"github.com/forceu/gokapi/internal/helper"
 	"github.com/forceu/gokapi/internal/models"
 	"sync"
 	"time"
 )
 
+const statusLifetime = 24 * time.Hour
+
+// Shared state stores the status ids per file in a group and the file id per status id as a key
+const sharedGroupPrefix = "dlstatus:"
+const sharedKeyPrefix = "dlstatusid:"
+
 var statusMap = make(map[string]models.DownloadStatus)
 var statusMutex sync.RWMutex
 
 // SetDownload creates a new DownloadStatus struct and returns its Id
 func SetDownload(file models.File) string {
 	newStatus := newDownloadStatus(file)
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Get().SetGroupEntry(sharedGroupPrefix+file.Id, newStatus.Id, []byte(newStatus.Id), statusLifetime)
+		if err == nil {
+			err = sharedstate.Get().Set(sharedKeyPrefix+newStatus.Id, []byte(file.Id), statusLifetime)
+		}
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return newStatus.Id
+	}
 	statusMutex.Lock()
 	statusMap[newStatus.Id] = newStatus
 	statusMutex.Unlock()
@@ -21,13 +38,29 @@ func SetDownload(file models.File) string {
 
 // SetComplete removes the download object
 func SetComplete(downloadStatusId string) {
+	if sharedstate.IsEnabled() {
+		fileId, ok, err := sharedstate.Get().Get(sharedKeyPrefix + downloadStatusId)
+		if err == nil && ok {
+			err = sharedstate.Get().DeleteGroupEntry(sharedGroupPrefix+string(fileId), downloadStatusId)
+			if err == nil {
+				err = sharedstate.Get().Delete(sharedKeyPrefix + downloadStatusId)
+			}
+		}
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return
+	}
 	statusMutex.Lock()
 	delete(statusMap, downloadStatusId)
 	statusMutex.Unlock()
 }
 
-// Clean removes all expires status objects
+// Clean removes all expires status objects. Status objects in the shared state expire automatically
 func Clean() {
+	if sharedstate.IsEnabled() {
+		return
+	}
 	now := time.Now().Unix()
 	for _, item := range statusMap {
 		if item.ExpireAt < now {
@@ -41,13 +74,22 @@ func newDownloadStatus(file models.File) models.DownloadStatus {
 	s := models.DownloadStatus{
 		Id:       helper.GenerateRandomString(30),
 		FileId:   file.Id,
-		ExpireAt: time.Now().Add(24 * time.Hour).Unix(),
+		ExpireAt: time.Now().Add(statusLifetime).Unix(),
 	}
 	return s
 }
 
 // IsCurrentlyDownloading returns true if file is currently being downloaded
 func IsCurrentlyDownloading(file models.File) bool {
+	if sharedstate.IsEnabled() {
+		entries, err := sharedstate.Get().GetGroupEntries(sharedGroupPrefix + file.Id)
+		if err != nil {
+			// Assume that the file is being downloaded, so that it is not deleted during a download
+			sharedstate.PrintError(err)
+			return true
+		}
+		return len(entries) > 0
+	}
 	isDownloading := false
 	statusMutex.RLock()
 	for _, status := range statusMap {
@@ -64,6 +106,25 @@ func IsCurrentlyDownloading(file models.File) bool {
 
 // SetAllComplete removes all download status associated with this file
 func SetAllComplete(fileId string) {
+	if sharedstate.IsEnabled() {
+		entries, err := sharedstate.Get().GetGroupEntries(sharedGroupPrefix + fileId)
+		if err != nil {
+			sharedstate.PrintError(err)
+			return
+		}
+		for statusId := range entries {
+			err = sharedstate.Get().Delete(sharedKeyPrefix + statusId)
+			if err != nil {
+				sharedstate.PrintError(err)
+				return
+			}
+		}
+		err = sharedstate.Get().DeleteGroup(sharedGroupPrefix + fileId)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return
+	}
 	statusMutex.Lock()
 	for _, status := range statusMap {
 		if status.FileId == fileId {
@@ -73,7 +134,7 @@ func SetAllComplete(fileId string) {
 	statusMutex.Unlock()
 }
 
-// DeleteAll removes all download status
+// DeleteAll removes all download status of this instance
 func DeleteAll() {
 	statusMutex.Lock()
 	statusMap = make(map[string]models.DownloadStatus)
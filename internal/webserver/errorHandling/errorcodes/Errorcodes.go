@@ -41,4 +41,8 @@ const (
 	UnsupportedFile
 	// ResourceCanNotBeEdited is returned when a resource cannot be edited
 	ResourceCanNotBeEdited
+	// QuotaExceeded is returned when an action would exceed the quota of the user
+	QuotaExceeded
+	// NotEnabled is returned when a feature has not been configured on the server
+	NotEnabled
 )
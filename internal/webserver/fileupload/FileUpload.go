@@ -10,6 +10,7 @@ import (
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/configuration/database"
 	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/logging/metrics"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage"
 	"github.com/forceu/gokapi/internal/storage/chunking"
@@ -39,13 +40,15 @@ func ProcessCompleteFile(w http.ResponseWriter, r *http.Request, userId, maxMemo
 	}
 
 	config.FileRequestId = ""
+	startTime := time.Now()
 	result, err := storage.NewFile(file, header, userId, config)
+	metrics.ObserveUploadProcessing(time.Since(startTime))
 	defer file.Close()
 	if err != nil {
 		return err
 	}
 	user, _ := database.GetUser(userId)
-	logging.LogUpload(result, user, models.FileRequest{})
+	logging.LogUpload(result, user, models.FileRequest{}, r)
 	_, _ = io.WriteString(w, result.ToJsonResult(config.ExternalUrl, configuration.Get().IncludeFilename))
 	return nil
 }
@@ -93,7 +96,11 @@ func ProcessNewChunk(w http.ResponseWriter, r *http.Request, isApiCall bool, fil
 		}
 	}
 
-	err = chunking.NewChunk(file, header, chunkInfo, maxFileSize)
+	if storage.IsStreamUploadEnabled() {
+		err = storage.NewStreamedChunk(file, header, chunkInfo, maxFileSize)
+	} else {
+		err = chunking.NewChunk(file, header, chunkInfo, maxFileSize)
+	}
 	defer file.Close()
 	if err != nil {
 		return errorcodes.CannotAllocateFile, err
@@ -125,6 +132,8 @@ func ParseFileHeader(r *http.Request) (string, chunking.FileHeader, models.Uploa
 // CompleteChunk processes a file after all the chunks have been completed
 // The parameters can be generated with  ParseFileHeader()
 func CompleteChunk(chunkId string, header chunking.FileHeader, userId int, config models.UploadParameters) (models.File, error) {
+	startTime := time.Now()
+	defer func() { metrics.ObserveUploadProcessing(time.Since(startTime)) }()
 	return storage.NewFileFromChunk(chunkId, header, userId, config)
 }
 
@@ -146,6 +155,30 @@ func CreateUploadConfig(allowedDownloads, expiryDays int, password string, unlim
 	}
 }
 
+// SetPublishDate sets the time from which on the upload can be downloaded. If expiryFromPublish is true,
+// the days until the expiry are counted from that time instead of the time of the upload
+func SetPublishDate(config *models.UploadParameters, publishAt int64, expiryFromPublish bool) error {
+	if publishAt < 0 {
+		return errors.New("invalid publication time")
+	}
+	config.PublishAt = publishAt
+	if expiryFromPublish && publishAt > time.Now().Unix() {
+		config.ExpiryTimestamp = time.Unix(publishAt, 0).Add(time.Duration(config.Expiry) * time.Hour * 24).Unix()
+	}
+	if !config.UnlimitedTime && config.PublishAt >= config.ExpiryTimestamp {
+		return storage.ErrorPublishAfterExpiry
+	}
+	return nil
+}
+
+// SetAccessRestriction limits the download of the upload to authenticated users. If users or groups are
+// passed as comma-separated lists, only these users or members of these groups can download the file
+func SetAccessRestriction(config *models.UploadParameters, requireLogin bool, users, groups string) {
+	config.RequireLogin = requireLogin
+	config.AllowedUsers = models.CleanAccessList(users)
+	config.AllowedGroups = models.CleanAccessList(groups)
+}
+
 func parseConfig(values formOrHeader) (models.UploadParameters, error) {
 	fileRequestId := values.Get("fileRequestId")
 	if fileRequestId != "" {
@@ -184,7 +217,20 @@ func parseConfig(values formOrHeader) (models.UploadParameters, error) {
 			return models.UploadParameters{}, err
 		}
 	}
-	return CreateUploadConfig(allowedDownloadsInt, expiryDaysInt, password, unlimitedTime, unlimitedDownload, isEnd2End, realSize, ""), nil
+	config := CreateUploadConfig(allowedDownloadsInt, expiryDaysInt, password, unlimitedTime, unlimitedDownload, isEnd2End, realSize, "")
+	publishAt := values.Get("publishAt")
+	if publishAt != "" {
+		publishAtInt, err := strconv.ParseInt(publishAt, 10, 64)
+		if err != nil {
+			return models.UploadParameters{}, err
+		}
+		err = SetPublishDate(&config, publishAtInt, values.Get("expiryFromPublish") == "true")
+		if err != nil {
+			return models.UploadParameters{}, err
+		}
+	}
+	SetAccessRestriction(&config, values.Get("requireLogin") == "true", values.Get("allowedUsers"), values.Get("allowedGroups"))
+	return config, nil
 }
 
 type formOrHeader interface {
@@ -10,11 +10,14 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/forceu/gokapi/internal/configuration"
 	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
 )
@@ -71,6 +74,49 @@ func TestParseConfig(t *testing.T) {
 	test.IsNil(t, err)
 	test.IsEqualBool(t, config.IsEndToEndEncrypted, true)
 	test.IsEqualInt64(t, config.RealSize, 200)
+
+	publishAt := time.Now().Add(48 * time.Hour).Unix()
+	data = testData{expiryDays: "1", publishAt: strconv.FormatInt(publishAt, 10)}
+	_, err = parseConfig(data)
+	test.IsEqual(t, err, storage.ErrorPublishAfterExpiry)
+	data.expiryFromPublish = "true"
+	config, err = parseConfig(data)
+	test.IsNil(t, err)
+	test.IsEqualInt64(t, config.PublishAt, publishAt)
+	test.IsEqualInt64(t, config.ExpiryTimestamp, publishAt+24*60*60)
+	data.publishAt = "invalid"
+	_, err = parseConfig(data)
+	test.IsNotNil(t, err)
+}
+
+func TestSetPublishDate(t *testing.T) {
+	publishAt := time.Now().Add(time.Hour).Unix()
+	config := CreateUploadConfig(1, 2, "", false, false, false, 0, "")
+	expiry := config.ExpiryTimestamp
+	test.IsNil(t, SetPublishDate(&config, publishAt, false))
+	test.IsEqualInt64(t, config.PublishAt, publishAt)
+	test.IsEqualInt64(t, config.ExpiryTimestamp, expiry)
+
+	config = CreateUploadConfig(1, 2, "", false, false, false, 0, "")
+	test.IsNil(t, SetPublishDate(&config, publishAt, true))
+	test.IsEqualInt64(t, config.ExpiryTimestamp, publishAt+2*24*60*60)
+
+	config = CreateUploadConfig(1, 0, "", true, false, false, 0, "")
+	test.IsNil(t, SetPublishDate(&config, time.Now().Add(1000*time.Hour).Unix(), false))
+	test.IsNotNil(t, SetPublishDate(&config, -1, false))
+}
+
+func TestSetAccessRestriction(t *testing.T) {
+	config, err := parseConfig(testData{requireLogin: "true", allowedUsers: " alice,,bob ", allowedGroups: "staff"})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, config.RequireLogin, true)
+	test.IsEqualString(t, config.AllowedUsers, "alice,bob")
+	test.IsEqualString(t, config.AllowedGroups, "staff")
+
+	config, err = parseConfig(testData{})
+	test.IsNil(t, err)
+	test.IsEqualBool(t, config.RequireLogin, false)
+	test.IsEqualString(t, config.AllowedUsers, "")
 }
 
 func TestProcess(t *testing.T) {
@@ -188,7 +234,8 @@ func getFileUploadRecorder(addChunkInfo bool) *http.Request {
 }
 
 type testData struct {
-	allowedDownloads, expiryDays, password, isE2E, realSize string
+	allowedDownloads, expiryDays, password, isE2E, realSize, publishAt, expiryFromPublish string
+	requireLogin, allowedUsers, allowedGroups                                             string
 }
 
 func (t testData) Get(key string) string {
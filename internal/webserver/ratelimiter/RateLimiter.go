@@ -7,7 +7,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
 	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/logging/metrics"
 	"golang.org/x/time/rate"
 )
 
@@ -48,31 +50,61 @@ func newLimiter() *store {
 // WaitOnLogin blocks the current goroutine until the rate limiter allows a request
 // Three attempts without limiting, thereafter one attempt every 3 seconds
 func WaitOnLogin(ip string) {
-	_ = failedLoginLimiter.Get(ip, 1, 9).WaitN(context.Background(), 3)
+	wait(failedLoginLimiter, ip, 1, 9, 3, "login")
 }
 
 // WaitOnApiAuthentication blocks the current goroutine until the rate limiter allows a request
 // 200 attempts without limiting, thereafter one attempt every second
 func WaitOnApiAuthentication(ip string) {
-	_ = failedApiKeyLimiter.Get(ip, 1, 200).WaitN(context.Background(), 1)
+	wait(failedApiKeyLimiter, ip, 1, 200, 1, "api_key")
 }
 
 // WaitOnDownloadPassword blocks the current goroutine until the rate limiter allows a request
 // Ten attempts without limiting, thereafter one attempt every 2 seconds
 func WaitOnDownloadPassword(ip string) {
-	_ = failedDownloadPasswordLimiter.Get(ip, 1, 20).WaitN(context.Background(), 2)
+	wait(failedDownloadPasswordLimiter, ip, 1, 20, 2, "download_password")
 }
 
 // WaitOnFailedId blocks the current goroutine until the rate limiter allows a request
 // Ten failed attempts without limiting, thereafter one attempt every second
 func WaitOnFailedId(r *http.Request) {
 	ip := logging.GetIpAddress(r)
-	_ = failedIdLimiter.Get(ip, 1, 10).Wait(context.Background())
+	wait(failedIdLimiter, ip, 1, 10, 1, "invalid_id")
+}
+
+// wait blocks until the limiter for the key allows n events. If the request has to be delayed,
+// the wait is counted in the metrics with the name of the limiter. If state is shared with other
+// instances, the limit applies to the requests of all instances. If the shared state cannot be reached,
+// only the limiter of this instance is used
+func wait(s *store, key string, r rate.Limit, burst, n int, name string) {
+	if sharedstate.IsEnabled() && !isUnitTest {
+		delay, _, err := sharedstate.Get().ReserveTokens(name+":"+key, float64(r), burst, n, true)
+		if err == nil {
+			if delay > 0 {
+				metrics.IncreaseRateLimiterWaits(name)
+				time.Sleep(delay)
+			}
+			return
+		}
+		sharedstate.PrintError(err)
+	}
+	limiter := s.Get(key, r, burst)
+	if limiter.Tokens() < float64(n) {
+		metrics.IncreaseRateLimiterWaits(name)
+	}
+	_ = limiter.WaitN(context.Background(), n)
 }
 
 // IsAllowedNewUuid returns true if a new uuid is not rate-limited
 // Four initial requests are allowed without rate limiting, thereafter one every second
 func IsAllowedNewUuid(key string) bool {
+	if sharedstate.IsEnabled() && !isUnitTest {
+		_, ok, err := sharedstate.Get().ReserveTokens("new_uuid:"+key, 1, 4, 1, false)
+		if err == nil {
+			return ok
+		}
+		sharedstate.PrintError(err)
+	}
 	return newUuidLimiter.Get(key, 1, 4).Allow()
 }
 
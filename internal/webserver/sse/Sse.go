@@ -7,7 +7,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
 	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging/metrics"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/storage/processingstatus/pstatusdb"
 	"github.com/forceu/gokapi/internal/webserver/authentication"
@@ -16,6 +18,10 @@ import (
 var listeners = make(map[string]listener)
 var mutex = sync.RWMutex{}
 
+var subscribeOnce sync.Once
+
+const sharedChannel = "sse"
+
 var maxConnection = 2 * time.Hour
 var pingInterval = 15 * time.Second
 
@@ -25,16 +31,35 @@ type listener struct {
 	UserId   int
 }
 
+// sharedMessage is published to all instances, if state is shared between multiple instances
+type sharedMessage struct {
+	UserId  int
+	Message string
+}
+
 func addListener(id string, channel listener) {
+	if sharedstate.IsEnabled() {
+		subscribeOnce.Do(func() {
+			sharedstate.Get().Subscribe(sharedChannel, receiveSharedMessage)
+		})
+	}
 	mutex.Lock()
+	_, exists := listeners[id]
 	listeners[id] = channel
 	mutex.Unlock()
+	if !exists {
+		metrics.AddSseListener()
+	}
 }
 
 func removeListener(id string) {
 	mutex.Lock()
+	_, exists := listeners[id]
 	delete(listeners, id)
 	mutex.Unlock()
+	if exists {
+		metrics.RemoveSseListener()
+	}
 }
 
 type eventFileDownload struct {
@@ -72,10 +97,34 @@ func publishMessage[d eventData](data d, userId int) {
 	message, err := json.Marshal(data)
 	helper.Check(err)
 
+	if sharedstate.IsEnabled() {
+		shared, err := json.Marshal(sharedMessage{UserId: userId, Message: string(message)})
+		helper.Check(err)
+		err = sharedstate.Get().Publish(sharedChannel, shared)
+		if err == nil {
+			return
+		}
+		// At least the listeners of this instance receive the message
+		sharedstate.PrintError(err)
+	}
+	replyToListeners(string(message), userId)
+}
+
+// receiveSharedMessage delivers a message that was published by any instance to the listeners of this instance
+func receiveSharedMessage(shared []byte) {
+	var message sharedMessage
+	err := json.Unmarshal(shared, &message)
+	if err != nil {
+		return
+	}
+	replyToListeners(message.Message, message.UserId)
+}
+
+func replyToListeners(message string, userId int) {
 	mutex.RLock()
 	for _, channel := range listeners {
 		if channel.UserId == userId {
-			channel.Reply("event: message\ndata: " + string(message) + "\n\n")
+			channel.Reply("event: message\ndata: " + message + "\n\n")
 		}
 	}
 	mutex.RUnlock()
@@ -10,7 +10,10 @@ import (
 	"testing/synctest"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate/redisstate"
 	"github.com/forceu/gokapi/internal/models"
 	"github.com/forceu/gokapi/internal/test"
 	"github.com/forceu/gokapi/internal/test/testconfiguration"
@@ -320,3 +323,44 @@ func TestGetStatusSSE(t *testing.T) {
 		<-done
 	})
 }
+
+func TestPublishSharedState(t *testing.T) {
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	defer sharedstate.Close()
+	otherInstance, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	defer otherInstance.Close()
+
+	replyChannel := make(chan string, 64)
+	addListener("test_shared", listener{
+		Reply:    func(reply string) { replyChannel <- reply },
+		Shutdown: func() {},
+		UserId:   testUserId,
+	})
+	defer removeListener("test_shared")
+	time.Sleep(100 * time.Millisecond)
+
+	// A status published by another instance must be delivered to the listener of this instance
+	test.IsNil(t, otherInstance.Publish("sse", []byte(`{"UserId":7,"Message":"{\"event\":\"test\"}"}`)))
+	select {
+	case reply := <-replyChannel:
+		test.IsEqualString(t, reply, "event: message\ndata: {\"event\":\"test\"}\n\n")
+	case <-time.After(2 * time.Second):
+		t.Fatal("message from other instance was not delivered")
+	}
+
+	PublishNewStatus(models.UploadStatus{
+		ChunkId:       "sharedChunk",
+		CurrentStatus: 1,
+		UserId:        testUserId,
+	})
+	select {
+	case reply := <-replyChannel:
+		test.IsEqualString(t, reply, "event: message\ndata: {\"event\":\"uploadStatus\",\"chunk_id\":\"sharedChunk\",\"file_id\":\"\",\"error_message\":\"\",\"upload_status\":1}\n\n")
+	case <-time.After(2 * time.Second):
+		t.Fatal("published message was not delivered")
+	}
+}
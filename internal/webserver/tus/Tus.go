@@ -0,0 +1,662 @@
+package tus
+
+/**
+Implementation of the tus 1.0 resumable upload protocol (https://tus.io/protocols/resumable-upload)
+Supported extensions: creation, termination, checksum and expiration
+*/
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/helper"
+	"github.com/forceu/gokapi/internal/logging"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/webserver/fileupload"
+)
+
+// Version is the supported version of the tus protocol
+const Version = "1.0.0"
+
+// Extensions are the supported extensions of the tus protocol
+const Extensions = "creation,termination,checksum,expiration"
+
+// ChecksumAlgorithms are the supported algorithms for the checksum extension
+const ChecksumAlgorithms = "sha1,sha256,md5"
+
+// HeaderFileId contains the ID of the file, after the upload has been completed
+const HeaderFileId = "X-Gokapi-File-Id"
+
+// UrlPath is the path of the tus endpoint
+const UrlPath = "api/tus/"
+
+// statusChecksumMismatch is returned if the checksum of a PATCH request does not match
+const statusChecksumMismatch = 460
+
+// expiryTime is the time after the last activity, after which an incomplete upload is discarded
+const expiryTime = 23 * time.Hour
+
+// lengthUploadId is the length of the generated ID for a new upload
+const lengthUploadId = 32
+
+// If state is shared between multiple instances, the uploads of a user are stored in a group
+// and the lock for each upload marks that it is in use by a PATCH request
+const sharedGroupPrefix = "tus:"
+const sharedLockPrefix = "tus:"
+
+// sharedLockDuration is the time after which the lock of an upload is released automatically,
+// in case the instance holding it is terminated. It is renewed while the lock is held
+const sharedLockDuration = 30 * time.Second
+
+var uploads = make(map[string]upload)
+var mutex sync.Mutex
+var runGcOnce = sync.Once{}
+
+type upload struct {
+	Id         string
+	UserId     int
+	Length     int64
+	Offset     int64
+	Expiry     int64
+	FileId     string
+	IsBusy     bool
+	FileHeader chunking.FileHeader
+	Parameters models.UploadParameters
+}
+
+func (u upload) isComplete() bool {
+	return u.FileId != ""
+}
+
+// Process handles a request to the tus endpoint. The user has already been authenticated by the API
+func Process(w http.ResponseWriter, r *http.Request, user models.User) {
+	w.Header().Set("Tus-Resumable", Version)
+	method := r.Method
+	if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && method == http.MethodPost {
+		method = override
+	}
+	if method == http.MethodOptions {
+		w.Header().Set("Tus-Version", Version)
+		w.Header().Set("Tus-Extension", Extensions)
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(getMaxSize(), 10))
+		w.Header().Set("Tus-Checksum-Algorithm", ChecksumAlgorithms)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != Version {
+		w.Header().Set("Tus-Version", Version)
+		http.Error(w, "unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+	id := getUploadId(r)
+	if id == "" {
+		if method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		create(w, r, user)
+		return
+	}
+	switch method {
+	case http.MethodHead:
+		head(w, id, user)
+	case http.MethodPatch:
+		patch(w, r, id, user)
+	case http.MethodDelete:
+		terminate(w, id, user)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func getUploadId(r *http.Request) string {
+	path := r.URL.Path
+	index := strings.Index(path, "/tus/")
+	if index == -1 {
+		return ""
+	}
+	return strings.Trim(path[index+len("/tus/"):], "/")
+}
+
+func getMaxSize() int64 {
+	return int64(configuration.Get().MaxFileSizeMB) * 1024 * 1024
+}
+
+// create handles the creation extension
+func create(w http.ResponseWriter, r *http.Request, user models.User) {
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		http.Error(w, "Upload-Defer-Length is not supported", http.StatusBadRequest)
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if length > getMaxSize() {
+		http.Error(w, "upload limit exceeded", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fileHeader, params, err := getUploadParameters(metadata, length)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = checkQuota(user, length, params)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, storage.ErrorQuotaStorage) || errors.Is(err, storage.ErrorQuotaFiles) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	newUpload := upload{
+		Id:         helper.GenerateRandomString(lengthUploadId),
+		UserId:     user.Id,
+		Length:     length,
+		Expiry:     time.Now().Add(expiryTime).Unix(),
+		FileHeader: fileHeader,
+		Parameters: params,
+	}
+	info := chunking.ChunkInfo{TotalFilesizeBytes: length, UUID: newUpload.Id}
+	err = chunking.NewChunk(strings.NewReader(""), &multipart.FileHeader{}, info, getMaxSize())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if length == 0 {
+		newUpload, err = complete(newUpload, user, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(HeaderFileId, newUpload.FileId)
+	}
+	saveUpload(newUpload)
+	runGcOnce.Do(func() { go cleanUp(true) })
+
+	w.Header().Set("Location", configuration.Get().ServerUrl+UrlPath+newUpload.Id)
+	w.Header().Set("Upload-Expires", formatExpiry(newUpload.Expiry))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// checkQuota returns an error, if the quota of the user does not allow to store the upload. Incomplete uploads
+// of the user are counted as well, so that the quota cannot be exceeded by creating multiple uploads at once
+func checkQuota(user models.User, length int64, params models.UploadParameters) error {
+	pendingFiles := 0
+	pendingBytes := int64(0)
+	for _, pendingUpload := range getUploadsOfUser(user.Id) {
+		if !pendingUpload.isComplete() && pendingUpload.Expiry > time.Now().Unix() {
+			pendingFiles++
+			pendingBytes = pendingBytes + pendingUpload.Length
+		}
+	}
+	err := storage.CheckQuota(user.Id, length+pendingBytes, pendingFiles)
+	if err != nil {
+		return err
+	}
+	return storage.CheckExpiryQuota(user.Id, params.ExpiryTimestamp, params.UnlimitedTime, params.AllowedDownloads, params.UnlimitedDownload)
+}
+
+// parseMetadata parses the Upload-Metadata header, which contains comma separated key value
+// pairs. The key and value are separated by a space and the value is base64 encoded
+func parseMetadata(header string) (map[string]string, error) {
+	result := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return result, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.New("invalid value for key " + key + " in Upload-Metadata")
+		}
+		result[key] = string(decoded)
+	}
+	return result, nil
+}
+
+// getUploadParameters converts the metadata to upload parameters. The keys are the same as
+// the parameters for /chunk/complete. For compatibility with common tus clients, the keys
+// name and type are also accepted for the filename and content type
+func getUploadParameters(metadata map[string]string, length int64) (chunking.FileHeader, models.UploadParameters, error) {
+	filename := getFirstValue(metadata, "filename", "name")
+	if filename == "" {
+		return chunking.FileHeader{}, models.UploadParameters{}, errors.New("filename is required in Upload-Metadata")
+	}
+	contentType := getFirstValue(metadata, "contenttype", "filetype", "type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	allowedDownloads := 1
+	unlimitedDownloads := false
+	value, ok := metadata["allowedDownloads"]
+	if ok {
+		var err error
+		allowedDownloads, err = strconv.Atoi(value)
+		if err != nil || allowedDownloads < 0 {
+			return chunking.FileHeader{}, models.UploadParameters{}, errors.New("invalid value for allowedDownloads")
+		}
+		unlimitedDownloads = allowedDownloads == 0
+	}
+
+	expiryDays := 14
+	unlimitedTime := false
+	value, ok = metadata["expiryDays"]
+	if ok {
+		var err error
+		expiryDays, err = strconv.Atoi(value)
+		if err != nil || expiryDays < 0 {
+			return chunking.FileHeader{}, models.UploadParameters{}, errors.New("invalid value for expiryDays")
+		}
+		unlimitedTime = expiryDays == 0 || expiryDays > 100000
+	}
+
+	fileHeader := chunking.FileHeader{
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        length,
+	}
+	params := fileupload.CreateUploadConfig(allowedDownloads, expiryDays, metadata["password"],
+		unlimitedTime, unlimitedDownloads, false, length, "")
+	return fileHeader, params, nil
+}
+
+func getFirstValue(metadata map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if metadata[key] != "" {
+			return metadata[key]
+		}
+	}
+	return ""
+}
+
+// getUpload returns the upload with the given ID, if it exists, has not expired and belongs to the user
+func getUpload(id string, user models.User) (upload, bool) {
+	result, ok := getStoredUpload(id, user.Id)
+	if !ok || result.Expiry < time.Now().Unix() {
+		return upload{}, false
+	}
+	return result, true
+}
+
+// getStoredUpload returns the upload with the given ID, if it exists and belongs to the user
+func getStoredUpload(id string, userId int) (upload, bool) {
+	if sharedstate.IsEnabled() {
+		result, ok := getSharedUploads(userId)[id]
+		return result, ok
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	result, ok := uploads[id]
+	if !ok || result.UserId != userId {
+		return upload{}, false
+	}
+	return result, true
+}
+
+// getUploadsOfUser returns all uploads of the user, including expired ones
+func getUploadsOfUser(userId int) []upload {
+	result := make([]upload, 0)
+	if sharedstate.IsEnabled() {
+		for _, entry := range getSharedUploads(userId) {
+			result = append(result, entry)
+		}
+		return result
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, entry := range uploads {
+		if entry.UserId == userId {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// saveUpload stores the upload. If state is shared with other instances, the upload is stored
+// in the shared state, so that the client can continue the upload on any instance
+func saveUpload(u upload) {
+	if sharedstate.IsEnabled() {
+		value, err := json.Marshal(u)
+		helper.Check(err)
+		err = sharedstate.Get().SetGroupEntry(getSharedGroupName(u.UserId), u.Id, value, time.Until(time.Unix(u.Expiry, 0)))
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return
+	}
+	mutex.Lock()
+	uploads[u.Id] = u
+	mutex.Unlock()
+}
+
+func getSharedUploads(userId int) map[string]upload {
+	result := make(map[string]upload)
+	entries, err := sharedstate.Get().GetGroupEntries(getSharedGroupName(userId))
+	if err != nil {
+		sharedstate.PrintError(err)
+		return result
+	}
+	for id, value := range entries {
+		var entry upload
+		err = json.Unmarshal(value, &entry)
+		if err != nil {
+			fmt.Println("Could not read tus upload " + id + " from shared state: " + err.Error())
+			continue
+		}
+		result[id] = entry
+	}
+	return result
+}
+
+func getSharedGroupName(userId int) string {
+	return sharedGroupPrefix + strconv.Itoa(userId)
+}
+
+// head returns the current offset of the upload
+func head(w http.ResponseWriter, id string, user models.User) {
+	currentUpload, ok := getUpload(id, user)
+	w.Header().Set("Cache-Control", "no-store")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(currentUpload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(currentUpload.Length, 10))
+	w.Header().Set("Upload-Expires", formatExpiry(currentUpload.Expiry))
+	if currentUpload.isComplete() {
+		w.Header().Set(HeaderFileId, currentUpload.FileId)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// lock marks the upload as busy, so that only one PATCH request can write to it at the same time
+func lock(id string, user models.User) (upload, int, string) {
+	if sharedstate.IsEnabled() {
+		return lockShared(id, user)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	result, ok := uploads[id]
+	if !ok || result.UserId != user.Id || result.Expiry < time.Now().Unix() {
+		return upload{}, http.StatusNotFound, "upload not found"
+	}
+	if result.IsBusy {
+		return upload{}, http.StatusConflict, "upload is currently in use by another request"
+	}
+	result.IsBusy = true
+	uploads[id] = result
+	return result, 0, ""
+}
+
+// lockShared acquires the lock of the upload that is shared with other instances
+func lockShared(id string, user models.User) (upload, int, string) {
+	isAcquired, err := sharedstate.TryLock(sharedLockPrefix+id, sharedLockDuration)
+	if err != nil {
+		sharedstate.PrintError(err)
+		return upload{}, http.StatusServiceUnavailable, "upload is currently not available"
+	}
+	if !isAcquired {
+		return upload{}, http.StatusConflict, "upload is currently in use by another request"
+	}
+	result, ok := getUpload(id, user)
+	if !ok {
+		unlockShared(id)
+		return upload{}, http.StatusNotFound, "upload not found"
+	}
+	return result, 0, ""
+}
+
+func unlockShared(id string) {
+	err := sharedstate.Unlock(sharedLockPrefix + id)
+	if err != nil {
+		sharedstate.PrintError(err)
+	}
+}
+
+func unlock(u upload) {
+	if sharedstate.IsEnabled() {
+		if _, ok := getStoredUpload(u.Id, u.UserId); ok {
+			saveUpload(u)
+		}
+		unlockShared(u.Id)
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if _, ok := uploads[u.Id]; !ok {
+		return
+	}
+	u.IsBusy = false
+	uploads[u.Id] = u
+}
+
+// patch writes the content of the request at the given offset
+func patch(w http.ResponseWriter, r *http.Request, id string, user models.User) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "invalid Content-Type", http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+	checksum, err := parseChecksum(r.Header.Get("Upload-Checksum"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	currentUpload, status, errMessage := lock(id, user)
+	if status != 0 {
+		http.Error(w, errMessage, status)
+		return
+	}
+	defer func() { unlock(currentUpload) }()
+
+	if currentUpload.isComplete() || offset != currentUpload.Offset {
+		http.Error(w, "Upload-Offset does not match the current offset", http.StatusConflict)
+		return
+	}
+	if r.ContentLength < 0 {
+		http.Error(w, "Content-Length is required", http.StatusLengthRequired)
+		return
+	}
+	if offset+r.ContentLength > currentUpload.Length {
+		http.Error(w, "upload would exceed the declared Upload-Length", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := &countingReader{r: r.Body, hash: checksum.Hash}
+	info := chunking.ChunkInfo{
+		TotalFilesizeBytes: currentUpload.Length,
+		Offset:             offset,
+		UUID:               currentUpload.Id,
+	}
+	err = chunking.NewChunk(body, &multipart.FileHeader{Size: r.ContentLength}, info, getMaxSize())
+	if checksum.Hash != nil {
+		// With a checksum, the data must only be accepted if it is complete and correct.
+		// Written data is overwritten by the next PATCH request, as the offset is not increased
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !checksum.isMatching() {
+			http.Error(w, "checksum mismatch", statusChecksumMismatch)
+			return
+		}
+	}
+	if err != nil && !body.isReadError {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// If the connection was interrupted, all data that has been received is kept, so the client can resume the upload
+	currentUpload.Offset = currentUpload.Offset + body.count
+	currentUpload.Expiry = time.Now().Add(expiryTime).Unix()
+	if err != nil {
+		return
+	}
+	if currentUpload.Offset == currentUpload.Length {
+		currentUpload, err = complete(currentUpload, user, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(HeaderFileId, currentUpload.FileId)
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(currentUpload.Offset, 10))
+	w.Header().Set("Upload-Expires", formatExpiry(currentUpload.Expiry))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// complete creates a new file from the uploaded data
+func complete(u upload, user models.User, r *http.Request) (upload, error) {
+	file, err := fileupload.CompleteChunk(u.Id, u.FileHeader, user.Id, u.Parameters)
+	if err != nil {
+		_ = chunking.DeleteChunk(u.Id)
+		removeUpload(u)
+		return u, err
+	}
+	logging.LogUpload(file, user, models.FileRequest{}, r)
+	u.FileId = file.Id
+	return u, nil
+}
+
+// terminate handles the termination extension
+func terminate(w http.ResponseWriter, id string, user models.User) {
+	currentUpload, status, errMessage := lock(id, user)
+	if status != 0 {
+		http.Error(w, errMessage, status)
+		return
+	}
+	if !currentUpload.isComplete() {
+		_ = chunking.DeleteChunk(currentUpload.Id)
+	}
+	removeUpload(currentUpload)
+	if sharedstate.IsEnabled() {
+		unlockShared(currentUpload.Id)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func removeUpload(u upload) {
+	if sharedstate.IsEnabled() {
+		err := sharedstate.Get().DeleteGroupEntry(getSharedGroupName(u.UserId), u.Id)
+		if err != nil {
+			sharedstate.PrintError(err)
+		}
+		return
+	}
+	mutex.Lock()
+	delete(uploads, u.Id)
+	mutex.Unlock()
+}
+
+func formatExpiry(expiry int64) string {
+	return time.Unix(expiry, 0).UTC().Format(http.TimeFormat)
+}
+
+type checksumInfo struct {
+	Hash     hash.Hash
+	Expected []byte
+}
+
+func (c checksumInfo) isMatching() bool {
+	return string(c.Hash.Sum(nil)) == string(c.Expected)
+}
+
+// parseChecksum parses the Upload-Checksum header, which has the format "<algorithm> <base64 encoded checksum>"
+func parseChecksum(header string) (checksumInfo, error) {
+	if header == "" {
+		return checksumInfo{}, nil
+	}
+	algorithm, value, ok := strings.Cut(header, " ")
+	if !ok {
+		return checksumInfo{}, errors.New("invalid Upload-Checksum")
+	}
+	expected, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return checksumInfo{}, errors.New("invalid Upload-Checksum")
+	}
+	switch algorithm {
+	case "sha1":
+		return checksumInfo{Hash: sha1.New(), Expected: expected}, nil
+	case "sha256":
+		return checksumInfo{Hash: sha256.New(), Expected: expected}, nil
+	case "md5":
+		return checksumInfo{Hash: md5.New(), Expected: expected}, nil
+	default:
+		return checksumInfo{}, errors.New("unsupported checksum algorithm")
+	}
+}
+
+// countingReader counts the bytes that have been read and optionally calculates a checksum
+type countingReader struct {
+	r           io.Reader
+	hash        hash.Hash
+	count       int64
+	isReadError bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.count = c.count + int64(n)
+	if c.hash != nil {
+		c.hash.Write(p[:n])
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.isReadError = true
+	}
+	return n, err
+}
+
+// cleanUp removes expired uploads and their data. Uploads in the shared state expire automatically
+// and their data is removed together with other old temporary files
+func cleanUp(isPeriodic bool) {
+	now := time.Now().Unix()
+	mutex.Lock()
+	for id, entry := range uploads {
+		if entry.Expiry < now && !entry.IsBusy {
+			if !entry.isComplete() {
+				_ = chunking.DeleteChunk(id)
+			}
+			delete(uploads, id)
+		}
+	}
+	mutex.Unlock()
+
+	if isPeriodic {
+		go func() {
+			time.Sleep(time.Minute * 5)
+			cleanUp(true)
+		}()
+	}
+}
@@ -0,0 +1,385 @@
+package tus
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/forceu/gokapi/internal/configuration"
+	"github.com/forceu/gokapi/internal/configuration/database"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate"
+	"github.com/forceu/gokapi/internal/configuration/sharedstate/redisstate"
+	"github.com/forceu/gokapi/internal/models"
+	"github.com/forceu/gokapi/internal/storage"
+	"github.com/forceu/gokapi/internal/storage/chunking"
+	"github.com/forceu/gokapi/internal/test"
+	"github.com/forceu/gokapi/internal/test/testconfiguration"
+)
+
+var testUser = models.User{Id: 7, Name: "TusUser", UserLevel: models.UserLevelUser}
+
+func TestMain(m *testing.M) {
+	testconfiguration.Create(false)
+	configuration.Load()
+	configuration.ConnectDatabase()
+	runGcOnce.Do(func() {
+		// prevent the periodic cleanup from running during tests
+	})
+	exitVal := m.Run()
+	testconfiguration.Delete()
+	os.Exit(exitVal)
+}
+
+func newRequest(method, url string, body io.Reader, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(method, url, body)
+	r.Header.Set("Tus-Resumable", Version)
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+	return r
+}
+
+func encodeMetadata(pairs ...string) string {
+	var result []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		result = append(result, pairs[i]+" "+base64.StdEncoding.EncodeToString([]byte(pairs[i+1])))
+	}
+	return strings.Join(result, ",")
+}
+
+func createUpload(t *testing.T, length string, metadata string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := newRequest(http.MethodPost, "/api/tus/", nil, map[string]string{"Upload-Length": length, "Upload-Metadata": metadata})
+	Process(w, r, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusCreated)
+	location := w.Header().Get("Location")
+	test.IsEqualBool(t, strings.HasPrefix(location, configuration.Get().ServerUrl+UrlPath), true)
+	test.IsNotEqualString(t, w.Header().Get("Upload-Expires"), "")
+	return strings.TrimPrefix(location, configuration.Get().ServerUrl+UrlPath)
+}
+
+func patchUpload(id string, offset string, content string, headers map[string]string) *httptest.ResponseRecorder {
+	allHeaders := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": offset}
+	for key, value := range headers {
+		allHeaders[key] = value
+	}
+	w := httptest.NewRecorder()
+	r := newRequest(http.MethodPatch, "/api/tus/"+id, strings.NewReader(content), allHeaders)
+	Process(w, r, testUser)
+	return w
+}
+
+func headUpload(id string, user models.User) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := newRequest(http.MethodHead, "/api/tus/"+id, nil, nil)
+	Process(w, r, user)
+	return w
+}
+
+func TestOptions(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodOptions, "/api/tus/", nil)
+	Process(w, r, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualString(t, w.Header().Get("Tus-Version"), Version)
+	test.IsEqualString(t, w.Header().Get("Tus-Extension"), Extensions)
+	test.IsEqualString(t, w.Header().Get("Tus-Checksum-Algorithm"), ChecksumAlgorithms)
+	test.IsEqualString(t, w.Header().Get("Tus-Max-Size"), "26214400")
+}
+
+func TestInvalidRequests(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/tus/", nil)
+	Process(w, r, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusPreconditionFailed)
+
+	w = httptest.NewRecorder()
+	r = newRequest(http.MethodGet, "/api/tus/", nil, nil)
+	Process(w, r, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusMethodNotAllowed)
+
+	w = httptest.NewRecorder()
+	r = newRequest(http.MethodGet, "/api/tus/invalid", nil, nil)
+	Process(w, r, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusMethodNotAllowed)
+
+	invalidCreations := []map[string]string{
+		{"Upload-Metadata": encodeMetadata("filename", "test")},
+		{"Upload-Length": "-1", "Upload-Metadata": encodeMetadata("filename", "test")},
+		{"Upload-Defer-Length": "1", "Upload-Metadata": encodeMetadata("filename", "test")},
+		{"Upload-Length": "10"},
+		{"Upload-Length": "10", "Upload-Metadata": "filename invalid!"},
+		{"Upload-Length": "10", "Upload-Metadata": encodeMetadata("filename", "test", "expiryDays", "abc")},
+		{"Upload-Length": "10", "Upload-Metadata": encodeMetadata("filename", "test", "allowedDownloads", "-2")},
+	}
+	for _, headers := range invalidCreations {
+		w = httptest.NewRecorder()
+		r = newRequest(http.MethodPost, "/api/tus/", nil, headers)
+		Process(w, r, testUser)
+		test.IsEqualInt(t, w.Code, http.StatusBadRequest)
+	}
+	w = httptest.NewRecorder()
+	r = newRequest(http.MethodPost, "/api/tus/", nil, map[string]string{"Upload-Length": "26214401", "Upload-Metadata": encodeMetadata("filename", "test")})
+	Process(w, r, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusRequestEntityTooLarge)
+
+	test.IsEqualInt(t, headUpload("invalid", testUser).Code, http.StatusNotFound)
+	test.IsEqualInt(t, patchUpload("invalid", "0", "test", nil).Code, http.StatusNotFound)
+}
+
+func TestUpload(t *testing.T) {
+	content := "This is a file uploaded with tus"
+	id := createUpload(t, "32", encodeMetadata("name", "tus.txt", "type", "text/plain", "allowedDownloads", "0", "expiryDays", "3", "password", "secret"))
+	test.IsEqualBool(t, chunking.FileExists(id), true)
+
+	w := headUpload(id, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusOK)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "0")
+	test.IsEqualString(t, w.Header().Get("Upload-Length"), "32")
+	test.IsEqualString(t, w.Header().Get("Tus-Resumable"), Version)
+	test.IsEqualInt(t, headUpload(id, models.User{Id: 8}).Code, http.StatusNotFound)
+
+	w = patchUpload(id, "0", content[:10], map[string]string{"Content-Type": "text/plain"})
+	test.IsEqualInt(t, w.Code, http.StatusUnsupportedMediaType)
+	w = patchUpload(id, "abc", content[:10], nil)
+	test.IsEqualInt(t, w.Code, http.StatusBadRequest)
+
+	w = patchUpload(id, "0", content[:10], nil)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "10")
+	test.IsEqualString(t, w.Header().Get(HeaderFileId), "")
+
+	w = patchUpload(id, "5", content[5:15], nil)
+	test.IsEqualInt(t, w.Code, http.StatusConflict)
+	w = patchUpload(id, "10", content[10:]+"too long", nil)
+	test.IsEqualInt(t, w.Code, http.StatusRequestEntityTooLarge)
+
+	w = patchUpload(id, "10", content[10:20], map[string]string{"Upload-Checksum": "sha1 " + base64.StdEncoding.EncodeToString([]byte("invalid"))})
+	test.IsEqualInt(t, w.Code, statusChecksumMismatch)
+	w = patchUpload(id, "10", content[10:20], map[string]string{"Upload-Checksum": "crc32 AAAA"})
+	test.IsEqualInt(t, w.Code, http.StatusBadRequest)
+	test.IsEqualString(t, headUpload(id, testUser).Header().Get("Upload-Offset"), "10")
+
+	checksum := sha1.Sum([]byte(content[10:]))
+	w = patchUpload(id, "10", content[10:], map[string]string{"Upload-Checksum": "sha1 " + base64.StdEncoding.EncodeToString(checksum[:])})
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "32")
+	fileId := w.Header().Get(HeaderFileId)
+	test.IsNotEqualString(t, fileId, "")
+	test.IsEqualBool(t, chunking.FileExists(id), false)
+
+	file, ok := database.GetMetaDataById(fileId)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.Name, "tus.txt")
+	test.IsEqualString(t, file.ContentType, "text/plain")
+	test.IsEqualInt64(t, file.SizeBytes, 32)
+	test.IsEqualInt(t, file.UserId, testUser.Id)
+	test.IsEqualBool(t, file.UnlimitedDownloads, true)
+	test.IsEqualBool(t, file.UnlimitedTime, false)
+	test.IsNotEqualString(t, file.PasswordHash, "")
+
+	w = headUpload(id, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusOK)
+	test.IsEqualString(t, w.Header().Get("Upload-Offset"), "32")
+	test.IsEqualString(t, w.Header().Get(HeaderFileId), fileId)
+	test.IsEqualInt(t, patchUpload(id, "32", "", nil).Code, http.StatusConflict)
+
+	w = httptest.NewRecorder()
+	Process(w, newRequest(http.MethodDelete, "/api/tus/"+id, nil, nil), testUser)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	_, ok = database.GetMetaDataById(fileId)
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, headUpload(id, testUser).Code, http.StatusNotFound)
+}
+
+func TestEmptyUpload(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := newRequest(http.MethodPost, "/api/tus/", nil, map[string]string{"Upload-Length": "0", "Upload-Metadata": encodeMetadata("filename", "empty.txt")})
+	Process(w, r, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusCreated)
+	file, ok := database.GetMetaDataById(w.Header().Get(HeaderFileId))
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualString(t, file.Name, "empty.txt")
+	test.IsEqualString(t, file.ContentType, "application/octet-stream")
+	test.IsEqualInt(t, file.DownloadsRemaining, 1)
+	test.IsEqualBool(t, file.UnlimitedTime, false)
+}
+
+func TestTermination(t *testing.T) {
+	id := createUpload(t, "100", encodeMetadata("filename", "terminate.bin"))
+	test.IsEqualBool(t, chunking.FileExists(id), true)
+	w := httptest.NewRecorder()
+	Process(w, newRequest(http.MethodDelete, "/api/tus/"+id, nil, nil), models.User{Id: 8})
+	test.IsEqualInt(t, w.Code, http.StatusNotFound)
+	w = httptest.NewRecorder()
+	r := newRequest(http.MethodPost, "/api/tus/"+id, nil, map[string]string{"X-HTTP-Method-Override": http.MethodDelete})
+	Process(w, r, testUser)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualBool(t, chunking.FileExists(id), false)
+	test.IsEqualInt(t, headUpload(id, testUser).Code, http.StatusNotFound)
+}
+
+func TestLock(t *testing.T) {
+	id := createUpload(t, "100", encodeMetadata("filename", "lock.bin"))
+	entry, status, _ := lock(id, testUser)
+	test.IsEqualInt(t, status, 0)
+	_, status, _ = lock(id, testUser)
+	test.IsEqualInt(t, status, http.StatusConflict)
+	test.IsEqualInt(t, patchUpload(id, "0", "test", nil).Code, http.StatusConflict)
+	unlock(entry)
+	test.IsEqualInt(t, patchUpload(id, "0", "test", nil).Code, http.StatusNoContent)
+}
+
+func postUpload(length string, metadata string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := newRequest(http.MethodPost, "/api/tus/", nil, map[string]string{"Upload-Length": length, "Upload-Metadata": metadata})
+	Process(w, r, testUser)
+	return w
+}
+
+func TestQuota(t *testing.T) {
+	mutex.Lock()
+	uploads = make(map[string]upload)
+	mutex.Unlock()
+	storage.SaveUserQuota(models.UserQuota{UserId: testUser.Id, MaxStorageMb: 1, MaxFiles: 0, MaxExpiryDays: 2, MaxDownloads: 3})
+	defer storage.SaveUserQuota(models.NewUserQuotaDefault(testUser.Id))
+	remaining := 1024*1024 - storage.GetQuotaUsage(testUser.Id).StorageBytes
+	length := strconv.FormatInt(remaining*2/3, 10)
+	metadata := encodeMetadata("filename", "quota.bin", "allowedDownloads", "1", "expiryDays", "1")
+
+	test.IsEqualInt(t, postUpload(strconv.FormatInt(remaining+1, 10), metadata).Code, http.StatusRequestEntityTooLarge)
+	test.IsEqualInt(t, postUpload("100", encodeMetadata("filename", "quota.bin", "expiryDays", "0")).Code, http.StatusBadRequest)
+	test.IsEqualInt(t, postUpload("100", encodeMetadata("filename", "quota.bin", "allowedDownloads", "0")).Code, http.StatusBadRequest)
+
+	id := createUpload(t, length, metadata)
+	// Incomplete uploads are counted as well
+	test.IsEqualInt(t, postUpload(length, metadata).Code, http.StatusRequestEntityTooLarge)
+	w := httptest.NewRecorder()
+	Process(w, newRequest(http.MethodDelete, "/api/tus/"+id, nil, nil), testUser)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	id = createUpload(t, length, metadata)
+	w = httptest.NewRecorder()
+	Process(w, newRequest(http.MethodDelete, "/api/tus/"+id, nil, nil), testUser)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+}
+
+func TestSharedState(t *testing.T) {
+	mRedis := miniredis.RunT(t)
+	backend, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	sharedstate.Init(backend)
+	defer sharedstate.Close()
+	otherInstance, err := redisstate.New(models.DbConnection{HostUrl: mRedis.Addr()})
+	test.IsNil(t, err)
+	defer otherInstance.Close()
+
+	content := "This is a file uploaded to multiple instances"
+	id := createUpload(t, strconv.Itoa(len(content)), encodeMetadata("filename", "shared.txt"))
+	mutex.Lock()
+	_, ok := uploads[id]
+	mutex.Unlock()
+	test.IsEqualBool(t, ok, false)
+	entries, err := otherInstance.GetGroupEntries(sharedGroupPrefix + strconv.Itoa(testUser.Id))
+	test.IsNil(t, err)
+	_, ok = entries[id]
+	test.IsEqualBool(t, ok, true)
+	test.IsEqualInt(t, headUpload(id, testUser).Code, http.StatusOK)
+	test.IsEqualInt(t, headUpload(id, models.User{Id: 8}).Code, http.StatusNotFound)
+
+	isLocked, err := otherInstance.TryLock(sharedLockPrefix+id, time.Minute)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, true)
+	test.IsEqualInt(t, patchUpload(id, "0", content[:10], nil).Code, http.StatusConflict)
+	test.IsNil(t, otherInstance.Unlock(sharedLockPrefix+id))
+
+	w := patchUpload(id, "0", content[:10], nil)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualString(t, headUpload(id, testUser).Header().Get("Upload-Offset"), "10")
+	isLocked, err = otherInstance.IsLocked(sharedLockPrefix + id)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, false)
+	w = patchUpload(id, "10", content[10:], nil)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsNotEqualString(t, w.Header().Get(HeaderFileId), "")
+
+	w = httptest.NewRecorder()
+	Process(w, newRequest(http.MethodDelete, "/api/tus/"+id, nil, nil), testUser)
+	test.IsEqualInt(t, w.Code, http.StatusNoContent)
+	test.IsEqualInt(t, headUpload(id, testUser).Code, http.StatusNotFound)
+	isLocked, err = otherInstance.IsLocked(sharedLockPrefix + id)
+	test.IsNil(t, err)
+	test.IsEqualBool(t, isLocked, false)
+
+	id = createUpload(t, "100", encodeMetadata("filename", "sharedexpiry.bin"))
+	mRedis.FastForward(expiryTime + time.Hour)
+	test.IsEqualInt(t, headUpload(id, testUser).Code, http.StatusNotFound)
+
+	// Malformed entries are treated as missing
+	id = createUpload(t, "100", encodeMetadata("filename", "sharedvalid.bin"))
+	test.IsNil(t, otherInstance.SetGroupEntry(sharedGroupPrefix+strconv.Itoa(testUser.Id), "malformed", []byte("{invalid"), time.Hour))
+	test.IsEqualInt(t, headUpload("malformed", testUser).Code, http.StatusNotFound)
+	test.IsEqualInt(t, headUpload(id, testUser).Code, http.StatusOK)
+}
+
+type failingReader struct {
+	content io.Reader
+}
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.content.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestInterruptedUpload(t *testing.T) {
+	id := createUpload(t, "100", encodeMetadata("filename", "interrupted.bin"))
+	w := httptest.NewRecorder()
+	r := newRequest(http.MethodPatch, "/api/tus/"+id, failingReader{bytes.NewReader(make([]byte, 40))},
+		map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"})
+	r.ContentLength = 100
+	Process(w, r, testUser)
+	test.IsEqualString(t, headUpload(id, testUser).Header().Get("Upload-Offset"), "40")
+}
+
+func TestCleanUp(t *testing.T) {
+	id := createUpload(t, "100", encodeMetadata("filename", "expired.bin"))
+	mutex.Lock()
+	entry := uploads[id]
+	entry.Expiry = time.Now().Add(-time.Minute).Unix()
+	uploads[id] = entry
+	mutex.Unlock()
+	test.IsEqualInt(t, headUpload(id, testUser).Code, http.StatusNotFound)
+	cleanUp(false)
+	test.IsEqualBool(t, chunking.FileExists(id), false)
+	mutex.Lock()
+	_, ok := uploads[id]
+	mutex.Unlock()
+	test.IsEqualBool(t, ok, false)
+}
+
+func TestParseMetadata(t *testing.T) {
+	result, err := parseMetadata("")
+	test.IsNil(t, err)
+	test.IsEqualInt(t, len(result), 0)
+	result, err = parseMetadata(encodeMetadata("filename", "test.txt", "password", "pw") + ",isFlag")
+	test.IsNil(t, err)
+	test.IsEqualString(t, result["filename"], "test.txt")
+	test.IsEqualString(t, result["password"], "pw")
+	_, ok := result["isFlag"]
+	test.IsEqualBool(t, ok, true)
+	_, err = parseMetadata(" ,filename dGVzdA==")
+	test.IsNotNil(t, err)
+}
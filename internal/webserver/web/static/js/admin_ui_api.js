@@ -255,6 +255,12 @@ function addRowApi(apiKey, publicId) {
             granted: false,
             title: 'Manage System Logs'
         },
+        {
+            perm: 'PERM_MANAGE_WEBHOOKS',
+            icon: 'bi-broadcast',
+            granted: false,
+            title: 'Manage Webhooks'
+        },
         {
             perm: 'PERM_API_MOD',
             icon: 'bi-sliders2',
@@ -300,6 +306,11 @@ function addRowApi(apiKey, publicId) {
         cell.classList.add("perm-unavailable");
         cell.classList.add("perm-nochange");
     }
+    if (!canManageWebhooks) {
+        let cell = document.getElementById("perm_manage_webhooks_" + publicId);
+        cell.classList.add("perm-unavailable");
+        cell.classList.add("perm-nochange");
+    }
 
     setTimeout(() => {
         cellFriendlyName.classList.remove("newApiKey");
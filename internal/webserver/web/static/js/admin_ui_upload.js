@@ -330,6 +330,15 @@ function parseProgressStatus(eventData) {
                 dropzoneUploadError(file, eventData.error_message);
             }
             return;
+        case 4:
+            text = "Checking file...";
+            break;
+        case 5:
+            let quarantinedFile = dropzoneGetFile(eventData.chunk_id);
+            if (quarantinedFile != null) {
+                dropzoneUploadError(quarantinedFile, "Quarantined: " + eventData.error_message);
+            }
+            return;
         default:
             text = "Unknown status";
             break;